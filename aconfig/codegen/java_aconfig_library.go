@@ -40,16 +40,71 @@ type JavaAconfigDeclarationsLibraryProperties struct {
 	// "exported": to generate exported mode version of the library
 	// "force-read-only": to generate force-read-only mode version of the library
 	// an error will be thrown if the mode is not supported
-	Mode *string
+	// The mode can be selected by release config, e.g. to switch between the internal and the
+	// exportable view of the flags with select(release_flag(...), {...}).
+	Mode proptools.Configurable[string]
+
+	// If true, also generate a "<name>.exportable" java_aconfig_library of the exportable view of
+	// the flags, which uses the "exported" mode and so only contains the finalized flags. The
+	// aconfig_declarations must have the exportable property set.
+	Generate_exportable_library *bool
 }
 
 type JavaAconfigDeclarationsLibraryCallbacks struct {
 	properties JavaAconfigDeclarationsLibraryProperties
 }
 
+// JavaAconfigLibraryInfo exposes which view of the flags a java_aconfig_library was generated for.
+type JavaAconfigLibraryInfo struct {
+	// The mode the library was generated with.
+	Mode string
+
+	// Whether the library is the exportable view of the flags, i.e. it was generated with the
+	// "exported" mode.
+	Exportable bool
+
+	// The name of the java_aconfig_library of the exportable view of the flags that was generated
+	// for this library by generate_exportable_library, or "" if there is none.
+	ExportableLibrary string
+}
+
+var JavaAconfigLibraryInfoProvider = blueprint.NewProvider[JavaAconfigLibraryInfo]()
+
 func JavaDeclarationsLibraryFactory() android.Module {
 	callbacks := &JavaAconfigDeclarationsLibraryCallbacks{}
-	return java.GeneratedJavaLibraryModuleFactory("java_aconfig_library", callbacks, &callbacks.properties)
+	module := java.GeneratedJavaLibraryModuleFactory("java_aconfig_library", callbacks, &callbacks.properties)
+	javaModule := module.(*java.GeneratedJavaLibraryModule)
+	javaModule.SetDefaultableHook(func(ctx android.DefaultableHookContext) {
+		callbacks.createExportableLibrary(ctx, javaModule)
+	})
+	return module
+}
+
+// exportableLibraryName returns the name of the java_aconfig_library of the exportable view of
+// the flags generated for the java_aconfig_library called name.
+func exportableLibraryName(name string) string {
+	return name + ".exportable"
+}
+
+// createExportableLibrary creates the java_aconfig_library of the exportable view of the flags
+// when generate_exportable_library is set. It runs after defaults have been applied, so that the
+// library is built against the same sdk and is available to the same apexes as the module, however
+// they were set.
+func (callbacks *JavaAconfigDeclarationsLibraryCallbacks) createExportableLibrary(ctx android.DefaultableHookContext, module *java.GeneratedJavaLibraryModule) {
+	if !proptools.Bool(callbacks.properties.Generate_exportable_library) {
+		return
+	}
+
+	props := struct {
+		Name                 *string
+		Aconfig_declarations string
+		Mode                 proptools.Configurable[string]
+	}{
+		Name:                 proptools.StringPtr(exportableLibraryName(ctx.ModuleName())),
+		Aconfig_declarations: callbacks.properties.Aconfig_declarations,
+		Mode:                 proptools.NewSimpleConfigurable("exported"),
+	}
+	ctx.CreateModule(JavaDeclarationsLibraryFactory, &props, module.PropertiesForCreatedModule())
 }
 
 func (callbacks *JavaAconfigDeclarationsLibraryCallbacks) DepsMutator(module *java.GeneratedJavaLibraryModule, ctx android.BottomUpMutatorContext) {
@@ -87,11 +142,15 @@ func (callbacks *JavaAconfigDeclarationsLibraryCallbacks) GenerateSourceJarBuild
 	// Generate the action to build the srcjar
 	srcJarPath := android.PathForModuleGen(ctx, ctx.ModuleName()+".srcjar")
 
-	mode := proptools.StringDefault(callbacks.properties.Mode, "production")
+	mode := callbacks.properties.Mode.GetOrDefault(ctx, "production")
 	if !isModeSupported(mode) {
 		ctx.PropertyErrorf("mode", "%q is not a supported mode", mode)
 	}
 
+	if mode == "exported" && proptools.Bool(callbacks.properties.Generate_exportable_library) {
+		ctx.PropertyErrorf("generate_exportable_library", "the library is already the exportable view of the flags")
+	}
+
 	if mode == "exported" && !declarations.Exportable {
 		// if mode is exported, the corresponding aconfig_declaration must mark its
 		// exportable property true
@@ -141,6 +200,16 @@ func (callbacks *JavaAconfigDeclarationsLibraryCallbacks) GenerateSourceJarBuild
 			}},
 	})
 
+	var exportableLibrary string
+	if proptools.Bool(callbacks.properties.Generate_exportable_library) {
+		exportableLibrary = exportableLibraryName(ctx.ModuleName())
+	}
+	android.SetProvider(ctx, JavaAconfigLibraryInfoProvider, JavaAconfigLibraryInfo{
+		Mode:              mode,
+		Exportable:        mode == "exported",
+		ExportableLibrary: exportableLibrary,
+	})
+
 	return srcJarPath, declarations.IntermediateCacheOutputPath
 }
 
@@ -16,6 +16,7 @@ package codegen
 
 import (
 	"fmt"
+	"strconv"
 	"testing"
 
 	"android/soong/android"
@@ -239,6 +240,163 @@ func TestUnsupportedMode(t *testing.T) {
 	testCodegenModeWithError(t, "mode: `unsupported`,", "mode: \"unsupported\" is not a supported mode")
 }
 
+func TestModeSelectedByReleaseFlag(t *testing.T) {
+	bp := `
+		aconfig_declarations {
+			name: "my_aconfig_declarations",
+			package: "com.example.package",
+			container: "com.android.foo",
+			srcs: ["foo.aconfig"],
+			exportable: true,
+		}
+
+		java_aconfig_library {
+			name: "my_java_aconfig_library",
+			aconfig_declarations: "my_aconfig_declarations",
+			mode: select(release_flag("RELEASE_MY_EXPORTABLE_FLAGS"), {
+				true: "exported",
+				default: "production",
+			}),
+		}
+	`
+	for _, exportable := range []bool{false, true} {
+		result := android.GroupFixturePreparers(
+			PrepareForTestWithAconfigBuildComponents,
+			java.PrepareForTestWithJavaDefaultModules,
+			android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+				variables.BuildFlags = map[string]string{
+					"RELEASE_MY_EXPORTABLE_FLAGS": strconv.FormatBool(exportable),
+				}
+				variables.BuildFlagTypes = map[string]string{
+					"RELEASE_MY_EXPORTABLE_FLAGS": "bool",
+				}
+			}),
+		).RunTestWithBp(t, bp)
+
+		expectedMode := "production"
+		if exportable {
+			expectedMode = "exported"
+		}
+		module := result.ModuleForTests(t, "my_java_aconfig_library", "android_common")
+		rule := module.Rule("java_aconfig_library")
+		android.AssertStringEquals(t, "mode", expectedMode, rule.Args["mode"])
+
+		info, _ := android.OtherModuleProvider(result.OtherModuleProviderAdaptor(), module.Module(), JavaAconfigLibraryInfoProvider)
+		android.AssertStringEquals(t, "provider mode", expectedMode, info.Mode)
+		android.AssertBoolEquals(t, "provider exportable", exportable, info.Exportable)
+	}
+}
+
+func TestGenerateExportableLibrary(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithAconfigBuildComponents,
+		java.PrepareForTestWithJavaDefaultModules).
+		ExtendWithErrorHandler(android.FixtureExpectsNoErrors).
+		RunTestWithBp(t, `
+			aconfig_declarations {
+				name: "my_aconfig_declarations",
+				package: "com.example.package",
+				container: "com.android.foo",
+				srcs: ["foo.aconfig"],
+				exportable: true,
+			}
+
+			java_aconfig_library {
+				name: "my_java_aconfig_library",
+				aconfig_declarations: "my_aconfig_declarations",
+				generate_exportable_library: true,
+				sdk_version: "current",
+				min_sdk_version: "30",
+				apex_available: ["com.android.foo"],
+			}
+		`)
+
+	internal := result.ModuleForTests(t, "my_java_aconfig_library", "android_common")
+	android.AssertStringEquals(t, "internal mode", "production",
+		internal.Rule("java_aconfig_library").Args["mode"])
+	internalInfo, _ := android.OtherModuleProvider(result.OtherModuleProviderAdaptor(), internal.Module(), JavaAconfigLibraryInfoProvider)
+	android.AssertDeepEquals(t, "internal provider", JavaAconfigLibraryInfo{
+		Mode:              "production",
+		ExportableLibrary: "my_java_aconfig_library.exportable",
+	}, internalInfo)
+
+	exportable := result.ModuleForTests(t, "my_java_aconfig_library.exportable", "android_common")
+	android.AssertStringEquals(t, "exportable mode", "exported",
+		exportable.Rule("java_aconfig_library").Args["mode"])
+	exportableInfo, _ := android.OtherModuleProvider(result.OtherModuleProviderAdaptor(), exportable.Module(), JavaAconfigLibraryInfoProvider)
+	android.AssertDeepEquals(t, "exportable provider", JavaAconfigLibraryInfo{
+		Mode:       "exported",
+		Exportable: true,
+	}, exportableInfo)
+
+	exportableModule := exportable.Module().(*java.GeneratedJavaLibraryModule)
+	android.AssertDeepEquals(t, "exportable apex_available",
+		[]string{"com.android.foo"}, exportableModule.ApexAvailable())
+	android.AssertStringEquals(t, "exportable min_sdk_version",
+		"30", exportableModule.MinSdkVersionString())
+}
+
+func TestGenerateExportableLibraryWithDefaults(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithAconfigBuildComponents,
+		java.PrepareForTestWithJavaDefaultModules).
+		ExtendWithErrorHandler(android.FixtureExpectsNoErrors).
+		RunTestWithBp(t, `
+			aconfig_declarations {
+				name: "my_aconfig_declarations",
+				package: "com.example.package",
+				container: "com.android.foo",
+				srcs: ["foo.aconfig"],
+				exportable: true,
+			}
+
+			java_defaults {
+				name: "my_defaults",
+				sdk_version: "current",
+				min_sdk_version: "30",
+				apex_available: ["com.android.foo"],
+			}
+
+			java_aconfig_library {
+				name: "my_java_aconfig_library",
+				defaults: ["my_defaults"],
+				aconfig_declarations: "my_aconfig_declarations",
+				generate_exportable_library: true,
+			}
+		`)
+
+	exportable := result.ModuleForTests(t, "my_java_aconfig_library.exportable", "android_common")
+	exportableModule := exportable.Module().(*java.GeneratedJavaLibraryModule)
+	android.AssertDeepEquals(t, "exportable apex_available",
+		[]string{"com.android.foo"}, exportableModule.ApexAvailable())
+	android.AssertStringEquals(t, "exportable min_sdk_version",
+		"30", exportableModule.MinSdkVersionString())
+}
+
+func TestGenerateExportableLibraryInExportedMode(t *testing.T) {
+	android.GroupFixturePreparers(
+		PrepareForTestWithAconfigBuildComponents,
+		java.PrepareForTestWithJavaDefaultModules).
+		ExtendWithErrorHandler(android.FixtureExpectsOneErrorPattern(
+			"generate_exportable_library: the library is already the exportable view of the flags")).
+		RunTestWithBp(t, `
+			aconfig_declarations {
+				name: "my_aconfig_declarations",
+				package: "com.example.package",
+				container: "com.android.foo",
+				srcs: ["foo.aconfig"],
+				exportable: true,
+			}
+
+			java_aconfig_library {
+				name: "my_java_aconfig_library",
+				aconfig_declarations: "my_aconfig_declarations",
+				mode: "exported",
+				generate_exportable_library: true,
+			}
+		`)
+}
+
 func TestMkEntriesMatchedContainer(t *testing.T) {
 	result := android.GroupFixturePreparers(
 		PrepareForTestWithAconfigBuildComponents,
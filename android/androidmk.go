@@ -419,49 +419,59 @@ func getDistContributions(ctx ConfigAndOtherModuleProviderContext, mod Module) *
 				panic(fmt.Errorf("Dist file should not be nil for the %s tag in %s", tagName, name))
 			}
 
-			dest := filepath.Base(path.String())
-
-			if dist.Dest != nil {
-				var err error
-				if dest, err = validateSafePath(*dist.Dest); err != nil {
-					// This was checked in ModuleBase.GenerateBuildActions
-					panic(err)
-				}
+			dest, err := DistDest(ctx.Config(), dist, filepath.Base(path.String()))
+			if err != nil {
+				// This was checked in ModuleBase.GenerateBuildActions
+				panic(err)
 			}
 
-			ext := filepath.Ext(dest)
-			suffix := ""
-			if dist.Suffix != nil {
-				suffix = *dist.Suffix
-			}
+			copiesForGoals.addCopyInstruction(path, dest)
+		}
+	}
 
-			prependProductString := ""
-			if proptools.Bool(dist.Prepend_artifact_with_product) {
-				prependProductString = fmt.Sprintf("%s-", ctx.Config().DeviceProduct())
-			}
+	return distContributions
+}
 
-			appendProductString := ""
-			if proptools.Bool(dist.Append_artifact_with_product) {
-				appendProductString = fmt.Sprintf("_%s", ctx.Config().DeviceProduct())
-			}
+// DistDest returns the path in the dist directory that a file named base is copied to by dist,
+// after applying its dest, suffix, product and dir properties.
+func DistDest(config Config, dist Dist, base string) (string, error) {
+	dest := base
 
-			if suffix != "" || appendProductString != "" || prependProductString != "" {
-				dest = prependProductString + strings.TrimSuffix(dest, ext) + suffix + appendProductString + ext
-			}
+	if dist.Dest != nil {
+		var err error
+		if dest, err = validateSafePath(*dist.Dest); err != nil {
+			return "", err
+		}
+	}
 
-			if dist.Dir != nil {
-				var err error
-				if dest, err = validateSafePath(*dist.Dir, dest); err != nil {
-					// This was checked in ModuleBase.GenerateBuildActions
-					panic(err)
-				}
-			}
+	ext := filepath.Ext(dest)
+	suffix := ""
+	if dist.Suffix != nil {
+		suffix = *dist.Suffix
+	}
 
-			copiesForGoals.addCopyInstruction(path, dest)
+	prependProductString := ""
+	if proptools.Bool(dist.Prepend_artifact_with_product) {
+		prependProductString = fmt.Sprintf("%s-", config.DeviceProduct())
+	}
+
+	appendProductString := ""
+	if proptools.Bool(dist.Append_artifact_with_product) {
+		appendProductString = fmt.Sprintf("_%s", config.DeviceProduct())
+	}
+
+	if suffix != "" || appendProductString != "" || prependProductString != "" {
+		dest = prependProductString + strings.TrimSuffix(dest, ext) + suffix + appendProductString + ext
+	}
+
+	if dist.Dir != nil {
+		var err error
+		if dest, err = validateSafePath(*dist.Dir, dest); err != nil {
+			return "", err
 		}
 	}
 
-	return distContributions
+	return dest, nil
 }
 
 // generateDistContributionsForMake generates make rules that will generate the
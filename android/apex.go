@@ -804,6 +804,12 @@ type ApexExportsInfo struct {
 
 	// Map from the apex library name (without prebuilt_ prefix) to the dex file path on host
 	LibraryNameToDexJarPathOnHost map[string]Path
+
+	// Paths to the hidden API annotation-flags.csv, metadata.csv and index.csv files on host, if
+	// they are extracted from a prebuilt apex.
+	HiddenAPIAnnotationFlagsPathOnHost Path
+	HiddenAPIMetadataPathOnHost        Path
+	HiddenAPIIndexPathOnHost           Path
 }
 
 var PrebuiltInfoProvider = blueprint.NewProvider[PrebuiltInfo]()
@@ -1541,12 +1541,52 @@ func (m *ModuleBase) setArchProperties(ctx BottomUpMutatorContext) {
 			propStructs = append(propStructs, propStructShard...)
 		}
 
+		if arch.ArchType == Common && os.Class == Device && primaryDeviceArchProperties(m, genProps) {
+			// Handle the properties of the primary device architecture in the form:
+			// arch: {
+			//     arm: {
+			//         key: value,
+			//     },
+			// },
+			// multilib: {
+			//     lib32: {
+			//         key: value,
+			//     },
+			// },
+			if primaryArch := ctx.Config().DevicePrimaryArchType(); primaryArch != Common {
+				for _, archProperty := range m.archProperties[i] {
+					if archStruct, ok := getArchTypeStruct(ctx, archProperty, primaryArch); ok {
+						propStructs = append(propStructs, archStruct)
+					}
+					if multilibStruct, ok := getMultilibStruct(ctx, archProperty, primaryArch); ok {
+						propStructs = append(propStructs, multilibStruct)
+					}
+				}
+			}
+		}
+
 		for _, propStruct := range propStructs {
 			mergePropertyStruct(ctx, genProps, propStruct)
 		}
 	}
 }
 
+// PrimaryDeviceArchPropertiesModule is implemented by modules that are built for the common
+// architecture but have property structs whose arch_variant properties should take the values
+// for the primary device architecture, e.g. dex flags that differ on 32-bit-only products.
+type PrimaryDeviceArchPropertiesModule interface {
+	// PrimaryDeviceArchProperties returns the property structs, which must be a subset of those
+	// returned by GetProperties, that use the values of the primary device architecture.
+	PrimaryDeviceArchProperties() []interface{}
+}
+
+func primaryDeviceArchProperties(m *ModuleBase, props interface{}) bool {
+	if pm, ok := m.module.(PrimaryDeviceArchPropertiesModule); ok {
+		return InList(props, pm.PrimaryDeviceArchProperties())
+	}
+	return false
+}
+
 // determineBuildOS stores the OS and architecture used for host targets used during the build into
 // config based on the runtime OS and architecture determined by Go and the product configuration.
 func determineBuildOS(config *config) {
@@ -0,0 +1,183 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/blueprint/proptools"
+)
+
+func init() {
+	RegisterBuildBrokenBuildComponents(InitRegistrationContext)
+}
+
+func RegisterBuildBrokenBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterParallelSingletonType("build_broken_escapes", buildBrokenEscapesSingletonFactory)
+}
+
+// BuildBrokenEscape registers the use of a BUILD_BROKEN_* escape hatch by a product, set with
+// PRODUCT_BUILD_BROKEN_ESCAPES in the product configuration.
+type BuildBrokenEscape struct {
+	// The name of the BUILD_BROKEN_* variable, e.g. BUILD_BROKEN_DUP_SYSPROP.
+	Name string
+	// The bug tracking the removal of the escape hatch.
+	Bug string
+	// The date, formatted as YYYY-MM-DD, after which using the escape hatch is an error.
+	Expires string
+	// If set, the date, formatted as YYYY-MM-DD, that the expiry was extended to.
+	ExtendedUntil string `json:",omitempty"`
+}
+
+// buildBrokenVariables maps the BUILD_BROKEN_* variables that Soong reads to their fields in
+// ProductVariables.
+var buildBrokenVariables = map[string]string{
+	"BUILD_BROKEN_CLANG_ASFLAGS":              "BuildBrokenClangAsFlags",
+	"BUILD_BROKEN_CLANG_CFLAGS":               "BuildBrokenClangCFlags",
+	"BUILD_BROKEN_CLANG_PROPERTY":             "BuildBrokenClangProperty",
+	"BUILD_BROKEN_DONT_CHECK_SYSTEMSDK":       "BuildBrokenDontCheckSystemSdk",
+	"BUILD_BROKEN_DUP_SYSPROP":                "BuildBrokenDupSysprop",
+	"BUILD_BROKEN_ENFORCE_SYSPROP_OWNER":      "BuildBrokenEnforceSyspropOwner",
+	"BUILD_BROKEN_INCORRECT_PARTITION_IMAGES": "BuildBrokenIncorrectPartitionImages",
+	"BUILD_BROKEN_INPUT_DIR_MODULES":          "BuildBrokenInputDirModules",
+	"BUILD_BROKEN_PLUGIN_VALIDATION":          "BuildBrokenPluginValidation",
+	"BUILD_BROKEN_TREBLE_SYSPROP_NEVERALLOW":  "BuildBrokenTrebleSyspropNeverallow",
+	"BUILD_BROKEN_VENDOR_PROPERTY_NAMESPACE":  "BuildBrokenVendorPropertyNamespace",
+}
+
+// The number of days before the expiry of an escape hatch that a warning is printed.
+const buildBrokenExpiryWarningDays = 30
+
+const buildBrokenDateFormat = "2006-01-02"
+
+var buildBrokenTodayKey = NewOnceKey("buildBrokenToday")
+
+// buildBrokenToday returns the date that the expiry of the escape hatches is checked against. It is
+// read from SOONG_BUILD_BROKEN_TODAY, which soong_ui sets, so that Soong is re-run when the date
+// changes. It is only read for products that set escape hatches.
+func buildBrokenToday(config Config) time.Time {
+	return config.Once(buildBrokenTodayKey, func() interface{} {
+		if today, err := time.Parse(buildBrokenDateFormat, config.Getenv("SOONG_BUILD_BROKEN_TODAY")); err == nil {
+			return today
+		}
+		now := time.Now().UTC()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}).(time.Time)
+}
+
+// activeBuildBrokenVariables returns the sorted names of the BUILD_BROKEN_* variables that are set
+// by the product.
+func activeBuildBrokenVariables(config Config) []string {
+	variables := reflect.ValueOf(config.productVariables)
+	var active []string
+	for name, field := range buildBrokenVariables {
+		if !variables.FieldByName(field).IsZero() {
+			active = append(active, name)
+		}
+	}
+	sort.Strings(active)
+	return active
+}
+
+func buildBrokenEscapesSingletonFactory() Singleton {
+	return &buildBrokenEscapesSingleton{}
+}
+
+// buildBrokenEscapesSingleton checks the BUILD_BROKEN_* escape hatches set by the product against
+// the escapes registered in the product configuration. It reports an error for the escape hatches
+// that have expired, adds a rule to droidcore that prints a warning for the ones that expire within
+// buildBrokenExpiryWarningDays, and writes a report of all of them to
+// $OUT/soong/build_broken_escapes.txt, which is disted.
+type buildBrokenEscapesSingleton struct{}
+
+func (s *buildBrokenEscapesSingleton) GenerateBuildActions(ctx SingletonContext) {
+	active := activeBuildBrokenVariables(ctx.Config())
+
+	escapes := make(map[string]BuildBrokenEscape)
+	for _, escape := range ctx.DeviceConfig().BuildBrokenEscapes() {
+		if _, exists := escapes[escape.Name]; exists {
+			ctx.Errorf("BUILD_BROKEN escape %s is registered more than once", escape.Name)
+			continue
+		}
+		escapes[escape.Name] = escape
+	}
+
+	var lines, warnings []string
+	for _, name := range SortedKeys(escapes) {
+		escape := escapes[name]
+		if !InList(name, active) {
+			lines = append(lines, fmt.Sprintf("%s %s expires %s: not set, the registration can be removed",
+				name, escape.Bug, escape.Expires))
+			continue
+		}
+
+		expires := escape.Expires
+		if escape.ExtendedUntil != "" {
+			expires = escape.ExtendedUntil
+		}
+		expiry, err := time.Parse(buildBrokenDateFormat, expires)
+		if err != nil {
+			ctx.Errorf("BUILD_BROKEN escape %s has an invalid expiry date %q, expected YYYY-MM-DD",
+				name, expires)
+			continue
+		}
+		if escape.Bug == "" {
+			ctx.Errorf("BUILD_BROKEN escape %s has no bug", name)
+		}
+
+		status := "active"
+		daysLeft := int(expiry.Sub(buildBrokenToday(ctx.Config())).Hours() / 24)
+		if daysLeft < 0 {
+			status = "expired"
+			ctx.Errorf("BUILD_BROKEN escape %s (%s) expired on %s, fix the build breakage or "+
+				"extend the expiry in PRODUCT_BUILD_BROKEN_ESCAPES", name, escape.Bug, expires)
+		} else if daysLeft <= buildBrokenExpiryWarningDays {
+			status = fmt.Sprintf("expires in %d days", daysLeft)
+			warnings = append(warnings, fmt.Sprintf("warning: BUILD_BROKEN escape %s (%s) expires on %s",
+				name, escape.Bug, expires))
+		}
+		if escape.ExtendedUntil != "" {
+			status += ", extended from " + escape.Expires
+		}
+		lines = append(lines, fmt.Sprintf("%s %s expires %s: %s", name, escape.Bug, expires, status))
+	}
+
+	for _, name := range active {
+		if _, ok := escapes[name]; !ok {
+			lines = append(lines, fmt.Sprintf("%s: not registered", name))
+		}
+	}
+
+	report := PathForOutput(ctx, "build_broken_escapes.txt")
+	WriteFileRule(ctx, report, strings.Join(lines, "\n"))
+	ctx.Phony("build_broken_escapes", report)
+	ctx.DistForGoal("droidcore", report)
+
+	if len(warnings) > 0 {
+		stamp := PathForOutput(ctx, "build_broken_escapes_warnings.stamp")
+		rule := NewRuleBuilder(pctx, ctx)
+		for _, warning := range warnings {
+			rule.Command().Text("echo").Text(proptools.ShellEscape(warning)).Text(">&2")
+		}
+		rule.Command().Text("touch").Output(stamp)
+		rule.Build("build_broken_escapes_warnings", "BUILD_BROKEN escape expiry warnings")
+		ctx.Phony("build_broken_escapes", stamp)
+		ctx.Phony("droidcore", stamp)
+	}
+}
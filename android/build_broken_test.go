@@ -0,0 +1,96 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+func prepareForBuildBrokenEscapesTest(escapes ...BuildBrokenEscape) FixturePreparer {
+	return GroupFixturePreparers(
+		FixtureRegisterWithContext(RegisterBuildBrokenBuildComponents),
+		FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+			variables.BuildBrokenDupSysprop = true
+			variables.BuildBrokenClangCFlags = true
+			variables.BuildBrokenInputDirModules = []string{"foo"}
+			variables.BuildBrokenEscapes = escapes
+		}),
+		FixtureMergeEnv(map[string]string{
+			"SOONG_BUILD_BROKEN_TODAY": "2025-03-01",
+		}),
+	)
+}
+
+func TestBuildBrokenEscapesReport(t *testing.T) {
+	t.Parallel()
+	result := prepareForBuildBrokenEscapesTest(
+		BuildBrokenEscape{Name: "BUILD_BROKEN_DUP_SYSPROP", Bug: "b/1", Expires: "2025-12-31"},
+		BuildBrokenEscape{Name: "BUILD_BROKEN_CLANG_CFLAGS", Bug: "b/2", Expires: "2025-03-11"},
+		BuildBrokenEscape{Name: "BUILD_BROKEN_CLANG_PROPERTY", Bug: "b/3", Expires: "2025-12-31"},
+		BuildBrokenEscape{Name: "BUILD_BROKEN_INPUT_DIR_MODULES", Bug: "b/4", Expires: "2025-01-01",
+			ExtendedUntil: "2025-06-30"},
+	).RunTest(t)
+
+	report := ContentFromFileRuleForTests(t, result.TestContext,
+		result.SingletonForTests(t, "build_broken_escapes").Output("build_broken_escapes.txt"))
+	AssertStringEquals(t, "report", ""+
+		"BUILD_BROKEN_CLANG_CFLAGS b/2 expires 2025-03-11: expires in 10 days\n"+
+		"BUILD_BROKEN_CLANG_PROPERTY b/3 expires 2025-12-31: not set, the registration can be removed\n"+
+		"BUILD_BROKEN_DUP_SYSPROP b/1 expires 2025-12-31: active\n"+
+		"BUILD_BROKEN_INPUT_DIR_MODULES b/4 expires 2025-06-30: active, extended from 2025-01-01\n",
+		report)
+
+	warnings := result.SingletonForTests(t, "build_broken_escapes").Rule("build_broken_escapes_warnings")
+	AssertStringDoesContain(t, "warnings", warnings.RuleParams.Command,
+		`echo 'warning: BUILD_BROKEN escape BUILD_BROKEN_CLANG_CFLAGS (b/2) expires on 2025-03-11' >&2`)
+	AssertStringDoesNotContain(t, "warnings", warnings.RuleParams.Command, "BUILD_BROKEN_DUP_SYSPROP")
+}
+
+func TestBuildBrokenEscapesUnregistered(t *testing.T) {
+	t.Parallel()
+	result := prepareForBuildBrokenEscapesTest().RunTest(t)
+
+	report := ContentFromFileRuleForTests(t, result.TestContext,
+		result.SingletonForTests(t, "build_broken_escapes").Output("build_broken_escapes.txt"))
+	AssertStringEquals(t, "report", ""+
+		"BUILD_BROKEN_CLANG_CFLAGS: not registered\n"+
+		"BUILD_BROKEN_DUP_SYSPROP: not registered\n"+
+		"BUILD_BROKEN_INPUT_DIR_MODULES: not registered\n",
+		report)
+}
+
+func TestBuildBrokenEscapesExpired(t *testing.T) {
+	t.Parallel()
+	prepareForBuildBrokenEscapesTest(
+		BuildBrokenEscape{Name: "BUILD_BROKEN_DUP_SYSPROP", Bug: "b/1", Expires: "2025-02-28"},
+		BuildBrokenEscape{Name: "BUILD_BROKEN_CLANG_CFLAGS", Bug: "b/2", Expires: "2025-03-01"},
+		BuildBrokenEscape{Name: "BUILD_BROKEN_INPUT_DIR_MODULES", Bug: "b/4", Expires: "2025-01-01",
+			ExtendedUntil: "2025-06-30"},
+	).ExtendWithErrorHandler(FixtureExpectsOneErrorPattern(
+		`BUILD_BROKEN escape BUILD_BROKEN_DUP_SYSPROP \(b/1\) expired on 2025-02-28`)).
+		RunTest(t)
+}
+
+func TestBuildBrokenEscapesInvalid(t *testing.T) {
+	t.Parallel()
+	prepareForBuildBrokenEscapesTest(
+		BuildBrokenEscape{Name: "BUILD_BROKEN_DUP_SYSPROP", Bug: "b/1", Expires: "31/12/2025"},
+		BuildBrokenEscape{Name: "BUILD_BROKEN_DUP_SYSPROP", Bug: "b/1", Expires: "2025-12-31"},
+	).ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern([]string{
+		`BUILD_BROKEN escape BUILD_BROKEN_DUP_SYSPROP is registered more than once`,
+		`BUILD_BROKEN escape BUILD_BROKEN_DUP_SYSPROP has an invalid expiry date "31/12/2025"`,
+	})).
+		RunTest(t)
+}
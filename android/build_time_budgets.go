@@ -0,0 +1,89 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+
+	"android/soong/shared"
+)
+
+func init() {
+	RegisterBuildTimeBudgetsBuildComponents(InitRegistrationContext)
+}
+
+func RegisterBuildTimeBudgetsBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterParallelSingletonType("build_time_budgets", buildTimeBudgetsSingletonFactory)
+}
+
+// Modules can declare the time they are expected to take to build with expected_build_seconds. The
+// buildTimeBudgetsSingleton writes the budgets to $OUT_DIR/soong/build_time_budgets.json, and
+// soong_ui --build-time-budgets attributes the action durations from the ninja log to the modules
+// by the intermediates directory of their outputs and reports the modules that exceed their budget,
+// so that modules whose build time explodes don't go unnoticed.
+
+func buildTimeBudgetsPath(ctx PathContext) WritablePath {
+	return PathForOutput(ctx, shared.BuildTimeBudgetsFileName)
+}
+
+func buildTimeBudgetsSingletonFactory() Singleton {
+	return &buildTimeBudgetsSingleton{}
+}
+
+type buildTimeBudgetsSingleton struct{}
+
+func (s *buildTimeBudgetsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	// The variants of a module share the budget, record it once per module.
+	budgets := make(map[string]shared.BuildTimeBudget)
+	ctx.VisitAllModules(func(module Module) {
+		seconds := module.base().commonProperties.Expected_build_seconds
+		if seconds == nil {
+			return
+		}
+		if *seconds <= 0 {
+			ctx.ModuleErrorf(module, "expected_build_seconds must be positive, got %d", *seconds)
+			return
+		}
+		budget := shared.BuildTimeBudget{
+			Name:                 ctx.ModuleName(module),
+			Dir:                  ctx.ModuleDir(module),
+			ExpectedBuildSeconds: *seconds,
+		}
+		budgets[budget.Dir+":"+budget.Name] = budget
+	})
+
+	list := make([]shared.BuildTimeBudget, 0, len(budgets))
+	for _, key := range SortedKeys(budgets) {
+		list = append(list, budgets[key])
+	}
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal build time budgets: %s", err)
+		return
+	}
+	output := buildTimeBudgetsPath(ctx)
+	if err := WriteFileToOutputDir(output, data, 0666); err != nil {
+		ctx.Errorf("failed to write %s: %s", output, err)
+		return
+	}
+
+	// This is necessary to satisfy the dangling rules check as this file is written by Soong rather
+	// than a rule.
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: output,
+	})
+}
@@ -90,9 +90,23 @@ type CmdArgs struct {
 	ModuleActionsFile string
 	DocFile           string
 
+	// The module whose properties are written to DumpModuleFile.
+	DumpModule     string
+	DumpModuleFile string
+
+	// The file listing the modules whose direct dependencies are written to ModuleDepsFile.
+	ModuleDepsAllowlist string
+	ModuleDepsFile      string
+
+	// The file that the IDE metadata of all the modules is written to.
+	IdeMetadataFile string
+
 	BuildFromSourceStub bool
 
 	EnsureAllowlistIntegrity bool
+
+	// If set, a heap profile is written to this directory after every mutator.
+	MemprofileMutatorsDir string
 }
 
 // Build modes that soong_build can run as.
@@ -105,6 +119,15 @@ const (
 
 	// Generate a documentation file for module type definitions and exit.
 	GenerateDocFile
+
+	// Write the resolved properties of a module to a JSON file and exit.
+	GenerateModulePropertiesFile
+
+	// Write the direct dependencies of the modules in an allowlist to a JSON file and exit.
+	GenerateModuleDepsFile
+
+	// Analyze all the modules, write their IDE metadata to a JSON file and exit.
+	GenerateIdeMetadataFile
 )
 
 const testKeyDir = "build/make/target/product/security"
@@ -200,6 +223,11 @@ func (c Config) ReleaseVersion() string {
 	return c.config.productVariables.ReleaseVersion
 }
 
+// The name of the release config of the build, e.g. "trunk_staging", derived from TARGET_RELEASE
+func (c Config) ReleaseConfigName() string {
+	return c.Getenv("TARGET_RELEASE")
+}
+
 // The aconfig value set passed to aconfig, derived from RELEASE_VERSION
 func (c Config) ReleaseAconfigValueSets() []string {
 	return c.config.productVariables.ReleaseAconfigValueSets
@@ -227,6 +255,20 @@ func (c Config) ReleaseAconfigExtraReleaseConfigs() []string {
 	return result
 }
 
+// The modules generating the api versions with the SDK extension api levels that are merged into
+// the lint api database of each sdk kind, derived from RELEASE_LINT_SDK_EXTENSIONS_API_VERSIONS,
+// a list of <sdk kind>:<module> entries
+func (c Config) ReleaseLintSdkExtensionsApiVersions() map[string]string {
+	result := make(map[string]string)
+	if val, ok := c.config.productVariables.BuildFlags["RELEASE_LINT_SDK_EXTENSIONS_API_VERSIONS"]; ok {
+		for _, entry := range strings.Fields(val) {
+			sdk, module, _ := strings.Cut(entry, ":")
+			result[sdk] = module
+		}
+	}
+	return result
+}
+
 func (c Config) ReleaseAconfigExtraReleaseConfigsValueSets() map[string][]string {
 	result := make(map[string][]string)
 	for _, rcName := range c.ReleaseAconfigExtraReleaseConfigs() {
@@ -282,6 +324,13 @@ func (c Config) ReleaseHiddenApiExportableStubs() bool {
 		Bool(c.config.productVariables.HiddenapiExportableStubs)
 }
 
+// Dist the "exportable" stubs of java_sdk_library modules into a parallel dist tree per release
+// config, apistubs/<release config>/<group>/<scope>, so that the stubs of multiple release configs can be
+// dist'ed next to each other.
+func (c Config) ReleaseExportableStubsReleaseConfigDist() bool {
+	return c.config.productVariables.GetBuildFlagBool("RELEASE_EXPORTABLE_STUBS_RELEASE_CONFIG_DIST")
+}
+
 // Enable read flag from new storage
 func (c Config) ReleaseReadFromNewStorage() bool {
 	return c.config.productVariables.GetBuildFlagBool("RELEASE_READ_FROM_NEW_STORAGE")
@@ -356,6 +405,9 @@ type config struct {
 	envDeps   map[string]string
 	envFrozen bool
 
+	vendorVarsLock sync.Mutex
+	vendorVarsDeps map[string]bool
+
 	// Changes behavior based on whether Kati runs after soong_build, or if soong_build
 	// runs standalone.
 	katiEnabled bool
@@ -383,6 +435,14 @@ type config struct {
 	// built from the source Java files, not the signature text files.
 	buildFromSourceStub bool
 
+	// If memprofileMutatorsDir is set then a heap profile is written to it after
+	// every mutator.
+	memprofileMutatorsDir string
+
+	// If incrementalBuildActions is true then the build actions of modules that
+	// haven't changed since the last analysis may be restored from the cache.
+	incrementalBuildActions bool
+
 	// If ensureAllowlistIntegrity is true, then the presence of any allowlisted
 	// modules that aren't mixed-built for at least one variant will cause a build
 	// failure
@@ -673,7 +733,10 @@ func initConfig(cmdArgs CmdArgs, availableEnv map[string]string) (*config, error
 
 		OncePer: &OncePer{},
 
-		buildFromSourceStub: cmdArgs.BuildFromSourceStub,
+		buildFromSourceStub:   cmdArgs.BuildFromSourceStub,
+		memprofileMutatorsDir: cmdArgs.MemprofileMutatorsDir,
+
+		incrementalBuildActions: cmdArgs.IncrementalBuildActions,
 	}
 	variant, ok := os.LookupEnv("TARGET_BUILD_VARIANT")
 	isEngBuild := !ok || variant == "eng"
@@ -773,6 +836,9 @@ func initConfig(cmdArgs CmdArgs, availableEnv map[string]string) (*config, error
 	}
 	setBuildMode(cmdArgs.ModuleGraphFile, GenerateModuleGraph)
 	setBuildMode(cmdArgs.DocFile, GenerateDocFile)
+	setBuildMode(cmdArgs.DumpModuleFile, GenerateModulePropertiesFile)
+	setBuildMode(cmdArgs.ModuleDepsFile, GenerateModuleDepsFile)
+	setBuildMode(cmdArgs.IdeMetadataFile, GenerateIdeMetadataFile)
 
 	newConfig.productVariables.Build_from_text_stub = boolPtr(newConfig.BuildFromTextStub())
 
@@ -1369,6 +1435,35 @@ func (c *config) MinimizeJavaDebugInfo() bool {
 	return Bool(c.productVariables.MinimizeJavaDebugInfo) && !Bool(c.productVariables.Eng)
 }
 
+// JavacWerrorEnforcedModules returns the modules that are compiled with javac -Werror, "*" meaning
+// all modules.
+func (c *config) JavacWerrorEnforcedModules() []string {
+	return c.productVariables.JavacWerrorEnforcedModules
+}
+
+// JavacWerrorExemptModules returns the modules that are exempt from javac -Werror enforcement.
+func (c *config) JavacWerrorExemptModules() []string {
+	return c.productVariables.JavacWerrorExemptModules
+}
+
+// EnforceUnsafeEscapesAllowlist returns true if modules may only use unsafe escape hatch
+// properties, e.g. unsafe_ignore_missing_latest_api, when they are in UnsafeEscapesAllowlist.
+func (c *config) EnforceUnsafeEscapesAllowlist() bool {
+	return Bool(c.productVariables.EnforceUnsafeEscapesAllowlist)
+}
+
+// UnsafeEscapesAllowlist returns the approved uses of unsafe escape hatch properties, formatted as
+// "<module>:<property>".
+func (c *config) UnsafeEscapesAllowlist() []string {
+	return c.productVariables.UnsafeEscapesAllowlist
+}
+
+// RelaxUsesLibraryCheckModules returns the modules for which a failure of the
+// verify_uses_libraries check is reported as a warning instead of an error.
+func (c *config) RelaxUsesLibraryCheckModules() []string {
+	return c.productVariables.RelaxUsesLibraryCheckModules
+}
+
 func (c *config) Debuggable() bool {
 	return Bool(c.productVariables.Debuggable)
 }
@@ -1982,7 +2077,29 @@ func (c *config) HWASanEnabledForPath(path string) bool {
 }
 
 func (c *config) VendorConfig(name string) VendorConfig {
-	return soongconfig.Config(c.productVariables.VendorVars[name])
+	vars, _ := c.vendorVars(name)
+	return soongconfig.Config(vars)
+}
+
+// vendorVars returns the soong config variables in the given namespace, and records that the
+// namespace was used so that soong_build is only rerun when the variables in the namespaces it
+// used change.
+func (c *config) vendorVars(namespace string) (map[string]string, bool) {
+	c.vendorVarsLock.Lock()
+	defer c.vendorVarsLock.Unlock()
+	if c.vendorVarsDeps == nil {
+		c.vendorVarsDeps = make(map[string]bool)
+	}
+	c.vendorVarsDeps[namespace] = true
+	vars, ok := c.productVariables.VendorVars[namespace]
+	return vars, ok
+}
+
+// VendorVarsDeps returns the sorted soong config variable namespaces this build depends on.
+func (c *config) VendorVarsDeps() []string {
+	c.vendorVarsLock.Lock()
+	defer c.vendorVarsLock.Unlock()
+	return SortedKeys(c.vendorVarsDeps)
 }
 
 func (c *config) NdkAbis() bool {
@@ -2193,6 +2310,10 @@ func (c *deviceConfig) BuildBrokenDupSysprop() bool {
 	return c.config.productVariables.BuildBrokenDupSysprop
 }
 
+func (c *deviceConfig) BuildBrokenEscapes() []BuildBrokenEscape {
+	return c.config.productVariables.BuildBrokenEscapes
+}
+
 func (c *config) BuildWarningBadOptionalUsesLibsAllowlist() []string {
 	return c.productVariables.BuildWarningBadOptionalUsesLibsAllowlist
 }
@@ -2248,6 +2369,14 @@ func (c *config) ApexBootJars() ConfiguredJarList {
 	return c.productVariables.ApexBootJars
 }
 
+func (c *config) BootclasspathJarjarRules() []string {
+	return c.productVariables.BootclasspathJarjarRules
+}
+
+func (c *config) BootclasspathBannedLanguageFeatures() []string {
+	return c.productVariables.BootclasspathBannedLanguageFeatures
+}
+
 func (c *config) RBEWrapper() string {
 	return c.GetenvWithDefault("RBE_WRAPPER", remoteexec.DefaultWrapperPath)
 }
@@ -2285,6 +2414,16 @@ func (c *config) BuildFromTextStub() bool {
 		!c.deviceConfig.BuildFromSourceStub()
 }
 
+// MemprofileMutatorsDir returns the directory that a heap profile is written to after every
+// mutator, or an empty string if the heap should not be profiled.
+func (c *config) MemprofileMutatorsDir() string {
+	return c.memprofileMutatorsDir
+}
+
+func (c *config) IncrementalBuildActions() bool {
+	return c.incrementalBuildActions
+}
+
 func (c *config) SetBuildFromTextStub(b bool) {
 	c.buildFromSourceStub = !b
 	c.productVariables.Build_from_text_stub = boolPtr(b)
@@ -88,6 +88,26 @@ func TestMissingVendorConfig(t *testing.T) {
 	}
 }
 
+func TestVendorVarsDeps(t *testing.T) {
+	c := &config{}
+	c.productVariables.VendorVars = map[string]map[string]string{
+		"used":   {"a": "true"},
+		"unused": {"b": "true"},
+	}
+	if !c.VendorConfig("used").Bool("a") {
+		t.Errorf("Expected true")
+	}
+	c.VendorConfig("not_set")
+	AssertArrayString(t, "vendor vars deps", []string{"not_set", "used"}, c.VendorVarsDeps())
+}
+
+func TestProductVariableNames(t *testing.T) {
+	names := ProductVariableNames()
+	AssertStringListContains(t, "product variable names", names, "Platform_sdk_version")
+	AssertStringListContains(t, "product variable names", names, "VendorVars")
+	AssertStringListDoesNotContain(t, "product variable names", names, "Make_only")
+}
+
 func verifyProductVariableMarshaling(t *testing.T, v ProductVariables) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "test.variables")
@@ -0,0 +1,196 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+// When soong_build is run with --dump_module, it prints the properties of every variant of a
+// module after the mutators have run, i.e. after defaults, arch and target specific properties,
+// soong config variables and selects have been applied. It reads the same property structs that
+// the module uses when generating its build actions. This is used by `m soong_dump_module`.
+
+// DumpedModuleVariant is the JSON representation of the properties of a single variant of a module.
+type DumpedModuleVariant struct {
+	Name       string         `json:"name"`
+	Dir        string         `json:"dir"`
+	Type       string         `json:"type"`
+	Variant    string         `json:"variant"`
+	Properties map[string]any `json:"properties"`
+}
+
+// DumpModuleProperties returns the properties of all the variants of the module as JSON. The module
+// is either a module name or a fully qualified "//path/to/dir:name" reference.
+func DumpModuleProperties(ctx *Context, module string) ([]byte, error) {
+	ref, err := parseDumpModuleReference(module)
+	if err != nil {
+		return nil, err
+	}
+
+	evalCtx := &dumpModuleEvaluatorContext{ctx: ctx}
+	var variants []DumpedModuleVariant
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		if !ref.matches(ctx, m) {
+			return
+		}
+		mod, ok := m.(Module)
+		if !ok {
+			return
+		}
+		properties := map[string]any{}
+		evaluator := mod.ConfigurableEvaluator(evalCtx)
+		for _, p := range mod.GetProperties() {
+			if values, ok := dumpPropertyValue(evaluator, reflect.ValueOf(p)).(map[string]any); ok {
+				for k, v := range values {
+					properties[k] = v
+				}
+			}
+		}
+		variants = append(variants, DumpedModuleVariant{
+			Name:       ctx.ModuleName(m),
+			Dir:        ctx.ModuleDir(m),
+			Type:       ctx.ModuleType(m),
+			Variant:    ctx.ModuleSubDir(m),
+			Properties: properties,
+		})
+	})
+
+	if len(evalCtx.errs) > 0 {
+		return nil, errors.Join(evalCtx.errs...)
+	}
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("module %q not found", module)
+	}
+	return json.MarshalIndent(variants, "", "  ")
+}
+
+// dumpModuleReference is a module name, optionally qualified with the directory of the module.
+type dumpModuleReference struct {
+	dir, name string
+}
+
+// parseDumpModuleReference parses a module name or a fully qualified "//path/to/dir:name" reference.
+func parseDumpModuleReference(module string) (dumpModuleReference, error) {
+	if !strings.HasPrefix(module, "//") {
+		return dumpModuleReference{name: module}, nil
+	}
+	dir, name, ok := strings.Cut(strings.TrimPrefix(module, "//"), ":")
+	if !ok {
+		return dumpModuleReference{}, fmt.Errorf("invalid module reference %q, expected //path/to/dir:name", module)
+	}
+	return dumpModuleReference{dir: dir, name: name}, nil
+}
+
+func (r dumpModuleReference) matches(ctx *Context, m blueprint.Module) bool {
+	return ctx.ModuleName(m) == r.name && (r.dir == "" || ctx.ModuleDir(m) == r.dir)
+}
+
+// dumpPropertyValue converts a property value to a value that can be marshalled to JSON, evaluating
+// any configurable properties. It returns nil for unset properties.
+func dumpPropertyValue(evaluator proptools.ConfigurableEvaluator, v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return dumpPropertyValue(evaluator, v.Elem())
+	case reflect.Struct:
+		if proptools.IsConfigurable(v.Type()) {
+			return dumpPropertyValue(evaluator, evaluateConfigurable(evaluator, v))
+		}
+		properties := map[string]any{}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() || proptools.HasTag(field, "blueprint", "mutated") {
+				continue
+			}
+			if value := dumpPropertyValue(evaluator, v.Field(i)); value != nil {
+				if field.Anonymous {
+					// Embedded structs contribute their properties to the enclosing struct.
+					if embedded, ok := value.(map[string]any); ok {
+						for k, e := range embedded {
+							properties[k] = e
+						}
+						continue
+					}
+				}
+				properties[proptools.PropertyNameForField(field.Name)] = value
+			}
+		}
+		if len(properties) == 0 {
+			return nil
+		}
+		return properties
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return nil
+		}
+		values := make([]any, v.Len())
+		for i := range values {
+			values[i] = dumpPropertyValue(evaluator, v.Index(i))
+		}
+		return values
+	case reflect.Invalid:
+		return nil
+	default:
+		return v.Interface()
+	}
+}
+
+// evaluateConfigurable returns the value of a proptools.Configurable property, or an invalid
+// reflect.Value if it is not set.
+func evaluateConfigurable(evaluator proptools.ConfigurableEvaluator, v reflect.Value) reflect.Value {
+	// Copy the values into pointers so that both value and pointer receiver methods can be called.
+	configurable := reflect.New(v.Type())
+	configurable.Elem().Set(v)
+	result := configurable.MethodByName("Get").Call([]reflect.Value{reflect.ValueOf(evaluator)})[0]
+	optional := reflect.New(result.Type())
+	optional.Elem().Set(result)
+	if !optional.MethodByName("IsPresent").Call(nil)[0].Bool() {
+		return reflect.Value{}
+	}
+	return optional.MethodByName("Get").Call(nil)[0]
+}
+
+// dumpModuleEvaluatorContext is a ConfigurableEvaluatorContext that evaluates selects outside of
+// the module's own contexts, after all the mutators have run.
+type dumpModuleEvaluatorContext struct {
+	ctx  *Context
+	errs []error
+}
+
+func (d *dumpModuleEvaluatorContext) Config() Config {
+	return d.ctx.config
+}
+
+func (d *dumpModuleEvaluatorContext) HasMutatorFinished(mutatorName string) bool {
+	return d.ctx.HasMutatorFinished(mutatorName)
+}
+
+func (d *dumpModuleEvaluatorContext) OtherModulePropertyErrorf(module Module, property string, format string, args ...interface{}) {
+	d.errs = append(d.errs, d.ctx.PropertyErrorf(module, property, format, args...))
+}
+
+func (d *dumpModuleEvaluatorContext) otherModuleProvider(m blueprint.Module, p blueprint.AnyProviderKey) (any, bool) {
+	return d.ctx.ModuleProvider(m, p)
+}
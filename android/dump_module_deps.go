@@ -0,0 +1,127 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+)
+
+// When soong_build is run with --module_deps_allowlist, it writes the names of the direct
+// dependencies of every variant of the modules listed in the allowlist file to a JSON file. Policy
+// checks in CI, e.g. that no new dependencies on legacy libraries are added, can then be run
+// against the JSON file instead of being written as Go tests in the tree. This is used by
+// `m soong_module_deps`.
+
+// DumpedModuleDeps is the JSON representation of the direct dependencies of a single variant of a
+// module.
+type DumpedModuleDeps struct {
+	Name    string   `json:"name"`
+	Dir     string   `json:"dir"`
+	Type    string   `json:"type"`
+	Variant string   `json:"variant"`
+	Deps    []string `json:"deps"`
+}
+
+// ReadModuleDepsAllowlist reads a file that lists one module per line, either as a module name or
+// as a fully qualified "//path/to/dir:name" reference. Empty lines and lines starting with # are
+// ignored.
+func ReadModuleDepsAllowlist(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var modules []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		modules = append(modules, line)
+	}
+	return modules, nil
+}
+
+// DumpModuleDependencies returns the sorted, unique names of the direct dependencies of all the
+// variants of the modules in the allowlist as JSON. It returns an error if one of the modules in
+// the allowlist doesn't exist, so that typos don't silently disable a policy check.
+func DumpModuleDependencies(ctx *Context, allowlist []string) ([]byte, error) {
+	refs := make([]dumpModuleReference, 0, len(allowlist))
+	for _, module := range allowlist {
+		ref, err := parseDumpModuleReference(module)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+
+	found := make([]bool, len(refs))
+	dumped := []DumpedModuleDeps{}
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		matched := false
+		for i, ref := range refs {
+			if ref.matches(ctx, m) {
+				found[i] = true
+				matched = true
+			}
+		}
+		if !matched {
+			return
+		}
+
+		var deps []string
+		ctx.VisitDirectDeps(m, func(dep blueprint.Module) {
+			deps = append(deps, ctx.ModuleName(dep))
+		})
+		deps = SortedUniqueStrings(deps)
+		if deps == nil {
+			// Write an empty list instead of null, the checks shouldn't have to handle both.
+			deps = []string{}
+		}
+		dumped = append(dumped, DumpedModuleDeps{
+			Name:    ctx.ModuleName(m),
+			Dir:     ctx.ModuleDir(m),
+			Type:    ctx.ModuleType(m),
+			Variant: ctx.ModuleSubDir(m),
+			Deps:    deps,
+		})
+	})
+
+	var missing []string
+	for i, module := range allowlist {
+		if !found[i] {
+			missing = append(missing, module)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("modules in the allowlist not found: %s", strings.Join(missing, ", "))
+	}
+
+	sort.SliceStable(dumped, func(i, j int) bool {
+		if dumped[i].Name != dumped[j].Name {
+			return dumped[i].Name < dumped[j].Name
+		}
+		if dumped[i].Dir != dumped[j].Dir {
+			return dumped[i].Dir < dumped[j].Dir
+		}
+		return dumped[i].Variant < dumped[j].Variant
+	})
+	return json.MarshalIndent(dumped, "", "  ")
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDumpModuleDependencies(t *testing.T) {
+	t.Parallel()
+	result := GroupFixturePreparers(
+		prepareForDefaultsTest,
+		FixtureWithRootAndroidBp(`
+			test {
+				name: "foo",
+				path_prop: [":gen", ":legacy"],
+			}
+
+			test {
+				name: "bar",
+			}
+
+			test {
+				name: "gen",
+			}
+
+			test {
+				name: "legacy",
+			}
+		`),
+	).RunTest(t)
+
+	data, err := DumpModuleDependencies(result.TestContext.Context, []string{"foo", "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dumped []DumpedModuleDeps
+	if err := json.Unmarshal(data, &dumped); err != nil {
+		t.Fatal(err)
+	}
+
+	AssertIntEquals(t, "number of dumped modules", 2, len(dumped))
+	AssertStringEquals(t, "first module", "bar", dumped[0].Name)
+	AssertDeepEquals(t, "bar deps", []string{}, dumped[0].Deps)
+	AssertStringEquals(t, "second module", "foo", dumped[1].Name)
+	AssertStringEquals(t, "foo type", "test", dumped[1].Type)
+	AssertDeepEquals(t, "foo deps", []string{"gen", "legacy"}, dumped[1].Deps)
+
+	_, err = DumpModuleDependencies(result.TestContext.Context, []string{"foo", "baz"})
+	AssertErrorMessageEquals(t, "missing module", "modules in the allowlist not found: baz", err)
+}
+
+func TestReadModuleDepsAllowlist(t *testing.T) {
+	t.Parallel()
+	file := filepath.Join(t.TempDir(), "allowlist.txt")
+	if err := os.WriteFile(file, []byte("# Modules checked by CI\nfoo\n\n  //path/to/dir:bar  \n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	modules, err := ReadModuleDepsAllowlist(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertDeepEquals(t, "modules", []string{"foo", "//path/to/dir:bar"}, modules)
+}
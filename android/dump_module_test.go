@@ -0,0 +1,92 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDumpModuleProperties(t *testing.T) {
+	t.Parallel()
+	result := GroupFixturePreparers(
+		PrepareForTestWithDefaults,
+		PrepareForTestWithArchMutator,
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("my_module_type", newSelectsMockModule)
+			ctx.RegisterModuleType("my_defaults", newSelectsMockModuleDefaults)
+		}),
+		FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+			variables.VendorVars = map[string]map[string]string{
+				"my_namespace": {"my_variable": "a"},
+			}
+		}),
+	).RunTestWithBp(t, `
+		my_defaults {
+			name: "foo_defaults",
+			my_string_list: ["from_defaults"],
+		}
+
+		my_module_type {
+			name: "foo",
+			defaults: ["foo_defaults"],
+			my_string: select(soong_config_variable("my_namespace", "my_variable"), {
+				"a": "a",
+				default: "b",
+			}),
+			my_string_list: ["from_module"],
+			my_nonconfigurable_bool: true,
+		}
+	`)
+
+	data, err := DumpModuleProperties(result.TestContext.Context, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var variants []DumpedModuleVariant
+	if err := json.Unmarshal(data, &variants); err != nil {
+		t.Fatal(err)
+	}
+
+	variant := "android_arm64_armv8-a"
+	var dumped *DumpedModuleVariant
+	for i := range variants {
+		if variants[i].Variant == variant {
+			dumped = &variants[i]
+		}
+	}
+	if dumped == nil {
+		t.Fatalf("variant %s of foo was not dumped:\n%s", variant, data)
+	}
+	AssertStringEquals(t, "type", "my_module_type", dumped.Type)
+
+	// The dumped properties must match the values that the module itself used.
+	foo := result.ModuleForTests(t, "foo", variant)
+	p, _ := OtherModuleProvider(result.testContext.OtherModuleProviderAdaptor(), foo.Module(), selectsTestProviderKey)
+	AssertDeepEquals(t, "my_string", *p.my_string, dumped.Properties["my_string"])
+	var stringList []any
+	for _, s := range *p.my_string_list {
+		stringList = append(stringList, s)
+	}
+	AssertDeepEquals(t, "my_string_list", stringList, dumped.Properties["my_string_list"])
+	AssertDeepEquals(t, "my_nonconfigurable_bool", true, dumped.Properties["my_nonconfigurable_bool"])
+	if _, ok := dumped.Properties["my_int64"]; ok {
+		t.Errorf("expected unset property my_int64 not to be dumped")
+	}
+
+	if _, err := DumpModuleProperties(result.TestContext.Context, "//other:foo"); err == nil {
+		t.Errorf("expected an error for a module in another directory")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+// Host tool modules can declare the version of the tool they build by providing
+// HostToolVersionInfo. Modules that run host tools can then declare the minimum version of each
+// tool they need with RequiredHostTool, which is checked at analysis time instead of failing with
+// an obscure error when the tool runs.
+
+// HostToolVersionInfo is provided by host tool modules that declare the version of their tool.
+type HostToolVersionInfo struct {
+	// Version of the tool, a dot separated list of numbers, e.g. "1.2".
+	Version string
+}
+
+var HostToolVersionInfoProvider = blueprint.NewProvider[HostToolVersionInfo]()
+
+// RequiredHostTool is a constraint on the version of a host tool used by a module.
+type RequiredHostTool struct {
+	// Name of the host tool module.
+	Name *string
+
+	// Minimum version of the host tool, a dot separated list of numbers, e.g. "1.2".
+	Min_version *string
+}
+
+// ParseHostToolVersion parses a dot separated list of numbers, e.g. "1.2".
+func ParseHostToolVersion(version string) ([]int, error) {
+	var ret []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%q is not a valid host tool version, expected a dot separated list of numbers", version)
+		}
+		ret = append(ret, n)
+	}
+	return ret, nil
+}
+
+// compareHostToolVersions returns -1, 0 or 1 if a is older than, the same as, or newer than b.
+// Missing trailing components are treated as 0, so "1.2" is the same as "1.2.0".
+func compareHostToolVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+	}
+	return 0
+}
+
+// CheckRequiredHostTools reports errors on the required_host_tools property for the constraints
+// that are not satisfied by the host tools the module depends on. tools maps the names of the
+// host tool dependencies of the module to their version information, or nil if they don't declare
+// a version.
+func CheckRequiredHostTools(ctx BaseModuleContext, required []RequiredHostTool, tools map[string]*HostToolVersionInfo) {
+	for _, r := range required {
+		name := proptools.String(r.Name)
+		if name == "" {
+			ctx.PropertyErrorf("required_host_tools", "name must be set")
+			continue
+		}
+		minVersion, err := ParseHostToolVersion(proptools.String(r.Min_version))
+		if err != nil {
+			ctx.PropertyErrorf("required_host_tools", "%s: %s", name, err)
+			continue
+		}
+		info, ok := tools[name]
+		if !ok {
+			ctx.PropertyErrorf("required_host_tools", "%q is not a host tool dependency of this module", name)
+			continue
+		}
+		if info == nil {
+			ctx.PropertyErrorf("required_host_tools", "host tool %q does not declare a version, but version %s is required",
+				name, *r.Min_version)
+			continue
+		}
+		version, err := ParseHostToolVersion(info.Version)
+		if err != nil {
+			ctx.PropertyErrorf("required_host_tools", "host tool %q: %s", name, err)
+			continue
+		}
+		if compareHostToolVersions(version, minVersion) < 0 {
+			ctx.PropertyErrorf("required_host_tools", "host tool %q has version %s, but version %s or newer is required",
+				name, info.Version, *r.Min_version)
+		}
+	}
+}
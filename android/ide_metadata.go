@@ -0,0 +1,151 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/google/blueprint"
+)
+
+// When soong_build is run with --ide_metadata_file, it analyzes all the modules and writes the
+// metadata that IDEs need to create a project for each module that provides IdeInfo to a JSON
+// file: its sources and the directories that contain them, the source jars generated for it, the
+// classpath it is compiled against and its Kotlin settings. IDE integrations can read this file
+// instead of parsing module-info.json and module_bp_java_deps.json, whose formats aren't meant for
+// them. This is used by `m soong_ide_metadata`.
+
+// IdeMetadataVersion is the version of the schema of the IDE metadata file. It must be
+// incremented for every change to the schema that isn't backwards compatible, e.g. removing or
+// renaming a field.
+const IdeMetadataVersion = 1
+
+// IdeMetadata is the JSON representation of the IDE metadata file.
+type IdeMetadata struct {
+	Version int                          `json:"version"`
+	Modules map[string]IdeModuleMetadata `json:"modules"`
+}
+
+// IdeModuleMetadata is the JSON representation of the IDE metadata of a module. The metadata of
+// all the variants of a module is merged.
+type IdeModuleMetadata struct {
+	Dir string `json:"dir"`
+
+	// The sources of the module, and the directories that contain them.
+	Srcs       []string `json:"srcs"`
+	SourceDirs []string `json:"source_dirs"`
+
+	// The source jars that are generated for the module, e.g. by aidl or annotation processors.
+	GeneratedSrcJars []string `json:"generated_srcjars"`
+
+	// The jars the module is compiled against, and the jars that modules that depend on it are
+	// compiled against.
+	Classpath []string `json:"classpath"`
+	Jars      []string `json:"jars"`
+
+	Deps []string `json:"deps"`
+
+	// The Kotlin settings of the module, only set for modules that compile Kotlin sources.
+	Kotlin *IdeKotlinInfo `json:"kotlin,omitempty"`
+}
+
+// IdeKotlinInfo is the Kotlin settings of a module.
+type IdeKotlinInfo struct {
+	LanguageVersion string   `json:"language_version"`
+	ApiVersion      string   `json:"api_version"`
+	Flags           []string `json:"flags"`
+	Plugins         []string `json:"plugins"`
+}
+
+// IDECompileInfo is implemented by modules that compile sources that IDEs need to analyze.
+type IDECompileInfo interface {
+	IDECompileInfo(ctx BaseModuleContext, info *IdeCompileInfo)
+}
+
+// IdeCompileInfo is the information about how the sources of a module are compiled, in addition
+// to the IdeInfo of the module.
+type IdeCompileInfo struct {
+	Classpath []string
+	Kotlin    *IdeKotlinInfo
+}
+
+var IdeCompileInfoProvider = blueprint.NewProvider[IdeCompileInfo]()
+
+// emptyIfNil returns an empty list instead of nil, so that the IDE integrations don't have to
+// handle both null and [] in the JSON file.
+func emptyIfNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+// DumpIdeMetadata returns the IDE metadata of all the enabled, preferred modules that provide
+// IdeInfo as JSON.
+func DumpIdeMetadata(ctx *Context) ([]byte, error) {
+	providers := NewOtherModuleProviderAdaptor(ctx.ModuleProvider)
+
+	modules := make(map[string]IdeModuleMetadata)
+	ctx.VisitAllModules(func(m blueprint.Module) {
+		if module, ok := m.(Module); !ok || !IsModulePreferred(module) {
+			return
+		}
+		ideInfo, ok := OtherModuleProvider(providers, m, IdeInfoProviderKey)
+		if !ok {
+			return
+		}
+		compileInfo, _ := OtherModuleProvider(providers, m, IdeCompileInfoProvider)
+
+		name := RemoveOptionalPrebuiltPrefix(ctx.ModuleName(m))
+		metadata := modules[name]
+		metadata.Dir = ctx.ModuleDir(m)
+		metadata.Srcs = append(metadata.Srcs, ideInfo.Srcs...)
+		for _, src := range ideInfo.Srcs {
+			metadata.SourceDirs = append(metadata.SourceDirs, filepath.Dir(src))
+		}
+		metadata.GeneratedSrcJars = append(metadata.GeneratedSrcJars, ideInfo.SrcJars...)
+		metadata.Classpath = append(metadata.Classpath, compileInfo.Classpath...)
+		metadata.Jars = append(metadata.Jars, ideInfo.Jars...)
+		metadata.Deps = append(metadata.Deps, ideInfo.Deps...)
+		if metadata.Kotlin == nil {
+			metadata.Kotlin = compileInfo.Kotlin
+		}
+		modules[name] = metadata
+	})
+
+	for name, metadata := range modules {
+		metadata.Srcs = emptyIfNil(FirstUniqueStrings(metadata.Srcs))
+		metadata.SourceDirs = emptyIfNil(SortedUniqueStrings(metadata.SourceDirs))
+		metadata.GeneratedSrcJars = emptyIfNil(FirstUniqueStrings(metadata.GeneratedSrcJars))
+		metadata.Classpath = emptyIfNil(FirstUniqueStrings(metadata.Classpath))
+		metadata.Jars = emptyIfNil(FirstUniqueStrings(metadata.Jars))
+		metadata.Deps = emptyIfNil(SortedUniqueStrings(metadata.Deps))
+		if kotlin := metadata.Kotlin; kotlin != nil {
+			metadata.Kotlin = &IdeKotlinInfo{
+				LanguageVersion: kotlin.LanguageVersion,
+				ApiVersion:      kotlin.ApiVersion,
+				Flags:           emptyIfNil(kotlin.Flags),
+				Plugins:         emptyIfNil(kotlin.Plugins),
+			}
+		}
+		modules[name] = metadata
+	}
+
+	return json.MarshalIndent(IdeMetadata{
+		Version: IdeMetadataVersion,
+		Modules: modules,
+	}, "", "  ")
+}
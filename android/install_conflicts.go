@@ -0,0 +1,110 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"slices"
+)
+
+func init() {
+	RegisterInstallConflictsBuildComponents(InitRegistrationContext)
+}
+
+func RegisterInstallConflictsBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterParallelSingletonType("install_conflicts", installConflictsSingletonFactory)
+}
+
+func installConflictsSingletonFactory() Singleton {
+	return &installConflictsSingleton{}
+}
+
+// installConflictsSingleton indexes the files installed by the modules listed in PRODUCT_PACKAGES
+// and reports an error for each install path that different modules install different files to.
+// Without it, such conflicts are only found when the partition images are built, if at all.
+//
+// Modules that aren't listed in PRODUCT_PACKAGES are not installed in the partition images, so
+// they may install to the same path as other modules. Two modules may also install to the same
+// path if they install the same file, or if one of them lists the other in its overrides
+// property, in which case only one of them is installed in a given product.
+type installConflictsSingleton struct{}
+
+// installConflictsProductPackages returns the set of the modules that are installed in the
+// partition images of the product.
+func installConflictsProductPackages(config Config) map[string]bool {
+	partitionVars := config.productVariables.PartitionVarsForSoongMigrationOnlyDoNotUse
+	productPackages := make(map[string]bool)
+	for _, name := range partitionVars.ProductPackages {
+		productPackages[name] = true
+	}
+	if config.Debuggable() {
+		for _, name := range partitionVars.ProductPackagesDebug {
+			productPackages[name] = true
+		}
+	}
+	return productPackages
+}
+
+func (s *installConflictsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	productPackages := installConflictsProductPackages(ctx.Config())
+	if len(productPackages) == 0 {
+		return
+	}
+
+	specs := make(map[string][]PackagingSpec)
+	ctx.VisitAllModuleProxies(func(module ModuleProxy) {
+		if !productPackages[ctx.ModuleName(module)] {
+			return
+		}
+		info, ok := OtherModuleProvider(ctx, module, InstallFilesProvider)
+		if !ok {
+			return
+		}
+		for _, spec := range info.PackagingSpecs {
+			if spec.SkipInstall() || !spec.RequiresFullInstall() {
+				continue
+			}
+			installPath := spec.FullInstallPath().String()
+			specs[installPath] = append(specs[installPath], spec)
+		}
+	})
+
+	for _, installPath := range SortedKeys(specs) {
+		if a, b := installConflict(specs[installPath]); a != nil {
+			owners := SortedUniqueStrings([]string{a.Owner(), b.Owner()})
+			ctx.Errorf("%s is installed by both %q and %q. If one of them is meant to replace "+
+				"the other, add the other to its overrides property.",
+				installPath, owners[0], owners[1])
+		}
+	}
+}
+
+// installConflict returns the first pair of packaging specs of different modules that install
+// different files to the same path and don't override each other, or nil if there is none.
+func installConflict(specs []PackagingSpec) (*PackagingSpec, *PackagingSpec) {
+	for i := range specs {
+		for j := i + 1; j < len(specs); j++ {
+			a, b := &specs[i], &specs[j]
+			if a.owner == b.owner || a.Equals(b) {
+				continue
+			}
+			if slices.Contains(a.overrides.ToSlice(), b.owner) ||
+				slices.Contains(b.overrides.ToSlice(), a.owner) {
+				continue
+			}
+			return a, b
+		}
+	}
+	return nil, nil
+}
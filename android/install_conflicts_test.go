@@ -0,0 +1,104 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+)
+
+type fakeInstallModule struct {
+	ModuleBase
+	props struct {
+		Stem *string
+	}
+}
+
+func fakeInstallModuleFactory() Module {
+	module := &fakeInstallModule{}
+	module.AddProperties(&module.props)
+	InitAndroidModule(module)
+	return module
+}
+
+func (f *fakeInstallModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	builtFile := PathForModuleOut(ctx, ctx.ModuleName())
+	ctx.InstallFile(PathForModuleInstall(ctx, "bin"), proptools.StringDefault(f.props.Stem, ctx.ModuleName()), builtFile)
+}
+
+func TestInstallConflicts(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name            string
+		bp              string
+		productPackages []string
+		expectedError   string
+	}{
+		{
+			name: "different paths",
+			bp: `
+				fake_install_module { name: "foo" }
+				fake_install_module { name: "bar" }
+			`,
+		},
+		{
+			name: "same path",
+			bp: `
+				fake_install_module { name: "foo" }
+				fake_install_module { name: "bar", stem: "foo" }
+			`,
+			expectedError: `system/bin/foo is installed by both "bar" and "foo"`,
+		},
+		{
+			name: "same path not in PRODUCT_PACKAGES",
+			bp: `
+				fake_install_module { name: "foo" }
+				fake_install_module { name: "bar", stem: "foo" }
+			`,
+			productPackages: []string{"foo"},
+		},
+		{
+			name: "same path with overrides",
+			bp: `
+				fake_install_module { name: "foo" }
+				fake_install_module { name: "bar", stem: "foo", overrides: ["foo"] }
+			`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			errorHandler := FixtureExpectsNoErrors
+			if tc.expectedError != "" {
+				errorHandler = FixtureExpectsOneErrorPattern(tc.expectedError)
+			}
+			productPackages := tc.productPackages
+			if productPackages == nil {
+				productPackages = []string{"foo", "bar"}
+			}
+			GroupFixturePreparers(
+				FixtureRegisterWithContext(func(ctx RegistrationContext) {
+					ctx.RegisterModuleType("fake_install_module", fakeInstallModuleFactory)
+					RegisterInstallConflictsBuildComponents(ctx)
+				}),
+				FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+					variables.PartitionVarsForSoongMigrationOnlyDoNotUse.ProductPackages = productPackages
+				}),
+			).ExtendWithErrorHandler(errorHandler).RunTestWithBp(t, tc.bp)
+		})
+	}
+}
@@ -373,6 +373,10 @@ type commonProperties struct {
 	// vendor who owns this module
 	Owner *string
 
+	// The expected time in seconds to run the actions that build the outputs of all the variants
+	// of this module. soong_ui --build-time-budgets reports the modules that take longer.
+	Expected_build_seconds *int64
+
 	// whether this module is specific to an SoC (System-On-a-Chip). When set to true,
 	// it is installed into /vendor (or /system/vendor if vendor partition does not exist).
 	// Use `soc_specific` instead for better meaning.
@@ -880,6 +884,9 @@ type ModuleBase struct {
 
 	noAddressSanitizer bool
 
+	// The content hash of the module computed by the module_hash mutator.
+	moduleHash uint64
+
 	hooks hooks
 
 	registerProps []interface{}
@@ -2135,6 +2142,12 @@ func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 			SetProvider(ctx, IdeInfoProviderKey, result)
 		}
 
+		if x, ok := m.module.(IDECompileInfo); ok {
+			var result IdeCompileInfo
+			x.IDECompileInfo(ctx, &result)
+			SetProvider(ctx, IdeCompileInfoProvider, result)
+		}
+
 		// Create the set of tagged dist files after calling GenerateAndroidBuildActions
 		// as GenerateTaggedDistFiles() calls OutputFiles(tag) and so relies on the
 		// output paths being set which must be done before or during
@@ -2706,7 +2719,7 @@ func (e configurationEvalutor) EvaluateConfiguration(condition proptools.Configu
 		}
 		namespace := condition.Arg(0)
 		variable := condition.Arg(1)
-		if n, ok := ctx.Config().productVariables.VendorVars[namespace]; ok {
+		if n, ok := ctx.Config().vendorVars(namespace); ok {
 			if v, ok := n[variable]; ok {
 				ty := ""
 				if namespaces, ok := ctx.Config().productVariables.VendorVarTypes[namespace]; ok {
@@ -2764,7 +2777,7 @@ func (e configurationEvalutor) EvaluateConfiguration(condition proptools.Configu
 			return proptools.ConfigurableValueUndefined()
 		}
 
-		if n, ok := ctx.Config().productVariables.VendorVars["boolean_var"]; ok {
+		if n, ok := ctx.Config().vendorVars("boolean_var"); ok {
 			if v, ok := n["for_testing"]; ok {
 				switch v {
 				case "true":
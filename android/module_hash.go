@@ -0,0 +1,173 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint/proptools"
+)
+
+func init() {
+	RegisterModuleHashBuildComponents(InitRegistrationContext)
+}
+
+func RegisterModuleHashBuildComponents(ctx RegistrationContext) {
+	ctx.FinalDepsMutators(func(ctx RegisterMutatorsContext) {
+		ctx.BottomUp("module_hash", moduleHashMutator)
+	})
+	ctx.RegisterParallelSingletonType("module_hashes", moduleHashesSingletonFactory)
+}
+
+// With --incremental-build-actions, the module_hash mutator computes a hash of the resolved
+// properties of every module variant together with the configuration that applies to all modules.
+// The moduleHashesSingleton stores the hashes in $OUT_DIR/soong/module_hashes.json and compares
+// them with the hashes from the previous analysis, writing the module variants that were added or
+// changed to $OUT_DIR/soong/module_hash_changes.txt to show how much of the analysis was
+// invalidated.
+//
+// The hash is informational only. It doesn't cover the outputs of the dependencies of the module,
+// the environment or the results of globs, so an unchanged hash is not enough on its own to restore
+// the build actions of the previous analysis instead of calling GenerateAndroidBuildActions again.
+
+const moduleHashesFileName = "module_hashes.json"
+const moduleHashChangesFileName = "module_hash_changes.txt"
+
+var previousModuleHashesKey = NewOnceKey("previousModuleHashes")
+var configHashKey = NewOnceKey("configHash")
+
+func moduleHashesPath(ctx PathContext) WritablePath {
+	return PathForOutput(ctx, moduleHashesFileName)
+}
+
+func moduleHashChangesPath(ctx PathContext) WritablePath {
+	return PathForOutput(ctx, moduleHashChangesFileName)
+}
+
+// previousModuleHashes returns the module hashes written by the previous analysis, or nil if there
+// are none.
+func previousModuleHashes(ctx PathContext) map[string]uint64 {
+	return ctx.Config().Once(previousModuleHashesKey, func() interface{} {
+		data, err := os.ReadFile(absolutePath(moduleHashesPath(ctx).String()))
+		if err != nil {
+			return map[string]uint64(nil)
+		}
+		var hashes map[string]uint64
+		if err := json.Unmarshal(data, &hashes); err != nil {
+			// A corrupt file invalidates all modules.
+			return map[string]uint64(nil)
+		}
+		return hashes
+	}).(map[string]uint64)
+}
+
+// configHash returns the hash of the parts of the configuration that can affect the build actions
+// of any module.
+func configHash(ctx ConfigContext) (uint64, error) {
+	type result struct {
+		hash uint64
+		err  error
+	}
+	r := ctx.Config().Once(configHashKey, func() interface{} {
+		hash, err := proptools.CalculateHash(struct {
+			ProductVariables ProductVariables
+			BuildMode        SoongBuildMode
+		}{ctx.Config().productVariables, ctx.Config().BuildMode})
+		return result{hash, err}
+	}).(result)
+	return r.hash, r.err
+}
+
+func moduleHashKey(dir, name, variant string) string {
+	return fmt.Sprintf("//%s:%s{%s}", dir, name, variant)
+}
+
+func moduleHashMutator(ctx BottomUpMutatorContext) {
+	if !ctx.Config().IncrementalBuildActions() {
+		return
+	}
+	m := ctx.Module().base()
+
+	config, err := configHash(ctx)
+	if err != nil {
+		ctx.ModuleErrorf("failed to hash the configuration: %s", err)
+		return
+	}
+	hash, err := proptools.CalculateHash(struct {
+		Config     uint64
+		Type       string
+		Variant    string
+		Properties []interface{}
+	}{config, ctx.ModuleType(), ctx.OtherModuleSubDir(ctx.Module()), m.GetProperties()})
+	if err != nil {
+		ctx.ModuleErrorf("failed to hash the module: %s", err)
+		return
+	}
+
+	m.moduleHash = hash
+}
+
+func moduleHashesSingletonFactory() Singleton {
+	return &moduleHashesSingleton{}
+}
+
+// moduleHashesSingleton writes the hashes computed by the module_hash mutator for the next
+// analysis, and the module variants whose hash differs from the previous analysis.
+type moduleHashesSingleton struct{}
+
+func (s *moduleHashesSingleton) GenerateBuildActions(ctx SingletonContext) {
+	if !ctx.Config().IncrementalBuildActions() {
+		return
+	}
+
+	previous := previousModuleHashes(ctx)
+	hashes := make(map[string]uint64)
+	var changes []string
+	ctx.VisitAllModules(func(module Module) {
+		key := moduleHashKey(ctx.ModuleDir(module), ctx.ModuleName(module), ctx.ModuleSubDir(module))
+		hash := module.base().moduleHash
+		hashes[key] = hash
+		if previousHash, ok := previous[key]; !ok || previousHash != hash {
+			changes = append(changes, key)
+		}
+	})
+	sort.Strings(changes)
+
+	data, err := json.MarshalIndent(hashes, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal module hashes: %s", err)
+		return
+	}
+	s.writeFile(ctx, moduleHashesPath(ctx), data)
+	s.writeFile(ctx, moduleHashChangesPath(ctx), []byte(strings.Join(changes, "\n")))
+}
+
+func (s *moduleHashesSingleton) writeFile(ctx SingletonContext, output WritablePath, data []byte) {
+	if err := WriteFileToOutputDir(output, data, 0666); err != nil {
+		ctx.Errorf("failed to write %s: %s", output, err)
+		return
+	}
+
+	// This is necessary to satisfy the dangling rules check as this file is written by Soong rather
+	// than a rule.
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: output,
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+type moduleHashTestModule struct {
+	ModuleBase
+	props struct {
+		Value *string
+	}
+}
+
+func moduleHashTestModuleFactory() Module {
+	module := &moduleHashTestModule{}
+	module.AddProperties(&module.props)
+	InitAndroidModule(module)
+	return module
+}
+
+func (m *moduleHashTestModule) GenerateAndroidBuildActions(ModuleContext) {}
+
+func TestModuleHashes(t *testing.T) {
+	t.Parallel()
+
+	run := func(bp string, previous map[string]uint64) *TestResult {
+		return GroupFixturePreparers(
+			FixtureRegisterWithContext(func(ctx RegistrationContext) {
+				ctx.RegisterModuleType("test_module", moduleHashTestModuleFactory)
+				RegisterModuleHashBuildComponents(ctx)
+			}),
+			FixtureModifyConfig(func(config Config) {
+				config.incrementalBuildActions = true
+				config.Once(previousModuleHashesKey, func() interface{} { return previous })
+			}),
+			FixtureWithRootAndroidBp(bp),
+		).RunTest(t)
+	}
+
+	readFile := func(result *TestResult, path WritablePath) []byte {
+		data, err := os.ReadFile(absolutePath(path.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	result := run(`
+		test_module { name: "foo", value: "a" }
+		test_module { name: "bar", value: "a" }
+	`, nil)
+	ctx := PathContextForTesting(result.Config)
+	AssertStringEquals(t, "changes without previous hashes", "//:bar{}\n//:foo{}",
+		string(readFile(result, moduleHashChangesPath(ctx))))
+
+	data := readFile(result, moduleHashesPath(ctx))
+	var hashes map[string]uint64
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		t.Fatal(err)
+	}
+	AssertIntEquals(t, "number of module hashes", 2, len(hashes))
+	AssertBoolEquals(t, "foo and bar hashes differ", true, hashes["//:foo{}"] != hashes["//:bar{}"])
+
+	result = run(`
+		test_module { name: "foo", value: "a" }
+		test_module { name: "bar", value: "b" }
+	`, hashes)
+	AssertStringEquals(t, "changes", "//:bar{}",
+		string(readFile(result, moduleHashChangesPath(PathContextForTesting(result.Config)))))
+}
@@ -44,6 +44,7 @@ type ExtraModuleInfoJSON struct {
 	AutoTestConfig       []string `json:"auto_test_config,omitempty"`     // $(ALL_MODULES.$(m).auto_test_config)
 	TestConfig           []string `json:"test_config,omitempty"`          // $(strip $(ALL_MODULES.$(m).TEST_CONFIG) $(ALL_MODULES.$(m).EXTRA_TEST_CONFIGS)
 	TestModuleConfigBase string   `json:"test_module_config_base,omitempty"`
+	ShardCount           string   `json:"shard_count,omitempty"`
 	ExtraRequired        []string `json:"-"`
 	ExtraHostRequired    []string `json:"-"`
 
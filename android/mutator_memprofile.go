@@ -0,0 +1,72 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sync"
+
+	"github.com/google/blueprint"
+)
+
+// When soong_build is run with --memprofile_mutators_dir, a heap profile is written to that
+// directory after every mutator, so that the memory usage of each phase of the analysis can be
+// inspected when debugging out of memory failures. The profiles are numbered in the order in which
+// the mutators run and named after the mutator that was just finished, e.g. 012_arch.pprof.
+
+const heapProfileMutatorPrefix = "heap_profile_"
+
+// addHeapProfileMutators returns a copy of mutators with a mutator that writes a heap profile to dir
+// inserted after each of them.
+func addHeapProfileMutators(mutators sortableComponents, dir string) sortableComponents {
+	ret := make(sortableComponents, 0, 2*len(mutators))
+	for i, m := range mutators {
+		ret = append(ret, m)
+		file := filepath.Join(dir, fmt.Sprintf("%03d_%s.pprof", i, m.componentName()))
+		ret = append(ret, &mutator{
+			name:            heapProfileMutatorPrefix + m.componentName(),
+			bottomUpMutator: heapProfileMutator(file),
+		})
+	}
+	return ret
+}
+
+// heapProfileMutator returns a mutator that writes a heap profile to file the first time it is
+// called. The previous mutator has finished running on all modules by then.
+func heapProfileMutator(file string) blueprint.BottomUpMutator {
+	var once sync.Once
+	return func(ctx blueprint.BottomUpMutatorContext) {
+		once.Do(func() {
+			if err := writeHeapProfile(file); err != nil {
+				ctx.ModuleErrorf("failed to write heap profile: %s", err)
+			}
+		})
+	}
+}
+
+func writeHeapProfile(file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return pprof.WriteHeapProfile(f)
+}
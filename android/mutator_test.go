@@ -16,6 +16,8 @@ package android
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -162,3 +164,38 @@ func TestFinalDepsPhase(t *testing.T) {
 
 	AssertDeepEquals(t, "final", finalWant, finalGotMap)
 }
+
+func TestAddHeapProfileMutators(t *testing.T) {
+	dir := t.TempDir()
+	mutators := sortableComponents{
+		&mutator{name: "first"},
+		&mutator{name: "second"},
+	}
+
+	got := addHeapProfileMutators(mutators, dir)
+
+	var names []string
+	for _, m := range got {
+		names = append(names, m.componentName())
+	}
+	AssertDeepEquals(t, "mutators", []string{
+		"first",
+		"heap_profile_first",
+		"second",
+		"heap_profile_second",
+	}, names)
+
+	// The profile is only written by the first module the mutator runs on, the context is only
+	// used to report errors.
+	profileMutator := got[3].(*mutator).bottomUpMutator
+	profileMutator(nil)
+	profileMutator(nil)
+
+	profile := filepath.Join(dir, "001_second.pprof")
+	if _, err := os.Stat(profile); err != nil {
+		t.Errorf("expected heap profile %s to be written: %s", profile, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "000_first.pprof")); !os.IsNotExist(err) {
+		t.Errorf("expected the heap profile of the first mutator not to be written yet")
+	}
+}
@@ -43,6 +43,46 @@ type ProtoFlags struct {
 	Deps                  Paths
 }
 
+// ProtoSrcsInfo records the .proto files that a module generated code from, along with the
+// language and the generator flavor that were used.  It is used to detect the same proto file being
+// compiled into incompatible variants by different languages.
+type ProtoSrcsInfo struct {
+	// The language the code was generated for, e.g. "cc" or "java".
+	Language string
+
+	// The flavor of the generated code, see ProtoFlavor.
+	Flavor string
+
+	// The .proto files that were compiled.
+	Srcs Paths
+}
+
+var ProtoSrcsInfoProvider = blueprint.NewProvider[ProtoSrcsInfo]()
+
+// ProtoFlavor returns the flavor of generated code that the given flags produce: "lite" or "full"
+// for the builtin C++ and Java generators, or the name of the generator otherwise, e.g. "nanopb"
+// or "javamicro".
+func ProtoFlavor(flags ProtoFlags) string {
+	switch flags.OutTypeFlag {
+	case "--cpp_out", "--java_out":
+		if InList("lite", flags.OutParams) {
+			return "lite"
+		}
+		return "full"
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(flags.OutTypeFlag, "--"), "_out")
+}
+
+// SetProtoSrcsInfo sets the ProtoSrcsInfoProvider for a module that generated code for language
+// from the given .proto files.
+func SetProtoSrcsInfo(ctx ModuleContext, language string, flags ProtoFlags, srcs Paths) {
+	SetProvider(ctx, ProtoSrcsInfoProvider, ProtoSrcsInfo{
+		Language: language,
+		Flavor:   ProtoFlavor(flags),
+		Srcs:     srcs,
+	})
+}
+
 type protoDependencyTag struct {
 	blueprint.BaseDependencyTag
 	name string
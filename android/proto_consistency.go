@@ -0,0 +1,128 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func init() {
+	RegisterProtoConsistencyBuildComponents(InitRegistrationContext)
+}
+
+func RegisterProtoConsistencyBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterParallelSingletonType("proto_consistency", protoConsistencySingletonFactory)
+}
+
+func protoConsistencySingletonFactory() Singleton {
+	return &protoConsistencySingleton{}
+}
+
+// protoConsistencySingleton indexes the .proto files compiled by all modules and reports the files
+// that are compiled into incompatible flavors (lite vs. full) by different languages, as mixing
+// them across a language boundary leads to hard to debug runtime failures.
+//
+// The report is always written to $OUT/soong/proto_consistency.txt.  If
+// SOONG_ENFORCE_PROTO_CONSISTENCY=true is set, each conflict is also reported as an error.
+type protoConsistencySingleton struct{}
+
+// protoUse is a single module variant compiling a .proto file.
+type protoUse struct {
+	language string
+	flavor   string
+	module   string
+}
+
+func (p *protoConsistencySingleton) GenerateBuildActions(ctx SingletonContext) {
+	// Index the uses of each .proto file, separately for the host and the device as they never
+	// share generated code at runtime.
+	uses := make(map[string][]protoUse)
+	ctx.VisitAllModuleProxies(func(module ModuleProxy) {
+		info, ok := OtherModuleProvider(ctx, module, ProtoSrcsInfoProvider)
+		if !ok {
+			return
+		}
+		commonInfo := OtherModulePointerProviderOrDefault(ctx, module, CommonModuleInfoProvider)
+		if !commonInfo.Enabled {
+			return
+		}
+		for _, src := range info.Srcs {
+			key := commonInfo.Target.Os.Class.String() + ":" + src.String()
+			uses[key] = append(uses[key], protoUse{
+				language: info.Language,
+				flavor:   info.Flavor,
+				module:   ctx.ModuleName(module),
+			})
+		}
+	})
+
+	enforce := ctx.Config().IsEnvTrue("SOONG_ENFORCE_PROTO_CONSISTENCY")
+
+	var report strings.Builder
+	for _, key := range SortedKeys(uses) {
+		if conflict := protoConflict(uses[key]); conflict != "" {
+			src := key[strings.Index(key, ":")+1:]
+			fmt.Fprintf(&report, "%s: %s\n", src, conflict)
+			if enforce {
+				ctx.Errorf("%s is compiled into incompatible proto flavors: %s", src, conflict)
+			}
+		}
+	}
+
+	reportFile := PathForOutput(ctx, "proto_consistency.txt")
+	WriteFileRuleVerbatim(ctx, reportFile, report.String())
+	ctx.Phony("proto_consistency", reportFile)
+}
+
+// protoConflict returns a description of the uses of a single .proto file if it is compiled as
+// both "lite" and "full" by different languages, or an empty string otherwise.
+func protoConflict(uses []protoUse) string {
+	flavorsByLanguage := make(map[string]map[string]bool)
+	for _, use := range uses {
+		if use.flavor != "lite" && use.flavor != "full" {
+			continue
+		}
+		if flavorsByLanguage[use.language] == nil {
+			flavorsByLanguage[use.language] = make(map[string]bool)
+		}
+		flavorsByLanguage[use.language][use.flavor] = true
+	}
+
+	conflict := false
+	for language, flavors := range flavorsByLanguage {
+		for otherLanguage, otherFlavors := range flavorsByLanguage {
+			if language == otherLanguage {
+				continue
+			}
+			for flavor := range flavors {
+				if !otherFlavors[flavor] {
+					conflict = true
+				}
+			}
+		}
+	}
+	if !conflict {
+		return ""
+	}
+
+	var descriptions []string
+	for _, use := range uses {
+		descriptions = append(descriptions, fmt.Sprintf("%s (%s %s)", use.module, use.language, use.flavor))
+	}
+	sort.Strings(descriptions)
+	return strings.Join(FirstUniqueStrings(descriptions), ", ")
+}
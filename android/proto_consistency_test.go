@@ -0,0 +1,105 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+type fakeProtoModule struct {
+	ModuleBase
+	props struct {
+		Language string
+		Type     string
+		Srcs     []string `android:"path"`
+	}
+}
+
+func fakeProtoModuleFactory() Module {
+	module := &fakeProtoModule{}
+	module.AddProperties(&module.props)
+	InitAndroidModule(module)
+	return module
+}
+
+func (f *fakeProtoModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	flags := ProtoFlags{OutTypeFlag: "--" + f.props.Language + "_out"}
+	if f.props.Type == "lite" {
+		flags.OutParams = []string{"lite"}
+	}
+	SetProtoSrcsInfo(ctx, f.props.Language, flags, PathsForModuleSrc(ctx, f.props.Srcs))
+}
+
+var prepareForProtoConsistencyTest = GroupFixturePreparers(
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.RegisterModuleType("fake_proto_module", fakeProtoModuleFactory)
+		RegisterProtoConsistencyBuildComponents(ctx)
+	}),
+	FixtureWithRootAndroidBp(`
+		fake_proto_module {
+			name: "libfoo-cc",
+			language: "cpp",
+			type: "full",
+			srcs: ["foo.proto", "bar.proto"],
+		}
+		fake_proto_module {
+			name: "libfoo-java",
+			language: "java",
+			type: "lite",
+			srcs: ["foo.proto"],
+		}
+		fake_proto_module {
+			name: "libbar-java",
+			language: "java",
+			type: "full",
+			srcs: ["bar.proto"],
+		}
+	`),
+	FixtureAddTextFile("foo.proto", ""),
+	FixtureAddTextFile("bar.proto", ""),
+)
+
+func TestProtoFlavor(t *testing.T) {
+	t.Parallel()
+	flags := func(outType string, params ...string) ProtoFlags {
+		return ProtoFlags{OutTypeFlag: outType, OutParams: params}
+	}
+	AssertStringEquals(t, "cpp full", "full", ProtoFlavor(flags("--cpp_out")))
+	AssertStringEquals(t, "cpp lite", "lite", ProtoFlavor(flags("--cpp_out", "lite")))
+	AssertStringEquals(t, "java lite", "lite", ProtoFlavor(flags("--java_out", "lite", "annotate_code")))
+	AssertStringEquals(t, "nanopb", "nanopb", ProtoFlavor(flags("--nanopb_out", "-T")))
+}
+
+func TestProtoConsistencyReport(t *testing.T) {
+	t.Parallel()
+	result := prepareForProtoConsistencyTest.RunTest(t)
+
+	report := ContentFromFileRuleForTests(t, result.TestContext,
+		result.SingletonForTests(t, "proto_consistency").Output("proto_consistency.txt"))
+	AssertStringEquals(t, "report",
+		"foo.proto: libfoo-cc (cpp full), libfoo-java (java lite)\n", report)
+}
+
+func TestProtoConsistencyEnforced(t *testing.T) {
+	t.Parallel()
+	GroupFixturePreparers(
+		prepareForProtoConsistencyTest,
+		FixtureMergeEnv(map[string]string{
+			"SOONG_ENFORCE_PROTO_CONSISTENCY": "true",
+		}),
+	).ExtendWithErrorHandler(FixtureExpectsOneErrorPattern(
+		`foo.proto is compiled into incompatible proto flavors: libfoo-cc \(cpp full\), libfoo-java \(java lite\)`)).
+		RunTest(t)
+}
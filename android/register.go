@@ -16,6 +16,7 @@ package android
 
 import (
 	"fmt"
+	"os"
 	"reflect"
 
 	"github.com/google/blueprint"
@@ -174,6 +175,11 @@ func (ctx *Context) Register() {
 	}
 
 	mutators := collateGloballyRegisteredMutators()
+	if dir := ctx.config.MemprofileMutatorsDir(); dir != "" {
+		// Remove the profiles of a previous run, the set of mutators may have changed since.
+		os.RemoveAll(dir)
+		mutators = addHeapProfileMutators(mutators, dir)
+	}
 	mutators.registerAll(ctx)
 
 	singletons := collateGloballyRegisteredSingletons()
@@ -1090,6 +1090,20 @@ func (m TestingModule) OutputFiles(ctx *TestContext, t *testing.T, tag string) P
 	return nil
 }
 
+// DistsForTests returns the files that the module dists with the DistForGoal family of methods as
+// "<goals> <path relative to top>:<dest>" strings, in the order in which they were added.
+func (m TestingModule) DistsForTests(ctx *TestContext) []string {
+	var ret []string
+	distInfo := OtherModuleProviderOrDefault(ctx.OtherModuleProviderAdaptor(), m.Module(), DistProvider)
+	for _, d := range distInfo.Dists {
+		for _, c := range d.paths {
+			ret = append(ret, fmt.Sprintf("%s %s:%s", strings.Join(d.goals, " "),
+				c.from.RelativeToTop().String(), c.dest))
+		}
+	}
+	return ret
+}
+
 // TestingSingleton is wrapper around an android.Singleton that provides methods to find information about individual
 // ctx.Build parameters for verification in tests.
 type TestingSingleton struct {
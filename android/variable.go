@@ -319,6 +319,14 @@ type ProductVariables struct {
 	BootJars     ConfiguredJarList `json:",omitempty"`
 	ApexBootJars ConfiguredJarList `json:",omitempty"`
 
+	// Jarjar rules files, relative to the top of the tree, that are applied to all the libraries
+	// on the bootclasspath in addition to their own jarjar_rules.
+	BootclasspathJarjarRules []string `json:",omitempty"`
+
+	// Java language features that must not be used by the libraries on the bootclasspath, see
+	// the banned_language_features property of java modules.
+	BootclasspathBannedLanguageFeatures []string `json:",omitempty"`
+
 	IntegerOverflowExcludePaths []string `json:",omitempty"`
 
 	EnableCFI       *bool    `json:",omitempty"`
@@ -353,6 +361,12 @@ type ProductVariables struct {
 	ClangTidy  *bool   `json:",omitempty"`
 	TidyChecks *string `json:",omitempty"`
 
+	JavacWerrorEnforcedModules []string `json:",omitempty"`
+	JavacWerrorExemptModules   []string `json:",omitempty"`
+
+	EnforceUnsafeEscapesAllowlist *bool    `json:",omitempty"`
+	UnsafeEscapesAllowlist        []string `json:",omitempty"`
+
 	JavaCoveragePaths        []string `json:",omitempty"`
 	JavaCoverageExcludePaths []string `json:",omitempty"`
 
@@ -415,6 +429,8 @@ type ProductVariables struct {
 
 	WithDexpreopt bool `json:",omitempty"`
 
+	RelaxUsesLibraryCheckModules []string `json:",omitempty"`
+
 	ManifestPackageNameOverrides   []string `json:",omitempty"`
 	CertificateOverrides           []string `json:",omitempty"`
 	PackageNameOverrides           []string `json:",omitempty"`
@@ -463,6 +479,8 @@ type ProductVariables struct {
 	BuildBrokenDontCheckSystemSdk       bool     `json:",omitempty"`
 	BuildBrokenDupSysprop               bool     `json:",omitempty"`
 
+	BuildBrokenEscapes []BuildBrokenEscape `json:",omitempty"`
+
 	BuildWarningBadOptionalUsesLibsAllowlist []string `json:",omitempty"`
 
 	BuildDebugfsRestrictionsEnabled bool `json:",omitempty"`
@@ -552,6 +570,26 @@ type ProductVariables struct {
 	StripByDefault *bool `json:",omitempty"`
 }
 
+// ProductVariableNames returns the names of the top-level product variables in soong.variables that
+// soong_build reads, i.e. the fields of ProductVariables. Other top-level variables are ignored when
+// soong.variables is loaded, so changing them doesn't need to rerun soong_build.
+func ProductVariableNames() []string {
+	t := reflect.TypeOf(ProductVariables{})
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
 type PartitionQualifiedVariablesType struct {
 	BuildingImage               bool   `json:",omitempty"`
 	PrebuiltImage               bool   `json:",omitempty"`
@@ -2275,6 +2275,10 @@ func (a *apexBundle) setOutputFiles(ctx android.ModuleContext) {
 	if a.outputApexFile != nil {
 		ctx.SetOutputFiles(android.Paths{a.outputApexFile}, imageApexSuffix)
 	}
+	// lint reports covering the contents of the apex
+	if len(a.lintReports) > 0 {
+		ctx.SetOutputFiles(a.lintReports, ".lint-reports")
+	}
 }
 
 // enforceAppUpdatability propagates updatable=true to apps of updatable apexes
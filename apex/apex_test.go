@@ -3173,6 +3173,61 @@ func TestApexMinSdkVersion_ErrorIfDepIsNewer_Java(t *testing.T) {
 	`)
 }
 
+func TestApexMinSdkVersion_JavaApexInherit(t *testing.T) {
+	t.Parallel()
+	ctx := testApex(t, `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			java_libs: ["libx"],
+			apps: ["AppFoo"],
+			min_sdk_version: "29",
+			updatable: false,
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+
+		android_app {
+			name: "AppFoo",
+			srcs: ["foo/bar/MyClass.java"],
+			sdk_version: "current",
+			min_sdk_version: "29",
+			system_modules: "none",
+			static_libs: ["bar"],
+			apex_available: [ "myapex" ],
+		}
+
+		java_library {
+			name: "libx",
+			srcs: ["a.java"],
+			sdk_version: "current",
+			min_sdk_version: "apex_inherit",
+			compile_dex: true,
+			apex_available: [ "myapex" ],
+		}
+
+		java_library {
+			name: "bar",
+			sdk_version: "current",
+			min_sdk_version: "apex_inherit",
+			srcs: ["a.java"],
+			apex_available: [ "myapex" ],
+		}
+	`)
+
+	// The apex variant is compiled for the min_sdk_version of the apex.
+	d8Flags := ctx.ModuleForTests(t, "libx", "android_common_apex29").Rule("d8").Args["d8Flags"]
+	android.AssertStringDoesContain(t, "libx apex variant d8 flags", d8Flags, "--min-api 29")
+
+	// The platform variant is compiled for the sdk_version.
+	d8Flags = ctx.ModuleForTests(t, "libx", "android_common").Rule("d8").Args["d8Flags"]
+	android.AssertStringDoesNotContain(t, "libx platform variant d8 flags", d8Flags, "--min-api 29")
+}
+
 func TestApexMinSdkVersion_OkayEvenWhenDepIsNewer_IfItSatisfiesApexMinSdkVersion(t *testing.T) {
 	t.Parallel()
 	ctx := testApex(t, `
@@ -7782,6 +7837,52 @@ func TestJavaSDKLibrary(t *testing.T) {
 	ensureMatches(t, contents, "<library\\n\\s+name=\\\"foo\\\"\\n\\s+file=\\\"/apex/myapex/javalib/foo.jar\\\"")
 }
 
+func TestJavaSDKLibraryXmlFilenameCollision(t *testing.T) {
+	t.Parallel()
+	testApexError(t, `the permissions xml file "foo.xml" of multiple java_sdk_library modules collides:`+
+		`(?s).*/apex/otherapex/etc/permissions/foo.xml.*/apex/myapex/etc/permissions/foo.xml`, `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			java_libs: ["foo"],
+			updatable: false,
+		}
+
+		apex {
+			name: "otherapex",
+			key: "myapex.key",
+			java_libs: ["bar"],
+			updatable: false,
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+
+		java_sdk_library {
+			name: "foo",
+			srcs: ["a.java"],
+			api_packages: ["foo"],
+			apex_available: [ "myapex" ],
+		}
+
+		java_sdk_library {
+			name: "bar",
+			srcs: ["a.java"],
+			api_packages: ["bar"],
+			apex_available: [ "otherapex" ],
+			xml_filename: "foo.xml",
+		}
+
+		prebuilt_apis {
+			name: "sdk",
+			api_dirs: ["100"],
+		}
+	`, withFiles(filesForSdkLibrary))
+}
+
 func TestJavaSDKLibraryOverrideApexes(t *testing.T) {
 	t.Parallel()
 	ctx := testApex(t, `
@@ -10325,6 +10426,43 @@ func TestApexLintBcpFragmentSdkLibDeps(t *testing.T) {
 		lintReportInputs, "foo.impl")
 }
 
+func TestApexLintReportOutputFiles(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForApexTest,
+	).RunTestWithBp(t, `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			java_libs: ["foo"],
+			updatable: false,
+		}
+		apex_key {
+			name: "myapex.key",
+		}
+		java_library {
+			name: "foo",
+			srcs: ["MyClass.java"],
+			apex_available: ["myapex"],
+			sdk_version: "current",
+			compile_dex: true,
+		}
+	`)
+
+	myapex := result.ModuleForTests(t, "myapex", "android_common_myapex")
+	android.AssertPathsRelativeToTopEquals(t, "myapex lint reports",
+		[]string{
+			"out/soong/.intermediates/myapex/android_common_myapex/lint-report-html.zip",
+			"out/soong/.intermediates/myapex/android_common_myapex/lint-report-text.zip",
+			"out/soong/.intermediates/myapex/android_common_myapex/lint-report-xml.zip",
+		},
+		myapex.OutputFiles(result.TestContext, t, ".lint-reports"))
+
+	lintReportInputs := strings.Join(myapex.Output("lint-report-xml.zip").Inputs.Strings(), " ")
+	android.AssertStringDoesContain(t, "myapex lint report expected to contain that of foo",
+		lintReportInputs, "foo/android_common_apex10000/lint/lint-report.xml")
+}
+
 // updatable apexes should propagate updatable=true to its apps
 func TestUpdatableApexEnforcesAppUpdatability(t *testing.T) {
 	t.Parallel()
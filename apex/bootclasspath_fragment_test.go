@@ -609,6 +609,67 @@ func TestBootclasspathFragmentInPrebuiltArtApex(t *testing.T) {
 	})
 }
 
+func TestPrebuiltBootclasspathFragmentApexBootJars(t *testing.T) {
+	t.Parallel()
+	bp := `
+		prebuilt_apex {
+			name: "com.android.foo",
+			apex_name: "com.android.foo",
+			src: "com.android.foo-arm.apex",
+			exported_bootclasspath_fragments: ["foo-bootclasspath-fragment"],
+		}
+
+		// The boot jar is extracted directly from the prebuilt apex, there is no java_import for it.
+		prebuilt_bootclasspath_fragment {
+			name: "foo-bootclasspath-fragment",
+			apex_boot_jars: ["framework-foo"],
+			apex_available: ["com.android.foo"],
+		}
+	`
+
+	fragment := java.ApexVariantReference{
+		Apex:   proptools.StringPtr("com.android.foo"),
+		Module: proptools.StringPtr("foo-bootclasspath-fragment"),
+	}
+
+	preparer := android.GroupFixturePreparers(
+		java.FixtureConfigureApexBootJars("com.android.foo:framework-foo"),
+		// Make sure that there is a platform library so that the monolithic hidden API files are
+		// generated.
+		java.FixtureConfigureBootJars("platform:foo"),
+		android.FixtureModifyMockFS(func(fs android.MockFS) {
+			fs["com.android.foo-arm.apex"] = nil
+			fs["platform/Android.bp"] = []byte(`
+		java_library {
+			name: "foo",
+			srcs: ["Test.java"],
+			compile_dex: true,
+		}
+		`)
+			fs["platform/Test.java"] = nil
+		}),
+	)
+	ctx := testDexpreoptWithApexes(t, bp, "", preparer, fragment)
+
+	ensureExactDeapexedContents(t, ctx, "com.android.foo", "android_common_prebuilt_com.android.foo", []string{
+		"etc/hiddenapi/annotation-flags.csv",
+		"etc/hiddenapi/index.csv",
+		"etc/hiddenapi/metadata.csv",
+		"javalib/framework-foo.jar",
+	})
+
+	deapexerDir := "out/soong/.intermediates/com.android.foo/android_common_prebuilt_com.android.foo/deapexer/"
+
+	platformBcp := ctx.ModuleForTests(t, "platform-bootclasspath", "android_common")
+	stubFlagsCmd := platformBcp.Output("out/soong/hiddenapi/hiddenapi-stub-flags.txt").RuleParams.Command
+	android.AssertStringDoesContain(t, "monolithic hidden API stub flags command", stubFlagsCmd, "--boot-dex="+deapexerDir+"javalib/framework-foo.jar")
+
+	info, _ := android.OtherModuleProvider(ctx, platformBcp.Module(), java.MonolithicHiddenAPIInfoProvider)
+	android.AssertPathsRelativeToTopEquals(t, "annotation flags", []string{deapexerDir + "etc/hiddenapi/annotation-flags.csv"}, info.AnnotationFlagsPaths)
+	android.AssertPathsRelativeToTopEquals(t, "metadata", []string{deapexerDir + "etc/hiddenapi/metadata.csv"}, info.MetadataPaths)
+	android.AssertPathsRelativeToTopEquals(t, "index", []string{deapexerDir + "etc/hiddenapi/index.csv"}, info.IndexPaths)
+}
+
 // checkCopiesToPredefinedLocationForArt checks that the supplied modules are copied to the
 // predefined locations of boot dex jars used as inputs for the ART boot image.
 func checkCopiesToPredefinedLocationForArt(t *testing.T, config android.Config, module android.TestingModule, modules ...string) {
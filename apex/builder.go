@@ -1177,6 +1177,12 @@ func (a *apexBundle) buildLintReports(ctx android.ModuleContext) {
 	}
 
 	a.lintReports = java.BuildModuleLintReportZips(ctx, depSets, validations)
+
+	// Dist the reports under names prefixed by the apex name, so that teams owning an apex get a
+	// report that covers only its contents rather than the whole tree.
+	for _, report := range a.lintReports {
+		ctx.DistForGoalWithFilename("lint-check", report, a.Name()+"-"+report.Base())
+	}
 }
 
 func (a *apexBundle) buildCannedFsConfig(ctx android.ModuleContext) android.Path {
@@ -530,3 +530,73 @@ func TestDexpreoptWithMainlinePrebuiltNoSource(t *testing.T) {
 		t.Errorf("Expected dexpreopt to use prebuilt apex")
 	}
 }
+
+func TestDexpreoptBootJarsProfileContributions(t *testing.T) {
+	t.Parallel()
+	bp := `
+		platform_bootclasspath {
+			name: "platform-bootclasspath",
+			fragments: [
+				{
+					apex: "com.android.art",
+					module: "art-bootclasspath-fragment",
+				},
+			],
+		}
+
+		java_library {
+			name: "core-oj",
+			srcs: ["core-oj.java"],
+			installable: true,
+			apex_available: [
+				"com.android.art",
+			],
+		}
+
+		bootclasspath_fragment {
+			name: "art-bootclasspath-fragment",
+			image_name: "art",
+			contents: ["core-oj"],
+			boot_image_profiles: ["art-extra-profile.txt"],
+			apex_available: [
+				"com.android.art",
+			],
+			hidden_api: {
+				split_packages: ["*"],
+			},
+		}
+
+		apex_key {
+			name: "com.android.art.key",
+			public_key: "com.android.art.avbpubkey",
+			private_key: "com.android.art.pem",
+		}
+
+		apex {
+			name: "com.android.art",
+			key: "com.android.art.key",
+			bootclasspath_fragments: ["art-bootclasspath-fragment"],
+			updatable: false,
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		java.PrepareForTestWithDexpreopt,
+		java.FixtureConfigureBootJars("com.android.art:core-oj"),
+		PrepareForTestWithApexBuildComponents,
+		prepareForTestWithArtApex,
+		android.FixtureAddFile("art-extra-profile.txt", nil),
+	).RunTestWithBp(t, bp)
+
+	// The contribution is merged, after the global profiles, into the fragment's own profile...
+	fragment := result.ModuleForTests(t, "art-bootclasspath-fragment", "android_common_com.android.art")
+	fragmentCmd := fragment.Rule("bootJarsProfile_art-bootclasspath-fragment").RuleParams.Command
+	android.AssertStringDoesContain(t, "fragment profile inputs", fragmentCmd,
+		"art/build/boot/boot-image-profile.txt art-extra-profile.txt >")
+
+	// ...and into the profile of the boot image built by dex_bootjars.
+	dexBootJars := result.ModuleForTests(t, "dex_bootjars", "android_common")
+	imageCmd := dexBootJars.Rule("bootJarsProfile_boot").RuleParams.Command
+	android.AssertStringDoesContain(t, "boot image profile inputs", imageCmd,
+		"art/build/boot/boot-image-profile.txt art-extra-profile.txt >")
+}
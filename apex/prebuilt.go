@@ -499,6 +499,11 @@ func (p *prebuiltCommon) getDeapexerPropertiesIfNeeded(ctx android.ModuleContext
 			requiredFiles := extract.RequiredFilesFromPrebuiltApex(ctx)
 			exportedFiles = append(exportedFiles, requiredFiles...)
 
+			if fragment, ok := child.(*java.PrebuiltBootclasspathFragmentModule); ok {
+				// The boot jars that are extracted directly from the apex do not have modules of their own.
+				commonModules = append(commonModules, fragment.ApexBootJars()...)
+			}
+
 			if extract.UseProfileGuidedDexpreopt() {
 				dexpreoptProfileGuidedModules = append(dexpreoptProfileGuidedModules, name)
 			}
@@ -621,6 +626,11 @@ func (p *prebuiltCommon) provideApexExportsInfo(ctx android.ModuleContext, di *a
 		ProfilePathOnHost:             di.PrebuiltExportPath(java.ProfileInstallPathInApex),
 		LibraryNameToDexJarPathOnHost: javaModuleToDexPath,
 	}
+	if path := di.PrebuiltExportPath(java.HiddenAPIAnnotationFlagsPathInApex); path != nil {
+		exports.HiddenAPIAnnotationFlagsPathOnHost = path
+		exports.HiddenAPIMetadataPathOnHost = di.PrebuiltExportPath(java.HiddenAPIMetadataPathInApex)
+		exports.HiddenAPIIndexPathOnHost = di.PrebuiltExportPath(java.HiddenAPIIndexPathInApex)
+	}
 	android.SetProvider(ctx, android.ApexExportsInfoProvider, exports)
 }
 
@@ -15,6 +15,7 @@
 package apex
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -456,6 +457,172 @@ func TestPrebuiltStandaloneSystemserverclasspathFragmentContents(t *testing.T) {
 	assertProfileGuidedPrebuilt(t, ctx, "myapex", "bar", true)
 }
 
+func TestSystemserverclasspathFragmentMissingStandaloneJarsPolicy(t *testing.T) {
+	t.Parallel()
+	bp := `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			systemserverclasspath_fragments: [
+				"mysystemserverclasspathfragment",
+			],
+			updatable: false,
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+
+		java_library {
+			name: "foo",
+			srcs: ["b.java"],
+			installable: true,
+			apex_available: [
+				"myapex",
+			],
+		}
+
+		systemserverclasspath_fragment {
+			name: "mysystemserverclasspathfragment",
+			standalone_contents: [
+				"foo",
+			],
+			missing_standalone_jars_policy: "%s",
+			apex_available: [
+				"myapex",
+			],
+		}
+
+		prebuilt_apex {
+			name: "myapex",
+			arch: {
+				arm64: {
+					src: "myapex-arm64.apex",
+				},
+				arm: {
+					src: "myapex-arm.apex",
+				},
+			},
+			exported_systemserverclasspath_fragments: ["mysystemserverclasspathfragment"],
+		}
+
+		java_import {
+			name: "foo",
+			jars: ["foo.jar"],
+			apex_available: [
+				"myapex",
+			],
+		}
+
+		java_import {
+			name: "bar",
+			jars: ["bar.jar"],
+			apex_available: [
+				"myapex",
+			],
+		}
+
+		prebuilt_systemserverclasspath_fragment {
+			name: "mysystemserverclasspathfragment",
+			standalone_contents: [
+				"foo",
+				"bar",
+			],
+			apex_available: [
+				"myapex",
+			],
+		}
+
+		apex_contributions {
+			name: "myapex.source.contributions",
+			api_domain: "myapex",
+			contents: ["myapex"],
+		}
+
+		apex_contributions {
+			name: "myapex.prebuilt.contributions",
+			api_domain: "myapex",
+			contents: ["prebuilt_myapex"],
+		}
+	`
+
+	testCases := []struct {
+		desc                      string
+		policy                    string
+		selectedApexContributions string
+		expectedJars              []string
+		expectedSubstitutedJars   []string
+		expectedError             string
+	}{
+		{
+			desc:                      "skip leaves the missing jar out of the classpaths config",
+			policy:                    "skip",
+			selectedApexContributions: "myapex.source.contributions",
+			expectedJars:              []string{"/apex/myapex/javalib/foo.jar"},
+		},
+		{
+			desc:                      "substitute leaves the missing jar undexpreopted",
+			policy:                    "substitute",
+			selectedApexContributions: "myapex.source.contributions",
+			expectedJars:              []string{"/apex/myapex/javalib/foo.jar"},
+			expectedSubstitutedJars:   []string{"bar"},
+		},
+		{
+			desc:                      "error reports the missing jar",
+			policy:                    "error",
+			selectedApexContributions: "myapex.source.contributions",
+			expectedError:             `standalone_contents: \["myapex:bar"\] in PRODUCT_APEX_STANDALONE_SYSTEM_SERVER_JARS are missing`,
+		},
+		{
+			desc:                      "error ignores the fragment of the apex that is not selected",
+			policy:                    "error",
+			selectedApexContributions: "myapex.prebuilt.contributions",
+			expectedJars:              []string{"/apex/myapex/javalib/foo.jar"},
+		},
+		{
+			desc:                      "unknown policy",
+			policy:                    "ignore",
+			selectedApexContributions: "myapex.source.contributions",
+			expectedError:             `missing_standalone_jars_policy: unknown policy "ignore"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			errorHandler := android.FixtureExpectsNoErrors
+			if tc.expectedError != "" {
+				errorHandler = android.FixtureExpectsAtLeastOneErrorMatchingPattern(tc.expectedError)
+			}
+			result := android.GroupFixturePreparers(
+				prepareForTestWithSystemserverclasspathFragment,
+				prepareForTestWithMyapex,
+				dexpreopt.FixtureSetApexStandaloneSystemServerJars("myapex:foo", "myapex:bar"),
+				android.PrepareForTestWithBuildFlag("RELEASE_APEX_CONTRIBUTIONS_ADSERVICES", tc.selectedApexContributions),
+			).ExtendWithErrorHandler(errorHandler).RunTestWithBp(t, fmt.Sprintf(bp, tc.policy))
+			if tc.expectedError != "" {
+				return
+			}
+
+			fragment := result.ModuleForTests(t, "mysystemserverclasspathfragment", "android_common_myapex")
+			textproto := android.ContentFromFileRuleForTests(t, result.TestContext,
+				fragment.Output("systemserverclasspath.pb.textproto"))
+			var jars []string
+			for _, line := range strings.Split(textproto, "\n") {
+				if path, ok := strings.CutPrefix(line, "path: "); ok {
+					jars = append(jars, strings.Trim(path, `"`))
+				}
+			}
+			android.AssertArrayString(t, "classpaths config jars", tc.expectedJars, jars)
+
+			info, _ := android.OtherModuleProvider(result, fragment.Module(), java.MissingStandaloneJarsInfoProvider)
+			android.AssertArrayString(t, "substituted jars", tc.expectedSubstitutedJars, info.Jars)
+		})
+	}
+}
+
 func assertProfileGuided(t *testing.T, ctx *android.TestContext, moduleName string, variant string, expected bool) {
 	dexpreopt := ctx.ModuleForTests(t, moduleName, variant).Rule("dexpreopt")
 	actual := strings.Contains(dexpreopt.RuleParams.Command, "--profile-file=")
@@ -588,3 +755,51 @@ func TestCheckSystemServerOrderWithArtApex(t *testing.T) {
 
 	_ = result
 }
+
+func TestSystemserverclasspathFragmentMaxDeviceSdkBelowApexMinSdk(t *testing.T) {
+	t.Parallel()
+	android.GroupFixturePreparers(
+		prepareForTestWithSystemserverclasspathFragment,
+		prepareForTestWithMyapex,
+		java.PrepareForTestWithJavaSdkLibraryFiles,
+		dexpreopt.FixtureSetApexSystemServerJars("myapex:foo"),
+	).ExtendWithErrorHandler(android.FixtureExpectsOneErrorPattern(
+		`module "mysystemserverclasspathfragment".*foo has max_device_sdk 33 which is lower than the min_sdk_version 34 of myapex`,
+	)).RunTestWithBp(t, `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			systemserverclasspath_fragments: [
+				"mysystemserverclasspathfragment",
+			],
+			min_sdk_version: "34",
+			updatable: false,
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+
+		java_sdk_library {
+			name: "foo",
+			srcs: ["a.java"],
+			apex_available: ["myapex"],
+			compile_dex: true,
+			unsafe_ignore_missing_latest_api: true,
+			min_sdk_version: "33",
+			max_device_sdk: "33",
+		}
+
+		systemserverclasspath_fragment {
+			name: "mysystemserverclasspathfragment",
+			contents: [
+				"foo",
+			],
+			apex_available: [
+				"myapex",
+			],
+		}
+	`)
+}
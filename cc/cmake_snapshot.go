@@ -25,6 +25,7 @@ import (
 	"text/template"
 
 	"android/soong/android"
+	"android/soong/cc/config"
 
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
@@ -44,12 +45,25 @@ var templateCmakeModuleCc *template.Template = parseTemplate(templateCmakeModule
 var templateCmakeModuleAidlRaw string
 var templateCmakeModuleAidl *template.Template = parseTemplate(templateCmakeModuleAidlRaw)
 
+//go:embed cmake_toolchain_android.txt
+var templateCmakeToolchainAndroidRaw string
+var templateCmakeToolchainAndroid *template.Template = parseTemplate(templateCmakeToolchainAndroidRaw)
+
 //go:embed cmake_ext_add_aidl_library.txt
 var cmakeExtAddAidlLibrary string
 
 //go:embed cmake_ext_append_flags.txt
 var cmakeExtAppendFlags string
 
+// The flags for the Android toolchain files are written by a rule so that the ninja variables that
+// hold the Soong toolchain flags are expanded.
+var cmakeToolchainFlags = pctx.AndroidStaticRule("cmakeToolchainFlags",
+	blueprint.RuleParams{
+		Command: `echo 'set(SOONG_CFLAGS "$cflags")' > $out && ` +
+			`echo 'set(SOONG_CPPFLAGS "$cppflags")' >> $out && ` +
+			`echo 'set(SOONG_LDFLAGS "$ldflags")' >> $out`,
+	}, "cflags", "cppflags", "ldflags")
+
 var defaultUnportableFlags []string = []string{
 	"-Wno-c99-designator",
 	"-Wno-class-memaccess",
@@ -124,6 +138,14 @@ type CmakeSnapshotProperties struct {
 
 	// Whether to include source code as part of the snapshot package.
 	Include_sources bool
+
+	// Whether to generate CMake toolchain files in the toolchains directory of the snapshot package
+	// to cross-compile the device modules with the Android NDK, one per device architecture.
+	Ndk_toolchain_files bool
+
+	// The API level to target in the Android NDK toolchain files. Defaults to the minimum API level
+	// supported by each architecture.
+	Ndk_toolchain_sdk_version *string
 }
 
 var cmakeSnapshotSourcesProvider = blueprint.NewProvider[android.Paths]()
@@ -449,6 +471,11 @@ func (m *CmakeSnapshot) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	})
 	android.WriteFileRule(ctx, mainCmakePath, mainContents)
 
+	// Generating toolchain files for cross-compilation with the Android NDK
+	if m.Properties.Ndk_toolchain_files {
+		makefilesList = append(makefilesList, m.generateNdkToolchainFiles(ctx, &templateBuffer)...)
+	}
+
 	// Generating CMake extensions
 	extPath := android.PathForModuleGen(ctx, "cmake", "AppendCxxFlagsIfSupported.cmake")
 	makefilesList = append(makefilesList, extPath)
@@ -505,6 +532,69 @@ func (m *CmakeSnapshot) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	ctx.SetOutputFiles(android.Paths{m.zipPath}, "")
 }
 
+// generateNdkToolchainFiles writes a toolchain file for every device architecture that sets up
+// CMake to cross-compile with the Android NDK, using the toolchain flags of the Soong config.
+func (m *CmakeSnapshot) generateNdkToolchainFiles(ctx android.ModuleContext,
+	templateBuffer *bytes.Buffer) android.Paths {
+	var files android.Paths
+	seen := make(map[android.ArchType]bool)
+	for _, target := range ctx.Config().Targets[android.Android] {
+		arch := target.Arch
+		if seen[arch.ArchType] || target.NativeBridge == android.NativeBridgeEnabled {
+			continue
+		}
+		seen[arch.ArchType] = true
+
+		apiLevel := MinApiForArch(ctx, arch.ArchType)
+		if v := proptools.String(m.Properties.Ndk_toolchain_sdk_version); v != "" {
+			requested, err := android.ApiLevelFromUser(ctx, v)
+			if err != nil {
+				ctx.PropertyErrorf("ndk_toolchain_sdk_version", "%s", err)
+				return nil
+			}
+			if apiLevel.LessThan(requested) {
+				apiLevel = requested
+			}
+		}
+
+		abi := arch.ArchType.Name
+		if len(arch.Abi) > 0 {
+			abi = arch.Abi[0]
+		}
+
+		toolchain := config.FindToolchain(android.Android, arch)
+		flagsPath := android.PathForModuleGen(ctx, "toolchains", "android-"+arch.ArchType.Name+"-flags.cmake")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        cmakeToolchainFlags,
+			Description: "cmake toolchain flags " + arch.ArchType.Name,
+			Output:      flagsPath,
+			Args: map[string]string{
+				"cflags":   toolchain.Cflags() + " " + toolchain.ToolchainCflags(),
+				"cppflags": toolchain.Cppflags(),
+				"ldflags":  toolchain.Lldflags() + " " + toolchain.ToolchainLdflags(),
+			},
+		})
+
+		toolchainPath := android.PathForModuleGen(ctx, "toolchains", "android-"+arch.ArchType.Name+".cmake")
+		android.WriteFileRule(ctx, toolchainPath, executeTemplate(templateCmakeToolchainAndroid, templateBuffer, struct {
+			Name     string
+			Arch     string
+			Abi      string
+			Triple   string
+			ApiLevel int
+		}{
+			ctx.ModuleName(),
+			arch.ArchType.Name,
+			abi,
+			toolchain.ClangTriple(),
+			apiLevel.FinalOrFutureInt(),
+		}))
+
+		files = append(files, toolchainPath, flagsPath)
+	}
+	return files
+}
+
 func (m *CmakeSnapshot) AndroidMkEntries() []android.AndroidMkEntries {
 	return []android.AndroidMkEntries{{
 		Class:      "DATA",
@@ -115,3 +115,39 @@ func TestCmakeSnapshotAsTestData(t *testing.T) {
 	wasGenerated(t, &snapshotModule, "CMakeLists.txt", "rawFileCopy")
 	wasGenerated(t, &snapshotModule, "foo.zip", "")
 }
+
+func TestCmakeSnapshotNdkToolchainFiles(t *testing.T) {
+	t.Parallel()
+	result := PrepareForIntegrationTestWithCc.RunTestWithBp(t, `
+		cc_cmake_snapshot {
+			name: "foo",
+			modules_system: [],
+			ndk_toolchain_files: true,
+			ndk_toolchain_sdk_version: "29",
+		}`)
+
+	if runtime.GOOS != "linux" {
+		t.Skip("CMake snapshots are only supported on Linux")
+	}
+
+	snapshotModule := result.ModuleForTests(t, "foo", "linux_glibc_x86_64")
+
+	arm64 := android.ContentFromFileRuleForTests(t, result.TestContext,
+		snapshotModule.Output("toolchains/android-arm64.cmake"))
+	android.AssertStringDoesContain(t, "arm64 abi", arm64, "set(CMAKE_ANDROID_ARCH_ABI arm64-v8a)")
+	android.AssertStringDoesContain(t, "arm64 target", arm64,
+		"set(CMAKE_CXX_COMPILER_TARGET aarch64-linux-android29)")
+	android.AssertStringDoesContain(t, "arm64 flags", arm64,
+		`include("${CMAKE_CURRENT_LIST_DIR}/android-arm64-flags.cmake")`)
+
+	arm := android.ContentFromFileRuleForTests(t, result.TestContext,
+		snapshotModule.Output("toolchains/android-arm.cmake"))
+	android.AssertStringDoesContain(t, "arm abi", arm, "set(CMAKE_ANDROID_ARCH_ABI armeabi-v7a)")
+
+	flags := snapshotModule.Output("toolchains/android-arm64-flags.cmake")
+	android.AssertStringDoesContain(t, "arm64 cflags", flags.Args["cflags"], "${config.Arm64Cflags}")
+
+	zip := snapshotModule.Output("foo.zip")
+	android.AssertStringListContains(t, "zipped makefiles",
+		append(zip.Inputs.Strings(), zip.Implicits.Strings()...), flags.Output.String())
+}
@@ -259,6 +259,7 @@ func genSources(
 	}
 
 	var generatedSources android.Paths = nil
+	var protoSrcs android.Paths
 
 	for i, srcFile := range srcFiles {
 		switch srcFile.Ext() {
@@ -284,6 +285,7 @@ func genSources(
 			generatedSources = append(generatedSources, cppFile)
 		case ".proto":
 			ccFile, headerFile := genProto(ctx, srcFile, buildFlags)
+			protoSrcs = append(protoSrcs, srcFile)
 			srcFiles[i] = ccFile
 			info.protoHeaders = append(info.protoHeaders, headerFile)
 			// Use the generated header as an order only dep to ensure that it is up to date when needed.
@@ -367,6 +369,10 @@ func genSources(
 		yaccRule_.Build("yacc", "gen yacc")
 	}
 
+	if len(protoSrcs) > 0 {
+		android.SetProtoSrcsInfo(ctx, "cc", buildFlags.proto, protoSrcs)
+	}
+
 	deps = append(deps, info.protoOrderOnlyDeps...)
 	deps = append(deps, info.aidlOrderOnlyDeps...)
 	deps = append(deps, info.syspropOrderOnlyDeps...)
@@ -77,7 +77,7 @@ func CreateState(inputs []string, inspect_contents bool, fsys StatReadFileFS) (*
 }
 
 // We ignore any suffix digit caused by sharding.
-var InspectExtsZipRegexp = regexp.MustCompile("\\.(jar|apex|apk)[0-9]*$")
+var InspectExtsZipRegexp = regexp.MustCompile("\\.(jar|apex|apk|srcjar)[0-9]*$")
 
 // Inspect the file and extract the state of the elements in the archive.
 // If this is not an archive of some sort, nil is returned.
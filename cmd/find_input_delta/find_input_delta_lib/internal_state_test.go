@@ -272,6 +272,9 @@ func TestCompareInspectExtsZipRegexp(t *testing.T) {
 		{Name: ".apexx", Expected: false},
 		{Name: ".apk", Expected: true},
 		{Name: ".apk3", Expected: true},
+		{Name: ".srcjar", Expected: true},
+		{Name: ".srcjar2", Expected: true},
+		{Name: ".srcjarx", Expected: false},
 		{Name: ".go", Expected: false},
 	}
 	for _, tc := range testCases {
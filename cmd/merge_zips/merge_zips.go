@@ -535,6 +535,19 @@ func (miz *ManagedInputZip) Entries() []*zip.File {
 	return miz.realInputZip.Entries()
 }
 
+// Size and number of entries above which a jar requires the zip64 extensions, lowered by tests.
+var maxJarSize int64 = jar.MaxSizeWithoutZip64
+var maxJarEntries = jar.MaxEntriesWithoutZip64
+
+// checkJarSize returns a jar.Zip64RequiredError if the jar written by the closed writer requires
+// the zip64 extensions.
+func checkJarSize(writer *zip.Writer) error {
+	if writer.NumEntries() > maxJarEntries || writer.Size() > maxJarSize {
+		return jar.Zip64RequiredError{Entries: writer.NumEntries(), Size: writer.Size()}
+	}
+	return nil
+}
+
 // Actual processing.
 func mergeZips(inputZips []InputZip, writer *zip.Writer, manifest, pyMain string,
 	sortEntries, emulateJar, emulatePar, stripDirEntries, ignoreDuplicates bool,
@@ -652,6 +665,7 @@ var (
 	pyMain           = flag.String("pm", "", "__main__.py file to insert in par")
 	prefix           = flag.String("prefix", "", "A file to prefix to the zip file")
 	ignoreDuplicates = flag.Bool("ignore-duplicates", false, "take each entry from the first zip it exists in and don't warn")
+	noZip64          = flag.Bool("no-zip64", false, "fail instead of writing -j outputs that require the zip64 extensions")
 )
 
 func init() {
@@ -760,6 +774,12 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
+		if *emulateJar && *noZip64 {
+			if err := checkJarSize(writer); err != nil {
+				os.Remove(outputPath)
+				log.Fatal(err)
+			}
+		}
 	}()
 	writer.SetOffset(offset)
 
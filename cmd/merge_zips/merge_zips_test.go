@@ -459,3 +459,52 @@ func TestInputZipsManager(t *testing.T) {
 		}
 	})
 }
+
+func TestCheckJarSize(t *testing.T) {
+	defer func(size int64, entries int) {
+		maxJarSize, maxJarEntries = size, entries
+	}(maxJarSize, maxJarEntries)
+	maxJarSize = 1024
+	maxJarEntries = 3
+
+	large := testZipEntry{"large", 0755, bytes.Repeat([]byte("a"), 2048), zip.Store, jar.DefaultTime}
+	testCases := []struct {
+		name string
+		in   []testZipEntry
+		err  bool
+	}{
+		{name: "small", in: []testZipEntry{A}},
+		{name: "large", in: []testZipEntry{A, large}, err: true},
+		{name: "many entries", in: []testZipEntry{A, ba, bc, bd}, err: true},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			writer := zip.NewWriter(&bytes.Buffer{})
+			err := mergeZips([]InputZip{&testInputZip{name: "in", entries: test.in}}, writer, "", "",
+				false, true, false, false, false, nil, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			err = checkJarSize(writer)
+			if !test.err {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			zip64Err, ok := err.(jar.Zip64RequiredError)
+			if !ok {
+				t.Fatalf("want Zip64RequiredError, got %v", err)
+			}
+			if zip64Err.Entries != writer.NumEntries() || zip64Err.Size != writer.Size() {
+				t.Errorf("want %d entries and %d bytes, got %d entries and %d bytes",
+					writer.NumEntries(), writer.Size(), zip64Err.Entries, zip64Err.Size)
+			}
+		})
+	}
+}
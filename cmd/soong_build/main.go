@@ -42,8 +42,11 @@ var (
 	availableEnvFile string
 	usedEnvFile      string
 
+	usedVariablesFile string
+
 	delveListen string
 	delvePath   string
+	delveWait   bool
 
 	cmdlineArgs android.CmdArgs
 )
@@ -62,6 +65,7 @@ func init() {
 	flag.StringVar(&cmdlineArgs.SoongOutDir, "soong_out", "", "Soong output directory (usually $TOP/out/soong)")
 	flag.StringVar(&availableEnvFile, "available_env", "", "File containing available environment variables")
 	flag.StringVar(&usedEnvFile, "used_env", "", "File containing used environment variables")
+	flag.StringVar(&usedVariablesFile, "used_variables", "", "File containing used product variables")
 	flag.StringVar(&cmdlineArgs.OutDir, "out", "", "the ninja builddir directory")
 	flag.StringVar(&cmdlineArgs.ModuleListFile, "l", "", "file that lists filepaths to parse")
 	flag.StringVar(&cmdlineArgs.KatiSuffix, "kati_suffix", "", "the suffix for kati and ninja files, so that different configurations don't clobber each other")
@@ -69,15 +73,22 @@ func init() {
 	// Debug flags
 	flag.StringVar(&delveListen, "delve_listen", "", "Delve port to listen on for debugging")
 	flag.StringVar(&delvePath, "delve_path", "", "Path to Delve. Only used if --delve_listen is set")
+	flag.BoolVar(&delveWait, "delve_wait", false, "Pause until a debugger connects to Delve. Only used if --delve_listen is set")
 	flag.StringVar(&cmdlineArgs.Cpuprofile, "cpuprofile", "", "write cpu profile to file")
 	flag.StringVar(&cmdlineArgs.TraceFile, "trace", "", "write trace to file")
 	flag.StringVar(&cmdlineArgs.Memprofile, "memprofile", "", "write memory profile to file")
+	flag.StringVar(&cmdlineArgs.MemprofileMutatorsDir, "memprofile_mutators_dir", "", "write a memory profile to this directory after every mutator")
 	flag.BoolVar(&cmdlineArgs.NoGC, "nogc", false, "turn off GC for debugging")
 
 	// Flags representing various modes soong_build can run in
 	flag.StringVar(&cmdlineArgs.ModuleGraphFile, "module_graph_file", "", "JSON module graph file to output")
 	flag.StringVar(&cmdlineArgs.ModuleActionsFile, "module_actions_file", "", "JSON file to output inputs/outputs of actions of modules")
 	flag.StringVar(&cmdlineArgs.DocFile, "soong_docs", "", "build documentation file to output")
+	flag.StringVar(&cmdlineArgs.DumpModule, "dump_module", "", "module whose resolved properties are written to --dump_module_file")
+	flag.StringVar(&cmdlineArgs.DumpModuleFile, "dump_module_file", "", "JSON file to write the resolved properties of --dump_module to")
+	flag.StringVar(&cmdlineArgs.ModuleDepsAllowlist, "module_deps_allowlist", "", "file listing the modules whose direct dependencies are written to --module_deps_file")
+	flag.StringVar(&cmdlineArgs.ModuleDepsFile, "module_deps_file", "", "JSON file to write the direct dependencies of the modules in --module_deps_allowlist to")
+	flag.StringVar(&cmdlineArgs.IdeMetadataFile, "ide_metadata_file", "", "JSON file to write the IDE metadata of all the modules to")
 	flag.StringVar(&cmdlineArgs.OutFile, "o", "build.ninja", "the Ninja file to output")
 	flag.StringVar(&cmdlineArgs.SoongVariables, "soong_variables", "soong.variables", "the file contains all build variables")
 	flag.BoolVar(&cmdlineArgs.EmptyNinjaFile, "empty-ninja-file", false, "write out a 0-byte ninja file")
@@ -270,9 +281,9 @@ func runSoongOnlyBuild(ctx *android.Context) (string, []string) {
 
 	var stopBefore bootstrap.StopBefore
 	switch ctx.Config().BuildMode {
-	case android.GenerateModuleGraph:
+	case android.GenerateModuleGraph, android.GenerateIdeMetadataFile:
 		stopBefore = bootstrap.StopBeforeWriteNinja
-	case android.GenerateDocFile:
+	case android.GenerateDocFile, android.GenerateModulePropertiesFile, android.GenerateModuleDepsFile:
 		stopBefore = bootstrap.StopBeforePrepareBuildActions
 	default:
 		stopBefore = bootstrap.DoEverything
@@ -293,6 +304,27 @@ func runSoongOnlyBuild(ctx *android.Context) (string, []string) {
 		err := writeDocs(ctx, shared.JoinPath(topDir, cmdlineArgs.DocFile))
 		maybeQuit(err, "error building Soong documentation")
 		return cmdlineArgs.DocFile, ninjaDeps
+	case android.GenerateModulePropertiesFile:
+		data, err := android.DumpModuleProperties(ctx, cmdlineArgs.DumpModule)
+		maybeQuit(err, "error dumping the properties of %s", cmdlineArgs.DumpModule)
+		err = os.WriteFile(shared.JoinPath(topDir, cmdlineArgs.DumpModuleFile), data, 0666)
+		maybeQuit(err, "error writing %s", cmdlineArgs.DumpModuleFile)
+		return cmdlineArgs.DumpModuleFile, ninjaDeps
+	case android.GenerateModuleDepsFile:
+		allowlist, err := android.ReadModuleDepsAllowlist(shared.JoinPath(topDir, cmdlineArgs.ModuleDepsAllowlist))
+		maybeQuit(err, "error reading %s", cmdlineArgs.ModuleDepsAllowlist)
+		data, err := android.DumpModuleDependencies(ctx, allowlist)
+		maybeQuit(err, "error dumping the dependencies of the modules in %s", cmdlineArgs.ModuleDepsAllowlist)
+		err = os.WriteFile(shared.JoinPath(topDir, cmdlineArgs.ModuleDepsFile), data, 0666)
+		maybeQuit(err, "error writing %s", cmdlineArgs.ModuleDepsFile)
+		// Rerun when the allowlist changes.
+		return cmdlineArgs.ModuleDepsFile, append(ninjaDeps, cmdlineArgs.ModuleDepsAllowlist)
+	case android.GenerateIdeMetadataFile:
+		data, err := android.DumpIdeMetadata(ctx)
+		maybeQuit(err, "error dumping the IDE metadata")
+		err = os.WriteFile(shared.JoinPath(topDir, cmdlineArgs.IdeMetadataFile), data, 0666)
+		maybeQuit(err, "error writing %s", cmdlineArgs.IdeMetadataFile)
+		return cmdlineArgs.IdeMetadataFile, ninjaDeps
 	default:
 		// The actual output (build.ninja) was written in the RunBlueprint() call
 		// above
@@ -316,6 +348,13 @@ func runSoongOnlyBuild(ctx *android.Context) (string, []string) {
 //
 // The dependency of build.ninja on soong.environment.used is declared in
 // build.ninja.d
+//
+// The product variables are handled the same way: soong.variables.used
+// contains the current value of all product variables that soong_build reads,
+// with the soong config variables tracked per namespace, and build.ninja
+// depends on it instead of on soong.variables so that changes to product
+// variables that soong_build ignores, or to soong config variables in
+// namespaces that were not used, do not cause a rebuild.
 func parseAvailableEnv() map[string]string {
 	if availableEnvFile == "" {
 		fmt.Fprintf(os.Stderr, "--available_env not set\n")
@@ -331,7 +370,7 @@ func main() {
 
 	soongStartTime := time.Now()
 
-	shared.ReexecWithDelveMaybe(delveListen, delvePath)
+	shared.ReexecWithDelveMaybe(delveListen, delvePath, delveWait)
 	android.InitSandbox(topDir)
 
 	availableEnv := parseAvailableEnv()
@@ -360,7 +399,11 @@ func main() {
 	ctx.Register()
 	finalOutputFile, ninjaDeps := runSoongOnlyBuild(ctx)
 
-	ninjaDeps = append(ninjaDeps, configuration.ProductVariablesFileName)
+	if usedVariablesFile != "" {
+		ninjaDeps = append(ninjaDeps, usedVariablesFile)
+	} else {
+		ninjaDeps = append(ninjaDeps, configuration.ProductVariablesFileName)
+	}
 	ninjaDeps = append(ninjaDeps, usedEnvFile)
 	if shared.IsDebugging() {
 		// Add a non-existent file to the dependencies so that soong_build will rerun when the debugger is
@@ -381,6 +424,7 @@ func main() {
 	writeMetrics(configuration, ctx.EventHandler, metricsDir)
 
 	writeUsedEnvironmentFile(configuration)
+	writeUsedProductVariablesFile(configuration)
 
 	err = writeGlobFile(ctx.EventHandler, finalOutputFile, ctx.Globs(), soongStartTime)
 	maybeQuit(err, "")
@@ -405,6 +449,21 @@ func writeUsedEnvironmentFile(configuration android.Config) {
 	maybeQuit(err, "error writing used environment file '%s'", usedEnvFile)
 }
 
+func writeUsedProductVariablesFile(configuration android.Config) {
+	if usedVariablesFile == "" {
+		return
+	}
+
+	path := shared.JoinPath(topDir, usedVariablesFile)
+	data, err := shared.ProductVariablesFileContents(
+		shared.JoinPath(topDir, configuration.ProductVariablesFileName), android.ProductVariableNames(),
+		configuration.VendorVarsDeps())
+	maybeQuit(err, "error writing used product variables file '%s'\n", usedVariablesFile)
+
+	err = pathtools.WriteFileIfChanged(path, data, 0666)
+	maybeQuit(err, "error writing used product variables file '%s'", usedVariablesFile)
+}
+
 func writeGlobFile(eventHandler *metrics.EventHandler, finalOutFile string, globs pathtools.MultipleGlobResults, soongStartTime time.Time) error {
 	eventHandler.Begin("writeGlobFile")
 	defer eventHandler.End("writeGlobFile")
@@ -16,6 +16,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"html/template"
 	"io/ioutil"
 	"path/filepath"
@@ -38,6 +39,23 @@ type moduleTypeTemplateData struct {
 	Properties []bpdoc.Property
 }
 
+// jsonModuleType and jsonProperty are the machine-readable form of the documentation, written to
+// soong_build.json for IDE plugins and documentation sites.
+type jsonModuleType struct {
+	Name       string         `json:"name"`
+	Package    string         `json:"package"`
+	Text       string         `json:"text,omitempty"`
+	Properties []jsonProperty `json:"properties"`
+}
+
+type jsonProperty struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Text       string         `json:"text,omitempty"`
+	Default    string         `json:"default,omitempty"`
+	Properties []jsonProperty `json:"properties,omitempty"`
+}
+
 // The properties in this map are displayed first, according to their rank.
 // TODO(jungjw): consider providing module type-dependent ranking
 var propertyRank = map[string]int{
@@ -96,6 +114,38 @@ func moduleTypeDocsToTemplates(moduleTypeList []*bpdoc.ModuleType) []moduleTypeT
 	return result
 }
 
+func propertiesToJson(props []bpdoc.Property) []jsonProperty {
+	result := make([]jsonProperty, 0, len(props))
+	for _, prop := range props {
+		result = append(result, jsonProperty{
+			Name:       prop.Name,
+			Type:       prop.Type,
+			Text:       string(prop.Text),
+			Default:    prop.Default,
+			Properties: propertiesToJson(prop.Properties),
+		})
+	}
+	return result
+}
+
+// moduleTypeDocsToJson returns the documentation of all the module types in the given packages,
+// sorted by module type name, in the form that is written to soong_build.json.
+func moduleTypeDocsToJson(packages []*bpdoc.Package) []jsonModuleType {
+	result := make([]jsonModuleType, 0)
+	for _, pkg := range packages {
+		for _, m := range moduleTypeDocsToTemplates(pkg.ModuleTypes) {
+			result = append(result, jsonModuleType{
+				Name:       m.Name,
+				Package:    pkg.Name,
+				Text:       string(m.Synopsis),
+				Properties: propertiesToJson(m.Properties),
+			})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
 func getPackages(ctx *android.Context) ([]*bpdoc.Package, error) {
 	moduleTypeFactories := android.ModuleTypeFactoriesForDocs()
 	return bootstrap.ModuleTypeDocs(ctx.Context, moduleTypeFactories)
@@ -155,8 +205,18 @@ func writeDocs(ctx *android.Context, filename string) error {
 	// building syntax highlighters.
 	keywordsFilename := filepath.Join(filepath.Dir(filename), "keywords.txt")
 	err = ioutil.WriteFile(keywordsFilename, keywordsBuf.Bytes(), 0666)
+	if err != nil {
+		return err
+	}
 
-	return err
+	// Write out a JSON index of all module types and their properties for tools that consume the
+	// documentation programmatically.
+	jsonBuf, err := json.MarshalIndent(moduleTypeDocsToJson(packages), "", "  ")
+	if err != nil {
+		return err
+	}
+	jsonFilename := filepath.Join(filepath.Dir(filename), "soong_build.json")
+	return ioutil.WriteFile(jsonFilename, jsonBuf, 0666)
 }
 
 // TODO(jungjw): Consider ordering by name.
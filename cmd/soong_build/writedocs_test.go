@@ -0,0 +1,147 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/google/blueprint/bootstrap/bpdoc"
+)
+
+func TestPropertiesToJson(t *testing.T) {
+	props := []bpdoc.Property{
+		{
+			Name: "srcs",
+			Type: "list of string",
+			Text: "list of source files",
+		},
+		{
+			Name: "static",
+			Type: "struct",
+			Properties: []bpdoc.Property{
+				{
+					Name:    "enabled",
+					Type:    "bool",
+					Default: "false",
+				},
+			},
+		},
+	}
+
+	want := []jsonProperty{
+		{
+			Name: "srcs",
+			Type: "list of string",
+			Text: "list of source files",
+		},
+		{
+			Name: "static",
+			Type: "struct",
+			Properties: []jsonProperty{
+				{
+					Name:    "enabled",
+					Type:    "bool",
+					Default: "false",
+				},
+			},
+		},
+	}
+
+	got := propertiesToJson(props)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("propertiesToJson() = %#v, want %#v", got, want)
+	}
+
+	if got := propertiesToJson(nil); got == nil || len(got) != 0 {
+		t.Errorf("propertiesToJson(nil) = %#v, want an empty list", got)
+	}
+}
+
+func TestModuleTypeDocsToJson(t *testing.T) {
+	packages := []*bpdoc.Package{
+		{
+			Name: "soong-java",
+			ModuleTypes: []*bpdoc.ModuleType{
+				{
+					Name: "java_library",
+					Text: "java_library builds a jar.",
+					PropertyStructs: []*bpdoc.PropertyStruct{
+						{
+							Properties: []bpdoc.Property{
+								{Name: "libs", Type: "list of string"},
+								{Name: "srcs", Type: "list of string"},
+							},
+						},
+						{
+							Properties: []bpdoc.Property{
+								{Name: "name", Type: "string"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "soong-cc",
+			ModuleTypes: []*bpdoc.ModuleType{
+				{
+					Name: "cc_binary",
+					PropertyStructs: []*bpdoc.PropertyStruct{
+						{
+							Properties: []bpdoc.Property{
+								{Name: "name", Type: "string"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	want := []jsonModuleType{
+		{
+			Name:    "cc_binary",
+			Package: "soong-cc",
+			Properties: []jsonProperty{
+				{Name: "name", Type: "string"},
+			},
+		},
+		{
+			Name:    "java_library",
+			Package: "soong-java",
+			Text:    "java_library builds a jar.",
+			Properties: []jsonProperty{
+				{Name: "name", Type: "string"},
+				{Name: "srcs", Type: "list of string"},
+				{Name: "libs", Type: "list of string"},
+			},
+		},
+	}
+
+	got := moduleTypeDocsToJson(packages)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("moduleTypeDocsToJson() = %#v, want %#v", got, want)
+	}
+
+	data, err := json.Marshal(got[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := string(data), `{"name":"cc_binary","package":"soong-cc","properties":[{"name":"name","type":"string"}]}`; g != w {
+		t.Errorf("json.Marshal() = %s, want %s", g, w)
+	}
+}
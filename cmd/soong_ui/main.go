@@ -126,7 +126,7 @@ func deleteStaleMetrics(metricsFilePathSlice []string) error {
 // Command is the type of soong_ui execution. Only one type of
 // execution is specified. The args are specific to the command.
 func main() {
-	shared.ReexecWithDelveMaybe(os.Getenv("SOONG_UI_DELVE"), shared.ResolveDelveBinary())
+	shared.ReexecWithDelveMaybe(os.Getenv("SOONG_UI_DELVE"), shared.ResolveDelveBinary(), true)
 
 	buildStarted := time.Now()
 
@@ -171,6 +171,8 @@ func main() {
 		stat.Finish()
 	})
 	criticalPath := status.NewCriticalPath()
+	actionCounter := build.NewActionCounter()
+	stat.AddOutput(actionCounter)
 	emet := execution_metrics.NewExecutionMetrics(log)
 	buildCtx := build.Context{ContextImpl: &build.ContextImpl{
 		Context:          ctx,
@@ -198,6 +200,7 @@ func main() {
 	soongBuildMetricsFile := filepath.Join(logsDir, c.logsPrefix+"soong_build_metrics.pb")
 	buildTraceFile := filepath.Join(logsDir, c.logsPrefix+"build.trace.gz")
 	executionMetricsFile := filepath.Join(logsDir, c.logsPrefix+"execution_metrics.pb")
+	buildSummaryFile := filepath.Join(logsDir, c.logsPrefix+"build_summary.json")
 
 	metricsFiles := []string{
 		buildErrorFile,        // build error strings
@@ -212,6 +215,7 @@ func main() {
 		stat.Finish()
 		criticalPath.WriteToMetrics(met)
 		met.Dump(soongMetricsFile)
+		build.WriteBuildSummary(buildCtx, buildSummaryFile, met, actionCounter, rbeMetricsFile)
 		emet.Dump(executionMetricsFile, args)
 		// If there are execution metrics, upload them.
 		if _, err := os.Stat(executionMetricsFile); err == nil {
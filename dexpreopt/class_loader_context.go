@@ -193,6 +193,9 @@ type ClassLoaderContext struct {
 	// The name of the library.
 	Name string
 
+	// Optional attributes of the library.
+	UsesLibraryAttributes
+
 	// If the library is optional or required.
 	Optional bool
 
@@ -206,6 +209,16 @@ type ClassLoaderContext struct {
 	Subcontexts []*ClassLoaderContext
 }
 
+// UsesLibraryAttributes contains the optional attributes of a <uses-library> that newer platforms
+// use to validate <uses-library android:required> against the library installed on the device.
+type UsesLibraryAttributes struct {
+	// The version of the library, 0 if unset.
+	Version int64
+
+	// The SHA-256 digest of the certificate that the library is signed with, empty if unset.
+	CertDigest string
+}
+
 // excludeLibs excludes the libraries from this ClassLoaderContext.
 //
 // This treats the supplied context as being immutable (as it may come from a dependency). So, it
@@ -286,7 +299,8 @@ const AnySdkVersion int = android.FutureApiLevelInt
 
 // Add class loader context for the given library to the map entry for the given SDK version.
 func (clcMap ClassLoaderContextMap) addContext(ctx android.ModuleInstallPathContext, sdkVer int, lib string,
-	optional bool, hostPath, installPath android.Path, nestedClcMap ClassLoaderContextMap) error {
+	attrs UsesLibraryAttributes, optional bool, hostPath, installPath android.Path,
+	nestedClcMap ClassLoaderContextMap) error {
 
 	// For prebuilts, library should have the same name as the source module.
 	lib = android.RemoveOptionalPrebuiltPrefix(lib)
@@ -328,6 +342,10 @@ func (clcMap ClassLoaderContextMap) addContext(ctx android.ModuleInstallPathCont
 		} else if clc.Host == hostPath && clc.Device == devicePath {
 			// Ok, the same library with the same paths. Don't re-add it, but don't raise an error
 			// either, as the same library may be reachable via different transitional dependencies.
+			if clc.UsesLibraryAttributes != attrs {
+				return fmt.Errorf("a <uses-library> named %q is already in class loader context,"+
+					"but the library attributes are different: %+v and %+v", lib, clc.UsesLibraryAttributes, attrs)
+			}
 			clc.Optional = clc.Optional && optional
 			return nil
 		} else {
@@ -339,11 +357,12 @@ func (clcMap ClassLoaderContextMap) addContext(ctx android.ModuleInstallPathCont
 	}
 
 	clcMap[sdkVer] = append(clcMap[sdkVer], &ClassLoaderContext{
-		Name:        lib,
-		Optional:    optional,
-		Host:        hostPath,
-		Device:      devicePath,
-		Subcontexts: subcontexts,
+		Name:                  lib,
+		UsesLibraryAttributes: attrs,
+		Optional:              optional,
+		Host:                  hostPath,
+		Device:                devicePath,
+		Subcontexts:           subcontexts,
 	})
 	return nil
 }
@@ -355,7 +374,17 @@ func (clcMap ClassLoaderContextMap) addContext(ctx android.ModuleInstallPathCont
 func (clcMap ClassLoaderContextMap) AddContext(ctx android.ModuleInstallPathContext, sdkVer int,
 	lib string, optional bool, hostPath, installPath android.Path, nestedClcMap ClassLoaderContextMap) {
 
-	err := clcMap.addContext(ctx, sdkVer, lib, optional, hostPath, installPath, nestedClcMap)
+	clcMap.AddContextWithAttributes(ctx, sdkVer, lib, UsesLibraryAttributes{}, optional, hostPath,
+		installPath, nestedClcMap)
+}
+
+// AddContextWithAttributes is like AddContext, but also sets the optional <uses-library> attributes
+// of the library.
+func (clcMap ClassLoaderContextMap) AddContextWithAttributes(ctx android.ModuleInstallPathContext,
+	sdkVer int, lib string, attrs UsesLibraryAttributes, optional bool, hostPath, installPath android.Path,
+	nestedClcMap ClassLoaderContextMap) {
+
+	err := clcMap.addContext(ctx, sdkVer, lib, attrs, optional, hostPath, installPath, nestedClcMap)
 	if err != nil {
 		ctx.ModuleErrorf(err.Error())
 	}
@@ -587,6 +616,8 @@ func ComputeClassLoaderContextDependenciesRec(clcs []*ClassLoaderContext) (names
 // the same as Soong representation except that SDK versions and paths are represented with strings.
 type jsonClassLoaderContext struct {
 	Name        string
+	Version     int64  `json:",omitempty"`
+	CertDigest  string `json:",omitempty"`
 	Optional    bool
 	Host        string
 	Device      string
@@ -618,7 +649,11 @@ func fromJsonClassLoaderContextRec(ctx android.PathContext, jClcs []*jsonClassLo
 	clcs := make([]*ClassLoaderContext, 0, len(jClcs))
 	for _, clc := range jClcs {
 		clcs = append(clcs, &ClassLoaderContext{
-			Name:        clc.Name,
+			Name: clc.Name,
+			UsesLibraryAttributes: UsesLibraryAttributes{
+				Version:    clc.Version,
+				CertDigest: clc.CertDigest,
+			},
 			Optional:    clc.Optional,
 			Host:        constructPath(ctx, clc.Host),
 			Device:      clc.Device,
@@ -654,6 +689,8 @@ func toJsonClassLoaderContextRec(clcs []*ClassLoaderContext) []*jsonClassLoaderC
 		}
 		jClcs[i] = &jsonClassLoaderContext{
 			Name:        clc.Name,
+			Version:     clc.Version,
+			CertDigest:  clc.CertDigest,
 			Optional:    clc.Optional,
 			Host:        host,
 			Device:      clc.Device,
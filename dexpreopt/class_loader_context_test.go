@@ -231,7 +231,7 @@ func TestCLCNestedConditional(t *testing.T) {
 	m1 := make(ClassLoaderContextMap)
 	m1.AddContext(ctx, 42, "a", optional, buildPath(ctx, "a"), installPath(ctx, "a"), nil)
 	m := make(ClassLoaderContextMap)
-	err := m.addContext(ctx, AnySdkVersion, "b", optional, buildPath(ctx, "b"), installPath(ctx, "b"), m1)
+	err := m.addContext(ctx, AnySdkVersion, "b", UsesLibraryAttributes{}, optional, buildPath(ctx, "b"), installPath(ctx, "b"), m1)
 	checkError(t, err, "nested class loader context shouldn't have conditional part")
 }
 
@@ -364,6 +364,39 @@ func TestCLCtoJSON(t *testing.T) {
 }`, m.Dump())
 }
 
+// Test that the optional <uses-library> attributes are serialized to JSON and restored.
+func TestCLCAttributes(t *testing.T) {
+	ctx := testContext()
+	optional := false
+	attrs := UsesLibraryAttributes{Version: 3, CertDigest: "0123abcd"}
+	m := make(ClassLoaderContextMap)
+	m.AddContextWithAttributes(ctx, AnySdkVersion, "a", attrs, optional, buildPath(ctx, "a"), installPath(ctx, "a"), nil)
+	android.AssertStringEquals(t, "output CLCM ", `{
+  "any": [
+    {
+      "Name": "a",
+      "Version": 3,
+      "CertDigest": "0123abcd",
+      "Optional": false,
+      "Host": "out/soong/a.jar",
+      "Device": "/system/a.jar",
+      "Subcontexts": []
+    }
+  ]
+}`, m.Dump())
+
+	restored := fromJsonClassLoaderContext(ctx, toJsonClassLoaderContext(m))
+	android.AssertDeepEquals(t, "restored attributes", attrs, restored[AnySdkVersion][0].UsesLibraryAttributes)
+
+	// Adding the same library again with the same attributes is fine, different attributes are an error.
+	err := m.addContext(ctx, AnySdkVersion, "a", attrs, optional, buildPath(ctx, "a"), installPath(ctx, "a"), nil)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err = m.addContext(ctx, AnySdkVersion, "a", UsesLibraryAttributes{Version: 4}, optional, buildPath(ctx, "a"), installPath(ctx, "a"), nil)
+	checkError(t, err, `a <uses-library> named "a" is already in class loader context,but the library attributes are different`)
+}
+
 func checkError(t *testing.T, have error, want string) {
 	if have == nil {
 		t.Errorf("\nwant error: '%s'\nhave: none", want)
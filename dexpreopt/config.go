@@ -64,6 +64,8 @@ type GlobalConfig struct {
 
 	GenerateDMFiles bool // generate Dex Metadata files
 
+	CompressedDex bool // install the apps that allow it compressed (.apk.gz) instead of dexpreopting them
+
 	NoDebugInfo                 bool // don't generate debug info by default
 	DontResolveStartupStrings   bool // don't resolve string literals loaded during application startup.
 	AlwaysSystemServerDebugInfo bool // always generate mini debug info for system server modules (overrides NoDebugInfo=true)
@@ -195,6 +197,15 @@ type ModuleConfig struct {
 	NoCreateAppImage    bool
 	ForceCreateAppImage bool
 
+	// VdexOnly forces the "verify" compiler filter and omits the .odex (and app image) from the
+	// installed files, so that only the verification artifacts (.vdex) end up on the device.
+	VdexOnly bool
+
+	// CompressedDex allows the app to be installed gzip-compressed at the dex location with a ".gz"
+	// suffix instead of being dexpreopted, if the product enables compressed dex. The app is
+	// extracted and compiled on the device, which trades first boot time for storage.
+	CompressedDex bool
+
 	PresignedPrebuilt bool
 
 	// ApexPartition is the partition in which the dexpreopt files of apex system server jars (if any) are installed.
@@ -760,6 +771,7 @@ func GlobalConfigForTests(ctx android.PathContext) *GlobalConfig {
 		DefaultCompilerFilter:          "",
 		SystemServerCompilerFilter:     "",
 		GenerateDMFiles:                false,
+		CompressedDex:                  false,
 		NoDebugInfo:                    false,
 		DontResolveStartupStrings:      false,
 		AlwaysSystemServerDebugInfo:    false,
@@ -82,14 +82,16 @@ func GenerateDexpreoptRule(ctx android.BuilderContext, globalSoong *GlobalSoongC
 		bootProfileCommand(ctx, globalSoong, global, module, rule)
 	}
 
-	if !dexpreoptDisabled(ctx, global, module) {
+	// A compressed app is installed by its module and compiled on the device, so there is nothing
+	// to dexpreopt.
+	if !dexpreoptDisabled(ctx, global, module) && !CompressDex(ctx, global, module) {
 		if valid, err := validateClassLoaderContext(module.ClassLoaderContexts); err != nil {
 			android.ReportPathErrorf(ctx, "%s", err.Error())
 		} else if valid {
 			fixClassLoaderContext(module.ClassLoaderContexts)
 
 			appImage := (generateProfile || module.ForceCreateAppImage || global.DefaultAppImages) &&
-				!module.NoCreateAppImage
+				!module.NoCreateAppImage && !module.VdexOnly
 
 			generateDM := shouldGenerateDM(module, global)
 
@@ -132,6 +134,14 @@ func dexpreoptDisabled(ctx android.PathContext, global *GlobalConfig, module *Mo
 	return false
 }
 
+// CompressDex returns whether the module is installed compressed instead of being dexpreopted.
+// System server jars are always dexpreopted, as they are needed before the compressed apps can be
+// extracted.
+func CompressDex(ctx android.PathContext, global *GlobalConfig, module *ModuleConfig) bool {
+	return global.CompressedDex && module.CompressedDex && !dexpreoptDisabled(ctx, global, module) &&
+		!global.AllSystemServerJars(ctx).ContainsJar(module.Name)
+}
+
 func profileCommand(ctx android.PathContext, globalSoong *GlobalSoongConfig, global *GlobalConfig,
 	module *ModuleConfig, rule *android.RuleBuilder) android.WritablePath {
 
@@ -398,7 +408,11 @@ func dexpreoptCommand(ctx android.BuilderContext, globalSoong *GlobalSoongConfig
 		cmd.FlagWithArg("--copy-dex-files=", "false")
 	}
 
-	if !android.PrefixInList(preoptFlags, "--compiler-filter=") {
+	if module.VdexOnly {
+		// Only the vdex file is installed, so there is no point in compiling any code. This
+		// overrides both the global and the module-specific compiler filter.
+		cmd.FlagWithArg("--compiler-filter=", "verify")
+	} else if !android.PrefixInList(preoptFlags, "--compiler-filter=") {
 		var compilerFilter string
 		if systemServerJars.ContainsJar(module.Name) {
 			if global.SystemServerCompilerFilter != "" {
@@ -497,7 +511,9 @@ func dexpreoptCommand(ctx android.BuilderContext, globalSoong *GlobalSoongConfig
 		cmd.FlagWithInput("--profile-file=", profile)
 	}
 
-	rule.Install(odexPath, odexInstallPath)
+	if !module.VdexOnly {
+		rule.Install(odexPath, odexInstallPath)
+	}
 	rule.Install(vdexPath, vdexInstallPath)
 }
 
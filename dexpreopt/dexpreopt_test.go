@@ -348,6 +348,129 @@ func TestDexPreoptProfile(t *testing.T) {
 	}
 }
 
+func TestDexPreoptVdexOnly(t *testing.T) {
+	config := android.TestConfig("out", nil, "", nil)
+	ctx := android.BuilderContextForTesting(config)
+	globalSoong := globalSoongConfigForTests(ctx)
+	global := GlobalConfigForTests(ctx)
+	global.DefaultCompilerFilter = "speed"
+	module := testSystemModuleConfig(ctx, "test")
+	module.VdexOnly = true
+	productPackages := android.PathForTesting("product_packages.txt")
+
+	rule, err := GenerateDexpreoptRule(ctx, globalSoong, global, module, productPackages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantInstalls := android.RuleBuilderInstalls{
+		{android.PathForOutput(ctx, "test/oat/arm/package.vdex"), "/system/app/test/oat/arm/test.vdex"},
+	}
+
+	android.AssertStringEquals(t, "installs", wantInstalls.String(), rule.Installs().String())
+
+	cmd := rule.Commands()[len(rule.Commands())-1]
+	android.AssertStringDoesContain(t, "compiler filter", cmd, "--compiler-filter=verify")
+	android.AssertStringDoesNotContain(t, "compiler filter", cmd, "--compiler-filter=speed")
+}
+
+func TestDexPreoptVdexOnlyProfile(t *testing.T) {
+	config := android.TestConfig("out", nil, "", nil)
+	ctx := android.BuilderContextForTesting(config)
+	globalSoong := globalSoongConfigForTests(ctx)
+	global := GlobalConfigForTests(ctx)
+	module := testSystemModuleConfig(ctx, "test")
+	module.VdexOnly = true
+	module.ProfileClassListing = android.OptionalPathForPath(android.PathForTesting("profile"))
+	productPackages := android.PathForTesting("product_packages.txt")
+
+	rule, err := GenerateDexpreoptRule(ctx, globalSoong, global, module, productPackages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither the app image nor the odex file are installed, only the profile and the vdex file.
+	wantInstalls := android.RuleBuilderInstalls{
+		{android.PathForOutput(ctx, "test/profile.prof"), "/system/app/test/test.apk.prof"},
+		{android.PathForOutput(ctx, "test/oat/arm/package.vdex"), "/system/app/test/oat/arm/test.vdex"},
+	}
+
+	android.AssertStringEquals(t, "installs", wantInstalls.String(), rule.Installs().String())
+}
+
+func TestDexPreoptCompressedDex(t *testing.T) {
+	config := android.TestConfig("out", nil, "", nil)
+	ctx := android.BuilderContextForTesting(config)
+	globalSoong := globalSoongConfigForTests(ctx)
+	global := GlobalConfigForTests(ctx)
+	global.CompressedDex = true
+	module := testSystemModuleConfig(ctx, "test")
+	module.CompressedDex = true
+	productPackages := android.PathForTesting("product_packages.txt")
+
+	rule, err := GenerateDexpreoptRule(ctx, globalSoong, global, module, productPackages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The compressed app is installed by the module, without any dexpreopt files.
+	android.AssertStringEquals(t, "installs", "", rule.Installs().String())
+	android.AssertBoolEquals(t, "compress dex", true, CompressDex(ctx, global, module))
+	for _, cmd := range rule.Commands() {
+		android.AssertStringDoesNotContain(t, "command", cmd, "dex2oat")
+	}
+}
+
+func TestDexPreoptCompressedDexNotEnabled(t *testing.T) {
+	config := android.TestConfig("out", nil, "", nil)
+	ctx := android.BuilderContextForTesting(config)
+	globalSoong := globalSoongConfigForTests(ctx)
+	global := GlobalConfigForTests(ctx)
+	module := testSystemModuleConfig(ctx, "test")
+	module.CompressedDex = true
+	productPackages := android.PathForTesting("product_packages.txt")
+
+	rule, err := GenerateDexpreoptRule(ctx, globalSoong, global, module, productPackages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The module is dexpreopted as usual if the product does not enable compressed dex.
+	wantInstalls := android.RuleBuilderInstalls{
+		{android.PathForOutput(ctx, "test/oat/arm/package.odex"), "/system/app/test/oat/arm/test.odex"},
+		{android.PathForOutput(ctx, "test/oat/arm/package.vdex"), "/system/app/test/oat/arm/test.vdex"},
+	}
+
+	android.AssertStringEquals(t, "installs", wantInstalls.String(), rule.Installs().String())
+	android.AssertBoolEquals(t, "compress dex", false, CompressDex(ctx, global, module))
+}
+
+func TestDexPreoptCompressedDexSystemServerJars(t *testing.T) {
+	config := android.TestConfig("out", nil, "", nil)
+	ctx := android.BuilderContextForTesting(config)
+	globalSoong := globalSoongConfigForTests(ctx)
+	global := GlobalConfigForTests(ctx)
+	global.CompressedDex = true
+	global.StandaloneSystemServerJars = android.CreateTestConfiguredJarList(
+		[]string{"platform:service-A"})
+	module := testPlatformSystemServerModuleConfig(ctx, "service-A")
+	module.CompressedDex = true
+	productPackages := android.PathForTesting("product_packages.txt")
+
+	rule, err := GenerateDexpreoptRule(ctx, globalSoong, global, module, productPackages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// System server jars are always dexpreopted.
+	wantInstalls := android.RuleBuilderInstalls{
+		{android.PathForOutput(ctx, "service-A/dexpreopt/oat/arm/javalib.odex"), "/system/framework/oat/arm/service-A.odex"},
+		{android.PathForOutput(ctx, "service-A/dexpreopt/oat/arm/javalib.vdex"), "/system/framework/oat/arm/service-A.vdex"},
+	}
+
+	android.AssertStringEquals(t, "installs", wantInstalls.String(), rule.Installs().String())
+}
+
 func TestDexPreoptConfigToJson(t *testing.T) {
 	config := android.TestConfig("out", nil, "", nil)
 	ctx := android.BuilderContextForTesting(config)
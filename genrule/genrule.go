@@ -139,6 +139,10 @@ type generatorProperties struct {
 	// Local files that are used by the tool
 	Tool_files []string `android:"path"`
 
+	// Minimum versions of modules in tools that declare the version of their tool. The build fails
+	// at analysis time if a tool is older than the required version.
+	Required_host_tools []android.RequiredHostTool
+
 	// List of directories to export generated headers from
 	Export_include_dirs []string
 
@@ -192,6 +196,11 @@ type Module struct {
 	// prefix environment variables to it.
 	CmdModifier func(ctx android.ModuleContext, cmd string) string
 
+	// OutputValidator can be set by wrappers around genrule to validate the outputs, for example
+	// to check that they are well-formed. It returns the validation actions, which are run whenever
+	// the outputs are used.
+	OutputValidator func(ctx android.ModuleContext, outputs android.WritablePaths) android.Paths
+
 	android.ImageInterface
 
 	properties generatorProperties
@@ -342,6 +351,7 @@ func (g *Module) generateCommonBuildActions(ctx android.ModuleContext) {
 	var packagedTools []android.PackagingSpec
 	if len(g.properties.Tools) > 0 {
 		seenTools := make(map[string]bool)
+		toolVersions := make(map[string]*android.HostToolVersionInfo)
 		ctx.VisitDirectDepsProxyAllowDisabled(func(proxy android.ModuleProxy) {
 			switch tag := ctx.OtherModuleDependencyTag(proxy).(type) {
 			case hostToolDependencyTag:
@@ -394,10 +404,17 @@ func (g *Module) generateCommonBuildActions(ctx android.ModuleContext) {
 					return
 				}
 
+				if v, ok := android.OtherModuleProvider(ctx, module, android.HostToolVersionInfoProvider); ok {
+					toolVersions[tag.label] = &v
+				} else {
+					toolVersions[tag.label] = nil
+				}
 				seenTools[tag.label] = true
 			}
 		})
 
+		android.CheckRequiredHostTools(ctx, g.properties.Required_host_tools, toolVersions)
+
 		// If AllowMissingDependencies is enabled, the build will not have stopped when
 		// AddFarVariationDependencies was called on a missing tool, which will result in nonsensical
 		// "cmd: unknown location label ..." errors later.  Add a placeholder file to the local label.
@@ -412,6 +429,10 @@ func (g *Module) generateCommonBuildActions(ctx android.ModuleContext) {
 		}
 	}
 
+	if len(g.properties.Tools) == 0 && len(g.properties.Required_host_tools) > 0 {
+		ctx.PropertyErrorf("required_host_tools", "requires tools to be set")
+	}
+
 	if ctx.Failed() {
 		return
 	}
@@ -616,6 +637,10 @@ func (g *Module) generateCommonBuildActions(ctx android.ModuleContext) {
 			}
 		}
 
+		if g.OutputValidator != nil {
+			cmd.Validations(g.OutputValidator(ctx, task.out))
+		}
+
 		// Create the rule to run the genrule command inside sbox.
 		rule.Build(name, desc)
 
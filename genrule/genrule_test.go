@@ -1104,6 +1104,68 @@ func TestPrebuiltTool(t *testing.T) {
 	}
 }
 
+func TestGenruleRequiredHostTools(t *testing.T) {
+	testcases := []struct {
+		name          string
+		tool          string
+		required      string
+		expectedError string
+	}{
+		{
+			name:     "same version",
+			tool:     `tool { name: "tool", version: "1.2" }`,
+			required: `{ name: "tool", min_version: "1.2.0" }`,
+		},
+		{
+			name:     "newer version",
+			tool:     `tool { name: "tool", version: "1.10" }`,
+			required: `{ name: "tool", min_version: "1.9" }`,
+		},
+		{
+			name:          "older version",
+			tool:          `tool { name: "tool", version: "1.2" }`,
+			required:      `{ name: "tool", min_version: "1.2.1" }`,
+			expectedError: `host tool "tool" has version 1.2, but version 1.2.1 or newer is required`,
+		},
+		{
+			name:          "no version",
+			tool:          `tool { name: "tool" }`,
+			required:      `{ name: "tool", min_version: "1" }`,
+			expectedError: `host tool "tool" does not declare a version, but version 1 is required`,
+		},
+		{
+			name:          "not a tool",
+			tool:          `tool { name: "tool", version: "1" }`,
+			required:      `{ name: "other", min_version: "1" }`,
+			expectedError: `"other" is not a host tool dependency of this module`,
+		},
+		{
+			name:          "invalid version",
+			tool:          `tool { name: "tool", version: "1" }`,
+			required:      `{ name: "tool", min_version: "1.x" }`,
+			expectedError: `tool: "1.x" is not a valid host tool version`,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			fixtures := prepareForGenRuleTest
+			if test.expectedError != "" {
+				fixtures = fixtures.ExtendWithErrorHandler(android.FixtureExpectsOneErrorPattern(regexp.QuoteMeta(test.expectedError)))
+			}
+			fixtures.RunTestWithBp(t, test.tool+`
+				genrule {
+					name: "gen",
+					tools: ["tool"],
+					required_host_tools: [`+test.required+`],
+					out: ["foo"],
+					cmd: "$(location tool) > $(out)",
+				}
+			`)
+		})
+	}
+}
+
 func TestGenruleWithGlobPaths(t *testing.T) {
 	testcases := []struct {
 		name            string
@@ -1255,16 +1317,26 @@ genrule {
 type testTool struct {
 	android.ModuleBase
 	outputFile android.Path
+
+	properties struct {
+		Version *string
+	}
 }
 
 func toolFactory() android.Module {
 	module := &testTool{}
+	module.AddProperties(&module.properties)
 	android.InitAndroidArchModule(module, android.HostSupported, android.MultilibFirst)
 	return module
 }
 
 func (t *testTool) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	t.outputFile = ctx.InstallFile(android.PathForModuleInstall(ctx, "bin"), ctx.ModuleName(), android.PathForOutput(ctx, ctx.ModuleName()))
+	if t.properties.Version != nil {
+		android.SetProvider(ctx, android.HostToolVersionInfoProvider, android.HostToolVersionInfo{
+			Version: *t.properties.Version,
+		})
+	}
 }
 
 func (t *testTool) HostToolPath() android.OptionalPath {
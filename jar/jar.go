@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"strings"
 	"text/scanner"
@@ -37,6 +38,26 @@ var DefaultTime = time.Date(2008, 1, 1, 0, 0, 0, 0, time.UTC)
 
 var MetaDirExtra = [2]byte{0xca, 0xfe}
 
+// MaxSizeWithoutZip64 is the size in bytes above which a jar requires the zip64 extensions.
+const MaxSizeWithoutZip64 = math.MaxUint32
+
+// MaxEntriesWithoutZip64 is the number of entries above which a jar requires the zip64 extensions.
+const MaxEntriesWithoutZip64 = math.MaxUint16
+
+// Zip64RequiredError is returned by soong_zip and merge_zips when -no-zip64 was passed and a jar
+// has more than MaxEntriesWithoutZip64 entries or is larger than MaxSizeWithoutZip64, which
+// requires the zip64 extensions that some jar consumers do not support.
+type Zip64RequiredError struct {
+	Entries int
+	Size    int64
+}
+
+func (x Zip64RequiredError) Error() string {
+	return fmt.Sprintf("jar with %d entries is %d bytes, which requires the zip64 extensions "+
+		"that some jar consumers do not support; remove -no-zip64 (unset DISALLOW_ZIP64_JARS for "+
+		"java modules) if this is expected", x.Entries, x.Size)
+}
+
 // EntryNamesLess tells whether <filepathA> should precede <filepathB> in
 // the order of files with a .jar
 func EntryNamesLess(filepathA string, filepathB string) (less bool) {
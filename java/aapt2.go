@@ -338,6 +338,36 @@ var aapt2ConvertRule = pctx.AndroidStaticRule("aapt2Convert",
 	}, "format",
 )
 
+var aapt2OptimizeRule = pctx.AndroidStaticRule("aapt2Optimize",
+	blueprint.RuleParams{
+		Command:     `${config.Aapt2Cmd} optimize $flags $in -o $out`,
+		CommandDeps: []string{"${config.Aapt2Cmd}"},
+	}, "flags",
+)
+
+// aapt2Optimize runs aapt2 optimize on the resources in the given apk with the given flags. If
+// pathMap is not nil, the resource paths are shortened and the mapping from the shortened paths to
+// the original ones is written to it.
+func aapt2Optimize(ctx android.ModuleContext, out android.WritablePath, in android.Path, flags []string,
+	pathMap android.WritablePath) {
+
+	var implicitOutputs android.WritablePaths
+	if pathMap != nil {
+		flags = append(flags, "--shorten-resource-paths", "--resource-path-shortening-map", pathMap.String())
+		implicitOutputs = append(implicitOutputs, pathMap)
+	}
+	ctx.Build(pctx, android.BuildParams{
+		Rule:            aapt2OptimizeRule,
+		Description:     "aapt2 optimize",
+		Input:           in,
+		Output:          out,
+		ImplicitOutputs: implicitOutputs,
+		Args: map[string]string{
+			"flags": strings.Join(flags, " "),
+		},
+	})
+}
+
 // Converts xml files and resource tables (resources.arsc) in the given jar/apk file to a proto
 // format. The proto definition is available at frameworks/base/tools/aapt2/Resources.proto.
 func aapt2Convert(ctx android.ModuleContext, out android.WritablePath, in android.Path, format string) {
@@ -351,3 +381,54 @@ func aapt2Convert(ctx android.ModuleContext, out android.WritablePath, in androi
 		},
 	})
 }
+
+// The manifest of an ABI split APK. The package and version code are filled in from the base APK
+// when the split is linked, as they are only known after the manifest of the app is merged.
+const abiSplitManifestTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<manifest xmlns:android="http://schemas.android.com/apk/res/android"
+    package="@PACKAGE@" android:versionCode="@VERSION_CODE@" split="%s">
+    <application android:hasCode="false" />
+</manifest>
+`
+
+var aapt2LinkAbiSplitRule = pctx.AndroidStaticRule("aapt2LinkAbiSplit",
+	blueprint.RuleParams{
+		Command: `badging=$$(${config.Aapt2Cmd} dump badging $baseApk | head -n 1) && ` +
+			`package=$$(echo "$$badging" | sed -n "s/^package: name='\([^']*\)'.*/\1/p") && ` +
+			`version=$$(echo "$$badging" | sed -n "s/.* versionCode='\([^']*\)'.*/\1/p") && ` +
+			`sed -e "s/@PACKAGE@/$$package/" -e "s/@VERSION_CODE@/$$version/" $in > $out.AndroidManifest.xml && ` +
+			`${config.Aapt2Cmd} link -o $out $flags --manifest $out.AndroidManifest.xml`,
+		CommandDeps: []string{"${config.Aapt2Cmd}"},
+	}, "baseApk", "flags",
+)
+
+// abiSplitName returns the name of the split APK that contains the native libraries for abi.
+func abiSplitName(abi string) string {
+	return "config." + strings.ReplaceAll(abi, "-", "_")
+}
+
+// aapt2LinkAbiSplit links the resources of the split APK that contains the native libraries for
+// abi, with the package name and version code of baseApk. sharedLibs are the resource packages
+// the base APK was linked against.
+func aapt2LinkAbiSplit(ctx android.ModuleContext, out android.WritablePath, baseApk android.Path,
+	abi string, sharedLibs android.Paths) {
+
+	manifestTemplate := android.PathForModuleOut(ctx, "abi_splits", abi, "AndroidManifest.xml.in")
+	android.WriteFileRuleVerbatim(ctx, manifestTemplate, fmt.Sprintf(abiSplitManifestTemplate, abiSplitName(abi)))
+
+	var flags []string
+	for _, sharedLib := range sharedLibs {
+		flags = append(flags, "-I "+sharedLib.String())
+	}
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        aapt2LinkAbiSplitRule,
+		Description: "link " + abiSplitName(abi) + " split",
+		Input:       manifestTemplate,
+		Implicits:   append(android.Paths{baseApk}, sharedLibs...),
+		Output:      out,
+		Args: map[string]string{
+			"baseApk": baseApk.String(),
+			"flags":   strings.Join(flags, " "),
+		},
+	})
+}
@@ -135,6 +135,9 @@ type aapt struct {
 	splitNames []string
 	splits     []split
 
+	// The resource packages that the resources were linked against.
+	sharedLibs android.Paths
+
 	aaptProperties aaptProperties
 
 	resourcesNodesDepSet depset.DepSet[*resourcesNode]
@@ -417,6 +420,7 @@ type aaptBuildActionOptions struct {
 	enforceDefaultTargetSdkVersion bool
 	forceNonFinalResourceIDs       bool
 	extraLinkFlags                 []string
+	extraLinkDeps                  android.Paths
 	aconfigTextFiles               android.Paths
 	usesLibrary                    *usesLibrary
 	// If rroDirs is provided, it will be used to generate package-res.apk
@@ -444,6 +448,7 @@ func (a *aapt) buildActions(ctx android.ModuleContext, opts aaptBuildActionOptio
 
 	staticResourcesNodesDepSet, sharedResourcesNodesDepSet, staticRRODirsDepSet, staticManifestsDepSet, sharedExportPackages, libFlags :=
 		aaptLibs(ctx, opts.sdkContext, opts.classLoaderContexts, opts.usesLibrary)
+	a.sharedLibs = sharedExportPackages
 
 	// Exclude any libraries from the supplied list.
 	opts.classLoaderContexts = opts.classLoaderContexts.ExcludeLibs(opts.excludedLibs)
@@ -507,6 +512,7 @@ func (a *aapt) buildActions(ctx android.ModuleContext, opts aaptBuildActionOptio
 	linkDeps = append(linkDeps, sharedExportPackages...)
 	linkDeps = append(linkDeps, staticDeps.resPackages()...)
 	linkFlags = append(linkFlags, opts.extraLinkFlags...)
+	linkDeps = append(linkDeps, opts.extraLinkDeps...)
 	if a.isLibrary {
 		linkFlags = append(linkFlags, "--static-lib")
 	}
@@ -972,6 +978,7 @@ func (a *AndroidLibrary) DepsMutator(ctx android.BottomUpMutatorContext) {
 }
 
 func (a *AndroidLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	a.setApexMinSdkVersion(ctx)
 	a.aapt.isLibrary = true
 	a.classLoaderContexts = a.usesLibrary.classLoaderContextForUsesLibDeps(ctx)
 	if a.usesLibrary.shouldDisableDexpreopt {
@@ -1346,6 +1353,7 @@ func (a *AARImport) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 			nil,
 			transitiveUnconditionalExportedFlags,
 		),
+		ProguardFlagsFileOwners: proguardFlagsFileOwners(ctx, android.Paths{proguardFlags}),
 	})
 
 	ctx.Build(pctx, android.BuildParams{
@@ -20,6 +20,7 @@ package java
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/google/blueprint"
@@ -59,6 +60,8 @@ func RegisterAppBuildComponents(ctx android.RegistrationContext) {
 	ctx.RegisterModuleType("android_app_certificate", AndroidAppCertificateFactory)
 	ctx.RegisterModuleType("override_android_app", OverrideAndroidAppModuleFactory)
 	ctx.RegisterModuleType("override_android_test", OverrideAndroidTestModuleFactory)
+
+	ctx.RegisterParallelSingletonType("verify_uses_libraries_relaxed", verifyUsesLibrariesRelaxedSingletonFactory)
 }
 
 type AppInfo struct {
@@ -83,6 +86,13 @@ type AppInfo struct {
 	PrivAppAllowlist              android.OptionalPath
 	OverriddenManifestPackageName *string
 	ApkCertsFile                  android.Path
+
+	// UnsignedAlignedApk is the zipaligned APK before it is signed, nil for prebuilt apps.
+	UnsignedAlignedApk android.Path
+
+	// ResourcePathShorteningMap maps the shortened resource paths in the APK to the original ones,
+	// nil unless optimize.resources.shorten_paths is set.
+	ResourcePathShorteningMap android.Path
 }
 
 var AppInfoProvider = blueprint.NewProvider[*AppInfo]()
@@ -106,6 +116,10 @@ type appProperties struct {
 	// list of resource labels to generate individual resource packages
 	Package_splits []string
 
+	// If true, package the embedded JNI libraries of each ABI into a separate split APK instead
+	// of the base APK. Defaults to false.
+	Abi_splits *bool
+
 	// list of native libraries that will be provided in or alongside the resulting jar
 	Jni_libs proptools.Configurable[[]string] `android:"arch_variant"`
 
@@ -171,6 +185,40 @@ type appProperties struct {
 
 	ProductCharacteristicsRROPackageName        *string `blueprint:"mutated"`
 	ProductCharacteristicsRROManifestModuleName *string `blueprint:"mutated"`
+
+	Optimize struct {
+		// Properties that run aapt2 optimize on the resources of the app after they are linked
+		// and shrunk, before they are packaged into the APK.
+		Resources struct {
+			// If true, collapse the names of the resources in the resource table into a single
+			// value. Resources that are looked up by name must be kept with a #no_collapse
+			// entry in a resources config. Defaults to false.
+			Collapse_keys *bool
+
+			// If true, shorten the paths of the resources inside the APK. The mapping from the
+			// shortened paths to the original ones is recorded for deobfuscating crashes.
+			// Defaults to false.
+			Shorten_paths *bool
+
+			// If true, encode the resource tables sparsely, which makes them smaller at a small
+			// cost in lookup time. Defaults to false.
+			Sparse_encoding *bool
+		}
+	}
+
+	App_bundle struct {
+		// If true, build a signed Android App Bundle (.aab) of the app with bundletool, available
+		// through the ".aab" output tag. Defaults to false.
+		Enabled *bool
+
+		// Path to a BundleConfig json file passed to bundletool when building the app bundle.
+		Config *string `android:"path"`
+	}
+
+	// If set, the version of the Kotlin standard library that must be statically linked into the
+	// app. It is an error if a different version is reachable through the static dependencies of
+	// the app.
+	Expected_kotlin_stdlib_version *string
 }
 
 // android_app properties that can be overridden by override_android_app
@@ -218,7 +266,22 @@ type AndroidApp struct {
 	embeddedJniLibs          bool
 	jniCoverageOutputs       android.Paths
 
+	// the jars of the JNI libraries of each ABI, if abi_splits is set.
+	abiSplitJniJars []abiSplitJniJar
+
+	// the signed split APKs, and a zip of them together with the base APK.
+	splitApks    []splitApk
+	splitApksZip android.Path
+
 	bundleFile android.Path
+	appBundle  android.Path
+
+	// the zipaligned APK before it is signed, for signing infrastructure outside of the build.
+	unsignedAlignedApk android.Path
+
+	// the mapping from shortened resource paths to the original ones, if
+	// optimize.resources.shorten_paths is set.
+	resourcePathShorteningMap android.Path
 
 	// the install APK name is normally the same as the module name, but can be overridden with PRODUCT_PACKAGE_NAME_OVERRIDES.
 	installApkName string
@@ -229,6 +292,9 @@ type AndroidApp struct {
 
 	additionalAaptFlags []string
 
+	// Files referenced by additionalAaptFlags.
+	additionalAaptLinkDeps android.Paths
+
 	overriddenManifestPackageName string
 
 	android.ApexBundleDepsInfo
@@ -289,6 +355,7 @@ func (a *AndroidApp) DepsMutator(ctx android.BottomUpMutatorContext) {
 	sdkDep := decodeSdkDep(ctx, android.SdkContext(a))
 	a.usesLibrary.deps(ctx, sdkDep.hasFrameworkLibs())
 	a.Module.deps(ctx)
+	addDexBootJarsDependency(ctx)
 	if sdkDep.hasFrameworkLibs() {
 		a.aapt.deps(ctx, sdkDep)
 	}
@@ -413,8 +480,10 @@ func (a *AndroidTestHelperApp) GenerateAndroidBuildActions(ctx android.ModuleCon
 		TestOnly: true,
 	})
 	appInfo := &AppInfo{
-		Updatable:     Bool(a.appProperties.Updatable),
-		TestHelperApp: true,
+		Updatable:                 Bool(a.appProperties.Updatable),
+		TestHelperApp:             true,
+		UnsignedAlignedApk:        a.unsignedAlignedApk,
+		ResourcePathShorteningMap: a.resourcePathShorteningMap,
 	}
 	setCommonAppInfo(appInfo, a)
 	android.SetProvider(ctx, AppInfoProvider, appInfo)
@@ -433,6 +502,7 @@ func (a *AndroidTestHelperApp) GenerateAndroidBuildActions(ctx android.ModuleCon
 }
 
 func (a *AndroidApp) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	a.setApexMinSdkVersion(ctx)
 	a.checkAppSdkVersions(ctx)
 	a.checkEmbedJnis(ctx)
 	a.generateAndroidBuildActions(ctx)
@@ -452,6 +522,8 @@ func (a *AndroidApp) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		EmbeddedJNILibs:               embeddedJniLibs,
 		MergedManifestFile:            a.mergedManifest,
 		OverriddenManifestPackageName: &overriddenName,
+		UnsignedAlignedApk:            a.unsignedAlignedApk,
+		ResourcePathShorteningMap:     a.resourcePathShorteningMap,
 	}
 	setCommonAppInfo(appInfo, a)
 	android.SetProvider(ctx, AppInfoProvider, appInfo)
@@ -466,6 +538,7 @@ func (a *AndroidApp) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 			ProguardUsageZip:   a.dexer.proguardUsageZip.Path(),
 			ClassesJar:         a.implementationAndResourcesJar,
 		})
+		proguardDictionaryDistZip(ctx, a.dexer.proguardDictionary.Path(), a.exportPackage)
 	}
 }
 
@@ -704,6 +777,7 @@ func (a *AndroidApp) aaptBuildActions(ctx android.ModuleContext) {
 			enforceDefaultTargetSdkVersion: a.enforceDefaultTargetSdkVersion(),
 			forceNonFinalResourceIDs:       nonFinalIds,
 			extraLinkFlags:                 aaptLinkFlags,
+			extraLinkDeps:                  a.additionalAaptLinkDeps,
 			aconfigTextFiles:               aconfigTextFilePaths,
 			usesLibrary:                    &a.usesLibrary,
 		},
@@ -756,6 +830,7 @@ func (a *AndroidApp) installPath(ctx android.ModuleContext) android.InstallPath
 func (a *AndroidApp) dexBuildActions(ctx android.ModuleContext) (android.Path, android.Path, *JavaInfo) {
 	a.dexpreopter.installPath = a.installPath(ctx)
 	a.dexpreopter.isApp = true
+	a.dexpreopter.supportsCompressedDex = true
 	if a.dexProperties.Uncompress_dex == nil {
 		// If the value was not force-set by the user, use reasonable default based on the module.
 		a.dexProperties.Uncompress_dex = proptools.BoolPtr(a.shouldUncompressDex(ctx))
@@ -800,17 +875,65 @@ func (a *AndroidApp) dexBuildActions(ctx android.ModuleContext) (android.Path, a
 		}
 	}
 
+	packageResources = a.optimizeResources(ctx, packageResources)
+
 	return a.dexJarFile.PathOrNil(), packageResources, javaInfo
 }
 
+// optimizeResources runs aapt2 optimize on the linked resources if any of the optimize.resources
+// properties are set, and returns the resources to package into the APK.
+func (a *AndroidApp) optimizeResources(ctx android.ModuleContext, packageResources android.Path) android.Path {
+	props := a.appProperties.Optimize.Resources
+	var flags []string
+	if Bool(props.Collapse_keys) {
+		flags = append(flags, "--collapse-resource-names")
+	}
+	if Bool(props.Sparse_encoding) {
+		flags = append(flags, "--enable-sparse-encoding")
+	}
+	var pathMap android.WritablePath
+	if Bool(props.Shorten_paths) {
+		pathMap = android.PathForModuleOut(ctx, "aapt2_optimize", "resource-path-map.txt")
+		a.resourcePathShorteningMap = pathMap
+	}
+	if len(flags) == 0 && pathMap == nil {
+		return packageResources
+	}
+
+	optimized := android.PathForModuleOut(ctx, "aapt2_optimize", packageResources.Base())
+	aapt2Optimize(ctx, optimized, packageResources, flags, pathMap)
+	return optimized
+}
+
+type abiSplitJniJar struct {
+	abi    string
+	jniJar android.Path
+}
+
+type splitApk struct {
+	suffix string
+	path   android.Path
+}
+
 func (a *AndroidApp) jniBuildActions(jniLibs []jniLib, prebuiltJniPackages android.Paths, ctx android.ModuleContext) android.WritablePath {
 	var jniJarFile android.WritablePath
+	if Bool(a.appProperties.Abi_splits) && !a.shouldEmbedJnis(ctx) {
+		ctx.PropertyErrorf("abi_splits", "requires the JNI libraries to be embedded in the APK, "+
+			"set use_embedded_native_libs: true")
+	}
 	if len(jniLibs) > 0 || len(prebuiltJniPackages) > 0 {
 		a.jniLibs = jniLibs
 		if a.shouldEmbedJnis(ctx) {
-			jniJarFile = android.PathForModuleOut(ctx, "jnilibs.zip")
 			a.installPathForJNISymbols = a.installPath(ctx)
-			TransformJniLibsToJar(ctx, jniJarFile, jniLibs, prebuiltJniPackages, a.useEmbeddedNativeLibs(ctx))
+			baseJniLibs := jniLibs
+			if Bool(a.appProperties.Abi_splits) {
+				baseJniLibs = nil
+				a.abiSplitJniBuildActions(ctx, jniLibs)
+			}
+			if len(baseJniLibs) > 0 || len(prebuiltJniPackages) > 0 {
+				jniJarFile = android.PathForModuleOut(ctx, "jnilibs.zip")
+				TransformJniLibsToJar(ctx, jniJarFile, baseJniLibs, prebuiltJniPackages, a.useEmbeddedNativeLibs(ctx))
+			}
 			for _, jni := range jniLibs {
 				if jni.coverageFile.Valid() {
 					// Only collect coverage for the first target arch if this is a multilib target.
@@ -833,6 +956,26 @@ func (a *AndroidApp) jniBuildActions(jniLibs []jniLib, prebuiltJniPackages andro
 	return jniJarFile
 }
 
+// abiSplitJniBuildActions packages the JNI libraries of each ABI into a separate jar, to be
+// packaged into the split APK of the ABI.
+func (a *AndroidApp) abiSplitJniBuildActions(ctx android.ModuleContext, jniLibs []jniLib) {
+	var abis []string
+	jniLibsByAbi := make(map[string][]jniLib)
+	for _, jni := range jniLibs {
+		abi := jni.target.Arch.Abi[0]
+		if _, exists := jniLibsByAbi[abi]; !exists {
+			abis = append(abis, abi)
+		}
+		jniLibsByAbi[abi] = append(jniLibsByAbi[abi], jni)
+	}
+
+	for _, abi := range abis {
+		jniJar := android.PathForModuleOut(ctx, "abi_splits", abi, "jnilibs.zip")
+		TransformJniLibsToJar(ctx, jniJar, jniLibsByAbi[abi], nil, a.useEmbeddedNativeLibs(ctx))
+		a.abiSplitJniJars = append(a.abiSplitJniJars, abiSplitJniJar{abi: abi, jniJar: jniJar})
+	}
+}
+
 func (a *AndroidApp) JNISymbolsInstalls(installPath string) android.RuleBuilderInstalls {
 	var jniSymbols android.RuleBuilderInstalls
 	for _, jniLib := range a.jniLibs {
@@ -968,6 +1111,7 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 	}
 	a.onDeviceDir = android.InstallPathToOnDevicePath(ctx, a.installDir)
 
+	a.usesLibrary.validateProvidesUsesLibAttributes(ctx)
 	a.classLoaderContexts = a.usesLibrary.classLoaderContextForUsesLibDeps(ctx)
 	if a.usesLibrary.shouldDisableDexpreopt {
 		a.dexpreopter.disableDexpreopt()
@@ -1033,8 +1177,14 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 	}
 	rotationMinSdkVersion := String(a.overridableAppProperties.RotationMinSdkVersion)
 
-	CreateAndSignAppPackage(ctx, packageFile, packageResources, jniJarFile, dexJarFile, certificates, apkDeps, v4SignatureFile, lineageFile, rotationMinSdkVersion)
+	unsignedApk := CreateAndSignAppPackage(ctx, packageFile, packageResources, jniJarFile, dexJarFile, certificates, apkDeps, v4SignatureFile, lineageFile, rotationMinSdkVersion)
 	a.outputFile = packageFile
+
+	// signapk aligns the APK it signs, align the unsigned APK the same way for consumers that sign
+	// it themselves.
+	unsignedAlignedApk := android.PathForModuleOut(ctx, "unsigned", a.installApkName+".apk")
+	TransformZipAlign(ctx, unsignedAlignedApk, unsignedApk, nil)
+	a.unsignedAlignedApk = unsignedAlignedApk
 	if v4SigningRequested {
 		a.extraOutputFiles = append(a.extraOutputFiles, v4SignatureFile)
 	}
@@ -1067,12 +1217,56 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 		if v4SigningRequested {
 			a.extraOutputFiles = append(a.extraOutputFiles, v4SignatureFile)
 		}
+		a.splitApks = append(a.splitApks, splitApk{suffix: split.suffix, path: packageFile})
+	}
+
+	for _, split := range a.abiSplitJniJars {
+		// Link and sign the ABI split APKs
+		suffix := strings.ReplaceAll(split.abi, "-", "_")
+		splitResources := android.PathForModuleOut(ctx, "abi_splits", split.abi, "resources.apk")
+		aapt2LinkAbiSplit(ctx, splitResources, packageResources, split.abi, a.aapt.sharedLibs)
+		packageFile := android.PathForModuleOut(ctx, a.installApkName+"_"+suffix+".apk")
+		if v4SigningRequested {
+			v4SignatureFile = android.PathForModuleOut(ctx, a.installApkName+"_"+suffix+".apk.idsig")
+		}
+		CreateAndSignAppPackage(ctx, packageFile, splitResources, split.jniJar, nil, certificates, apkDeps, v4SignatureFile, lineageFile, rotationMinSdkVersion)
+		a.extraOutputFiles = append(a.extraOutputFiles, packageFile)
+		if v4SigningRequested {
+			a.extraOutputFiles = append(a.extraOutputFiles, v4SignatureFile)
+		}
+		a.splitApks = append(a.splitApks, splitApk{suffix: suffix, path: packageFile})
+	}
+
+	if len(a.splitApks) > 0 {
+		// Zip the base APK together with the split APKs, for dist and test harnesses that install
+		// all of them at once.
+		splitApksZip := android.PathForModuleOut(ctx, a.installApkName+"-splits.zip")
+		rule := android.NewRuleBuilder(pctx, ctx)
+		cmd := rule.Command().
+			BuiltTool("soong_zip").
+			FlagWithOutput("-o ", splitApksZip).
+			Flag("-j").
+			FlagWithInput("-f ", a.outputFile)
+		for _, split := range a.splitApks {
+			cmd.FlagWithInput("-f ", split.path)
+		}
+		rule.Build("split_apks_zip", "zip split APKs")
+		a.splitApksZip = splitApksZip
+		ctx.DistForGoal("apps_only", splitApksZip)
 	}
 
 	// Build an app bundle.
 	bundleFile := android.PathForModuleOut(ctx, "base.zip")
 	BuildBundleModule(ctx, bundleFile, a.exportPackage, jniJarFile, dexJarFile)
 	a.bundleFile = bundleFile
+	if Bool(a.appProperties.App_bundle.Enabled) {
+		unsignedAppBundle := android.PathForModuleOut(ctx, "unsigned", a.installApkName+".aab")
+		BuildAppBundle(ctx, unsignedAppBundle, bundleFile,
+			android.OptionalPathForModuleSrc(ctx, a.appProperties.App_bundle.Config))
+		appBundle := android.PathForModuleOut(ctx, a.installApkName+".aab")
+		SignAppBundle(ctx, appBundle, unsignedAppBundle, certificates, a.MinSdkVersion(ctx).FinalOrFutureInt())
+		a.appBundle = appBundle
+	}
 
 	allowlist := a.createPrivappAllowlist(ctx)
 	if allowlist != nil {
@@ -1109,7 +1303,19 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 				}
 			}
 		}
-		ctx.InstallFile(a.installDir, a.outputFile.Base(), a.outputFile, extraInstalledPaths...)
+		if a.dexpreopter.compressedDex {
+			// The app is extracted and compiled on the device instead of being dexpreopted.
+			compressedApk := android.PathForModuleOut(ctx, "compressed_apk", a.outputFile.Base()+".gz")
+			ctx.Build(pctx, android.BuildParams{
+				Rule:        gzipRule,
+				Input:       a.outputFile,
+				Output:      compressedApk,
+				Description: "Compressing " + a.outputFile.Base(),
+			})
+			ctx.InstallFile(a.installDir, compressedApk.Base(), compressedApk, extraInstalledPaths...)
+		} else {
+			ctx.InstallFile(a.installDir, a.outputFile.Base(), a.outputFile, extraInstalledPaths...)
+		}
 	}
 
 	ctx.CheckbuildFile(a.outputFile)
@@ -1124,6 +1330,7 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 	)
 
 	if javaInfo != nil {
+		checkKotlinStdlibs(ctx, javaInfo.KotlinStdlibs, a.appProperties.Expected_kotlin_stdlib_version)
 		javaInfo.OutputFile = a.outputFile
 		setExtraJavaInfo(ctx, a, javaInfo)
 		android.SetProvider(ctx, JavaInfoProvider, javaInfo)
@@ -1156,8 +1363,18 @@ func (a *AndroidApp) setOutputFiles(ctx android.ModuleContext) {
 		ctx.SetOutputFiles([]android.Path{a.rJar}, ".aapt.jar")
 	}
 	ctx.SetOutputFiles([]android.Path{a.outputFile}, ".apk")
+	ctx.SetOutputFiles([]android.Path{a.unsignedAlignedApk}, ".apk.unsigned")
 	ctx.SetOutputFiles([]android.Path{a.exportPackage}, ".export-package.apk")
 	ctx.SetOutputFiles([]android.Path{a.aapt.manifestPath}, ".manifest.xml")
+	if a.appBundle != nil {
+		ctx.SetOutputFiles([]android.Path{a.appBundle}, ".aab")
+	}
+	for _, split := range a.splitApks {
+		ctx.SetOutputFiles([]android.Path{split.path}, ".split."+split.suffix)
+	}
+	if a.splitApksZip != nil {
+		ctx.SetOutputFiles([]android.Path{a.splitApksZip}, ".splits.zip")
+	}
 	setOutputFiles(ctx, a.Library.Module)
 }
 
@@ -1583,10 +1800,21 @@ type appTestProperties struct {
 	// If specified, the instrumentation target package name in the manifest is overwritten by it.
 	Instrumentation_target_package *string
 
+	// If true, the resources of the test are linked against the resources of the android_app in
+	// instrumentation_for and use a separate resource package id, so that the test can reference
+	// the resources of the app without recompiling them and the resource ids of the test do not
+	// collide with the ones of the app when both are installed. Requires instrumentation_for.
+	Instrumentation_resource_namespacing *bool
+
 	// If specified, the mainline module package name in the test config is overwritten by it.
 	Mainline_package_name *string
 
 	Manifest_values Manifest_values
+
+	// If true, the test config is generated from the <instrumentation> tag of the manifest and the
+	// JUnit annotations used by the test classes, e.g. @LargeTest for the timeout, instead of from
+	// test_config_template. Can not be used with test_config_template.
+	Test_config_from_annotations *bool
 }
 
 type AndroidTest struct {
@@ -1617,6 +1845,46 @@ func (a *AndroidTestHelperApp) includedInTestSuite(searchPrefix string) bool {
 	return android.PrefixInList(a.appTestHelperAppProperties.Test_suites, searchPrefix)
 }
 
+// The resource package id used by the test with instrumentation_resource_namespacing, which must
+// differ from the 0x7f package id of the resources of the instrumented app.
+const instrumentationResourcePackageId = "0x80"
+
+// instrumentationResourceNamespacing adds the aapt2 link flags to link the resources of the test
+// against the resources of the instrumented app if instrumentation_resource_namespacing is set.
+func (a *AndroidTest) instrumentationResourceNamespacing(ctx android.ModuleContext) {
+	if !proptools.Bool(a.appTestProperties.Instrumentation_resource_namespacing) {
+		return
+	}
+	if a.appTestProperties.Instrumentation_for == nil {
+		ctx.PropertyErrorf("instrumentation_resource_namespacing", "requires instrumentation_for to be set")
+		return
+	}
+	if android.PrefixInList(a.aaptProperties.Aaptflags, "--package-id") {
+		ctx.PropertyErrorf("aaptflags", "--package-id can not be set with instrumentation_resource_namespacing")
+		return
+	}
+
+	var appResources android.Path
+	ctx.VisitDirectDepsProxyWithTag(instrumentationForTag, func(dep android.ModuleProxy) {
+		if info, ok := android.OtherModuleProvider(ctx, dep, JavaInfoProvider); ok && info.AndroidLibraryDependencyInfo != nil {
+			appResources = info.AndroidLibraryDependencyInfo.ExportPackage
+		}
+	})
+	if appResources == nil {
+		if !ctx.Config().AllowMissingDependencies() {
+			ctx.PropertyErrorf("instrumentation_for",
+				"%q has no resources to link against for instrumentation_resource_namespacing",
+				*a.appTestProperties.Instrumentation_for)
+		}
+		return
+	}
+
+	a.additionalAaptFlags = append(a.additionalAaptFlags,
+		"-I "+appResources.String(),
+		"--package-id "+instrumentationResourcePackageId)
+	a.additionalAaptLinkDeps = append(a.additionalAaptLinkDeps, appResources)
+}
+
 func (a *AndroidTest) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	checkMinSdkVersionMts(ctx, a.MinSdkVersion(ctx))
 	var configs []tradefed.Config
@@ -1630,6 +1898,7 @@ func (a *AndroidTest) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 			a.additionalAaptFlags = append(a.additionalAaptFlags, "--rename-instrumentation-target-package "+manifestPackageName)
 		}
 	}
+	a.instrumentationResourceNamespacing(ctx)
 	applicationId := a.appTestProperties.Manifest_values.ApplicationId
 	if applicationId != nil {
 		packageNameProp := a.overridableAppProperties.Package_name.Get(ctx)
@@ -1646,10 +1915,38 @@ func (a *AndroidTest) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	for _, module := range a.testProperties.Test_mainline_modules {
 		configs = append(configs, tradefed.Option{Name: "config-descriptor:metadata", Key: "mainline-param", Value: module})
 	}
+	if len(a.splitApks) > 0 {
+		// Install the split APKs together with the base APK, which is installed again.
+		splitApkNames := []string{a.outputFile.Base()}
+		for _, split := range a.splitApks {
+			splitApkNames = append(splitApkNames, split.path.Base())
+		}
+		configs = append(configs, tradefed.Object{
+			Type:  "target_preparer",
+			Class: "com.android.tradefed.targetprep.suite.SuiteApkInstaller",
+			Options: []tradefed.Option{
+				{Name: "split-apk-file-names", Value: strings.Join(splitApkNames, ",")},
+			},
+		})
+	}
 
-	testConfig := tradefed.AutoGenInstrumentationTestConfig(ctx, a.testProperties.Test_config,
-		a.testProperties.Test_config_template, a.manifestPath, a.testProperties.Test_suites,
-		a.testProperties.Auto_gen_config, configs, a.testProperties.Test_options.Test_runner_options)
+	var testConfig android.Path
+	if Bool(a.appTestProperties.Test_config_from_annotations) {
+		if a.testProperties.Test_config_template != nil {
+			ctx.PropertyErrorf("test_config_from_annotations", "can not be used with test_config_template")
+		}
+		if a.implementationJarFile == nil {
+			ctx.PropertyErrorf("test_config_from_annotations", "requires the test to contain classes")
+			return
+		}
+		testConfig = tradefed.AutoGenInstrumentationTestConfigFromAnnotations(ctx, a.testProperties.Test_config,
+			a.manifestPath, a.implementationJarFile, a.installApkName+".apk", a.testProperties.Test_suites,
+			a.testProperties.Auto_gen_config, configs, a.testProperties.Test_options.Test_runner_options)
+	} else {
+		testConfig = tradefed.AutoGenInstrumentationTestConfig(ctx, a.testProperties.Test_config,
+			a.testProperties.Test_config_template, a.manifestPath, a.testProperties.Test_suites,
+			a.testProperties.Auto_gen_config, configs, a.testProperties.Test_options.Test_runner_options)
+	}
 	a.testConfig = a.FixTestConfig(ctx, testConfig)
 	a.extraTestConfigs = android.PathsForModuleSrc(ctx, a.testProperties.Test_options.Extra_test_configs)
 	a.data = android.PathsForModuleSrc(ctx, a.testProperties.Data)
@@ -1657,6 +1954,9 @@ func (a *AndroidTest) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	a.data = append(a.data, android.PathsForModuleSrc(ctx, a.testProperties.Device_first_data)...)
 	a.data = append(a.data, android.PathsForModuleSrc(ctx, a.testProperties.Device_first_prefer32_data)...)
 	a.data = append(a.data, android.PathsForModuleSrc(ctx, a.testProperties.Host_common_data)...)
+	for _, split := range a.splitApks {
+		a.data = append(a.data, split.path)
+	}
 
 	// Install test deps
 	if !ctx.Config().KatiEnabled() {
@@ -1775,6 +2075,7 @@ func (a *AndroidTest) DepsMutator(ctx android.BottomUpMutatorContext) {
 		a.setApiMapper(true)
 	}
 	a.AndroidApp.DepsMutator(ctx)
+	addTestFixturesDeps(ctx, a.testProperties.Uses_test_fixtures)
 }
 
 func (a *AndroidTest) OverridablePropertiesDepsMutator(ctx android.BottomUpMutatorContext) {
@@ -1978,11 +2279,26 @@ type UsesLibraryProperties struct {
 	// to true if either uses_libs or optional_uses_libs is set.  Will unconditionally default to true in the future.
 	Enforce_uses_libs *bool
 
+	// If true, a mismatch between the uses_libs and optional_uses_libs properties and the
+	// <uses-library> tags in the AndroidManifest.xml file is reported as a warning instead of failing
+	// the build, and the module is dexpreopted without AOT-compilation. Intended for apps whose
+	// manifests list optional libraries that are missing on some products.
+	Relax_uses_libs_check *bool
+
 	// Optional name of the <uses-library> provided by this module. This is needed for non-SDK
 	// libraries, because SDK ones are automatically picked up by Soong. The <uses-library> name
 	// normally is the same as the module name, but there are exceptions.
 	Provides_uses_lib *string
 
+	// Optional version of the <uses-library> provided by this module. It is recorded in the class
+	// loader context of the modules that use the library and in its permissions XML file.
+	Provides_uses_lib_version *int64
+
+	// Optional SHA-256 digest of the certificate that the <uses-library> provided by this module is
+	// signed with, as 64 hex digits that may be separated by colons. It is recorded in the class
+	// loader context of the modules that use the library and in its permissions XML file.
+	Provides_uses_lib_cert_digest *string
+
 	// A list of shared library names to exclude from the classpath of the APK. Adding a library here
 	// will prevent it from being used when precompiling the APK and prevent it from being implicitly
 	// added to the APK's manifest's <uses-library> elements.
@@ -2095,10 +2411,14 @@ func (u *usesLibrary) classLoaderContextForUsesLibDeps(ctx android.ModuleContext
 				}
 			}
 			libName := dep
-			if ulib := javaInfo.ProvidesUsesLibInfo; ulib != nil && ulib.ProvidesUsesLib != nil {
-				libName = *ulib.ProvidesUsesLib
+			var attrs dexpreopt.UsesLibraryAttributes
+			if ulib := javaInfo.ProvidesUsesLibInfo; ulib != nil {
+				if ulib.ProvidesUsesLib != nil {
+					libName = *ulib.ProvidesUsesLib
+				}
+				attrs = ulib.Attributes
 			}
-			clcMap.AddContext(ctx, tag.sdkVersion, libName, tag.optional,
+			clcMap.AddContextWithAttributes(ctx, tag.sdkVersion, libName, attrs, tag.optional,
 				javaInfo.DexJarBuildPath.PathOrNil(), lib.DexJarInstallPath,
 				lib.ClassLoaderContexts)
 		} else if ctx.Config().AllowMissingDependencies() {
@@ -2110,6 +2430,29 @@ func (u *usesLibrary) classLoaderContextForUsesLibDeps(ctx android.ModuleContext
 	return clcMap
 }
 
+// providesUsesLibAttributes returns the optional attributes of the <uses-library> provided by the
+// module.
+func (u *usesLibrary) providesUsesLibAttributes() dexpreopt.UsesLibraryAttributes {
+	return dexpreopt.UsesLibraryAttributes{
+		Version:    int64(proptools.Int(u.usesLibraryProperties.Provides_uses_lib_version)),
+		CertDigest: strings.ToLower(strings.ReplaceAll(proptools.String(u.usesLibraryProperties.Provides_uses_lib_cert_digest), ":", "")),
+	}
+}
+
+var certDigestRegexp = regexp.MustCompile(`^[0-9a-fA-F]{2}(:?[0-9a-fA-F]{2}){31}$`)
+
+// validateProvidesUsesLibAttributes reports errors for invalid provides_uses_lib_version and
+// provides_uses_lib_cert_digest properties.
+func (u *usesLibrary) validateProvidesUsesLibAttributes(ctx android.ModuleContext) {
+	if v := u.usesLibraryProperties.Provides_uses_lib_version; v != nil && *v < 0 {
+		ctx.PropertyErrorf("provides_uses_lib_version", "must not be negative, got %d", *v)
+	}
+	if d := u.usesLibraryProperties.Provides_uses_lib_cert_digest; d != nil && !certDigestRegexp.MatchString(*d) {
+		ctx.PropertyErrorf("provides_uses_lib_cert_digest",
+			"must be a SHA-256 digest of 64 hex digits optionally separated by colons, got %q", *d)
+	}
+}
+
 // enforceUsesLibraries returns true of <uses-library> tags should be checked against uses_libs and optional_uses_libs
 // properties.  Defaults to true if either of uses_libs or optional_uses_libs is specified.  Will default to true
 // unconditionally in the future.
@@ -2156,6 +2499,11 @@ func (u *usesLibrary) verifyUsesLibraries(ctx android.ModuleContext, inputFile a
 
 	if dexpreopt.GetGlobalConfig(ctx).RelaxUsesLibraryCheck {
 		cmd.Flag("--enforce-uses-libraries-relax")
+	} else if u.relaxUsesLibrariesCheck(ctx) {
+		cmd.Flag("--enforce-uses-libraries-warn")
+		android.SetProvider(ctx, UsesLibrariesRelaxedInfoProvider, UsesLibrariesRelaxedInfo{
+			StatusFile: statusFile,
+		})
 	}
 
 	requiredUsesLibs, optionalUsesLibs := classLoaderContexts.UsesLibs()
@@ -20,6 +20,7 @@ package java
 
 import (
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/google/blueprint"
@@ -51,8 +52,10 @@ var combineApk = pctx.AndroidStaticRule("combineApk",
 		CommandDeps: []string{"${config.MergeZipsCmd}"},
 	})
 
+// CreateAndSignAppPackage combines the resources, dex and JNI files into an APK and signs it into
+// outputFile. It returns the path to the combined APK before signing.
 func CreateAndSignAppPackage(ctx android.ModuleContext, outputFile android.WritablePath,
-	packageFile, jniJarFile, dexJarFile android.Path, certificates []Certificate, deps android.Paths, v4SignatureFile android.WritablePath, lineageFile android.Path, rotationMinSdkVersion string) {
+	packageFile, jniJarFile, dexJarFile android.Path, certificates []Certificate, deps android.Paths, v4SignatureFile android.WritablePath, lineageFile android.Path, rotationMinSdkVersion string) android.Path {
 
 	unsignedApkName := strings.TrimSuffix(outputFile.Base(), ".apk") + "-unsigned.apk"
 	unsignedApk := android.PathForModuleOut(ctx, unsignedApkName)
@@ -72,6 +75,7 @@ func CreateAndSignAppPackage(ctx android.ModuleContext, outputFile android.Writa
 		Implicits: deps,
 	})
 	SignAppPackage(ctx, outputFile, unsignedApk, certificates, v4SignatureFile, lineageFile, rotationMinSdkVersion)
+	return unsignedApk
 }
 
 func SignAppPackage(ctx android.ModuleContext, signedApk android.WritablePath, unsignedApk android.Path, certificates []Certificate, v4SignatureFile android.WritablePath, lineageFile android.Path, rotationMinSdkVersion string) {
@@ -118,6 +122,42 @@ func SignAppPackage(ctx android.ModuleContext, signedApk android.WritablePath, u
 	})
 }
 
+var signAppBundle = pctx.AndroidStaticRule("signAppBundle",
+	blueprint.RuleParams{
+		Command: `rm -f $out && ${config.ApksignerCmd} sign --v1-signing-enabled true ` +
+			`--v2-signing-enabled false --v3-signing-enabled false --v4-signing-enabled false ` +
+			`--min-sdk-version $minSdkVersion $certificates --in $in --out $out`,
+		CommandDeps: []string{"${config.ApksignerCmd}"},
+	},
+	"certificates", "minSdkVersion")
+
+// SignAppBundle signs an app bundle with a JAR signature, the only signature scheme supported by
+// app bundles.  The bundle has no AndroidManifest.xml at its root that apksigner could read the
+// minimum SDK version from, so it has to be passed explicitly; it selects the digest algorithm.
+func SignAppBundle(ctx android.ModuleContext, signedBundle android.WritablePath, unsignedBundle android.Path, certificates []Certificate, minSdkVersion int) {
+	var certificateArgs []string
+	var deps android.Paths
+	for i, c := range certificates {
+		if i > 0 {
+			certificateArgs = append(certificateArgs, "--next-signer")
+		}
+		certificateArgs = append(certificateArgs, "--cert", c.Pem.String(), "--key", c.Key.String())
+		deps = append(deps, c.Pem, c.Key)
+	}
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        signAppBundle,
+		Description: "sign app bundle",
+		Output:      signedBundle,
+		Input:       unsignedBundle,
+		Implicits:   deps,
+		Args: map[string]string{
+			"certificates":  strings.Join(certificateArgs, " "),
+			"minSdkVersion": strconv.Itoa(minSdkVersion),
+		},
+	})
+}
+
 var buildAAR = pctx.AndroidStaticRule("buildAAR",
 	blueprint.RuleParams{
 		Command: `rm -rf ${outDir} && mkdir -p ${outDir} && ` +
@@ -219,6 +259,36 @@ func BuildBundleModule(ctx android.ModuleContext, outputFile android.WritablePat
 	})
 }
 
+var buildAppBundle = pctx.AndroidStaticRule("buildAppBundle",
+	blueprint.RuleParams{
+		Command: `rm -f $out && ${config.JavaCmd} ${config.JavaVmFlags} -jar ${config.BundletoolJar} ` +
+			`build-bundle --modules=$in --output=$out $flags`,
+		CommandDeps: []string{"${config.BundletoolJar}"},
+	}, "flags")
+
+// Builds an unsigned app bundle from a module built by BuildBundleModule with bundletool.
+func BuildAppBundle(ctx android.ModuleContext, outputFile android.WritablePath,
+	bundleModule android.Path, bundleConfig android.OptionalPath) {
+
+	var flags []string
+	var implicits android.Paths
+	if bundleConfig.Valid() {
+		flags = append(flags, "--config="+bundleConfig.String())
+		implicits = append(implicits, bundleConfig.Path())
+	}
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        buildAppBundle,
+		Input:       bundleModule,
+		Implicits:   implicits,
+		Output:      outputFile,
+		Description: "app bundle",
+		Args: map[string]string{
+			"flags": strings.Join(flags, " "),
+		},
+	})
+}
+
 func TransformJniLibsToJar(
 	ctx android.ModuleContext,
 	outputFile android.WritablePath,
@@ -380,6 +380,10 @@ func (a *AndroidAppImport) generateAndroidBuildActions(ctx android.ModuleContext
 		a.hideApexVariantFromMake = true
 	}
 
+	if proptools.Bool(a.properties.Skip_preprocessed_apk_checks) {
+		checkUnsafeEscapes(ctx, skipPreprocessedApkChecks)
+	}
+
 	if Bool(a.properties.Preprocessed) {
 		if a.properties.Presigned != nil && !*a.properties.Presigned {
 			ctx.ModuleErrorf("Setting preprocessed: true implies presigned: true, so you cannot set presigned to false")
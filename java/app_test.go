@@ -127,6 +127,124 @@ func TestAppSplits(t *testing.T) {
 	android.AssertPathsRelativeToTopEquals(t, `OutputFiles("")`, expectedOutputs, outputFiles)
 }
 
+func TestAppUnsignedApk(t *testing.T) {
+	t.Parallel()
+	ctx := testApp(t, `
+				android_app {
+					name: "foo",
+					srcs: ["a.java"],
+					sdk_version: "current"
+				}`)
+
+	foo := ctx.ModuleForTests(t, "foo", "android_common")
+
+	unsigned := foo.Output("unsigned/foo.apk")
+	android.AssertStringEquals(t, "unsigned apk rule", zipalign.String(), unsigned.Rule.String())
+	android.AssertPathRelativeToTopEquals(t, "unsigned apk input",
+		"out/soong/.intermediates/foo/android_common/foo-unsigned.apk", unsigned.Input)
+
+	outputFiles := foo.OutputFiles(ctx, t, ".apk.unsigned")
+	android.AssertPathsRelativeToTopEquals(t, `OutputFiles(".apk.unsigned")`,
+		[]string{"out/soong/.intermediates/foo/android_common/unsigned/foo.apk"}, outputFiles)
+
+	appInfo, _ := android.OtherModuleProvider(ctx, foo.Module(), AppInfoProvider)
+	android.AssertPathRelativeToTopEquals(t, "AppInfo.UnsignedAlignedApk",
+		"out/soong/.intermediates/foo/android_common/unsigned/foo.apk", appInfo.UnsignedAlignedApk)
+}
+
+func TestAppBundle(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureAddFile("bundle_config.json", nil),
+	).RunTestWithBp(t, `
+		android_app {
+			name: "foo",
+			srcs: ["a.java"],
+			sdk_version: "current",
+			app_bundle: {
+				enabled: true,
+				config: "bundle_config.json",
+			},
+		}
+
+		android_app {
+			name: "bar",
+			srcs: ["a.java"],
+			sdk_version: "current",
+		}`)
+
+	foo := result.ModuleForTests(t, "foo", "android_common")
+
+	unsigned := foo.Output("unsigned/foo.aab")
+	android.AssertPathRelativeToTopEquals(t, "app bundle input",
+		"out/soong/.intermediates/foo/android_common/base.zip", unsigned.Input)
+	android.AssertStringDoesContain(t, "app bundle config", unsigned.Args["flags"],
+		"--config=bundle_config.json")
+
+	signed := foo.Output("foo.aab")
+	android.AssertStringEquals(t, "signed app bundle rule", signAppBundle.String(), signed.Rule.String())
+	android.AssertStringEquals(t, "signed app bundle min sdk version", "10000", signed.Args["minSdkVersion"])
+	android.AssertStringDoesContain(t, "signed app bundle certificates", signed.Args["certificates"],
+		"--cert build/make/target/product/security/testkey.x509.pem --key build/make/target/product/security/testkey.pk8")
+
+	outputFiles := foo.OutputFiles(result.TestContext, t, ".aab")
+	android.AssertPathsRelativeToTopEquals(t, `OutputFiles(".aab")`,
+		[]string{"out/soong/.intermediates/foo/android_common/foo.aab"}, outputFiles)
+
+	bar := result.ModuleForTests(t, "bar", "android_common")
+	if bar.MaybeOutput("bar.aab").Rule != nil {
+		t.Errorf("expected no app bundle for bar")
+	}
+}
+
+func TestAppOptimizeResources(t *testing.T) {
+	t.Parallel()
+	ctx := testApp(t, `
+				android_app {
+					name: "foo",
+					srcs: ["a.java"],
+					sdk_version: "current",
+					optimize: {
+						resources: {
+							collapse_keys: true,
+							shorten_paths: true,
+							sparse_encoding: true,
+						},
+					},
+				}
+
+				android_app {
+					name: "bar",
+					srcs: ["a.java"],
+					sdk_version: "current",
+				}`)
+
+	foo := ctx.ModuleForTests(t, "foo", "android_common")
+	optimize := foo.Rule("aapt2Optimize")
+	android.AssertPathRelativeToTopEquals(t, "aapt2 optimize input",
+		"out/soong/.intermediates/foo/android_common/package-res.apk", optimize.Input)
+	android.AssertStringEquals(t, "aapt2 optimize flags",
+		"--collapse-resource-names --enable-sparse-encoding --shorten-resource-paths "+
+			"--resource-path-shortening-map out/soong/.intermediates/foo/android_common/aapt2_optimize/resource-path-map.txt",
+		optimize.Args["flags"])
+
+	combine := foo.Output("foo-unsigned.apk")
+	android.AssertPathsRelativeToTopEquals(t, "combined apk inputs", []string{
+		"out/soong/.intermediates/foo/android_common/aapt2_optimize/package-res.apk",
+	}, combine.Inputs[1:2])
+
+	appInfo, _ := android.OtherModuleProvider(ctx, foo.Module(), AppInfoProvider)
+	android.AssertPathRelativeToTopEquals(t, "AppInfo.ResourcePathShorteningMap",
+		"out/soong/.intermediates/foo/android_common/aapt2_optimize/resource-path-map.txt",
+		appInfo.ResourcePathShorteningMap)
+
+	bar := ctx.ModuleForTests(t, "bar", "android_common")
+	if bar.MaybeRule("aapt2Optimize").Rule != nil {
+		t.Errorf("expected no aapt2 optimize rule for bar")
+	}
+}
+
 func TestPlatformAPIs(t *testing.T) {
 	t.Parallel()
 	testJava(t, `
@@ -2190,6 +2308,112 @@ func TestJNIPackaging(t *testing.T) {
 	}
 }
 
+func TestAppAbiSplits(t *testing.T) {
+	t.Parallel()
+	ctx, _ := testJava(t, cc.GatherRequiredDepsForTest(android.Android)+`
+		cc_library {
+			name: "libjni",
+			system_shared_libs: [],
+			stl: "none",
+			sdk_version: "current",
+		}
+
+		android_app {
+			name: "app",
+			jni_libs: ["libjni"],
+			use_embedded_native_libs: true,
+			compile_multilib: "both",
+			abi_splits: true,
+			package_splits: ["hdpi"],
+			sdk_version: "current",
+		}
+
+		android_test {
+			name: "test",
+			jni_libs: ["libjni"],
+			compile_multilib: "both",
+			abi_splits: true,
+			sdk_version: "current",
+		}
+		`)
+
+	app := ctx.ModuleForTests(t, "app", "android_common")
+
+	if jniLibZip := app.MaybeOutput("jnilibs.zip"); jniLibZip.Rule != nil {
+		t.Errorf("expected no JNI libraries in the base APK")
+	}
+
+	for _, abi := range []string{"arm64-v8a", "armeabi-v7a"} {
+		jniJar := app.Output("abi_splits/" + abi + "/jnilibs.zip")
+		android.AssertStringDoesContain(t, "JNI jar args", jniJar.Args["jarArgs"], "-P lib/"+abi)
+
+		splitResources := app.Output("abi_splits/" + abi + "/resources.apk")
+		android.AssertStringDoesContain(t, "split resources base APK",
+			splitResources.Args["baseApk"], "package-res.apk")
+
+		manifest := android.ContentFromFileRuleForTests(t, ctx,
+			app.Output("abi_splits/"+abi+"/AndroidManifest.xml.in"))
+		android.AssertStringDoesContain(t, "split manifest", manifest,
+			`split="config.`+strings.ReplaceAll(abi, "-", "_")+`"`)
+	}
+
+	expectedSplits := map[string]string{
+		"hdpi":        "out/soong/.intermediates/app/android_common/app_hdpi.apk",
+		"arm64_v8a":   "out/soong/.intermediates/app/android_common/app_arm64_v8a.apk",
+		"armeabi_v7a": "out/soong/.intermediates/app/android_common/app_armeabi_v7a.apk",
+	}
+	for suffix, expected := range expectedSplits {
+		outputFiles := app.OutputFiles(ctx, t, ".split."+suffix)
+		android.AssertPathsRelativeToTopEquals(t, `OutputFiles(".split.`+suffix+`")`,
+			[]string{expected}, outputFiles)
+	}
+
+	splitsZipInputs := android.PathsRelativeToTop(app.Output("app-splits.zip").Implicits)
+	android.AssertStringListContains(t, "split APKs zip inputs", splitsZipInputs,
+		"out/soong/.intermediates/app/android_common/app.apk")
+	for _, expected := range expectedSplits {
+		android.AssertStringListContains(t, "split APKs zip inputs", splitsZipInputs, expected)
+	}
+	android.AssertPathsRelativeToTopEquals(t, `OutputFiles(".splits.zip")`,
+		[]string{"out/soong/.intermediates/app/android_common/app-splits.zip"},
+		app.OutputFiles(ctx, t, ".splits.zip"))
+	android.AssertStringListContains(t, "split APKs zip dist", app.DistsForTests(ctx),
+		"apps_only out/soong/.intermediates/app/android_common/app-splits.zip:app-splits.zip")
+
+	// Tests install the split APKs together with the base APK.
+	test := ctx.ModuleForTests(t, "test", "android_common")
+	testConfig := test.Output("test.config")
+	android.AssertStringDoesContain(t, "test config", testConfig.Args["extraConfigs"],
+		`<option name="split-apk-file-names" value="test.apk,test_arm64_v8a.apk,test_armeabi_v7a.apk" />`)
+	android.AssertStringListContains(t, "test data",
+		android.PathsRelativeToTop(test.Module().(*AndroidTest).data),
+		"out/soong/.intermediates/test/android_common/test_arm64_v8a.apk")
+}
+
+func TestAppAbiSplitsRequireEmbeddedJniLibs(t *testing.T) {
+	t.Parallel()
+	android.GroupFixturePreparers(
+		prepareForJavaTest,
+		cc.PrepareForTestWithCcDefaultModules,
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`abi_splits: requires the JNI libraries to be embedded in the APK`)).
+		RunTestWithBp(t, `
+		cc_library {
+			name: "libjni",
+			system_shared_libs: [],
+			stl: "none",
+			sdk_version: "current",
+		}
+
+		android_app {
+			name: "app",
+			jni_libs: ["libjni"],
+			abi_splits: true,
+			sdk_version: "current",
+		}
+		`)
+}
+
 func TestJNISDK(t *testing.T) {
 	t.Parallel()
 	ctx, _ := testJava(t, cc.GatherRequiredDepsForTest(android.Android)+`
@@ -2666,6 +2890,64 @@ func TestInstrumentationTargetOverridden(t *testing.T) {
 	}
 }
 
+func TestInstrumentationResourceNamespacing(t *testing.T) {
+	t.Parallel()
+	result := PrepareForTestWithJavaDefaultModules.RunTestWithBp(t, `
+		android_app {
+			name: "foo",
+			srcs: ["a.java"],
+			sdk_version: "current",
+		}
+
+		android_test {
+			name: "bar",
+			srcs: ["b.java"],
+			instrumentation_for: "foo",
+			instrumentation_resource_namespacing: true,
+			sdk_version: "current",
+		}
+		`)
+
+	fooResources := result.ModuleForTests(t, "foo", "android_common").Output("package-res.apk").Output
+	res := result.ModuleForTests(t, "bar", "android_common").Output("package-res.apk")
+	android.AssertStringDoesContain(t, "aapt2 link flags", res.Args["flags"], "-I "+fooResources.String())
+	android.AssertStringDoesContain(t, "aapt2 link flags", res.Args["flags"], "--package-id 0x80")
+	android.AssertStringListContains(t, "aapt2 link implicits",
+		android.PathsRelativeToTop(res.Implicits), android.PathRelativeToTop(fooResources))
+}
+
+func TestInstrumentationResourceNamespacingErrors(t *testing.T) {
+	t.Parallel()
+	PrepareForTestWithJavaDefaultModules.
+		ExtendWithErrorHandler(android.FixtureExpectsAllErrorsToMatchAPattern([]string{
+			`module "bar".*instrumentation_resource_namespacing: requires instrumentation_for to be set`,
+			`module "baz".*aaptflags: --package-id can not be set with instrumentation_resource_namespacing`,
+		})).
+		RunTestWithBp(t, `
+		android_app {
+			name: "foo",
+			srcs: ["a.java"],
+			sdk_version: "current",
+		}
+
+		android_test {
+			name: "bar",
+			srcs: ["b.java"],
+			instrumentation_resource_namespacing: true,
+			sdk_version: "current",
+		}
+
+		android_test {
+			name: "baz",
+			srcs: ["b.java"],
+			instrumentation_for: "foo",
+			instrumentation_resource_namespacing: true,
+			aaptflags: ["--package-id 0x81"],
+			sdk_version: "current",
+		}
+		`)
+}
+
 func TestOverrideAndroidApp(t *testing.T) {
 	t.Parallel()
 	result := PrepareForTestWithJavaDefaultModules.RunTestWithBp(
@@ -3529,6 +3811,54 @@ func TestUsesLibraries(t *testing.T) {
 		"--product-packages=out/soong/.intermediates/app/android_common/dexpreopt/app/product_packages.txt")
 }
 
+func TestUsesLibrariesRelaxed(t *testing.T) {
+	t.Parallel()
+	bp := `
+		android_app {
+			name: "app",
+			srcs: ["a.java"],
+			sdk_version: "current",
+			enforce_uses_libs: true,
+		}
+
+		android_app {
+			name: "relaxed_app",
+			srcs: ["a.java"],
+			sdk_version: "current",
+			enforce_uses_libs: true,
+			relax_uses_libs_check: true,
+		}
+
+		android_app {
+			name: "relaxed_by_product_app",
+			srcs: ["a.java"],
+			sdk_version: "current",
+			enforce_uses_libs: true,
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.RelaxUsesLibraryCheckModules = []string{"relaxed_by_product_app"}
+		}),
+	).RunTestWithBp(t, bp)
+
+	verifyCmd := func(name string) string {
+		return result.ModuleForTests(t, name, "android_common").Rule("verify_uses_libraries").RuleParams.Command
+	}
+	android.AssertStringDoesNotContain(t, "app verify cmd", verifyCmd("app"), "--enforce-uses-libraries-warn")
+	android.AssertStringDoesContain(t, "relaxed_app verify cmd", verifyCmd("relaxed_app"), "--enforce-uses-libraries-warn")
+	android.AssertStringDoesContain(t, "relaxed_by_product_app verify cmd", verifyCmd("relaxed_by_product_app"), "--enforce-uses-libraries-warn")
+
+	// Only the relaxed modules are included in the report.
+	report := result.SingletonForTests(t, "verify_uses_libraries_relaxed").Output("verify_uses_libraries/verify_uses_libraries_relaxed.txt")
+	android.AssertPathsRelativeToTopEquals(t, "report inputs", []string{
+		"out/soong/.intermediates/relaxed_app/android_common/enforce_uses_libraries.status",
+		"out/soong/.intermediates/relaxed_by_product_app/android_common/enforce_uses_libraries.status",
+	}, report.Implicits)
+}
+
 func TestDexpreoptBcp(t *testing.T) {
 	t.Parallel()
 	bp := `
@@ -4795,6 +5125,54 @@ func TestTestConfigTemplate(t *testing.T) {
 		parse_options(rule.Args["extraTestRunnerConfigs"]))
 }
 
+func TestAndroidTestConfigFromAnnotations(t *testing.T) {
+	t.Parallel()
+	ctx := testApp(t, `
+		android_test {
+			name: "android-test",
+			srcs: ["a.java"],
+			sdk_version: "current",
+			stem: "AndroidTests",
+			test_suites: ["device-tests", "general-tests"],
+			test_config_from_annotations: true,
+			test_options: {
+				test_runner_options: [
+					{
+						name: "exclude-annotation",
+						value: "androidx.test.filters.FlakyTest",
+					},
+				],
+			},
+		}
+	`)
+
+	test := ctx.ModuleForTests(t, "android-test", "android_common")
+	rule := test.Rule("autogenInstrumentationTestFromAnnotations")
+	android.AssertPathRelativeToTopEquals(t, "test config", "out/soong/.intermediates/android-test/android_common/android-test.config", rule.Output)
+	android.AssertStringEquals(t, "classes jar", test.Module().(*AndroidTest).implementationJarFile.String(), rule.Args["classesJar"])
+	android.AssertStringEquals(t, "apk name", "AndroidTests.apk", rule.Args["apkName"])
+	android.AssertStringEquals(t, "test suites", "--test-suite device-tests --test-suite general-tests", rule.Args["testSuites"])
+	android.AssertStringDoesContain(t, "extra test runner configs", rule.Args["extraTestRunnerConfigs"],
+		`--extra-test-runner-config '<option name="exclude-annotation" value="androidx.test.filters.FlakyTest" />'`)
+	if test.MaybeRule("autogenInstrumentationTest").Rule != nil {
+		t.Errorf("expected the test config not to be generated from the template")
+	}
+
+	android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureAddTextFile("AndroidTestTemplate.xml", ""),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		"can not be used with test_config_template")).RunTestWithBp(t, `
+		android_test {
+			name: "android-test",
+			srcs: ["a.java"],
+			sdk_version: "current",
+			test_config_from_annotations: true,
+			test_config_template: "AndroidTestTemplate.xml",
+		}
+	`)
+}
+
 func TestAppStem(t *testing.T) {
 	t.Parallel()
 	ctx := testApp(t, `
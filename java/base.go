@@ -48,7 +48,9 @@ import (
 // Properties that are common to most Java modules, i.e. whether it's a host or device module.
 type CommonProperties struct {
 	// list of source files used to compile the Java module.  May be .java, .kt, .logtags, .proto,
-	// or .aidl files.
+	// or .aidl files.  Entries starting with "!" are negative glob patterns, e.g. "!**/internal/**",
+	// that are matched against the path relative to the module directory of every source file,
+	// including sources listed explicitly and sources from filegroups.
 	Srcs []string `android:"path,arch_variant"`
 
 	// list Kotlin of source files containing Kotlin code that should be treated as common code in
@@ -75,6 +77,13 @@ type CommonProperties struct {
 	// list of files that should be excluded from java_resources and java_resource_dirs
 	Exclude_java_resources []string `android:"path,arch_variant"`
 
+	// path prefix to strip from the path of each file in java_resources in the jar.  The path of a
+	// source file is relative to the module directory, the path of a file from a filegroup is
+	// relative to the path property of the filegroup and the path of a file generated by another
+	// module, e.g. a genrule, is relative to the output directory of that module.  It is an error
+	// for a file in java_resources not to be under the prefix.
+	Java_resource_strip_prefix *string
+
 	// Same as java_resources, but modules added here will use the device variant. Can be useful
 	// for making a host test that tests the contents of a device built app.
 	Device_common_java_resources proptools.Configurable[[]string] `android:"path_device_common"`
@@ -90,19 +99,38 @@ type CommonProperties struct {
 	// list of module-specific flags that will be used for kotlinc compiles
 	Kotlincflags []string `android:"arch_variant"`
 
-	// Kotlin language version to target. Currently only 1.9 and 2 are supported.
+	// Kotlin language version to target, one of 1.9, 2.0, 2.1 or 2.  2 targets the default 2.x
+	// version of kotlinc.  Defaults to the RELEASE_KOTLIN_LANG_VERSION build flag.
 	// See kotlinc's `-language-version` flag.
 	Kotlin_lang_version *string
 
+	// Kotlin API version to target, one of 1.9, 2.0 or 2.1.  Must not be newer than
+	// kotlin_lang_version.  Defaults to the language version.
+	// See kotlinc's `-api-version` flag.
+	Kotlin_api_version *string
+
 	// list of java libraries that will be in the classpath
 	Libs []string `android:"arch_variant"`
 
 	// list of java libraries that will be compiled into the resulting jar
 	Static_libs proptools.Configurable[[]string] `android:"arch_variant"`
 
+	// If true, fail the build if a class is in more than one of the jars that are merged into the
+	// resulting jar, e.g. in two static_libs, as only the first copy of the class is kept.
+	// Defaults to false, unless the CHECK_DUPLICATE_CLASSES environment variable is set to true.
+	Check_duplicate_classes *bool
+
+	// Classes that may be in more than one of the merged jars when check_duplicate_classes is
+	// enabled, as class names or globs of class names, e.g. "com.foo.Bar" or "com.foo.*".
+	Allowed_duplicate_classes []string
+
 	// manifest file to be included in resulting jar
 	Manifest *string `android:"path"`
 
+	// Sources compiled for specific Java releases and packaged under META-INF/versions/<release>
+	// to make the jar a multi-release jar.  Only supported for host modules.
+	Multi_release []MultiReleaseProperties
+
 	// if not blank, run jarjar using the specified rules file
 	Jarjar_rules *string `android:"path,arch_variant"`
 
@@ -113,13 +141,36 @@ type CommonProperties struct {
 	// if not blank, used as prefix to generate repackage rule
 	Jarjar_prefix *string
 
-	// Number of shards for jarjar. It needs to be an integer represented as a string.
+	// Number of shards for jarjar. It needs to be a positive integer represented as a string, or
+	// "auto" to pick the number of shards from the number of source files of the module.
 	// TODO(b/383559945) change it to int, once Configurable supports the type.
 	Jarjar_shards proptools.Configurable[string]
 
 	// If not blank, set the java version passed to javac as -source and -target
 	Java_version *string
 
+	// If set to true, compile with javac --enable-preview so that the module can experiment with the
+	// preview language features of the latest supported java version. javac only allows preview
+	// features when targeting its own release, so the module is always compiled as that version.
+	// Preview class files can only run on that exact release, so the module can not be put on the
+	// bootclasspath or in an APEX.
+	Javac_enable_preview *bool
+
+	// Java language features that must not be used by the classes compiled from the sources of
+	// this module, checked on the class files produced by javac.  Supported features are
+	// "lambdas", "records", "sealed_classes", "string_templates" and "switch_patterns".  Modules
+	// on the bootclasspath are also checked for the features banned by the product.
+	Banned_language_features []string
+
+	// If set to true, the build fails if any of the libs dependencies is not referenced by the
+	// classes compiled from the sources of this module.  Dependencies that are only needed to
+	// compile the sources, e.g. for source retention annotations or compile time constants, leave
+	// no references in the classes and must be listed in enforce_used_libs_exemptions.
+	Enforce_used_libs *bool
+
+	// List of libs dependencies that are not checked by enforce_used_libs.
+	Enforce_used_libs_exemptions []string
+
 	// If set to true, allow this module to be dexed and installed on devices.  Has no
 	// effect on host modules, which are always considered installable.
 	Installable *bool
@@ -127,6 +178,11 @@ type CommonProperties struct {
 	// If set to true, include sources used to compile the module in to the final jar
 	Include_srcs *bool
 
+	// If set to true, create a <stem>-sources.jar with the Java and Kotlin sources used to compile
+	// the module, including generated sources, and dist it next to the class jar for every dist
+	// of the module's default output. It is also available with the "-sources.jar" output tag.
+	Dist_srcjar *bool
+
 	// If not empty, classes are restricted to the specified packages and their sub-packages.
 	// This restriction is checked after applying jarjar rules and including static libs.
 	Permitted_packages []string
@@ -134,6 +190,11 @@ type CommonProperties struct {
 	// List of modules to use as annotation processors
 	Plugins []string
 
+	// If true, the annotation processors of this module may access the network when
+	// SOONG_JAVA_NETWORK_SANDBOX is set, which otherwise runs them without network access.  Only
+	// for processors that can't be fixed, network access makes the build slow and unreproducible.
+	Allow_network_access *bool
+
 	// List of modules to use as kotlin plugin
 	Kotlin_plugins []string
 
@@ -217,6 +278,11 @@ type CommonProperties struct {
 	// If true, package the kotlin stdlib into the jar.  Defaults to true.
 	Static_kotlin_stdlib *bool `android:"arch_variant"`
 
+	// If set, this module packages the Kotlin standard library of the given version.  Apps report
+	// an error if more than one version of the Kotlin standard library is statically linked into
+	// them.
+	Kotlin_stdlib_version *string
+
 	// A list of java_library instances that provide additional hiddenapi annotations for the library.
 	Hiddenapi_additional_annotations []string
 
@@ -316,6 +382,10 @@ type DeviceProperties struct {
 
 		// list of flags that will be passed to the AIDL compiler
 		Flags []string
+
+		// The version of the stable AIDL interfaces compiled by this module, returned by their
+		// getInterfaceVersion() method.  Must be a positive integer.
+		Version *string
 	}
 
 	// If true, export a copy of the module as a -hostdex module for host testing.
@@ -355,7 +425,10 @@ type OverridableProperties struct {
 	Stem *string
 
 	// if not blank, set the minimum version of the sdk that the compiled artifacts will run against.
-	// Defaults to sdk_version if not set. See sdk_version for possible values.
+	// Defaults to sdk_version if not set. See sdk_version for possible values.  When this is set to
+	// "apex_inherit", this tracks the min_sdk_version of the APEX the module is built for, so it
+	// doesn't need to be updated when the APEX's min_sdk_version changes.  When the module is not
+	// built for an APEX, "apex_inherit" defaults to sdk_version.
 	Min_sdk_version *string
 }
 
@@ -528,6 +601,9 @@ type Module struct {
 	// list of srcjars that was passed to javac
 	compiledSrcJars android.Paths
 
+	// The jar of the sources of the module, if dist_srcjar is set.
+	sourcesJar android.Path
+
 	// manifest file to use instead of properties.Manifest
 	overrideManifest android.OptionalPath
 
@@ -544,6 +620,9 @@ type Module struct {
 	// will be used by android.IDEInfo struct
 	expandIDEInfoCompiledSrcs []string
 
+	// classpath and Kotlin settings, will be used by android.IDECompileInfo
+	ideCompileInfo android.IdeCompileInfo
+
 	// expanded Jarjar_rules
 	expandJarjarRules android.Path
 
@@ -563,12 +642,18 @@ type Module struct {
 	kytheFiles       android.Paths
 	kytheKotlinFiles android.Paths
 
+	// list of the javac actions, recorded when SOONG_GEN_JAVAC_COMMANDS is set
+	javacCommands []javacCommand
+
 	hideApexVariantFromMake bool
 
 	sdkVersion    android.SdkSpec
 	minSdkVersion android.ApiLevel
 	maxSdkVersion android.ApiLevel
 
+	// The min_sdk_version of the APEX the variant is built for, or nil for the platform variant.
+	apexMinSdkVersion *android.ApiLevel
+
 	sourceExtensions []string
 
 	annoSrcJars android.Paths
@@ -696,6 +781,22 @@ func (j *Module) checkHeadersOnly(ctx android.ModuleContext) {
 	}
 }
 
+// checkJavacEnablePreview makes sure that modules compiled with preview language features are not
+// used on the bootclasspath or in APEXes, where they would have to run on other releases.
+func (j *Module) checkJavacEnablePreview(ctx android.ModuleContext) {
+	if !Bool(j.properties.Javac_enable_preview) {
+		return
+	}
+	if android.InList(ctx.ModuleName(), ctx.Config().BootJars()) {
+		ctx.PropertyErrorf("javac_enable_preview", "can not be used by modules on the bootclasspath")
+	}
+	apexInfo, _ := android.ModuleProvider(ctx, android.ApexInfoProvider)
+	if !apexInfo.IsForPlatform() {
+		ctx.PropertyErrorf("javac_enable_preview", "can not be used by modules in APEXes, but %s is in %s",
+			ctx.ModuleName(), apexInfo.BaseApexName)
+	}
+}
+
 func (j *Module) addHostProperties() {
 	j.AddProperties(
 		&j.properties,
@@ -715,13 +816,23 @@ func (j *Module) addHostAndDeviceProperties() {
 	)
 }
 
+// PrimaryDeviceArchProperties makes the arch_variant dex properties of device modules, which are
+// built for the common architecture, take the values of the primary device architecture.
+func (j *Module) PrimaryDeviceArchProperties() []interface{} {
+	return []interface{}{&j.dexer.dexProperties}
+}
+
+var _ android.PrimaryDeviceArchPropertiesModule = (*Module)(nil)
+
 // provideHiddenAPIPropertyInfo populates a HiddenAPIPropertyInfo from hidden API properties and
-// makes it available through the hiddenAPIPropertyInfoProvider.
-func (j *Module) provideHiddenAPIPropertyInfo(ctx android.ModuleContext) {
+// makes it available through the hiddenAPIPropertyInfoProvider. The additionalFlagFiles are
+// flag files generated by the module, which are added to those from the properties.
+func (j *Module) provideHiddenAPIPropertyInfo(ctx android.ModuleContext, additionalFlagFiles FlagFilesByCategory) {
 	hiddenAPIInfo := newHiddenAPIPropertyInfo()
 
 	// Populate with flag file paths from the properties.
 	hiddenAPIInfo.extractFlagFilesFromProperties(ctx, &j.deviceProperties.HiddenAPIFlagFileProperties)
+	hiddenAPIInfo.FlagFilesByCategory.append(additionalFlagFiles)
 
 	// Populate with package rules from the properties.
 	hiddenAPIInfo.extractPackageRulesFromProperties(&j.deviceProperties.HiddenAPIPackageProperties)
@@ -738,7 +849,13 @@ func setOutputFiles(ctx android.ModuleContext, m Module) {
 	if m.dexer.proguardDictionary.Valid() {
 		ctx.SetOutputFiles(android.Paths{m.dexer.proguardDictionary.Path()}, ".proguard_map")
 	}
+	if m.dexer.annotatedProguardFlags.Valid() {
+		ctx.SetOutputFiles(android.Paths{m.dexer.annotatedProguardFlags.Path()}, ".proguard_flags_annotated")
+	}
 	ctx.SetOutputFiles(m.properties.Generated_srcjars, ".generated_srcjars")
+	if m.sourcesJar != nil {
+		ctx.SetOutputFiles(android.Paths{m.sourcesJar}, sourcesJarTag)
+	}
 }
 
 func InitJavaModule(module android.DefaultableModule, hod android.HostOrDeviceSupported) {
@@ -811,12 +928,33 @@ func (j *Module) SystemModules() string {
 }
 
 func (j *Module) MinSdkVersion(ctx android.EarlyModuleContext) android.ApiLevel {
+	if j.inheritsApexMinSdkVersion() {
+		if j.apexMinSdkVersion != nil {
+			return *j.apexMinSdkVersion
+		}
+		return j.SdkVersion(ctx).ApiLevel
+	}
 	if j.overridableProperties.Min_sdk_version != nil {
 		return android.ApiLevelFrom(ctx, *j.overridableProperties.Min_sdk_version)
 	}
 	return j.SdkVersion(ctx).ApiLevel
 }
 
+// inheritsApexMinSdkVersion returns true if min_sdk_version is "apex_inherit".
+func (j *Module) inheritsApexMinSdkVersion() bool {
+	return String(j.overridableProperties.Min_sdk_version) == "apex_inherit"
+}
+
+// setApexMinSdkVersion records the min_sdk_version of the APEX the variant is built for, which is
+// used as the min_sdk_version of the module if it is "apex_inherit".  It must be called before the
+// min_sdk_version of the module is used.
+func (j *Module) setApexMinSdkVersion(ctx android.ModuleContext) {
+	if apexInfo, _ := android.ModuleProvider(ctx, android.ApexInfoProvider); !apexInfo.IsForPlatform() {
+		minSdkVersion := apexInfo.MinSdkVersion
+		j.apexMinSdkVersion = &minSdkVersion
+	}
+}
+
 func (j *Module) GetDeviceProperties() *DeviceProperties {
 	return &j.deviceProperties
 }
@@ -949,8 +1087,49 @@ func (j *Module) deps(ctx android.BottomUpMutatorContext) {
 	}
 }
 
+// pathsForJavaSrcsExcludes resolves srcs to paths with the sources matching excludes removed, like
+// PathsForModuleSrcExcludes.  Entries in srcs starting with "!" are negative patterns, which are
+// also matched against the module directory relative path of every resolved source file, so they
+// remove matching sources that were listed explicitly or came from filegroups.  Generated sources
+// are not matched.
+func pathsForJavaSrcsExcludes(ctx android.ModuleContext, srcs, excludes []string) android.Paths {
+	var includes, negatives []string
+	for _, src := range srcs {
+		if pattern, ok := strings.CutPrefix(src, "!"); ok {
+			negatives = append(negatives, pattern)
+		} else {
+			includes = append(includes, src)
+		}
+	}
+	if len(negatives) == 0 {
+		return android.PathsForModuleSrcExcludes(ctx, srcs, excludes)
+	}
+
+	srcFiles := android.PathsForModuleSrcExcludes(ctx, includes, append(slices.Clone(excludes), negatives...))
+	for _, pattern := range negatives {
+		srcFiles = slices.DeleteFunc(srcFiles, func(src android.Path) bool {
+			if _, ok := src.(android.SourcePath); !ok {
+				return false
+			}
+			rel, err := filepath.Rel(ctx.ModuleDir(), src.String())
+			if err != nil {
+				return false
+			}
+			match, err := pathtools.Match(pattern, rel)
+			if err != nil {
+				ctx.PropertyErrorf("srcs", "invalid pattern %q: %s", "!"+pattern, err)
+			}
+			return match
+		})
+	}
+	return srcFiles
+}
+
 func hasSrcExt(srcs []string, ext string) bool {
 	for _, src := range srcs {
+		if strings.HasPrefix(src, "!") {
+			continue
+		}
 		if filepath.Ext(src) == ext {
 			return true
 		}
@@ -1022,6 +1201,14 @@ func (j *Module) aidlFlags(ctx android.ModuleContext, aidlPreprocess android.Opt
 		flags = append(flags, "--transaction_names")
 	}
 
+	if version := j.deviceProperties.Aidl.Version; version != nil {
+		if v, err := strconv.Atoi(*version); err != nil || v <= 0 {
+			ctx.PropertyErrorf("aidl.version", "must be a positive integer, got %q", *version)
+		} else {
+			flags = append(flags, "--version="+*version)
+		}
+	}
+
 	if Bool(j.deviceProperties.Aidl.Enforce_permissions) {
 		exceptions := j.deviceProperties.Aidl.Enforce_permissions_exceptions
 		j.ignoredAidlPermissionList = android.PathsForModuleSrcExcludes(ctx, exceptions, nil)
@@ -1039,6 +1226,13 @@ func (j *Module) collectBuilderFlags(ctx android.ModuleContext, deps deps) javaB
 
 	// javaVersion flag.
 	flags.javaVersion = getJavaVersion(ctx, String(j.properties.Java_version), android.SdkContext(j))
+	if Bool(j.properties.Javac_enable_preview) {
+		if j.properties.Java_version != nil && flags.javaVersion != javaPreviewVersion {
+			ctx.PropertyErrorf("javac_enable_preview", "requires java_version %s, got %s",
+				javaPreviewVersion, flags.javaVersion)
+		}
+		flags.javaVersion = javaPreviewVersion
+	}
 
 	epEnabled := j.properties.Errorprone.Enabled
 	if (ctx.Config().RunErrorProne() && epEnabled == nil) || Bool(epEnabled) {
@@ -1067,6 +1261,8 @@ func (j *Module) collectBuilderFlags(ctx android.ModuleContext, deps deps) javaB
 
 	flags.processors = append(flags.processors, deps.processorClasses...)
 	flags.processors = android.FirstUniqueStrings(flags.processors)
+	flags.networkSandbox = ctx.Config().IsEnvTrue("SOONG_JAVA_NETWORK_SANDBOX") &&
+		!proptools.Bool(j.properties.Allow_network_access)
 
 	if len(flags.bootClasspath) == 0 && ctx.Host() && !flags.javaVersion.usesJavaModules() &&
 		decodeSdkDep(ctx, android.SdkContext(j)).hasStandardLibs() {
@@ -1119,6 +1315,18 @@ func (j *Module) collectJavacFlags(
 	}
 	javacFlags = append(javacFlags, "-Xlint:-dep-ann")
 
+	if Bool(j.properties.Javac_enable_preview) {
+		javacFlags = append(javacFlags, "--enable-preview")
+	}
+
+	if len(srcFiles.FilterByExt(".java")) > 0 || len(srcFiles.FilterByExt(".srcjar")) > 0 {
+		werrorStatus := javacWerrorStatus(ctx, j.properties.Javacflags)
+		if werrorStatus == javacWerrorEnforced {
+			javacFlags = append(javacFlags, "-Werror")
+		}
+		android.SetProvider(ctx, JavacWerrorInfoProvider, JavacWerrorInfo{Status: werrorStatus})
+	}
+
 	if flags.javaVersion.usesJavaModules() {
 		javacFlags = append(javacFlags, j.properties.Openjdk9.Javacflags...)
 	} else if len(j.properties.Openjdk9.Javacflags) > 0 {
@@ -1194,7 +1402,7 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 		ctx.PropertyErrorf("openjdk9.srcs", "JDK version defaults to higher than 9")
 	}
 
-	srcFiles := android.PathsForModuleSrcExcludes(ctx, j.properties.Srcs, j.properties.Exclude_srcs)
+	srcFiles := pathsForJavaSrcsExcludes(ctx, j.properties.Srcs, j.properties.Exclude_srcs)
 	j.sourceExtensions = []string{}
 	for _, ext := range []string{".kt", ".proto", ".aidl", ".java", ".logtags"} {
 		if hasSrcExt(srcFiles.Strings(), ext) {
@@ -1229,6 +1437,7 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 	if j.properties.Jarjar_rules != nil {
 		j.expandJarjarRules = android.PathForModuleSrc(ctx, *j.properties.Jarjar_rules)
 	}
+	j.addBootclasspathJarjarRules(ctx)
 
 	jarName := j.Stem() + ".jar"
 
@@ -1269,6 +1478,9 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 	// final R classes from the app.
 	flags.classpath = append(android.CopyOf(extraClasspathJars), flags.classpath...)
 
+	// Collect the classpath for the IDE metadata.
+	j.ideCompileInfo.Classpath = append(flags.bootClasspath.Strings(), flags.classpath.Strings()...)
+
 	j.aconfigCacheFiles = append(deps.aconfigProtoFiles, j.properties.Aconfig_Cache_files...)
 
 	var localImplementationJars android.Paths
@@ -1336,25 +1548,8 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 		kotlincFlags := j.properties.Kotlincflags
 		CheckKotlincFlags(ctx, kotlincFlags)
 
-		// Available kotlin versions can be found at
-		// https://github.com/JetBrains/kotlin/blob/master/compiler/util/src/org/jetbrains/kotlin/config/LanguageVersionSettings.kt#L560
-		// in the `LanguageVersion` class.
-		// For now, avoid targeting language versions directly, as we'd like to kee our source
-		// code version aligned as much as possible. Ideally, after defaulting to "2", we
-		// can remove the "1.9" option entirely, or at least make it emit a warning.
-		kotlin_default_lang_version := "1.9"
-		if build_flag_lang_version, ok := ctx.Config().GetBuildFlag("RELEASE_KOTLIN_LANG_VERSION"); ok {
-			kotlin_default_lang_version = build_flag_lang_version
-		}
-		kotlin_lang_version := proptools.StringDefault(j.properties.Kotlin_lang_version, kotlin_default_lang_version)
-		switch kotlin_lang_version {
-		case "1.9":
-			kotlincFlags = append(kotlincFlags, "-language-version 1.9")
-		case "2":
-			kotlincFlags = append(kotlincFlags, "-Xsuppress-version-warnings", "-Xconsistent-data-class-copy-visibility")
-		default:
-			ctx.PropertyErrorf("kotlin_lang_version", "Must be one of `1.9` or `2`")
-		}
+		kotlinLangVersion, kotlinApiVersion, kotlinVersionFlags := j.kotlinVersions(ctx)
+		kotlincFlags = append(kotlincFlags, kotlinVersionFlags...)
 
 		// Workaround for KT-46512
 		kotlincFlags = append(kotlincFlags, "-Xsam-conversions=class")
@@ -1378,23 +1573,68 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 			flags.kotlincFlags += "$kotlincFlags"
 		}
 
+		j.ideCompileInfo.Kotlin = &android.IdeKotlinInfo{
+			LanguageVersion: kotlinLangVersion,
+			ApiVersion:      kotlinApiVersion,
+			Flags:           slices.Clone(kotlincFlags),
+			Plugins:         deps.kotlinPlugins.Strings(),
+		}
+
 		// Collect common .kt files for AIDEGen
 		j.expandIDEInfoCompiledSrcs = append(j.expandIDEInfoCompiledSrcs, kotlinCommonSrcFiles.Strings()...)
 
 		flags.kotlincClasspath = append(flags.kotlincClasspath, flags.bootClasspath...)
 		flags.kotlincClasspath = append(flags.kotlincClasspath, flags.classpath...)
 
-		if len(flags.processorPath) > 0 {
+		if len(deps.kspProcessorPath) > 0 {
+			// Use KSP for the annotation processors that support it. It runs before kapt so that the
+			// sources it generates are visible to the kapt stubs and the other annotation processors.
+			kspSrcJar := android.PathForModuleOut(ctx, "ksp", "ksp-sources.jar")
+			kspResJar := android.PathForModuleOut(ctx, "ksp", "ksp-res.jar")
+			kotlinKsp(ctx, kspSrcJar, kspResJar, uniqueSrcFiles, srcJars, deps.kspProcessorPath,
+				deps.kspProcessorClasses, kotlinLangVersion, kotlinApiVersion, flags)
+			srcJars = append(srcJars, kspSrcJar)
+			localImplementationJars = append(localImplementationJars, kspResJar)
+			flags.processors = android.RemoveListFromList(flags.processors, deps.kspProcessorClasses)
+		}
+
+		// Leave the annotation processors that are incompatible with turbine to javac, which runs them
+		// on the kapt stubs.
+		javacAptFlags := flags
+		javacAptFlags.processorPath = deps.turbineIncompatibleProcessorPath
+		javacAptFlags.processors = android.FilterListPred(flags.processors, func(processor string) bool {
+			return android.InList(processor, deps.turbineIncompatibleProcessorClasses)
+		})
+		if len(deps.kspProcessorPath) > 0 || len(deps.turbineIncompatibleProcessorPath) > 0 {
+			// Only the jars of the plugins that kapt runs are kept, as they may share jars with the
+			// plugins that are run by KSP or javac.
+			flags.processorPath = deps.kaptProcessorPath
+			flags.processors = android.RemoveListFromList(flags.processors, deps.turbineIncompatibleProcessorClasses)
+		}
+
+		if len(flags.processorPath) > 0 || len(javacAptFlags.processors) > 0 {
 			// Use kapt for annotation processing
-			kaptSrcJar := android.PathForModuleOut(ctx, "kapt", "kapt-sources.jar")
-			kaptResJar := android.PathForModuleOut(ctx, "kapt", "kapt-res.jar")
-			kotlinKapt(ctx, kaptSrcJar, kaptResJar, uniqueSrcFiles, kotlinCommonSrcFiles, srcJars, flags)
-			srcJars = append(srcJars, kaptSrcJar)
-			localImplementationJars = append(localImplementationJars, kaptResJar)
-			// Disable annotation processing in javac, it's already been handled by kapt
-			flags.processorPath = nil
-			flags.processors = nil
+			kaptStubsJar := kotlinKaptStubs(ctx, uniqueSrcFiles, kotlinCommonSrcFiles, srcJars, flags)
+			javaSrcFiles := uniqueSrcFiles.FilterByExt(".java")
+			aptSrcJars := append(android.Paths{kaptStubsJar}, srcJars...)
+			if len(flags.processorPath) > 0 {
+				kaptSrcJar := android.PathForModuleOut(ctx, "kapt", "kapt-sources.jar")
+				kaptResJar := android.PathForModuleOut(ctx, "kapt", "kapt-res.jar")
+				TurbineApt(ctx, kaptSrcJar, kaptResJar, javaSrcFiles, aptSrcJars, flags)
+				srcJars = append(srcJars, kaptSrcJar)
+				localImplementationJars = append(localImplementationJars, kaptResJar)
+			}
+			if len(javacAptFlags.processors) > 0 {
+				javacAptSrcJar := android.PathForModuleOut(ctx, "kapt_javac", "kapt-javac-sources.jar")
+				javacAptResJar := android.PathForModuleOut(ctx, "kapt_javac", "kapt-javac-res.jar")
+				kaptJavacApt(ctx, javacAptSrcJar, javacAptResJar, javaSrcFiles, aptSrcJars, javacAptFlags)
+				srcJars = append(srcJars, javacAptSrcJar)
+				localImplementationJars = append(localImplementationJars, javacAptResJar)
+			}
 		}
+		// Disable annotation processing in javac, it's already been handled by KSP and kapt
+		flags.processorPath = nil
+		flags.processors = nil
 
 		kotlinJar := android.PathForModuleOut(ctx, "kotlin", jarName)
 		kotlinHeaderJar := android.PathForModuleOut(ctx, "kotlin_headers", jarName)
@@ -1435,6 +1675,8 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 		var combinedHeaderJarFile android.Path
 		localHeaderJars, combinedHeaderJarFile = j.compileJavaHeader(ctx, uniqueJavaFiles, srcJars, deps, flags, jarName, extraJars)
 		shardingHeaderJars = localHeaderJars
+		flags.errorProneClasspath = errorproneHeaderClasspath(flags.classpath, combinedHeaderJarFile,
+			localHeaderJars, transitiveStaticLibsHeaderJars.ToList())
 
 		var jarjared bool
 		j.headerJarFile, jarjared = j.jarjarIfNecessary(ctx, combinedHeaderJarFile, jarName, "turbine", false)
@@ -1483,6 +1725,12 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 				// We also don't want to run this if errorprone is enabled by default for
 				// this module, or else we could have duplicated errorprone messages.
 				errorproneFlags := enableErrorproneFlags(flags)
+				if len(errorproneFlags.errorProneClasspath) > 0 {
+					// Compile against the combined turbine header jar instead of the header jars
+					// of the static dependencies, the errorprone compile only needs to see the
+					// same API as the regular javac compile.
+					errorproneFlags.classpath = errorproneFlags.errorProneClasspath
+				}
 				errorprone := android.PathForModuleOut(ctx, "errorprone", jarName)
 				errorproneAnnoSrcJar := android.PathForModuleOut(ctx, "errorprone", "anno.srcjar")
 
@@ -1531,8 +1779,30 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 		}
 	}
 
+	if len(j.properties.Multi_release) > 0 {
+		localImplementationJars = append(localImplementationJars,
+			j.compileMultiRelease(ctx, flags, localImplementationJars)...)
+		if ctx.Failed() {
+			return nil
+		}
+	}
+
+	bannedLanguageFeaturesReport := j.checkBannedLanguageFeatures(ctx, localImplementationJars, jarName)
+	proguardFlagsCheck := j.checkProguardFlagsFiles(ctx)
+
 	localImplementationJars = append(localImplementationJars, extraCombinedJars...)
 
+	var unusedLibsCheck android.Path
+	enforceUsedLibs := proptools.Bool(j.properties.Enforce_used_libs)
+	if (unusedDepsReport.enabled(ctx) || enforceUsedLibs) && len(localImplementationJars) > 0 {
+		unusedLibsCheck = buildUnusedDepsReport(ctx, localImplementationJars, enforceUsedLibs,
+			j.properties.Enforce_used_libs_exemptions)
+	}
+
+	if javacCommandsReport.enabled(ctx) {
+		j.writeJavacCommands(ctx)
+	}
+
 	j.srcJarArgs, j.srcJarDeps = resourcePathsToJarArgs(srcFiles), srcFiles
 
 	var includeSrcJar android.WritablePath
@@ -1541,9 +1811,14 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 		TransformResourcesToJar(ctx, includeSrcJar, j.srcJarArgs, j.srcJarDeps)
 	}
 
+	if Bool(j.properties.Dist_srcjar) {
+		j.buildSourcesJar(ctx, srcFiles, srcJars)
+	}
+
 	dirArgs, dirDeps := ResourceDirsToJarArgs(ctx, j.properties.Java_resource_dirs,
 		j.properties.Exclude_java_resource_dirs, j.properties.Exclude_java_resources)
-	fileArgs, fileDeps := ResourceFilesToJarArgs(ctx, j.properties.Java_resources.GetOrDefault(ctx, nil), j.properties.Exclude_java_resources)
+	fileArgs, fileDeps := ResourceFilesToJarArgsWithStripPrefix(ctx, j.properties.Java_resources.GetOrDefault(ctx, nil),
+		j.properties.Exclude_java_resources, proptools.String(j.properties.Java_resource_strip_prefix))
 	fileArgs2, fileDeps2 := ResourceFilesToJarArgs(ctx, j.properties.Device_common_java_resources.GetOrDefault(ctx, nil), nil)
 	fileArgs3, fileDeps3 := ResourceFilesToJarArgs(ctx, j.properties.Device_first_java_resources.GetOrDefault(ctx, nil), nil)
 	fileArgs = slices.Concat(fileArgs, fileArgs2, fileArgs3)
@@ -1619,8 +1894,26 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 		manifest = android.OptionalPathForPath(android.PathForModuleSrc(ctx, *j.properties.Manifest))
 	}
 
+	var manifestValidations android.Paths
+	if len(j.properties.Multi_release) > 0 {
+		manifest, manifestValidations = multiReleaseManifest(ctx, manifest)
+	}
+
+	// The checks of the classes of the module are validations of the combined jar, so that any
+	// dependency on the output file will cause ninja to run them.
+	var checkValidations android.Paths
+	if bannedLanguageFeaturesReport != nil {
+		checkValidations = append(checkValidations, bannedLanguageFeaturesReport)
+	}
+	if unusedLibsCheck != nil {
+		checkValidations = append(checkValidations, unusedLibsCheck)
+	}
+	if proguardFlagsCheck != nil {
+		checkValidations = append(checkValidations, proguardFlagsCheck)
+	}
+
 	// Combine the classes built from sources, any manifests, and any static libraries into
-	// classes.jar. If there is only one input jar this step will be skipped.
+	// classes.jar. If there is only one input jar and nothing to validate this step will be skipped.
 	var outputFile android.Path
 
 	completeStaticLibsImplementationJars := depset.New(depset.PREORDER, localImplementationJars, deps.transitiveStaticLibsImplementationJars)
@@ -1629,7 +1922,7 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 
 	jars = append(jars, extraDepCombinedJars...)
 
-	if len(jars) == 1 && !manifest.Valid() {
+	if len(jars) == 1 && !manifest.Valid() && len(checkValidations) == 0 {
 		// Optimization: skip the combine step as there is nothing to do
 		// TODO(ccross): this leaves any module-info.class files, but those should only come from
 		// prebuilt dependencies until we support modules in the platform build, so there shouldn't be
@@ -1655,8 +1948,10 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 		}
 	} else {
 		combinedJar := android.PathForModuleOut(ctx, "combined", jarName)
-		TransformJarsToJar(ctx, combinedJar, "for javac", jars, manifest,
-			false, nil, nil)
+		validations := append(j.checkDuplicateClasses(ctx, jars, jarName), manifestValidations...)
+		validations = append(validations, checkValidations...)
+		transformJarsToJar(ctx, combinedJar, "for javac", jars, manifest,
+			false, nil, nil, validations)
 		outputFile = combinedJar
 	}
 
@@ -1665,6 +1960,7 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 	if jarjarred {
 		localImplementationJars = android.Paths{jarjarFile}
 		completeStaticLibsImplementationJars = depset.New(depset.PREORDER, localImplementationJars, nil)
+		j.buildJarjarRepackageReport(ctx, outputFile, jarName)
 	}
 	outputFile = jarjarFile
 
@@ -1727,13 +2023,24 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 		// the output file of this module.
 		inputFile := outputFile
 		packageCheckOutputFile := android.PathForModuleOut(ctx, "package-check", jarName)
+		validations := android.Paths{pkgckFile}
+
+		// Updatable boot jars must declare exactly the packages that they contain, so also check
+		// that all of the permitted packages are used.
+		apexBootJars := ctx.Config().ApexBootJars()
+		if apexBootJars.ContainsJar(j.BaseModuleName()) {
+			permittedPackagesDiff := android.PathForModuleOut(ctx, "package-check", "permitted_packages.diff")
+			CheckPermittedPackagesMatch(ctx, permittedPackagesDiff, inputFile, j.properties.Permitted_packages)
+			validations = append(validations, permittedPackagesDiff)
+		}
+
 		ctx.Build(pctx, android.BuildParams{
 			Rule:   android.Cp,
 			Input:  inputFile,
 			Output: packageCheckOutputFile,
 			// Make sure that any dependency on the output file will cause ninja to run the package check
-			// rule.
-			Validation: pkgckFile,
+			// rules.
+			Validations: validations,
 		})
 		outputFile = packageCheckOutputFile
 		localImplementationJars = android.Paths{packageCheckOutputFile}
@@ -1779,7 +2086,7 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 	compileDex := Bool(j.dexProperties.Compile_dex) || Bool(j.properties.Installable)
 
 	if j.shouldInstrument(ctx) && (!ctx.Device() || compileDex) {
-		instrumentedOutputFile := j.instrument(ctx, flags, outputFile, jarName, specs)
+		instrumentedOutputFile := j.instrument(ctx, flags, outputFile, jarName, specs, srcFiles.HasExt(".kt"))
 		completeStaticLibsImplementationJarsToCombine = depset.New(depset.PREORDER, android.Paths{instrumentedOutputFile}, nil)
 		outputFile = instrumentedOutputFile
 	}
@@ -1951,6 +2258,7 @@ func (j *Module) compile(ctx android.ModuleContext, extraSrcJars, extraClasspath
 		AconfigIntermediateCacheOutputPaths: j.aconfigCacheFiles,
 		SdkVersion:                          j.SdkVersion(ctx),
 		OutputFile:                          j.outputFile,
+		KotlinStdlibs:                       collectKotlinStdlibs(ctx, j.properties.Kotlin_stdlib_version),
 	}
 }
 
@@ -1974,6 +2282,22 @@ func collectDepProguardSpecInfo(ctx android.ModuleContext) (transitiveProguardFl
 	return transitiveProguardFlags, transitiveUnconditionalExportedFlags
 }
 
+// proguardFlagsFileOwners returns the depset of the modules that the given proguard flags files of
+// the current module and the proguard flags files of all its transitive deps come from.
+func proguardFlagsFileOwners(ctx android.ModuleContext, files android.Paths) depset.DepSet[ProguardFlagsFileOwner] {
+	var direct []ProguardFlagsFileOwner
+	for _, file := range files {
+		direct = append(direct, ProguardFlagsFileOwner{Module: ctx.ModuleName(), File: file})
+	}
+	var transitive []depset.DepSet[ProguardFlagsFileOwner]
+	ctx.VisitDirectDepsProxy(func(m android.ModuleProxy) {
+		if depProguardInfo, ok := android.OtherModuleProvider(ctx, m, ProguardSpecInfoProvider); ok {
+			transitive = append(transitive, depProguardInfo.ProguardFlagsFileOwners)
+		}
+	})
+	return depset.New(depset.POSTORDER, direct, transitive)
+}
+
 func (j *Module) collectProguardSpecInfo(ctx android.ModuleContext) ProguardSpecInfo {
 	transitiveProguardFlags, transitiveUnconditionalExportedFlags := collectDepProguardSpecInfo(ctx)
 
@@ -1998,6 +2322,7 @@ func (j *Module) collectProguardSpecInfo(ctx android.ModuleContext) ProguardSpec
 			directUnconditionalExportedFlags,
 			transitiveUnconditionalExportedFlags,
 		),
+		ProguardFlagsFileOwners: proguardFlagsFileOwners(ctx, proguardFlagsForThisModule),
 	}
 
 }
@@ -2017,6 +2342,30 @@ func enableErrorproneFlags(flags javaBuilderFlags) javaBuilderFlags {
 	return flags
 }
 
+// errorproneHeaderClasspath returns the classpath for the separate errorprone compile. The header
+// jars of the module and of its static dependencies are replaced with the combined header jar that
+// turbine already produced for them, so that the errorprone compile depends on a single jar
+// instead of on the header jar of each static dependency.
+func errorproneHeaderClasspath(javacClasspath classpath, combinedHeaderJar android.Path,
+	localHeaderJars, staticLibsHeaderJars android.Paths) classpath {
+
+	combined := make(map[android.Path]bool)
+	for _, jar := range localHeaderJars {
+		combined[jar] = true
+	}
+	for _, jar := range staticLibsHeaderJars {
+		combined[jar] = true
+	}
+
+	ret := classpath{combinedHeaderJar}
+	for _, jar := range javacClasspath {
+		if !combined[jar] {
+			ret = append(ret, jar)
+		}
+	}
+	return ret
+}
+
 func (j *Module) compileJavaClasses(ctx android.ModuleContext, jarName string, idx int,
 	srcFiles, srcJars android.Paths, flags javaBuilderFlags, extraJarDeps android.Paths) android.Path {
 
@@ -2031,6 +2380,10 @@ func (j *Module) compileJavaClasses(ctx android.ModuleContext, jarName string, i
 	classes := android.PathForModuleOut(ctx, "javac", jarName)
 	TransformJavaToClasses(ctx, classes, idx, srcFiles, srcJars, annoSrcJar, flags, extraJarDeps)
 
+	if javacCommandsReport.enabled(ctx) {
+		j.recordJavacCommand(ctx, classes, srcFiles, srcJars, flags)
+	}
+
 	if ctx.Config().EmitXrefRules() && ctx.Module() == ctx.PrimaryModule() {
 		extractionFile := android.PathForModuleOut(ctx, kzipName)
 		emitXrefRule(ctx, extractionFile, idx, srcFiles, srcJars, flags, extraJarDeps)
@@ -2099,7 +2452,7 @@ func (j *Module) compileJavaHeader(ctx android.ModuleContext, srcFiles, srcJars
 }
 
 func (j *Module) instrument(ctx android.ModuleContext, flags javaBuilderFlags,
-	classesJar android.Path, jarName string, specs string) android.Path {
+	classesJar android.Path, jarName string, specs string, kotlin bool) android.Path {
 
 	jacocoReportClassesFile := android.PathForModuleOut(ctx, "jacoco-report-classes", jarName)
 	instrumentedJar := android.PathForModuleOut(ctx, "jacoco", jarName)
@@ -2108,6 +2461,8 @@ func (j *Module) instrument(ctx android.ModuleContext, flags javaBuilderFlags,
 
 	j.jacocoReportClassesFile = jacocoReportClassesFile
 
+	jacocoCoverageMetadataFiles(ctx, jacocoReportClassesFile, jarName, kotlin)
+
 	return instrumentedJar
 }
 
@@ -2209,12 +2564,16 @@ func (j *Module) IDEInfo(ctx android.BaseModuleContext, dpInfo *android.IdeInfo)
 	dpInfo.Libs = append(dpInfo.Libs, j.properties.Libs...)
 }
 
+func (j *Module) IDECompileInfo(ctx android.BaseModuleContext, info *android.IdeCompileInfo) {
+	*info = j.ideCompileInfo
+}
+
 func (j *Module) CompilerDeps() []string {
 	return j.compileDepNames
 }
 
 func (j *Module) hasCode(ctx android.ModuleContext) bool {
-	srcFiles := android.PathsForModuleSrcExcludes(ctx, j.properties.Srcs, j.properties.Exclude_srcs)
+	srcFiles := pathsForJavaSrcsExcludes(ctx, j.properties.Srcs, j.properties.Exclude_srcs)
 	return len(srcFiles) > 0 || len(ctx.GetDirectDepsProxyWithTag(staticLibTag)) > 0
 }
 
@@ -2241,6 +2600,11 @@ func (j *Module) MinSdkVersionSupported(ctx android.BaseModuleContext) android.A
 		return android.MinApiLevel
 	}
 
+	// A module that inherits the min_sdk_version of the APEX supports any APEX it is in.
+	if j.inheritsApexMinSdkVersion() {
+		return android.MinApiLevel
+	}
+
 	return minSdkVersion
 }
 
@@ -2460,6 +2824,7 @@ func (j *Module) collectDeps(ctx android.ModuleContext) deps {
 				}
 				deps.aidlIncludeDirs = append(deps.aidlIncludeDirs, dep.AidlIncludeDirs...)
 				addPlugins(&deps, dep.ExportedPlugins, dep.ExportedPluginClasses...)
+				deps.kaptProcessorPath = append(deps.kaptProcessorPath, dep.ExportedPlugins...)
 				deps.disableTurbine = deps.disableTurbine || dep.ExportedPluginDisableTurbine
 
 				transitiveClasspathHeaderJars = append(transitiveClasspathHeaderJars, dep.TransitiveStaticLibsHeaderJars)
@@ -2476,6 +2841,7 @@ func (j *Module) collectDeps(ctx android.ModuleContext) deps {
 				deps.staticResourceJars = append(deps.staticResourceJars, dep.ResourceJars...)
 				deps.aidlIncludeDirs = append(deps.aidlIncludeDirs, dep.AidlIncludeDirs...)
 				addPlugins(&deps, dep.ExportedPlugins, dep.ExportedPluginClasses...)
+				deps.kaptProcessorPath = append(deps.kaptProcessorPath, dep.ExportedPlugins...)
 				// Turbine doesn't run annotation processors, so any module that uses an
 				// annotation processor that generates API is incompatible with the turbine
 				// optimization.
@@ -2493,6 +2859,19 @@ func (j *Module) collectDeps(ctx android.ModuleContext) deps {
 					} else {
 						addPlugins(&deps, dep.ImplementationAndResourcesJars)
 					}
+					if plugin.SupportsKsp {
+						deps.kspProcessorPath = append(deps.kspProcessorPath, dep.ImplementationAndResourcesJars...)
+						if plugin.ProcessorClass != nil {
+							deps.kspProcessorClasses = append(deps.kspProcessorClasses, *plugin.ProcessorClass)
+						}
+					} else if plugin.TurbineIncompatible {
+						deps.turbineIncompatibleProcessorPath = append(deps.turbineIncompatibleProcessorPath, dep.ImplementationAndResourcesJars...)
+						if plugin.ProcessorClass != nil {
+							deps.turbineIncompatibleProcessorClasses = append(deps.turbineIncompatibleProcessorClasses, *plugin.ProcessorClass)
+						}
+					} else {
+						deps.kaptProcessorPath = append(deps.kaptProcessorPath, dep.ImplementationAndResourcesJars...)
+					}
 					// Turbine doesn't run annotation processors, so any module that uses an
 					// annotation processor that generates API is incompatible with the turbine
 					// optimization.
@@ -2948,18 +3327,37 @@ func (j *Module) jarjarIfNecessary(ctx android.ModuleContext, infile android.Pat
 	totalShards := 1
 	if useShards {
 		totalShardsStr := j.properties.Jarjar_shards.GetOrDefault(ctx, "1")
-		ts, err := strconv.Atoi(totalShardsStr)
-		if err != nil {
-			ctx.PropertyErrorf("jarjar_shards", "jarjar_shards must be an integer represented as a string")
-			return infile, false
+		if totalShardsStr == "auto" {
+			totalShards = autoJarjarShards(len(j.uniqueSrcFiles))
+		} else {
+			ts, err := strconv.Atoi(totalShardsStr)
+			if err != nil || ts < 1 {
+				ctx.PropertyErrorf("jarjar_shards", "jarjar_shards must be a positive integer represented as a string or \"auto\"")
+				return infile, false
+			}
+			totalShards = ts
 		}
-		totalShards = ts
 	}
 	TransformJarJarWithShards(ctx, jarjarFile, infile, j.expandJarjarRules, totalShards)
 	return jarjarFile, true
 
 }
 
+const (
+	// The number of source files per jarjar shard when jarjar_shards is "auto".
+	jarjarSrcFilesPerShard = 2000
+	// The maximum number of jarjar shards when jarjar_shards is "auto".
+	maxAutoJarjarShards = 16
+)
+
+// autoJarjarShards returns the number of jarjar shards for a module with srcFiles source files.
+// The size of the jar isn't known until it is built, so the number of source files is used as an
+// estimate of it.
+func autoJarjarShards(srcFiles int) int {
+	shards := (srcFiles + jarjarSrcFilesPerShard - 1) / jarjarSrcFilesPerShard
+	return min(max(shards, 1), maxAutoJarjarShards)
+}
+
 func addPlugins(deps *deps, pluginJars android.Paths, pluginClasses ...string) {
 	deps.processorPath = append(deps.processorPath, pluginJars...)
 	deps.processorClasses = append(deps.processorClasses, pluginClasses...)
@@ -2969,12 +3367,17 @@ func addPlugins(deps *deps, pluginJars android.Paths, pluginClasses ...string) {
 // this interface.
 type ProvidesUsesLib interface {
 	ProvidesUsesLib() *string
+	ProvidesUsesLibAttributes() dexpreopt.UsesLibraryAttributes
 }
 
 func (j *Module) ProvidesUsesLib() *string {
 	return j.usesLibraryProperties.Provides_uses_lib
 }
 
+func (j *Module) ProvidesUsesLibAttributes() dexpreopt.UsesLibraryAttributes {
+	return j.usesLibrary.providesUsesLibAttributes()
+}
+
 type ModuleWithStem interface {
 	Stem() string
 }
@@ -0,0 +1,264 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+// Host java binaries that don't specify a wrapper are installed with build/soong/scripts/jar-wrapper.sh.
+// When any of the jvm_flags, required_env or jdk_version properties are set, a wrapper with the same
+// behavior is generated for the module instead, which additionally passes the default JVM flags,
+// fails early if a required environment variable is unset, and runs the jar with a specific JDK
+// from prebuilts/jdk.
+//
+// Windows host cross binaries that don't specify a wrapper get a generated .bat wrapper with the same
+// behavior instead, as jar-wrapper.sh can't run on Windows.
+
+var (
+	envVarNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	jdkVersionRegexp = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// hasHostBinaryWrapperProperties returns true if any of the properties that require a generated
+// host wrapper are set.
+func (j *Binary) hasHostBinaryWrapperProperties() bool {
+	p := j.binaryProperties
+	return len(p.Jvm_flags) > 0 || len(p.Required_env) > 0 || p.Jdk_version != nil
+}
+
+// checkHostBinaryWrapperProperties reports errors for invalid values of the properties that configure
+// the generated host wrapper.
+func (j *Binary) checkHostBinaryWrapperProperties(ctx android.ModuleContext) {
+	p := j.binaryProperties
+	if p.Wrapper != nil || ctx.Device() {
+		if len(p.Jvm_flags) > 0 {
+			ctx.PropertyErrorf("jvm_flags", "is only supported for host binaries without a wrapper")
+		}
+		if len(p.Required_env) > 0 {
+			ctx.PropertyErrorf("required_env", "is only supported for host binaries without a wrapper")
+		}
+		if p.Jdk_version != nil {
+			ctx.PropertyErrorf("jdk_version", "is only supported for host binaries without a wrapper")
+		}
+		return
+	}
+	for _, env := range p.Required_env {
+		if !envVarNameRegexp.MatchString(env) {
+			ctx.PropertyErrorf("required_env", "%q is not a valid environment variable name", env)
+		}
+	}
+	if p.Jdk_version != nil && !jdkVersionRegexp.MatchString(*p.Jdk_version) {
+		ctx.PropertyErrorf("jdk_version", "%q is not a valid JDK version, expected e.g. \"21\"", *p.Jdk_version)
+	}
+	if ctx.Windows() {
+		for _, flag := range p.Jvm_flags {
+			if strings.Contains(flag, `"`) {
+				ctx.PropertyErrorf("jvm_flags", "%q can not contain double quotes for Windows binaries", flag)
+			}
+		}
+	}
+}
+
+// generateHostBinaryWrapper writes the wrapper script of a host binary to the module's output
+// directory and returns its path.
+func (j *Binary) generateHostBinaryWrapper(ctx android.ModuleContext) android.Path {
+	wrapper := android.PathForModuleOut(ctx, ctx.ModuleName()+".sh")
+	script := hostBinaryWrapperScript(j.binaryProperties.Jvm_flags, j.binaryProperties.Required_env,
+		String(j.binaryProperties.Jdk_version), ctx.Config().PrebuiltOS())
+	android.WriteExecutableFileRuleVerbatim(ctx, wrapper, script)
+	return wrapper
+}
+
+// hostBinaryWrapperScript returns the contents of a wrapper script that runs the jar installed next
+// to it, or in ../framework, with the given default JVM flags before any -J flags passed on the
+// command line.
+func hostBinaryWrapperScript(jvmFlags, requiredEnv []string, jdkVersion, prebuiltOS string) string {
+	var sb strings.Builder
+	sb.WriteString(`#!/bin/bash
+# Generated by Soong, do not edit.
+
+# Set up prog to be the path of this script, including following symlinks,
+# and set up progdir to be the fully-qualified pathname of its directory.
+
+prog="$0"
+while [ -h "${prog}" ]; do
+    fullprog=` + "`/bin/ls -ld \"${prog}\"`" + `
+    fullprog=` + "`expr \"${fullprog}\" : \".* -> \\(.*\\)$\"`" + `
+    if expr "x${fullprog}" : 'x/' >/dev/null; then
+        prog="${fullprog}"
+    else
+        progdir=` + "`dirname \"${prog}\"`" + `
+        prog="${progdir}/${fullprog}"
+    fi
+done
+
+oldwd=` + "`pwd`" + `
+progdir=` + "`dirname \"${prog}\"`" + `
+cd "${progdir}"
+progdir=` + "`pwd`" + `
+prog="${progdir}"/` + "`basename \"${prog}\"`" + `
+cd "${oldwd}"
+
+jarfile=` + "`basename \"${prog}\"`" + `.jar
+jardir="${progdir}"
+
+if [ ! -r "${jardir}/${jarfile}" ]; then
+    jardir=` + "`dirname \"${progdir}\"`" + `/framework
+fi
+
+if [ ! -r "${jardir}/${jarfile}" ]; then
+    echo ` + "`basename \"${prog}\"`" + `": can't find ${jarfile}"
+    exit 1
+fi
+`)
+
+	if len(requiredEnv) > 0 {
+		sb.WriteString("\n")
+		for _, env := range requiredEnv {
+			fmt.Fprintf(&sb, "if [ -z \"${%s+x}\" ]; then\n", env)
+			fmt.Fprintf(&sb, "    echo `basename \"${prog}\"`\": required environment variable %s is not set\" >&2\n", env)
+			sb.WriteString("    exit 1\n")
+			sb.WriteString("fi\n")
+		}
+	}
+
+	if jdkVersion == "" {
+		sb.WriteString("\njava=java\n")
+	} else {
+		fmt.Fprintf(&sb, "\njava=\"${ANDROID_BUILD_TOP:-${progdir}/../../../..}/prebuilts/jdk/jdk%s/%s/bin/java\"\n",
+			jdkVersion, prebuiltOS)
+		sb.WriteString("if [ ! -x \"${java}\" ]; then\n")
+		fmt.Fprintf(&sb, "    echo `basename \"${prog}\"`\": can't find JDK %s at ${java}\" >&2\n", jdkVersion)
+		sb.WriteString("    exit 1\n")
+		sb.WriteString("fi\n")
+	}
+
+	fmt.Fprintf(&sb, "\ndeclare -a javaOpts=(%s)\n", strings.Join(proptools.ShellEscapeList(jvmFlags), " "))
+	sb.WriteString(`while expr "x$1" : 'x-J' >/dev/null; do
+    opt=` + "`expr \"$1\" : '-J-\\{0,1\\}\\(.*\\)'`" + `
+    javaOpts+=("-${opt}")
+    shift
+done
+
+exec "${java}" "${javaOpts[@]}" -jar ${jardir}/${jarfile} "$@"
+`)
+	return sb.String()
+}
+
+// generateWindowsBinaryWrapper writes the .bat wrapper of a Windows host binary to the module's
+// output directory and returns its path.
+func (j *Binary) generateWindowsBinaryWrapper(ctx android.ModuleContext) android.Path {
+	wrapper := android.PathForModuleOut(ctx, ctx.ModuleName()+".bat")
+	script := windowsBinaryWrapperScript(j.binaryProperties.Jvm_flags, j.binaryProperties.Required_env,
+		String(j.binaryProperties.Jdk_version))
+	android.WriteExecutableFileRuleVerbatim(ctx, wrapper, script)
+	return wrapper
+}
+
+var windowsUnquotedArgRegexp = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// windowsEscape escapes a JVM flag for use in a batch file, quoting it if it contains characters
+// that cmd.exe would interpret.
+func windowsEscape(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	if windowsUnquotedArgRegexp.MatchString(s) {
+		return s
+	}
+	return `"` + s + `"`
+}
+
+// windowsBinaryWrapperScript returns the contents of a batch file that runs the jar installed next
+// to it, or in ..\framework, with the given default JVM flags before any -J flags passed on the
+// command line.
+func windowsBinaryWrapperScript(jvmFlags, requiredEnv []string, jdkVersion string) string {
+	var lines []string
+	add := func(format string, a ...any) {
+		lines = append(lines, fmt.Sprintf(format, a...))
+	}
+
+	add("@echo off")
+	add("rem Generated by Soong, do not edit.")
+	add("setlocal")
+	add("")
+	add(`set "progdir=%%~dp0"`)
+	add(`set "jarfile=%%~n0.jar"`)
+	add(`set "jardir=%%progdir%%"`)
+	add(`if not exist "%%jardir%%%%jarfile%%" set "jardir=%%progdir%%..\framework\"`)
+	add(`if not exist "%%jardir%%%%jarfile%%" (`)
+	add(`    echo %%~n0: can't find %%jarfile%% 1>&2`)
+	add(`    exit /b 1`)
+	add(`)`)
+
+	if len(requiredEnv) > 0 {
+		add("")
+		for _, env := range requiredEnv {
+			add(`if not defined %s (`, env)
+			add(`    echo %%~n0: required environment variable %s is not set 1>&2`, env)
+			add(`    exit /b 1`)
+			add(`)`)
+		}
+	}
+
+	add("")
+	if jdkVersion == "" {
+		add(`set "java=java"`)
+	} else {
+		javaPath := fmt.Sprintf(`prebuilts\jdk\jdk%s\windows-x86\bin\java.exe`, jdkVersion)
+		add(`set "java=%%progdir%%..\..\..\..\%s"`, javaPath)
+		add(`if defined ANDROID_BUILD_TOP set "java=%%ANDROID_BUILD_TOP%%\%s"`, javaPath)
+		add(`if not exist "%%java%%" (`)
+		add(`    echo %%~n0: can't find JDK %s at %%java%% 1>&2`, jdkVersion)
+		add(`    exit /b 1`)
+		add(`)`)
+	}
+
+	var escapedFlags []string
+	for _, flag := range jvmFlags {
+		escapedFlags = append(escapedFlags, windowsEscape(flag))
+	}
+	add("")
+	add(`set javaOpts=%s`, strings.Join(escapedFlags, " "))
+	add(`set args=`)
+	add("")
+	add(`:parseJavaOpts`)
+	add(`if "%%~1"=="" goto run`)
+	add(`set "arg=%%~1"`)
+	add(`if not "%%arg:~0,2%%"=="-J" goto collectArgs`)
+	add(`set "opt=%%arg:~2%%"`)
+	add(`if "%%opt:~0,1%%"=="-" set "opt=%%opt:~1%%"`)
+	add(`set javaOpts=%%javaOpts%% "-%%opt%%"`)
+	add(`shift`)
+	add(`goto parseJavaOpts`)
+	add("")
+	add(`:collectArgs`)
+	add(`if "%%~1"=="" goto run`)
+	add(`set args=%%args%% %%1`)
+	add(`shift`)
+	add(`goto collectArgs`)
+	add("")
+	add(`:run`)
+	add(`"%%java%%" %%javaOpts%% -jar "%%jardir%%%%jarfile%%" %%args%%`)
+	add(`exit /b %%errorlevel%%`)
+
+	// cmd.exe expects CRLF line endings.
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"android/soong/android"
+	"android/soong/dexpreopt"
+)
+
+// The boot image profile installed on the device is merged from the profiles in the source tree,
+// the profiles from the global config and the profiles contributed by the bootclasspath fragments,
+// so what it ends up containing depends on the product. The profile is dumped with profman and
+// disted, both as profman's human readable dump and as a boot_image_profile_info proto that lists
+// the classes and methods in the profile and summarizes them per package, so that it can be
+// inspected for each product without rebuilding it locally.
+
+// bootImageProfileInfoRule generates the rules to dump the binary boot image profile of the image
+// and dists the dumps.
+func bootImageProfileInfoRule(ctx android.ModuleContext, image *bootImageConfig, profile android.Path) {
+	globalSoong := dexpreopt.GetGlobalSoongConfig(ctx)
+
+	dexFiles := image.dexPathsDeps.Paths()
+	dexLocations := image.getAnyAndroidVariant().dexLocationsDeps
+
+	info := android.PathForModuleOut(ctx, image.name, "boot-image-profile-info.txt")
+	classesAndMethods := android.PathForModuleOut(ctx, image.name, "boot-image-profile-classes-and-methods.txt")
+	infoProto := android.PathForModuleOut(ctx, image.name, "boot-image-profile-info.pb")
+
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		Text(`ANDROID_LOG_TAGS="*:e"`).
+		Tool(globalSoong.Profman).
+		Flag("--dump-only").
+		FlagWithInput("--profile-file=", profile).
+		FlagForEachInput("--apk=", dexFiles).
+		FlagForEachArg("--dex-location=", dexLocations).
+		Text(">").Output(info)
+	rule.Command().
+		Text(`ANDROID_LOG_TAGS="*:e"`).
+		Tool(globalSoong.Profman).
+		Flag("--dump-classes-and-methods").
+		FlagWithInput("--profile-file=", profile).
+		FlagForEachInput("--apk=", dexFiles).
+		FlagForEachArg("--dex-location=", dexLocations).
+		Text(">").Output(classesAndMethods)
+	rule.Command().
+		BuiltTool("boot_image_profile_info").
+		FlagWithOutput("--output ", infoProto).
+		Input(classesAndMethods)
+	rule.Build("bootImageProfileInfo_"+image.name, "dump boot image profile "+image.name)
+
+	ctx.DistForGoal("droidcore", info, infoProto)
+}
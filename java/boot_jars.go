@@ -15,6 +15,8 @@
 package java
 
 import (
+	"strings"
+
 	"android/soong/android"
 )
 
@@ -28,6 +30,18 @@ func isActiveModule(ctx android.ConfigurableEvaluatorContext, module android.Mod
 	return android.IsModulePreferred(module)
 }
 
+// isConfiguredBootJar returns true if the module being built provides one of the jars on the
+// bootclasspath that are configured in the BootJars or ApexBootJars product variables. A prebuilt
+// and the implementation library of a java_sdk_library provide the jar named after the source
+// module and the java_sdk_library respectively.
+func isConfiguredBootJar(ctx android.BaseModuleContext) bool {
+	name := android.RemoveOptionalPrebuiltPrefix(ctx.ModuleName())
+	name = strings.TrimSuffix(name, ".impl")
+	nonApexBootJars := ctx.Config().NonApexBootJars()
+	apexBootJars := ctx.Config().ApexBootJars()
+	return nonApexBootJars.ContainsJar(name) || apexBootJars.ContainsJar(name)
+}
+
 // buildRuleForBootJarsPackageCheck generates the build rule to perform the boot jars package
 // check.
 func buildRuleForBootJarsPackageCheck(ctx android.ModuleContext, bootDexJarByModule bootDexJarByModule) {
@@ -161,6 +161,7 @@ func gatherApexModulePairDepsWithTag(ctx android.BaseModuleContext, tagType boot
 
 	for _, moduleInApex := range modulesInApexes {
 		var found android.Module
+		extractedFromApex := false
 		ctx.WalkDeps(func(child, parent android.Module) bool {
 			t := ctx.OtherModuleDependencyTag(child)
 			if parent == ctx.Module() {
@@ -169,6 +170,9 @@ func gatherApexModulePairDepsWithTag(ctx android.BaseModuleContext, tagType boot
 					return true
 				}
 			} else if tagType != fragment && android.IsFragmentInApexTag(t) {
+				if prebuilt, ok := child.(*PrebuiltBootclasspathFragmentModule); ok && android.InList(moduleInApex.module, prebuilt.ApexBootJars()) {
+					extractedFromApex = true
+				}
 				return true
 			} else if android.IsDontReplaceSourceWithPrebuiltTag(t) {
 				return false
@@ -192,6 +196,8 @@ func gatherApexModulePairDepsWithTag(ctx android.BaseModuleContext, tagType boot
 			} else {
 				modulesToApex[found] = moduleInApex.apex
 			}
+		} else if extractedFromApex {
+			// The dex jar is extracted directly from the prebuilt apex so there is no module for it.
 		} else if !ctx.Config().AllowMissingDependencies() {
 			ctx.ModuleErrorf("failed to find module %q in apex %q\n",
 				moduleInApex.module, moduleInApex.apex)
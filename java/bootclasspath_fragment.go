@@ -39,9 +39,18 @@ func registerBootclasspathFragmentBuildComponents(ctx android.RegistrationContex
 	ctx.RegisterModuleType("bootclasspath_fragment", bootclasspathFragmentFactory)
 	ctx.RegisterModuleType("bootclasspath_fragment_test", testBootclasspathFragmentFactory)
 	ctx.RegisterModuleType("prebuilt_bootclasspath_fragment", prebuiltBootclasspathFragmentFactory)
+
+	// Runs once the final variants of the fragment contents have been established so that the
+	// variants that are linted are updated.
+	ctx.FinalDepsMutators(func(ctx android.RegisterMutatorsContext) {
+		ctx.BottomUp("bootclasspath_fragment_strict_updatability_linting", bootclasspathFragmentStrictUpdatabilityLintingMutator)
+	})
 }
 
-type BootclasspathFragmentInfo struct{}
+type BootclasspathFragmentInfo struct {
+	// The text boot image profiles contributed by the fragment.
+	BootImageProfiles android.Paths
+}
 
 var BootclasspathFragmentInfoProvider = blueprint.NewProvider[BootclasspathFragmentInfo]()
 
@@ -155,6 +164,18 @@ type bootclasspathFragmentProperties struct {
 	// processing as it needs access to all the classes used by a fragment including those provided
 	// by other fragments.
 	BootclasspathFragmentsDepsProperties
+
+	// Text boot image profiles contributed by this fragment.
+	//
+	// They are merged into the profile built for this fragment's contents and, ordered by the name
+	// of the apex containing the fragment, into the profiles of the boot images. Classes and
+	// methods that are not in the jars being profiled are ignored by profman.
+	Boot_image_profiles []string `android:"path"`
+
+	// If true, baselining updatability lint checks (e.g. NewApi) is prohibited for all the contents
+	// of this fragment, as if they all set lint.strict_updatability_linting. It is an error for a
+	// content module to explicitly set lint.strict_updatability_linting to false. Defaults to false.
+	Strict_updatability_linting *bool
 }
 
 type HiddenAPIPackageProperties struct {
@@ -279,7 +300,7 @@ func bootclasspathFragmentFactory() android.Module {
 	m := &BootclasspathFragmentModule{}
 	m.AddProperties(&m.properties, &m.sourceOnlyProperties)
 	android.InitApexModule(m)
-	initClasspathFragment(m, BOOTCLASSPATH)
+	InitClasspathFragment(m, BOOTCLASSPATH)
 	android.InitAndroidArchModule(m, android.DeviceSupported, android.MultilibCommon)
 	android.InitDefaultableModule(m)
 
@@ -562,7 +583,29 @@ func (b *BootclasspathFragmentModule) GenerateAndroidBuildActions(ctx android.Mo
 		b.HideFromMake()
 	}
 
-	android.SetProvider(ctx, BootclasspathFragmentInfoProvider, BootclasspathFragmentInfo{})
+	android.SetProvider(ctx, BootclasspathFragmentInfoProvider, BootclasspathFragmentInfo{
+		BootImageProfiles: android.PathsForModuleSrc(ctx, b.properties.Boot_image_profiles),
+	})
+}
+
+// bootclasspathFragmentStrictUpdatabilityLintingMutator propagates strict_updatability_linting of a
+// bootclasspath_fragment to the lint of its contents, which then verifies that their lint baselines,
+// and those of their transitive dependencies, do not baseline any updatability lint checks.
+func bootclasspathFragmentStrictUpdatabilityLintingMutator(ctx android.BottomUpMutatorContext) {
+	b, ok := ctx.Module().(*BootclasspathFragmentModule)
+	if !ok || !proptools.Bool(b.properties.Strict_updatability_linting) {
+		return
+	}
+	ctx.VisitDirectDeps(func(module android.Module) {
+		if !IsBootclasspathFragmentContentDepTag(ctx.OtherModuleDependencyTag(module)) {
+			return
+		}
+		if lintable, ok := module.(strictUpdatabilityLintable); ok && !lintable.setStrictUpdatabilityLinting() {
+			ctx.PropertyErrorf("strict_updatability_linting",
+				"content module %q must not set lint.strict_updatability_linting to false",
+				ctx.OtherModuleName(module))
+		}
+	})
 }
 
 // getProfileProviderApex returns the name of the apex that provides a boot image profile, or an
@@ -609,15 +652,15 @@ func (b *BootclasspathFragmentModule) provideApexContentInfo(ctx android.ModuleC
 
 // generateClasspathProtoBuildActions generates all required build actions for classpath.proto config
 func (b *BootclasspathFragmentModule) generateClasspathProtoBuildActions(ctx android.ModuleContext) {
-	var classpathJars []classpathJar
+	var classpathJars []ClasspathJar
 	configuredJars := b.configuredJars(ctx)
 	if "art" == proptools.String(b.properties.Image_name) {
 		// ART and platform boot jars must have a corresponding entry in DEX2OATBOOTCLASSPATH
-		classpathJars = configuredJarListToClasspathJars(ctx, configuredJars, BOOTCLASSPATH, DEX2OATBOOTCLASSPATH)
+		classpathJars = ConfiguredJarListToClasspathJars(ctx, configuredJars, BOOTCLASSPATH, DEX2OATBOOTCLASSPATH)
 	} else {
-		classpathJars = configuredJarListToClasspathJars(ctx, configuredJars, b.classpathType)
+		classpathJars = ConfiguredJarListToClasspathJars(ctx, configuredJars, b.classpathType)
 	}
-	b.classpathFragmentBase().generateClasspathProtoBuildActions(ctx, configuredJars, classpathJars)
+	b.classpathFragmentBase().GenerateClasspathProtoBuildActions(ctx, configuredJars, classpathJars)
 }
 
 func (b *BootclasspathFragmentModule) configuredJars(ctx android.ModuleContext) android.ConfiguredJarList {
@@ -628,6 +671,9 @@ func (b *BootclasspathFragmentModule) configuredJars(ctx android.ModuleContext)
 	}
 
 	possibleUpdatableModules := gatherPossibleApexModuleNamesAndStems(ctx, b.properties.Contents.GetOrDefault(ctx, nil), bootclasspathFragmentContentDepTag)
+	if prebuilt, ok := ctx.Module().(*PrebuiltBootclasspathFragmentModule); ok {
+		possibleUpdatableModules = append(possibleUpdatableModules, prebuilt.ApexBootJars()...)
+	}
 	jars, unknown := global.ApexBootJars.Filter(possibleUpdatableModules)
 
 	// TODO(satayev): for apex_test we want to include all contents unconditionally to classpaths
@@ -689,7 +735,7 @@ func (b *BootclasspathFragmentModule) generateHiddenAPIBuildActions(ctx android.
 	// from the flags.
 	// TODO(b/192868581): Remove once the source and prebuilts provide a signature patterns file of
 	//  their own.
-	if output.SignaturePatternsPath == nil {
+	if output.SignaturePatternsPath == nil && output.AllFlagsPath != nil {
 		output.SignaturePatternsPath = buildRuleSignaturePatternsFile(
 			ctx, output.AllFlagsPath, []string{"*"}, nil, nil, "")
 	}
@@ -857,7 +903,8 @@ func (b *BootclasspathFragmentModule) produceBootImageProfileFromSource(ctx andr
 	}
 
 	// Build a profile for the modules in this fragment.
-	return bootImageProfileRuleCommon(ctx, b.Name(), dexPaths, dexLocations)
+	return bootImageProfileRuleCommon(ctx, b.Name(), dexPaths, dexLocations,
+		android.PathsForModuleSrc(ctx, b.properties.Boot_image_profiles))
 }
 
 func (b *BootclasspathFragmentModule) AndroidMkEntries() []android.AndroidMkEntries {
@@ -1084,7 +1131,25 @@ type prebuiltBootclasspathFragmentProperties struct {
 		// The path to the filtered-flags.csv file created by the bootclasspath_fragment.
 		Filtered_flags *string `android:"path"`
 	}
-}
+
+	// The names of boot jars that are extracted directly from the prebuilt APEX that contains this
+	// fragment, e.g. when only the .apex file is available and there are no java_import modules to
+	// list in contents.
+	//
+	// The dex jar of each one is extracted from javalib/<name>.jar in the APEX. The hidden API
+	// annotation-flags.csv, metadata.csv and index.csv files of this fragment are extracted from
+	// etc/hiddenapi/ in the APEX so hidden_api.annotation_flags, hidden_api.metadata and
+	// hidden_api.index must not be specified.
+	Apex_boot_jars []string
+}
+
+// The paths of the hidden API files within a prebuilt APEX that are used by a
+// prebuilt_bootclasspath_fragment that specifies apex_boot_jars.
+const (
+	HiddenAPIAnnotationFlagsPathInApex = "etc/hiddenapi/annotation-flags.csv"
+	HiddenAPIMetadataPathInApex        = "etc/hiddenapi/metadata.csv"
+	HiddenAPIIndexPathInApex           = "etc/hiddenapi/index.csv"
+)
 
 // A prebuilt version of the bootclasspath_fragment module.
 //
@@ -1107,6 +1172,12 @@ func (module *PrebuiltBootclasspathFragmentModule) Name() string {
 	return module.prebuilt.Name(module.ModuleBase.Name())
 }
 
+// ApexBootJars returns the names of the boot jars that are extracted directly from the prebuilt
+// APEX that contains this fragment.
+func (module *PrebuiltBootclasspathFragmentModule) ApexBootJars() []string {
+	return module.prebuiltProperties.Apex_boot_jars
+}
+
 // produceHiddenAPIOutput returns a path to the prebuilt all-flags.csv or nil if none is specified.
 func (module *PrebuiltBootclasspathFragmentModule) produceHiddenAPIOutput(ctx android.ModuleContext, contents []android.Module, fragments []android.Module, input HiddenAPIFlagInput) *HiddenAPIOutput {
 	pathForOptionalSrc := func(src *string, defaultPath android.Path) android.Path {
@@ -1123,6 +1194,17 @@ func (module *PrebuiltBootclasspathFragmentModule) produceHiddenAPIOutput(ctx an
 		return android.PathForModuleSrc(ctx, *src)
 	}
 
+	if len(module.prebuiltProperties.Apex_boot_jars) > 0 {
+		// The files are extracted from the prebuilt APEX and passed to the platform_bootclasspath by
+		// the prebuilt_apex module instead.
+		pathForSrc = func(property string, src *string) android.Path {
+			if src != nil {
+				ctx.PropertyErrorf(property, "must not be specified when apex_boot_jars is set, it is extracted from the prebuilt APEX")
+			}
+			return nil
+		}
+	}
+
 	output := HiddenAPIOutput{
 		HiddenAPIFlagOutput: HiddenAPIFlagOutput{
 			AnnotationFlagsPath:   pathForSrc("hidden_api.annotation_flags", module.prebuiltProperties.Hidden_api.Annotation_flags),
@@ -1160,15 +1242,23 @@ var _ commonBootclasspathFragment = (*PrebuiltBootclasspathFragmentModule)(nil)
 // RequiredFilesFromPrebuiltApex returns the list of all files the prebuilt_bootclasspath_fragment
 // requires from a prebuilt .apex file.
 //
-// If there is no image config associated with this fragment then it returns nil. Otherwise, it
-// returns the files that are listed in the image config.
+// That includes the boot image profile if this fragment provides it, and the dex jars and hidden
+// API files if apex_boot_jars is specified.
 func (module *PrebuiltBootclasspathFragmentModule) RequiredFilesFromPrebuiltApex(ctx android.BaseModuleContext) []string {
+	var files []string
 	for _, apex := range module.ApexProperties.Apex_available {
 		if isProfileProviderApex(ctx, apex) {
-			return []string{ProfileInstallPathInApex}
+			files = append(files, ProfileInstallPathInApex)
+			break
+		}
+	}
+	if jars := module.prebuiltProperties.Apex_boot_jars; len(jars) > 0 {
+		for _, jar := range jars {
+			files = append(files, ApexRootRelativePathToJavaLib(jar))
 		}
+		files = append(files, HiddenAPIAnnotationFlagsPathInApex, HiddenAPIMetadataPathInApex, HiddenAPIIndexPathInApex)
 	}
-	return nil
+	return files
 }
 
 func (module *PrebuiltBootclasspathFragmentModule) UseProfileGuidedDexpreopt() bool {
@@ -15,6 +15,7 @@
 package java
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -530,3 +531,66 @@ func TestBootclasspathFragment_Test(t *testing.T) {
 	fragment = result.Module("a_test_fragment", "android_common").(*BootclasspathFragmentModule)
 	android.AssertBoolEquals(t, "is a test fragment by type", true, fragment.isTestFragment())
 }
+
+func TestBootclasspathFragment_StrictUpdatabilityLinting(t *testing.T) {
+	t.Parallel()
+	bp := `
+		bootclasspath_fragment {
+			name: "myfragment",
+			contents: ["mybootlib"],
+			strict_updatability_linting: true,
+			hidden_api: {
+				split_packages: ["*"],
+			},
+		}
+
+		java_library {
+			name: "mybootlib",
+			srcs: ["Test.java"],
+			system_modules: "none",
+			sdk_version: "none",
+			compile_dex: true,
+			static_libs: ["mystaticlib"],
+			lint: {
+				baseline_filename: "mybootlib_lint_baseline.xml",
+				%s
+			},
+		}
+
+		java_library {
+			name: "mystaticlib",
+			srcs: ["Test.java"],
+			system_modules: "none",
+			sdk_version: "none",
+			lint: {
+				baseline_filename: "mystaticlib_lint_baseline.xml",
+			},
+		}
+	`
+
+	preparer := android.GroupFixturePreparers(
+		prepareForTestWithBootclasspathFragment,
+		FixtureConfigureApexBootJars("someapex:mybootlib"),
+		android.FixtureAddFile("mybootlib_lint_baseline.xml", nil),
+		android.FixtureAddFile("mystaticlib_lint_baseline.xml", nil),
+	)
+
+	t.Run("propagates to contents", func(t *testing.T) {
+		t.Parallel()
+		result := preparer.RunTestWithBp(t, fmt.Sprintf(bp, ""))
+
+		mybootlib := result.ModuleForTests(t, "mybootlib", "android_common")
+		check := mybootlib.Output("lint_strict_updatability_check.stamp")
+		inputs := check.Inputs.Strings()
+		android.AssertStringListContains(t, "strict updatability check baseline inputs", inputs, "mybootlib_lint_baseline.xml")
+		android.AssertStringListContains(t, "strict updatability check baseline inputs", inputs, "mystaticlib_lint_baseline.xml")
+	})
+
+	t.Run("content disables it", func(t *testing.T) {
+		t.Parallel()
+		preparer.
+			ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+				`module "myfragment".*: strict_updatability_linting: content module "mybootlib" must not set lint.strict_updatability_linting to false`)).
+			RunTestWithBp(t, fmt.Sprintf(bp, "strict_updatability_linting: false,"))
+	})
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"android/soong/android"
+)
+
+// Products can repackage classes on the bootclasspath by listing jarjar rules files in the
+// BootclasspathJarjarRules product variable. The rules are applied to every library on the
+// bootclasspath, after being merged with the module's own jarjar_rules.
+
+// addBootclasspathJarjarRules merges the product's bootclasspath jarjar rules into the jarjar rules
+// of the module if it is on the bootclasspath. The merge fails the build if the rules repackage
+// the same pattern to different results.
+func (j *Module) addBootclasspathJarjarRules(ctx android.ModuleContext) {
+	productRules := ctx.Config().BootclasspathJarjarRules()
+	if len(productRules) == 0 || !isConfiguredBootJar(ctx) {
+		return
+	}
+
+	var rules android.Paths
+	if j.expandJarjarRules != nil {
+		rules = append(rules, j.expandJarjarRules)
+	}
+	for _, file := range productRules {
+		path := android.ExistentPathForSource(ctx, file)
+		if !path.Valid() {
+			ctx.ModuleErrorf("jarjar rules file %q in BootclasspathJarjarRules does not exist", file)
+			continue
+		}
+		rules = append(rules, path.Path())
+	}
+	if ctx.Failed() {
+		return
+	}
+
+	merged := android.PathForModuleOut(ctx, "jarjar", "bootclasspath-jarjar-rules.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        mergeJarjarRules,
+		Description: "merge bootclasspath jarjar rules",
+		Inputs:      rules,
+		Output:      merged,
+	})
+	j.expandJarjarRules = merged
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
+	"android/soong/java/config"
 	"android/soong/remoteexec"
 )
 
@@ -46,14 +47,14 @@ var (
 				`mkdir -p "$outDir" "$annoDir" "$srcJarDir" && ` +
 				`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
 				`(if [ -s $srcJarDir/list ] || [ -s $out.rsp ] ; then ` +
-				`${config.FindInputDeltaCmd} --template '' --target "$out" --inputs_file "$out.rsp" && ` +
-				`${config.SoongJavacWrapper} $javaTemplate${config.JavacCmd} ` +
+				`${config.FindInputDeltaCmd} --template '' --target "$out" --inputs_file "$out.rsp" --inspect $srcJars && ` +
+				`$networkSandbox${config.SoongJavacWrapper} $javaTemplate${config.JavacCmd} ` +
 				`${config.JavacHeapFlags} ${config.JavacVmFlags} ${config.CommonJdkFlags} ` +
 				`$processorpath $processor $javacFlags $bootClasspath $classpath ` +
 				`-source $javaVersion -target $javaVersion ` +
 				`-d $outDir -s $annoDir @$out.rsp @$srcJarDir/list ; fi ) && ` +
 				`$annoSrcJarTemplate${config.SoongZipCmd} -jar -o $annoSrcJar.tmp -C $annoDir -D $annoDir && ` +
-				`$zipTemplate${config.SoongZipCmd} -jar -o $out.tmp -C $outDir -D $outDir && ` +
+				`$zipTemplate${config.SoongZipCmd} -jar $jarNoZip64Flag -o $out.tmp -C $outDir -D $outDir && ` +
 				`if ! cmp -s "$out.tmp" "$out"; then mv "$out.tmp" "$out"; fi && ` +
 				`if ! cmp -s "$annoSrcJar.tmp" "$annoSrcJar"; then mv "$annoSrcJar.tmp" "$annoSrcJar"; fi && ` +
 				`if [ -f "$out.pc_state.new" ]; then mv "$out.pc_state.new" "$out.pc_state"; fi && ` +
@@ -89,7 +90,17 @@ var (
 				Platform:     map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
 			},
 		}, []string{"javacFlags", "bootClasspath", "classpath", "processorpath", "processor", "srcJars", "srcJarDir",
-			"outDir", "annoDir", "annoSrcJar", "javaVersion"}, nil)
+			"outDir", "annoDir", "annoSrcJar", "javaVersion", "networkSandbox"}, nil)
+
+	// Jars that are 4GB or larger, e.g. class jars built with full debug info, or that have more than
+	// 65535 entries require the zip64 extensions.  javac, turbine, d8 and r8 can read them, but some
+	// other jar consumers cannot, so DISALLOW_ZIP64_JARS makes soong_zip and merge_zips fail on them.
+	_ = pctx.VariableFunc("jarNoZip64Flag", func(ctx android.PackageVarContext) string {
+		if ctx.Config().IsEnvTrue("DISALLOW_ZIP64_JARS") {
+			return "-no-zip64"
+		}
+		return ""
+	})
 
 	_ = pctx.VariableFunc("kytheCorpus",
 		func(ctx android.PackageVarContext) string { return ctx.Config().XrefCorpusName() })
@@ -143,6 +154,29 @@ var (
 		"javacFlags", "bootClasspath", "classpath", "processorpath", "processor", "srcJars", "srcJarDir",
 		"outDir", "annoDir", "javaVersion")
 
+	// Compiles the sources of a header jar with javac to find sources that turbine accepts but javac
+	// rejects. Javac failures are reported as warnings and the javac output is kept in $out.log, the
+	// rule itself never fails.
+	turbineJavacValidation = pctx.AndroidStaticRule("turbineJavacValidation",
+		blueprint.RuleParams{
+			Command: `rm -rf "$outDir" "$srcJarDir" "$out.log" && mkdir -p "$outDir" "$srcJarDir" && ` +
+				`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
+				`(if ! ${config.JavacCmd} ${config.JavacHeapFlags} ${config.JavacVmFlags} ${config.CommonJdkFlags} ` +
+				`-proc:none -implicit:none $javacFlags $bootClasspath $classpath ` +
+				`-source $javaVersion -target $javaVersion ` +
+				`-d $outDir @$out.rsp @$srcJarDir/list > $out.log 2>&1 ; then ` +
+				`echo "warning: turbine accepted sources of $headerJar that javac rejected, see $out.log" >&2 ; ` +
+				`else rm -f $out.log ; fi ) && ` +
+				`rm -rf "$outDir" "$srcJarDir" && touch $out`,
+			CommandDeps: []string{
+				"${config.JavacCmd}",
+				"${config.ZipSyncCmd}",
+			},
+			Rspfile:        "$out.rsp",
+			RspfileContent: "$in",
+		},
+		"javacFlags", "bootClasspath", "classpath", "srcJars", "srcJarDir", "outDir", "javaVersion", "headerJar")
+
 	extractMatchingApks = pctx.StaticRule(
 		"extractMatchingApks",
 		blueprint.RuleParams{
@@ -158,7 +192,7 @@ var (
 
 	turbine, turbineRE = pctx.RemoteStaticRules("turbine",
 		blueprint.RuleParams{
-			Command: `$reTemplate${config.JavaCmd} ${config.JavaVmFlags} -jar ${config.TurbineJar} $outputFlags ` +
+			Command: `$networkSandbox$reTemplate${config.JavaCmd} ${config.JavaVmFlags} -jar ${config.TurbineJar} $outputFlags ` +
 				`--sources @$out.rsp ` +
 				`--javacopts ${config.CommonJdkFlags} ` +
 				`$javacFlags -source $javaVersion -target $javaVersion -- $turbineFlags && ` +
@@ -179,11 +213,12 @@ var (
 			ToolchainInputs: []string{"${config.JavaCmd}"},
 			Platform:        map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
 		},
-		[]string{"javacFlags", "turbineFlags", "outputFlags", "javaVersion", "outputs", "rbeOutputs"}, []string{"rbeInputs", "rspFiles"})
+		[]string{"javacFlags", "turbineFlags", "outputFlags", "javaVersion", "outputs", "rbeOutputs", "networkSandbox"},
+		[]string{"rbeInputs", "rspFiles"})
 
 	jar, jarRE = pctx.RemoteStaticRules("jar",
 		blueprint.RuleParams{
-			Command:        `$reTemplate${config.SoongZipCmd} -jar -o $out @$out.rsp`,
+			Command:        `$reTemplate${config.SoongZipCmd} -jar $jarNoZip64Flag -o $out @$out.rsp`,
 			CommandDeps:    []string{"${config.SoongZipCmd}"},
 			Rspfile:        "$out.rsp",
 			RspfileContent: "$jarArgs",
@@ -213,13 +248,13 @@ var (
 
 	combineJar = pctx.AndroidStaticRule("combineJar",
 		blueprint.RuleParams{
-			Command:     `${config.MergeZipsCmd} --ignore-duplicates -j $jarArgs $out $in`,
+			Command:     `${config.MergeZipsCmd} --ignore-duplicates -j $jarNoZip64Flag $jarArgs $out $in`,
 			CommandDeps: []string{"${config.MergeZipsCmd}"},
 		},
 		"jarArgs")
 	combineJarRsp = pctx.AndroidStaticRule("combineJarRsp",
 		blueprint.RuleParams{
-			Command:        `${config.MergeZipsCmd} --ignore-duplicates -j $jarArgs $out @$out.rsp`,
+			Command:        `${config.MergeZipsCmd} --ignore-duplicates -j $jarNoZip64Flag $jarArgs $out @$out.rsp`,
 			CommandDeps:    []string{"${config.MergeZipsCmd}"},
 			Rspfile:        "$out.rsp",
 			RspfileContent: "$in",
@@ -251,6 +286,12 @@ var (
 		},
 		"rulesFile", "total_shards", "shard_index")
 
+	mergeJarjarRules = pctx.AndroidStaticRule("mergeJarjarRules",
+		blueprint.RuleParams{
+			Command:     "${config.MergeJarjarRulesCmd} $out $in",
+			CommandDeps: []string{"${config.MergeJarjarRulesCmd}"},
+		})
+
 	packageCheck = pctx.AndroidStaticRule("packageCheck",
 		blueprint.RuleParams{
 			Command: "rm -f $out && " +
@@ -260,6 +301,14 @@ var (
 		},
 		"packages")
 
+	permittedPackagesCheck = pctx.AndroidStaticRule("permittedPackagesCheck",
+		blueprint.RuleParams{
+			Command: "rm -f $out && " +
+				"${config.PermittedPackagesCheckCmd} --module $module --jar $in --output $out $packages",
+			CommandDeps: []string{"${config.PermittedPackagesCheckCmd}"},
+		},
+		"module", "packages")
+
 	jetifier = pctx.AndroidStaticRule("jetifier",
 		blueprint.RuleParams{
 			Command:     "${config.JavaCmd}  ${config.JavaVmFlags} -jar ${config.JetifierJar} -l error -o $out -i $in -t epoch",
@@ -374,6 +423,16 @@ type javaBuilderFlags struct {
 	errorProneExtraJavacFlags string
 	errorProneProcessorPath   classpath
 
+	// errorProneClasspath is the classpath for the separate errorprone compile when it is run
+	// alongside the regular javac compile.  It contains the combined turbine header jar of the
+	// module in place of the header jars of its static dependencies, and is empty if turbine
+	// was not run.
+	errorProneClasspath classpath
+
+	// networkSandbox is true if the rules that run annotation processors must run them without
+	// network access.
+	networkSandbox bool
+
 	kotlincFlags     string
 	kotlincClasspath classpath
 	kotlincDeps      android.Paths
@@ -500,6 +559,7 @@ func turbineFlags(ctx android.ModuleContext, flags javaBuilderFlags, dir string,
 		}
 	}
 
+	classpath = classpath.firstUnique()
 	classpathFlags := classpath.FormTurbineClassPath("")
 	implicits = append(implicits, classpath...)
 	const classpathLimit = 32 * 1024
@@ -538,6 +598,10 @@ func TransformJavaToHeaderClasses(ctx android.ModuleContext, outputFile android.
 		args["rbeOutputs"] = outputFile.String() + ".tmp"
 		args["rspFiles"] = strings.Join(rspFiles.Strings(), ",")
 	}
+	var validations android.Paths
+	if ctx.Config().IsEnvTrue("TURBINE_JAVAC_VALIDATION") {
+		validations = append(validations, turbineJavacValidationRule(ctx, outputFile, srcFiles, srcJars, flags))
+	}
 	ctx.Build(pctx, android.BuildParams{
 		Rule:        rule,
 		Description: "turbine",
@@ -545,7 +609,60 @@ func TransformJavaToHeaderClasses(ctx android.ModuleContext, outputFile android.
 		Inputs:      srcFiles,
 		Implicits:   implicits,
 		Args:        args,
+		Validations: validations,
+	})
+}
+
+// turbineJavacValidationRule emits a rule that compiles the sources of the header jar headerJar with
+// javac and returns its output timestamp. It is used as a validation of the turbine rule so that it
+// runs whenever the header jar is built, without delaying the modules that depend on the header jar.
+func turbineJavacValidationRule(ctx android.ModuleContext, headerJar android.WritablePath,
+	srcFiles, srcJars android.Paths, flags javaBuilderFlags) android.Path {
+
+	deps := append(android.Paths(nil), srcJars...)
+	classpath := flags.classpath
+
+	var bootClasspath string
+	if flags.javaVersion.usesJavaModules() {
+		var systemModuleDeps android.Paths
+		bootClasspath, systemModuleDeps = flags.systemModules.FormJavaSystemModulesPath(ctx.Device())
+		deps = append(deps, systemModuleDeps...)
+		classpath = append(flags.java9Classpath, classpath...)
+	} else {
+		deps = append(deps, flags.bootClasspath...)
+		if len(flags.bootClasspath) == 0 && ctx.Device() {
+			// explicitly specify -bootclasspath "" if the bootclasspath is empty to
+			// ensure java does not fall back to the default bootclasspath.
+			bootClasspath = `-bootclasspath ""`
+		} else {
+			bootClasspath = flags.bootClasspath.FormJavaClassPath("-bootclasspath")
+		}
+	}
+	deps = append(deps, classpath...)
+
+	// The validation only reports sources that javac rejects, so warnings must not be turned into
+	// errors.
+	javacFlags := strings.Join(android.RemoveListFromList(strings.Fields(flags.javacFlags), []string{"-Werror"}), " ")
+
+	stamp := headerJar.ReplaceExtension(ctx, "javac_validation")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        turbineJavacValidation,
+		Description: "turbine javac validation",
+		Output:      stamp,
+		Inputs:      srcFiles,
+		Implicits:   deps,
+		Args: map[string]string{
+			"javacFlags":    javacFlags,
+			"bootClasspath": bootClasspath,
+			"classpath":     classpath.FormJavaClassPath("-classpath"),
+			"srcJars":       strings.Join(srcJars.Strings(), " "),
+			"srcJarDir":     android.PathForModuleOut(ctx, "turbine-javac-validation", "srcjars").String(),
+			"outDir":        android.PathForModuleOut(ctx, "turbine-javac-validation", "classes").String(),
+			"javaVersion":   flags.javaVersion.String(),
+			"headerJar":     headerJar.String(),
+		},
 	})
+	return stamp
 }
 
 // TurbineApt produces a rule to run annotation processors using turbine.
@@ -576,6 +693,10 @@ func TurbineApt(ctx android.ModuleContext, outputSrcJar, outputResJar android.Wr
 		args["rbeInputs"] = strings.Join(rbeInputs.Strings(), ",")
 		args["rbeOutputs"] = outputSrcJar.String() + ".tmp," + outputResJar.String() + ".tmp"
 		args["rspFiles"] = strings.Join(rspFiles.Strings(), ",")
+	} else {
+		var sandboxDeps android.Paths
+		args["networkSandbox"], sandboxDeps = networkSandboxArg(ctx, flags, flags.processorPath, flags.processors)
+		implicits = append(implicits, sandboxDeps...)
 	}
 	ctx.Build(pctx, android.BuildParams{
 		Rule:            rule,
@@ -653,8 +774,13 @@ func transformJavaToClasses(ctx android.ModuleContext, outputFile android.Writab
 		annoDir = filepath.Join(shardDir, annoDir)
 	}
 	rule := javac
+	networkSandbox := ""
 	if ctx.Config().UseRBE() && ctx.Config().IsEnvTrue("RBE_JAVAC") {
 		rule = javacRE
+	} else {
+		var sandboxDeps android.Paths
+		networkSandbox, sandboxDeps = networkSandboxArg(ctx, flags, flags.processorPath, flags.processors)
+		deps = append(deps, sandboxDeps...)
 	}
 	ctx.Build(pctx, android.BuildParams{
 		Rule:           rule,
@@ -664,21 +790,42 @@ func transformJavaToClasses(ctx android.ModuleContext, outputFile android.Writab
 		Inputs:         srcFiles,
 		Implicits:      deps,
 		Args: map[string]string{
-			"javacFlags":    flags.javacFlags,
-			"bootClasspath": bootClasspath,
-			"classpath":     classpathArg,
-			"processorpath": flags.processorPath.FormJavaClassPath("-processorpath"),
-			"processor":     processor,
-			"srcJars":       strings.Join(srcJars.Strings(), " "),
-			"srcJarDir":     android.PathForModuleOut(ctx, intermediatesDir, srcJarDir).String(),
-			"outDir":        android.PathForModuleOut(ctx, intermediatesDir, outDir).String(),
-			"annoDir":       android.PathForModuleOut(ctx, intermediatesDir, annoDir).String(),
-			"annoSrcJar":    annoSrcJar.String(),
-			"javaVersion":   flags.javaVersion.String(),
+			"javacFlags":     flags.javacFlags,
+			"bootClasspath":  bootClasspath,
+			"classpath":      classpathArg,
+			"processorpath":  flags.processorPath.FormJavaClassPath("-processorpath"),
+			"processor":      processor,
+			"srcJars":        strings.Join(srcJars.Strings(), " "),
+			"srcJarDir":      android.PathForModuleOut(ctx, intermediatesDir, srcJarDir).String(),
+			"outDir":         android.PathForModuleOut(ctx, intermediatesDir, outDir).String(),
+			"annoDir":        android.PathForModuleOut(ctx, intermediatesDir, annoDir).String(),
+			"annoSrcJar":     annoSrcJar.String(),
+			"javaVersion":    flags.javaVersion.String(),
+			"networkSandbox": networkSandbox,
 		},
 	})
 }
 
+// networkSandboxArg returns the command prefix that runs a command that runs the annotation
+// processors in processorPath without network access if the module sets flags.networkSandbox, and
+// the dependencies of the prefix.  processors are the class names of the annotation processors,
+// they are only used in error messages and may be empty when the tool discovers the processors in
+// the processor path.  Rules that run remotely are not sandboxed, the remote execution service
+// controls their network access.
+func networkSandboxArg(ctx android.ModuleContext, flags javaBuilderFlags, processorPath classpath,
+	processors []string) (string, android.Paths) {
+
+	if !flags.networkSandbox || len(processorPath) == 0 {
+		return "", nil
+	}
+	processorList := strings.Join(processors, ",")
+	if processorList == "" {
+		processorList = "''"
+	}
+	return "${config.NetworkSandboxCmd} " + processorList + " -- ",
+		android.Paths{android.PathForSource(ctx, config.NetworkSandboxPath)}
+}
+
 func TransformResourcesToJar(ctx android.ModuleContext, outputFile android.WritablePath,
 	jarArgs []string, deps android.Paths) {
 
@@ -701,6 +848,15 @@ func TransformJarsToJar(ctx android.ModuleContext, outputFile android.WritablePa
 	jars android.Paths, manifest android.OptionalPath, stripDirEntries bool, filesToStrip []string,
 	dirsToStrip []string) {
 
+	transformJarsToJar(ctx, outputFile, desc, jars, manifest, stripDirEntries, filesToStrip, dirsToStrip, nil)
+}
+
+// transformJarsToJar is TransformJarsToJar with validations that must succeed for the merged jar
+// to be used.
+func transformJarsToJar(ctx android.ModuleContext, outputFile android.WritablePath, desc string,
+	jars android.Paths, manifest android.OptionalPath, stripDirEntries bool, filesToStrip []string,
+	dirsToStrip []string, validations android.Paths) {
+
 	var deps android.Paths
 
 	var jarArgs []string
@@ -747,6 +903,7 @@ func TransformJarsToJar(ctx android.ModuleContext, outputFile android.WritablePa
 		Output:      outputFile,
 		Inputs:      jars,
 		Implicits:   deps,
+		Validations: validations,
 		Args: map[string]string{
 			"jarArgs": strings.Join(jarArgs, " "),
 		},
@@ -841,6 +998,23 @@ func CheckJarPackages(ctx android.ModuleContext, outputFile android.WritablePath
 	})
 }
 
+// CheckPermittedPackagesMatch creates a rule that compares the packages of the classes in the jar
+// with the permitted packages, writes the missing and spurious packages to the output file and fails
+// if there are any.
+func CheckPermittedPackagesMatch(ctx android.ModuleContext, outputFile android.WritablePath,
+	classesJar android.Path, permittedPackages []string) {
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        permittedPackagesCheck,
+		Description: "permittedPackagesCheck",
+		Output:      outputFile,
+		Input:       classesJar,
+		Args: map[string]string{
+			"module":   ctx.ModuleName(),
+			"packages": strings.Join(permittedPackages, " "),
+		},
+	})
+}
+
 func TransformJetifier(ctx android.ModuleContext, outputFile android.WritablePath,
 	inputFile android.Path) {
 	ctx.Build(pctx, android.BuildParams{
@@ -889,12 +1063,21 @@ func writeCombinedProguardFlagsFile(ctx android.ModuleContext, outputFile androi
 
 type classpath android.Paths
 
+// firstUnique returns a copy of the classpath with duplicate jars removed, keeping the first
+// occurrence of each jar so that the lookup order is unchanged. The same jar can be reached through
+// multiple dependencies, e.g. through different sdk variants of a library, and repeating it only
+// makes the command lines and rsp files longer.
+func (x classpath) firstUnique() classpath {
+	return classpath(android.FirstUniquePaths(android.CopyOfPaths(android.Paths(x))))
+}
+
 func (x *classpath) formJoinedClassPath(optName string, sep string) string {
 	if optName != "" && !strings.HasSuffix(optName, "=") && !strings.HasSuffix(optName, " ") {
 		optName += " "
 	}
 	if len(*x) > 0 {
-		return optName + strings.Join(x.Strings(), sep)
+		unique := x.firstUnique()
+		return optName + strings.Join(unique.Strings(), sep)
 	} else {
 		return ""
 	}
@@ -912,8 +1095,9 @@ func (x *classpath) FormRepeatedClassPath(optName string) []string {
 	if x == nil || *x == nil {
 		return nil
 	}
-	flags := make([]string, len(*x))
-	for i, v := range *x {
+	unique := x.firstUnique()
+	flags := make([]string, len(unique))
+	for i, v := range unique {
 		flags[i] = optName + v.String()
 	}
 
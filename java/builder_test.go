@@ -0,0 +1,37 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestClasspathDedup(t *testing.T) {
+	t.Parallel()
+	cp := classpath(android.PathsForTesting("a.jar", "b.jar", "a.jar", "c.jar", "b.jar"))
+
+	android.AssertStringEquals(t, "FormJavaClassPath",
+		"-classpath a.jar:b.jar:c.jar", cp.FormJavaClassPath("-classpath"))
+	android.AssertStringEquals(t, "FormTurbineClassPath",
+		"--classpath a.jar b.jar c.jar", cp.FormTurbineClassPath("--classpath "))
+	android.AssertDeepEquals(t, "FormRepeatedClassPath",
+		[]string{"--lib a.jar", "--lib b.jar", "--lib c.jar"}, cp.FormRepeatedClassPath("--lib "))
+
+	// Forming the flags must not modify the classpath, it is shared between rules.
+	android.AssertDeepEquals(t, "classpath",
+		[]string{"a.jar", "b.jar", "a.jar", "c.jar", "b.jar"}, cp.Strings())
+}
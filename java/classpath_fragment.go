@@ -31,18 +31,26 @@ import (
 //
 // See `derive_classpath` service that reads the configs at runtime and defines *CLASSPATH variables
 // on the device.
+//
+// Module types outside of this package, e.g. from vendor soong plugins, can generate classpaths.proto
+// configs for additional classpath fragments, e.g. product specific ones, by embedding
+// ClasspathFragmentBase, calling InitClasspathFragment from their factory and calling
+// GenerateClasspathProtoBuildActions with the jars from ConfiguredJarListToClasspathJars from
+// their GenerateAndroidBuildActions. Fragments that are not in an APEX must also install the
+// config with InstallClasspathProto or ClasspathFragmentAndroidMkEntries.
 
-type classpathType int
+// ClasspathType is the *CLASSPATH variable a jar is added to.
+type ClasspathType int
 
 const (
 	// Matches definition in packages/modules/common/proto/classpaths.proto
-	BOOTCLASSPATH classpathType = iota
+	BOOTCLASSPATH ClasspathType = iota
 	DEX2OATBOOTCLASSPATH
 	SYSTEMSERVERCLASSPATH
 	STANDALONE_SYSTEMSERVER_JARS
 )
 
-func (c classpathType) String() string {
+func (c ClasspathType) String() string {
 	return [...]string{"BOOTCLASSPATH", "DEX2OATBOOTCLASSPATH", "SYSTEMSERVERCLASSPATH", "STANDALONE_SYSTEMSERVER_JARS"}[c]
 }
 
@@ -54,20 +62,20 @@ type classpathFragmentProperties struct {
 	Generate_classpaths_proto *bool
 }
 
-// classpathFragment interface is implemented by a module that contributes jars to a *CLASSPATH
-// variables at runtime.
-type classpathFragment interface {
+// ClasspathFragment interface is implemented by a module that contributes jars to a *CLASSPATH
+// variables at runtime. It is implemented by embedding ClasspathFragmentBase.
+type ClasspathFragment interface {
 	android.Module
 
 	classpathFragmentBase() *ClasspathFragmentBase
 }
 
-// ClasspathFragmentBase is meant to be embedded in any module types that implement classpathFragment;
-// such modules are expected to call initClasspathFragment().
+// ClasspathFragmentBase is meant to be embedded in any module types that implement ClasspathFragment;
+// such modules are expected to call InitClasspathFragment().
 type ClasspathFragmentBase struct {
 	properties classpathFragmentProperties
 
-	classpathType classpathType
+	classpathType ClasspathType
 
 	outputFilepath android.OutputPath
 	installDirPath android.InstallPath
@@ -77,21 +85,37 @@ func (c *ClasspathFragmentBase) classpathFragmentBase() *ClasspathFragmentBase {
 	return c
 }
 
+// ClasspathType returns the *CLASSPATH variable the jars of the fragment are added to.
+func (c *ClasspathFragmentBase) ClasspathType() ClasspathType {
+	return c.classpathType
+}
+
 // Initializes ClasspathFragmentBase struct. Must be called by all modules that include ClasspathFragmentBase.
-func initClasspathFragment(c classpathFragment, classpathType classpathType) {
+func InitClasspathFragment(c ClasspathFragment, classpathType ClasspathType) {
 	base := c.classpathFragmentBase()
 	base.classpathType = classpathType
 	c.AddProperties(&base.properties)
 }
 
 // Matches definition of Jar in packages/modules/SdkExtensions/proto/classpaths.proto
-type classpathJar struct {
+type ClasspathJar struct {
 	path          string
-	classpath     classpathType
+	classpath     ClasspathType
 	minSdkVersion string
 	maxSdkVersion string
 }
 
+// NewClasspathJar returns a ClasspathJar for a jar that is not in a ConfiguredJarList. path is
+// the path of the jar on the device, minSdkVersion and maxSdkVersion may be empty.
+func NewClasspathJar(path string, classpath ClasspathType, minSdkVersion, maxSdkVersion string) ClasspathJar {
+	return ClasspathJar{
+		path:          path,
+		classpath:     classpath,
+		minSdkVersion: minSdkVersion,
+		maxSdkVersion: maxSdkVersion,
+	}
+}
+
 // gatherPossibleApexModuleNamesAndStems returns a set of module and stem names from the
 // supplied contents that may be in the apex boot jars.
 //
@@ -119,50 +143,77 @@ func gatherPossibleApexModuleNamesAndStems(ctx android.ModuleContext, contents [
 	return android.SortedKeys(set)
 }
 
-// Converts android.ConfiguredJarList into a list of classpathJars for each given classpathType.
-func configuredJarListToClasspathJars(ctx android.ModuleContext, configuredJars android.ConfiguredJarList, classpaths ...classpathType) []classpathJar {
+// Converts android.ConfiguredJarList into a list of ClasspathJars for each given ClasspathType.
+func ConfiguredJarListToClasspathJars(ctx android.ModuleContext, configuredJars android.ConfiguredJarList, classpaths ...ClasspathType) []ClasspathJar {
+	// The java_sdk_library modules among the direct dependencies, by name.
+	sdkLibraries := make(map[string]SdkLibraryInfo)
+	ctx.VisitDirectDepsProxy(func(m android.ModuleProxy) {
+		if info, ok := android.OtherModuleProvider(ctx, m, SdkLibraryInfoProvider); ok && !info.Prebuilt {
+			sdkLibraries[ctx.OtherModuleName(m)] = info
+		}
+	})
+
+	// TODO(208456999): instead of mapping "current" to latest, min_sdk_version should never be set to "current"
+	sdkVersionString := func(level android.ApiLevel) string {
+		if level.IsCurrent() {
+			return ctx.Config().DefaultAppTargetSdk(ctx).String()
+		}
+		return level.String()
+	}
+
 	paths := configuredJars.DevicePaths(ctx.Config(), android.Android)
-	jars := make([]classpathJar, 0, len(paths)*len(classpaths))
+	jars := make([]ClasspathJar, 0, len(paths)*len(classpaths))
 	for i := 0; i < len(paths); i++ {
+		name := configuredJars.Jar(i)
+		sdkLibrary, isSdkLibrary := sdkLibraries[name]
+		if isSdkLibrary {
+			validateDeviceSdkAgainstApexMinSdk(ctx, name, sdkLibrary)
+		}
 		for _, classpathType := range classpaths {
-			jar := classpathJar{
+			jar := ClasspathJar{
 				classpath: classpathType,
 				path:      paths[i],
 			}
-			ctx.VisitDirectDepsIf(func(m android.Module) bool {
-				return m.Name() == configuredJars.Jar(i)
-			}, func(m android.Module) {
-				if s, ok := m.(*SdkLibrary); ok {
-					minSdkVersion := s.MinSdkVersion(ctx)
-					maxSdkVersion := s.MaxSdkVersion(ctx)
-					// TODO(208456999): instead of mapping "current" to latest, min_sdk_version should never be set to "current"
-					if minSdkVersion.Specified() {
-						if minSdkVersion.IsCurrent() {
-							jar.minSdkVersion = ctx.Config().DefaultAppTargetSdk(ctx).String()
-						} else {
-							jar.minSdkVersion = minSdkVersion.String()
-						}
-					}
-					if maxSdkVersion.Specified() {
-						if maxSdkVersion.IsCurrent() {
-							jar.maxSdkVersion = ctx.Config().DefaultAppTargetSdk(ctx).String()
-						} else {
-							jar.maxSdkVersion = maxSdkVersion.String()
-						}
-					}
+			if isSdkLibrary {
+				if sdkLibrary.MinSdkVersion.Specified() {
+					jar.minSdkVersion = sdkVersionString(sdkLibrary.MinSdkVersion)
+				}
+				if sdkLibrary.MaxSdkVersion.Specified() {
+					jar.maxSdkVersion = sdkVersionString(sdkLibrary.MaxSdkVersion)
 				}
-			})
+			}
 			jars = append(jars, jar)
 		}
 	}
 	return jars
 }
 
+// validateDeviceSdkAgainstApexMinSdk reports an error if the max_device_sdk of a java_sdk_library in
+// the fragment is lower than the min_sdk_version of the APEX containing the fragment. PackageManager
+// ignores the library on devices newer than max_device_sdk, so it would never be loaded from the APEX.
+func validateDeviceSdkAgainstApexMinSdk(ctx android.ModuleContext, name string, lib SdkLibraryInfo) {
+	apexInfo, _ := android.ModuleProvider(ctx, android.ApexInfoProvider)
+	apexMinSdk := apexInfo.MinSdkVersion
+	if apexInfo.IsForPlatform() || !apexMinSdk.Specified() || apexMinSdk.IsPreview() {
+		return
+	}
+	if lib.MaxDeviceSdk == nil {
+		return
+	}
+	// Invalid values are reported by the sdk library's xml module.
+	if level, err := android.ApiLevelFromUser(ctx, *lib.MaxDeviceSdk); err == nil && level.LessThan(apexMinSdk) {
+		ctx.ModuleErrorf("%s has max_device_sdk %s which is lower than the min_sdk_version %s of %s, "+
+			"so it would never be loaded", name, level, apexMinSdk, apexInfo.BaseApexName)
+	}
+}
+
 func (c *ClasspathFragmentBase) outputFilename() string {
 	return strings.ToLower(c.classpathType.String()) + ".pb"
 }
 
-func (c *ClasspathFragmentBase) generateClasspathProtoBuildActions(ctx android.ModuleContext, configuredJars android.ConfiguredJarList, jars []classpathJar) {
+// GenerateClasspathProtoBuildActions generates the classpaths.proto config of the fragment from
+// the jars, unless generate_classpaths_proto is false, and provides ClasspathFragmentProtoContentInfo.
+func (c *ClasspathFragmentBase) GenerateClasspathProtoBuildActions(ctx android.ModuleContext, configuredJars android.ConfiguredJarList, jars []ClasspathJar) {
 	generateProto := proptools.BoolDefault(c.properties.Generate_classpaths_proto, true)
 	if generateProto {
 		outputFilename := c.outputFilename()
@@ -192,11 +243,13 @@ func (c *ClasspathFragmentBase) generateClasspathProtoBuildActions(ctx android.M
 	android.SetProvider(ctx, ClasspathFragmentProtoContentInfoProvider, classpathProtoInfo)
 }
 
-func (c *ClasspathFragmentBase) installClasspathProto(ctx android.ModuleContext) android.InstallPath {
+// InstallClasspathProto installs the classpaths.proto config of the fragment into etc/classpaths
+// of the partition of the module.
+func (c *ClasspathFragmentBase) InstallClasspathProto(ctx android.ModuleContext) android.InstallPath {
 	return ctx.InstallFile(c.installDirPath, c.outputFilename(), c.outputFilepath)
 }
 
-func writeClasspathsTextproto(ctx android.ModuleContext, output android.WritablePath, jars []classpathJar) {
+func writeClasspathsTextproto(ctx android.ModuleContext, output android.WritablePath, jars []ClasspathJar) {
 	var content strings.Builder
 
 	for _, jar := range jars {
@@ -213,7 +266,7 @@ func writeClasspathsTextproto(ctx android.ModuleContext, output android.Writable
 
 // Returns AndroidMkEntries objects to install generated classpath.proto.
 // Do not use this to install into APEXes as the injection of the generated files happen separately for APEXes.
-func (c *ClasspathFragmentBase) androidMkEntries() []android.AndroidMkEntries {
+func (c *ClasspathFragmentBase) ClasspathFragmentAndroidMkEntries() []android.AndroidMkEntries {
 	return []android.AndroidMkEntries{{
 		Class:      "ETC",
 		OutputFile: android.OptionalPathForPath(c.outputFilepath),
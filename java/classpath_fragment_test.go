@@ -0,0 +1,92 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+// testProductClasspathFragment is a classpath fragment module type that only uses the exported
+// API, as a module type from a vendor soong plugin would.
+type testProductClasspathFragment struct {
+	android.ModuleBase
+	ClasspathFragmentBase
+
+	properties struct {
+		Jars []string
+	}
+}
+
+func testProductClasspathFragmentFactory() android.Module {
+	m := &testProductClasspathFragment{}
+	m.AddProperties(&m.properties)
+	android.InitAndroidArchModule(m, android.DeviceSupported, android.MultilibCommon)
+	InitClasspathFragment(m, SYSTEMSERVERCLASSPATH)
+	return m
+}
+
+func (m *testProductClasspathFragment) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	var jars []ClasspathJar
+	var pairs []string
+	for _, jar := range m.properties.Jars {
+		jars = append(jars, NewClasspathJar("/product/framework/"+jar+".jar", m.ClasspathType(), "34", ""))
+		pairs = append(pairs, "product:"+jar)
+	}
+	configuredJars := android.CreateTestConfiguredJarList(pairs)
+	m.GenerateClasspathProtoBuildActions(ctx, configuredJars, jars)
+	m.InstallClasspathProto(ctx)
+}
+
+func TestCustomClasspathFragment(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureRegisterWithContext(func(ctx android.RegistrationContext) {
+			ctx.RegisterModuleType("test_product_classpath_fragment", testProductClasspathFragmentFactory)
+		}),
+	).RunTestWithBp(t, `
+		test_product_classpath_fragment {
+			name: "product-classpath",
+			product_specific: true,
+			jars: ["foo", "bar"],
+		}
+	`)
+
+	module := result.ModuleForTests(t, "product-classpath", "android_common")
+	textproto := android.ContentFromFileRuleForTests(t, result.TestContext,
+		module.Output("systemserverclasspath.pb.textproto"))
+	android.AssertStringEquals(t, "textproto", `jars {
+path: "/product/framework/foo.jar"
+classpath: SYSTEMSERVERCLASSPATH
+min_sdk_version: "34"
+max_sdk_version: ""
+}
+jars {
+path: "/product/framework/bar.jar"
+classpath: SYSTEMSERVERCLASSPATH
+min_sdk_version: "34"
+max_sdk_version: ""
+}
+`, textproto)
+
+	info, _ := android.OtherModuleProvider(result, module.Module(), ClasspathFragmentProtoContentInfoProvider)
+	android.AssertBoolEquals(t, "classpath proto generated", true, info.ClasspathFragmentProtoGenerated)
+	android.AssertStringEquals(t, "classpath proto contents", "product:foo,product:bar",
+		info.ClasspathFragmentProtoContents.String())
+	android.AssertPathRelativeToTopEquals(t, "install dir",
+		"out/target/product/test_device/product/etc/classpaths", info.ClasspathFragmentProtoInstallDir)
+}
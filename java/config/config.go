@@ -36,6 +36,10 @@ var (
 	DefaultLambdaStubsLibrary                = "core-lambda-stubs"
 	SdkLambdaStubsPath                       = "prebuilts/sdk/tools/core-lambda-stubs.jar"
 
+	// The script that runs annotation processors without network access when
+	// SOONG_JAVA_NETWORK_SANDBOX is set.
+	NetworkSandboxPath = "build/soong/scripts/network-sandbox.sh"
+
 	DefaultMakeJacocoExcludeFilter = []string{"org.junit.*", "org.jacoco.*", "org.mockito.*"}
 	DefaultJacocoExcludeFilter     = []string{"org.junit.**", "org.jacoco.**", "org.mockito.**"}
 
@@ -151,6 +155,7 @@ func init() {
 	pctx.SourcePathVariable("JavadocCmd", "${JavaToolchain}/javadoc")
 	pctx.SourcePathVariable("JlinkCmd", "${JavaToolchain}/jlink")
 	pctx.SourcePathVariable("JmodCmd", "${JavaToolchain}/jmod")
+	pctx.SourcePathVariable("JimageCmd", "${JavaToolchain}/jimage")
 	pctx.SourcePathVariable("JrtFsJar", "${JavaHome}/lib/jrt-fs.jar")
 	pctx.SourcePathVariable("JavaKytheExtractorJar", "prebuilts/build-tools/common/framework/javac_extractor.jar")
 	pctx.SourcePathVariable("KotlinKytheExtractor", "prebuilts/build-tools/${hostPrebuiltTag}/bin/kotlinc_extractor")
@@ -163,6 +168,8 @@ func init() {
 
 	pctx.SourcePathVariable("JarArgsCmd", "build/soong/scripts/jar-args.sh")
 	pctx.SourcePathVariable("PackageCheckCmd", "build/soong/scripts/package-check.sh")
+	pctx.SourcePathVariable("MergeJarjarRulesCmd", "build/soong/scripts/merge-jarjar-rules.sh")
+	pctx.SourcePathVariable("NetworkSandboxCmd", NetworkSandboxPath)
 	pctx.HostBinToolVariable("ExtractJarPackagesCmd", "extract_jar_packages")
 	pctx.HostBinToolVariable("SoongZipCmd", "soong_zip")
 	pctx.HostBinToolVariable("MergeZipsCmd", "merge_zips")
@@ -193,9 +200,11 @@ func init() {
 	pctx.HostJavaToolVariable("JetifierJar", "jetifier.jar")
 	pctx.HostJavaToolVariable("R8Jar", "r8.jar")
 	pctx.HostJavaToolVariable("D8Jar", "d8.jar")
+	pctx.HostJavaToolVariable("BundletoolJar", "bundletool.jar")
 
 	pctx.HostBinToolVariable("SoongJavacWrapper", "soong_javac_wrapper")
 	pctx.HostBinToolVariable("DexpreoptGen", "dexpreopt_gen")
+	pctx.HostBinToolVariable("ApksignerCmd", "apksigner")
 
 	pctx.StaticVariableWithEnvOverride("REJavaPool", "RBE_JAVA_POOL", "java16")
 	pctx.StaticVariableWithEnvOverride("REJavacExecStrategy", "RBE_JAVAC_EXEC_STRATEGY", remoteexec.RemoteLocalFallbackExecStrategy)
@@ -210,6 +219,7 @@ func init() {
 
 	pctx.HostBinToolVariable("ManifestCheckCmd", "manifest_check")
 	pctx.HostBinToolVariable("ManifestFixerCmd", "manifest_fixer")
+	pctx.HostBinToolVariable("PermittedPackagesCheckCmd", "permitted_packages_check")
 
 	pctx.HostBinToolVariable("ManifestMergerCmd", "manifest-merger")
 
@@ -22,6 +22,7 @@ var (
 		"-no-jdk",
 		"-no-stdlib",
 		"-language-version",
+		"-api-version",
 	}
 )
 
@@ -37,6 +38,19 @@ func init() {
 	pctx.SourcePathVariable("KotlinStdlibJar", KotlinStdlibJar)
 	pctx.SourcePathVariable("KotlinAbiGenPluginJar", "external/kotlinc/lib/jvm-abi-gen.jar")
 
+	// KSP runs standalone rather than as a kotlinc plugin, with its dependencies on the classpath.
+	pctx.SourcePathVariable("KspApiJar", "external/kotlinc/lib/symbol-processing-api.jar")
+	pctx.SourcePathVariable("KspCommonDepsJar", "external/kotlinc/lib/symbol-processing-common-deps.jar")
+	pctx.SourcePathVariable("KspAaEmbeddableJar", "external/kotlinc/lib/symbol-processing-aa-embeddable.jar")
+	pctx.SourcePathVariable("KotlinCoroutinesJar", "external/kotlinc/lib/kotlinx-coroutines-core-jvm.jar")
+	pctx.StaticVariable("KspClasspath", strings.Join([]string{
+		"${KspApiJar}",
+		"${KspCommonDepsJar}",
+		"${KspAaEmbeddableJar}",
+		"${KotlinStdlibJar}",
+		"${KotlinCoroutinesJar}",
+	}, ":"))
+
 	// These flags silence "Illegal reflective access" warnings when running kapt in OpenJDK9+
 	pctx.StaticVariable("KaptSuppressJDK9Warnings", strings.Join([]string{
 		"-J--add-exports=jdk.compiler/com.sun.tools.javac.file=ALL-UNNAMED",
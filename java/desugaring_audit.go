@@ -0,0 +1,126 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+	"android/soong/java/config"
+)
+
+// D8 backports APIs that are not available at the min_sdk_version of a module, and rewrites the
+// references to APIs provided by the desugared library to its classes in the j$ package, neither of
+// which is visible in the sources. When DESUGARING_AUDIT=true every module that is dexed writes a
+// report of the backported methods its classes use and the desugared library classes its dex code
+// references, and the
+// desugaring_audit singleton merges them into desugaring_audit.txt, which is disted so that the
+// desugaring needed by each module can be tracked when deciding on min_sdk_version bumps. Each line
+// of the report has the form "<module>: backport <class>#<method><descriptor>" or
+// "<module>: library <class>".
+
+// DesugaringAuditInfo contains the report of the APIs that D8 desugars in a module.
+type DesugaringAuditInfo struct {
+	// The report of the desugared APIs used by the classes of the module.
+	Report android.Path
+}
+
+var DesugaringAuditInfoProvider = blueprint.NewProvider[DesugaringAuditInfo]()
+
+func desugaringAuditEnabled(config android.Config) bool {
+	return config.IsEnvTrue("DESUGARING_AUDIT")
+}
+
+// desugaringAudit writes the report of the APIs in the classes jar that D8 desugars with the
+// given common dex flags into dexJar, if the audit is enabled.
+func (d *dexer) desugaringAudit(ctx android.ModuleContext, dexParams *compileDexParams, commonFlags []string,
+	dexJar android.Path) {
+	if !desugaringAuditEnabled(ctx.Config()) {
+		return
+	}
+
+	// The backported methods depend on the same flags as the desugaring done by D8.
+	var flags []string
+	for _, flag := range commonFlags {
+		if strings.HasPrefix(flag, "--min-api ") || flag == "--android-platform-build" {
+			flags = append(flags, flag)
+		}
+	}
+
+	backportedMethods := android.PathForModuleOut(ctx, "desugaring", dexParams.jarName+".backported.txt")
+	report := android.PathForModuleOut(ctx, "desugaring", dexParams.jarName+".audit.txt")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		Tool(config.JavaCmd(ctx)).
+		FlagWithInput("-cp ", ctx.Config().HostJavaToolPath(ctx, "r8.jar")).
+		Text("com.android.tools.r8.BackportedMethodList").
+		Flags(flags).
+		FlagWithOutput("--output ", backportedMethods)
+	rule.Command().
+		BuiltTool("desugaring_audit").
+		Text("generate").
+		FlagWithInput("--backported-methods ", backportedMethods).
+		FlagWithArg("--module ", ctx.ModuleName()).
+		FlagWithInput("--dex-jar ", dexJar).
+		FlagWithOutput("--output ", report).
+		Input(dexParams.classesJar)
+	rule.Build("desugaring_audit", "desugaring audit")
+
+	android.SetProvider(ctx, DesugaringAuditInfoProvider, DesugaringAuditInfo{
+		Report: report,
+	})
+}
+
+// desugaringAuditSingleton merges the desugaring audit reports of all modules.
+type desugaringAuditSingleton struct{}
+
+func (s *desugaringAuditSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	if !desugaringAuditEnabled(ctx.Config()) {
+		return
+	}
+
+	var reports android.Paths
+	ctx.VisitAllModuleProxies(func(module android.ModuleProxy) {
+		if !android.OtherModulePointerProviderOrDefault(ctx, module, android.CommonModuleInfoProvider).Enabled {
+			return
+		}
+		if info, ok := android.OtherModuleProvider(ctx, module, DesugaringAuditInfoProvider); ok {
+			reports = append(reports, info.Report)
+		}
+	})
+
+	report := desugaringAuditPath(ctx)
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		BuiltTool("desugaring_audit").
+		Text("merge").
+		FlagWithOutput("--output ", report).
+		FlagWithRspFileInputList("@", android.PathForOutput(ctx, "desugaring", "desugaring_audit.rsp"),
+			android.SortedUniquePaths(reports))
+	rule.Build("desugaring_audit", "merge desugaring audit reports")
+
+	ctx.Phony("desugaring_audit", report)
+	ctx.DistForGoal("droidcore", report)
+}
+
+func desugaringAuditPath(ctx android.PathContext) android.WritablePath {
+	return android.PathForOutput(ctx, "desugaring", "desugaring_audit.txt")
+}
+
+func desugaringAuditSingletonFactory() android.Singleton {
+	return &desugaringAuditSingleton{}
+}
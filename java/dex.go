@@ -33,7 +33,12 @@ type DexProperties struct {
 	Dxflags []string `android:"arch_variant"`
 
 	// A list of files containing rules that specify the classes to keep in the main dex file.
-	Main_dex_rules []string `android:"path"`
+	Main_dex_rules []string `android:"path,arch_variant"`
+
+	// If set, the minimum sdk version passed to the dex compiler instead of min_sdk_version.
+	// This is usually set per architecture, e.g. arch: { arm: { dex_min_sdk_version: "..." } },
+	// to use a different dex output on products whose primary architecture is 32-bit.
+	Dex_min_sdk_version *string `android:"arch_variant"`
 
 	Optimize struct {
 		// If false, disable all optimization.  Defaults to true for android_app and
@@ -108,6 +113,11 @@ type DexProperties struct {
 		// module's proguard spec appended to their optimization action
 		Export_proguard_flags_files *bool
 
+		// If false, the proguard flags files are not checked for malformed class specifications
+		// when the module is built.  Unknown options are only reported as warnings.  Defaults to
+		// true.
+		Check_proguard_flags_files *bool
+
 		// Path to a file containing a list of class names that should not be compiled using R8.
 		// These classes will be compiled by D8 similar to when Optimize.Enabled is false.
 		//
@@ -159,6 +169,8 @@ type dexer struct {
 	proguardDictionary      android.OptionalPath
 	proguardConfiguration   android.OptionalPath
 	proguardUsageZip        android.OptionalPath
+	r8FlagsFiles            android.Paths
+	annotatedProguardFlags  android.OptionalPath
 	resourcesInput          android.OptionalPath
 	resourcesOutput         android.OptionalPath
 
@@ -200,11 +212,13 @@ var d8, d8RE = pctx.MultiCommandRemoteStaticRules("d8",
 		},
 	}, map[string]*remoteexec.REParams{
 		"$d8Template": &remoteexec.REParams{
-			Labels:          map[string]string{"type": "compile", "compiler": "d8"},
-			Inputs:          []string{"${config.D8Jar}"},
-			ExecStrategy:    "${config.RED8ExecStrategy}",
-			ToolchainInputs: []string{"${config.JavaCmd}"},
-			Platform:        map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
+			Labels:            map[string]string{"type": "compile", "compiler": "d8"},
+			Inputs:            []string{"$implicits", "${config.D8Jar}", "$in"},
+			OutputFiles:       []string{"${outD8ArtProfile}"},
+			OutputDirectories: []string{"$outDir"},
+			ExecStrategy:      "${config.RED8ExecStrategy}",
+			ToolchainInputs:   []string{"${config.JavaCmd}"},
+			Platform:          map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
 		},
 		"$zipTemplate": &remoteexec.REParams{
 			Labels:       map[string]string{"type": "tool", "name": "soong_zip"},
@@ -213,7 +227,7 @@ var d8, d8RE = pctx.MultiCommandRemoteStaticRules("d8",
 			ExecStrategy: "${config.RED8ExecStrategy}",
 			Platform:     map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
 		},
-	}, []string{"outDir", "d8Flags", "zipFlags", "mergeZipsFlags"}, nil)
+	}, []string{"outDir", "d8Flags", "zipFlags", "mergeZipsFlags", "outD8ArtProfile"}, []string{"implicits"})
 
 // Include all of the args for d8r8, so that we can generate the partialcompileclean target's build using the same list.
 var d8r8Clean = pctx.AndroidStaticRule("d8r8-partialcompileclean",
@@ -221,7 +235,8 @@ var d8r8Clean = pctx.AndroidStaticRule("d8r8-partialcompileclean",
 		Command: `rm -rf "${outDir}" "${outDict}" "${outConfig}" "${outUsage}" "${outUsageZip}" "${outUsageDir}" ` +
 			`"${resourcesOutput}" "${outR8ArtProfile}" ${builtOut}`,
 	}, "outDir", "outDict", "outConfig", "outUsage", "outUsageZip", "outUsageDir", "builtOut",
-	"d8Flags", "r8Flags", "zipFlags", "mergeZipsFlags", "resourcesOutput", "outR8ArtProfile", "implicits",
+	"d8Flags", "r8Flags", "zipFlags", "mergeZipsFlags", "resourcesOutput", "outR8ArtProfile", "outD8ArtProfile",
+	"implicits",
 )
 
 var d8r8, d8r8RE = pctx.MultiCommandRemoteStaticRules("d8r8",
@@ -256,19 +271,23 @@ var d8r8, d8r8RE = pctx.MultiCommandRemoteStaticRules("d8r8",
 		},
 	}, map[string]*remoteexec.REParams{
 		"$d8Template": &remoteexec.REParams{
-			Labels:          map[string]string{"type": "compile", "compiler": "d8"},
-			Inputs:          []string{"${config.D8Jar}"},
-			ExecStrategy:    "${config.RED8ExecStrategy}",
-			ToolchainInputs: []string{"${config.JavaCmd}"},
-			Platform:        map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
+			Labels:            map[string]string{"type": "compile", "compiler": "d8"},
+			Inputs:            []string{"$implicits", "${config.D8Jar}", "$in"},
+			OutputFiles:       []string{"${outD8ArtProfile}"},
+			OutputDirectories: []string{"$outDir"},
+			ExecStrategy:      "${config.RED8ExecStrategy}",
+			ToolchainInputs:   []string{"${config.JavaCmd}"},
+			Platform:          map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
 		},
 		"$r8Template": &remoteexec.REParams{
-			Labels:          map[string]string{"type": "compile", "compiler": "r8"},
-			Inputs:          []string{"$implicits", "${config.R8Jar}"},
-			OutputFiles:     []string{"${outUsage}", "${outConfig}", "${outDict}", "${resourcesOutput}", "${outR8ArtProfile}"},
-			ExecStrategy:    "${config.RER8ExecStrategy}",
-			ToolchainInputs: []string{"${config.JavaCmd}"},
-			Platform:        map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
+			Labels: map[string]string{"type": "compile", "compiler": "r8"},
+			Inputs: []string{"$implicits", "${config.R8Jar}", "$in"},
+			OutputFiles: []string{"${outUsage}", "${outConfig}", "${outDict}", "${resourcesOutput}",
+				"${outR8ArtProfile}", "${out}.d"},
+			OutputDirectories: []string{"$outDir"},
+			ExecStrategy:      "${config.RER8ExecStrategy}",
+			ToolchainInputs:   []string{"${config.JavaCmd}"},
+			Platform:          map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
 		},
 		"$zipTemplate": &remoteexec.REParams{
 			Labels:       map[string]string{"type": "tool", "name": "soong_zip"},
@@ -278,7 +297,8 @@ var d8r8, d8r8RE = pctx.MultiCommandRemoteStaticRules("d8r8",
 			Platform:     map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
 		},
 	}, []string{"outDir", "outDict", "outConfig", "outUsage", "outUsageZip", "outUsageDir",
-		"d8Flags", "r8Flags", "zipFlags", "mergeZipsFlags", "resourcesOutput", "outR8ArtProfile"}, []string{"implicits"})
+		"d8Flags", "r8Flags", "zipFlags", "mergeZipsFlags", "resourcesOutput", "outR8ArtProfile", "outD8ArtProfile"},
+	[]string{"implicits"})
 
 var r8, r8RE = pctx.MultiCommandRemoteStaticRules("r8",
 	blueprint.RuleParams{
@@ -306,12 +326,14 @@ var r8, r8RE = pctx.MultiCommandRemoteStaticRules("r8",
 		},
 	}, map[string]*remoteexec.REParams{
 		"$r8Template": &remoteexec.REParams{
-			Labels:          map[string]string{"type": "compile", "compiler": "r8"},
-			Inputs:          []string{"$implicits", "${config.R8Jar}"},
-			OutputFiles:     []string{"${outUsage}", "${outConfig}", "${outDict}", "${resourcesOutput}", "${outR8ArtProfile}"},
-			ExecStrategy:    "${config.RER8ExecStrategy}",
-			ToolchainInputs: []string{"${config.JavaCmd}"},
-			Platform:        map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
+			Labels: map[string]string{"type": "compile", "compiler": "r8"},
+			Inputs: []string{"$implicits", "${config.R8Jar}", "$in"},
+			OutputFiles: []string{"${outUsage}", "${outConfig}", "${outDict}", "${resourcesOutput}",
+				"${outR8ArtProfile}", "${out}.d"},
+			OutputDirectories: []string{"$outDir"},
+			ExecStrategy:      "${config.RER8ExecStrategy}",
+			ToolchainInputs:   []string{"${config.JavaCmd}"},
+			Platform:          map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
 		},
 		"$zipTemplate": &remoteexec.REParams{
 			Labels:       map[string]string{"type": "tool", "name": "soong_zip"},
@@ -497,6 +519,10 @@ func (d *dexer) r8Flags(ctx android.ModuleContext, dexParams *compileDexParams,
 	}
 
 	flagFiles = android.FirstUniquePaths(flagFiles)
+	d.r8FlagsFiles = flagFiles
+	if ctx.Config().IsEnvTrue("ANNOTATE_PROGUARD_FLAGS") {
+		d.annotatedProguardFlags = android.OptionalPathForPath(annotateProguardFlags(ctx, flagFiles))
+	}
 
 	r8Flags = append(r8Flags, android.JoinWithPrefix(flagFiles.Strings(), "-include "))
 	r8Deps = append(r8Deps, flagFiles...)
@@ -623,6 +649,15 @@ func (d *dexer) compileDex(ctx android.ModuleContext, dexParams *compileDexParam
 	cleanPhonyPath := android.PathForModuleOut(ctx, "dex", dexParams.jarName+"-partialcompileclean").OutputPath
 	outDir := android.PathForModuleOut(ctx, "dex")
 
+	if v := proptools.String(d.dexProperties.Dex_min_sdk_version); v != "" {
+		minSdkVersion, err := android.ApiLevelFromUser(ctx, v)
+		if err != nil {
+			ctx.PropertyErrorf("dex_min_sdk_version", "%s", err)
+		} else {
+			dexParams.minSdkVersion = minSdkVersion
+		}
+	}
+
 	zipFlags := "--ignore_missing_files"
 	if proptools.Bool(d.dexProperties.Uncompress_dex) {
 		zipFlags += " -L 0"
@@ -643,6 +678,7 @@ func (d *dexer) compileDex(ctx android.ModuleContext, dexParams *compileDexParam
 	var rule blueprint.Rule
 	var description string
 	var artProfileOutputPath *android.OutputPath
+	var r8ArtProfile android.Path
 	var implicitOutputs android.WritablePaths
 	var deps android.Paths
 	args := map[string]string{
@@ -671,9 +707,11 @@ func (d *dexer) compileDex(ctx android.ModuleContext, dexParams *compileDexParam
 		debugMode := android.InList("--debug", commonFlags)
 		r8Flags, r8Deps, r8ArtProfileOutputPath := d.r8Flags(ctx, dexParams, debugMode)
 		deps = append(deps, r8Deps...)
+		deps = append(deps, commonDeps...)
 		args["r8Flags"] = strings.Join(append(commonFlags, r8Flags...), " ")
 		if r8ArtProfileOutputPath != nil {
 			artProfileOutputPath = r8ArtProfileOutputPath
+			r8ArtProfile = *r8ArtProfileOutputPath
 			// Add the implicit r8 Art profile output to args so that r8RE knows
 			// about this implicit output
 			args["outR8ArtProfile"] = r8ArtProfileOutputPath.String()
@@ -691,7 +729,6 @@ func (d *dexer) compileDex(ctx android.ModuleContext, dexParams *compileDexParam
 		rule = r8
 		if rbeR8 {
 			rule = r8RE
-			args["implicits"] = strings.Join(deps.Strings(), ",")
 		}
 	}
 	if useD8 {
@@ -702,21 +739,30 @@ func (d *dexer) compileDex(ctx android.ModuleContext, dexParams *compileDexParam
 		args["d8Flags"] = strings.Join(append(commonFlags, d8Flags...), " ")
 		if d8ArtProfileOutputPath != nil {
 			artProfileOutputPath = d8ArtProfileOutputPath
+			// Add the implicit d8 Art profile output to args so that d8RE knows
+			// about this implicit output
+			args["outD8ArtProfile"] = d8ArtProfileOutputPath.String()
 		}
 		// If we are generating both d8 and r8, only use RBE when both are enabled.
 		switch {
-		case useR8 && rule == r8:
-			rule = d8r8
-			description = "d8r8"
-		case useR8 && rule == r8RE && rbeD8:
+		case useR8 && rbeR8 && rbeD8:
 			rule = d8r8RE
 			description = "d8r8"
+		case useR8:
+			rule = d8r8
+			description = "d8r8"
 		case rbeD8:
 			rule = d8RE
 		default:
 			rule = d8
 		}
 	}
+	deps = android.FirstUniquePaths(deps)
+	if rule == r8RE || rule == d8RE || rule == d8r8RE {
+		// The remote action needs all the inputs of the local one, including the proguard flags
+		// files and the Art profile.
+		args["implicits"] = strings.Join(deps.Strings(), ",")
+	}
 	if artProfileOutputPath != nil {
 		implicitOutputs = append(
 			implicitOutputs,
@@ -732,6 +778,20 @@ func (d *dexer) compileDex(ctx android.ModuleContext, dexParams *compileDexParam
 		Implicits:       deps,
 		Args:            args,
 	})
+	d.desugaringAudit(ctx, dexParams, commonFlags, javalibJar)
+	if useR8 {
+		r8Info := R8Info{
+			MappingFile:   d.proguardDictionary.Path(),
+			Configuration: d.proguardConfiguration.Path(),
+			UsageZip:      d.proguardUsageZip.Path(),
+			DexInputs:     android.Paths{dexParams.classesJar},
+			FlagsFiles:    d.r8FlagsFiles,
+			Flags:         args["r8Flags"],
+			DexJar:        javalibJar,
+			ArtProfile:    r8ArtProfile,
+		}
+		android.SetProvider(ctx, R8InfoProvider, r8Info)
+	}
 	if useR8 && useD8 {
 		// Generate the rule for partial compile clean.
 		args["builtOut"] = javalibJar.String()
@@ -754,6 +814,40 @@ func (d *dexer) compileDex(ctx android.ModuleContext, dexParams *compileDexParam
 	return javalibJar, artProfileOutputPath
 }
 
+// annotateProguardFlags writes the proguard flags files passed to R8 into a single file in which
+// each flag is annotated with the module and the file it came from. It isn't used by R8, it is
+// only generated when ANNOTATE_PROGUARD_FLAGS=true and is available through the
+// ".proguard_flags_annotated" output tag to find the origin of keep rules.
+// Files that don't come from a dependency, e.g. the global flags files and the flags generated by
+// aapt2, are attributed to the current module.
+func annotateProguardFlags(ctx android.ModuleContext, flagFiles android.Paths) android.Path {
+	owners := make(map[string]string)
+	ctx.VisitDirectDepsProxy(func(m android.ModuleProxy) {
+		if depProguardInfo, ok := android.OtherModuleProvider(ctx, m, ProguardSpecInfoProvider); ok {
+			for _, owner := range depProguardInfo.ProguardFlagsFileOwners.ToList() {
+				if _, exists := owners[owner.File.String()]; !exists {
+					owners[owner.File.String()] = owner.Module
+				}
+			}
+		}
+	})
+
+	annotated := android.PathForModuleOut(ctx, "proguard", "annotated_proguard_flags.txt")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	cmd := rule.Command().
+		BuiltTool("annotate_proguard_flags").
+		FlagWithOutput("--output ", annotated)
+	for _, file := range flagFiles {
+		owner, ok := owners[file.String()]
+		if !ok {
+			owner = ctx.ModuleName()
+		}
+		cmd.Flag("--flags").Text(owner).Input(file)
+	}
+	rule.Build("annotate_proguard_flags", "annotate proguard flags")
+	return annotated
+}
+
 type ProguardInfo struct {
 	ModuleName         string
 	Class              string
@@ -763,3 +857,26 @@ type ProguardInfo struct {
 }
 
 var ProguardProvider = blueprint.NewProvider[ProguardInfo]()
+
+// R8Info describes the inputs and outputs of the R8 invocation of a module, so that modules that
+// postprocess the optimized dex code (e.g. to symbolicate stack traces) don't need to hardcode paths.
+type R8Info struct {
+	// The mapping from the obfuscated to the original names.
+	MappingFile android.Path
+	// The full configuration used by R8 after merging all the flags files.
+	Configuration android.Path
+	// The list of classes and members removed by R8, zipped.
+	UsageZip android.Path
+	// The ART profile rewritten to match the optimized dex code, or nil if no profile was given.
+	ArtProfile android.Path
+	// The jars compiled into dex code by R8.
+	DexInputs android.Paths
+	// The proguard flags files passed to R8.
+	FlagsFiles android.Paths
+	// The flags passed to R8 on the command line.
+	Flags string
+	// The dex jar produced by R8, before any alignment.
+	DexJar android.Path
+}
+
+var R8InfoProvider = blueprint.NewProvider[R8Info]()
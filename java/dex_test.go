@@ -17,6 +17,7 @@ package java
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"testing"
 
 	"android/soong/android"
@@ -357,6 +358,156 @@ func TestD8(t *testing.T) {
 		fooD8.Args["d8Flags"], "--debug")
 }
 
+func TestDexRemoteExecution(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["foo.java"],
+			libs: ["lib"],
+			installable: true,
+		}
+
+		java_library {
+			name: "lib",
+			srcs: ["foo.java"],
+		}
+
+		android_app {
+			name: "app",
+			srcs: ["foo.java"],
+			platform_apis: true,
+			optimize: {
+				proguard_flags_files: ["proguard.flags"],
+			},
+			dex_preopt: {
+				profile_guided: true,
+				profile: "profile.txt.prof",
+				enable_profile_rewriting: true,
+			},
+		}
+	`
+	preparer := func(env map[string]string) android.FixturePreparer {
+		return android.GroupFixturePreparers(
+			PrepareForTestWithJavaDefaultModules,
+			android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+				variables.UseRBE = proptools.BoolPtr(true)
+			}),
+			android.FixtureMergeMockFs(android.MockFS{
+				"proguard.flags": nil,
+			}),
+			android.FixtureMergeEnv(env),
+		)
+	}
+
+	t.Run("r8", func(t *testing.T) {
+		t.Parallel()
+		result := preparer(map[string]string{"RBE_R8": "true"}).RunTestWithBp(t, bp)
+
+		r8 := result.ModuleForTests(t, "app", "android_common").Rule("java.r8RE")
+		android.AssertStringDoesContain(t, "r8 inputs", r8.RuleParams.Command,
+			"--inputs=$implicits,${config.R8Jar},$in")
+		android.AssertStringDoesContain(t, "r8 output directories", r8.RuleParams.Command,
+			"--output_directories=$outDir")
+		implicits := strings.Split(android.StringRelativeToTop(result.Config, r8.Args["implicits"]), ",")
+		android.AssertStringListContains(t, "r8 implicits", implicits, "proguard.flags")
+		android.AssertStringListContains(t, "r8 implicits", implicits, "build/make/core/proguard.flags")
+		android.AssertStringListContains(t, "r8 implicits", implicits, "profile.txt.prof")
+
+		// d8 isn't run remotely unless RBE_D8 is set.
+		if result.ModuleForTests(t, "foo", "android_common").MaybeRule("java.d8RE").Rule != nil {
+			t.Errorf("expected foo to run d8 locally")
+		}
+	})
+
+	t.Run("d8", func(t *testing.T) {
+		t.Parallel()
+		result := preparer(map[string]string{"RBE_D8": "true"}).RunTestWithBp(t, bp)
+
+		d8 := result.ModuleForTests(t, "foo", "android_common").Rule("java.d8RE")
+		android.AssertStringDoesContain(t, "d8 inputs", d8.RuleParams.Command,
+			"--inputs=$implicits,${config.D8Jar},$in")
+		android.AssertStringDoesContain(t, "d8 output directories", d8.RuleParams.Command,
+			"--output_directories=$outDir")
+		implicits := strings.Split(android.StringRelativeToTop(result.Config, d8.Args["implicits"]), ",")
+		android.AssertStringListContains(t, "d8 implicits", implicits,
+			"out/soong/.intermediates/lib/android_common/turbine-combined/lib.jar")
+
+		// r8 isn't run remotely unless RBE_R8 is set.
+		if result.ModuleForTests(t, "app", "android_common").MaybeRule("java.r8RE").Rule != nil {
+			t.Errorf("expected app to run r8 locally")
+		}
+	})
+}
+
+func TestDexPrimaryDeviceArchProperties(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["foo.java"],
+			installable: true,
+			min_sdk_version: "29",
+			arch: {
+				arm64: {
+					dxflags: ["--arm64-flag"],
+				},
+				arm: {
+					dxflags: ["--arm-flag"],
+				},
+			},
+			multilib: {
+				lib32: {
+					dex_min_sdk_version: "31",
+				},
+			},
+		}
+	`
+
+	testCases := []struct {
+		name           string
+		targets        []android.Target
+		expectedFlag   string
+		unexpectedFlag string
+		expectedMinApi string
+	}{
+		{
+			name:           "64-bit",
+			expectedFlag:   "--arm64-flag",
+			unexpectedFlag: "--arm-flag",
+			expectedMinApi: "--min-api 29",
+		},
+		{
+			name: "32-bit only",
+			targets: []android.Target{
+				{Os: android.Android, Arch: android.Arch{ArchType: android.Arm, ArchVariant: "armv7-a-neon", Abi: []string{"armeabi-v7a"}}},
+			},
+			expectedFlag:   "--arm-flag",
+			unexpectedFlag: "--arm64-flag",
+			expectedMinApi: "--min-api 31",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result := android.GroupFixturePreparers(
+				PrepareForTestWithJavaDefaultModules,
+				android.FixtureModifyConfig(func(config android.Config) {
+					if tc.targets != nil {
+						config.Targets[android.Android] = tc.targets
+					}
+				}),
+			).RunTestWithBp(t, bp)
+
+			d8Flags := result.ModuleForTests(t, "foo", "android_common").Rule("d8").Args["d8Flags"]
+			android.AssertStringDoesContain(t, "d8 flags", d8Flags, tc.expectedFlag)
+			android.AssertStringDoesNotContain(t, "d8 flags", d8Flags, tc.unexpectedFlag)
+			android.AssertStringDoesContain(t, "d8 flags", d8Flags, tc.expectedMinApi)
+		})
+	}
+}
+
 func TestProguardFlagsInheritanceStatic(t *testing.T) {
 	t.Parallel()
 	result := PrepareForTestWithJavaDefaultModules.RunTestWithBp(t, `
@@ -696,6 +847,100 @@ func TestProguardFlagsInheritanceAppImport(t *testing.T) {
 		appR8.Args["r8Flags"], "proguard.txt")
 }
 
+func TestProguardFlagsInheritanceJavaImport(t *testing.T) {
+	t.Parallel()
+	bp := `
+		android_app {
+			name: "app",
+			static_libs: ["import"],
+			platform_apis: true,
+		}
+
+		java_import {
+			name: "import",
+			jars: ["import.jar"],
+			proguard_flags_files: ["import.flags"],
+		}
+	`
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+	).RunTestWithBp(t, bp)
+
+	appR8 := result.ModuleForTests(t, "app", "android_common").Rule("r8")
+	android.AssertStringDoesContain(t, "expected java_import's proguard flags",
+		appR8.Args["r8Flags"], "import.flags")
+	android.AssertStringDoesContain(t, "expected java_import's embedded R8 rules",
+		appR8.Args["r8Flags"], "import/android_common/proguard_flags")
+}
+
+func TestAnnotatedProguardFlags(t *testing.T) {
+	t.Parallel()
+	bp := `
+		android_app {
+			name: "app",
+			srcs: ["foo.java"],
+			static_libs: [
+				"primary_lib",
+				"import",
+			],
+			platform_apis: true,
+			optimize: {
+				proguard_flags_files: ["app.flags"],
+			},
+		}
+
+		java_library {
+			name: "primary_lib",
+			static_libs: ["secondary_lib"],
+			optimize: {
+				proguard_flags_files: ["primary.flags"],
+			},
+		}
+
+		java_library {
+			name: "secondary_lib",
+			optimize: {
+				proguard_flags_files: ["secondary.flags"],
+			},
+		}
+
+		java_import {
+			name: "import",
+			jars: ["import.jar"],
+			proguard_flags_files: ["import.flags"],
+		}
+	`
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureMergeEnv(map[string]string{
+			"ANNOTATE_PROGUARD_FLAGS": "true",
+		}),
+	).RunTestWithBp(t, bp)
+
+	app := result.ModuleForTests(t, "app", "android_common")
+	annotated := app.Output("proguard/annotated_proguard_flags.txt")
+	for _, flags := range []string{
+		"--flags app build/make/core/proguard.flags",
+		"--flags app app.flags",
+		"--flags primary_lib primary.flags",
+		"--flags secondary_lib secondary.flags",
+		"--flags import import.flags",
+		"--flags import out/soong/.intermediates/import/android_common/proguard_flags",
+	} {
+		android.AssertStringDoesContain(t, "annotate command",
+			android.StringRelativeToTop(result.Config, annotated.RuleParams.Command), flags)
+	}
+	android.AssertPathsRelativeToTopEquals(t, "annotated flags output",
+		[]string{"out/soong/.intermediates/app/android_common/proguard/annotated_proguard_flags.txt"},
+		app.OutputFiles(result.TestContext, t, ".proguard_flags_annotated"))
+
+	// The flags are not annotated unless it is enabled.
+	result = PrepareForTestWithJavaDefaultModules.RunTestWithBp(t, bp)
+	if result.ModuleForTests(t, "app", "android_common").MaybeOutput("proguard/annotated_proguard_flags.txt").Rule != nil {
+		t.Errorf("expected no annotated proguard flags when ANNOTATE_PROGUARD_FLAGS is not set")
+	}
+}
+
 func TestR8FlagsArtProfile(t *testing.T) {
 	t.Parallel()
 	result := PrepareForTestWithJavaDefaultModules.RunTestWithBp(t, `
@@ -724,6 +969,208 @@ func TestR8FlagsArtProfile(t *testing.T) {
 	)
 }
 
+func TestR8InfoProvider(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureMergeMockFs(android.MockFS{
+			"proguard.flags": nil,
+		}),
+	).RunTestWithBp(t, `
+		android_app {
+			name: "app",
+			srcs: ["foo.java"],
+			platform_apis: true,
+			optimize: {
+				proguard_flags_files: ["proguard.flags"],
+			},
+			dex_preopt: {
+				profile_guided: true,
+				profile: "profile.txt.prof",
+				enable_profile_rewriting: true,
+			},
+		}
+
+		java_library {
+			name: "lib",
+			srcs: ["foo.java"],
+		}
+	`)
+
+	app := result.ModuleForTests(t, "app", "android_common")
+	appR8 := app.Rule("r8")
+	info, ok := android.OtherModuleProvider(result, app.Module(), R8InfoProvider)
+	if !ok {
+		t.Fatalf("expected app to provide R8Info")
+	}
+
+	android.AssertPathRelativeToTopEquals(t, "MappingFile",
+		"out/soong/.intermediates/app/android_common/proguard_dictionary", info.MappingFile)
+	android.AssertPathRelativeToTopEquals(t, "Configuration",
+		"out/soong/.intermediates/app/android_common/proguard_configuration", info.Configuration)
+	android.AssertPathRelativeToTopEquals(t, "ArtProfile",
+		"out/soong/.intermediates/app/android_common/profile.prof.txt", info.ArtProfile)
+	android.AssertPathsRelativeToTopEquals(t, "DexInputs", []string{appR8.Input.String()}, info.DexInputs)
+	android.AssertPathRelativeToTopEquals(t, "DexJar", appR8.Output.String(), info.DexJar)
+	android.AssertStringEquals(t, "Flags", appR8.Args["r8Flags"], info.Flags)
+	android.AssertStringListContains(t, "FlagsFiles", info.FlagsFiles.Strings(), "proguard.flags")
+
+	lib := result.ModuleForTests(t, "lib", "android_common")
+	if _, ok := android.OtherModuleProvider(result, lib.Module(), R8InfoProvider); ok {
+		t.Errorf("expected lib not optimized with R8 to not provide R8Info")
+	}
+}
+
+func TestProguardDictionaries(t *testing.T) {
+	t.Parallel()
+	result := PrepareForTestWithJavaDefaultModules.RunTestWithBp(t, `
+		android_app {
+			name: "app",
+			srcs: ["foo.java"],
+			platform_apis: true,
+		}
+
+		android_app {
+			name: "unoptimized_app",
+			srcs: ["foo.java"],
+			platform_apis: true,
+			optimize: {
+				enabled: false,
+			},
+		}
+
+		java_library {
+			name: "optimized_lib",
+			srcs: ["foo.java"],
+			installable: true,
+			optimize: {
+				enabled: true,
+			},
+		}
+	`)
+
+	app := result.ModuleForTests(t, "app", "android_common")
+	zip := app.Output("proguard_dictionary/proguard_dictionary.zip")
+	android.AssertPathRelativeToTopEquals(t, "dictionary",
+		"out/soong/.intermediates/app/android_common/proguard_dictionary", zip.Input)
+	android.AssertPathsRelativeToTopEquals(t, "package",
+		[]string{"out/soong/.intermediates/app/android_common/package-res.apk"}, zip.Implicits)
+
+	unoptimizedApp := result.ModuleForTests(t, "unoptimized_app", "android_common")
+	if unoptimizedApp.MaybeOutput("proguard_dictionary/proguard_dictionary.zip").Rule != nil {
+		t.Errorf("expected no proguard dictionary zip for unoptimized_app")
+	}
+
+	merged := result.SingletonForTests(t, "proguard_dictionaries").Output("proguard_dictionaries/proguard-dict-by-package.zip")
+	android.AssertStringDoesContain(t, "merge command", merged.RuleParams.Command, "merge_zips -s")
+	android.AssertStringDoesContain(t, "library dictionaries", merged.RuleParams.Command,
+		"-e libraries/optimized_lib/proguard_dictionary -f out/soong/.intermediates/optimized_lib/android_common/proguard_dictionary")
+	// The zips of the build fingerprint and the library dictionaries are built by the same rule.
+	android.AssertPathsRelativeToTopEquals(t, "merged inputs",
+		[]string{
+			"out/soong/.intermediates/app/android_common/proguard_dictionary/proguard_dictionary.zip",
+			"out/soong/.intermediates/optimized_lib/android_common/proguard_dictionary",
+		},
+		merged.Implicits)
+}
+
+func TestDesugaringAudit(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["foo.java"],
+			sdk_version: "current",
+			min_sdk_version: "21",
+		}
+
+		java_library_host {
+			name: "bar",
+			srcs: ["bar.java"],
+		}
+	`
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureMergeEnv(map[string]string{
+			"DESUGARING_AUDIT": "true",
+		}),
+	).RunTestWithBp(t, bp)
+
+	foo := result.ModuleForTests(t, "foo", "android_common")
+	report := foo.Output("desugaring/foo.jar.audit.txt")
+	android.AssertStringDoesContain(t, "report command", report.RuleParams.Command,
+		"com.android.tools.r8.BackportedMethodList --min-api 21 --output")
+	android.AssertStringDoesContain(t, "report command", report.RuleParams.Command,
+		"desugaring_audit generate --backported-methods")
+	android.AssertStringDoesContain(t, "report command", report.RuleParams.Command,
+		"--dex-jar out/soong/.intermediates/foo/android_common/dex/foo.jar")
+	android.AssertStringListContains(t, "report inputs", report.Implicits.Strings(),
+		foo.Output("dex/foo.jar").Input.String())
+	android.AssertStringListContains(t, "report inputs", report.Implicits.Strings(),
+		foo.Output("dex/foo.jar").Output.String())
+
+	bar := result.ModuleForTests(t, "bar", result.Config.BuildOSCommonTarget.String())
+	if _, ok := android.OtherModuleProvider(result.OtherModuleProviderAdaptor(), bar.Module(),
+		DesugaringAuditInfoProvider); ok {
+		t.Errorf("expected no desugaring audit for bar")
+	}
+
+	merged := result.SingletonForTests(t, "desugaring_audit").Output("desugaring/desugaring_audit.txt")
+	android.AssertStringDoesContain(t, "merge command", merged.RuleParams.Command, "desugaring_audit merge")
+	android.AssertPathsRelativeToTopEquals(t, "merged reports",
+		[]string{"out/soong/.intermediates/foo/android_common/desugaring/foo.jar.audit.txt"},
+		merged.Inputs)
+
+	// The audit is not generated unless it is enabled.
+	result = PrepareForTestWithJavaDefaultModules.RunTestWithBp(t, bp)
+	if result.ModuleForTests(t, "foo", "android_common").MaybeOutput("desugaring/foo.jar.audit.txt").Rule != nil {
+		t.Errorf("expected no desugaring audit when DESUGARING_AUDIT is not set")
+	}
+}
+
+func TestCheckProguardFlagsFiles(t *testing.T) {
+	t.Parallel()
+	result := PrepareForTestWithJavaDefaultModules.RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			optimize: {
+				proguard_flags_files: ["foo.flags", "common.flags"],
+			},
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["a.java"],
+			optimize: {
+				proguard_flags_files: ["bar.flags"],
+				check_proguard_flags_files: false,
+			},
+		}
+
+		java_library {
+			name: "baz",
+			srcs: ["a.java"],
+		}
+	`)
+
+	foo := result.ModuleForTests(t, "foo", "android_common")
+	check := foo.Output("proguard/proguard_flags_check.stamp")
+	android.AssertStringDoesContain(t, "check command", check.RuleParams.Command, "check_proguard_flags --output")
+	android.AssertPathsRelativeToTopEquals(t, "check implicits", []string{"common.flags", "foo.flags"},
+		check.Implicits)
+	android.AssertPathsRelativeToTopEquals(t, "check validation",
+		[]string{"out/soong/.intermediates/foo/android_common/proguard/proguard_flags_check.stamp"},
+		foo.Output("combined/foo.jar").Validations)
+
+	for _, name := range []string{"bar", "baz"} {
+		m := result.ModuleForTests(t, name, "android_common")
+		if m.MaybeOutput("proguard/proguard_flags_check.stamp").Rule != nil {
+			t.Errorf("expected no proguard flags check for %s", name)
+		}
+	}
+}
+
 // This test checks that users explicitly set `enable_profile_rewriting` to true when the following are true
 // 1. optimize or obfuscate is enabled AND
 // 2. dex_preopt.profile_guided is enabled
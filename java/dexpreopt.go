@@ -77,6 +77,11 @@ type dexpreopter struct {
 	isPresignedPrebuilt bool
 	preventInstall      bool
 
+	// If true, the module can be installed compressed instead of being dexpreopted. compressedDex
+	// is set if the module must install itself compressed, as it was not dexpreopted.
+	supportsCompressedDex bool
+	compressedDex         bool
+
 	manifestFile        android.Path
 	statusFile          android.WritablePath
 	enforceUsesLibs     bool
@@ -130,6 +135,17 @@ type DexpreoptProperties struct {
 		// the optimized dex.
 		// The new profile will be subsequently used as the profile to dexpreopt the dex file.
 		Enable_profile_rewriting proptools.Configurable[bool] `android:"replace_instead_of_append"`
+
+		// If true, only verify the dex code at build time and install the resulting .vdex file,
+		// without the compiled .odex file or an app image. This overrides the global compiler
+		// filter and is intended for modules on space-constrained partitions. Defaults to false.
+		Vdex_only proptools.Configurable[bool] `android:"replace_instead_of_append"`
+
+		// If true, install the app gzip-compressed (<name>.apk.gz) instead of dexpreopting it on
+		// products that enable compressed dex in their dexpreopt config, so that it is extracted and
+		// compiled on the device. Only supported for android_app modules, as the class loaders of
+		// java libraries cannot load compressed jars. Defaults to false.
+		Compressed_dex proptools.Configurable[bool] `android:"replace_instead_of_append"`
 	}
 
 	Dex_preopt_result struct {
@@ -365,6 +381,10 @@ func (d *dexpreopter) dexpreopt(ctx android.ModuleContext, libName string, dexJa
 		}
 	}
 
+	if !checkBootImageForTargets(ctx, bootImage, targets) {
+		return
+	}
+
 	var archs []android.ArchType
 	var images android.Paths
 	var imagesDeps []android.OutputPaths
@@ -410,6 +430,12 @@ func (d *dexpreopter) dexpreopt(ctx android.ModuleContext, libName string, dexJa
 
 	appImage := d.dexpreoptProperties.Dex_preopt.App_image.Get(ctx)
 
+	compressedDex := d.dexpreoptProperties.Dex_preopt.Compressed_dex.GetOrDefault(ctx, false)
+	if compressedDex && !d.supportsCompressedDex {
+		ctx.PropertyErrorf("dex_preopt.compressed_dex", "is only supported for android_app modules")
+		compressedDex = false
+	}
+
 	// Full dexpreopt config, used to create dexpreopt build rules.
 	dexpreoptConfig := &dexpreopt.ModuleConfig{
 		Name:            libName,
@@ -442,6 +468,10 @@ func (d *dexpreopter) dexpreopt(ctx android.ModuleContext, libName string, dexJa
 		ForceCreateAppImage: appImage.GetOrDefault(false),
 
 		PresignedPrebuilt: d.isPresignedPrebuilt,
+
+		VdexOnly: d.dexpreoptProperties.Dex_preopt.Vdex_only.GetOrDefault(ctx, false),
+
+		CompressedDex: compressedDex,
 	}
 
 	if ctx.Config().InstallApexSystemServerDexpreoptSamePartition() {
@@ -498,6 +528,9 @@ func (d *dexpreopter) dexpreopt(ctx android.ModuleContext, libName string, dexJa
 
 	dexpreoptRule.Build("dexpreopt"+"."+dexJarStem, "dexpreopt")
 
+	// The module installs itself compressed instead of installing dexpreopt files.
+	d.compressedDex = !d.preventInstall && dexpreopt.CompressDex(ctx, global, dexpreoptConfig)
+
 	// The current ctx might be of a deapexer module created by a prebuilt apex
 	// Use the path of the dex file to determine the library name
 	isApexSystemServerJar := global.AllApexSystemServerJars(ctx).ContainsJar(dexJarStem)
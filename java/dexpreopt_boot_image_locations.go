@@ -0,0 +1,224 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// dex_bootjars records the locations of the boot images it builds, the jars they contain and the
+// checksums of the profiles they are compiled with in boot_image_locations.json and
+// boot_image_profile_checksums.txt, which are disted, and provides them to apps. Apps check that
+// the boot image they are dexpreopted against is the one dex_bootjars built, as the runtime rejects
+// dexpreopted code compiled against a different boot image. Comparing the files with the boot image
+// used on a device helps debugging such mismatches.
+
+// BootImageVariantLocations describes the boot image built for one architecture.
+type BootImageVariantLocations struct {
+	// The locations of the boot image and the images it extends, as passed to dex2oat.
+	ImageLocationsOnHost   []string
+	ImageLocationsOnDevice []string
+
+	// The locations of the jars on the bootclasspath of the image, including the jars of the images
+	// it extends.
+	DexLocations []string
+}
+
+// BootImageLocations describes a boot image built by dex_bootjars.
+type BootImageLocations struct {
+	// The <apex>:<jar> pairs of the jars compiled into the image.
+	Jars []string
+
+	// The path of the profile the image is compiled with, empty if the image is not profile guided.
+	Profile string `json:",omitempty"`
+
+	// The image built for each architecture, keyed by architecture name.
+	Variants map[string]BootImageVariantLocations
+}
+
+type BootImageLocationsInfo struct {
+	// The boot images built by dex_bootjars, keyed by image name.
+	Images map[string]BootImageLocations
+
+	// A JSON file containing Images.
+	LocationsFile android.Path
+
+	// A file containing the checksums of the profiles of the profile guided boot images, or nil if
+	// there are none.
+	ProfileChecksumsFile android.Path
+}
+
+var BootImageLocationsInfoProvider = blueprint.NewProvider[BootImageLocationsInfo]()
+
+// dexBootJarsDependencyTag is the tag of the dependency of apps on dex_bootjars, which they read
+// BootImageLocationsInfo from.
+type dexBootJarsDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+// The boot images are not part of the apex that contains the app.
+func (dexBootJarsDependencyTag) ExcludeFromApexContents() {}
+
+var _ android.ExcludeFromApexContentsTag = dexBootJarsDependencyTag{}
+
+var dexBootJarsTag = dexBootJarsDependencyTag{}
+
+// addDexBootJarsDependency adds a dependency on dex_bootjars if it exists. Only modules that are
+// not on the bootclasspath, like apps, can depend on it, as dex_bootjars depends on the boot jars.
+func addDexBootJarsDependency(ctx android.BottomUpMutatorContext) {
+	if ctx.Device() && ctx.OtherModuleExists("dex_bootjars") {
+		ctx.AddVariationDependencies(ctx.Config().AndroidCommonTarget.Variations(), dexBootJarsTag, "dex_bootjars")
+	}
+}
+
+// bootImageLocations returns the locations of the device variants of the boot image.
+func bootImageLocations(image *bootImageConfig, profile android.Path) BootImageLocations {
+	locations := BootImageLocations{
+		Jars:     image.modules.CopyOfApexJarPairs(),
+		Variants: make(map[string]BootImageVariantLocations),
+	}
+	if profile != nil {
+		locations.Profile = profile.String()
+	}
+	for _, variant := range image.apexVariants() {
+		hostLocations, deviceLocations := variant.imageLocations()
+		locations.Variants[variant.target.Arch.ArchType.String()] = BootImageVariantLocations{
+			ImageLocationsOnHost:   hostLocations,
+			ImageLocationsOnDevice: deviceLocations,
+			DexLocations:           variant.dexLocationsDeps,
+		}
+	}
+	return locations
+}
+
+// provideBootImageLocations writes the locations of the enabled boot images and the checksums of
+// their profiles, dists them and provides them to other modules.
+func (d *dexpreoptBootJars) provideBootImageLocations(ctx android.ModuleContext, images []*bootImageConfig, profiles map[string]android.Path) {
+	info := BootImageLocationsInfo{
+		Images: make(map[string]BootImageLocations),
+	}
+	var profilePaths android.Paths
+	for _, image := range images {
+		profile := profiles[image.name]
+		info.Images[image.name] = bootImageLocations(image, profile)
+		if profile != nil {
+			profilePaths = append(profilePaths, profile)
+		}
+	}
+
+	data, err := json.MarshalIndent(info.Images, "", "  ")
+	if err != nil {
+		ctx.ModuleErrorf("failed to marshal boot image locations: %s", err)
+		return
+	}
+	locationsFile := android.PathForModuleOut(ctx, "boot_image_locations.json")
+	android.WriteFileRule(ctx, locationsFile, string(data))
+	ctx.DistForGoal("droidcore", locationsFile)
+	info.LocationsFile = locationsFile
+
+	if len(profilePaths) > 0 {
+		checksumsFile := android.PathForModuleOut(ctx, "boot_image_profile_checksums.txt")
+		rule := android.NewRuleBuilder(pctx, ctx)
+		rule.Command().Text("sha256sum").Inputs(profilePaths).FlagWithOutput("> ", checksumsFile)
+		rule.Build("boot_image_profile_checksums", "boot image profile checksums")
+		ctx.DistForGoal("droidcore", checksumsFile)
+		info.ProfileChecksumsFile = checksumsFile
+	}
+
+	android.SetProvider(ctx, BootImageLocationsInfoProvider, info)
+}
+
+// builtBootImageLocations returns the locations of the given boot image that dex_bootjars built,
+// or nil if the module does not depend on dex_bootjars.
+func builtBootImageLocations(ctx android.ModuleContext, image *bootImageConfig) (*BootImageLocations, error) {
+	var locations *BootImageLocations
+	var err error
+	ctx.VisitDirectDepsProxyWithTag(dexBootJarsTag, func(module android.ModuleProxy) {
+		info, ok := android.OtherModuleProvider(ctx, module, BootImageLocationsInfoProvider)
+		if !ok {
+			return
+		}
+		built, ok := info.Images[image.name]
+		if !ok {
+			err = fmt.Errorf("the %q boot image is not built by %s", image.name, ctx.OtherModuleName(module))
+			return
+		}
+		locations = &built
+	})
+	return locations, err
+}
+
+// checkBootImageForTargets reports an error and returns false if a module that is dexpreopted for
+// the given targets would be compiled against a boot image that is not built for all of them. If
+// the module depends on dex_bootjars the locations of the image it is compiled against must also be
+// the locations of the image that dex_bootjars built, otherwise the runtime would reject the
+// dexpreopted code.
+func checkBootImageForTargets(ctx android.ModuleContext, image *bootImageConfig, targets []android.Target) bool {
+	built, err := builtBootImageLocations(ctx, image)
+	if err != nil {
+		ctx.ModuleErrorf("%s", err)
+		return false
+	}
+
+	var missing []string
+	for _, target := range targets {
+		arch := target.Arch.ArchType.String()
+		variant := image.getVariant(target)
+		if variant == nil {
+			missing = append(missing, arch)
+			continue
+		}
+		if built == nil {
+			continue
+		}
+		builtVariant, ok := built.Variants[arch]
+		if !ok {
+			missing = append(missing, arch)
+			continue
+		}
+		_, deviceLocations := variant.imageLocations()
+		if !slices.Equal(deviceLocations, builtVariant.ImageLocationsOnDevice) {
+			ctx.ModuleErrorf("is dexpreopted for %s against the %q boot image at %q, but it is built at %q",
+				arch, image.name, deviceLocations, builtVariant.ImageLocationsOnDevice)
+			return false
+		}
+	}
+	if len(missing) == 0 {
+		return true
+	}
+
+	var builtArchs []string
+	if built != nil {
+		for arch := range built.Variants {
+			builtArchs = append(builtArchs, arch)
+		}
+	} else {
+		for _, variant := range image.apexVariants() {
+			builtArchs = append(builtArchs, variant.target.Arch.ArchType.String())
+		}
+	}
+	sort.Strings(builtArchs)
+	ctx.ModuleErrorf("is dexpreopted for %s, but the %q boot image is only built for [%s]",
+		strings.Join(missing, ", "), image.name, strings.Join(builtArchs, ", "))
+	return false
+}
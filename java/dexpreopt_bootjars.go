@@ -629,6 +629,8 @@ func (d *dexpreoptBootJars) GenerateAndroidBuildActions(ctx android.ModuleContex
 	d.otherImages = make([]*bootImageConfig, 0, len(imageConfigs)-1)
 	var profileInstalls android.RuleBuilderInstalls
 	var artBootImageHostInstalls android.RuleBuilderInstalls
+	var enabledImages []*bootImageConfig
+	profiles := make(map[string]android.Path)
 	for _, name := range getImageNames() {
 		config := imageConfigs[name]
 		if config != d.defaultBootImage {
@@ -637,7 +639,11 @@ func (d *dexpreoptBootJars) GenerateAndroidBuildActions(ctx android.ModuleContex
 		if !config.isEnabled(ctx) {
 			continue
 		}
-		installs := generateBootImage(ctx, config)
+		enabledImages = append(enabledImages, config)
+		installs, profile := generateBootImage(ctx, config)
+		if profile != nil {
+			profiles[config.name] = profile
+		}
 		profileInstalls = append(profileInstalls, installs...)
 		if config == d.defaultBootImage {
 			bootProfile, installs := bootFrameworkProfileRule(ctx, config)
@@ -676,6 +682,8 @@ func (d *dexpreoptBootJars) GenerateAndroidBuildActions(ctx android.ModuleContex
 		},
 	)
 
+	d.provideBootImageLocations(ctx, enabledImages, profiles)
+
 	d.buildBootZip(ctx)
 }
 
@@ -829,11 +837,14 @@ func shouldBuildBootImages(config android.Config, global *dexpreopt.GlobalConfig
 	return true
 }
 
-func generateBootImage(ctx android.ModuleContext, imageConfig *bootImageConfig) android.RuleBuilderInstalls {
+// generateBootImage generates the rules to build the boot image and returns the rules to install
+// its profile and the path of the profile, or nil if the image is not profile guided.
+func generateBootImage(ctx android.ModuleContext, imageConfig *bootImageConfig) (android.RuleBuilderInstalls, android.Path) {
 	apexJarModulePairs := getModulesForImage(ctx, imageConfig)
 
 	// Copy module dex jars to their predefined locations.
 	bootDexJarsByModule := extractEncodedDexJarsFromModulesOrBootclasspathFragments(ctx, apexJarModulePairs)
+	addBootDexJarsExtractedFromPrebuiltApexes(ctx, imageConfig, bootDexJarsByModule)
 	copyBootJarsToPredefinedLocations(ctx, bootDexJarsByModule, imageConfig.dexPathsByModule)
 
 	// Build a profile for the image config from the profile at the default path. The profile will
@@ -843,7 +854,7 @@ func generateBootImage(ctx android.ModuleContext, imageConfig *bootImageConfig)
 	// If dexpreopt of boot image jars should be skipped, stop after generating a profile.
 	global := dexpreopt.GetGlobalConfig(ctx)
 	if SkipDexpreoptBootJars(ctx) || (global.OnlyPreoptArtBootImage && imageConfig.name != "art") {
-		return profileInstalls
+		return profileInstalls, profilePath(profile)
 	}
 
 	// Build boot image files for the android variants.
@@ -858,7 +869,16 @@ func generateBootImage(ctx android.ModuleContext, imageConfig *bootImageConfig)
 	// Create a `dump-oat-<image-name>` rule that runs `oatdump` for debugging purposes.
 	dumpOatRules(ctx, imageConfig)
 
-	return profileInstalls
+	return profileInstalls, profilePath(profile)
+}
+
+// profilePath converts a profile returned by bootImageProfileRule to an android.Path, keeping nil
+// profiles nil.
+func profilePath(profile android.WritablePath) android.Path {
+	if profile == nil {
+		return nil
+	}
+	return profile
 }
 
 type apexJarModulePair struct {
@@ -868,6 +888,7 @@ type apexJarModulePair struct {
 
 func getModulesForImage(ctx android.ModuleContext, imageConfig *bootImageConfig) []apexJarModulePair {
 	modules := make([]apexJarModulePair, 0, imageConfig.modules.Len())
+	apexNameToApexExportsInfoMap := getApexNameToApexExportsInfoMap(ctx)
 	for i := 0; i < imageConfig.modules.Len(); i++ {
 		found := false
 		dexpreoptBootJarModules, _ := gatherApexModulePairDepsWithTag(ctx, dexpreoptBootJar)
@@ -882,6 +903,10 @@ func getModulesForImage(ctx android.ModuleContext, imageConfig *bootImageConfig)
 				break
 			}
 		}
+		if !found && apexNameToApexExportsInfoMap.isExtractedFromPrebuiltApex(imageConfig.modules.Apex(i), imageConfig.modules.Jar(i)) {
+			// There is no module for the jar, it is added by addBootDexJarsExtractedFromPrebuiltApexes.
+			continue
+		}
 		if !found && !ctx.Config().AllowMissingDependencies() {
 			ctx.ModuleErrorf(
 				"Boot image '%s' module '%s' not added as a dependency of dex_bootjars",
@@ -922,6 +947,32 @@ func (m *apexNameToApexExportsInfoMap) javaLibraryDexPathOnHost(ctx android.Modu
 	return nil, false
 }
 
+// isExtractedFromPrebuiltApex returns true if the apex provides the dex jar of the java library. It
+// is used for java libraries that have no module of their own because their dex jar is extracted
+// directly from a prebuilt apex, see the apex_boot_jars property of prebuilt_bootclasspath_fragment.
+func (m *apexNameToApexExportsInfoMap) isExtractedFromPrebuiltApex(apex string, javalib string) bool {
+	if info, exists := (*m)[apex]; exists {
+		_, exists := info.LibraryNameToDexJarPathOnHost[javalib]
+		return exists
+	}
+	return false
+}
+
+// addBootDexJarsExtractedFromPrebuiltApexes adds the dex jars of the boot image modules that are
+// extracted directly from a prebuilt apex, as getModulesForImage does not return them.
+func addBootDexJarsExtractedFromPrebuiltApexes(ctx android.ModuleContext, imageConfig *bootImageConfig, bootDexJars bootDexJarByModule) {
+	apexNameToApexExportsInfoMap := getApexNameToApexExportsInfoMap(ctx)
+	for i := 0; i < imageConfig.modules.Len(); i++ {
+		apex, jar := imageConfig.modules.Apex(i), imageConfig.modules.Jar(i)
+		if _, exists := bootDexJars[jar]; exists {
+			continue
+		}
+		if apexNameToApexExportsInfoMap.isExtractedFromPrebuiltApex(apex, jar) {
+			bootDexJars[jar] = apexNameToApexExportsInfoMap[apex].LibraryNameToDexJarPathOnHost[jar]
+		}
+	}
+}
+
 // Returns the stem of an artifact inside a prebuilt apex
 func ModuleStemForDeapexing(m android.Module) string {
 	bmn, _ := m.(interface{ BaseModuleName() string })
@@ -1381,7 +1432,10 @@ const failureMessage = `ERROR: Dex2oat failed to compile a boot image.
 It is likely that the boot classpath is inconsistent.
 Rebuild with ART_BOOT_IMAGE_EXTRA_ARGS="--runtime-arg -verbose:verifier" to see verification errors.`
 
-func bootImageProfileRuleCommon(ctx android.ModuleContext, name string, dexFiles android.Paths, dexLocations []string) android.WritablePath {
+// bootImageProfileRuleCommon builds a binary boot profile for dexFiles by merging the text boot image
+// profiles from the global config and the ART and framework source trees, followed by
+// extraProfiles in order.
+func bootImageProfileRuleCommon(ctx android.ModuleContext, name string, dexFiles android.Paths, dexLocations []string, extraProfiles android.Paths) android.WritablePath {
 	globalSoong := dexpreopt.GetGlobalSoongConfig(ctx)
 	global := dexpreopt.GetGlobalConfig(ctx)
 
@@ -1416,6 +1470,7 @@ func bootImageProfileRuleCommon(ctx android.ModuleContext, name string, dexFiles
 	if path := android.ExistentPathForSource(ctx, extraProfile); path.Valid() {
 		profiles = append(profiles, path.Path())
 	}
+	profiles = append(profiles, extraProfiles...)
 	bootImageProfile := android.PathForModuleOut(ctx, name, "boot-image-profile.txt")
 	rule.Command().Text("cat").Inputs(profiles).Text(">").Output(bootImageProfile)
 
@@ -1435,6 +1490,19 @@ func bootImageProfileRuleCommon(ctx android.ModuleContext, name string, dexFiles
 	return profile
 }
 
+// bootImageProfileContributions returns the boot image profiles contributed by the bootclasspath
+// fragments, ordered by the name of the apex containing each fragment so that the merged profile
+// does not depend on the order in which the fragments were found.
+func bootImageProfileContributions(ctx android.ModuleContext) android.Paths {
+	fragments := gatherBootclasspathFragments(ctx)
+	var profiles android.Paths
+	for _, apex := range android.SortedKeys(fragments) {
+		info, _ := android.OtherModuleProvider(ctx, fragments[apex], BootclasspathFragmentInfoProvider)
+		profiles = append(profiles, info.BootImageProfiles...)
+	}
+	return profiles
+}
+
 type profileInstallInfo struct {
 	// Rules which should be used in make to install the outputs.
 	profileInstalls android.RuleBuilderInstalls
@@ -1450,9 +1518,12 @@ func bootImageProfileRule(ctx android.ModuleContext, image *bootImageConfig) (an
 		return nil, nil
 	}
 
-	profile := bootImageProfileRuleCommon(ctx, image.name, image.dexPathsDeps.Paths(), image.getAnyAndroidVariant().dexLocationsDeps)
+	profile := bootImageProfileRuleCommon(ctx, image.name, image.dexPathsDeps.Paths(), image.getAnyAndroidVariant().dexLocationsDeps,
+		bootImageProfileContributions(ctx))
 
 	if image == defaultBootImageConfig(ctx) && profile != nil {
+		bootImageProfileInfoRule(ctx, image, profile)
+
 		rule := android.NewRuleBuilder(pctx, ctx)
 		rule.Install(profile, "/system/etc/boot-image.prof")
 		return profile, rule.Installs()
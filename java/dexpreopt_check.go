@@ -45,7 +45,11 @@ func RegisterDexpreoptCheckBuildComponents(ctx android.RegistrationContext) {
 type dexpreoptSystemserverCheck struct {
 	android.SingletonModuleBase
 
-	// The install paths to the compilation artifacts.
+	// The names of the system server jars and the install paths to their compilation artifacts.
+	jars           []string
+	artifactsByJar map[string][]string
+
+	// The install paths to the compilation artifacts that are required to be installed.
 	artifacts []string
 }
 
@@ -95,6 +99,8 @@ func (m *dexpreoptSystemserverCheck) GenerateAndroidBuildActions(ctx android.Mod
 	global := dexpreopt.GetGlobalConfig(ctx)
 	targets := ctx.Config().Targets[android.Android]
 
+	m.jars = nil
+	m.artifactsByJar = make(map[string][]string)
 	ctx.VisitDirectDepsWithTag(systemServerJarDepTag, func(systemServerJar android.Module) {
 		partition := "system"
 		if systemServerJar.InstallInSystemExt() && ctx.Config().InstallApexSystemServerDexpreoptSamePartition() {
@@ -109,11 +115,30 @@ func (m *dexpreoptSystemserverCheck) GenerateAndroidBuildActions(ctx android.Mod
 		odexLocation := dexpreopt.ToOdexPath(dexLocation, targets[0].Arch.ArchType, partition)
 		odexPath := getInstallPath(ctx, odexLocation)
 		vdexPath := getInstallPath(ctx, pathtools.ReplaceExtension(odexLocation, "vdex"))
-		m.artifacts = append(m.artifacts, odexPath.String(), vdexPath.String())
+		name := android.RemoveOptionalPrebuiltPrefix(ctx.OtherModuleName(systemServerJar))
+		if _, exists := m.artifactsByJar[name]; !exists {
+			m.jars = append(m.jars, name)
+		}
+		m.artifactsByJar[name] = append(m.artifactsByJar[name], odexPath.String(), vdexPath.String())
 	})
 }
 
+// GenerateSingletonBuildActions leaves out the artifacts of the standalone system server jars that
+// the apexes substitute, as they fall back to not being dexpreopted.
 func (m *dexpreoptSystemserverCheck) GenerateSingletonBuildActions(ctx android.SingletonContext) {
+	var substituted []string
+	ctx.VisitAllModuleProxies(func(module android.ModuleProxy) {
+		if info, ok := android.OtherModuleProvider(ctx, module, MissingStandaloneJarsInfoProvider); ok {
+			substituted = append(substituted, info.Jars...)
+		}
+	})
+
+	m.artifacts = nil
+	for _, jar := range m.jars {
+		if !android.InList(jar, substituted) {
+			m.artifacts = append(m.artifacts, m.artifactsByJar[jar]...)
+		}
+	}
 }
 
 func (m *dexpreoptSystemserverCheck) MakeVars(ctx android.MakeVarsContext) {
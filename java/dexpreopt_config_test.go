@@ -15,6 +15,7 @@
 package java
 
 import (
+	"encoding/json"
 	"runtime"
 	"sort"
 	"testing"
@@ -56,3 +57,66 @@ func TestImageNames(t *testing.T) {
 
 	android.AssertArrayString(t, "getImageNames vs genBootImageConfigs", names, namesFromConfigs)
 }
+
+func TestBootImageLocations(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		PrepareForBootImageConfigTest,
+		PrepareApexBootJarConfigs,
+	).RunTest(t)
+
+	dexBootJars := result.ModuleForTests(t, "dex_bootjars", "android_common")
+	var locations map[string]BootImageLocations
+	content := android.ContentFromFileRuleForTests(t, result.TestContext, dexBootJars.Output("boot_image_locations.json"))
+	if err := json.Unmarshal([]byte(content), &locations); err != nil {
+		t.Fatalf("failed to parse boot_image_locations.json: %s", err)
+	}
+
+	art, ok := locations["art"]
+	if !ok {
+		t.Fatalf("missing locations of the art boot image")
+	}
+	android.AssertArrayString(t, "art jars",
+		[]string{"com.android.art:core1", "com.android.art:core2", "platform:extra1"}, art.Jars)
+	android.AssertArrayString(t, "art arm64 image locations on device",
+		[]string{"/apex/art_boot_images/javalib/boot.art"}, art.Variants["arm64"].ImageLocationsOnDevice)
+	android.AssertArrayString(t, "art arm64 dex locations",
+		[]string{"/apex/com.android.art/javalib/core1.jar", "/apex/com.android.art/javalib/core2.jar", "/system/framework/extra1.jar"},
+		art.Variants["arm64"].DexLocations)
+
+	// The same locations are provided to apps, which check that they are dexpreopted against them.
+	info, ok := android.OtherModuleProvider(result, dexBootJars.Module(), BootImageLocationsInfoProvider)
+	if !ok {
+		t.Fatalf("dex_bootjars did not provide BootImageLocationsInfo")
+	}
+	android.AssertDeepEquals(t, "provided art locations", art, info.Images["art"])
+	android.AssertPathRelativeToTopEquals(t, "locations file",
+		"out/soong/.intermediates/dex_bootjars/android_common/boot_image_locations.json", info.LocationsFile)
+}
+
+func TestBootImageProfileInfo(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		PrepareForBootImageConfigTest,
+		PrepareApexBootJarConfigs,
+	).RunTest(t)
+
+	dexBootJars := result.ModuleForTests(t, "dex_bootjars", "android_common")
+	rule := dexBootJars.Rule("bootImageProfileInfo_boot")
+	android.AssertStringDoesContain(t, "dump command", rule.RuleParams.Command,
+		"profman --dump-only --profile-file=out/soong/.intermediates/default/java/dex_bootjars/android_common/boot/boot.prof")
+	android.AssertStringDoesContain(t, "dump command", rule.RuleParams.Command,
+		"profman --dump-classes-and-methods")
+	android.AssertStringDoesContain(t, "dump command", rule.RuleParams.Command,
+		"boot_image_profile_info --output out/soong/.intermediates/default/java/dex_bootjars/android_common/boot/boot-image-profile-info.pb")
+
+	android.AssertArrayString(t, "dists", []string{
+		"droidcore out/soong/.intermediates/default/java/dex_bootjars/android_common/boot/boot-image-profile-info.txt:boot-image-profile-info.txt",
+		"droidcore out/soong/.intermediates/default/java/dex_bootjars/android_common/boot/boot-image-profile-info.pb:boot-image-profile-info.pb",
+	}, dexBootJars.DistsForTests(result.TestContext))
+
+	// Only the profile of the default boot image is dumped.
+	if dexBootJars.MaybeRule("bootImageProfileInfo_art").Rule != nil {
+		t.Errorf("expected no dump of the art boot image profile")
+	}
+}
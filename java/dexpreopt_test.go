@@ -402,3 +402,63 @@ func TestGenerateProfileEvenIfDexpreoptIsDisabled(t *testing.T) {
 
 	android.AssertArrayString(t, "outputs", expected, dexpreopt.AllOutputs())
 }
+
+func TestDexpreoptCompressedDex(t *testing.T) {
+	preparers := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		PrepareForTestWithFakeApexMutator,
+		dexpreopt.FixtureModifyGlobalConfig(func(_ android.PathContext, dexpreoptConfig *dexpreopt.GlobalConfig) {
+			dexpreoptConfig.CompressedDex = true
+		}),
+	)
+
+	result := preparers.RunTestWithBp(t, `
+		android_app {
+			name: "foo",
+			dex_preopt: {
+				compressed_dex: true,
+			},
+			srcs: ["a.java"],
+			sdk_version: "current",
+		}
+
+		android_app {
+			name: "bar",
+			srcs: ["a.java"],
+			sdk_version: "current",
+		}`)
+
+	ctx := result.TestContext
+	foo := ctx.ModuleForTests(t, "foo", "android_common")
+	android.AssertBoolEquals(t, "dexpreopt", true, foo.MaybeRule("dexpreopt").Rule == nil)
+
+	// The compressed app is installed instead of the app.
+	compressed := foo.Output("compressed_apk/foo.apk.gz")
+	android.AssertPathRelativeToTopEquals(t, "compressed input",
+		"out/soong/.intermediates/foo/android_common/foo.apk", compressed.Input)
+	install := foo.Output("out/target/product/test_device/system/app/foo/foo.apk.gz")
+	android.AssertPathRelativeToTopEquals(t, "installed",
+		"out/soong/.intermediates/foo/android_common/compressed_apk/foo.apk.gz", install.Input)
+	if foo.MaybeOutput("out/target/product/test_device/system/app/foo/foo.apk").Rule != nil {
+		t.Errorf("expected foo.apk not to be installed")
+	}
+
+	// Modules that don't allow compressed dex are dexpreopted as usual.
+	bar := ctx.ModuleForTests(t, "bar", "android_common")
+	bar.Output("out/target/product/test_device/system/app/bar/bar.apk")
+	android.AssertStringDoesContain(t, "dexpreopt installs", bar.Module().(*AndroidApp).dexpreopter.builtInstalled,
+		":/system/app/bar/oat/arm64/bar.odex")
+}
+
+func TestDexpreoptCompressedDexLibrary(t *testing.T) {
+	testJavaError(t, `dex_preopt.compressed_dex: is only supported for android_app modules`, `
+		java_library {
+			name: "foo",
+			installable: true,
+			srcs: ["a.java"],
+			sdk_version: "current",
+			dex_preopt: {
+				compressed_dex: true,
+			},
+		}`)
+}
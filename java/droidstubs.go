@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/google/blueprint"
@@ -727,6 +729,73 @@ func metalavaUseRbe(ctx android.ModuleContext) bool {
 	return ctx.Config().UseRBE() && ctx.Config().IsEnvTrue("RBE_METALAVA")
 }
 
+// metalavaCacheDir returns the directory set in METALAVA_CACHE_DIR, in which metalava outputs are
+// cached keyed by the digest of the arguments and the declared inputs of the rule so that identical
+// runs are not repeated, e.g. after switching lunch targets.  Remote metalava runs are already
+// cached by RBE, so the local cache is not used for them.
+func metalavaCacheDir(ctx android.ModuleContext) string {
+	if metalavaUseRbe(ctx) {
+		return ""
+	}
+	dir := ctx.Config().Getenv("METALAVA_CACHE_DIR")
+	if dir != "" && !filepath.IsAbs(dir) {
+		ctx.ModuleErrorf("METALAVA_CACHE_DIR must be an absolute path, got %q", dir)
+		return ""
+	}
+	return dir
+}
+
+// The default size in megabytes above which metalava_cache evicts the least recently used entries
+// of the metalava cache.
+const defaultMetalavaCacheMaxSizeMb = 10 * 1024
+
+// metalavaCacheMaxSizeMb returns the size in megabytes that the metalava cache is bounded to, which
+// can be overridden with METALAVA_CACHE_MAX_SIZE_MB.
+func metalavaCacheMaxSizeMb(ctx android.ModuleContext) int {
+	size := ctx.Config().Getenv("METALAVA_CACHE_MAX_SIZE_MB")
+	if size == "" {
+		return defaultMetalavaCacheMaxSizeMb
+	}
+	n, err := strconv.Atoi(size)
+	if err != nil || n <= 0 {
+		ctx.ModuleErrorf("METALAVA_CACHE_MAX_SIZE_MB must be a positive integer, got %q", size)
+		return defaultMetalavaCacheMaxSizeMb
+	}
+	return n
+}
+
+// metalavaCacheKeyPath returns the file holding the digest of the declared inputs of the metalava
+// rule for stubsType, which metalava_cache combines with the arguments of metalava into the cache
+// key.
+func metalavaCacheKeyPath(ctx android.ModuleContext, stubsType StubsType) android.WritablePath {
+	return android.PathForModuleOut(ctx, stubsType.String()+".metalava_cache_key")
+}
+
+// metalavaCacheKey adds a rule that hashes the declared inputs and tools of the sandboxed metalava
+// rule into the file returned by metalavaCacheKeyPath, once all of its commands have been added, and
+// makes the file an input of the metalava rule.
+func metalavaCacheKey(ctx android.ModuleContext, rule *android.RuleBuilder, cmd *android.RuleBuilderCommand,
+	stubsType StubsType) {
+	if metalavaCacheDir(ctx) == "" {
+		return
+	}
+	inputsPath := android.PathForModuleOut(ctx, stubsType.String()+".metalava_cache_inputs.txt")
+	keyPath := metalavaCacheKeyPath(ctx, stubsType)
+	inputs := android.SortedUniquePaths(slices.Concat(rule.Inputs(), rule.RspFileInputs(), rule.Tools()))
+	android.WriteFileRule(ctx, inputsPath, strings.Join(inputs.Strings(), "\n"))
+
+	keyRule := android.NewRuleBuilder(pctx, ctx)
+	keyRule.Command().
+		BuiltTool("metalava_cache").
+		Flag("--hash_inputs").
+		Input(inputsPath).
+		Implicits(inputs).
+		FlagWithOutput("--key ", keyPath)
+	keyRule.Build(stubsType.String()+"_metalava_cache_key", "metalava cache key")
+
+	cmd.Implicit(keyPath)
+}
+
 func metalavaCmd(ctx android.ModuleContext, rule *android.RuleBuilder, srcs android.Paths,
 	srcJarList android.Path, homeDir android.WritablePath, params stubsCommandConfigParams,
 	configFiles android.Paths, apiSurface *string) *android.RuleBuilderCommand {
@@ -756,6 +825,17 @@ func metalavaCmd(ctx android.ModuleContext, rule *android.RuleBuilder, srcs andr
 		})
 	}
 
+	if cacheDir := metalavaCacheDir(ctx); cacheDir != "" {
+		// The metalava rule stays sandboxed, so the outputs that metalava_cache restores from the
+		// cache are limited to the declared outputs of the rule.
+		cmd.BuiltTool("metalava_cache").
+			FlagWithArg("--cache_dir ", cacheDir).
+			FlagWithArg("--max_size_mb ", strconv.Itoa(metalavaCacheMaxSizeMb(ctx))).
+			FlagWithArg("--out_dir ", cmd.PathForOutput(android.PathForModuleOut(ctx, params.stubsType.String()))).
+			FlagWithArg("--key ", cmd.PathForInput(metalavaCacheKeyPath(ctx, params.stubsType))).
+			Flag("--")
+	}
+
 	cmd.BuiltTool("metalava").ImplicitTool(ctx.Config().HostJavaToolPath(ctx, "metalava.jar")).
 		Flag(config.JavacVmFlags).
 		Flag(config.MetalavaAddOpens).
@@ -989,6 +1069,7 @@ func (d *Droidstubs) everythingStubCmd(ctx android.ModuleContext, params stubsCo
 
 	zipSyncCleanupCmd(rule, srcJarDir)
 
+	metalavaCacheKey(ctx, rule, cmd, Everything)
 	rule.Build("metalava", "metalava merged")
 }
 
@@ -1212,6 +1293,7 @@ func (d *Droidstubs) optionalStubCmd(ctx android.ModuleContext, params stubsComm
 
 	zipSyncCleanupCmd(rule, params.srcJarDir)
 
+	metalavaCacheKey(ctx, rule, cmd, params.stubConfig.stubsType)
 	rule.Build(fmt.Sprintf("metalava_%s", params.stubConfig.stubsType.String()), "metalava merged")
 }
 
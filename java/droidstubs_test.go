@@ -222,6 +222,68 @@ func TestDroidstubsSandbox(t *testing.T) {
 	}
 }
 
+func TestDroidstubsCache(t *testing.T) {
+	t.Parallel()
+	bp := `
+		droidstubs {
+			name: "bar-stubs",
+			srcs: ["bar-doc/a.java"],
+		}
+	`
+	run := func(t *testing.T, env map[string]string) (*android.TestResult, android.TestingModule) {
+		result := android.GroupFixturePreparers(
+			prepareForJavaTest,
+			android.FixtureMergeEnv(env),
+			android.FixtureAddFile("bar-doc/a.java", nil),
+		).RunTestWithBp(t, bp)
+		return result, result.ModuleForTests(t, "bar-stubs", "android_common")
+	}
+
+	result, m := run(t, nil)
+	manifest := android.RuleBuilderSboxProtoForTests(t, result.TestContext, m.Output("metalava.sbox.textproto"))
+	android.AssertStringDoesNotContain(t, "metalava_cache without METALAVA_CACHE_DIR",
+		manifest.Commands[0].GetCommand(), "metalava_cache")
+
+	// The cached rule stays sandboxed, and its cache key is a declared input.
+	result, m = run(t, map[string]string{"METALAVA_CACHE_DIR": "/tmp/metalava-cache"})
+	manifest = android.RuleBuilderSboxProtoForTests(t, result.TestContext, m.Output("metalava.sbox.textproto"))
+	android.AssertStringDoesContain(t, "metalava_cache with METALAVA_CACHE_DIR", manifest.Commands[0].GetCommand(),
+		"metalava_cache --cache_dir /tmp/metalava-cache --max_size_mb 10240 --out_dir __SBOX_SANDBOX_DIR__/out "+
+			"--key __SBOX_SANDBOX_DIR__/out/soong/.intermediates/bar-stubs/android_common/everything.metalava_cache_key -- ")
+
+	keyRule := m.Rule("everything_metalava_cache_key")
+	keyPath := "out/soong/.intermediates/bar-stubs/android_common/everything.metalava_cache_key"
+	android.AssertStringEquals(t, "metalava_cache key output", keyPath, keyRule.Output.String())
+	android.AssertStringListContains(t, "metalava_cache key is an input of the metalava rule",
+		m.Rule("metalava").Implicits.Strings(), keyPath)
+
+	inputsFile := m.Output("everything.metalava_cache_inputs.txt")
+	android.AssertStringListContains(t, "metalava_cache inputs are hashed",
+		keyRule.Implicits.Strings(), inputsFile.Output.String())
+	android.AssertStringListContains(t, "sources are declared inputs of the cache key",
+		keyRule.Implicits.Strings(), "bar-doc/a.java")
+	inputs := strings.Split(android.ContentFromFileRuleForTests(t, result.TestContext, inputsFile), "\n")
+	android.AssertStringListContains(t, "sources are part of the cache key", inputs, "bar-doc/a.java")
+	android.AssertStringDoesContain(t, "metalava.jar is part of the cache key", strings.Join(inputs, " "),
+		"/framework/metalava.jar")
+
+	result, m = run(t, map[string]string{
+		"METALAVA_CACHE_DIR":         "/tmp/metalava-cache",
+		"METALAVA_CACHE_MAX_SIZE_MB": "512",
+	})
+	manifest = android.RuleBuilderSboxProtoForTests(t, result.TestContext, m.Output("metalava.sbox.textproto"))
+	android.AssertStringDoesContain(t, "metalava_cache with METALAVA_CACHE_MAX_SIZE_MB",
+		manifest.Commands[0].GetCommand(), "--max_size_mb 512 ")
+
+	android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureMergeEnv(map[string]string{"METALAVA_CACHE_DIR": "metalava-cache"}),
+		android.FixtureAddFile("bar-doc/a.java", nil),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`METALAVA_CACHE_DIR must be an absolute path`,
+	)).RunTestWithBp(t, bp)
+}
+
 func TestDroidstubsWithSystemModules(t *testing.T) {
 	ctx, _ := testJava(t, `
 		droidstubs {
@@ -0,0 +1,46 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"android/soong/android"
+)
+
+// Merging the jars of a module and its static_libs keeps only the first copy of each class, so a
+// class that is in more than one of them silently drops the code of the other copies. When
+// check_duplicate_classes is enabled a validation lists the classes that are in more than one of
+// the merged jars, and the jars they are in, and fails the build if there are any that are not in
+// allowed_duplicate_classes.
+
+// checkDuplicateClasses returns the validation that checks jars for duplicate classes, or nil if
+// the check is not enabled for the module.
+func (j *Module) checkDuplicateClasses(ctx android.ModuleContext, jars android.Paths, jarName string) android.Paths {
+	if !BoolDefault(j.properties.Check_duplicate_classes, ctx.Config().IsEnvTrue("CHECK_DUPLICATE_CLASSES")) {
+		return nil
+	}
+
+	report := android.PathForModuleOut(ctx, "duplicate_classes", jarName+".txt")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	cmd := rule.Command().
+		BuiltTool("check_duplicate_classes").
+		FlagWithOutput("--output ", report)
+	for _, allowed := range j.properties.Allowed_duplicate_classes {
+		cmd.FlagWithArg("--allow ", allowed)
+	}
+	cmd.Inputs(jars)
+	rule.Build("check_duplicate_classes", "check duplicate classes")
+
+	return android.Paths{report}
+}
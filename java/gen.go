@@ -155,6 +155,7 @@ func (j *Module) genSources(ctx android.ModuleContext, srcFiles android.Paths,
 	if len(protoSrcs) > 0 {
 		srcJarFiles := genProto(ctx, protoSrcs, flags.proto)
 		outSrcFiles = append(outSrcFiles, srcJarFiles...)
+		android.SetProtoSrcsInfo(ctx, "java", flags.proto, protoSrcs)
 	}
 
 	// Process all aidl files together to support sharding them into one or more rules that produce srcjars.
@@ -62,6 +62,21 @@ func GeneratedJavaLibraryModuleFactory(moduleName string, callbacks GeneratedJav
 	return module
 }
 
+// PropertiesForCreatedModule returns the properties that a module created from this one in a
+// defaultable hook shares with it, so that it can be used in the same places: the sdk it is built against and
+// the apexes it is available to.
+func (module *GeneratedJavaLibraryModule) PropertiesForCreatedModule() interface{} {
+	return &struct {
+		Sdk_version     *string
+		Min_sdk_version *string
+		Apex_available  []string
+	}{
+		Sdk_version:     module.deviceProperties.Sdk_version,
+		Min_sdk_version: module.overridableProperties.Min_sdk_version,
+		Apex_available:  module.ApexProperties.Apex_available,
+	}
+}
+
 // Add a java shared library as a dependency, as if they had said `libs: [ "name" ]`
 func (module *GeneratedJavaLibraryModule) AddSharedLibrary(name string) {
 	if module.depsMutatorDone {
@@ -15,6 +15,10 @@
 package java
 
 import (
+	"strings"
+
+	"github.com/google/blueprint/proptools"
+
 	"android/soong/android"
 	"android/soong/genrule"
 )
@@ -60,8 +64,30 @@ func RegisterGenRuleBuildComponents(ctx android.RegistrationContext) {
 //	    srcs: ["src/**/*.java"],
 //	    static_libs: ["generated_resources"],
 //	}
+//
+// Use a java_genrule with out_srcjar to generate java sources:
+//
+//	java_genrule {
+//	    name: "generated_sources",
+//	    tools: [
+//	        "generator",
+//	        "soong_zip",
+//	    ],
+//	    srcs: ["generator_inputs/**/*"],
+//	    out_srcjar: "generated_sources.srcjar",
+//	    cmd: "$(location generator) $(in) -o $(genDir)/src " +
+//	        "&& $(location soong_zip) -srcjar -o $(out) -C $(genDir)/src -D $(genDir)/src",
+//	}
+//
+//	java_library {
+//	    name: "lib_with_generated_sources",
+//	    srcs: [
+//	        "src/**/*.java",
+//	        ":generated_sources",
+//	    ],
+//	}
 func GenRuleFactory() android.Module {
-	module := genrule.NewGenRule()
+	module := newJavaGenRule()
 
 	android.InitAndroidArchModule(module, android.HostAndDeviceSupported, android.MultilibCommon)
 	android.InitDefaultableModule(module)
@@ -74,10 +100,68 @@ func GenRuleFactory() android.Module {
 // A java_genrule_host has a single variant that will run against the host variant of its dependencies and
 // produce an output that can be used as an input to a host java rule.
 func GenRuleFactoryHost() android.Module {
-	module := genrule.NewGenRule()
+	module := newJavaGenRule()
 
 	android.InitAndroidArchModule(module, android.HostSupported, android.MultilibCommon)
 	android.InitDefaultableModule(module)
 
 	return module
 }
+
+type genRuleProperties struct {
+	// Name of a .srcjar output file, in addition to the files listed in out. Like them, it is
+	// referenced with $(out) or $(location <name>) in cmd. The srcjar is checked to be a zip file
+	// that only contains .java and .kt files before any module uses it. Java modules that list the
+	// java_genrule in srcs use it as a srcjar without repackaging it: turbine reads it directly,
+	// javac and kotlinc extract it along with the other srcjars of the module.
+	Out_srcjar *string
+}
+
+func newJavaGenRule() *genrule.Module {
+	module := genrule.NewGenRule()
+
+	properties := &genRuleProperties{}
+	module.AddProperties(properties)
+	module.OutputValidator = func(ctx android.ModuleContext, outputs android.WritablePaths) android.Paths {
+		return checkSrcjar(ctx, proptools.String(properties.Out_srcjar), outputs)
+	}
+
+	android.AddLoadHook(module, func(ctx android.LoadHookContext) {
+		if outSrcjar := proptools.String(properties.Out_srcjar); outSrcjar != "" {
+			ctx.AppendProperties(&struct {
+				Out []string
+			}{
+				Out: []string{outSrcjar},
+			})
+		}
+	})
+
+	return module
+}
+
+// checkSrcjar returns the validation that checks that the out_srcjar output of a java_genrule is
+// a well-formed srcjar, or nil if it doesn't have one.
+func checkSrcjar(ctx android.ModuleContext, outSrcjar string, outputs android.WritablePaths) android.Paths {
+	if outSrcjar == "" {
+		return nil
+	}
+	if !strings.HasSuffix(outSrcjar, ".srcjar") {
+		ctx.PropertyErrorf("out_srcjar", "must end with .srcjar, got %q", outSrcjar)
+		return nil
+	}
+
+	srcjar := android.PathForModuleGen(ctx, outSrcjar)
+	if !android.InList(srcjar.String(), outputs.Strings()) {
+		return nil
+	}
+
+	stamp := android.PathForModuleOut(ctx, "check_srcjar", outSrcjar+".stamp")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		BuiltTool("check_srcjar").
+		FlagWithOutput("--output ", stamp).
+		Input(srcjar)
+	rule.Build("check_srcjar", "check srcjar "+outSrcjar)
+
+	return android.Paths{stamp}
+}
@@ -118,3 +118,52 @@ func TestJarGenrules(t *testing.T) {
 			barCombined.Inputs.Strings(), bar.Output.String(), jargen.Output.String())
 	}
 }
+
+func TestGenruleOutSrcjar(t *testing.T) {
+	t.Parallel()
+	ctx, _ := testJava(t, `
+		java_genrule {
+			name: "gen",
+			tool_files: ["tool"],
+			cmd: "$(location tool) $(in) -o $(out)",
+			srcs: ["foo.txt"],
+			out_srcjar: "gen.srcjar",
+		}
+
+		java_library {
+			name: "foo",
+			srcs: ["a.java", ":gen"],
+		}
+	`)
+
+	gen := ctx.ModuleForTests(t, "gen", "android_common")
+	srcjar := gen.Output("gen.srcjar")
+	checkSrcjar := gen.Output("check_srcjar/gen.srcjar.stamp")
+	android.AssertPathsRelativeToTopEquals(t, "check_srcjar implicits", []string{srcjar.Output.String()}, checkSrcjar.Implicits)
+	android.AssertPathsRelativeToTopEquals(t, "gen validations", []string{checkSrcjar.Output.String()}, srcjar.Validations)
+
+	// The srcjar is used as is, turbine reads it and javac extracts it with the other srcjars.
+	foo := ctx.ModuleForTests(t, "foo", "android_common")
+	javac := foo.Rule("javac")
+	android.AssertStringDoesContain(t, "foo javac srcjars", javac.Args["srcJars"], srcjar.Output.String())
+	android.AssertStringListContains(t, "foo javac implicits", javac.Implicits.Strings(), srcjar.Output.String())
+	turbine := foo.Rule("turbine")
+	android.AssertStringDoesContain(t, "foo turbine srcjars", turbine.Args["turbineFlags"], srcjar.Output.String())
+	android.AssertStringListContains(t, "foo turbine implicits", turbine.Implicits.Strings(), srcjar.Output.String())
+}
+
+func TestGenruleOutSrcjarExtension(t *testing.T) {
+	t.Parallel()
+	android.GroupFixturePreparers(
+		prepareForJavaTest,
+	).ExtendWithErrorHandler(android.FixtureExpectsOneErrorPattern(`out_srcjar: must end with .srcjar, got "gen.jar"`)).
+		RunTestWithBp(t, `
+		java_genrule {
+			name: "gen",
+			tool_files: ["tool"],
+			cmd: "$(location tool) $(in) -o $(out)",
+			srcs: ["foo.txt"],
+			out_srcjar: "gen.jar",
+		}
+	`)
+}
@@ -81,9 +81,17 @@ func newMonolithicHiddenAPIInfo(ctx android.ModuleContext, flagFilesByCategory F
 // append appends all the files from the supplied info to the corresponding files in this struct.
 func (i *MonolithicHiddenAPIInfo) append(ctx android.ModuleContext, otherModule android.Module, other *HiddenAPIInfo) {
 	i.FlagsFilesByCategory.append(other.FlagFilesByCategory)
-	i.AnnotationFlagsPaths = append(i.AnnotationFlagsPaths, other.AnnotationFlagsPath)
-	i.MetadataPaths = append(i.MetadataPaths, other.MetadataPath)
-	i.IndexPaths = append(i.IndexPaths, other.IndexPath)
+	// A prebuilt_bootclasspath_fragment that extracts its boot jars directly from the prebuilt apex
+	// does not provide these, they are provided by the apex instead.
+	if other.AnnotationFlagsPath != nil {
+		i.AnnotationFlagsPaths = append(i.AnnotationFlagsPaths, other.AnnotationFlagsPath)
+	}
+	if other.MetadataPath != nil {
+		i.MetadataPaths = append(i.MetadataPaths, other.MetadataPath)
+	}
+	if other.IndexPath != nil {
+		i.IndexPaths = append(i.IndexPaths, other.IndexPath)
+	}
 
 	apexInfo, ok := android.OtherModuleProvider(ctx, otherModule, android.ApexInfoProvider)
 	if !ok {
@@ -100,4 +108,17 @@ func (i *MonolithicHiddenAPIInfo) append(ctx android.ModuleContext, otherModule
 	}
 }
 
+// appendFromApexExports appends the hidden API files extracted from a prebuilt apex, if any.
+func (i *MonolithicHiddenAPIInfo) appendFromApexExports(exports android.ApexExportsInfo) {
+	if exports.HiddenAPIAnnotationFlagsPathOnHost != nil {
+		i.AnnotationFlagsPaths = append(i.AnnotationFlagsPaths, exports.HiddenAPIAnnotationFlagsPathOnHost)
+	}
+	if exports.HiddenAPIMetadataPathOnHost != nil {
+		i.MetadataPaths = append(i.MetadataPaths, exports.HiddenAPIMetadataPathOnHost)
+	}
+	if exports.HiddenAPIIndexPathOnHost != nil {
+		i.IndexPaths = append(i.IndexPaths, exports.HiddenAPIIndexPathOnHost)
+	}
+}
+
 var MonolithicHiddenAPIInfoProvider = blueprint.NewProvider[MonolithicHiddenAPIInfo]()
@@ -45,6 +45,46 @@ var (
 		"strippedJar", "stripSpec", "tmpDir", "tmpJar")
 )
 
+// JacocoCoverageMetadataInfo contains the files that the coverage report needs to attribute the
+// coverage of a module instrumented by jacoco to its sources.
+type JacocoCoverageMetadataInfo struct {
+	// The jar of the classes before instrumentation that the coverage report is generated from.
+	ReportClassesJar android.Path
+
+	// A JSON file with the Kotlin line mappings of the classes in ReportClassesJar, which attribute
+	// the lines of inlined functions back to the files and lines they were inlined from.  Nil if the
+	// module has no Kotlin sources.
+	KotlinSmapFile android.Path
+
+	// A copy of ReportClassesJar in which the lines of inlined functions are replaced by the lines of
+	// the calls they were inlined at.  Nil if the module has no Kotlin sources.
+	CorrectedReportClassesJar android.Path
+
+	// A JSON file that maps the jacoco ids of the classes in ReportClassesJar to the ids of the
+	// classes in CorrectedReportClassesJar, which the execution data has to be rewritten with before
+	// it is reported against CorrectedReportClassesJar.  Nil if the module has no Kotlin sources.
+	ClassIdsFile android.Path
+
+	// The line of the module in the coverage metadata list, with the paths of the files above.
+	CoverageMetadataFile android.Path
+}
+
+var JacocoCoverageMetadataInfoProvider = blueprint.NewProvider[JacocoCoverageMetadataInfo]()
+
+// jacocoCoverageMetadata collects the coverage metadata of all the modules instrumented by jacoco
+// into a single list for the coverage report.
+var jacocoCoverageMetadata = &moduleReport[JacocoCoverageMetadataInfo]{
+	env:      "EMMA_INSTRUMENT",
+	provider: JacocoCoverageMetadataInfoProvider,
+	goal:     "jacoco_coverage_metadata",
+	dist:     true,
+	outputs: []moduleReportOutput[JacocoCoverageMetadataInfo]{{
+		description: "jacoco coverage metadata",
+		path:        jacocoCoverageMetadataListPath,
+		file:        func(info JacocoCoverageMetadataInfo) android.Path { return info.CoverageMetadataFile },
+	}},
+}
+
 func jacocoDepsMutator(ctx android.BottomUpMutatorContext) {
 	type instrumentable interface {
 		shouldInstrument(ctx android.BaseModuleContext) bool
@@ -98,6 +138,60 @@ func jacocoInstrumentJar(ctx android.ModuleContext, instrumentedJar, strippedJar
 	})
 }
 
+// jacocoCoverageMetadataFiles sets the coverage metadata of the current module, which is instrumented
+// into reportClassesJar.  If kotlin is true it also extracts the SMAPs that the Kotlin compiler
+// stores in the classes of reportClassesJar into a JSON file of line mappings, and corrects the
+// lines of the inlined functions in a copy of reportClassesJar.  Jacoco reports the coverage of
+// inlined functions against the line numbers the Kotlin compiler gives them past the end of the
+// calling file, which the coverage report can only attribute to the inlined sources with the
+// mappings, or to the calls they were inlined at with the corrected jar.
+func jacocoCoverageMetadataFiles(ctx android.ModuleContext, reportClassesJar android.Path, jarName string, kotlin bool) {
+	info := JacocoCoverageMetadataInfo{
+		ReportClassesJar: reportClassesJar,
+	}
+	files := android.Paths{reportClassesJar}
+
+	if kotlin {
+		baseName := strings.TrimSuffix(jarName, filepath.Ext(jarName))
+		smapFile := android.PathForModuleOut(ctx, "jacoco-report-classes", baseName+"-kotlin-smap.json")
+		correctedJar := android.PathForModuleOut(ctx, "jacoco-report-classes", "kotlin", jarName)
+		classIdsFile := android.PathForModuleOut(ctx, "jacoco-report-classes", baseName+"-kotlin-class-ids.json")
+
+		rule := android.NewRuleBuilder(pctx, ctx)
+		rule.Command().
+			BuiltTool("extract_kotlin_smap").
+			FlagWithOutput("--output ", smapFile).
+			FlagWithOutput("--classes-output ", correctedJar).
+			FlagWithOutput("--class-ids-output ", classIdsFile).
+			Input(reportClassesJar)
+		rule.Build("jacoco_kotlin_smap", "jacoco kotlin smap")
+
+		info.KotlinSmapFile = smapFile
+		info.CorrectedReportClassesJar = correctedJar
+		info.ClassIdsFile = classIdsFile
+		files = append(files, smapFile, correctedJar, classIdsFile)
+	}
+
+	metadataFile := android.PathForModuleOut(ctx, "jacoco", "coverage-metadata.txt")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().Text("echo").Inputs(files).Text(">").Output(metadataFile)
+	rule.Build("jacoco_coverage_metadata", "jacoco coverage metadata")
+	info.CoverageMetadataFile = metadataFile
+
+	jacocoCoverageMetadata.setModuleFiles(ctx, info)
+}
+
+// jacocoCoverageMetadataListPath returns the path of the list of the coverage metadata files, one
+// line per instrumented module with the path of its report classes jar optionally followed by the
+// paths of its Kotlin line mappings, its corrected report classes jar and its class ids map.
+func jacocoCoverageMetadataListPath(ctx android.PathContext) android.WritablePath {
+	return android.PathForOutput(ctx, "jacoco", "coverage-metadata.txt")
+}
+
+func jacocoCoverageMetadataSingletonFactory() android.Singleton {
+	return jacocoCoverageMetadata
+}
+
 func (j *Module) jacocoModuleToZipCommand(ctx android.ModuleContext) string {
 	includes, err := jacocoFiltersToSpecs(j.properties.Jacoco.Include_filter)
 	if err != nil {
@@ -0,0 +1,79 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// Classes repackaged by jarjar_rules are invisible to hiddenapi and compat tooling that look for
+// them by their original name. When JARJAR_REPACKAGE_REPORT=true every module that runs jarjar on
+// its implementation jar writes a report of the classes that the rename rules apply to, and the
+// reports of all modules are concatenated into jarjar_repackage_report.txt, which is disted. Each
+// line of the report has the form "<module>: <original class> -> <renamed class>".
+
+// JarjarRepackageReportInfo contains the report of the classes that jarjar renamed in a module.
+type JarjarRepackageReportInfo struct {
+	// The report of the classes renamed in the implementation jar of the module.
+	Report android.Path
+}
+
+var JarjarRepackageReportInfoProvider = blueprint.NewProvider[JarjarRepackageReportInfo]()
+
+// jarjarRepackageReport collects the jarjar repackage reports of all java modules into a single
+// report.
+var jarjarRepackageReport = &moduleReport[JarjarRepackageReportInfo]{
+	env:      "JARJAR_REPACKAGE_REPORT",
+	provider: JarjarRepackageReportInfoProvider,
+	goal:     "jarjar_repackage_report",
+	dist:     true,
+	outputs: []moduleReportOutput[JarjarRepackageReportInfo]{{
+		description: "jarjar repackage report",
+		path:        jarjarRepackageReportPath,
+		file:        func(info JarjarRepackageReportInfo) android.Path { return info.Report },
+	}},
+}
+
+// buildJarjarRepackageReport writes the report of the classes in jar that are renamed by the jarjar
+// rules of the module, if the report is enabled.
+func (j *Module) buildJarjarRepackageReport(ctx android.ModuleContext, jar android.Path, jarName string) {
+	if !jarjarRepackageReport.enabled(ctx) {
+		return
+	}
+
+	report := android.PathForModuleOut(ctx, "jarjar", jarName+".repackage_report.txt")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		BuiltTool("jarjar_repackage_report").
+		FlagWithInput("--rules ", j.expandJarjarRules).
+		FlagWithArg("--module ", ctx.ModuleName()).
+		FlagWithOutput("--output ", report).
+		Input(jar)
+	rule.Build("jarjar_repackage_report", "jarjar repackage report")
+
+	jarjarRepackageReport.setModuleFiles(ctx, JarjarRepackageReportInfo{
+		Report: report,
+	})
+}
+
+func jarjarRepackageReportPath(ctx android.PathContext) android.WritablePath {
+	return android.PathForOutput(ctx, "jarjar", "jarjar_repackage_report.txt")
+}
+
+func jarjarRepackageReportSingletonFactory() android.Singleton {
+	return jarjarRepackageReport
+}
@@ -83,3 +83,211 @@ func TestJarJarRenameSameModule(t *testing.T) {
 	AssertJarJarRename(t, result, "their_lib", original, renamed)
 	AssertJarJarRename(t, result, "my_lib", original, renamed)
 }
+
+func TestBootclasspathJarjarRules(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			jarjar_rules: "foo-jarjar-rules.txt",
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["a.java"],
+		}
+
+		java_sdk_library {
+			name: "baz",
+			srcs: ["a.java"],
+			public: {enabled: true},
+		}
+	`
+	preparer := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithJavaSdkLibraryFiles,
+		FixtureWithLastReleaseApis("baz"),
+		FixtureConfigureBootJars("platform:foo"),
+		FixtureConfigureApexBootJars("myapex:baz"),
+		android.FixtureAddFile("foo-jarjar-rules.txt", nil),
+		android.FixtureAddFile("vendor/jarjar-rules.txt", nil),
+	)
+
+	t.Run("merged", func(t *testing.T) {
+		t.Parallel()
+		result := android.GroupFixturePreparers(
+			preparer,
+			android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+				variables.BootclasspathJarjarRules = []string{"vendor/jarjar-rules.txt"}
+			}),
+		).RunTestWithBp(t, bp)
+
+		foo := result.ModuleForTests(t, "foo", "android_common")
+		merged := foo.Output("jarjar/bootclasspath-jarjar-rules.txt")
+		android.AssertPathsRelativeToTopEquals(t, "merged rules inputs",
+			[]string{"foo-jarjar-rules.txt", "vendor/jarjar-rules.txt"}, merged.Inputs)
+		android.AssertStringEquals(t, "jarjar rules file", merged.Output.String(),
+			foo.Output("jarjar/foo.jar").Args["rulesFile"])
+
+		bar := result.ModuleForTests(t, "bar", "android_common")
+		if bar.MaybeOutput("jarjar/bootclasspath-jarjar-rules.txt").Rule != nil {
+			t.Errorf("expected bootclasspath jarjar rules not to apply to bar")
+		}
+
+		// The implementation library of a java_sdk_library in ApexBootJars provides its jar.
+		bazImpl := result.ModuleForTests(t, "baz.impl", "android_common")
+		android.AssertPathsRelativeToTopEquals(t, "baz.impl merged rules inputs",
+			[]string{"vendor/jarjar-rules.txt"}, bazImpl.Output("jarjar/bootclasspath-jarjar-rules.txt").Inputs)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		t.Parallel()
+		android.GroupFixturePreparers(
+			preparer,
+			android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+				variables.BootclasspathJarjarRules = []string{"vendor/missing.txt"}
+			}),
+		).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`module "foo".*: jarjar rules file "vendor/missing.txt" in BootclasspathJarjarRules does not exist`)).
+			RunTestWithBp(t, bp)
+	})
+}
+
+func TestJarjarShards(t *testing.T) {
+	t.Parallel()
+	preparer := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureAddFile("jarjar-rules.txt", nil),
+	)
+
+	t.Run("sharded", func(t *testing.T) {
+		t.Parallel()
+		result := preparer.RunTestWithBp(t, `
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				jarjar_rules: "jarjar-rules.txt",
+				jarjar_shards: "3",
+			}
+		`)
+
+		foo := result.ModuleForTests(t, "foo", "android_common")
+		var shards []string
+		for i := 0; i < 3; i++ {
+			shard := foo.Output(fmt.Sprintf("jarjar/foo.-%d.jar", i))
+			android.AssertStringEquals(t, "total_shards", "3", shard.Args["total_shards"])
+			android.AssertStringEquals(t, "shard_index", fmt.Sprint(i), shard.Args["shard_index"])
+			shards = append(shards, android.PathRelativeToTop(shard.Output))
+		}
+		if foo.MaybeOutput("jarjar/foo.-3.jar").Rule != nil {
+			t.Errorf("expected only 3 jarjar shards")
+		}
+
+		merge := foo.Output("jarjar/foo.jar")
+		android.AssertStringEquals(t, "merge description", "merge jarjar shards", merge.Description)
+		android.AssertPathsRelativeToTopEquals(t, "merge inputs", shards, merge.Inputs)
+	})
+
+	t.Run("auto", func(t *testing.T) {
+		t.Parallel()
+		result := preparer.RunTestWithBp(t, `
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				jarjar_rules: "jarjar-rules.txt",
+				jarjar_shards: "auto",
+			}
+		`)
+
+		foo := result.ModuleForTests(t, "foo", "android_common")
+		jarjar := foo.Output("jarjar/foo.jar")
+		android.AssertStringEquals(t, "total_shards", "1", jarjar.Args["total_shards"])
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		preparer.ExtendWithErrorHandler(android.FixtureExpectsOneErrorPattern(
+			`jarjar_shards must be a positive integer represented as a string or "auto"`)).
+			RunTestWithBp(t, `
+				java_library {
+					name: "foo",
+					srcs: ["a.java"],
+					jarjar_rules: "jarjar-rules.txt",
+					jarjar_shards: "0",
+				}
+			`)
+	})
+}
+
+func TestAutoJarjarShards(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		srcFiles int
+		shards   int
+	}{
+		{0, 1},
+		{1, 1},
+		{jarjarSrcFilesPerShard, 1},
+		{jarjarSrcFilesPerShard + 1, 2},
+		{5 * jarjarSrcFilesPerShard, 5},
+		{100 * jarjarSrcFilesPerShard, maxAutoJarjarShards},
+	}
+	for _, tc := range testCases {
+		android.AssertIntEquals(t, fmt.Sprintf("shards for %d source files", tc.srcFiles),
+			tc.shards, autoJarjarShards(tc.srcFiles))
+	}
+}
+
+func TestJarjarRepackageReport(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			jarjar_rules: "jarjar-rules.txt",
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+		}
+	`
+	preparer := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureAddFile("jarjar-rules.txt", nil),
+	)
+
+	result := android.GroupFixturePreparers(
+		preparer,
+		android.FixtureMergeEnv(map[string]string{
+			"JARJAR_REPACKAGE_REPORT": "true",
+		}),
+	).RunTestWithBp(t, bp)
+
+	foo := result.ModuleForTests(t, "foo", "android_common")
+	jarjar := foo.Output("jarjar/foo.jar")
+	report := foo.Output("jarjar/foo.jar.repackage_report.txt")
+	android.AssertStringDoesContain(t, "report command", report.RuleParams.Command,
+		"jarjar_repackage_report --rules jarjar-rules.txt --module foo")
+	android.AssertPathsRelativeToTopEquals(t, "report inputs",
+		android.SortedUniqueStrings([]string{"jarjar-rules.txt", android.PathRelativeToTop(jarjar.Input)}),
+		report.Implicits)
+
+	bar := result.ModuleForTests(t, "bar", "android_common")
+	if _, ok := android.OtherModuleProvider(result.OtherModuleProviderAdaptor(), bar.Module(),
+		JarjarRepackageReportInfoProvider); ok {
+		t.Errorf("expected no jarjar repackage report for bar")
+	}
+
+	merged := result.SingletonForTests(t, "jarjar_repackage_report").Output("jarjar/jarjar_repackage_report.txt")
+	android.AssertPathsRelativeToTopEquals(t, "merged reports",
+		[]string{"out/soong/.intermediates/foo/android_common/jarjar/foo.jar.repackage_report.txt"},
+		merged.Inputs)
+
+	// The report is not generated unless it is enabled.
+	result = preparer.RunTestWithBp(t, bp)
+	if result.ModuleForTests(t, "foo", "android_common").MaybeOutput("jarjar/foo.jar.repackage_report.txt").Rule != nil {
+		t.Errorf("expected no jarjar repackage report when JARJAR_REPACKAGE_REPORT is not set")
+	}
+}
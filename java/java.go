@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 
 	"android/soong/remoteexec"
@@ -77,6 +78,14 @@ func registerJavaBuildComponents(ctx android.RegistrationContext) {
 	})
 
 	ctx.RegisterParallelSingletonType("kythe_java_extract", kytheExtractJavaFactory)
+	ctx.RegisterParallelSingletonType("javac_werror_singleton", javacWerrorSingletonFactory)
+	ctx.RegisterParallelSingletonType("unused_deps_singleton", unusedDepsSingletonFactory)
+	ctx.RegisterParallelSingletonType("jacoco_coverage_metadata", jacocoCoverageMetadataSingletonFactory)
+	ctx.RegisterParallelSingletonType("javac_commands", javacCommandsSingletonFactory)
+	ctx.RegisterParallelSingletonType("jarjar_repackage_report", jarjarRepackageReportSingletonFactory)
+	ctx.RegisterParallelSingletonType("unsafe_escapes", unsafeEscapesSingletonFactory)
+	ctx.RegisterParallelSingletonType("proguard_dictionaries", proguardDictionariesSingletonFactory)
+	ctx.RegisterParallelSingletonType("desugaring_audit", desugaringAuditSingletonFactory)
 }
 
 func RegisterJavaSdkMemberTypes() {
@@ -247,6 +256,16 @@ type ProguardSpecInfo struct {
 
 	// implementation detail to store transitive proguard flags files from exporting shared deps
 	UnconditionallyExportedProguardFlags depset.DepSet[android.Path]
+
+	// ProguardFlagsFileOwners is a depset of the modules that the proguard flags files of this module
+	// and all its transitive deps come from, used to annotate the merged proguard configuration.
+	ProguardFlagsFileOwners depset.DepSet[ProguardFlagsFileOwner]
+}
+
+// ProguardFlagsFileOwner records the module that a proguard flags file comes from.
+type ProguardFlagsFileOwner struct {
+	Module string
+	File   android.Path
 }
 
 var ProguardSpecInfoProvider = blueprint.NewProvider[ProguardSpecInfo]()
@@ -270,6 +289,9 @@ type SdkLibraryComponentDependencyInfo struct {
 
 type ProvidesUsesLibInfo struct {
 	ProvidesUsesLib *string
+
+	// The optional attributes of the provided <uses-library>.
+	Attributes dexpreopt.UsesLibraryAttributes
 }
 
 type ModuleWithUsesLibraryInfo struct {
@@ -412,6 +434,9 @@ type JavaInfo struct {
 
 	LogtagsSrcs android.Paths
 
+	// The versions of the Kotlin standard library that are statically linked into this module.
+	KotlinStdlibs []KotlinStdlib
+
 	ProguardDictionary android.OptionalPath
 
 	ProguardUsageZip android.OptionalPath
@@ -561,6 +586,7 @@ func IsJniDepTag(depTag blueprint.DependencyTag) bool {
 var (
 	dataNativeBinsTag       = dependencyTag{name: "dataNativeBins"}
 	dataDeviceBinsTag       = dependencyTag{name: "dataDeviceBins"}
+	dataDeviceJniLibsTag    = dependencyTag{name: "dataDeviceJniLibs"}
 	staticLibTag            = dependencyTag{name: "staticlib", static: true}
 	libTag                  = dependencyTag{name: "javalib", runtimeLinked: true}
 	sdkLibTag               = dependencyTag{name: "sdklib", runtimeLinked: true}
@@ -707,6 +733,8 @@ type deps struct {
 	processorPath           classpath ``
 	errorProneProcessorPath classpath
 	processorClasses        []string
+	kspProcessorPath        classpath
+	kspProcessorClasses     []string
 	staticJars              android.Paths
 	staticHeaderJars        android.Paths
 	staticResourceJars      android.Paths
@@ -720,6 +748,15 @@ type deps struct {
 
 	disableTurbine bool
 
+	// The jars of the annotation processors that neither support KSP nor are incompatible with
+	// turbine, which are run with kapt in modules with Kotlin sources.
+	kaptProcessorPath classpath
+
+	// The annotation processors that are run with javac on the kapt stubs instead of with turbine in
+	// modules with Kotlin sources.
+	turbineIncompatibleProcessorPath    classpath
+	turbineIncompatibleProcessorClasses []string
+
 	transitiveStaticLibsHeaderJars         []depset.DepSet[android.Path]
 	transitiveStaticLibsImplementationJars []depset.DepSet[android.Path]
 	transitiveStaticLibsResourceJars       []depset.DepSet[android.Path]
@@ -766,6 +803,10 @@ const (
 	JAVA_VERSION_21          = 21
 )
 
+// The java version modules using javac preview language features are compiled as, which is the
+// release of the javac in the tree.
+const javaPreviewVersion javaVersion = JAVA_VERSION_21
+
 func (v javaVersion) String() string {
 	switch v {
 	case JAVA_VERSION_6:
@@ -849,6 +890,8 @@ type Library struct {
 	InstallMixin func(ctx android.ModuleContext, installPath android.Path) (extraInstallDeps android.InstallPaths)
 
 	apiXmlFile android.WritablePath
+
+	testFixturesProperties testFixturesProperties
 }
 
 var _ android.ApexModule = (*Library)(nil)
@@ -1080,8 +1123,9 @@ func (j *Library) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		j.HideFromMake()
 		j.SkipInstall()
 	}
-	j.provideHiddenAPIPropertyInfo(ctx)
+	j.provideHiddenAPIPropertyInfo(ctx, nil)
 
+	j.setApexMinSdkVersion(ctx)
 	j.sdkVersion = j.SdkVersion(ctx)
 	j.minSdkVersion = j.MinSdkVersion(ctx)
 	j.maxSdkVersion = j.MaxSdkVersion(ctx)
@@ -1105,6 +1149,8 @@ func (j *Library) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 
 	j.stem = proptools.StringDefault(j.overridableProperties.Stem, ctx.ModuleName())
 
+	j.usesLibrary.validateProvidesUsesLibAttributes(ctx)
+
 	proguardSpecInfo := j.collectProguardSpecInfo(ctx)
 	android.SetProvider(ctx, ProguardSpecInfoProvider, proguardSpecInfo)
 	exportedProguardFlagsFiles := proguardSpecInfo.ProguardFlagsFiles.ToList()
@@ -1121,6 +1167,7 @@ func (j *Library) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 
 	j.checkSdkVersions(ctx)
 	j.checkHeadersOnly(ctx)
+	j.checkJavacEnablePreview(ctx)
 	if ctx.Device() {
 		libName := j.Name()
 		if j.SdkLibraryName() != nil && strings.HasSuffix(libName, ".impl") {
@@ -1471,12 +1518,13 @@ func LibraryFactory() android.Module {
 	module := &Library{}
 
 	module.addHostAndDeviceProperties()
-	module.AddProperties(&module.sourceProperties)
+	module.AddProperties(&module.sourceProperties, &module.testFixturesProperties)
 
 	module.initModuleAndImport(module)
 
 	android.InitApexModule(module)
 	InitJavaModule(module, android.HostAndDeviceSupported)
+	module.SetDefaultableHook(module.createTestFixturesLibrary)
 	return module
 }
 
@@ -1493,11 +1541,13 @@ func LibraryHostFactory() android.Module {
 	module := &Library{}
 
 	module.addHostProperties()
+	module.AddProperties(&module.testFixturesProperties)
 
 	module.Module.properties.Installable = proptools.BoolPtr(true)
 
 	android.InitApexModule(module)
 	InitJavaModule(module, android.HostSupported)
+	module.SetDefaultableHook(module.createTestFixturesLibrary)
 	return module
 }
 
@@ -1519,6 +1569,11 @@ type TestOptions struct {
 	// Extra <option> tags to add to the auto generated test xml file under the test runner, e.g., AndroidJunitTest.
 	// The "key" is optional in each of these.
 	Test_runner_options []tradefed.Option
+
+	// The number of shards to split the test into.  If greater than 1, an additional test config
+	// named <module>_shard<index>.config is autogenerated for each shard, which runs only the
+	// tests in that shard.  Requires an autogenerated test config.
+	Shards *int64
 }
 
 type testProperties struct {
@@ -1576,12 +1631,19 @@ type testProperties struct {
 
 	// Install the test into a folder named for the module in all test suites.
 	Per_testcase_directory *bool
+
+	// list of java libraries whose test_fixtures are statically included in the test.
+	Uses_test_fixtures []string
 }
 
 type hostTestProperties struct {
 	// list of native binary modules that should be installed alongside the test
 	Data_native_bins []string `android:"arch_variant"`
 
+	// list of device shared library modules containing JNI libraries that should be installed
+	// alongside the test and pushed to the device for each supported device architecture
+	Data_device_jni_libs []string `android:"arch_variant"`
+
 	// list of device binary modules that should be installed alongside the test
 	// This property only adds the first variant of the dependency
 	Data_device_bins_first []string `android:"arch_variant"`
@@ -1749,6 +1811,25 @@ func (j *TestHost) addDataDeviceBinsDeps(ctx android.BottomUpMutatorContext) {
 	}
 }
 
+func (j *TestHost) addDataDeviceJniLibsDeps(ctx android.BottomUpMutatorContext) {
+	if len(j.testHostProperties.Data_device_jni_libs) == 0 {
+		return
+	}
+
+	var deviceTargets []android.Target
+	for _, multilib := range []string{"lib32", "lib64"} {
+		deviceTargets = append(deviceTargets, android.FirstTarget(ctx.Config().Targets[android.Android], multilib)...)
+	}
+	if len(deviceTargets) == 0 {
+		ctx.PropertyErrorf("data_device_jni_libs", "no device targets available. Targets: %q", ctx.Config().Targets)
+		return
+	}
+	for _, target := range deviceTargets {
+		sharedLibVariations := append(target.Variations(), blueprint.Variation{Mutator: "link", Variation: "shared"})
+		ctx.AddFarVariationDependencies(sharedLibVariations, dataDeviceJniLibsTag, j.testHostProperties.Data_device_jni_libs...)
+	}
+}
+
 func (j *TestHost) DepsMutator(ctx android.BottomUpMutatorContext) {
 	if len(j.testHostProperties.Data_native_bins) > 0 {
 		for _, target := range ctx.MultiTargets() {
@@ -1765,7 +1846,9 @@ func (j *TestHost) DepsMutator(ctx android.BottomUpMutatorContext) {
 	}
 
 	j.addDataDeviceBinsDeps(ctx)
+	j.addDataDeviceJniLibsDeps(ctx)
 	j.deps(ctx)
+	addTestFixturesDeps(ctx, j.testProperties.Uses_test_fixtures)
 }
 
 func (j *TestHost) AddExtraResource(p android.Path) {
@@ -1791,16 +1874,43 @@ func (j *TestHost) dataDeviceBins() []string {
 }
 
 func (j *TestHost) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	var dataDeviceJniLibs android.Paths
+	ctx.VisitDirectDepsProxyWithTag(dataDeviceJniLibsTag, func(dep android.ModuleProxy) {
+		sharedLibInfo, _ := android.OtherModuleProvider(ctx, dep, cc.SharedLibraryInfoProvider)
+		if sharedLibInfo.SharedLibrary == nil {
+			ctx.PropertyErrorf("data_device_jni_libs", "%q of type %q is not supported", dep.Name(), ctx.OtherModuleType(dep))
+			return
+		}
+		libDir := "lib"
+		if sharedLibInfo.Target.Arch.ArchType.Multilib == "lib64" {
+			libDir = "lib64"
+		}
+		// Install the device libraries into a separate directory so that they don't collide with
+		// the host libraries from jni_libs.
+		relocatedLib := android.PathForModuleOut(ctx, "relocated_device").Join(ctx,
+			"device", libDir, sharedLibInfo.SharedLibrary.Base())
+		ctx.Build(pctx, android.BuildParams{
+			Rule:   android.Cp,
+			Input:  sharedLibInfo.SharedLibrary,
+			Output: relocatedLib,
+		})
+		dataDeviceJniLibs = append(dataDeviceJniLibs, relocatedLib)
+	})
+
 	var configs []tradefed.Config
 	dataDeviceBins := j.dataDeviceBins()
-	if len(dataDeviceBins) > 0 {
-		// add Tradefed configuration to push device bins to device for testing
+	if len(dataDeviceBins) > 0 || len(dataDeviceJniLibs) > 0 {
+		// add Tradefed configuration to push device bins and libraries to device for testing
 		remoteDir := filepath.Join("/data/local/tests/unrestricted/", j.Name())
 		options := []tradefed.Option{{Name: "cleanup", Value: "true"}}
 		for _, bin := range dataDeviceBins {
 			fullPath := filepath.Join(remoteDir, bin)
 			options = append(options, tradefed.Option{Name: "push-file", Key: bin, Value: fullPath})
 		}
+		for _, lib := range dataDeviceJniLibs {
+			fullPath := filepath.Join(remoteDir, strings.TrimPrefix(lib.Rel(), "device/"))
+			options = append(options, tradefed.Option{Name: "push-file", Key: lib.Rel(), Value: fullPath})
+		}
 		configs = append(configs, tradefed.Object{
 			Type:    "target_preparer",
 			Class:   "com.android.tradefed.targetprep.PushFilePreparer",
@@ -1808,7 +1918,7 @@ func (j *TestHost) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		})
 	}
 
-	j.Test.generateAndroidBuildActionsWithConfig(ctx, configs)
+	j.Test.generateAndroidBuildActionsWithConfig(ctx, configs, dataDeviceJniLibs)
 	android.SetProvider(ctx, tradefed.BaseTestProviderKey, tradefed.BaseTestProviderData{
 		TestcaseRelDataFiles: testcaseRel(j.data),
 		OutputFile:           j.outputFile,
@@ -1828,18 +1938,23 @@ func (j *TestHost) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	}
 }
 
+func (j *Test) DepsMutator(ctx android.BottomUpMutatorContext) {
+	j.Library.DepsMutator(ctx)
+	addTestFixturesDeps(ctx, j.testProperties.Uses_test_fixtures)
+}
+
 func (j *Test) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	checkMinSdkVersionMts(ctx, j.MinSdkVersion(ctx))
-	j.generateAndroidBuildActionsWithConfig(ctx, nil)
+	j.generateAndroidBuildActionsWithConfig(ctx, nil, nil)
 }
 
-func (j *Test) generateAndroidBuildActionsWithConfig(ctx android.ModuleContext, configs []tradefed.Config) {
+func (j *Test) generateAndroidBuildActionsWithConfig(ctx android.ModuleContext, configs []tradefed.Config, extraData android.Paths) {
 	if j.testProperties.Test_options.Unit_test == nil && ctx.Host() {
 		// TODO(b/): Clean temporary heuristic to avoid unexpected onboarding.
 		defaultUnitTest := !inList("tradefed", j.properties.Libs) && !inList("cts", j.testProperties.Test_suites)
 		j.testProperties.Test_options.Unit_test = proptools.BoolPtr(defaultUnitTest)
 	}
-	j.testConfig = tradefed.AutoGenTestConfig(ctx, tradefed.AutoGenTestConfigOptions{
+	testConfigOptions := tradefed.AutoGenTestConfigOptions{
 		TestConfigProp:          j.testProperties.Test_config,
 		TestConfigTemplateProp:  j.testProperties.Test_config_template,
 		TestSuites:              j.testProperties.Test_suites,
@@ -1851,7 +1966,30 @@ func (j *Test) generateAndroidBuildActionsWithConfig(ctx android.ModuleContext,
 		DeviceTemplate:          "${JavaTestConfigTemplate}",
 		HostTemplate:            "${JavaHostTestConfigTemplate}",
 		HostUnitTestTemplate:    "${JavaHostUnitTestConfigTemplate}",
-	})
+	}
+
+	// A sharded test replaces its test config with the config of the first shard, and the configs
+	// of the other shards are installed as extra test configs, so that each test runs only once.
+	var shardTestConfigs android.Paths
+	if shards := proptools.Int(j.testProperties.Test_options.Shards); shards < 0 {
+		ctx.PropertyErrorf("test_options.shards", "must not be negative, got %d", shards)
+	} else if shards > 1 {
+		shardTestConfigs = tradefed.AutoGenShardedTestConfigs(ctx, testConfigOptions, shards)
+		if shardTestConfigs == nil {
+			ctx.PropertyErrorf("test_options.shards", "sharding requires an autogenerated test config")
+		} else {
+			android.SetProvider(ctx, tradefed.TestShardingInfoProvider, tradefed.TestShardingInfo{
+				ShardCount:       shards,
+				ShardTestConfigs: shardTestConfigs,
+			})
+		}
+	}
+	if len(shardTestConfigs) > 0 {
+		j.testConfig = shardTestConfigs[0]
+		shardTestConfigs = shardTestConfigs[1:]
+	} else {
+		j.testConfig = tradefed.AutoGenTestConfig(ctx, testConfigOptions)
+	}
 
 	j.data = android.PathsForModuleSrc(ctx, j.testProperties.Data)
 	j.data = append(j.data, android.PathsForModuleSrc(ctx, j.testProperties.Device_common_data)...)
@@ -1860,6 +1998,7 @@ func (j *Test) generateAndroidBuildActionsWithConfig(ctx android.ModuleContext,
 	j.data = append(j.data, android.PathsForModuleSrc(ctx, j.testProperties.Host_common_data)...)
 
 	j.extraTestConfigs = android.PathsForModuleSrc(ctx, j.testProperties.Test_options.Extra_test_configs)
+	j.extraTestConfigs = append(j.extraTestConfigs, shardTestConfigs...)
 
 	ctx.VisitDirectDepsProxyWithTag(dataNativeBinsTag, func(dep android.ModuleProxy) {
 		j.data = append(j.data, android.OutputFileForModule(ctx, dep, ""))
@@ -1869,6 +2008,8 @@ func (j *Test) generateAndroidBuildActionsWithConfig(ctx android.ModuleContext,
 		j.data = append(j.data, android.OutputFileForModule(ctx, dep, ""))
 	})
 
+	j.data = append(j.data, extraData...)
+
 	var directImplementationDeps android.Paths
 	var transitiveImplementationDeps []depset.DepSet[android.Path]
 	ctx.VisitDirectDepsProxyWithTag(jniLibTag, func(dep android.ModuleProxy) {
@@ -1929,6 +2070,9 @@ func (j *Test) generateAndroidBuildActionsWithConfig(ctx android.ModuleContext,
 	if _, ok := j.testConfig.(android.WritablePath); ok {
 		moduleInfoJSON.AutoTestConfig = []string{"true"}
 	}
+	if len(shardTestConfigs) > 0 {
+		moduleInfoJSON.ShardCount = strconv.Itoa(len(shardTestConfigs))
+	}
 	if proptools.Bool(j.testProperties.Test_options.Unit_test) {
 		moduleInfoJSON.IsUnitTest = "true"
 		if ctx.Host() {
@@ -2160,6 +2304,22 @@ type binaryProperties struct {
 
 	// Names of modules containing JNI libraries that should be installed alongside the binary.
 	Jni_libs []string `android:"arch_variant"`
+
+	// Default flags passed to the JVM by the generated wrapper of a host binary, before any -J flags
+	// passed to the wrapper. Not supported when wrapper is set.
+	Jvm_flags []string
+
+	// Environment variables that must be set when running a host binary. The generated wrapper fails
+	// with an error if any of them is unset. Not supported when wrapper is set.
+	Required_env []string
+
+	// Version of the JDK in prebuilts/jdk used by the generated wrapper of a host binary to run
+	// the jar, e.g. "21". Defaults to the java found in PATH. Not supported when wrapper is set.
+	Jdk_version *string
+
+	// Version of the tool built by a host binary, a dot separated list of numbers, e.g. "1.2".
+	// Modules using the binary as a tool can require a minimum version with required_host_tools.
+	Tool_version *string
 }
 
 type Binary struct {
@@ -2180,15 +2340,25 @@ func (j *Binary) HostToolPath() android.OptionalPath {
 func (j *Binary) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	j.stem = proptools.StringDefault(j.overridableProperties.Stem, ctx.ModuleName())
 
+	j.checkHostBinaryWrapperProperties(ctx)
+
+	if v := j.binaryProperties.Tool_version; v != nil {
+		if ctx.Device() {
+			ctx.PropertyErrorf("tool_version", "is only supported for host binaries")
+		} else if _, err := android.ParseHostToolVersion(*v); err != nil {
+			ctx.PropertyErrorf("tool_version", "%s", err)
+		} else {
+			android.SetProvider(ctx, android.HostToolVersionInfoProvider, android.HostToolVersionInfo{
+				Version: *v,
+			})
+		}
+	}
+
 	// Handle the binary wrapper. This comes before compiling the jar so that the wrapper
 	// is the first PackagingSpec
 	if j.binaryProperties.Wrapper != nil {
 		j.wrapperFile = android.PathForModuleSrc(ctx, *j.binaryProperties.Wrapper)
 	} else {
-		if ctx.Windows() {
-			ctx.PropertyErrorf("wrapper", "wrapper is required for Windows")
-		}
-
 		if ctx.Device() {
 			// device binary should have a main_class property if it does not
 			// have a specific wrapper, so that a default wrapper can
@@ -2211,6 +2381,10 @@ func (j *Binary) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 				})
 				j.wrapperFile = wrapper
 			}
+		} else if ctx.Windows() {
+			j.wrapperFile = j.generateWindowsBinaryWrapper(ctx)
+		} else if j.hasHostBinaryWrapperProperties() {
+			j.wrapperFile = j.generateHostBinaryWrapper(ctx)
 		} else {
 			j.wrapperFile = android.PathForSource(ctx, "build/soong/scripts/jar-wrapper.sh")
 		}
@@ -2384,6 +2558,15 @@ type JavaApiLibraryProperties struct {
 	// This is a list of Soong modules
 	Api_contributions []string
 
+	// How overlapping definitions in the modules listed in api_contributions are handled. If set,
+	// must be one of "error" or "merge". If unset, all the contributed API files are passed to
+	// metalava without any checks.
+	// - "error" fails the build if the same API file is contributed more than once, or if two
+	// contributions to the same API surface define the same class.
+	// - "merge" drops duplicate API files and passes the rest to metalava, which merges the
+	// overlapping class definitions.
+	Api_contributions_merge_strategy *string
+
 	// List of flags to be passed to the javac compiler to generate jar file
 	Javacflags []string
 
@@ -2609,6 +2792,76 @@ func (al *ApiLibrary) sortApiFilesByApiScope(ctx android.ModuleContext, srcFiles
 	return srcFilesInfo
 }
 
+const (
+	apiContributionsMergeStrategyError = "error"
+	apiContributionsMergeStrategyMerge = "merge"
+)
+
+// checkApiContributionConflicts returns srcFilesInfo without API files that were contributed more
+// than once if api_contributions_merge_strategy is set. With the "error" merge strategy such
+// duplicates are reported, and a validation is added that fails the build if two contributions to
+// the same API surface define the same class.
+func (al *ApiLibrary) checkApiContributionConflicts(ctx android.ModuleContext, srcFilesInfo []JavaApiImportInfo) []JavaApiImportInfo {
+	if al.properties.Api_contributions_merge_strategy == nil {
+		return srcFilesInfo
+	}
+	strategy := *al.properties.Api_contributions_merge_strategy
+	validStrategies := []string{apiContributionsMergeStrategyError, apiContributionsMergeStrategyMerge}
+	if !android.InList(strategy, validStrategies) {
+		ctx.PropertyErrorf("api_contributions_merge_strategy", "%q is not a valid merge strategy, must be one of %q",
+			strategy, validStrategies)
+		return srcFilesInfo
+	}
+
+	var uniqueSrcFilesInfo []JavaApiImportInfo
+	seen := make(map[string]bool)
+	filesPerSurface := make(map[string]int)
+	for _, srcFileInfo := range srcFilesInfo {
+		if srcFileInfo.ApiFile != nil {
+			apiFile := srcFileInfo.ApiFile.String()
+			if seen[apiFile] {
+				if strategy == apiContributionsMergeStrategyError {
+					ctx.PropertyErrorf("api_contributions", "%s is contributed more than once", apiFile)
+				}
+				continue
+			}
+			seen[apiFile] = true
+		}
+		filesPerSurface[srcFileInfo.ApiSurface]++
+		uniqueSrcFilesInfo = append(uniqueSrcFilesInfo, srcFileInfo)
+	}
+
+	if strategy == apiContributionsMergeStrategyMerge {
+		return uniqueSrcFilesInfo
+	}
+
+	// Classes can only conflict between API files of the same surface.
+	needsCheck := false
+	for _, count := range filesPerSurface {
+		if count > 1 {
+			needsCheck = true
+		}
+	}
+	if !needsCheck {
+		return uniqueSrcFilesInfo
+	}
+
+	stamp := android.PathForModuleOut(ctx, "check_api_contributions.timestamp")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	cmd := rule.Command().BuiltTool("check_api_contributions")
+	for _, srcFileInfo := range uniqueSrcFilesInfo {
+		if srcFileInfo.ApiFile != nil {
+			cmd.FlagWithInput("--api_file "+srcFileInfo.ApiSurface+":",
+				android.PathForSource(ctx, srcFileInfo.ApiFile.String()))
+		}
+	}
+	cmd.FlagWithOutput("--stamp ", stamp)
+	rule.Build("check_api_contributions", "check api contributions")
+
+	al.validationPaths = append(al.validationPaths, stamp)
+	return uniqueSrcFilesInfo
+}
+
 var validstubsType = []StubsType{Everything, Runtime, Exportable}
 
 func (al *ApiLibrary) validateProperties(ctx android.ModuleContext) {
@@ -2692,6 +2945,7 @@ func (al *ApiLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	})
 
 	srcFilesInfo = al.sortApiFilesByApiScope(ctx, srcFilesInfo)
+	srcFilesInfo = al.checkApiContributionConflicts(ctx, srcFilesInfo)
 	var srcFiles android.Paths
 	for _, srcFileInfo := range srcFilesInfo {
 		srcFiles = append(srcFiles, android.PathForSource(ctx, srcFileInfo.ApiFile.String()))
@@ -2855,6 +3109,10 @@ var _ android.IDEInfo = (*ApiLibrary)(nil)
 type ImportProperties struct {
 	Jars []string `android:"path,arch_variant"`
 
+	// If set, the jars are the Kotlin standard library of the given version.  Apps report an error
+	// if more than one version of the Kotlin standard library is statically linked into them.
+	Kotlin_stdlib_version *string
+
 	// The version of the SDK that the source prebuilt file was built against. Defaults to the
 	// current version if not specified.
 	Sdk_version *string
@@ -2908,6 +3166,10 @@ type ImportProperties struct {
 
 	// Property signifying whether the module provides stubs jar or not.
 	Is_stubs_module *bool
+
+	// Files containing proguard flags, such as -keep rules, that the jars require.  They are
+	// exported to modules that depend on this module along with any R8 rules embedded in the jars.
+	Proguard_flags_files []string `android:"path"`
 }
 
 type Import struct {
@@ -3164,12 +3426,14 @@ func (j *Import) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 
 	proguardFlags := android.PathForModuleOut(ctx, "proguard_flags")
 	TransformJarToR8Rules(ctx, proguardFlags, outputFile)
+	proguardFlagsFiles := append(android.Paths{proguardFlags},
+		android.PathsForModuleSrc(ctx, j.properties.Proguard_flags_files)...)
 
 	transitiveProguardFlags, transitiveUnconditionalExportedFlags := collectDepProguardSpecInfo(ctx)
 	android.SetProvider(ctx, ProguardSpecInfoProvider, ProguardSpecInfo{
 		ProguardFlagsFiles: depset.New[android.Path](
 			depset.POSTORDER,
-			android.Paths{proguardFlags},
+			proguardFlagsFiles,
 			transitiveProguardFlags,
 		),
 		UnconditionallyExportedProguardFlags: depset.New[android.Path](
@@ -3177,6 +3441,7 @@ func (j *Import) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 			nil,
 			transitiveUnconditionalExportedFlags,
 		),
+		ProguardFlagsFileOwners: proguardFlagsFileOwners(ctx, proguardFlagsFiles),
 	})
 
 	// Save the output file with no relative path so that it doesn't end up in a subdirectory when used as a resource.
@@ -3256,6 +3521,7 @@ func (j *Import) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		ResourceJars:                           android.PathsIfNonNil(resourceJarFile),
 		AidlIncludeDirs:                        j.exportAidlIncludeDirs,
 		StubsLinkType:                          j.stubsLinkType,
+		KotlinStdlibs:                          collectKotlinStdlibs(ctx, j.properties.Kotlin_stdlib_version),
 		// TODO(b/289117800): LOCAL_ACONFIG_FILES for prebuilts
 	}
 	setExtraJavaInfo(ctx, j, javaInfo)
@@ -3686,6 +3952,10 @@ func addCLCFromDep(ctx android.ModuleContext, depModule android.ModuleProxy,
 	depName := android.RemoveOptionalPrebuiltPrefix(ctx.OtherModuleName(depModule))
 
 	var sdkLib *string
+	var sdkLibAttrs dexpreopt.UsesLibraryAttributes
+	if ulib := dep.ProvidesUsesLibInfo; ulib != nil {
+		sdkLibAttrs = ulib.Attributes
+	}
 	if lib, ok := android.OtherModuleProvider(ctx, depModule, SdkLibraryInfoProvider); ok && lib.SharedLibrary {
 		// A shared SDK library. This should be added as a top-level CLC element.
 		sdkLib = &depName
@@ -3727,7 +3997,7 @@ func addCLCFromDep(ctx android.ModuleContext, depModule android.ModuleProxy,
 				optional = true
 			}
 		}
-		clcMap.AddContext(ctx, dexpreopt.AnySdkVersion, *sdkLib, optional,
+		clcMap.AddContextWithAttributes(ctx, dexpreopt.AnySdkVersion, *sdkLib, sdkLibAttrs, optional,
 			dep.DexJarBuildPath.PathOrNil(),
 			dep.UsesLibraryDependencyInfo.DexJarInstallPath, dep.UsesLibraryDependencyInfo.ClassLoaderContexts)
 	} else {
@@ -3826,6 +4096,7 @@ func setExtraJavaInfo(ctx android.ModuleContext, module android.Module, javaInfo
 	if pul, ok := module.(ProvidesUsesLib); ok {
 		javaInfo.ProvidesUsesLibInfo = &ProvidesUsesLibInfo{
 			ProvidesUsesLib: pul.ProvidesUsesLib(),
+			Attributes:      pul.ProvidesUsesLibAttributes(),
 		}
 	}
 
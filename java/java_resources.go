@@ -103,6 +103,42 @@ func ResourceFilesToJarArgs(ctx android.ModuleContext,
 	return resourceFilesToJarArgs(ctx, res, slices.Concat(exclude, resourceExcludes))
 }
 
+// ResourceFilesToJarArgsWithStripPrefix is like ResourceFilesToJarArgs, but strips stripPrefix from
+// the path of each file in the jar.  Files that are not under stripPrefix are reported as errors.
+func ResourceFilesToJarArgsWithStripPrefix(ctx android.ModuleContext,
+	res, exclude []string, stripPrefix string) (args []string, deps android.Paths) {
+
+	if stripPrefix == "" {
+		return ResourceFilesToJarArgs(ctx, res, exclude)
+	}
+
+	if filepath.IsAbs(stripPrefix) || filepath.Clean(stripPrefix) != stripPrefix ||
+		stripPrefix == "." || stripPrefix == ".." || strings.HasPrefix(stripPrefix, "../") {
+		ctx.PropertyErrorf("java_resource_strip_prefix", "must be a clean relative path, got %q", stripPrefix)
+		return nil, nil
+	}
+
+	files := android.PathsForModuleSrcExcludes(ctx, res, slices.Concat(exclude, resourceExcludes))
+	if len(files) == 0 {
+		ctx.PropertyErrorf("java_resource_strip_prefix", "is set but there are no java_resources")
+		return nil, nil
+	}
+
+	notUnderPrefix := false
+	for _, f := range files {
+		if !strings.HasPrefix(f.Rel(), stripPrefix+"/") {
+			ctx.PropertyErrorf("java_resource_strip_prefix", "java_resources file %q is not under %q",
+				f.Rel(), stripPrefix)
+			notUnderPrefix = true
+		}
+	}
+	if notUnderPrefix {
+		return nil, nil
+	}
+
+	return resourcePathsToJarArgsWithStripPrefix(files, stripPrefix), files
+}
+
 func resourceFilesToJarArgs(ctx android.ModuleContext,
 	res, exclude []string) (args []string, deps android.Paths) {
 
@@ -114,11 +150,20 @@ func resourceFilesToJarArgs(ctx android.ModuleContext,
 }
 
 func resourcePathsToJarArgs(files android.Paths) []string {
+	return resourcePathsToJarArgsWithStripPrefix(files, "")
+}
+
+// resourcePathsToJarArgsWithStripPrefix returns the arguments to soong_zip that put each file at
+// its path with stripPrefix removed in the jar.  The path of each file must be under stripPrefix.
+func resourcePathsToJarArgsWithStripPrefix(files android.Paths, stripPrefix string) []string {
 	var args []string
 
 	lastDir := ""
 	for i, f := range files {
 		rel := f.Rel()
+		if stripPrefix != "" {
+			rel = strings.TrimPrefix(rel, stripPrefix+"/")
+		}
 		path := f.String()
 		if !strings.HasSuffix(path, rel) {
 			panic(fmt.Errorf("path %q does not end with %q", path, rel))
@@ -15,10 +15,12 @@
 package java
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"runtime"
 	"slices"
 	"strconv"
@@ -33,6 +35,7 @@ import (
 	"android/soong/cc"
 	"android/soong/dexpreopt"
 	"android/soong/genrule"
+	"android/soong/tradefed"
 )
 
 // Legacy preparer used for running tests within the java package.
@@ -585,6 +588,41 @@ func TestTest(t *testing.T) {
 	}
 }
 
+func TestTestHostDataDeviceJniLibs(t *testing.T) {
+	t.Parallel()
+	ctx := android.GroupFixturePreparers(PrepareForIntegrationTestWithJava).RunTestWithBp(t, `
+		java_test_host {
+			name: "foo",
+			srcs: ["a.java"],
+			data_device_jni_libs: ["libjni"],
+		}
+
+		cc_library_shared {
+			name: "libjni",
+			stl: "none",
+		}
+	`)
+
+	buildOS := ctx.Config.BuildOS.String()
+	fooVariant := ctx.ModuleForTests(t, "foo", buildOS+"_common")
+	foo := fooVariant.Module().(*TestHost)
+
+	var dataRel []string
+	for _, data := range foo.data {
+		dataRel = append(dataRel, data.Rel())
+	}
+	android.AssertDeepEquals(t, "foo test data", []string{"device/lib/libjni.so", "device/lib64/libjni.so"},
+		android.SortedUniqueStrings(dataRel))
+
+	autogen := fooVariant.Rule("autogen")
+	for _, expected := range []string{
+		`<option name="push-file" key="device/lib/libjni.so" value="/data/local/tests/unrestricted/foo/lib/libjni.so" />`,
+		`<option name="push-file" key="device/lib64/libjni.so" value="/data/local/tests/unrestricted/foo/lib64/libjni.so" />`,
+	} {
+		android.AssertStringDoesContain(t, "foo extraConfigs", autogen.Args["extraConfigs"], expected)
+	}
+}
+
 func TestHostCommonData(t *testing.T) {
 	t.Parallel()
 	ctx, _ := testJava(t, `
@@ -647,6 +685,524 @@ func TestHostBinaryNoJavaDebugInfoOverride(t *testing.T) {
 	}
 }
 
+func TestMultiRelease(t *testing.T) {
+	t.Parallel()
+	ctx, _ := testJava(t, `
+		java_library_host {
+			name: "foo",
+			srcs: ["a.java"],
+			multi_release: [
+				{
+					release: 17,
+					srcs: ["b.java"],
+				},
+				{
+					release: 21,
+					srcs: ["c.java"],
+				},
+			],
+		}
+
+		java_library_host {
+			name: "bar",
+			srcs: ["a.java"],
+			manifest: "manifest.txt",
+			multi_release: [
+				{
+					release: 17,
+					srcs: ["b.java"],
+				},
+			],
+		}
+	`)
+
+	buildOSCommon := ctx.Config().BuildOSCommonTarget.String()
+	foo := ctx.ModuleForTests(t, "foo", buildOSCommon)
+	javac := foo.Output("javac/foo.jar")
+
+	release17 := foo.Output("multi-release/17.jar")
+	android.AssertStringEquals(t, "release", "17", release17.Args["release"])
+	android.AssertStringEquals(t, "release 17 classes dir", "META-INF/versions/17", release17.Args["versionDir"])
+	android.AssertStringDoesContain(t, "release 17 jar", release17.RuleParams.Command,
+		"-jar -o $out -P $versionDir -C $outDir -D $outDir")
+	android.AssertPathsRelativeToTopEquals(t, "release 17 srcs", []string{"b.java"}, release17.Inputs)
+	android.AssertStringDoesContain(t, "release 17 classpath", release17.Args["classpath"], javac.Output.String())
+	release21 := foo.Output("multi-release/21.jar")
+	android.AssertPathsRelativeToTopEquals(t, "release 21 srcs", []string{"c.java"}, release21.Inputs)
+	android.AssertStringEquals(t, "release 21 classes dir", "META-INF/versions/21", release21.Args["versionDir"])
+
+	combined := foo.Output("combined/foo.jar")
+	android.AssertPathsRelativeToTopEquals(t, "combined inputs", []string{
+		javac.Output.RelativeToTop().String(),
+		release17.Output.RelativeToTop().String(),
+		release21.Output.RelativeToTop().String(),
+	}, combined.Inputs)
+	manifest := foo.Output("multi-release/manifest.txt")
+	android.AssertStringDoesContain(t, "combined manifest", combined.Args["jarArgs"], manifest.Output.String())
+	android.AssertStringEquals(t, "generated manifest", "Multi-Release: true",
+		android.ContentFromFileRuleForTests(t, ctx, manifest))
+	// Only the module-info.class files of the releases are stripped from the combined jar, the
+	// classes of the releases are kept under META-INF/versions.
+	android.AssertStringDoesContain(t, "combined jar args", combined.Args["jarArgs"],
+		"-stripFile META-INF/versions/*/module-info.class")
+	android.AssertStringDoesNotContain(t, "combined jar args", combined.Args["jarArgs"], "-stripDir META-INF")
+
+	bar := ctx.ModuleForTests(t, "bar", buildOSCommon)
+	check := bar.Output("multi-release/manifest.stamp")
+	android.AssertPathRelativeToTopEquals(t, "manifest check", "manifest.txt", check.Input)
+	android.AssertPathsRelativeToTopEquals(t, "combined validations", []string{check.Output.RelativeToTop().String()},
+		bar.Output("combined/bar.jar").Validations)
+}
+
+func TestMultiReleaseErrors(t *testing.T) {
+	t.Parallel()
+	testJavaError(t, `multi_release: release must be at least 9, got 8`, `
+		java_library_host {
+			name: "foo",
+			srcs: ["a.java"],
+			multi_release: [
+				{
+					release: 8,
+					srcs: ["b.java"],
+				},
+			],
+		}
+	`)
+
+	testJavaError(t, `multi_release: release 17 is listed more than once`, `
+		java_library_host {
+			name: "foo",
+			srcs: ["a.java"],
+			multi_release: [
+				{
+					release: 17,
+					srcs: ["b.java"],
+				},
+				{
+					release: 17,
+					srcs: ["c.java"],
+				},
+			],
+		}
+	`)
+
+	testJavaError(t, `multi_release: is only supported for host modules`, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			multi_release: [
+				{
+					release: 17,
+					srcs: ["b.java"],
+				},
+			],
+		}
+	`)
+}
+
+func TestTestFixtures(t *testing.T) {
+	t.Parallel()
+	ctx, _ := testJava(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			host_supported: true,
+			test_fixtures: {
+				srcs: ["fixtures/a.java"],
+				libs: ["bar"],
+			},
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+			host_supported: true,
+		}
+
+		java_test {
+			name: "foo-tests",
+			srcs: ["c.java"],
+			static_libs: ["foo"],
+			uses_test_fixtures: ["foo"],
+		}
+
+		java_test_host {
+			name: "foo-host-tests",
+			srcs: ["c.java"],
+			static_libs: ["foo"],
+			uses_test_fixtures: ["foo"],
+		}
+	`)
+
+	fixtures := ctx.ModuleForTests(t, "foo.test-fixtures", "android_common")
+	javac := fixtures.Rule("javac")
+	android.AssertPathsRelativeToTopEquals(t, "fixtures srcs", []string{"fixtures/a.java"}, javac.Inputs)
+	android.AssertStringDoesContain(t, "fixtures classpath", javac.Args["classpath"],
+		"out/soong/.intermediates/foo/android_common/turbine-combined/foo.jar")
+	android.AssertStringDoesContain(t, "fixtures classpath", javac.Args["classpath"],
+		"out/soong/.intermediates/bar/android_common/turbine-combined/bar.jar")
+
+	// The host variant of the fixtures is created as foo is host_supported.
+	ctx.ModuleForTests(t, "foo.test-fixtures", ctx.Config().BuildOSCommonTarget.String())
+
+	for _, test := range []struct{ name, variant string }{
+		{"foo-tests", "android_common"},
+		{"foo-host-tests", ctx.Config().BuildOSCommonTarget.String()},
+	} {
+		combined := ctx.ModuleForTests(t, test.name, test.variant).Output("combined/" + test.name + ".jar")
+		android.AssertStringDoesContain(t, test.name+" combined inputs",
+			strings.Join(combined.Inputs.RelativeToTop().Strings(), " "),
+			"out/soong/.intermediates/foo.test-fixtures/"+test.variant+"/")
+	}
+}
+
+func TestTestFixturesErrors(t *testing.T) {
+	t.Parallel()
+	testJavaError(t, `"bar" does not have test_fixtures`, `
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+		}
+
+		java_test {
+			name: "foo-tests",
+			srcs: ["c.java"],
+			uses_test_fixtures: ["bar"],
+		}
+	`)
+
+	testJavaError(t, `test_fixtures: srcs must be set`, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			test_fixtures: {
+				libs: ["bar"],
+			},
+		}
+	`)
+}
+
+func TestJavacWerrorEnforcement(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_library {
+			name: "enforced",
+			srcs: ["a.java"],
+		}
+
+		java_library {
+			name: "exempt",
+			srcs: ["a.java"],
+		}
+
+		java_library {
+			name: "opted_in",
+			srcs: ["a.java"],
+			javacflags: ["-Werror"],
+		}
+
+		java_library {
+			name: "no_srcs",
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.JavacWerrorEnforcedModules = []string{"*"}
+			variables.JavacWerrorExemptModules = []string{"exempt"}
+		}),
+	).RunTestWithBp(t, bp)
+
+	javacFlags := func(name string) []string {
+		module := result.ModuleForTests(t, name, "android_common")
+		return strings.Split(module.Module().VariablesForTests()["javacFlags"], " ")
+	}
+	android.AssertStringListContains(t, "enforced javacFlags", javacFlags("enforced"), "-Werror")
+	android.AssertStringListDoesNotContain(t, "exempt javacFlags", javacFlags("exempt"), "-Werror")
+
+	report := result.SingletonForTests(t, "javac_werror_singleton").Output("javac_werror/javac_werror_status.csv")
+	csv := android.ContentFromFileRuleForTests(t, result.TestContext, report)
+	android.AssertStringDoesContain(t, "javac_werror_status.csv", csv, "module,status\n")
+	android.AssertStringDoesContain(t, "javac_werror_status.csv", csv, "\nenforced,enforced\n")
+	android.AssertStringDoesContain(t, "javac_werror_status.csv", csv, "\nexempt,exempt\n")
+	android.AssertStringDoesContain(t, "javac_werror_status.csv", csv, "\nopted_in,opted_in\n")
+	android.AssertStringDoesNotContain(t, "javac_werror_status.csv", csv, "no_srcs")
+}
+
+func TestUnsafeEscapes(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_sdk_library {
+			name: "sdklib",
+			srcs: ["a.java"],
+			unsafe_ignore_missing_latest_api: true,
+		}
+
+		android_app_import {
+			name: "app",
+			apk: "prebuilts/apk/app.apk",
+			presigned: true,
+			preprocessed: true,
+			skip_preprocessed_apk_checks: true,
+		}
+
+		android_app_import {
+			name: "checked_app",
+			apk: "prebuilts/apk/app.apk",
+			presigned: true,
+			preprocessed: true,
+		}
+	`
+	preparer := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithJavaSdkLibraryFiles,
+	)
+
+	result := preparer.RunTestWithBp(t, bp)
+	report := result.SingletonForTests(t, "unsafe_escapes").Output("unsafe_escapes/unsafe_escapes.csv")
+	android.AssertStringEquals(t, "unsafe_escapes.csv", ""+
+		"module,property\n"+
+		"app,skip_preprocessed_apk_checks\n"+
+		"sdklib,unsafe_ignore_missing_latest_api\n",
+		android.ContentFromFileRuleForTests(t, result.TestContext, report))
+
+	enforce := func(allowlist ...string) android.FixturePreparer {
+		return android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.EnforceUnsafeEscapesAllowlist = proptools.BoolPtr(true)
+			variables.UnsafeEscapesAllowlist = allowlist
+		})
+	}
+
+	android.GroupFixturePreparers(
+		preparer,
+		enforce("sdklib:unsafe_ignore_missing_latest_api", "app:skip_preprocessed_apk_checks"),
+	).RunTestWithBp(t, bp)
+
+	android.GroupFixturePreparers(
+		preparer,
+		enforce("sdklib:unsafe_ignore_missing_latest_api"),
+	).ExtendWithErrorHandler(android.FixtureExpectsOneErrorPattern(
+		`skip_preprocessed_apk_checks: is an unsafe escape hatch that requires approval, `+
+			`add "app:skip_preprocessed_apk_checks" to PRODUCT_UNSAFE_ESCAPES_ALLOWLIST`)).
+		RunTestWithBp(t, bp)
+}
+
+func TestJavacEnablePreview(t *testing.T) {
+	t.Parallel()
+	result := PrepareForTestWithJavaDefaultModules.RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			javac_enable_preview: true,
+		}
+	`)
+
+	foo := result.ModuleForTests(t, "foo", "android_common")
+	javacFlags := strings.Split(foo.Module().VariablesForTests()["javacFlags"], " ")
+	android.AssertStringListContains(t, "foo javacFlags", javacFlags, "--enable-preview")
+	android.AssertStringEquals(t, "foo javaVersion", "21", foo.Rule("javac").Args["javaVersion"])
+
+	t.Run("java_version mismatch", func(t *testing.T) {
+		t.Parallel()
+		PrepareForTestWithJavaDefaultModules.
+			ExtendWithErrorHandler(android.FixtureExpectsOneErrorPattern(
+				`javac_enable_preview: requires java_version 21, got 17`)).
+			RunTestWithBp(t, `
+				java_library {
+					name: "foo",
+					srcs: ["a.java"],
+					java_version: "17",
+					javac_enable_preview: true,
+				}
+			`)
+	})
+
+	t.Run("on bootclasspath", func(t *testing.T) {
+		t.Parallel()
+		android.GroupFixturePreparers(
+			PrepareForTestWithJavaDefaultModules,
+			android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+				variables.BootJars = android.CreateTestConfiguredJarList([]string{"platform:foo"})
+			}),
+		).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`javac_enable_preview: can not be used by modules on the bootclasspath`,
+		)).RunTestWithBp(t, `
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				javac_enable_preview: true,
+			}
+		`)
+	})
+}
+
+func TestUnusedDepsReport(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			libs: ["bar"],
+			static_libs: ["baz"],
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+		}
+
+		java_library {
+			name: "baz",
+			srcs: ["c.java"],
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureMergeEnv(map[string]string{
+			"SOONG_UNUSED_DEPS_REPORT": "true",
+		}),
+	).RunTestWithBp(t, bp)
+
+	foo := result.ModuleForTests(t, "foo", "android_common")
+	javac := foo.Output("javac/foo.jar")
+
+	barTrace := foo.Output("unused_deps/libs/bar/0.keep")
+	android.AssertStringEquals(t, "bar trace rule", traceReferences.String(), barTrace.Rule.String())
+	barInfo, _ := android.OtherModuleProvider(result, result.ModuleForTests(t, "bar", "android_common").Module(), JavaInfoProvider)
+	android.AssertDeepEquals(t, "bar trace target", barInfo.HeaderJars[0], barTrace.Input)
+	android.AssertStringDoesContain(t, "bar trace sources", barTrace.Args["sources"],
+		"--source "+javac.Output.String())
+	foo.Output("unused_deps/static_libs/baz/0.keep")
+
+	reportCmd := foo.Rule("unused_deps").RuleParams.Command
+	android.AssertStringDoesContain(t, "report cmd", reportCmd, "'foo libs bar'")
+	android.AssertStringDoesContain(t, "report cmd", reportCmd, "'foo static_libs baz'")
+	android.AssertStringDoesContain(t, "report cmd", reportCmd,
+		"'foo libs bar "+barInfo.HeaderJars[0].String()+"'")
+	android.AssertBoolEquals(t, "unused libs check", false, foo.MaybeRule("unused_libs_check").Rule != nil)
+
+	singleton := result.SingletonForTests(t, "unused_deps_singleton")
+	report := singleton.Output("unused_deps/unused_deps.txt")
+	android.AssertPathsRelativeToTopEquals(t, "report inputs", []string{
+		"out/soong/.intermediates/bar/android_common/unused_deps/unused_deps.txt",
+		"out/soong/.intermediates/baz/android_common/unused_deps/unused_deps.txt",
+		"out/soong/.intermediates/foo/android_common/unused_deps/unused_deps.txt",
+	}, report.Inputs)
+	usedClasspath := singleton.Output("unused_deps/used_classpath.txt")
+	android.AssertPathsRelativeToTopEquals(t, "used classpath inputs", []string{
+		"out/soong/.intermediates/bar/android_common/unused_deps/used_classpath.txt",
+		"out/soong/.intermediates/baz/android_common/unused_deps/used_classpath.txt",
+		"out/soong/.intermediates/foo/android_common/unused_deps/used_classpath.txt",
+	}, usedClasspath.Inputs)
+}
+
+func TestEnforceUsedLibs(t *testing.T) {
+	t.Parallel()
+	ctx, _ := testJava(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			libs: ["bar", "annotations"],
+			enforce_used_libs: true,
+			enforce_used_libs_exemptions: ["annotations"],
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+			libs: ["baz"],
+		}
+
+		java_library {
+			name: "baz",
+			srcs: ["c.java"],
+		}
+
+		java_library {
+			name: "annotations",
+			srcs: ["d.java"],
+		}
+	`)
+
+	foo := ctx.ModuleForTests(t, "foo", "android_common")
+	foo.Output("unused_deps/libs/bar/0.keep")
+	foo.Output("unused_deps/libs/annotations/0.keep")
+	check := foo.Rule("unused_libs_check")
+	android.AssertStringDoesContain(t, "check cmd", check.RuleParams.Command,
+		"sed -n 's/^foo libs //p' out/soong/.intermediates/foo/android_common/unused_deps/unused_deps.txt"+
+			" | { grep -v -x -F -e annotations || true; }")
+	android.AssertPathsRelativeToTopEquals(t, "check inputs",
+		[]string{"out/soong/.intermediates/foo/android_common/unused_deps/unused_deps.txt"}, check.Implicits)
+
+	android.AssertPathsRelativeToTopEquals(t, "combined jar validations",
+		[]string{"out/soong/.intermediates/foo/android_common/unused_deps/unused_libs.stamp"},
+		foo.Output("combined/foo.jar").Validations)
+
+	// Modules that don't enforce used libs are not traced unless the report is enabled.
+	bar := ctx.ModuleForTests(t, "bar", "android_common")
+	android.AssertBoolEquals(t, "bar traced", false, bar.MaybeRule("unused_deps").Rule != nil)
+}
+
+func TestJavacCommands(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			libs: ["bar"],
+			plugins: ["plugin"],
+			javacflags: ["-Xlint:all"],
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+		}
+
+		java_plugin {
+			name: "plugin",
+			srcs: ["c.java"],
+			processor_class: "com.example.Processor",
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureMergeEnv(map[string]string{
+			"SOONG_GEN_JAVAC_COMMANDS": "true",
+		}),
+	).RunTestWithBp(t, bp)
+
+	foo := result.ModuleForTests(t, "foo", "android_common")
+	javac := foo.Output("javac/foo.jar")
+	content := android.ContentFromFileRuleForTests(t, result.TestContext,
+		foo.Output("javac_commands/javac_commands.jsonl"))
+
+	var command javacCommand
+	if err := json.Unmarshal([]byte(content), &command); err != nil {
+		t.Fatalf("failed to parse javac command %q: %s", content, err)
+	}
+	android.AssertStringEquals(t, "module", "foo", command.Module)
+	android.AssertStringEquals(t, "variant", "android_common", command.Variant)
+	android.AssertStringEquals(t, "output", javac.Output.String(), command.Output)
+	android.AssertDeepEquals(t, "sources", javac.Inputs.Strings(), command.Sources)
+	android.AssertDeepEquals(t, "processors", []string{"com.example.Processor"}, command.Processors)
+	android.AssertStringListContains(t, "flags", command.Flags, "-Xlint:all")
+	barInfo, _ := android.OtherModuleProvider(result, result.ModuleForTests(t, "bar", "android_common").Module(), JavaInfoProvider)
+	android.AssertStringListContains(t, "classpath", command.Classpath, barInfo.HeaderJars[0].String())
+	android.AssertStringEquals(t, "processorpath", javac.Args["processorpath"],
+		"-processorpath "+strings.Join(command.Processorpath, ":"))
+
+	commands := result.SingletonForTests(t, "javac_commands").Output("development/ide/javac/javac_commands.jsonl")
+	android.AssertPathsRelativeToTopEquals(t, "javac commands inputs", []string{
+		"out/soong/.intermediates/bar/android_common/javac_commands/javac_commands.jsonl",
+		"out/soong/.intermediates/foo/android_common/javac_commands/javac_commands.jsonl",
+		"out/soong/.intermediates/plugin/" + result.Config.BuildOSCommonTarget.String() + "/javac_commands/javac_commands.jsonl",
+	}, commands.Inputs)
+}
+
 // A minimal context object for use with DexJarBuildPath
 type moduleErrorfTestCtx struct {
 }
@@ -934,6 +1490,26 @@ func TestResources(t *testing.T) {
 			prop: `java_resource_dirs: ["java-res", "java-res2"], exclude_java_resource_dirs: ["java-res2"]`,
 			args: "-C java-res -f java-res/a/a -f java-res/b/b",
 		},
+		{
+			// Test java_resource_strip_prefix with java_resources
+			name: "resource files with strip prefix",
+			prop: `java_resources: ["java-res/a/a", "java-res/b/b"], java_resource_strip_prefix: "java-res"`,
+			args: "-C java-res -f java-res/a/a -f java-res/b/b",
+		},
+		{
+			// Test java_resource_strip_prefix with java_resources generated by a genrule
+			name: "generated resources with strip prefix",
+			prop: `java_resources: [":foo-gen"], java_resource_strip_prefix: "res/x"`,
+			extra: `
+				genrule {
+					name: "foo-gen",
+					cmd: "touch $(out)",
+					out: ["res/x/a", "res/x/b/b"],
+				}`,
+			args: "-C out/soong/.intermediates/foo-gen/gen/res/x " +
+				"-f out/soong/.intermediates/foo-gen/gen/res/x/a " +
+				"-f out/soong/.intermediates/foo-gen/gen/res/x/b/b",
+		},
 	}
 
 	for _, test := range table {
@@ -973,6 +1549,61 @@ func TestResources(t *testing.T) {
 	}
 }
 
+func TestResourceStripPrefixErrors(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name  string
+		prop  string
+		error string
+	}{
+		{
+			name:  "not under prefix",
+			prop:  `java_resources: ["java-res/a/a", "java-res2/a"], java_resource_strip_prefix: "java-res"`,
+			error: `java_resource_strip_prefix: java_resources file "java-res2/a" is not under "java-res"`,
+		},
+		{
+			name:  "prefix is a file",
+			prop:  `java_resources: ["java-res/a/a"], java_resource_strip_prefix: "java-res/a/a"`,
+			error: `java_resource_strip_prefix: java_resources file "java-res/a/a" is not under "java-res/a/a"`,
+		},
+		{
+			name:  "not relative",
+			prop:  `java_resources: ["java-res/a/a"], java_resource_strip_prefix: "../java-res"`,
+			error: `java_resource_strip_prefix: must be a clean relative path, got "../java-res"`,
+		},
+		{
+			name:  "not clean",
+			prop:  `java_resources: ["java-res/a/a"], java_resource_strip_prefix: "java-res/"`,
+			error: `java_resource_strip_prefix: must be a clean relative path, got "java-res/"`,
+		},
+		{
+			name:  "no java_resources",
+			prop:  `java_resource_dirs: ["java-res"], java_resource_strip_prefix: "java-res"`,
+			error: `java_resource_strip_prefix: is set but there are no java_resources`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			android.GroupFixturePreparers(
+				prepareForJavaTest,
+				android.MockFS{
+					"java-res/a/a": nil,
+					"java-res2/a":  nil,
+				}.AddToFixture(),
+			).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(regexp.QuoteMeta(test.error))).
+				RunTestWithBp(t, `
+					java_library {
+						name: "foo",
+						srcs: ["a.java"],
+						`+test.prop+`,
+					}
+				`)
+		})
+	}
+}
+
 func TestIncludeSrcs(t *testing.T) {
 	t.Parallel()
 	ctx, _ := testJavaWithFS(t, `
@@ -1041,6 +1672,61 @@ func TestIncludeSrcs(t *testing.T) {
 	}
 }
 
+func TestDistSrcjar(t *testing.T) {
+	t.Parallel()
+	ctx, _ := testJavaWithFS(t, `
+		java_library {
+			name: "foo",
+			srcs: [
+				"a.java",
+				"b.kt",
+				"c.srcjar",
+			],
+			dist_srcjar: true,
+			dists: [
+				{
+					targets: ["sdk"],
+					dest: "foo-impl.jar",
+					dir: "java",
+				},
+				{
+					targets: ["droidcore"],
+					tag: ".hjar",
+				},
+			],
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["a.java"],
+		}
+	`, map[string][]byte{
+		"b.kt":     nil,
+		"c.srcjar": nil,
+	})
+
+	foo := ctx.ModuleForTests(t, "foo", "android_common")
+	sourcesJar := foo.Output("sources/foo-sources.jar")
+	localSourcesJar := foo.Output("sources/local-sources.jar")
+	android.AssertPathsRelativeToTopEquals(t, "local sources jar inputs",
+		[]string{"a.java", "b.kt"}, localSourcesJar.Implicits)
+	android.AssertPathsRelativeToTopEquals(t, "sources jar inputs",
+		[]string{localSourcesJar.Output.RelativeToTop().String(), "c.srcjar"}, sourcesJar.Inputs)
+
+	android.AssertPathsRelativeToTopEquals(t, "-sources.jar output files",
+		[]string{sourcesJar.Output.RelativeToTop().String()}, foo.OutputFiles(ctx, t, "-sources.jar"))
+
+	// Only the dist of the class jar gets a sources jar.
+	android.AssertDeepEquals(t, "dists",
+		[]string{"sdk " + sourcesJar.Output.RelativeToTop().String() + ":java/foo-impl-sources.jar"},
+		foo.DistsForTests(ctx))
+
+	bar := ctx.ModuleForTests(t, "bar", "android_common")
+	if bar.MaybeOutput("sources/bar-sources.jar").Rule != nil {
+		t.Errorf("expected no sources jar for bar")
+	}
+}
+
 func TestGeneratedSources(t *testing.T) {
 	t.Parallel()
 	ctx, _ := testJavaWithFS(t, `
@@ -1120,6 +1806,47 @@ func TestTurbine(t *testing.T) {
 	android.AssertStringDoesContain(t, "baz javac classpath", bazJavac.Args["classpath"], "prebuilts/sdk/14/public/android.jar")
 }
 
+func TestTurbineJavacValidation(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+			libs: ["foo"],
+			javacflags: ["-Werror", "-Xlint:all"],
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureMergeEnv(map[string]string{
+			"TURBINE_JAVAC_VALIDATION": "true",
+		}),
+	).RunTestWithBp(t, bp)
+
+	bar := result.ModuleForTests(t, "bar", "android_common")
+	barTurbine := bar.Rule("turbine")
+	validation := bar.Rule("turbineJavacValidation")
+	android.AssertPathsRelativeToTopEquals(t, "bar turbine validations",
+		[]string{"out/soong/.intermediates/bar/android_common/turbine/bar.javac_validation"},
+		barTurbine.Validations)
+	android.AssertPathsRelativeToTopEquals(t, "bar javac validation inputs", []string{"b.java"}, validation.Inputs)
+	fooHeaderJar := filepath.Join("out", "soong", ".intermediates", "foo", "android_common", "turbine", "foo.jar")
+	android.AssertStringDoesContain(t, "bar javac validation classpath", validation.Args["classpath"], fooHeaderJar)
+	android.AssertStringDoesContain(t, "bar javac validation flags", validation.Args["javacFlags"], "-Xlint:all")
+	android.AssertStringDoesNotContain(t, "bar javac validation flags", validation.Args["javacFlags"], "-Werror")
+
+	// The validation is off by default.
+	result = prepareForJavaTest.RunTestWithBp(t, bp)
+	barTurbine = result.ModuleForTests(t, "bar", "android_common").Rule("turbine")
+	android.AssertIntEquals(t, "bar turbine validations without TURBINE_JAVAC_VALIDATION", 0, len(barTurbine.Validations))
+}
+
 func TestSharding(t *testing.T) {
 	t.Parallel()
 	ctx, _ := testJava(t, `
@@ -1166,6 +1893,47 @@ func TestExcludeFileGroupInSrcs(t *testing.T) {
 	}
 }
 
+func TestNegativeSrcsPatterns(t *testing.T) {
+	t.Parallel()
+	ctx, _ := testJavaWithFS(t, `
+		java_library {
+			name: "foo",
+			srcs: ["src/**/*.java", "!**/internal/**"],
+		}
+
+		java_library {
+			name: "bar",
+			srcs: [":bar-srcs", "!**/internal/**"],
+		}
+
+		java_library {
+			name: "baz",
+			srcs: [":bar-srcs"],
+		}
+
+		filegroup {
+			name: "bar-srcs",
+			srcs: ["java-fg/a.java", "java-fg/internal/b.java"],
+		}
+	`, map[string][]byte{
+		"src/a/A.java":            nil,
+		"src/a/internal/B.java":   nil,
+		"src/internal/C.java":     nil,
+		"java-fg/a.java":          nil,
+		"java-fg/internal/b.java": nil,
+	})
+
+	foo := ctx.ModuleForTests(t, "foo", "android_common").Rule("javac")
+	android.AssertPathsRelativeToTopEquals(t, "foo inputs", []string{"src/a/A.java"}, foo.Inputs)
+
+	bar := ctx.ModuleForTests(t, "bar", "android_common").Rule("javac")
+	android.AssertPathsRelativeToTopEquals(t, "bar inputs", []string{"java-fg/a.java"}, bar.Inputs)
+
+	baz := ctx.ModuleForTests(t, "baz", "android_common").Rule("javac")
+	android.AssertPathsRelativeToTopEquals(t, "baz inputs",
+		[]string{"java-fg/a.java", "java-fg/internal/b.java"}, baz.Inputs)
+}
+
 func TestJavaLibraryOutputFiles(t *testing.T) {
 	t.Parallel()
 	testJavaWithFS(t, "", map[string][]byte{
@@ -1549,6 +2317,28 @@ func TestAidlFlagsArePassedToTheAidlCompiler(t *testing.T) {
 	}
 }
 
+func TestAidlVersion(t *testing.T) {
+	t.Parallel()
+	ctx, _ := testJava(t, `
+		java_library {
+			name: "foo",
+			srcs: ["aidl/foo/IFoo.aidl"],
+			aidl: { version: "3" },
+		}
+	`)
+
+	aidlCommand := ctx.ModuleForTests(t, "foo", "android_common").Rule("aidl").RuleParams.Command
+	android.AssertStringDoesContain(t, "aidl command", aidlCommand, "--version=3")
+
+	testJavaError(t, `aidl.version: must be a positive integer, got "v3"`, `
+		java_library {
+			name: "foo",
+			srcs: ["aidl/foo/IFoo.aidl"],
+			aidl: { version: "v3" },
+		}
+	`)
+}
+
 func TestAidlFlagsWithMinSdkVersion(t *testing.T) {
 	t.Parallel()
 	fixture := android.GroupFixturePreparers(
@@ -1749,45 +2539,206 @@ func TestErrorproneDisabled(t *testing.T) {
 		t.Errorf("expected javacFlags to not contain %q, got %q", expectedSubstring, javac.Args["javacFlags"])
 	}
 
-	// Check that no errorprone build rule is created, like there would be
-	// if enabled was unset and RUN_ERROR_PRONE was true.
-	errorprone := ctx.ModuleForTests(t, "foo", "android_common").MaybeDescription("errorprone")
-	if errorprone.RuleParams.Description != "" {
-		t.Errorf("expected errorprone build rule to not exist, but it did")
+	// Check that no errorprone build rule is created, like there would be
+	// if enabled was unset and RUN_ERROR_PRONE was true.
+	errorprone := ctx.ModuleForTests(t, "foo", "android_common").MaybeDescription("errorprone")
+	if errorprone.RuleParams.Description != "" {
+		t.Errorf("expected errorprone build rule to not exist, but it did")
+	}
+}
+
+func TestErrorproneEnabledOnlyByEnvironmentVariable(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+		}
+	`
+	ctx := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureMergeEnv(map[string]string{
+			"RUN_ERROR_PRONE": "true",
+		}),
+	).RunTestWithBp(t, bp)
+
+	javac := ctx.ModuleForTests(t, "foo", "android_common").Description("javac")
+	errorprone := ctx.ModuleForTests(t, "foo", "android_common").Description("errorprone")
+
+	// Check that the errorprone plugins are not passed to javac, because they
+	// will instead be passed to the separate errorprone compilation
+	expectedSubstring := "-Xplugin:ErrorProne"
+	if strings.Contains(javac.Args["javacFlags"], expectedSubstring) {
+		t.Errorf("expected javacFlags to not contain %q, got %q", expectedSubstring, javac.Args["javacFlags"])
+	}
+
+	// Check that the errorprone plugin is enabled
+	if !strings.Contains(errorprone.Args["javacFlags"], expectedSubstring) {
+		t.Errorf("expected errorprone to contain %q, got %q", expectedSubstring, javac.Args["javacFlags"])
+	}
+}
+
+func TestErrorproneUsesTurbineHeaderJars(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			static_libs: ["bar"],
+			libs: ["baz"],
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+		}
+
+		java_library {
+			name: "baz",
+			srcs: ["c.java"],
+		}
+	`
+	ctx := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureMergeEnv(map[string]string{
+			"RUN_ERROR_PRONE": "true",
+		}),
+	).RunTestWithBp(t, bp)
+
+	foo := ctx.ModuleForTests(t, "foo", "android_common")
+	javacClasspath := foo.Description("javac").Args["classpath"]
+	errorproneClasspath := foo.Description("errorprone").Args["classpath"]
+
+	barHeaderJar := "bar/android_common/turbine/bar.jar"
+	if !strings.Contains(javacClasspath, barHeaderJar) {
+		t.Errorf("expected javac classpath to contain %q, got %q", barHeaderJar, javacClasspath)
+	}
+
+	// The errorprone compile uses the combined header jar of foo instead of the header jars of its
+	// static dependencies, but keeps its non-static dependencies.
+	fooCombinedHeaderJar := foo.Output("turbine-combined/foo.jar").Output.String()
+	if !strings.Contains(errorproneClasspath, fooCombinedHeaderJar) {
+		t.Errorf("expected errorprone classpath to contain %q, got %q", fooCombinedHeaderJar, errorproneClasspath)
+	}
+	if strings.Contains(errorproneClasspath, barHeaderJar) {
+		t.Errorf("expected errorprone classpath not to contain %q, got %q", barHeaderJar, errorproneClasspath)
+	}
+	if !strings.Contains(errorproneClasspath, "/baz/") {
+		t.Errorf("expected errorprone classpath to contain the header jar of baz, got %q", errorproneClasspath)
 	}
 }
 
-func TestErrorproneEnabledOnlyByEnvironmentVariable(t *testing.T) {
+func TestCheckDuplicateClasses(t *testing.T) {
 	t.Parallel()
 	bp := `
 		java_library {
 			name: "foo",
 			srcs: ["a.java"],
+			static_libs: ["bar"],
+			check_duplicate_classes: true,
+			allowed_duplicate_classes: ["com.foo.*"],
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+		}
+
+		java_library {
+			name: "baz",
+			srcs: ["c.java"],
+			static_libs: ["bar"],
 		}
 	`
-	ctx := android.GroupFixturePreparers(
+	result := PrepareForTestWithJavaDefaultModules.RunTestWithBp(t, bp)
+
+	foo := result.ModuleForTests(t, "foo", "android_common")
+	check := foo.Output("duplicate_classes/foo.jar.txt")
+	combined := foo.Output("combined/foo.jar")
+	android.AssertPathsRelativeToTopEquals(t, "checked jars",
+		android.SortedUniqueStrings(combined.Inputs.RelativeToTop().Strings()), check.Implicits)
+	android.AssertStringDoesContain(t, "check command", check.RuleParams.Command, "--allow com.foo.*")
+	android.AssertPathsRelativeToTopEquals(t, "combined jar validations",
+		[]string{"out/soong/.intermediates/foo/android_common/duplicate_classes/foo.jar.txt"}, combined.Validations)
+
+	baz := result.ModuleForTests(t, "baz", "android_common")
+	if baz.MaybeOutput("duplicate_classes/baz.jar.txt").Rule != nil {
+		t.Errorf("expected no duplicate classes check for baz")
+	}
+
+	// The environment variable enables the check for all modules.
+	result = android.GroupFixturePreparers(
 		PrepareForTestWithJavaDefaultModules,
 		android.FixtureMergeEnv(map[string]string{
-			"RUN_ERROR_PRONE": "true",
+			"CHECK_DUPLICATE_CLASSES": "true",
 		}),
 	).RunTestWithBp(t, bp)
+	result.ModuleForTests(t, "baz", "android_common").Output("duplicate_classes/baz.jar.txt")
+}
 
-	javac := ctx.ModuleForTests(t, "foo", "android_common").Description("javac")
-	errorprone := ctx.ModuleForTests(t, "foo", "android_common").Description("errorprone")
+func TestBannedLanguageFeatures(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		FixtureConfigureBootJars("platform:bar"),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.BootclasspathBannedLanguageFeatures = []string{"switch_patterns", "records"}
+		}),
+	).RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			banned_language_features: ["records", "sealed_classes"],
+		}
 
-	// Check that the errorprone plugins are not passed to javac, because they
-	// will instead be passed to the separate errorprone compilation
-	expectedSubstring := "-Xplugin:ErrorProne"
-	if strings.Contains(javac.Args["javacFlags"], expectedSubstring) {
-		t.Errorf("expected javacFlags to not contain %q, got %q", expectedSubstring, javac.Args["javacFlags"])
-	}
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+			banned_language_features: ["lambdas"],
+		}
 
-	// Check that the errorprone plugin is enabled
-	if !strings.Contains(errorprone.Args["javacFlags"], expectedSubstring) {
-		t.Errorf("expected errorprone to contain %q, got %q", expectedSubstring, javac.Args["javacFlags"])
+		java_library {
+			name: "baz",
+			srcs: ["c.java"],
+		}
+	`)
+
+	foo := result.ModuleForTests(t, "foo", "android_common")
+	check := foo.Output("language-features/foo.jar.txt")
+	android.AssertStringDoesContain(t, "foo check command", check.RuleParams.Command,
+		"--feature records --feature sealed_classes")
+	android.AssertPathsRelativeToTopEquals(t, "foo checked jars",
+		[]string{"out/soong/.intermediates/foo/android_common/javac/foo.jar"}, check.Implicits)
+	android.AssertPathsRelativeToTopEquals(t, "foo combined jar validations",
+		[]string{"out/soong/.intermediates/foo/android_common/language-features/foo.jar.txt"},
+		foo.Output("combined/foo.jar").Validations)
+
+	// Modules on the bootclasspath are also checked for the features banned by the product.
+	bar := result.ModuleForTests(t, "bar", "android_common")
+	android.AssertStringDoesContain(t, "bar check command",
+		bar.Output("language-features/bar.jar.txt").RuleParams.Command,
+		"--feature lambdas --feature records --feature switch_patterns")
+
+	baz := result.ModuleForTests(t, "baz", "android_common")
+	if baz.MaybeOutput("language-features/baz.jar.txt").Rule != nil {
+		t.Errorf("expected no banned language features check for baz")
 	}
 }
 
+func TestBannedLanguageFeaturesUnknown(t *testing.T) {
+	t.Parallel()
+	PrepareForTestWithJavaDefaultModules.
+		ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`banned_language_features: unknown language feature "generics"`)).
+		RunTestWithBp(t, `
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				banned_language_features: ["generics"],
+			}
+		`)
+}
+
 func TestDataDeviceBinsBuildsDeviceBinary(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -1985,6 +2936,128 @@ func TestDeviceBinaryWrapperGeneration(t *testing.T) {
 		}`)
 }
 
+func TestHostBinaryWrapperGeneration(t *testing.T) {
+	t.Parallel()
+	result := PrepareForTestWithJavaDefaultModules.RunTestWithBp(t, `
+		java_binary_host {
+			name: "foo",
+			srcs: ["foo.java"],
+			jvm_flags: ["-Xmx2g", "-Dfoo=bar baz"],
+			required_env: ["FOO_HOME"],
+			jdk_version: "21",
+		}
+
+		java_binary_host {
+			name: "bar",
+			srcs: ["bar.java"],
+		}
+	`)
+
+	buildOS := result.Config.BuildOS.String()
+	foo := result.ModuleForTests(t, "foo", buildOS+"_common")
+	content := android.ContentFromFileRuleForTests(t, result.TestContext, foo.Output("foo.sh"))
+	android.AssertStringDoesContain(t, "jvm flags", content, `declare -a javaOpts=(-Xmx2g '-Dfoo=bar baz')`)
+	android.AssertStringDoesContain(t, "required env", content, `if [ -z "${FOO_HOME+x}" ]; then`)
+	android.AssertStringDoesContain(t, "jdk version", content,
+		"/prebuilts/jdk/jdk21/"+result.Config.PrebuiltOS()+"/bin/java")
+	android.AssertStringDoesContain(t, "exec", content, `exec "${java}" "${javaOpts[@]}" -jar`)
+
+	bar := result.ModuleForTests(t, "bar", buildOS+"_common")
+	if bar.MaybeOutput("bar.sh").Rule != nil {
+		t.Errorf("expected bar to use the default wrapper")
+	}
+
+	android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+	).ExtendWithErrorHandler(android.FixtureExpectsAllErrorsToMatchAPattern([]string{
+		`required_env: "1FOO" is not a valid environment variable name`,
+		`jdk_version: "latest" is not a valid JDK version`,
+	})).RunTestWithBp(t, `
+		java_binary_host {
+			name: "foo",
+			srcs: ["foo.java"],
+			required_env: ["1FOO"],
+			jdk_version: "latest",
+		}
+	`)
+
+	android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`jvm_flags: is only supported for host binaries without a wrapper`,
+	)).RunTestWithBp(t, `
+		java_binary {
+			name: "foo",
+			srcs: ["foo.java"],
+			main_class: "foo.Main",
+			jvm_flags: ["-Xmx2g"],
+		}
+	`)
+}
+
+func TestWindowsBinaryWrapperGeneration(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureModifyConfig(func(config android.Config) {
+			config.Targets[android.Windows] = []android.Target{
+				{android.Windows, android.Arch{ArchType: android.X86_64}, android.NativeBridgeDisabled, "", "", true},
+			}
+		}),
+	).RunTestWithBp(t, `
+		java_binary_host {
+			name: "foo",
+			srcs: ["foo.java"],
+			jvm_flags: ["-Xmx2g", "-Dfoo=bar baz"],
+			required_env: ["FOO_HOME"],
+			target: {
+				windows: {
+					enabled: true,
+				},
+			},
+		}
+	`)
+
+	foo := result.ModuleForTests(t, "foo", "windows_common")
+	content := android.ContentFromFileRuleForTests(t, result.TestContext, foo.Output("foo.bat"))
+	android.AssertStringDoesContain(t, "jvm flags", content, "set javaOpts=-Xmx2g \"-Dfoo=bar baz\"\r\n")
+	android.AssertStringDoesContain(t, "required env", content, "if not defined FOO_HOME (")
+	android.AssertStringDoesContain(t, "run", content, `"%java%" %javaOpts% -jar "%jardir%%jarfile%" %args%`)
+
+	buildOS := result.Config.BuildOS.String()
+	if result.ModuleForTests(t, "foo", buildOS+"_common").MaybeOutput("foo.bat").Rule != nil {
+		t.Errorf("expected no Windows wrapper for the %s variant", buildOS)
+	}
+}
+
+func TestBinaryToolVersion(t *testing.T) {
+	t.Parallel()
+	result := PrepareForTestWithJavaDefaultModules.RunTestWithBp(t, `
+		java_binary_host {
+			name: "foo",
+			srcs: ["a.java"],
+			tool_version: "2.1",
+		}
+	`)
+
+	buildOS := result.Config.BuildOS.String()
+	foo := result.ModuleForTests(t, "foo", buildOS+"_common")
+	info, ok := android.OtherModuleProvider(result, foo.Module(), android.HostToolVersionInfoProvider)
+	android.AssertBoolEquals(t, "provides HostToolVersionInfo", true, ok)
+	android.AssertStringEquals(t, "tool version", "2.1", info.Version)
+
+	PrepareForTestWithJavaDefaultModules.
+		ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`tool_version: "2-beta" is not a valid host tool version`)).
+		RunTestWithBp(t, `
+			java_binary_host {
+				name: "foo",
+				srcs: ["a.java"],
+				tool_version: "2-beta",
+			}
+		`)
+}
+
 func TestJavaApiContributionEmptyApiFile(t *testing.T) {
 	t.Parallel()
 	android.GroupFixturePreparers(
@@ -2503,6 +3576,67 @@ java_test_host {
 	}
 }
 
+func TestTestShards(t *testing.T) {
+	t.Parallel()
+	result := PrepareForTestWithJavaBuildComponents.RunTestWithBp(t, `
+java_test_host {
+	name: "foo",
+	test_options: {
+		shards: 2,
+	}
+}
+`)
+
+	buildOS := result.Config.BuildOS.String()
+	foo := result.ModuleForTests(t, "foo", buildOS+"_common")
+	for i := 0; i < 2; i++ {
+		args := foo.Output(fmt.Sprintf("out/soong/.intermediates/foo/%s_common/shards/foo_shard%d.config", buildOS, i)).Args
+		android.AssertStringDoesContain(t, "shard config extraConfigs", args["extraConfigs"],
+			proptools.NinjaAndShellEscape("<option name=\"shard-count\" value=\"2\" />"))
+		android.AssertStringDoesContain(t, "shard config extraConfigs", args["extraConfigs"],
+			proptools.NinjaAndShellEscape(fmt.Sprintf("<option name=\"shard-index\" value=\"%d\" />", i)))
+	}
+
+	shardingInfo, ok := android.OtherModuleProvider(result, foo.Module(), tradefed.TestShardingInfoProvider)
+	if !ok {
+		t.Fatalf("expected TestShardingInfoProvider to be set")
+	}
+	android.AssertIntEquals(t, "shard count", 2, shardingInfo.ShardCount)
+	android.AssertPathsRelativeToTopEquals(t, "shard test configs", []string{
+		"out/soong/.intermediates/foo/" + buildOS + "_common/shards/foo_shard0.config",
+		"out/soong/.intermediates/foo/" + buildOS + "_common/shards/foo_shard1.config",
+	}, shardingInfo.ShardTestConfigs)
+
+	// The shard configs replace the config that would run every test.
+	if rule := foo.MaybeOutput("out/soong/.intermediates/foo/" + buildOS + "_common/foo.config").Rule; rule != nil {
+		t.Errorf("expected no unsharded test config, got rule %s", rule)
+	}
+	test := foo.Module().(*TestHost)
+	android.AssertPathRelativeToTopEquals(t, "test config",
+		"out/soong/.intermediates/foo/"+buildOS+"_common/shards/foo_shard0.config", test.testConfig)
+	android.AssertPathsRelativeToTopEquals(t, "extra test configs", []string{
+		"out/soong/.intermediates/foo/" + buildOS + "_common/shards/foo_shard1.config",
+	}, test.extraTestConfigs)
+}
+
+func TestTestShardsRequiresAutogenConfig(t *testing.T) {
+	t.Parallel()
+	android.GroupFixturePreparers(
+		PrepareForTestWithJavaBuildComponents,
+		android.FixtureAddFile("AndroidTest.xml", nil),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`sharding requires an autogenerated test config`,
+	)).RunTestWithBp(t, `
+java_test_host {
+	name: "foo",
+	test_config: "AndroidTest.xml",
+	test_options: {
+		shards: 2,
+	}
+}
+`)
+}
+
 func TestJavaLibraryWithResourcesStem(t *testing.T) {
 	t.Parallel()
 	ctx, _ := testJavaWithFS(t, `
@@ -3135,6 +4269,76 @@ func TestCoverage(t *testing.T) {
 	android.AssertStringListDoesNotContain(t, "foo combined inputs", fooCombine.Inputs.Strings(), androidCarJacoco.Output.String())
 }
 
+func TestCoverageKotlinSmap(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		PrepareForTestWithJacocoInstrumentation,
+	).RunTestWithBp(t, `
+		android_app {
+			name: "foo",
+			srcs: ["foo.kt"],
+			platform_apis: true,
+		}
+
+		android_app {
+			name: "bar",
+			srcs: ["bar.java"],
+			platform_apis: true,
+		}
+	`)
+
+	foo := result.ModuleForTests(t, "foo", "android_common")
+	fooJacoco := foo.Rule("jacoco")
+	fooSmap := foo.Rule("jacoco_kotlin_smap")
+	android.AssertPathsRelativeToTopEquals(t, "foo smap inputs",
+		[]string{android.PathRelativeToTop(fooJacoco.ImplicitOutput)}, fooSmap.Implicits)
+	android.AssertArrayString(t, "foo smap outputs", []string{
+		"out/soong/.intermediates/foo/android_common/jacoco-report-classes/foo-kotlin-class-ids.json",
+		"out/soong/.intermediates/foo/android_common/jacoco-report-classes/foo-kotlin-smap.json",
+		"out/soong/.intermediates/foo/android_common/jacoco-report-classes/kotlin/foo.jar",
+	}, android.SortedUniqueStrings(fooSmap.AllOutputs()))
+
+	fooInfo, _ := android.OtherModuleProvider(result.TestContext.OtherModuleProviderAdaptor(),
+		foo.Module(), JacocoCoverageMetadataInfoProvider)
+	android.AssertPathRelativeToTopEquals(t, "foo report classes jar",
+		android.PathRelativeToTop(fooJacoco.ImplicitOutput), fooInfo.ReportClassesJar)
+	android.AssertPathRelativeToTopEquals(t, "foo kotlin smap file",
+		"out/soong/.intermediates/foo/android_common/jacoco-report-classes/foo-kotlin-smap.json",
+		fooInfo.KotlinSmapFile)
+	android.AssertPathRelativeToTopEquals(t, "foo corrected report classes jar",
+		"out/soong/.intermediates/foo/android_common/jacoco-report-classes/kotlin/foo.jar",
+		fooInfo.CorrectedReportClassesJar)
+	android.AssertPathRelativeToTopEquals(t, "foo class ids file",
+		"out/soong/.intermediates/foo/android_common/jacoco-report-classes/foo-kotlin-class-ids.json",
+		fooInfo.ClassIdsFile)
+
+	fooMetadata := foo.Rule("jacoco_coverage_metadata")
+	android.AssertStringEquals(t, "foo coverage metadata command",
+		"echo out/soong/.intermediates/foo/android_common/jacoco-report-classes/foo.jar "+
+			"out/soong/.intermediates/foo/android_common/jacoco-report-classes/foo-kotlin-smap.json "+
+			"out/soong/.intermediates/foo/android_common/jacoco-report-classes/kotlin/foo.jar "+
+			"out/soong/.intermediates/foo/android_common/jacoco-report-classes/foo-kotlin-class-ids.json "+
+			"> out/soong/.intermediates/foo/android_common/jacoco/coverage-metadata.txt",
+		fooMetadata.RuleParams.Command)
+
+	bar := result.ModuleForTests(t, "bar", "android_common")
+	bar.Rule("jacoco")
+	if smap := bar.MaybeRule("jacoco_kotlin_smap"); smap.Rule != nil {
+		t.Errorf("expected no kotlin smap rule for java only module bar")
+	}
+	android.AssertStringEquals(t, "bar coverage metadata command",
+		"echo out/soong/.intermediates/bar/android_common/jacoco-report-classes/bar.jar "+
+			"> out/soong/.intermediates/bar/android_common/jacoco/coverage-metadata.txt",
+		bar.Rule("jacoco_coverage_metadata").RuleParams.Command)
+
+	list := result.SingletonForTests(t, "jacoco_coverage_metadata").Output("jacoco/coverage-metadata.txt")
+	android.AssertPathsRelativeToTopEquals(t, "coverage metadata list inputs", []string{
+		"out/soong/.intermediates/bar/android_common/jacoco/coverage-metadata.txt",
+		"out/soong/.intermediates/foo/android_common/jacoco/coverage-metadata.txt",
+	}, list.Inputs)
+}
+
 func assertTestOnlyAndTopLevel(t *testing.T, ctx *android.TestResult, expectedTestOnly []string, expectedTopLevel []string) {
 	t.Helper()
 	actualTrueModules := []string{}
@@ -3262,3 +4466,118 @@ func TestBootJarNotInUsesLibs(t *testing.T) {
 		"myothersdklibrary",
 	)
 }
+
+func TestProvidesUsesLibAttributes(t *testing.T) {
+	t.Parallel()
+	result := PrepareForTestWithJavaDefaultModules.RunTestWithBp(t, `
+		java_library {
+			name: "bar",
+			srcs: ["a.java"],
+			provides_uses_lib: "com.bar",
+			provides_uses_lib_version: 2,
+			provides_uses_lib_cert_digest: "01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF",
+		}
+
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			libs: ["bar"],
+		}
+	`)
+
+	foo := result.ModuleForTests(t, "foo", "android_common").Module().(*Library)
+	var bar *dexpreopt.ClassLoaderContext
+	for _, clc := range foo.ClassLoaderContexts()[dexpreopt.AnySdkVersion] {
+		if clc.Name == "com.bar" {
+			bar = clc
+		}
+	}
+	if bar == nil {
+		t.Fatalf("com.bar is missing from the class loader context of foo")
+	}
+	android.AssertDeepEquals(t, "attributes", dexpreopt.UsesLibraryAttributes{
+		Version:    2,
+		CertDigest: "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	}, bar.UsesLibraryAttributes)
+}
+
+func TestProvidesUsesLibAttributesValidation(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name string
+		bp   string
+	}{
+		{
+			name: "library",
+			bp: `
+				java_library {
+					name: "bar",
+					srcs: ["a.java"],
+					provides_uses_lib: "com.bar",
+					provides_uses_lib_version: -1,
+					provides_uses_lib_cert_digest: "0123",
+				}
+			`,
+		},
+		{
+			name: "app",
+			bp: `
+				android_app {
+					name: "bar",
+					srcs: ["a.java"],
+					sdk_version: "current",
+					provides_uses_lib: "com.bar",
+					provides_uses_lib_version: -1,
+					provides_uses_lib_cert_digest: "0123",
+				}
+			`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			PrepareForTestWithJavaDefaultModules.
+				ExtendWithErrorHandler(android.FixtureExpectsAllErrorsToMatchAPattern([]string{
+					`provides_uses_lib_version: must not be negative, got -1`,
+					`provides_uses_lib_cert_digest: must be a SHA-256 digest of 64 hex digits optionally separated by colons, got "0123"`,
+				})).
+				RunTestWithBp(t, tc.bp)
+		})
+	}
+}
+
+func TestPermittedPackagesCheckForApexBootJars(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		FixtureConfigureApexBootJars("myapex:foo"),
+	).RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			permitted_packages: ["com.foo", "com.foo.internal"],
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["a.java"],
+			permitted_packages: ["com.bar"],
+		}
+	`)
+
+	foo := result.ModuleForTests(t, "foo", "android_common")
+	check := foo.Rule("permittedPackagesCheck")
+	android.AssertStringEquals(t, "module", "foo", check.Args["module"])
+	android.AssertStringEquals(t, "packages", "com.foo com.foo.internal", check.Args["packages"])
+	android.AssertPathRelativeToTopEquals(t, "output", "out/soong/.intermediates/foo/android_common/package-check/permitted_packages.diff", check.Output)
+
+	cp := foo.Output("package-check/foo.jar")
+	android.AssertPathsRelativeToTopEquals(t, "validations", []string{
+		"out/soong/.intermediates/foo/android_common/package-check.stamp",
+		"out/soong/.intermediates/foo/android_common/package-check/permitted_packages.diff",
+	}, cp.Validations)
+
+	// Only updatable boot jars have to use all of their permitted packages.
+	bar := result.ModuleForTests(t, "bar", "android_common")
+	android.AssertBoolEquals(t, "bar has permitted packages check", false, bar.MaybeRule("permittedPackagesCheck").Rule != nil)
+}
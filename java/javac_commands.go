@@ -0,0 +1,133 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// When SOONG_GEN_JAVAC_COMMANDS=true, every java module that compiles sources with javac writes
+// the inputs and flags of its javac actions to javac_commands.jsonl, one JSON object per line and
+// per action, and javacCommandsReport collects them into
+// $OUT_DIR/soong/development/ide/javac/javac_commands.jsonl for IDEs and analysis tools, similar
+// to the compile_commands.json that is generated for cc modules.
+
+// javacCommand describes a javac action of a module.
+type javacCommand struct {
+	Module        string   `json:"module"`
+	Variant       string   `json:"variant"`
+	Output        string   `json:"output"`
+	Sources       []string `json:"sources"`
+	Srcjars       []string `json:"srcjars,omitempty"`
+	Bootclasspath []string `json:"bootclasspath,omitempty"`
+	SystemModules string   `json:"system_modules,omitempty"`
+	Classpath     []string `json:"classpath"`
+	Processorpath []string `json:"processorpath,omitempty"`
+	Processors    []string `json:"processors,omitempty"`
+	Flags         []string `json:"flags"`
+}
+
+type JavacCommandsInfo struct {
+	// The javac_commands.jsonl file of the module.
+	CommandsFile android.Path
+}
+
+var JavacCommandsInfoProvider = blueprint.NewProvider[JavacCommandsInfo]()
+
+// javacCommandsReport collects the javac actions of all java modules into a single file.
+var javacCommandsReport = &moduleReport[JavacCommandsInfo]{
+	env:      "SOONG_GEN_JAVAC_COMMANDS",
+	provider: JavacCommandsInfoProvider,
+	goal:     "javac_commands",
+	outputs: []moduleReportOutput[JavacCommandsInfo]{{
+		description: "javac commands",
+		path:        javacCommandsPath,
+		file:        func(info JavacCommandsInfo) android.Path { return info.CommandsFile },
+	}},
+}
+
+// recordJavacCommand records the javac action that compiles srcFiles and srcJars into output with
+// flags, to be written out by writeJavacCommands.
+func (j *Module) recordJavacCommand(ctx android.ModuleContext, output android.Path,
+	srcFiles, srcJars android.Paths, flags javaBuilderFlags) {
+
+	command := javacCommand{
+		Module:        ctx.ModuleName(),
+		Variant:       ctx.ModuleSubDir(),
+		Output:        output.String(),
+		Sources:       srcFiles.Strings(),
+		Srcjars:       srcJars.Strings(),
+		Classpath:     flags.classpath.Strings(),
+		Processorpath: flags.processorPath.Strings(),
+		Processors:    flags.processors,
+		Flags:         strings.Fields(flags.javacFlags),
+	}
+	if flags.javaVersion.usesJavaModules() {
+		if flags.systemModules != nil {
+			command.SystemModules = flags.systemModules.dir.String()
+		}
+		command.Classpath = append(flags.java9Classpath.Strings(), command.Classpath...)
+	} else {
+		command.Bootclasspath = flags.bootClasspath.Strings()
+	}
+	if command.Sources == nil {
+		command.Sources = []string{}
+	}
+	if command.Classpath == nil {
+		command.Classpath = []string{}
+	}
+	if command.Flags == nil {
+		command.Flags = []string{}
+	}
+	j.javacCommands = append(j.javacCommands, command)
+}
+
+// writeJavacCommands writes the javac actions recorded by recordJavacCommand to the
+// javac_commands.jsonl file of the module.
+func (j *Module) writeJavacCommands(ctx android.ModuleContext) {
+	if len(j.javacCommands) == 0 {
+		return
+	}
+
+	var lines []string
+	for _, command := range j.javacCommands {
+		line, err := json.Marshal(command)
+		if err != nil {
+			ctx.ModuleErrorf("failed to marshal javac command: %s", err)
+			return
+		}
+		lines = append(lines, string(line))
+	}
+
+	commandsFile := android.PathForModuleOut(ctx, "javac_commands", "javac_commands.jsonl")
+	android.WriteFileRule(ctx, commandsFile, strings.Join(lines, "\n"))
+
+	javacCommandsReport.setModuleFiles(ctx, JavacCommandsInfo{
+		CommandsFile: commandsFile,
+	})
+}
+
+func javacCommandsPath(ctx android.PathContext) android.WritablePath {
+	return android.PathForOutput(ctx, "development", "ide", "javac", "javac_commands.jsonl")
+}
+
+func javacCommandsSingletonFactory() android.Singleton {
+	return javacCommandsReport
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// The javac -Werror rollout is driven by two product variables: JavacWerrorEnforcedModules lists the
+// modules (or "*" for all modules) that are compiled with -Werror, and JavacWerrorExemptModules lists
+// modules that are excluded from enforcement, e.g. because they still have warnings to be fixed.
+// The status of every module that compiles java sources is written to javac_werror_status.csv so that
+// the progress of the migration can be tracked.
+
+const (
+	// The module is compiled with -Werror because of the enforcement list.
+	javacWerrorEnforced = "enforced"
+	// The module is in the enforcement list but has been exempted.
+	javacWerrorExempt = "exempt"
+	// The module already passes -Werror in its own javacflags.
+	javacWerrorOptedIn = "opted_in"
+	// The module is not in the enforcement list.
+	javacWerrorNotEnforced = "not_enforced"
+)
+
+type JavacWerrorInfo struct {
+	// One of "enforced", "exempt", "opted_in" or "not_enforced".
+	Status string
+}
+
+var JavacWerrorInfoProvider = blueprint.NewProvider[JavacWerrorInfo]()
+
+// javacWerrorStatus returns the -Werror enforcement status of the current module given the javac flags
+// it sets itself.
+func javacWerrorStatus(ctx android.ModuleContext, javacFlags []string) string {
+	if android.InList("-Werror", javacFlags) {
+		return javacWerrorOptedIn
+	}
+	name := ctx.ModuleName()
+	enforced := ctx.Config().JavacWerrorEnforcedModules()
+	if !android.InList("*", enforced) && !android.InList(name, enforced) {
+		return javacWerrorNotEnforced
+	}
+	if android.InList(name, ctx.Config().JavacWerrorExemptModules()) {
+		return javacWerrorExempt
+	}
+	return javacWerrorEnforced
+}
+
+func javacWerrorReportPath(ctx android.PathContext) android.WritablePath {
+	return android.PathForOutput(ctx, "javac_werror", "javac_werror_status.csv")
+}
+
+// javacWerrorSingleton writes the -Werror enforcement status of every java module to a CSV file.
+type javacWerrorSingleton struct{}
+
+func (s *javacWerrorSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	if len(ctx.Config().JavacWerrorEnforcedModules()) == 0 {
+		// The rollout has not started, there is nothing to track.
+		return
+	}
+
+	statuses := make(map[string]string)
+	ctx.VisitAllModuleProxies(func(module android.ModuleProxy) {
+		if !android.OtherModulePointerProviderOrDefault(ctx, module, android.CommonModuleInfoProvider).Enabled {
+			return
+		}
+		if info, ok := android.OtherModuleProvider(ctx, module, JavacWerrorInfoProvider); ok {
+			// Variants of the same module share the same name and status.
+			statuses[ctx.ModuleName(module)] = info.Status
+		}
+	})
+
+	lines := []string{"module,status"}
+	for _, name := range android.SortedKeys(statuses) {
+		lines = append(lines, name+","+statuses[name])
+	}
+
+	report := javacWerrorReportPath(ctx)
+	android.WriteFileRule(ctx, report, strings.Join(lines, "\n"))
+	ctx.DistForGoal("droidcore", report)
+}
+
+func javacWerrorSingletonFactory() android.Singleton {
+	return &javacWerrorSingleton{}
+}
@@ -15,6 +15,7 @@
 package java
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 
@@ -179,3 +180,56 @@ func TestDoNotAddNoneSystemModulesToDeps(t *testing.T) {
 	dpInfo, _ = android.OtherModuleProvider(ctx, javalib_stubs, android.IdeInfoProviderKey)
 	android.AssertStringListDoesNotContain(t, "IdeInfo.Deps should contain not contain `none`", dpInfo.Deps, "none")
 }
+
+func TestIdeMetadata(t *testing.T) {
+	t.Parallel()
+	ctx, _ := testJava(t,
+		`
+		java_library {
+			name: "Foo",
+			srcs: ["foo/Foo.java"],
+		}
+
+		java_library {
+			name: "javalib",
+			srcs: ["src/com/example/A.java", "src/com/example/B.kt", "src/com/example/c/C.java"],
+			libs: ["Foo"],
+			kotlin_lang_version: "2.1",
+			kotlin_api_version: "2.0",
+		}
+	`)
+
+	data, err := android.DumpIdeMetadata(ctx.Context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var metadata android.IdeMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		t.Fatal(err)
+	}
+	android.AssertIntEquals(t, "version", android.IdeMetadataVersion, metadata.Version)
+
+	javalib, ok := metadata.Modules["javalib"]
+	if !ok {
+		t.Fatalf("missing IDE metadata of javalib in %s", data)
+	}
+	android.AssertDeepEquals(t, "srcs",
+		[]string{"src/com/example/A.java", "src/com/example/B.kt", "src/com/example/c/C.java"}, javalib.Srcs)
+	android.AssertDeepEquals(t, "source dirs", []string{"src/com/example", "src/com/example/c"}, javalib.SourceDirs)
+	android.AssertStringListContains(t, "deps", javalib.Deps, "Foo")
+
+	foo := ctx.ModuleForTests(t, "Foo", "android_common").Module()
+	fooInfo, _ := android.OtherModuleProvider(ctx, foo, JavaInfoProvider)
+	android.AssertStringListContains(t, "classpath", javalib.Classpath, fooInfo.HeaderJars[0].String())
+
+	if javalib.Kotlin == nil {
+		t.Fatalf("missing Kotlin settings of javalib in %s", data)
+	}
+	android.AssertStringEquals(t, "kotlin language version", "2.1", javalib.Kotlin.LanguageVersion)
+	android.AssertStringEquals(t, "kotlin api version", "2.0", javalib.Kotlin.ApiVersion)
+	android.AssertStringListContains(t, "kotlin flags", javalib.Kotlin.Flags, "-api-version 2.0")
+
+	if fooMetadata := metadata.Modules["Foo"]; fooMetadata.Kotlin != nil {
+		t.Errorf("unexpected Kotlin settings of Foo: %+v", fooMetadata.Kotlin)
+	}
+}
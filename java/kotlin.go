@@ -19,11 +19,13 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"android/soong/android"
 
 	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
 )
 
 var kotlinc = pctx.AndroidRemoteStaticRule("kotlinc", android.RemoteRuleSupports{Goma: true},
@@ -102,6 +104,64 @@ func kotlinCommonSrcsList(ctx android.ModuleContext, commonSrcFiles android.Path
 	return android.OptionalPath{}
 }
 
+// The Kotlin language versions that modules can target, from oldest to newest.  Available kotlin
+// versions can be found at
+// https://github.com/JetBrains/kotlin/blob/master/compiler/util/src/org/jetbrains/kotlin/config/LanguageVersionSettings.kt#L560
+// in the `LanguageVersion` class.  "2" targets the default 2.x version of kotlinc without pinning
+// it.  Avoid adding more versions than necessary, as we'd like to keep our source code version
+// aligned as much as possible, but allow libraries to migrate to a newer version one at a time.
+var kotlinLangVersions = []string{"1.9", "2.0", "2.1", "2"}
+
+// The Kotlin API versions that modules can target, from oldest to newest.
+var kotlinApiVersions = []string{"1.9", "2.0", "2.1"}
+
+func kotlinVersionsList(versions []string) string {
+	quoted := make([]string, len(versions))
+	for i, version := range versions {
+		quoted[i] = "`" + version + "`"
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
+
+// kotlinVersions returns the Kotlin language and API versions targeted by the module, and the
+// kotlinc flags that select them.  The API version is empty if it defaults to the language
+// version.
+func (j *Module) kotlinVersions(ctx android.ModuleContext) (langVersion, apiVersion string, flags []string) {
+	defaultLangVersion := "1.9"
+	if buildFlagLangVersion, ok := ctx.Config().GetBuildFlag("RELEASE_KOTLIN_LANG_VERSION"); ok {
+		defaultLangVersion = buildFlagLangVersion
+	}
+	langVersion = proptools.StringDefault(j.properties.Kotlin_lang_version, defaultLangVersion)
+	langIndex := slices.Index(kotlinLangVersions, langVersion)
+	if langIndex < 0 {
+		ctx.PropertyErrorf("kotlin_lang_version", "Must be one of %s, got `%s`",
+			kotlinVersionsList(kotlinLangVersions), langVersion)
+		return langVersion, "", nil
+	}
+
+	if langVersion != "2" {
+		flags = append(flags, "-language-version "+langVersion)
+	}
+	if langVersion != "1.9" {
+		flags = append(flags, "-Xsuppress-version-warnings", "-Xconsistent-data-class-copy-visibility")
+	}
+
+	apiVersion = proptools.String(j.properties.Kotlin_api_version)
+	if apiVersion != "" {
+		if !slices.Contains(kotlinApiVersions, apiVersion) {
+			ctx.PropertyErrorf("kotlin_api_version", "Must be one of %s, got `%s`",
+				kotlinVersionsList(kotlinApiVersions), apiVersion)
+		} else if slices.Index(kotlinLangVersions, apiVersion) > langIndex {
+			ctx.PropertyErrorf("kotlin_api_version",
+				"API version `%s` must not be newer than the language version `%s`", apiVersion, langVersion)
+		} else {
+			flags = append(flags, "-api-version "+apiVersion)
+		}
+	}
+
+	return langVersion, apiVersion, flags
+}
+
 // kotlinCompile takes .java and .kt sources and srcJars, and compiles the .kt sources into a classes jar in outputFile.
 func (j *Module) kotlinCompile(ctx android.ModuleContext, outputFile, headerOutputFile android.WritablePath,
 	srcFiles, commonSrcFiles, srcJars android.Paths,
@@ -217,13 +277,13 @@ var kaptStubs = pctx.AndroidRemoteStaticRule("kaptStubs", android.RemoteRuleSupp
 	"classpath", "srcJars", "commonSrcFilesArg", "srcJarDir", "kaptDir", "kotlinJvmTarget",
 	"kotlinBuildFile", "name", "classesJarOut")
 
-// kotlinKapt performs Kotlin-compatible annotation processing.  It takes .kt and .java sources and srcjars, and runs
-// annotation processors over all of them, producing a srcjar of generated code in outputFile.  The srcjar should be
-// added as an additional input to kotlinc and javac rules, and the javac rule should have annotation processing
-// disabled.
-func kotlinKapt(ctx android.ModuleContext, srcJarOutputFile, resJarOutputFile android.WritablePath,
-	srcFiles, commonSrcFiles, srcJars android.Paths,
-	flags javaBuilderFlags) {
+// kotlinKaptStubs is the first step of Kotlin-compatible annotation processing.  It takes .kt and .java sources and
+// srcjars, and generates .java stubs of the .kt sources with kapt, returning a jar of the stubs.  Annotation
+// processors are then run over the stubs and the .java sources with turbine by TurbineApt, or with javac by
+// kaptJavacApt.  The srcjars of generated code should be added as additional inputs to kotlinc and javac rules, and
+// the javac rule should have annotation processing disabled.
+func kotlinKaptStubs(ctx android.ModuleContext, srcFiles, commonSrcFiles, srcJars android.Paths,
+	flags javaBuilderFlags) android.Path {
 
 	srcFiles = append(android.Paths(nil), srcFiles...)
 
@@ -283,14 +343,113 @@ func kotlinKapt(ctx android.ModuleContext, srcJarOutputFile, resJarOutputFile an
 			"kaptDir":           android.PathForModuleOut(ctx, "kapt/gen").String(),
 			"encodedJavacFlags": encodedJavacFlags,
 			"name":              kotlinName,
-			"classesJarOut":     resJarOutputFile.String(),
 		},
 	})
 
-	// Then run turbine to perform annotation processing on the stubs and any .java srcFiles.
-	javaSrcFiles := srcFiles.FilterByExt(".java")
-	turbineSrcJars := append(android.Paths{kaptStubsJar}, srcJars...)
-	TurbineApt(ctx, srcJarOutputFile, resJarOutputFile, javaSrcFiles, turbineSrcJars, flags)
+	return kaptStubsJar
+}
+
+// kaptJavacApt runs the annotation processors in flags, which are incompatible with turbine, with javac on the
+// kapt stubs in srcJars and the .java srcFiles, producing a srcjar of generated code in srcJarOutputFile and a jar
+// of generated resources in resJarOutputFile.
+func kaptJavacApt(ctx android.ModuleContext, srcJarOutputFile, resJarOutputFile android.WritablePath,
+	srcFiles, srcJars android.Paths, flags javaBuilderFlags) {
+
+	flags.javacFlags += " -proc:only"
+	transformJavaToClasses(ctx, resJarOutputFile, -1, srcFiles, srcJars, srcJarOutputFile, flags, nil,
+		"kapt_javac", "kapt javac")
+}
+
+var ksp = pctx.AndroidRemoteStaticRule("ksp", android.RemoteRuleSupports{Goma: true},
+	blueprint.RuleParams{
+		Command: `rm -rf "$srcJarDir" "$kspDir" && ` +
+			`mkdir -p "$srcJarDir" "$kspDir/kotlin" "$kspDir/java" "$kspDir/classes" "$kspDir/resources" "$kspDir/caches" && ` +
+			`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" -f "*.kt" $srcJars && ` +
+			`srcs=$$(cat $out.rsp $srcJarDir/list | tr -s ' \n' '::') && ` +
+			`$networkSandbox${config.JavaCmd} ${config.JavaVmFlags} -cp ${config.KspClasspath} ` +
+			`com.google.devtools.ksp.cmdline.KSPJvmMain ` +
+			`-module-name=$name -jvm-target=$kotlinJvmTarget $kspVersionFlags ` +
+			`-source-roots=$$srcs -java-source-roots=$$srcs -libraries=$$(cat $classpath) ` +
+			`-project-base-dir=$kspDir -output-base-dir=$kspDir -caches-dir=$kspDir/caches ` +
+			`-kotlin-output-dir=$kspDir/kotlin -java-output-dir=$kspDir/java ` +
+			`-class-output-dir=$kspDir/classes -resource-output-dir=$kspDir/resources ` +
+			`$kspProcessorPath && ` +
+			`${config.SoongZipCmd} -jar -write_if_changed -o $out ` +
+			`-C $kspDir/kotlin -D $kspDir/kotlin -C $kspDir/java -D $kspDir/java && ` +
+			`${config.SoongZipCmd} -jar -write_if_changed -o $resJar ` +
+			`-C $kspDir/resources -D $kspDir/resources -C $kspDir/classes -D $kspDir/classes && ` +
+			`rm -rf "$srcJarDir"`,
+		CommandDeps: []string{
+			"${config.JavaCmd}",
+			"${config.KspApiJar}",
+			"${config.KspCommonDepsJar}",
+			"${config.KspAaEmbeddableJar}",
+			"${config.KotlinStdlibJar}",
+			"${config.KotlinCoroutinesJar}",
+			"${config.SoongZipCmd}",
+			"${config.ZipSyncCmd}",
+		},
+		Rspfile:        "$out.rsp",
+		RspfileContent: `$in`,
+		Restat:         true,
+	},
+	"kspProcessorPath", "classpath", "srcJars", "srcJarDir", "kspDir", "kotlinJvmTarget", "kspVersionFlags", "name",
+	"resJar", "networkSandbox")
+
+// kotlinKsp runs the KSP processors in processorPath over .kt and .java sources and srcjars, producing a srcjar of
+// generated code in srcJarOutputFile and a jar of generated classes and resources in resJarOutputFile.  Unlike kapt,
+// KSP doesn't need stubs of the Kotlin sources, so it runs directly on them.  The srcjar should be added as an
+// additional input to the kapt, kotlinc and javac rules.  processors are the class names of the KSP processors, they
+// are only used in error messages and may be empty as KSP discovers the processors in processorPath.  langVersion and
+// apiVersion are the Kotlin versions targeted by the module, as returned by kotlinVersions, so that KSP analyzes the
+// sources the same way as kotlinc.
+func kotlinKsp(ctx android.ModuleContext, srcJarOutputFile, resJarOutputFile android.WritablePath,
+	srcFiles, srcJars android.Paths, processorPath classpath, processors []string, langVersion, apiVersion string,
+	flags javaBuilderFlags) {
+
+	var deps android.Paths
+	deps = append(deps, flags.kotlincClasspath...)
+	deps = append(deps, srcJars...)
+	deps = append(deps, processorPath...)
+
+	networkSandbox, sandboxDeps := networkSandboxArg(ctx, flags, processorPath, processors)
+	deps = append(deps, sandboxDeps...)
+
+	var versionFlags []string
+	if langVersion != "2" {
+		versionFlags = append(versionFlags, "-language-version="+langVersion)
+	}
+	if apiVersion != "" {
+		versionFlags = append(versionFlags, "-api-version="+apiVersion)
+	}
+
+	kotlinName := filepath.Join(ctx.ModuleDir(), ctx.ModuleSubDir(), ctx.ModuleName())
+	kotlinName = strings.ReplaceAll(kotlinName, "/", "__")
+
+	classpathRspFile := android.PathForModuleOut(ctx, "ksp", "classpath.rsp")
+	android.WriteFileRule(ctx, classpathRspFile, strings.Join(flags.kotlincClasspath.Strings(), ":"))
+	deps = append(deps, classpathRspFile)
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:           ksp,
+		Description:    "ksp",
+		Output:         srcJarOutputFile,
+		ImplicitOutput: resJarOutputFile,
+		Inputs:         srcFiles,
+		Implicits:      deps,
+		Args: map[string]string{
+			"classpath":        classpathRspFile.String(),
+			"kspProcessorPath": processorPath.FormJavaClassPath(""),
+			"srcJars":          strings.Join(srcJars.Strings(), " "),
+			"srcJarDir":        android.PathForModuleOut(ctx, "ksp", "srcJars").String(),
+			"kspDir":           android.PathForModuleOut(ctx, "ksp/gen").String(),
+			"kotlinJvmTarget":  flags.javaVersion.StringForKotlinc(),
+			"kspVersionFlags":  strings.Join(versionFlags, " "),
+			"name":             kotlinName,
+			"resJar":           resJarOutputFile.String(),
+			"networkSandbox":   networkSandbox,
+		},
+	})
 }
 
 // kapt converts a list of key, value pairs into a base64 encoded Java serialization, which is what kapt expects.
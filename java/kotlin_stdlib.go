@@ -0,0 +1,106 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"strings"
+
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+// Mixing different versions of the Kotlin standard library in the same app leads to hard to debug
+// runtime failures, as only one of the versions of each class is loaded. The modules that package
+// the Kotlin standard library set kotlin_stdlib_version, the versions are propagated through
+// static dependencies in JavaInfo.KotlinStdlibs, and apps report an error if more than one version
+// is statically linked into them, or a version other than expected_kotlin_stdlib_version.
+
+// KotlinStdlib is a version of the Kotlin standard library that is statically linked into a module.
+type KotlinStdlib struct {
+	// The version of the Kotlin standard library.
+	Version string
+
+	// The names of the modules in the chain of static dependencies from the module to the module
+	// that provides the Kotlin standard library.
+	Path []string
+}
+
+// collectKotlinStdlibs returns the versions of the Kotlin standard library that are statically
+// linked into the module, including the one it provides itself if version is set. Only the first
+// path to each module that provides the Kotlin standard library is kept.
+func collectKotlinStdlibs(ctx android.ModuleContext, version *string) []KotlinStdlib {
+	var stdlibs []KotlinStdlib
+	seen := make(map[string]bool)
+	add := func(stdlib KotlinStdlib) {
+		provider := stdlib.Path[len(stdlib.Path)-1]
+		if !seen[provider] {
+			seen[provider] = true
+			stdlibs = append(stdlibs, stdlib)
+		}
+	}
+
+	if v := proptools.String(version); v != "" {
+		add(KotlinStdlib{Version: v, Path: []string{ctx.ModuleName()}})
+	}
+	ctx.VisitDirectDepsProxyWithTag(staticLibTag, func(dep android.ModuleProxy) {
+		if info, ok := android.OtherModuleProvider(ctx, dep, JavaInfoProvider); ok {
+			for _, stdlib := range info.KotlinStdlibs {
+				add(KotlinStdlib{
+					Version: stdlib.Version,
+					Path:    append([]string{ctx.ModuleName()}, stdlib.Path...),
+				})
+			}
+		}
+	})
+	return stdlibs
+}
+
+// checkKotlinStdlibs reports an error if the module statically links more than one version of the
+// Kotlin standard library, or a version other than expected if it is set.
+func checkKotlinStdlibs(ctx android.ModuleContext, stdlibs []KotlinStdlib, expected *string) {
+	pathsByVersion := make(map[string][]string)
+	for _, stdlib := range stdlibs {
+		pathsByVersion[stdlib.Version] = append(pathsByVersion[stdlib.Version],
+			strings.Join(stdlib.Path, " -> "))
+	}
+
+	describe := func(versions []string) string {
+		var lines []string
+		for _, version := range versions {
+			for _, path := range pathsByVersion[version] {
+				lines = append(lines, "  "+version+": "+path)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	if v := proptools.String(expected); v != "" {
+		var unexpected []string
+		for _, version := range android.SortedKeys(pathsByVersion) {
+			if version != v {
+				unexpected = append(unexpected, version)
+			}
+		}
+		if len(unexpected) > 0 {
+			ctx.PropertyErrorf("expected_kotlin_stdlib_version",
+				"expected version %s of the Kotlin standard library, but found:\n%s",
+				v, describe(unexpected))
+		}
+	} else if len(pathsByVersion) > 1 {
+		ctx.ModuleErrorf("multiple versions of the Kotlin standard library are statically linked:\n%s",
+			describe(android.SortedKeys(pathsByVersion)))
+	}
+}
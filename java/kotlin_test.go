@@ -15,6 +15,8 @@
 package java
 
 import (
+	"fmt"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -361,6 +363,203 @@ func TestKapt(t *testing.T) {
 	})
 }
 
+func TestKsp(t *testing.T) {
+	t.Parallel()
+	ctx, _ := testJava(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java", "b.kt"],
+			plugins: ["bar", "baz"],
+			kotlin_lang_version: "2.1",
+			kotlin_api_version: "2.0",
+		}
+
+		java_library {
+			name: "qux",
+			srcs: ["a.java"],
+			plugins: ["bar"],
+		}
+
+		java_plugin {
+			name: "bar",
+			processor_class: "com.bar",
+			supports_ksp: true,
+			srcs: ["b.java"],
+		}
+
+		java_plugin {
+			name: "baz",
+			processor_class: "com.baz",
+			srcs: ["b.java"],
+		}
+	`)
+
+	buildOS := ctx.Config().BuildOS.String()
+	bar := ctx.ModuleForTests(t, "bar", buildOS+"_common").Rule("javac").Output.String()
+	baz := ctx.ModuleForTests(t, "baz", buildOS+"_common").Rule("javac").Output.String()
+
+	foo := ctx.ModuleForTests(t, "foo", "android_common")
+	ksp := foo.Rule("ksp")
+	kaptStubs := foo.Rule("kapt")
+	kotlinc := foo.Rule("kotlinc")
+	javac := foo.Rule("javac")
+
+	// Test that the kotlin and java sources are passed to ksp
+	android.AssertPathsRelativeToTopEquals(t, "ksp inputs", []string{"a.java", "b.kt"}, ksp.Inputs)
+
+	// Test that ksp targets the same kotlin versions as kotlinc
+	android.AssertStringEquals(t, "ksp version flags", "-language-version=2.1 -api-version=2.0",
+		ksp.Args["kspVersionFlags"])
+
+	// Test that the processors that support ksp are run with ksp, and the others with kapt
+	android.AssertStringEquals(t, "ksp processor path", bar, ksp.Args["kspProcessorPath"])
+	android.AssertStringEquals(t, "kapt processor path",
+		"-P plugin:org.jetbrains.kotlin.kapt3:apclasspath="+baz, kaptStubs.Args["kaptProcessorPath"])
+	android.AssertStringEquals(t, "kapt processors",
+		"-P plugin:org.jetbrains.kotlin.kapt3:processors=com.baz", kaptStubs.Args["kaptProcessor"])
+
+	// Test that the ksp srcjar is extracted by kapt, kotlinc and javac, so that kapt runs after ksp
+	kspSrcJar := ksp.Output.String()
+	android.AssertStringListContains(t, "kapt implicits", kaptStubs.Implicits.Strings(), kspSrcJar)
+	android.AssertStringDoesContain(t, "kapt srcjars", kaptStubs.Args["srcJars"], kspSrcJar)
+	android.AssertStringDoesContain(t, "kotlinc srcjars", kotlinc.Args["srcJars"], kspSrcJar)
+	android.AssertStringDoesContain(t, "javac srcjars", javac.Args["srcJars"], kspSrcJar)
+
+	// Test that the classes and resources generated by ksp are in the output jar
+	combined := foo.Output("combined/foo.jar")
+	android.AssertStringListContains(t, "combined inputs", combined.Inputs.Strings(), ksp.ImplicitOutput.String())
+
+	// Test that annotation processing is disabled in javac
+	android.AssertStringEquals(t, "javac processor", "-proc:none", javac.Args["processor"])
+
+	// Test that modules without kotlin sources run the processors that support ksp with javac
+	qux := ctx.ModuleForTests(t, "qux", "android_common")
+	if qux.MaybeRule("ksp").Rule != nil {
+		t.Errorf("expected no ksp rule for qux")
+	}
+	android.AssertStringDoesContain(t, "qux javac processorpath",
+		qux.Rule("javac").Args["processorpath"], bar)
+}
+
+func TestKspSharedProcessorJar(t *testing.T) {
+	t.Parallel()
+	ctx, _ := testJava(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java", "b.kt"],
+			plugins: ["bar_ksp", "bar_kapt"],
+		}
+
+		java_plugin {
+			name: "bar_ksp",
+			processor_class: "com.bar.ksp",
+			supports_ksp: true,
+			static_libs: ["bar_processors"],
+		}
+
+		java_plugin {
+			name: "bar_kapt",
+			processor_class: "com.bar.kapt",
+			static_libs: ["bar_processors"],
+		}
+
+		java_library_host {
+			name: "bar_processors",
+			srcs: ["b.java"],
+		}
+	`)
+
+	buildOS := ctx.Config().BuildOS.String()
+	barProcessors := ctx.ModuleForTests(t, "bar_processors", buildOS+"_common").Rule("javac").Output.String()
+
+	// Test that the jar shared by the plugins is passed to both ksp and kapt
+	foo := ctx.ModuleForTests(t, "foo", "android_common")
+	android.AssertStringEquals(t, "ksp processor path", barProcessors, foo.Rule("ksp").Args["kspProcessorPath"])
+	android.AssertStringEquals(t, "kapt processor path",
+		"-P plugin:org.jetbrains.kotlin.kapt3:apclasspath="+barProcessors, foo.Rule("kapt").Args["kaptProcessorPath"])
+	android.AssertStringEquals(t, "kapt processors",
+		"-P plugin:org.jetbrains.kotlin.kapt3:processors=com.bar.kapt", foo.Rule("kapt").Args["kaptProcessor"])
+}
+
+func TestKaptTurbineIncompatible(t *testing.T) {
+	t.Parallel()
+	ctx, _ := testJava(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java", "b.kt"],
+			plugins: ["bar", "baz"],
+		}
+
+		java_library {
+			name: "qux",
+			srcs: ["a.java", "b.kt"],
+			plugins: ["baz"],
+		}
+
+		java_plugin {
+			name: "bar",
+			processor_class: "com.bar",
+			srcs: ["b.java"],
+		}
+
+		java_plugin {
+			name: "baz",
+			processor_class: "com.baz",
+			turbine_incompatible: true,
+			srcs: ["b.java"],
+		}
+	`)
+
+	buildOS := ctx.Config().BuildOS.String()
+	bar := ctx.ModuleForTests(t, "bar", buildOS+"_common").Rule("javac").Output.String()
+	baz := ctx.ModuleForTests(t, "baz", buildOS+"_common").Rule("javac").Output.String()
+
+	foo := ctx.ModuleForTests(t, "foo", "android_common")
+	kaptStubs := foo.Rule("kapt")
+	turbineApt := foo.Description("turbine apt")
+	kaptJavac := foo.Description("kapt javac")
+	kotlinc := foo.Rule("kotlinc")
+	javac := foo.Output("javac/foo.jar")
+
+	// Test that the turbine incompatible processors are not passed to kapt and turbine-apt
+	android.AssertStringEquals(t, "kapt processor path",
+		"-P plugin:org.jetbrains.kotlin.kapt3:apclasspath="+bar, kaptStubs.Args["kaptProcessorPath"])
+	android.AssertStringEquals(t, "kapt processors",
+		"-P plugin:org.jetbrains.kotlin.kapt3:processors=com.bar", kaptStubs.Args["kaptProcessor"])
+	android.AssertStringDoesContain(t, "turbine-apt flags", turbineApt.Args["turbineFlags"],
+		"--processorpath "+bar+" --processors com.bar")
+	android.AssertStringDoesNotContain(t, "turbine-apt flags", turbineApt.Args["turbineFlags"], baz)
+
+	// Test that javac runs the turbine incompatible processors on the kapt stubs
+	android.AssertStringEquals(t, "kapt javac processorpath", "-processorpath "+baz, kaptJavac.Args["processorpath"])
+	android.AssertStringEquals(t, "kapt javac processor", "-processor com.baz", kaptJavac.Args["processor"])
+	android.AssertStringDoesContain(t, "kapt javac flags", kaptJavac.Args["javacFlags"], "-proc:only")
+	android.AssertStringDoesContain(t, "kapt javac srcjars", kaptJavac.Args["srcJars"], kaptStubs.Output.String())
+	android.AssertPathsRelativeToTopEquals(t, "kapt javac inputs", []string{"a.java"}, kaptJavac.Inputs)
+
+	// Test that the sources generated by kapt javac are compiled by kotlinc and javac, which doesn't run
+	// any annotation processors
+	kaptJavacSrcJar := kaptJavac.ImplicitOutput.String()
+	android.AssertStringDoesContain(t, "kotlinc srcjars", kotlinc.Args["srcJars"], kaptJavacSrcJar)
+	android.AssertStringDoesContain(t, "javac srcjars", javac.Args["srcJars"], kaptJavacSrcJar)
+	android.AssertStringEquals(t, "javac processor", "-proc:none", javac.Args["processor"])
+
+	// Test that the resources generated by kapt javac are in the output jar
+	combined := foo.Output("combined/foo.jar")
+	android.AssertStringListContains(t, "combined inputs", combined.Inputs.Strings(), kaptJavac.Output.String())
+
+	// Test that turbine-apt is not run when all the processors are incompatible with turbine, but the
+	// kapt stubs are still generated for javac
+	qux := ctx.ModuleForTests(t, "qux", "android_common")
+	if qux.MaybeDescription("turbine apt").Rule != nil {
+		t.Errorf("expected no turbine-apt rule for qux")
+	}
+	quxKaptJavac := qux.Description("kapt javac")
+	android.AssertStringEquals(t, "qux kapt javac processor", "-processor com.baz", quxKaptJavac.Args["processor"])
+	android.AssertStringDoesContain(t, "qux kapt javac srcjars", quxKaptJavac.Args["srcJars"],
+		qux.Rule("kapt").Output.String())
+}
+
 func TestKaptEncodeFlags(t *testing.T) {
 	t.Parallel()
 	// Compares the kaptEncodeFlags against the results of the example implementation at
@@ -474,6 +673,91 @@ func TestKotlinCompose(t *testing.T) {
 		noCompose.VariablesForTestsRelativeToTop()["kotlincFlags"], "-Xplugin="+composeCompiler.String())
 }
 
+func TestKotlinVersions(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name             string
+		properties       string
+		buildFlag        string
+		wantFlags        []string
+		dontExpectFlags  []string
+		expectedErrorRes []string
+	}{
+		{
+			name:            "default",
+			wantFlags:       []string{"-language-version 1.9"},
+			dontExpectFlags: []string{"-api-version", "-Xsuppress-version-warnings"},
+		},
+		{
+			name:            "build flag",
+			buildFlag:       "2",
+			wantFlags:       []string{"-Xsuppress-version-warnings"},
+			dontExpectFlags: []string{"-language-version", "-api-version"},
+		},
+		{
+			name:       "language and api version",
+			properties: `kotlin_lang_version: "2.1", kotlin_api_version: "2.0",`,
+			wantFlags:  []string{"-language-version 2.1", "-api-version 2.0", "-Xsuppress-version-warnings"},
+		},
+		{
+			name:       "api version with default language version",
+			properties: `kotlin_api_version: "1.9",`,
+			wantFlags:  []string{"-language-version 1.9", "-api-version 1.9"},
+		},
+		{
+			name:       "api version with build flag language version",
+			properties: `kotlin_api_version: "2.1",`,
+			buildFlag:  "2",
+			wantFlags:  []string{"-api-version 2.1"},
+		},
+		{
+			name:             "unsupported language version",
+			properties:       `kotlin_lang_version: "1.8",`,
+			expectedErrorRes: []string{"kotlin_lang_version: Must be one of `1.9`, `2.0`, `2.1` or `2`, got `1.8`"},
+		},
+		{
+			name:             "unsupported api version",
+			properties:       `kotlin_lang_version: "2", kotlin_api_version: "2",`,
+			expectedErrorRes: []string{"kotlin_api_version: Must be one of `1.9`, `2.0` or `2.1`, got `2`"},
+		},
+		{
+			name:             "api version newer than language version",
+			properties:       `kotlin_lang_version: "2.0", kotlin_api_version: "2.1",`,
+			expectedErrorRes: []string{"kotlin_api_version: API version `2.1` must not be newer than the language version `2.0`"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			preparers := []android.FixturePreparer{PrepareForTestWithJavaDefaultModules}
+			if tc.buildFlag != "" {
+				preparers = append(preparers, android.PrepareForTestWithBuildFlag("RELEASE_KOTLIN_LANG_VERSION", tc.buildFlag))
+			}
+			result := android.GroupFixturePreparers(preparers...).
+				ExtendWithErrorHandler(android.FixtureExpectsAllErrorsToMatchAPattern(tc.expectedErrorRes)).
+				RunTestWithBp(t, fmt.Sprintf(`
+					java_library {
+						name: "foo",
+						srcs: ["a.kt"],
+						%s
+					}
+				`, tc.properties))
+			if len(tc.expectedErrorRes) > 0 {
+				return
+			}
+
+			kotlincFlags := result.ModuleForTests(t, "foo", "android_common").VariablesForTestsRelativeToTop()["kotlincFlags"]
+			for _, flag := range tc.wantFlags {
+				android.AssertStringDoesContain(t, "missing kotlinc flag", kotlincFlags, flag)
+			}
+			for _, flag := range tc.dontExpectFlags {
+				android.AssertStringDoesNotContain(t, "unexpected kotlinc flag", kotlincFlags, flag)
+			}
+		})
+	}
+}
+
 func TestKotlinPlugin(t *testing.T) {
 	t.Parallel()
 	result := android.GroupFixturePreparers(
@@ -521,3 +805,78 @@ func TestKotlinPlugin(t *testing.T) {
 	android.AssertStringDoesNotContain(t, "unexpected kotlin plugin",
 		noKotlinPlugin.VariablesForTestsRelativeToTop()["kotlincFlags"], "-Xplugin="+kotlinPlugin.String())
 }
+
+func TestKotlinStdlibConflicts(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_library {
+			name: "kotlin-stdlib-1",
+			srcs: ["a.java"],
+			kotlin_stdlib_version: "1.9.0",
+		}
+
+		java_library {
+			name: "kotlin-stdlib-2",
+			srcs: ["a.java"],
+			kotlin_stdlib_version: "2.0.0",
+		}
+
+		java_library {
+			name: "libfoo",
+			srcs: ["a.java"],
+			static_libs: ["kotlin-stdlib-1"],
+		}
+
+		android_app {
+			name: "app",
+			srcs: ["a.java"],
+			sdk_version: "current",
+			static_libs: %s,
+			%s
+		}
+	`
+
+	testCases := []struct {
+		name          string
+		staticLibs    string
+		extraProps    string
+		expectedError string
+	}{
+		{
+			name:       "single version",
+			staticLibs: `["libfoo", "kotlin-stdlib-1"]`,
+		},
+		{
+			name:       "multiple versions",
+			staticLibs: `["libfoo", "kotlin-stdlib-2"]`,
+			expectedError: `multiple versions of the Kotlin standard library are statically linked:\n` +
+				`  1.9.0: app -> libfoo -> kotlin-stdlib-1\n` +
+				`  2.0.0: app -> kotlin-stdlib-2`,
+		},
+		{
+			name:       "expected version",
+			staticLibs: `["libfoo"]`,
+			extraProps: `expected_kotlin_stdlib_version: "1.9.0",`,
+		},
+		{
+			name:       "unexpected version",
+			staticLibs: `["libfoo"]`,
+			extraProps: `expected_kotlin_stdlib_version: "2.0.0",`,
+			expectedError: `expected_kotlin_stdlib_version: expected version 2.0.0 of the Kotlin standard library, ` +
+				`but found:\n  1.9.0: app -> libfoo -> kotlin-stdlib-1`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			errorHandler := android.FixtureExpectsNoErrors
+			if tc.expectedError != "" {
+				errorHandler = android.FixtureExpectsOneErrorPattern(regexp.QuoteMeta(tc.expectedError))
+			}
+			PrepareForTestWithJavaDefaultModules.
+				ExtendWithErrorHandler(errorHandler).
+				RunTestWithBp(t, fmt.Sprintf(bp, tc.staticLibs, tc.extraProps))
+		})
+	}
+}
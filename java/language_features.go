@@ -0,0 +1,80 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"android/soong/android"
+)
+
+// Some Java language features compile to class file constructs that are not supported everywhere
+// the classes may run, e.g. records need java.lang.Record and switch pattern matching needs
+// java.lang.runtime.SwitchBootstraps. Modules can ban features with banned_language_features, and
+// products can ban features for all the libraries on the bootclasspath with
+// BootclasspathBannedLanguageFeatures. A validation checks the class files produced by javac for
+// the banned features and fails the build if any are used.
+
+// languageFeatures are the features that the check_language_features tool can detect.
+var languageFeatures = []string{
+	"lambdas",
+	"records",
+	"sealed_classes",
+	"string_templates",
+	"switch_patterns",
+}
+
+// bannedLanguageFeatures returns the sorted language features that the module must not use.
+func (j *Module) bannedLanguageFeatures(ctx android.ModuleContext) []string {
+	features := j.properties.Banned_language_features
+	for _, feature := range features {
+		if !android.InList(feature, languageFeatures) {
+			ctx.PropertyErrorf("banned_language_features", "unknown language feature %q, must be one of %q",
+				feature, languageFeatures)
+		}
+	}
+
+	if productFeatures := ctx.Config().BootclasspathBannedLanguageFeatures(); len(productFeatures) > 0 && isConfiguredBootJar(ctx) {
+		for _, feature := range productFeatures {
+			if !android.InList(feature, languageFeatures) {
+				ctx.ModuleErrorf("unknown language feature %q in BootclasspathBannedLanguageFeatures, must be one of %q",
+					feature, languageFeatures)
+			}
+		}
+		features = append(android.CopyOf(features), productFeatures...)
+	}
+
+	return android.SortedUniqueStrings(features)
+}
+
+// checkBannedLanguageFeatures returns the validation that checks the classes compiled from the
+// sources of the module for banned language features, or nil if no features are banned.
+func (j *Module) checkBannedLanguageFeatures(ctx android.ModuleContext, classesJars android.Paths, jarName string) android.Path {
+	features := j.bannedLanguageFeatures(ctx)
+	if len(features) == 0 || len(classesJars) == 0 || ctx.Failed() {
+		return nil
+	}
+
+	report := android.PathForModuleOut(ctx, "language-features", jarName+".txt")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	cmd := rule.Command().
+		BuiltTool("check_language_features").
+		FlagWithOutput("--output ", report)
+	for _, feature := range features {
+		cmd.FlagWithArg("--feature ", feature)
+	}
+	cmd.Inputs(classesJars)
+	rule.Build("check_language_features", "check banned language features")
+
+	return report
+}
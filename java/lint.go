@@ -206,12 +206,52 @@ type LintInfo struct {
 	TransitiveText     depset.DepSet[android.Path]
 	TransitiveXML      depset.DepSet[android.Path]
 	TransitiveBaseline depset.DepSet[android.Path]
+
+	// The partial results of the module and its transitive static dependencies when lint is run
+	// in partial results mode, empty otherwise.
+	TransitivePartialResults depset.DepSet[LintPartialResults]
+}
+
+// LintPartialResults contains the results of running lint's analysis on a module without
+// reporting, which are merged into the report of the apps that statically link the module.
+type LintPartialResults struct {
+	// The name of the module.
+	Name string
+
+	// A zip file containing the partial results directory of the module.
+	Zip android.Path
+}
+
+// lintPartialResultsEnabled returns true if lint should analyze each module once and merge the
+// partial results of libraries into the reports of the apps that statically link them, instead
+// of only reporting the issues found in the sources of each module.
+func lintPartialResultsEnabled(config android.Config) bool {
+	return config.IsEnvTrue("ANDROID_LINT_PARTIAL_RESULTS")
 }
 
 func (l *linter) enabled() bool {
 	return BoolDefault(l.properties.Lint.Enabled, true)
 }
 
+// strictUpdatabilityLintable is implemented by modules whose lint can be made to enforce strict
+// updatability linting by a module that contains them.
+type strictUpdatabilityLintable interface {
+	setStrictUpdatabilityLinting() bool
+}
+
+var _ strictUpdatabilityLintable = (*linter)(nil)
+
+// setStrictUpdatabilityLinting makes the lint of the module verify that its baselines, and those of
+// its transitive dependencies, do not baseline updatability checks, as if it set
+// lint.strict_updatability_linting.  It returns false if the module sets it to false instead.
+func (l *linter) setStrictUpdatabilityLinting() bool {
+	if p := l.properties.Lint.Strict_updatability_linting; p != nil && !*p {
+		return false
+	}
+	l.properties.Lint.Strict_updatability_linting = proptools.BoolPtr(true)
+	return true
+}
+
 func (l *linter) deps(ctx android.BottomUpMutatorContext) {
 	if !l.enabled() {
 		return
@@ -235,6 +275,10 @@ type lintPaths struct {
 	cacheDir   android.WritablePath
 	homeDir    android.WritablePath
 	srcjarDir  android.WritablePath
+
+	// Only set in partial results mode.
+	reportProjectXML  android.WritablePath
+	partialResultsDir android.WritablePath
 }
 
 func lintRBEExecStrategy(ctx android.ModuleContext) string {
@@ -242,7 +286,7 @@ func lintRBEExecStrategy(ctx android.ModuleContext) string {
 }
 
 func (l *linter) writeLintProjectXML(ctx android.ModuleContext, rule *android.RuleBuilder, srcsList android.Path,
-	baselines android.Paths) lintPaths {
+	baselines android.Paths, partialResults bool, depPartialResults []LintPartialResults) lintPaths {
 
 	projectXMLPath := android.PathForModuleOut(ctx, "lint", "project.xml")
 	// Lint looks for a lint.xml file next to the project.xml file, give it one.
@@ -317,13 +361,32 @@ func (l *linter) writeLintProjectXML(ctx android.ModuleContext, rule *android.Ru
 		cmd.Validation(strictUpdatabilityChecksOutputFile)
 	}
 
-	return lintPaths{
+	paths := lintPaths{
 		projectXML: projectXMLPath,
 		configXML:  configXMLPath,
 		cacheDir:   cacheDir,
 		homeDir:    homeDir,
 	}
 
+	if partialResults {
+		// The project.xml used for the analysis only contains the module itself, the one used for
+		// reporting also contains the libraries whose partial results are merged into the report.
+		paths.reportProjectXML = android.PathForModuleOut(ctx, "lint", "report-project.xml")
+		paths.partialResultsDir = android.PathForModuleOut(ctx, "lint", "partial-results")
+		cmd.FlagWithOutput("--report_project_out ", paths.reportProjectXML)
+		cmd.FlagWithArg("--partial_results_dir ", cmd.PathForOutput(paths.partialResultsDir))
+		for _, dep := range depPartialResults {
+			cmd.FlagWithArg("--dependency ", dep.Name+":"+cmd.PathForOutput(lintDepPartialResultsDir(ctx, dep.Name)))
+		}
+	}
+
+	return paths
+}
+
+// lintDepPartialResultsDir returns the directory that the partial results of a static dependency
+// are extracted to before they are merged into the report of the module.
+func lintDepPartialResultsDir(ctx android.ModuleContext, name string) android.WritablePath {
+	return android.PathForModuleOut(ctx, "lint", "partial-results-deps", name)
 }
 
 func VerifyStrictUpdatabilityChecks(ctx android.ModuleContext, baselines android.Paths) android.Path {
@@ -422,14 +485,30 @@ func (l *linter) lint(ctx android.ModuleContext) {
 	referenceBaseline := android.PathForModuleOut(ctx, "lint", "lint-baseline.xml")
 
 	depSetsBuilder := NewLintDepSetBuilder().Direct(html, text, xml, baseline)
+	depPartialResultsBuilder := depset.NewBuilder[LintPartialResults](depset.POSTORDER)
 
 	ctx.VisitDirectDepsProxyWithTag(staticLibTag, func(dep android.ModuleProxy) {
 		if info, ok := android.OtherModuleProvider(ctx, dep, LintProvider); ok {
 			depSetsBuilder.Transitive(info)
+			depPartialResultsBuilder.Transitive(info.TransitivePartialResults)
 		}
 	})
 
 	depSets := depSetsBuilder.Build()
+	depPartialResultsSet := depPartialResultsBuilder.Build()
+
+	// In partial results mode every module only analyzes its own sources, libraries report the
+	// issues found in their own partial results and apps merge the partial results of all of their
+	// transitive static dependencies into their report.
+	partialResults := lintPartialResultsEnabled(ctx.Config())
+	var partialResultsZip android.WritablePath
+	var depPartialResults []LintPartialResults
+	if partialResults {
+		partialResultsZip = android.PathForModuleOut(ctx, "lint", "lint-partial-results.zip")
+		if !l.library {
+			depPartialResults = depPartialResultsSet.ToList()
+		}
+	}
 
 	rule := android.NewRuleBuilder(pctx, ctx).
 		Sbox(android.PathForModuleOut(ctx, "lint"),
@@ -459,7 +538,7 @@ func (l *linter) lint(ctx android.ModuleContext) {
 
 	baselines := depSets.Baseline.ToList()
 
-	lintPaths := l.writeLintProjectXML(ctx, rule, srcsList, baselines)
+	lintPaths := l.writeLintProjectXML(ctx, rule, srcsList, baselines, partialResults, depPartialResults)
 
 	rule.Command().Text("rm -rf").Flag(lintPaths.cacheDir.String()).Flag(lintPaths.homeDir.String())
 	rule.Command().Text("mkdir -p").Flag(lintPaths.cacheDir.String()).Flag(lintPaths.homeDir.String())
@@ -478,29 +557,63 @@ func (l *linter) lint(ctx android.ModuleContext) {
 		apiVersionsXMLPath = copiedLintDatabaseFilesPath(ctx, files.apiVersionsCopiedName)
 	}
 
-	cmd := rule.Command()
+	lintCmd := func(projectXML android.Path) *android.RuleBuilderCommand {
+		cmd := rule.Command()
+
+		cmd.Flag(`JAVA_OPTS="-Xmx4096m --add-opens java.base/java.util=ALL-UNNAMED"`).
+			FlagWithArg("ANDROID_SDK_HOME=", lintPaths.homeDir.String()).
+			FlagWithInput("SDK_ANNOTATIONS=", annotationsZipPath).
+			FlagWithInput("LINT_OPTS=-DLINT_API_DATABASE=", apiVersionsXMLPath)
+
+		cmd.BuiltTool("lint").ImplicitTool(ctx.Config().HostJavaToolPath(ctx, "lint.jar")).
+			Flag("--quiet").
+			Flag("--include-aosp-issues").
+			FlagWithInput("--project ", projectXML).
+			FlagWithInput("--config ", lintPaths.configXML).
+			FlagWithArg("--compile-sdk-version ", l.compileSdkVersion.String()).
+			FlagWithArg("--java-language-level ", l.javaLanguageLevel).
+			FlagWithArg("--kotlin-language-level ", l.kotlinLanguageLevel).
+			FlagWithArg("--url ", fmt.Sprintf(".=.,%s=out", android.PathForOutput(ctx).String())).
+			Flags(l.properties.Lint.Flags).
+			Implicit(annotationsZipPath).
+			Implicit(apiVersionsXMLPath)
+
+		if checkOnly := ctx.Config().Getenv("ANDROID_LINT_CHECK"); checkOnly != "" {
+			cmd.FlagWithArg("--check ", checkOnly)
+		}
+
+		return cmd
+	}
+
+	reportProjectXML := lintPaths.projectXML
+	if partialResults {
+		lintCmd(lintPaths.projectXML).Flag("--analyze-only")
 
-	cmd.Flag(`JAVA_OPTS="-Xmx4096m --add-opens java.base/java.util=ALL-UNNAMED"`).
-		FlagWithArg("ANDROID_SDK_HOME=", lintPaths.homeDir.String()).
-		FlagWithInput("SDK_ANNOTATIONS=", annotationsZipPath).
-		FlagWithInput("LINT_OPTS=-DLINT_API_DATABASE=", apiVersionsXMLPath)
+		zipCmd := rule.Command()
+		partialResultsDir := zipCmd.PathForOutput(lintPaths.partialResultsDir)
+		zipCmd.BuiltTool("soong_zip").
+			FlagWithOutput("-o ", partialResultsZip).
+			FlagWithArg("-C ", partialResultsDir).
+			FlagWithArg("-D ", partialResultsDir)
 
-	cmd.BuiltTool("lint").ImplicitTool(ctx.Config().HostJavaToolPath(ctx, "lint.jar")).
-		Flag("--quiet").
-		Flag("--include-aosp-issues").
-		FlagWithInput("--project ", lintPaths.projectXML).
-		FlagWithInput("--config ", lintPaths.configXML).
-		FlagWithOutput("--html ", html).
+		for _, dep := range depPartialResults {
+			unzipCmd := rule.Command()
+			unzipCmd.Text("unzip -qo").Input(dep.Zip).
+				FlagWithArg("-d ", unzipCmd.PathForOutput(lintDepPartialResultsDir(ctx, dep.Name)))
+		}
+
+		reportProjectXML = lintPaths.reportProjectXML
+		rule.Temporary(reportProjectXML)
+	}
+
+	cmd := lintCmd(reportProjectXML)
+	if partialResults {
+		cmd.Flag("--report-only")
+	}
+	cmd.FlagWithOutput("--html ", html).
 		FlagWithOutput("--text ", text).
 		FlagWithOutput("--xml ", xml).
-		FlagWithArg("--compile-sdk-version ", l.compileSdkVersion.String()).
-		FlagWithArg("--java-language-level ", l.javaLanguageLevel).
-		FlagWithArg("--kotlin-language-level ", l.kotlinLanguageLevel).
-		FlagWithArg("--url ", fmt.Sprintf(".=.,%s=out", android.PathForOutput(ctx).String())).
-		Flag("--apply-suggestions"). // applies suggested fixes to files in the sandbox
-		Flags(l.properties.Lint.Flags).
-		Implicit(annotationsZipPath).
-		Implicit(apiVersionsXMLPath)
+		Flag("--apply-suggestions") // applies suggested fixes to files in the sandbox
 
 	rule.Temporary(lintPaths.projectXML)
 	rule.Temporary(lintPaths.configXML)
@@ -510,10 +623,6 @@ func (l *linter) lint(ctx android.ModuleContext) {
 		cmd.Flag("--exitcode")
 	}
 
-	if checkOnly := ctx.Config().Getenv("ANDROID_LINT_CHECK"); checkOnly != "" {
-		cmd.FlagWithArg("--check ", checkOnly)
-	}
-
 	if baseline.Valid() {
 		cmd.FlagWithInput("--baseline ", baseline.Path())
 	}
@@ -539,6 +648,11 @@ func (l *linter) lint(ctx android.ModuleContext) {
 
 	rule.Build("lint", "lint")
 
+	var partialResultsDirect []LintPartialResults
+	if partialResults {
+		partialResultsDirect = []LintPartialResults{{Name: ctx.ModuleName(), Zip: partialResultsZip}}
+	}
+
 	android.SetProvider(ctx, LintProvider, &LintInfo{
 		HTML:              html,
 		Text:              text,
@@ -549,6 +663,9 @@ func (l *linter) lint(ctx android.ModuleContext) {
 		TransitiveText:     depSets.Text,
 		TransitiveXML:      depSets.XML,
 		TransitiveBaseline: depSets.Baseline,
+
+		TransitivePartialResults: depset.New(depset.POSTORDER, partialResultsDirect,
+			[]depset.DepSet[LintPartialResults]{depPartialResultsSet}),
 	})
 
 	if l.buildModuleReportZip {
@@ -615,6 +732,16 @@ func (l *lintSingleton) copyLintDependencies(ctx android.SingletonContext) {
 		return
 	}
 
+	// The SDK extension api levels are merged into the api versions of the sdk kinds that are
+	// listed in RELEASE_LINT_SDK_EXTENSIONS_API_VERSIONS, so that calls to APIs that are guarded by
+	// an SDK extension version check are not reported as NewApi.
+	sdkExtensionsApiVersions := ctx.Config().ReleaseLintSdkExtensionsApiVersions()
+	for _, sdk := range android.SortedKeys(sdkExtensionsApiVersions) {
+		if _, ok := allLintDatabasefiles[android.ToSdkKind(sdk)]; !ok {
+			ctx.Errorf("lint: RELEASE_LINT_SDK_EXTENSIONS_API_VERSIONS: unknown sdk kind %q", sdk)
+		}
+	}
+
 	for _, sdk := range android.SortedKeys(allLintDatabasefiles) {
 		files := allLintDatabasefiles[sdk]
 		apiVersionsDb := findModuleOrErr(ctx, files.apiVersionsModule)
@@ -639,14 +766,46 @@ func (l *lintSingleton) copyLintDependencies(ctx android.SingletonContext) {
 			Output: copiedLintDatabaseFilesPath(ctx, files.annotationCopiedName),
 		})
 
+		apiVersions := android.OutputFileForModule(ctx, *apiVersionsDb, ".api_versions.xml")
+		if module, ok := sdkExtensionsApiVersions[sdk.String()]; ok {
+			apiVersions = mergeSdkExtensionsApiVersions(ctx, files, apiVersions, module)
+		}
+
 		ctx.Build(pctx, android.BuildParams{
 			Rule:   android.CpIfChanged,
-			Input:  android.OutputFileForModule(ctx, *apiVersionsDb, ".api_versions.xml"),
+			Input:  apiVersions,
 			Output: copiedLintDatabaseFilesPath(ctx, files.apiVersionsCopiedName),
 		})
 	}
 }
 
+// mergeSdkExtensionsApiVersions returns the api versions with the SDK extension api levels generated
+// by the given module merged into them. If the module is missing the api versions are returned as
+// they are.
+func mergeSdkExtensionsApiVersions(ctx android.SingletonContext, files lintDatabaseFiles,
+	apiVersions android.Path, module string) android.Path {
+
+	sdkExtensionsApiVersionsDb := findModuleOrErr(ctx, module)
+	if sdkExtensionsApiVersionsDb == nil {
+		if !ctx.Config().AllowMissingDependencies() {
+			ctx.Errorf("lint: missing module %s", module)
+		}
+		return apiVersions
+	}
+
+	merged := android.PathForOutput(ctx, "lint", "sdk_extensions", files.apiVersionsCopiedName)
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		BuiltTool("merge_api_versions").
+		FlagWithInput("--extensions ",
+			android.OutputFileForModule(ctx, *sdkExtensionsApiVersionsDb, ".api_versions.xml")).
+		FlagWithOutput("--output ", merged).
+		Input(apiVersions)
+	rule.Build("merge_api_versions_"+files.apiVersionsModule,
+		"merge sdk extensions api levels into "+files.apiVersionsCopiedName)
+	return merged
+}
+
 func copiedLintDatabaseFilesPath(ctx android.PathContext, name string) android.WritablePath {
 	return android.PathForOutput(ctx, "lint", name)
 }
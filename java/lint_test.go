@@ -329,3 +329,124 @@ func TestNotTestViaDefault(t *testing.T) {
 		t.Fatalf("Expected command to contain --test")
 	}
 }
+
+func TestJavaLintPartialResults(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_library {
+			name: "bar",
+			srcs: ["a.java"],
+			sdk_version: "current",
+		}
+		android_library {
+			name: "foo",
+			srcs: ["a.java"],
+			static_libs: ["bar"],
+			sdk_version: "current",
+		}
+		android_app {
+			name: "app",
+			srcs: ["a.java"],
+			static_libs: ["foo"],
+			sdk_version: "current",
+		}
+	`
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureMergeEnv(map[string]string{
+			"ANDROID_LINT_PARTIAL_RESULTS": "true",
+		}),
+	).RunTestWithBp(t, bp)
+	ctx := result.TestContext
+
+	foo := ctx.ModuleForTests(t, "foo", "android_common")
+	fooCommand := *android.RuleBuilderSboxProtoForTests(t, ctx, foo.Output("lint.sbox.textproto")).Commands[0].Command
+	android.AssertStringDoesContain(t, "foo analyzes its sources", fooCommand, "--analyze-only")
+	android.AssertStringDoesContain(t, "foo reports its partial results", fooCommand, "--report-only")
+	android.AssertStringDoesNotContain(t, "foo doesn't merge the partial results of bar", fooCommand, "--dependency")
+
+	app := ctx.ModuleForTests(t, "app", "android_common")
+	appRule := app.Output("lint.sbox.textproto")
+	appCommand := *android.RuleBuilderSboxProtoForTests(t, ctx, appRule).Commands[0].Command
+	android.AssertStringDoesContain(t, "app merges the partial results of foo", appCommand,
+		"--dependency foo:__SBOX_SANDBOX_DIR__/out/partial-results-deps/foo")
+	android.AssertStringDoesContain(t, "app merges the partial results of bar", appCommand,
+		"--dependency bar:__SBOX_SANDBOX_DIR__/out/partial-results-deps/bar")
+	android.AssertStringListContains(t, "app lint inputs", android.PathsRelativeToTop(appRule.Implicits),
+		"out/soong/.intermediates/foo/android_common/lint/lint-partial-results.zip")
+	android.AssertStringListContains(t, "app lint inputs", android.PathsRelativeToTop(appRule.Implicits),
+		"out/soong/.intermediates/bar/android_common/lint/lint-partial-results.zip")
+}
+
+func TestJavaLintWithoutPartialResults(t *testing.T) {
+	t.Parallel()
+	ctx, _ := testJava(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			sdk_version: "current",
+		}
+	`)
+
+	foo := ctx.ModuleForTests(t, "foo", "android_common")
+	command := *android.RuleBuilderSboxProtoForTests(t, ctx, foo.Output("lint.sbox.textproto")).Commands[0].Command
+	android.AssertStringDoesNotContain(t, "foo runs a full analysis", command, "--analyze-only")
+	android.AssertStringDoesNotContain(t, "foo runs a full analysis", command, "--report-only")
+}
+
+func TestLintSdkExtensionsApiVersions(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		// Only the databases of the public sdk are defined.
+		android.PrepareForTestWithAllowMissingDependencies,
+		android.FixtureRegisterWithContext(func(ctx android.RegistrationContext) {
+			ctx.RegisterParallelSingletonType("lint", func() android.Singleton { return &lintSingleton{} })
+		}),
+		android.PrepareForTestWithBuildFlag("RELEASE_LINT_SDK_EXTENSIONS_API_VERSIONS",
+			"public:api_versions_public_sdk_extensions"),
+		android.FixtureAddFile("a.java", nil),
+		android.FixtureAddFile("annotations.zip", nil),
+	).RunTestWithBp(t, `
+		droiddoc_exported_dir {
+			name: "droiddoc-templates-sdk",
+			path: ".",
+		}
+
+		droidstubs {
+			name: "api_versions_public",
+			srcs: ["a.java"],
+			api_levels_annotations_dirs: ["droiddoc-templates-sdk"],
+			api_levels_annotations_enabled: true,
+		}
+
+		droidstubs {
+			name: "api_versions_public_sdk_extensions",
+			srcs: ["a.java"],
+			api_levels_annotations_dirs: ["droiddoc-templates-sdk"],
+			api_levels_annotations_enabled: true,
+		}
+
+		filegroup {
+			name: "sdk-annotations.zip",
+			srcs: ["annotations.zip"],
+		}
+	`)
+
+	apiVersions := result.ModuleForTests(t, "api_versions_public", "android_common").
+		OutputFiles(result.TestContext, t, ".api_versions.xml")
+	sdkExtensionsApiVersions := result.ModuleForTests(t, "api_versions_public_sdk_extensions", "android_common").
+		OutputFiles(result.TestContext, t, ".api_versions.xml")
+
+	lint := result.SingletonForTests(t, "lint")
+	merge := lint.Output("lint/sdk_extensions/api_versions_public.xml")
+	android.AssertStringDoesContain(t, "merge command", merge.RuleParams.Command,
+		"merge_api_versions --extensions "+sdkExtensionsApiVersions[0].String())
+	android.AssertPathsRelativeToTopEquals(t, "merge inputs",
+		append(apiVersions.Strings(), sdkExtensionsApiVersions.Strings()...), merge.Implicits)
+
+	// The merged api versions are copied for lint.
+	android.AssertPathRelativeToTopEquals(t, "copied api versions",
+		"out/soong/lint/sdk_extensions/api_versions_public.xml",
+		lint.Output("lint/api_versions_public.xml").Input)
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// moduleReport is a tree-wide report that is enabled by an environment variable. When it is
+// enabled every module that the report applies to writes its own files of the report and exposes
+// them through provider, and the moduleReport, which is also the singleton of the report,
+// concatenates the files of all modules into the outputs of the report.
+type moduleReport[T any] struct {
+	// The environment variable that enables the report.
+	env string
+
+	// The provider that the modules expose their files of the report through.
+	provider blueprint.ProviderKey[T]
+
+	// The phony goal that builds the outputs of the report.
+	goal string
+
+	// Whether the outputs of the report are dist'ed with goal.
+	dist bool
+
+	outputs []moduleReportOutput[T]
+}
+
+// moduleReportOutput is an output of a moduleReport, concatenated from one of the files in the
+// provider of every module.
+type moduleReportOutput[T any] struct {
+	description string
+
+	// The path of the output.
+	path func(ctx android.PathContext) android.WritablePath
+
+	// The file of a module that is concatenated into the output, or nil if the module has none.
+	file func(info T) android.Path
+}
+
+func (r *moduleReport[T]) enabled(ctx android.ConfigContext) bool {
+	return ctx.Config().IsEnvTrue(r.env)
+}
+
+// setModuleFiles exposes the files of the report of the current module to the singleton.
+func (r *moduleReport[T]) setModuleFiles(ctx android.ModuleContext, info T) {
+	android.SetProvider(ctx, r.provider, info)
+}
+
+func (r *moduleReport[T]) GenerateBuildActions(ctx android.SingletonContext) {
+	if !r.enabled(ctx) {
+		return
+	}
+
+	files := make([]android.Paths, len(r.outputs))
+	ctx.VisitAllModuleProxies(func(module android.ModuleProxy) {
+		info, ok := android.OtherModuleProvider(ctx, module, r.provider)
+		if !ok {
+			return
+		}
+		for i, output := range r.outputs {
+			if file := output.file(info); file != nil {
+				files[i] = append(files[i], file)
+			}
+		}
+	})
+
+	var outputs android.Paths
+	for i, output := range r.outputs {
+		path := output.path(ctx)
+		if len(files[i]) == 0 {
+			android.WriteFileRule(ctx, path, "")
+		} else {
+			ctx.Build(pctx, android.BuildParams{
+				Rule:        android.Cat,
+				Description: output.description,
+				Inputs:      android.SortedUniquePaths(files[i]),
+				Output:      path,
+			})
+		}
+		outputs = append(outputs, path)
+	}
+
+	ctx.Phony(r.goal, outputs...)
+	if r.dist {
+		ctx.DistForGoal(r.goal, outputs...)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"strconv"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+// Host tools sometimes need classes that are specific to a Java release, e.g. to use APIs that are
+// only available in newer releases while still running on older ones. The multi_release property
+// compiles additional sources for a release with javac --release and packs the classes under
+// META-INF/versions/<release> in the jar, which together with the Multi-Release attribute in the
+// manifest makes the jar a multi-release jar (JEP 238). If the module has a manifest it must set
+// the Multi-Release attribute, otherwise a manifest that sets it is generated.
+
+type MultiReleaseProperties struct {
+	// The Java release the sources are compiled for, e.g. 17.  Must be at least 9.
+	Release *int64
+
+	// The source files compiled for the release.  They are compiled against the classes compiled
+	// from the srcs of the module and its classpath.
+	Srcs []string `android:"path"`
+}
+
+var multiReleaseJavac = pctx.AndroidStaticRule("multiReleaseJavac",
+	blueprint.RuleParams{
+		Command: `rm -rf "$outDir" && mkdir -p "$outDir" && ` +
+			`${config.JavacCmd} ${config.JavacHeapFlags} ${config.JavacVmFlags} ${config.CommonJdkFlags} ` +
+			`$classpath --release $release -d $outDir @$out.rsp && ` +
+			`${config.SoongZipCmd} -jar -o $out -P $versionDir -C $outDir -D $outDir && ` +
+			`rm -rf "$outDir"`,
+		CommandDeps: []string{
+			"${config.JavacCmd}",
+			"${config.SoongZipCmd}",
+		},
+		Rspfile:        "$out.rsp",
+		RspfileContent: "$in",
+	},
+	"classpath", "outDir", "release", "versionDir")
+
+var multiReleaseManifestCheck = pctx.AndroidStaticRule("multiReleaseManifestCheck",
+	blueprint.RuleParams{
+		Command: `if ! grep -qiE '^Multi-Release:[[:space:]]*true[[:space:]]*$$' $in; then ` +
+			`echo "error: $in must set \"Multi-Release: true\" as the jar contains classes for specific Java releases" >&2; ` +
+			`exit 1; fi && touch $out`,
+	})
+
+// compileMultiRelease compiles the sources of each release in the multi_release property and
+// returns the jars containing their classes under META-INF/versions/<release>. localJars are the
+// jars containing the classes compiled from the srcs of the module.
+func (j *Module) compileMultiRelease(ctx android.ModuleContext, flags javaBuilderFlags,
+	localJars android.Paths) android.Paths {
+
+	if !ctx.Host() {
+		ctx.PropertyErrorf("multi_release", "is only supported for host modules")
+		return nil
+	}
+
+	cp := classpath(append(android.CopyOfPaths(localJars), flags.classpath...))
+
+	var jars android.Paths
+	releases := make(map[int]bool)
+	for _, props := range j.properties.Multi_release {
+		release := proptools.Int(props.Release)
+		if props.Release == nil || release < 9 {
+			ctx.PropertyErrorf("multi_release", "release must be at least 9, got %d", release)
+			continue
+		}
+		if releases[release] {
+			ctx.PropertyErrorf("multi_release", "release %d is listed more than once", release)
+			continue
+		}
+		releases[release] = true
+
+		srcs := android.PathsForModuleSrc(ctx, props.Srcs)
+		if len(srcs) == 0 {
+			ctx.PropertyErrorf("multi_release", "release %d has no srcs", release)
+			continue
+		}
+
+		releaseString := strconv.Itoa(release)
+		jar := android.PathForModuleOut(ctx, "multi-release", releaseString+".jar")
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        multiReleaseJavac,
+			Description: "javac --release " + releaseString,
+			Inputs:      srcs,
+			Implicits:   android.Paths(cp),
+			Output:      jar,
+			Args: map[string]string{
+				"classpath":  cp.FormJavaClassPath("-classpath"),
+				"outDir":     android.PathForModuleOut(ctx, "multi-release", releaseString).String(),
+				"release":    releaseString,
+				"versionDir": "META-INF/versions/" + releaseString,
+			},
+		})
+		jars = append(jars, jar)
+	}
+
+	return jars
+}
+
+// multiReleaseManifest returns the manifest of a multi-release jar and the validations that must
+// succeed for it to be used. The manifest of the module must set the Multi-Release attribute,
+// if the module doesn't have a manifest one that sets it is generated.
+func multiReleaseManifest(ctx android.ModuleContext, manifest android.OptionalPath) (android.OptionalPath, android.Paths) {
+	if !manifest.Valid() {
+		generated := android.PathForModuleOut(ctx, "multi-release", "manifest.txt")
+		android.WriteFileRule(ctx, generated, "Multi-Release: true")
+		return android.OptionalPathForPath(generated), nil
+	}
+
+	check := android.PathForModuleOut(ctx, "multi-release", "manifest.stamp")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        multiReleaseManifestCheck,
+		Description: "check Multi-Release manifest attribute",
+		Input:       manifest.Path(),
+		Output:      check,
+	})
+	return manifest, android.Paths{check}
+}
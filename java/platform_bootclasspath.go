@@ -17,6 +17,7 @@ package java
 import (
 	"maps"
 	"slices"
+	"strings"
 
 	"github.com/google/blueprint"
 
@@ -70,18 +71,38 @@ type platformBootclasspathModule struct {
 
 	// Path to the monolithic hiddenapi-unsupported.csv file.
 	hiddenAPIMetadataCSV android.OutputPath
+
+	// Path to the report of the differences between the monolithic hiddenapi-flags.csv file and
+	// the snapshot in Hidden_api_flags_diff.Snapshot, nil if there is no snapshot.
+	hiddenAPIFlagsDiff android.Path
 }
 
 type platformBootclasspathProperties struct {
 	BootclasspathFragmentsDepsProperties
 
 	HiddenAPIFlagFileProperties
+
+	// Compares the monolithic hiddenapi-flags.csv file with a checked-in snapshot, e.g. to review
+	// how the flags change when dropping new mainline prebuilts. The report of the new, removed
+	// and changed signatures is available with the hiddenapi-flags-diff.txt tag.
+	Hidden_api_flags_diff struct {
+		// Path to the snapshot of hiddenapi-flags.csv.
+		Snapshot *string `android:"path"`
+
+		// If true, signatures in the snapshot may be missing from the generated flags. Defaults
+		// to false.
+		Allow_removed *bool
+
+		// If true, signatures may move to a more restrictive API list than in the snapshot, e.g.
+		// from unsupported to blocked. Defaults to false.
+		Allow_restricted *bool
+	}
 }
 
 func platformBootclasspathFactory() android.Module {
 	m := &platformBootclasspathModule{}
 	m.AddProperties(&m.properties)
-	initClasspathFragment(m, BOOTCLASSPATH)
+	InitClasspathFragment(m, BOOTCLASSPATH)
 	android.InitAndroidArchModule(m, android.DeviceSupported, android.MultilibCommon)
 	return m
 }
@@ -93,7 +114,7 @@ func (b *platformBootclasspathModule) AndroidMkEntries() (entries []android.Andr
 		OutputFile: android.OptionalPathForPath(b.hiddenAPIFlagsCSV),
 		Include:    "$(BUILD_PHONY_PACKAGE)",
 	})
-	entries = append(entries, b.classpathFragmentBase().androidMkEntries()...)
+	entries = append(entries, b.classpathFragmentBase().ClasspathFragmentAndroidMkEntries()...)
 	return
 }
 
@@ -210,19 +231,94 @@ func (b *platformBootclasspathModule) GenerateAndroidBuildActions(ctx android.Mo
 	bootDexJarByModule := b.generateHiddenAPIBuildActions(ctx, b.configuredModules, b.fragments, b.libraryToApex, b.apexNameToFragment)
 	buildRuleForBootJarsPackageCheck(ctx, bootDexJarByModule)
 
+	global := dexpreopt.GetGlobalConfig(ctx)
+	bootJarsReport := b.generateBootJarsReport(ctx, []bootJarsReportGroup{
+		{global.ArtApexJars, artModules},
+		{b.platformJars(ctx), platformModules},
+		{global.ApexBootJars, apexModules},
+	})
+
 	ctx.SetOutputFiles(android.Paths{b.hiddenAPIFlagsCSV}, "hiddenapi-flags.csv")
 	ctx.SetOutputFiles(android.Paths{b.hiddenAPIIndexCSV}, "hiddenapi-index.csv")
 	ctx.SetOutputFiles(android.Paths{b.hiddenAPIMetadataCSV}, "hiddenapi-metadata.csv")
 	ctx.SetOutputFiles(android.Paths{srcjar}, ".srcjar")
+	ctx.SetOutputFiles(android.Paths{bootJarsReport}, "boot-jars-report.csv")
+	if b.hiddenAPIFlagsDiff != nil {
+		ctx.SetOutputFiles(android.Paths{b.hiddenAPIFlagsDiff}, "hiddenapi-flags-diff.txt")
+	}
+}
+
+// bootJarsReportGroup is a list of configured boot jars and the modules they resolved to.
+type bootJarsReportGroup struct {
+	jars    android.ConfiguredJarList
+	modules []android.Module
+}
+
+// generateBootJarsReport writes a CSV file describing how each configured boot jar was resolved:
+// the module and apex it came from, the fragment that covers it, where its hidden API flags come
+// from and its min_sdk_version.  Jars that did not resolve to a module are listed with empty
+// columns.  It is intended for debugging the composition of the boot image, and is dist'ed with
+// droidcore.
+func (b *platformBootclasspathModule) generateBootJarsReport(ctx android.ModuleContext, groups []bootJarsReportGroup) android.Path {
+	hiddenAPISource := func(fragment android.Module) string {
+		if ctx.Config().DisableHiddenApiChecks() {
+			return "disabled"
+		} else if fragment != nil {
+			return "fragment"
+		}
+		return "monolithic"
+	}
+
+	var report strings.Builder
+	report.WriteString("apex,jar,module,source_apex,fragment,hiddenapi,min_sdk_version\n")
+	for _, group := range groups {
+		modulesByName := make(map[string]android.Module)
+		for _, m := range group.modules {
+			modulesByName[android.RemoveOptionalPrebuiltPrefix(ctx.OtherModuleName(m))] = m
+		}
+
+		for i := 0; i < group.jars.Len(); i++ {
+			apex, jar := group.jars.Apex(i), group.jars.Jar(i)
+			row := []string{apex, jar, "", "", "", "", ""}
+			if m, ok := modulesByName[jar]; ok {
+				row[2] = ctx.OtherModuleName(m)
+
+				apexInfo, _ := android.OtherModuleProvider(ctx, m, android.ApexInfoProvider)
+				if apexInfo.IsForPlatform() {
+					row[3] = "platform"
+				} else {
+					row[3] = apexInfo.BaseApexName
+				}
+
+				fragment := b.apexNameToFragment[b.libraryToApex[m]]
+				if fragment != nil {
+					row[4] = ctx.OtherModuleName(fragment)
+				}
+				row[5] = hiddenAPISource(fragment)
+
+				commonInfo := android.OtherModulePointerProviderOrDefault(ctx, m, android.CommonModuleInfoProvider)
+				if commonInfo.MinSdkVersion.ApiLevel != nil {
+					row[6] = commonInfo.MinSdkVersion.ApiLevel.String()
+				}
+			}
+			report.WriteString(strings.Join(row, ","))
+			report.WriteString("\n")
+		}
+	}
+
+	reportFile := android.PathForModuleOut(ctx, "boot-jars-report.csv")
+	android.WriteFileRule(ctx, reportFile, report.String())
+	ctx.DistForGoal("droidcore", reportFile)
+	return reportFile
 }
 
 // Generate classpaths.proto config
 func (b *platformBootclasspathModule) generateClasspathProtoBuildActions(ctx android.ModuleContext) {
 	configuredJars := b.configuredJars(ctx)
 	// ART and platform boot jars must have a corresponding entry in DEX2OATBOOTCLASSPATH
-	classpathJars := configuredJarListToClasspathJars(ctx, configuredJars, BOOTCLASSPATH, DEX2OATBOOTCLASSPATH)
-	b.classpathFragmentBase().generateClasspathProtoBuildActions(ctx, configuredJars, classpathJars)
-	b.classpathFragmentBase().installClasspathProto(ctx)
+	classpathJars := ConfiguredJarListToClasspathJars(ctx, configuredJars, BOOTCLASSPATH, DEX2OATBOOTCLASSPATH)
+	b.classpathFragmentBase().GenerateClasspathProtoBuildActions(ctx, configuredJars, classpathJars)
+	b.classpathFragmentBase().InstallClasspathProto(ctx)
 }
 
 func (b *platformBootclasspathModule) configuredJars(ctx android.ModuleContext) android.ConfiguredJarList {
@@ -367,6 +463,7 @@ func (b *platformBootclasspathModule) generateHiddenAPIBuildActions(ctx android.
 	allAnnotationFlagFiles = append(allAnnotationFlagFiles, monolithicInfo.AnnotationFlagsPaths...)
 	allFlags := hiddenAPISingletonPaths(ctx).flags
 	buildRuleToGenerateHiddenApiFlags(ctx, "hiddenAPIFlagsFile", "monolithic hidden API flags", allFlags, stubFlags, allAnnotationFlagFiles, monolithicInfo.FlagsFilesByCategory, monolithicInfo.FlagSubsets, android.OptionalPath{})
+	b.buildRuleDiffHiddenAPIFlags(ctx, allFlags)
 
 	// Generate an intermediate monolithic hiddenapi-metadata.csv file directly from the annotations
 	// in the source code.
@@ -416,11 +513,45 @@ func (b *platformBootclasspathModule) createAndProvideMonolithicHiddenAPIInfo(ct
 	// in information from all the fragment dependencies of this.
 	monolithicInfo := newMonolithicHiddenAPIInfo(ctx, temporaryInput.FlagFilesByCategory, classpathElements)
 
+	// Add the hidden API files that are extracted directly from prebuilt apexes, see
+	// prebuilt_bootclasspath_fragment's apex_boot_jars property.
+	apexNameToApexExportsInfoMap := getApexNameToApexExportsInfoMap(ctx)
+	for _, apex := range android.SortedKeys(apexNameToApexExportsInfoMap) {
+		monolithicInfo.appendFromApexExports(apexNameToApexExportsInfoMap[apex])
+	}
+
 	// Store the information for testing.
 	android.SetProvider(ctx, MonolithicHiddenAPIInfoProvider, monolithicInfo)
 	return monolithicInfo
 }
 
+// buildRuleDiffHiddenAPIFlags creates a rule that reports the differences between the monolithic
+// hiddenapi-flags.csv file and the snapshot, and fails if any of them is not allowed.
+func (b *platformBootclasspathModule) buildRuleDiffHiddenAPIFlags(ctx android.ModuleContext, flags android.Path) {
+	props := b.properties.Hidden_api_flags_diff
+	if props.Snapshot == nil {
+		return
+	}
+
+	report := android.PathForModuleOut(ctx, "hiddenapi-monolithic", "hiddenapi-flags-diff.txt")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	cmd := rule.Command().
+		BuiltTool("diff_hiddenapi_flags").
+		FlagWithInput("--snapshot ", android.PathForModuleSrc(ctx, *props.Snapshot)).
+		FlagWithInput("--flags ", flags).
+		FlagWithOutput("--output ", report)
+	if Bool(props.Allow_removed) {
+		cmd.Flag("--allow-removed")
+	}
+	if Bool(props.Allow_restricted) {
+		cmd.Flag("--allow-restricted")
+	}
+	rule.Build("hiddenAPIFlagsDiff", "diff monolithic hidden API flags")
+
+	b.hiddenAPIFlagsDiff = report
+	ctx.CheckbuildFile(report)
+}
+
 func (b *platformBootclasspathModule) buildRuleMergeCSV(ctx android.ModuleContext, desc string, inputPaths android.Paths, outputPath android.WritablePath) {
 	rule := android.NewRuleBuilder(pctx, ctx)
 	rule.Command().
@@ -15,6 +15,7 @@
 package java
 
 import (
+	"strings"
 	"testing"
 
 	"android/soong/android"
@@ -323,6 +324,49 @@ func TestPlatformBootclasspath_Dist(t *testing.T) {
 	android.AssertStringEquals(t, "platform dist goals call", "$(call dist-for-goals,droidcore,out/soong/hiddenapi/hiddenapi-flags.csv:hiddenapi-flags.csv)", android.StringRelativeToTop(result.Config, goals[2]))
 }
 
+func TestPlatformBootclasspath_BootJarsReport(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForTestWithPlatformBootclasspath,
+		FixtureConfigureBootJars("platform:foo", "platform:bar"),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				min_sdk_version: "30",
+				compile_dex: true,
+			}
+
+			java_library {
+				name: "bar",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				min_sdk_version: "current",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	pbcp := result.ModuleForTests(t, "platform-bootclasspath", "android_common")
+	report := android.ContentFromFileRuleForTests(t, result.TestContext, pbcp.Output("boot-jars-report.csv"))
+	android.AssertStringEquals(t, "boot jars report", strings.Join([]string{
+		"apex,jar,module,source_apex,fragment,hiddenapi,min_sdk_version",
+		"platform,foo,foo,platform,,monolithic,30",
+		"platform,bar,bar,platform,,monolithic,current",
+		"",
+	}, "\n"), report)
+
+	android.AssertStringListContains(t, "boot jars report dist", pbcp.DistsForTests(result.TestContext),
+		"droidcore out/soong/.intermediates/platform-bootclasspath/android_common/boot-jars-report.csv:boot-jars-report.csv")
+}
+
 func TestPlatformBootclasspath_HiddenAPIMonolithicFiles(t *testing.T) {
 	t.Parallel()
 	result := android.GroupFixturePreparers(
@@ -406,3 +450,39 @@ func TestPlatformBootclasspath_HiddenAPIMonolithicFiles(t *testing.T) {
 		out/soong/.intermediates/myplatform-bootclasspath/android_common/hiddenapi-monolithic/index-from-classes.csv
 	`, rule)
 }
+
+func TestPlatformBootclasspath_HiddenAPIFlagsDiff(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		hiddenApiFixtureFactory,
+		FixtureConfigureBootJars("platform:foo"),
+		android.FixtureAddTextFile("hiddenapi-flags-snapshot.csv", ""),
+	).RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			compile_dex: true,
+		}
+
+		platform_bootclasspath {
+			name: "myplatform-bootclasspath",
+			hidden_api_flags_diff: {
+				snapshot: "hiddenapi-flags-snapshot.csv",
+				allow_removed: true,
+			},
+		}
+	`)
+
+	platformBootclasspath := result.ModuleForTests(t, "myplatform-bootclasspath", "android_common")
+	rule := platformBootclasspath.Output("hiddenapi-monolithic/hiddenapi-flags-diff.txt")
+	CheckHiddenAPIRuleInputs(t, "flags diff", `
+		hiddenapi-flags-snapshot.csv
+		out/soong/hiddenapi/hiddenapi-flags.csv
+	`, rule)
+	android.AssertStringDoesContain(t, "flags diff command", rule.RuleParams.Command, "--allow-removed")
+	android.AssertStringDoesNotContain(t, "flags diff command", rule.RuleParams.Command, "--allow-restricted")
+
+	android.AssertPathsRelativeToTopEquals(t, "hiddenapi-flags-diff.txt output files",
+		[]string{"out/soong/.intermediates/myplatform-bootclasspath/android_common/hiddenapi-monolithic/hiddenapi-flags-diff.txt"},
+		platformBootclasspath.OutputFiles(result.TestContext, t, "hiddenapi-flags-diff.txt"))
+}
@@ -20,8 +20,10 @@ import (
 )
 
 type JavaPluginInfo struct {
-	ProcessorClass *string
-	GeneratesApi   bool
+	ProcessorClass      *string
+	GeneratesApi        bool
+	SupportsKsp         bool
+	TurbineIncompatible bool
 }
 
 var JavaPluginInfoProvider = blueprint.NewProvider[JavaPluginInfo]()
@@ -76,14 +78,28 @@ type PluginProperties struct {
 	// This necessitates disabling the turbine optimization on modules that use this plugin, which will reduce
 	// parallelism and cause more recompilation for modules that depend on modules that use this plugin.
 	Generates_api *bool
+
+	// If true, the plugin also provides a KSP (Kotlin Symbol Processing) processor, which is much
+	// faster than running the annotation processor with kapt. Modules with Kotlin sources that list
+	// the plugin in plugins run it with KSP, while the other plugins keep running with kapt. Modules
+	// without Kotlin sources run it with javac as usual.
+	Supports_ksp *bool
+
+	// If true, the annotation processor does not work with the header-only compilation done by
+	// turbine. Modules with Kotlin sources that list the plugin in plugins run it with javac instead
+	// of turbine on the kapt stubs and their Java sources, while the other plugins keep running with
+	// turbine. The plugin must set processor_class.
+	Turbine_incompatible *bool
 }
 
 func (p *Plugin) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	p.Library.GenerateAndroidBuildActions(ctx)
 
 	android.SetProvider(ctx, JavaPluginInfoProvider, JavaPluginInfo{
-		ProcessorClass: p.pluginProperties.Processor_class,
-		GeneratesApi:   Bool(p.pluginProperties.Generates_api),
+		ProcessorClass:      p.pluginProperties.Processor_class,
+		GeneratesApi:        Bool(p.pluginProperties.Generates_api),
+		SupportsKsp:         Bool(p.pluginProperties.Supports_ksp),
+		TurbineIncompatible: Bool(p.pluginProperties.Turbine_incompatible),
 	})
 }
 
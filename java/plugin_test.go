@@ -16,6 +16,8 @@ package java
 
 import (
 	"testing"
+
+	"android/soong/android"
 )
 
 func TestNoPlugin(t *testing.T) {
@@ -123,3 +125,81 @@ func TestPluginGeneratesApi(t *testing.T) {
 		t.Errorf("foo processor %q != '-processor com.bar'", javac.Args["processor"])
 	}
 }
+
+func TestPluginNetworkSandbox(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			plugins: ["bar"],
+		}
+
+		java_library {
+			name: "foo_kt",
+			srcs: ["a.java", "b.kt"],
+			plugins: ["bar"],
+		}
+
+		java_library {
+			name: "foo_kt_discovered",
+			srcs: ["a.java", "b.kt"],
+			plugins: ["discovered"],
+		}
+
+		java_library {
+			name: "allowed",
+			srcs: ["a.java"],
+			plugins: ["bar"],
+			allow_network_access: true,
+		}
+
+		java_library {
+			name: "no_plugins",
+			srcs: ["a.java"],
+		}
+
+		java_plugin {
+			name: "bar",
+			processor_class: "com.bar",
+			srcs: ["b.java"],
+		}
+
+		java_plugin {
+			name: "discovered",
+			srcs: ["c.java"],
+		}
+	`
+	const sandbox = "${config.NetworkSandboxCmd} com.bar -- "
+
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureMergeEnv(map[string]string{"SOONG_JAVA_NETWORK_SANDBOX": "true"}),
+	).RunTestWithBp(t, bp)
+
+	javac := result.ModuleForTests(t, "foo", "android_common").Rule("javac")
+	android.AssertStringEquals(t, "foo javac network sandbox", sandbox, javac.Args["networkSandbox"])
+	android.AssertStringListContains(t, "foo javac implicits", javac.Implicits.Strings(),
+		"build/soong/scripts/network-sandbox.sh")
+
+	turbineApt := result.ModuleForTests(t, "foo_kt", "android_common").Description("turbine apt")
+	android.AssertStringEquals(t, "foo_kt turbine apt network sandbox", sandbox, turbineApt.Args["networkSandbox"])
+
+	// Turbine discovers the annotation processors in the processor path of plugins without a
+	// processor_class, so they are sandboxed too.
+	turbineApt = result.ModuleForTests(t, "foo_kt_discovered", "android_common").Description("turbine apt")
+	android.AssertStringEquals(t, "foo_kt_discovered turbine apt network sandbox",
+		"${config.NetworkSandboxCmd} '' -- ", turbineApt.Args["networkSandbox"])
+	android.AssertStringListContains(t, "foo_kt_discovered turbine apt implicits", turbineApt.Implicits.Strings(),
+		"build/soong/scripts/network-sandbox.sh")
+
+	for _, module := range []string{"allowed", "no_plugins"} {
+		javac := result.ModuleForTests(t, module, "android_common").Rule("javac")
+		android.AssertStringEquals(t, module+" javac network sandbox", "", javac.Args["networkSandbox"])
+	}
+
+	// The annotation processors aren't sandboxed unless SOONG_JAVA_NETWORK_SANDBOX is set.
+	result = prepareForJavaTest.RunTestWithBp(t, bp)
+	javac = result.ModuleForTests(t, "foo", "android_common").Rule("javac")
+	android.AssertStringEquals(t, "foo javac network sandbox without env", "", javac.Args["networkSandbox"])
+}
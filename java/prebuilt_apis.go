@@ -17,6 +17,7 @@ package java
 import (
 	"fmt"
 	"path"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -38,6 +39,11 @@ type prebuiltApisProperties struct {
 	// list of api version directories
 	Api_dirs []string
 
+	// If set to true, the subdirectories of the module directory that are named after an API level,
+	// e.g. "34" or "35.1", or "current" are used as api version directories in addition to the ones
+	// listed in api_dirs, so that the list doesn't have to be updated when an API level is finalized.
+	Auto_discover_api_dirs *bool
+
 	// Directory containing finalized api txt files for extension versions.
 	// Extension versions higher than the base sdk extension version will
 	// be assumed to be finalized later than all Api_dirs.
@@ -193,11 +199,35 @@ func createEmptyFile(mctx android.LoadHookContext, name string) {
 	mctx.CreateModule(genrule.GenRuleFactory, &props)
 }
 
+var apiDirRegexp = regexp.MustCompile(`^([0-9]+(\.[0-9]+)?|current)$`)
+
+// apiDirs returns the api version directories of the module: the api_dirs, followed by the
+// discovered ones if auto_discover_api_dirs is set.
+func apiDirs(mctx android.LoadHookContext, p *prebuiltApis) []string {
+	dirs := p.properties.Api_dirs
+	if !proptools.Bool(p.properties.Auto_discover_api_dirs) {
+		return dirs
+	}
+	// GlobWithDeps makes soong rerun when a new API level directory is added.
+	matches, err := mctx.GlobWithDeps(mctx.ModuleDir()+"/*/", nil)
+	if err != nil {
+		mctx.ModuleErrorf("failed to discover api dirs under %q: %s", mctx.ModuleDir(), err)
+		return dirs
+	}
+	dirs = android.CopyOf(dirs)
+	for _, match := range matches {
+		if dir := path.Base(match); apiDirRegexp.MatchString(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return android.FirstUniqueStrings(dirs)
+}
+
 // globApiDirs collects all the files in all api_dirs and all scopes that match the given glob, e.g. '*.jar' or 'api/*.txt'.
 // <api-dir>/<scope>/<glob> for all api-dir and scope.
 func globApiDirs(mctx android.LoadHookContext, p *prebuiltApis, api_dir_glob string) []string {
 	var files []string
-	for _, apiver := range p.properties.Api_dirs {
+	for _, apiver := range apiDirs(mctx, p) {
 		files = append(files, globScopeDir(mctx, apiver, api_dir_glob)...)
 	}
 	return files
@@ -402,6 +432,9 @@ func createPrebuiltApiModules(mctx android.LoadHookContext) {
 // <prebuilt-api-module>_<scope>_<ver>_<module>, and for SDK versions >= 30
 // a java_system_modules module named
 // <prebuilt-api-module>_public_<ver>_system_modules
+//
+// The <ver> directories are listed in api_dirs, or discovered automatically when
+// auto_discover_api_dirs is set.
 func PrebuiltApisFactory() android.Module {
 	module := &prebuiltApis{}
 	module.AddProperties(&module.properties)
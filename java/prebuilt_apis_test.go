@@ -125,3 +125,39 @@ func TestPrebuiltApis_WithMixedVersionCodes(t *testing.T) {
 	android.AssertStringEquals(t, "Expected latest bar = api level 34.1", "prebuilts/sdk/34.1/public/api/bar.txt", bar_input)
 	android.AssertStringEquals(t, "Expected latest baz = api level 34", "prebuilts/sdk/34/public/api/baz.txt", baz_input)
 }
+
+func TestPrebuiltApis_AutoDiscoverApiDirs(t *testing.T) {
+	t.Parallel()
+	fs := android.MockFS{}
+	for _, level := range []string{"33", "34", "34.1"} {
+		fs["prebuilts/other_sdk/"+level+"/public/api/foo.txt"] = nil
+		fs["prebuilts/other_sdk/"+level+"/public/foo.jar"] = nil
+	}
+	fs["prebuilts/other_sdk/34.1/public/api/bar.txt"] = nil
+	// Directories that aren't named after an API level are ignored.
+	fs["prebuilts/other_sdk/tools/public/api/baz.txt"] = nil
+
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureMergeMockFs(fs),
+		android.FixtureAddTextFile("prebuilts/other_sdk/Android.bp", `
+			prebuilt_apis {
+				name: "other_sdk",
+				auto_discover_api_dirs: true,
+			}
+		`),
+	).RunTest(t)
+
+	foo_input := result.ModuleForTests(t, "foo.api.public.latest", "").Rule("generator").Implicits[0].String()
+	android.AssertStringEquals(t, "Expected latest foo = api level 34.1", "prebuilts/other_sdk/34.1/public/api/foo.txt", foo_input)
+	bar_input := result.ModuleForTests(t, "bar.api.public.latest", "").Rule("generator").Implicits[0].String()
+	android.AssertStringEquals(t, "Expected latest bar = api level 34.1", "prebuilts/other_sdk/34.1/public/api/bar.txt", bar_input)
+
+	result.ModuleForTests(t, "foo.api.public.33", "")
+	result.ModuleForTests(t, "other_sdk_public_34_foo", "android_common")
+	result.VisitAllModules(func(module blueprint.Module) {
+		if strings.HasPrefix(module.Name(), "baz") {
+			t.Errorf("unexpected module %q created from a directory that isn't an API level", module.Name())
+		}
+	})
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// Deobfuscation services look up the R8 mapping of an app by its package name and version code,
+// which are only known once the manifest of the app is merged. Every app optimized by R8 zips its
+// proguard dictionary as <package name>/<version code>/proguard_dictionary, and the
+// proguard_dictionaries singleton merges them into proguard-dict-by-package.zip, together with the
+// fingerprint of the build, which is disted. The proguard dictionaries of the other modules
+// optimized by R8, which have no package name, are added as libraries/<module>/proguard_dictionary.
+
+// ProguardDictionaryDistInfo contains the zipped proguard dictionary of an app, laid out by the
+// package name and version code of the app.
+type ProguardDictionaryDistInfo struct {
+	Zip android.Path
+}
+
+var ProguardDictionaryDistInfoProvider = blueprint.NewProvider[ProguardDictionaryDistInfo]()
+
+var proguardDictionaryDistZipRule = pctx.AndroidStaticRule("proguardDictionaryDistZip",
+	blueprint.RuleParams{
+		Command: `badging=$$(${config.Aapt2Cmd} dump badging $package | head -n 1) && ` +
+			`name=$$(echo "$$badging" | sed -n "s/^package: name='\([^']*\)'.*/\1/p") && ` +
+			`version=$$(echo "$$badging" | sed -n "s/.* versionCode='\([^']*\)'.*/\1/p") && ` +
+			`${config.SoongZipCmd} -o $out -e $$name/$$version/proguard_dictionary -f $in`,
+		CommandDeps: []string{"${config.Aapt2Cmd}", "${config.SoongZipCmd}"},
+	}, "package",
+)
+
+// proguardDictionaryDistZip zips the proguard dictionary of an app under the package name and
+// version code of packageFile, the APK with the merged manifest of the app.
+func proguardDictionaryDistZip(ctx android.ModuleContext, dictionary, packageFile android.Path) {
+	zip := android.PathForModuleOut(ctx, "proguard_dictionary", "proguard_dictionary.zip")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        proguardDictionaryDistZipRule,
+		Description: "zip proguard dictionary",
+		Input:       dictionary,
+		Implicit:    packageFile,
+		Output:      zip,
+		Args: map[string]string{
+			"package": packageFile.String(),
+		},
+	})
+
+	android.SetProvider(ctx, ProguardDictionaryDistInfoProvider, ProguardDictionaryDistInfo{
+		Zip: zip,
+	})
+}
+
+// proguardDictionariesSingleton merges the proguard dictionaries of all the installed modules
+// optimized by R8.
+type proguardDictionariesSingleton struct{}
+
+func (s *proguardDictionariesSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	var zips android.Paths
+	libraryDictionaries := make(map[string]android.Path)
+	ctx.VisitAllModuleProxies(func(module android.ModuleProxy) {
+		commonInfo := android.OtherModulePointerProviderOrDefault(ctx, module, android.CommonModuleInfoProvider)
+		if !commonInfo.Enabled || commonInfo.SkipInstall {
+			return
+		}
+		if info, ok := android.OtherModuleProvider(ctx, module, ProguardDictionaryDistInfoProvider); ok {
+			zips = append(zips, info.Zip)
+		} else if info, ok := android.OtherModuleProvider(ctx, module, R8InfoProvider); ok {
+			name := ctx.ModuleName(module)
+			if _, exists := libraryDictionaries[name]; !exists {
+				libraryDictionaries[name] = info.MappingFile
+			}
+		}
+	})
+	if len(zips) == 0 && len(libraryDictionaries) == 0 {
+		return
+	}
+
+	// The fingerprint file is read without depending on it, like other rules that reference the
+	// build fingerprint, so that the zip is not rebuilt whenever the build number changes.
+	fingerprintFile := ctx.Config().BuildFingerprintFile(ctx)
+	fingerprintZip := android.PathForOutput(ctx, "proguard_dictionaries", "build_fingerprint.zip")
+	merged := proguardDictionariesZipPath(ctx)
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		BuiltTool("soong_zip").
+		FlagWithOutput("-o ", fingerprintZip).
+		FlagWithArg("-e ", "build_fingerprint.txt").
+		FlagWithArg("-f ", fingerprintFile.String()).
+		OrderOnly(fingerprintFile)
+	if len(libraryDictionaries) > 0 {
+		librariesZip := android.PathForOutput(ctx, "proguard_dictionaries", "libraries.zip")
+		cmd := rule.Command().
+			BuiltTool("soong_zip").
+			FlagWithOutput("-o ", librariesZip)
+		for _, name := range android.SortedKeys(libraryDictionaries) {
+			cmd.FlagWithArg("-e ", "libraries/"+name+"/proguard_dictionary").
+				FlagWithInput("-f ", libraryDictionaries[name])
+		}
+		zips = append(zips, librariesZip)
+	}
+	// Identical dictionaries of the same package and version code, e.g. from the variants of an app,
+	// are merged, while different ones fail the build.
+	rule.Command().
+		BuiltTool("merge_zips").
+		Flag("-s").
+		Output(merged).
+		Input(fingerprintZip).
+		Inputs(android.SortedUniquePaths(zips))
+	rule.Build("proguard_dictionaries", "merge proguard dictionaries")
+
+	ctx.Phony("proguard_dictionaries", merged)
+	ctx.DistForGoal("droidcore", merged)
+}
+
+func proguardDictionariesZipPath(ctx android.PathContext) android.WritablePath {
+	return android.PathForOutput(ctx, "proguard_dictionaries", "proguard-dict-by-package.zip")
+}
+
+func proguardDictionariesSingletonFactory() android.Singleton {
+	return &proguardDictionariesSingleton{}
+}
@@ -0,0 +1,49 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+// The proguard flags files of a library are only parsed by R8 when an optimized module that depends
+// on the library is built, so an unknown option or a malformed class specification in them is
+// reported far away from the module that declares them, and only once it has propagated to an app.
+// A validation on the module that declares the flags files runs the check_proguard_flags tool to
+// report obviously broken rules when the module itself is built.
+
+// checkProguardFlagsFiles returns the stamp file of the check of the proguard flags files declared
+// by the module, or nil if there is nothing to check.
+func (j *Module) checkProguardFlagsFiles(ctx android.ModuleContext) android.Path {
+	if !proptools.BoolDefault(j.dexProperties.Optimize.Check_proguard_flags_files, true) {
+		return nil
+	}
+	flagsFiles := android.PathsForModuleSrc(ctx, j.dexProperties.Optimize.Proguard_flags_files)
+	if len(flagsFiles) == 0 {
+		return nil
+	}
+
+	stamp := android.PathForModuleOut(ctx, "proguard", "proguard_flags_check.stamp")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		BuiltTool("check_proguard_flags").
+		FlagWithOutput("--output ", stamp).
+		Inputs(flagsFiles)
+	rule.Build("check_proguard_flags", "check proguard flags files")
+
+	return stamp
+}
@@ -16,6 +16,7 @@ package java
 
 import (
 	"fmt"
+	"strings"
 
 	"android/soong/android"
 	"android/soong/java/config"
@@ -79,6 +80,12 @@ type robolectricProperties struct {
 		Timeout *int64
 
 		// Number of shards to use when running the tests.
+		//
+		// If greater than one then the test classes, i.e. the sources whose names end in Test or
+		// Tests, are distributed between the shards and a test config that only runs the test
+		// classes of a shard is installed for each shard next to the test config for the whole
+		// suite, so that the shards can run in parallel. A <module>_merge_results.sh script is
+		// installed with them that merges the JUnit XML results of the shards.
 		Shards *int64
 	}
 
@@ -101,6 +108,9 @@ type robolectricTest struct {
 	testConfig android.Path
 	data       android.Paths
 
+	// The test configs of the shards, if the tests are sharded.
+	shardTestConfigs android.Paths
+
 	forceOSType   android.OsType
 	forceArchType android.ArchType
 }
@@ -132,6 +142,8 @@ func (r *robolectricTest) DepsMutator(ctx android.BottomUpMutatorContext) {
 
 	ctx.AddVariationDependencies(nil, roboCoverageLibsTag, r.robolectricProperties.Coverage_libs...)
 
+	addTestFixturesDeps(ctx, r.testProperties.Uses_test_fixtures)
+
 	ctx.AddFarVariationDependencies(ctx.Config().BuildOSCommonTarget.Variations(),
 		roboRuntimesTag, "robolectric-android-all-prebuilts")
 
@@ -255,6 +267,17 @@ func (r *robolectricTest) GenerateAndroidBuildActions(ctx android.ModuleContext)
 	installedConfig := ctx.InstallFile(installPath, ctx.ModuleName()+".config", r.testConfig)
 	installDeps = append(installDeps, installedConfig)
 
+	if shards := proptools.IntDefault(r.robolectricProperties.Test_options.Shards, 1); shards > 1 {
+		r.shardTestConfigs = r.generateShardTestConfigs(ctx, shards)
+		for i, shardTestConfig := range r.shardTestConfigs {
+			installedConfig := ctx.InstallFile(installPath, fmt.Sprintf("%s_shard%d.config", ctx.ModuleName(), i), shardTestConfig)
+			installDeps = append(installDeps, installedConfig)
+		}
+		installDeps = append(installDeps, r.installShardResultsMerger(ctx, installPath)...)
+	} else if shards < 1 {
+		ctx.PropertyErrorf("test_options.shards", "must be a positive integer, got %d", shards)
+	}
+
 	soInstallPath := installPath.Join(ctx, getLibPath(r.forceArchType))
 	for _, jniLib := range collectTransitiveJniDeps(ctx) {
 		installJni := ctx.InstallFile(soInstallPath, jniLib.path.Base(), jniLib.path)
@@ -275,6 +298,7 @@ func (r *robolectricTest) GenerateAndroidBuildActions(ctx android.ModuleContext)
 	if r.testConfig != nil {
 		moduleInfoJSON.TestConfig = append(moduleInfoJSON.TestConfig, r.testConfig.String())
 	}
+	moduleInfoJSON.TestConfig = append(moduleInfoJSON.TestConfig, r.shardTestConfigs.Strings()...)
 	if len(r.testProperties.Test_suites) > 0 {
 		moduleInfoJSON.CompatibilitySuites = append(moduleInfoJSON.CompatibilitySuites, r.testProperties.Test_suites...)
 	} else {
@@ -291,6 +315,55 @@ func (r *robolectricTest) GenerateAndroidBuildActions(ctx android.ModuleContext)
 	})
 }
 
+// generateShardTestConfigs generates a test config for each shard that only runs the test classes
+// distributed to the shard.
+func (r *robolectricTest) generateShardTestConfigs(ctx android.ModuleContext, shards int) android.Paths {
+	var testSrcs android.Paths
+	for _, src := range r.uniqueSrcFiles {
+		name := strings.TrimSuffix(src.Base(), src.Ext())
+		if strings.HasSuffix(name, "Test") || strings.HasSuffix(name, "Tests") {
+			testSrcs = append(testSrcs, src)
+		}
+	}
+	if len(testSrcs) == 0 {
+		ctx.PropertyErrorf("test_options.shards",
+			"no test classes to shard, the names of the sources of test classes must end in Test or Tests")
+		return nil
+	}
+
+	rule := android.NewRuleBuilder(pctx, ctx)
+	cmd := rule.Command().
+		BuiltTool("robolectric_shards").
+		Text("config").
+		FlagWithInput("--test-config ", r.testConfig)
+	var shardTestConfigs android.Paths
+	for i := 0; i < shards; i++ {
+		shardTestConfig := android.PathForModuleOut(ctx, "robolectric_shards", fmt.Sprintf("shard%d.config", i))
+		cmd.FlagWithOutput("--output ", shardTestConfig)
+		shardTestConfigs = append(shardTestConfigs, shardTestConfig)
+	}
+	cmd.FlagWithRspFileInputList("@", android.PathForModuleOut(ctx, "robolectric_shards", "srcs.rsp"), testSrcs)
+	rule.Build("robolectric_shards", "robolectric shard test configs")
+
+	return shardTestConfigs
+}
+
+// installShardResultsMerger installs the robolectric_shards tool and a <module>_merge_results.sh
+// script that uses it to merge the JUnit XML results of the shards. The script takes the file to
+// write the merged results to followed by the results of the shards.
+func (r *robolectricTest) installShardResultsMerger(ctx android.ModuleContext, installPath android.InstallPath) android.InstallPaths {
+	tool := ctx.Config().HostToolPath(ctx, "robolectric_shards")
+	installedTool := ctx.InstallFile(installPath, tool.Base(), tool)
+
+	script := android.PathForModuleOut(ctx, "robolectric_shards", "merge_results.sh")
+	android.WriteExecutableFileRuleVerbatim(ctx, script,
+		"#!/bin/bash\n"+
+			"exec \"$(dirname \"$0\")/robolectric_shards\" merge --output \"$@\"\n")
+	installedScript := ctx.InstallFile(installPath, ctx.ModuleName()+"_merge_results.sh", script)
+
+	return android.InstallPaths{installedTool, installedScript}
+}
+
 func generateSameDirRoboTestConfigJar(ctx android.ModuleContext, outputFile android.ModuleOutPath) {
 	rule := android.NewRuleBuilder(pctx, ctx)
 
@@ -119,3 +119,90 @@ func TestRobolectricJniTest(t *testing.T) {
 	assertTestOnlyAndTopLevel(t, ctx, expectedTestOnlyModules, expectedTopLevelTests)
 
 }
+
+func TestRobolectricShards(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS != "linux" {
+		t.Skip("requires linux")
+	}
+
+	ctx := android.GroupFixturePreparers(
+		PrepareForIntegrationTestWithJava,
+		prepareRobolectricRuntime,
+	).RunTestWithBp(t, `
+	android_app {
+		name: "inst-target",
+		srcs: ["App.java"],
+		platform_apis: true,
+	}
+
+	android_robolectric_test {
+		name: "robo-test",
+		instrumentation_for: "inst-target",
+		srcs: [
+			"FooTest.java",
+			"BarTests.java",
+			"TestUtils.java",
+		],
+		test_options: {
+			shards: 2,
+		},
+	}
+	`)
+
+	module := ctx.ModuleForTests(t, "robo-test", "android_common")
+	rule := module.Rule("robolectric_shards")
+	android.AssertStringDoesContain(t, "shard command", rule.RuleParams.Command, "robolectric_shards config")
+	android.AssertPathsRelativeToTopEquals(t, "shard test configs", []string{
+		"out/soong/.intermediates/robo-test/android_common/robolectric_shards/shard0.config",
+		"out/soong/.intermediates/robo-test/android_common/robolectric_shards/shard1.config",
+	}, rule.Outputs.Paths())
+	inputs := android.PathsRelativeToTop(rule.Inputs)
+	android.AssertStringListContains(t, "test sources", inputs, "FooTest.java")
+	android.AssertStringListContains(t, "test sources", inputs, "BarTests.java")
+	android.AssertStringListDoesNotContain(t, "test sources", inputs, "TestUtils.java")
+
+	// The test configs of the shards are installed next to the one for the whole suite, which is
+	// used by atest.
+	module.Output(installPathPrefix + "/robo-test/robo-test.config")
+	module.Output(installPathPrefix + "/robo-test/robo-test_shard0.config")
+	module.Output(installPathPrefix + "/robo-test/robo-test_shard1.config")
+
+	// The results of the shards are merged by the installed robolectric_shards tool.
+	module.Output(installPathPrefix + "/robo-test/robolectric_shards")
+	mergeScript := module.Output(installPathPrefix + "/robo-test/robo-test_merge_results.sh")
+	android.AssertPathRelativeToTopEquals(t, "merge script",
+		"out/soong/.intermediates/robo-test/android_common/robolectric_shards/merge_results.sh", mergeScript.Input)
+	android.AssertStringDoesContain(t, "merge script contents",
+		android.ContentFromFileRuleForTests(t, ctx.TestContext, module.Output("robolectric_shards/merge_results.sh")),
+		`/robolectric_shards" merge --output "$@"`)
+}
+
+func TestRobolectricShardsWithoutTestClasses(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS != "linux" {
+		t.Skip("requires linux")
+	}
+
+	android.GroupFixturePreparers(
+		PrepareForIntegrationTestWithJava,
+		prepareRobolectricRuntime,
+	).ExtendWithErrorHandler(android.FixtureExpectsOneErrorPattern(
+		`test_options.shards: no test classes to shard`,
+	)).RunTestWithBp(t, `
+	android_app {
+		name: "inst-target",
+		srcs: ["App.java"],
+		platform_apis: true,
+	}
+
+	android_robolectric_test {
+		name: "robo-test",
+		instrumentation_for: "inst-target",
+		srcs: ["TestUtils.java"],
+		test_options: {
+			shards: 2,
+		},
+	}
+	`)
+}
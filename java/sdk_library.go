@@ -263,6 +263,14 @@ func (scope *apiScope) stubsSourceModuleName(baseName string) string {
 	return baseName + ".stubs.source" + scope.moduleSuffix
 }
 
+func (scope *apiScope) kotlinStubsLibraryModuleName(baseName string) string {
+	return baseName + ".stubs.kotlin" + scope.moduleSuffix
+}
+
+func (scope *apiScope) kotlinStubsSourceModuleName(baseName string) string {
+	return baseName + ".stubs.source.kotlin" + scope.moduleSuffix
+}
+
 func (scope *apiScope) String() string {
 	return scope.name
 }
@@ -457,6 +465,7 @@ func init() {
 func RegisterSdkLibraryBuildComponents(ctx android.RegistrationContext) {
 	ctx.RegisterModuleType("java_sdk_library", SdkLibraryFactory)
 	ctx.RegisterModuleType("java_sdk_library_import", sdkLibraryImportFactory)
+	ctx.RegisterParallelSingletonType("sdk_library_xml_filenames", sdkLibraryXmlFilenamesSingletonFactory)
 }
 
 // Properties associated with each api scope.
@@ -492,6 +501,16 @@ type ApiScopeProperties struct {
 
 	// Name to override the api_surface that is passed down to droidstubs.
 	Api_surface *string
+
+	// Additional droiddoc options used only when generating the stubs source and API files for
+	// this scope, e.g. to hide different annotations than in the other scopes. They are passed
+	// after droiddoc_options and the options that are built into the scope.
+	//
+	// Supports the same substitutions as droiddoc_options.
+	Droiddoc_options []string
+
+	// Local files that are used within the droiddoc_options of this scope.
+	Droiddoc_option_files []string
 }
 
 type sdkLibraryProperties struct {
@@ -516,6 +535,12 @@ type sdkLibraryProperties struct {
 	// List of Java libraries that will included in the implementation lib.
 	Impl_only_static_libs []string `android:"arch_variant"`
 
+	// The min_sdk_version of the implementation library, if it needs to be newer than
+	// min_sdk_version. min_sdk_version remains the min sdk version of the library as seen by its
+	// users, while the implementation library and its dependencies are built for
+	// impl_min_sdk_version. Can not be lower than min_sdk_version.
+	Impl_min_sdk_version *string
+
 	// List of Java libraries that will be in the classpath when building stubs
 	Stub_only_libs []string `android:"arch_variant"`
 
@@ -550,6 +575,12 @@ type sdkLibraryProperties struct {
 	// is set to true, Metalava will allow framework SDK to contain annotations.
 	Annotations_enabled *bool
 
+	// If set to true then also generate stubs for Kotlin consumers in the
+	// <name>.stubs.kotlin.<scope> modules. The nullability annotations in those stubs are not
+	// migrated from the previous API, so Kotlin modules compiled against them get strict null
+	// types instead of platform types. Requires annotations_enabled.
+	Kotlin_stubs *bool
+
 	// a list of top-level directories containing files to merge qualifier annotations
 	// (i.e. those intended to be included in the stubs written) from.
 	Merge_annotations_dirs []string
@@ -581,6 +612,14 @@ type sdkLibraryProperties struct {
 	// in the public Android SDK.
 	Dist_group *string
 
+	// The name of the permissions xml file of the library that is installed in etc/permissions.
+	// Defaults to "<module name>.xml".
+	//
+	// The permissions files of all the libraries installed on the device must have unique names,
+	// even when they are installed in different APEXes, so this can be used to deliberately resolve
+	// a conflict between java_sdk_library modules with the same name.
+	Xml_filename *string
+
 	// A compatibility mode that allows historical API-tracking files to not exist.
 	// Do not use.
 	Unsafe_ignore_missing_latest_api bool
@@ -648,6 +687,25 @@ type sdkLibraryProperties struct {
 	// instead of the source Java files. Defaults to true.
 	Build_from_text_stub *bool
 
+	// Signatures of members of the library whose use by apps is restricted to apps targeting at
+	// most a specific SDK version. They are added to the hidden API flags in the same way as the
+	// signatures in the files listed in the corresponding hidden_api.max_target_* properties.
+	Hidden_api_max_target_sdk struct {
+		// Marks each signature as being supported only for targetSdkVersion <= R and low
+		// priority.
+		R_low_priority []string
+
+		// Marks each signature as being supported only for targetSdkVersion <= Q.
+		Q []string
+
+		// Marks each signature as being supported only for targetSdkVersion <= P.
+		P []string
+
+		// Marks each signature as being supported only for targetSdkVersion <= O and low
+		// priority. Any conflicts with other flags are ignored.
+		O_low_priority []string
+	}
+
 	// TODO: determines whether to create HTML doc or not
 	// Html_doc *bool
 }
@@ -1038,6 +1096,9 @@ const (
 	annotationsComponentName = "annotations.zip"
 )
 
+// setOutputFiles sets the per-scope output files, e.g. ".public.annotations.zip". References to them
+// through the name of the source module, e.g. ":sdklib{.public.annotations.zip}", resolve to the
+// source module or the prebuilt selected by apex_contributions or prefer.
 func (module *commonToSdkLibraryAndImport) setOutputFiles(ctx android.ModuleContext) {
 	if module.doctagPaths != nil {
 		ctx.SetOutputFiles(module.doctagPaths, ".doctags")
@@ -1225,6 +1286,13 @@ type SdkLibraryInfo struct {
 	SharedLibrary bool
 
 	Prebuilt bool
+
+	// The min_sdk_version and max_sdk_version of the library, only set if it is not a prebuilt.
+	MinSdkVersion android.ApiLevel
+	MaxSdkVersion android.ApiLevel
+
+	// The max_device_sdk of the library, only set if it is not a prebuilt.
+	MaxDeviceSdk *string
 }
 
 var SdkLibraryInfoProvider = blueprint.NewProvider[SdkLibraryInfo]()
@@ -1332,12 +1400,21 @@ func (module *SdkLibrary) getGeneratedApiScopes(ctx android.EarlyModuleContext)
 var _ android.ModuleWithMinSdkVersionCheck = (*SdkLibrary)(nil)
 
 func (module *SdkLibrary) CheckMinSdkVersion(ctx android.ModuleContext) {
-	CheckMinSdkVersion(ctx, &module.Library)
+	// When impl_min_sdk_version is set the implementation library checks its dependencies against
+	// it, so it is skipped here.
+	checkMinSdkVersion(ctx, &module.Library, module.sdkLibraryProperties.Impl_min_sdk_version != nil)
 }
 
 func CheckMinSdkVersion(ctx android.ModuleContext, module *Library) {
+	checkMinSdkVersion(ctx, module, false)
+}
+
+func checkMinSdkVersion(ctx android.ModuleContext, module *Library, skipImplLibrary bool) {
 	android.CheckMinSdkVersion(ctx, module.MinSdkVersion(ctx), func(c android.BaseModuleContext, do android.PayloadDepsCallback) {
 		ctx.WalkDepsProxy(func(child, parent android.ModuleProxy) bool {
+			if skipImplLibrary && ctx.OtherModuleDependencyTag(child) == implLibraryTag {
+				return false
+			}
 			isExternal := !android.IsDepInSameApex(ctx, module, child)
 			if am, ok := android.OtherModuleProvider(ctx, child, android.CommonModuleInfoProvider); ok && am.IsApexModule {
 				if !do(ctx, parent, child, isExternal) {
@@ -1447,6 +1524,30 @@ func (module *SdkLibrary) DepsMutator(ctx android.BottomUpMutatorContext) {
 	}
 }
 
+// hiddenAPIMaxTargetSdkFlagFiles writes the signatures listed in the hidden_api_max_target_sdk
+// properties to flag files and returns them by their hidden API flag file category.
+func (module *SdkLibrary) hiddenAPIMaxTargetSdkFlagFiles(ctx android.ModuleContext) FlagFilesByCategory {
+	p := module.sdkLibraryProperties.Hidden_api_max_target_sdk
+	signaturesByCategory := map[hiddenAPIFlagFileCategory][]string{
+		hiddenAPIFlagFileCategoryMaxTargetRLowPriority: p.R_low_priority,
+		hiddenAPIFlagFileCategoryMaxTargetQ:            p.Q,
+		hiddenAPIFlagFileCategoryMaxTargetP:            p.P,
+		hiddenAPIFlagFileCategoryMaxTargetOLowPriority: p.O_low_priority,
+	}
+
+	flagFiles := FlagFilesByCategory{}
+	for _, category := range HiddenAPIFlagFileCategories {
+		signatures := signaturesByCategory[category]
+		if len(signatures) == 0 {
+			continue
+		}
+		flagFile := android.PathForModuleOut(ctx, "hiddenapi", category.PropertyName()+".txt")
+		android.WriteFileRule(ctx, flagFile, strings.Join(android.SortedUniqueStrings(signatures), "\n"))
+		flagFiles[category] = android.Paths{flagFile}
+	}
+	return flagFiles
+}
+
 func (module *SdkLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	if disableSourceApexVariant(ctx) {
 		// Prebuilts are active, do not create the installation rules for the source javalib.
@@ -1458,7 +1559,11 @@ func (module *SdkLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext)
 
 	module.stem = proptools.StringDefault(module.overridableProperties.Stem, ctx.ModuleName())
 
-	module.provideHiddenAPIPropertyInfo(ctx)
+	if module.sdkLibraryProperties.Unsafe_ignore_missing_latest_api {
+		checkUnsafeEscapes(ctx, unsafeIgnoreMissingLatestApi)
+	}
+
+	module.provideHiddenAPIPropertyInfo(ctx, module.hiddenAPIMaxTargetSdkFlagFiles(ctx))
 
 	// Collate the components exported by this module. All scope specific modules are exported but
 	// the impl and xml component modules are not.
@@ -1588,6 +1693,9 @@ func (module *SdkLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext)
 
 	sdkLibInfo.GeneratingLibs = generatingLibs
 	sdkLibInfo.Prebuilt = false
+	sdkLibInfo.MinSdkVersion = module.MinSdkVersion(ctx)
+	sdkLibInfo.MaxSdkVersion = module.MaxSdkVersion(ctx)
+	sdkLibInfo.MaxDeviceSdk = module.commonSdkLibraryProperties.Max_device_sdk
 	android.SetProvider(ctx, SdkLibraryInfoProvider, sdkLibInfo)
 }
 
@@ -1628,6 +1736,16 @@ func (module *SdkLibrary) apiDistPath(apiScope *apiScope) string {
 	return path.Join("apistubs", module.distGroup(), apiScope.name)
 }
 
+// releaseApiDistPath returns the dist path of the "exportable" stub artifacts in the dist tree of
+// the current release config, or an empty string if they are not dist'ed per release config.
+func (module *SdkLibrary) releaseApiDistPath(ctx android.ConfigContext, apiScope *apiScope) string {
+	release := ctx.Config().ReleaseConfigName()
+	if !ctx.Config().ReleaseExportableStubsReleaseConfigDist() || release == "" {
+		return ""
+	}
+	return path.Join("apistubs", release, module.distGroup(), apiScope.name)
+}
+
 // Get the sdk version for use when compiling the stubs library.
 func (module *SdkLibrary) sdkVersionForStubsLibrary(mctx android.EarlyModuleContext, apiScope *apiScope) string {
 	scopeProperties := module.scopeToProperties[apiScope]
@@ -1763,6 +1881,13 @@ func (module *SdkLibrary) CreateInternalModules(mctx android.DefaultableHookCont
 		return
 	}
 
+	module.validateImplMinSdkVersion(mctx)
+
+	if proptools.Bool(module.sdkLibraryProperties.Kotlin_stubs) && !proptools.Bool(module.sdkLibraryProperties.Annotations_enabled) {
+		mctx.PropertyErrorf("kotlin_stubs", "requires annotations_enabled to be set")
+		return
+	}
+
 	// If this builds against standard libraries (i.e. is not part of the core libraries)
 	// then assume it provides both system and test apis.
 	sdkDep := decodeSdkDep(mctx, android.SdkContext(&module.Library))
@@ -1817,6 +1942,11 @@ func (module *SdkLibrary) CreateInternalModules(mctx android.DefaultableHookCont
 		}
 		module.createTopLevelStubsLibrary(mctx, scope)
 		module.createTopLevelExportableStubsLibrary(mctx, scope)
+
+		if proptools.Bool(module.sdkLibraryProperties.Kotlin_stubs) {
+			module.createKotlinDroidstubs(mctx, scope)
+			module.createKotlinStubsLibrary(mctx, scope)
+		}
 	}
 
 	if module.requiresRuntimeImplementationLibrary() {
@@ -1846,6 +1976,27 @@ func (module *SdkLibrary) CreateInternalModules(mctx android.DefaultableHookCont
 	module.properties.Static_libs.AppendSimpleValue(module.sdkLibraryProperties.Impl_only_static_libs)
 }
 
+// validateImplMinSdkVersion checks that impl_min_sdk_version is not lower than min_sdk_version.
+func (module *SdkLibrary) validateImplMinSdkVersion(ctx android.EarlyModuleContext) {
+	implMinSdkVersion := module.sdkLibraryProperties.Impl_min_sdk_version
+	if implMinSdkVersion == nil {
+		return
+	}
+	if module.overridableProperties.Min_sdk_version == nil {
+		ctx.PropertyErrorf("impl_min_sdk_version", "can only be used together with min_sdk_version")
+		return
+	}
+	implLevel, err := android.ApiLevelFromUser(ctx, *implMinSdkVersion)
+	if err != nil {
+		ctx.PropertyErrorf("impl_min_sdk_version", "%s", err)
+		return
+	}
+	if minLevel := module.Library.MinSdkVersion(ctx); implLevel.LessThan(minLevel) {
+		ctx.PropertyErrorf("impl_min_sdk_version", "%s can not be lower than min_sdk_version %s",
+			implLevel, minLevel)
+	}
+}
+
 func (module *SdkLibrary) InitSdkLibraryProperties() {
 	module.addHostAndDeviceProperties()
 	module.AddProperties(&module.sdkLibraryProperties)
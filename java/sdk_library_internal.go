@@ -17,11 +17,13 @@ package java
 import (
 	"fmt"
 	"path"
+	"strconv"
 	"strings"
 
 	"android/soong/android"
 	"android/soong/etc"
 
+	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
 )
 
@@ -67,6 +69,18 @@ func (c *commonToSdkLibraryAndImport) droidstubsModuleName(apiScope *apiScope) s
 	return apiScope.stubsSourceModuleName(baseName)
 }
 
+// Name of the java_library module that compiles the stubs for Kotlin consumers.
+func (c *commonToSdkLibraryAndImport) kotlinStubsLibraryModuleName(apiScope *apiScope) string {
+	baseName := c.module.RootLibraryName()
+	return apiScope.kotlinStubsLibraryModuleName(baseName)
+}
+
+// Name of the droidstubs module that generates the stubs source for Kotlin consumers.
+func (c *commonToSdkLibraryAndImport) kotlinDroidstubsModuleName(apiScope *apiScope) string {
+	baseName := c.module.RootLibraryName()
+	return apiScope.kotlinStubsSourceModuleName(baseName)
+}
+
 // Name of the java_api_library module that generates the from-text stubs source
 // and compiles to a jar file.
 func (c *commonToSdkLibraryAndImport) fromTextStubsLibraryModuleName(apiScope *apiScope) string {
@@ -126,6 +140,15 @@ func (c *commonToSdkLibraryAndImport) exportableFromSourceStubsLibraryModuleName
 //		module given that from-text stubs cannot be used for SDK builds as it does not contain
 //		documentations.
 //
+// - "framework-foo.stubs.source.kotlin.<[apiScope.name]>" (type: [Droidstubs]): droidstubs
+//		module that generates the stubs for Kotlin consumers, whose nullability annotations are
+//		strict. This module is only created when kotlin_stubs is set.
+//
+// - "framework-foo.stubs.kotlin.<[apiScope.name]>" (type: [Library]): stub library module that
+//		compiles the stubs generated by the Kotlin droidstubs submodule, so that Kotlin modules
+//		compiled against it get strict null types. This module is only created when
+//		kotlin_stubs is set.
+//
 // - "framework-foo.xml" (type: [sdkLibraryXml]): xml library that generates the permission xml
 //		file, which allows [SdkLibrary] to be used with <uses-permission> tag in the
 //		AndroidManifest.xml files.
@@ -145,6 +168,9 @@ func (module *SdkLibrary) createImplLibrary(mctx android.DefaultableHookContext)
 		Static_libs    proptools.Configurable[[]string]
 		Apex_available []string
 		Stem           *string
+
+		// Overrides the min_sdk_version of the module if impl_min_sdk_version is set.
+		Min_sdk_version *string
 	}{
 		Name:       proptools.StringPtr(module.implLibraryModuleName()),
 		Enabled:    module.EnabledProperty(),
@@ -158,6 +184,8 @@ func (module *SdkLibrary) createImplLibrary(mctx android.DefaultableHookContext)
 		Apex_available: module.ApexAvailable(),
 
 		Stem: proptools.StringPtr(module.Name()),
+
+		Min_sdk_version: module.sdkLibraryProperties.Impl_min_sdk_version,
 	}
 
 	properties := []interface{}{
@@ -260,33 +288,14 @@ func (module *SdkLibrary) createDroidstubs(mctx android.DefaultableHookContext,
 	props.Merge_inclusion_annotations_dirs = module.sdkLibraryProperties.Merge_inclusion_annotations_dirs
 	props.Aconfig_declarations = module.sdkLibraryProperties.Aconfig_declarations
 
-	droidstubsArgs := []string{}
-	if len(module.sdkLibraryProperties.Api_packages) != 0 {
-		droidstubsArgs = append(droidstubsArgs, "--stub-packages "+strings.Join(module.sdkLibraryProperties.Api_packages, ":"))
-	}
-	droidstubsArgs = append(droidstubsArgs, module.sdkLibraryProperties.Droiddoc_options...)
-	disabledWarnings := []string{"HiddenSuperclass"}
-	if proptools.BoolDefault(module.sdkLibraryProperties.Api_lint.Legacy_errors_allowed, true) {
-		disabledWarnings = append(disabledWarnings,
-			"BroadcastBehavior",
-			"DeprecationMismatch",
-			"MissingPermission",
-			"SdkConstant",
-			"Todo",
-		)
-	}
-	droidstubsArgs = append(droidstubsArgs, android.JoinWithPrefix(disabledWarnings, "--hide "))
+	props.Args = proptools.StringPtr(strings.Join(module.droidstubsArgs(apiScope, scopeSpecificDroidstubsArgs), " "))
+	props.Arg_files = module.droidstubsArgFiles(apiScope)
 
 	// Output Javadoc comments for public scope.
 	if apiScope == apiScopePublic {
 		props.Output_javadoc_comments = proptools.BoolPtr(true)
 	}
 
-	// Add in scope specific arguments.
-	droidstubsArgs = append(droidstubsArgs, scopeSpecificDroidstubsArgs...)
-	props.Arg_files = module.sdkLibraryProperties.Droiddoc_option_files
-	props.Args = proptools.StringPtr(strings.Join(droidstubsArgs, " "))
-
 	// List of APIs identified from the provided source files are created. They are later
 	// compared against to the not-yet-released (a.k.a current) list of APIs and to the
 	// last-released (a.k.a numbered) list of API.
@@ -353,12 +362,112 @@ func (module *SdkLibrary) createDroidstubs(mctx android.DefaultableHookContext,
 				Dest:    proptools.StringPtr(fmt.Sprintf(p.pattern, module.distStem())),
 				Tag:     proptools.StringPtr(fmt.Sprintf(p.tag, stubsTypeTagPrefix)),
 			})
+			// Also dist the "exportable" api files into the dist tree of the release config.
+			if releaseDistDir := module.releaseApiDistPath(mctx, apiScope); releaseDistDir != "" {
+				props.Dists = append(props.Dists, android.Dist{
+					Targets: []string{"sdk", "win_sdk"},
+					Dir:     proptools.StringPtr(path.Join(releaseDistDir, "api")),
+					Dest:    proptools.StringPtr(fmt.Sprintf(p.pattern, module.distStem())),
+					Tag:     proptools.StringPtr(fmt.Sprintf(p.tag, ".exportable")),
+				})
+			}
 		}
 	}
 
 	mctx.CreateModule(DroidstubsFactory, &props, module.sdkComponentPropertiesForChildLibrary()).(*Droidstubs).CallHookIfAvailable(mctx)
 }
 
+// Creates the [Droidstubs] module with ".stubs.source.kotlin.<[apiScope.name]>" suffix that
+// creates stubs source files for Kotlin consumers. Unlike the stubs created by [createDroidstubs],
+// the nullability annotations in these stubs are not migrated from the previous API (i.e. they
+// are not replaced with @RecentlyNullable and @RecentlyNonNull), so that Kotlin treats them as
+// strict null types. The module does not update or check the API specification files, as that is
+// done by the main droidstubs module.
+func (module *SdkLibrary) createKotlinDroidstubs(mctx android.DefaultableHookContext, apiScope *apiScope) {
+	props := struct {
+		Name                             *string
+		Enabled                          proptools.Configurable[bool]
+		Visibility                       []string
+		Srcs                             []string
+		Installable                      *bool
+		Sdk_version                      *string
+		Api_surface                      *string
+		System_modules                   *string
+		Libs                             proptools.Configurable[[]string]
+		Arg_files                        []string
+		Args                             *string
+		Java_version                     *string
+		Annotations_enabled              *bool
+		Merge_annotations_dirs           []string
+		Merge_inclusion_annotations_dirs []string
+		Aconfig_declarations             []string
+		Aidl                             struct {
+			Include_dirs       []string
+			Local_include_dirs []string
+		}
+	}{}
+
+	props.Name = proptools.StringPtr(module.kotlinDroidstubsModuleName(apiScope))
+	props.Enabled = module.EnabledProperty()
+	props.Visibility = []string{"//visibility:override", "//visibility:private"}
+	props.Srcs = append(props.Srcs, module.properties.Srcs...)
+	props.Srcs = append(props.Srcs, module.sdkLibraryProperties.Api_srcs...)
+	props.Sdk_version = module.deviceProperties.Sdk_version
+	props.Api_surface = module.getApiSurfaceForScope(apiScope)
+	props.System_modules = module.deviceProperties.System_modules
+	props.Installable = proptools.BoolPtr(false)
+	props.Libs = proptools.NewConfigurable[[]string](nil, nil)
+	props.Libs.AppendSimpleValue(module.properties.Libs)
+	props.Libs.Append(module.properties.Static_libs)
+	props.Libs.AppendSimpleValue(module.sdkLibraryProperties.Stub_only_libs)
+	props.Libs.AppendSimpleValue(module.scopeToProperties[apiScope].Libs)
+	props.Aidl.Include_dirs = module.deviceProperties.Aidl.Include_dirs
+	props.Aidl.Local_include_dirs = module.deviceProperties.Aidl.Local_include_dirs
+	props.Java_version = module.properties.Java_version
+
+	props.Annotations_enabled = proptools.BoolPtr(true)
+	props.Merge_annotations_dirs = module.sdkLibraryProperties.Merge_annotations_dirs
+	props.Merge_inclusion_annotations_dirs = module.sdkLibraryProperties.Merge_inclusion_annotations_dirs
+	props.Aconfig_declarations = module.sdkLibraryProperties.Aconfig_declarations
+
+	props.Args = proptools.StringPtr(strings.Join(module.droidstubsArgs(apiScope, apiScope.droidstubsArgs), " "))
+	props.Arg_files = module.droidstubsArgFiles(apiScope)
+
+	mctx.CreateModule(DroidstubsFactory, &props, module.sdkComponentPropertiesForChildLibrary()).(*Droidstubs).CallHookIfAvailable(mctx)
+}
+
+// Returns the metalava arguments that are used to generate the stubs source of the given api
+// scope.
+func (module *SdkLibrary) droidstubsArgs(apiScope *apiScope, scopeSpecificDroidstubsArgs []string) []string {
+	droidstubsArgs := []string{}
+	if len(module.sdkLibraryProperties.Api_packages) != 0 {
+		droidstubsArgs = append(droidstubsArgs, "--stub-packages "+strings.Join(module.sdkLibraryProperties.Api_packages, ":"))
+	}
+	droidstubsArgs = append(droidstubsArgs, module.sdkLibraryProperties.Droiddoc_options...)
+	disabledWarnings := []string{"HiddenSuperclass"}
+	if proptools.BoolDefault(module.sdkLibraryProperties.Api_lint.Legacy_errors_allowed, true) {
+		disabledWarnings = append(disabledWarnings,
+			"BroadcastBehavior",
+			"DeprecationMismatch",
+			"MissingPermission",
+			"SdkConstant",
+			"Todo",
+		)
+	}
+	droidstubsArgs = append(droidstubsArgs, android.JoinWithPrefix(disabledWarnings, "--hide "))
+
+	// Add in scope specific arguments, followed by the ones set for the scope on the module.
+	droidstubsArgs = append(droidstubsArgs, scopeSpecificDroidstubsArgs...)
+	droidstubsArgs = append(droidstubsArgs, module.scopeToProperties[apiScope].Droiddoc_options...)
+	return droidstubsArgs
+}
+
+// Returns the files that are referenced by the droiddoc options of the given api scope.
+func (module *SdkLibrary) droidstubsArgFiles(apiScope *apiScope) []string {
+	return append(android.CopyOf(module.sdkLibraryProperties.Droiddoc_option_files),
+		module.scopeToProperties[apiScope].Droiddoc_option_files...)
+}
+
 type libraryProperties struct {
 	Name           *string
 	Enabled        proptools.Configurable[bool]
@@ -382,6 +491,7 @@ type libraryProperties struct {
 		Dir     *string
 		Tag     *string
 	}
+	Dists                 []android.Dist
 	Is_stubs_module       *bool
 	Stub_contributing_api *string
 }
@@ -558,12 +668,40 @@ func (module *SdkLibrary) createTopLevelExportableStubsLibrary(
 	props := module.topLevelStubsLibraryProps(mctx, apiScope, doDist)
 	props.Name = proptools.StringPtr(module.exportableStubsLibraryModuleName(apiScope))
 
+	// Also dist the "exportable" stubs into the dist tree of the release config.
+	if releaseDistDir := module.releaseApiDistPath(mctx, apiScope); releaseDistDir != "" &&
+		!Bool(module.sdkLibraryProperties.No_dist) {
+		props.Dists = append(props.Dists, android.Dist{
+			Targets: []string{"sdk", "win_sdk"},
+			Dir:     proptools.StringPtr(releaseDistDir),
+			Dest:    proptools.StringPtr(fmt.Sprintf("%v.jar", module.distStem())),
+			Tag:     proptools.StringPtr(".jar"),
+		})
+	}
+
 	staticLib := module.exportableFromSourceStubsLibraryModuleName(apiScope)
 	props.Static_libs = append(props.Static_libs, staticLib)
 
 	mctx.CreateModule(LibraryFactory, &props, module.sdkComponentPropertiesForChildLibrary())
 }
 
+// Creates the Kotlin stub [Library] with ".stubs.kotlin.<[apiScope.name]>" suffix, which compiles
+// the stubs created by [createKotlinDroidstubs].
+func (module *SdkLibrary) createKotlinStubsLibrary(mctx android.DefaultableHookContext, apiScope *apiScope) {
+	props := module.stubsLibraryProps(mctx, apiScope)
+	props.Name = proptools.StringPtr(module.kotlinStubsLibraryModuleName(apiScope))
+	props.Visibility = childModuleVisibility(module.sdkLibraryProperties.Stubs_library_visibility)
+	props.Srcs = []string{":" + module.kotlinDroidstubsModuleName(apiScope)}
+
+	compileDex := module.dexProperties.Compile_dex
+	if module.stubLibrariesCompiledForDex() {
+		compileDex = proptools.BoolPtr(true)
+	}
+	props.Compile_dex = compileDex
+
+	mctx.CreateModule(LibraryFactory, &props, module.sdkComponentPropertiesForChildLibrary())
+}
+
 // Creates the [sdkLibraryXml] with ".xml" suffix.
 func (module *SdkLibrary) createXmlFile(mctx android.DefaultableHookContext) {
 	moduleMinApiLevel := module.Library.MinSdkVersion(mctx)
@@ -571,6 +709,10 @@ func (module *SdkLibrary) createXmlFile(mctx android.DefaultableHookContext) {
 	if moduleMinApiLevel == android.NoneApiLevel {
 		moduleMinApiLevelStr = "current"
 	}
+	var certDigest *string
+	if module.usesLibraryProperties.Provides_uses_lib_cert_digest != nil {
+		certDigest = proptools.StringPtr(module.usesLibrary.providesUsesLibAttributes().CertDigest)
+	}
 	props := struct {
 		Name                      *string
 		Enabled                   proptools.Configurable[bool]
@@ -582,6 +724,9 @@ func (module *SdkLibrary) createXmlFile(mctx android.DefaultableHookContext) {
 		Max_device_sdk            *string
 		Sdk_library_min_api_level *string
 		Uses_libs_dependencies    proptools.Configurable[[]string]
+		Lib_version               *int64
+		Lib_cert_digest           *string
+		Xml_filename              *string
 	}{
 		Name:                      proptools.StringPtr(module.xmlPermissionsModuleName()),
 		Enabled:                   module.EnabledProperty(),
@@ -593,6 +738,9 @@ func (module *SdkLibrary) createXmlFile(mctx android.DefaultableHookContext) {
 		Max_device_sdk:            module.commonSdkLibraryProperties.Max_device_sdk,
 		Sdk_library_min_api_level: &moduleMinApiLevelStr,
 		Uses_libs_dependencies:    module.usesLibraryProperties.Uses_libs.Clone(),
+		Lib_version:               module.usesLibraryProperties.Provides_uses_lib_version,
+		Lib_cert_digest:           certDigest,
+		Xml_filename:              module.sdkLibraryProperties.Xml_filename,
 	}
 
 	mctx.CreateModule(sdkLibraryXmlFactory, &props)
@@ -758,8 +906,32 @@ type sdkLibraryXmlProperties struct {
 	//
 	// This will add dependency="foo:bar" to the <library> section.
 	Uses_libs_dependencies proptools.Configurable[[]string]
+
+	// The version of the shared library.
+	//
+	// This will add version="N" to the <library> section.
+	Lib_version *int64
+
+	// The SHA-256 digest of the certificate that the shared library is signed with.
+	//
+	// This will add cert-digest="..." to the <library> section.
+	Lib_cert_digest *string
+
+	// The name of the installed permissions xml file, defaults to "<lib_name>.xml".
+	Xml_filename *string
+}
+
+// SdkLibraryXmlInfo contains information about the permissions xml file of a java_sdk_library.
+type SdkLibraryXmlInfo struct {
+	// The name of the permissions xml file.
+	FileName string
+
+	// The directory on the device that the permissions xml file is installed in.
+	DeviceDir string
 }
 
+var SdkLibraryXmlInfoProvider = blueprint.NewProvider[SdkLibraryXmlInfo]()
+
 // java_sdk_library_xml builds the permission xml file for a java_sdk_library.
 // Not to be used directly by users. java_sdk_library internally uses this.
 func sdkLibraryXmlFactory() android.Module {
@@ -882,6 +1054,12 @@ func (module *sdkLibraryXml) permissionsContents(ctx android.ModuleContext) stri
 	minSdkAttr := formattedOptionalSdkLevelAttribute(ctx, "min-device-sdk", module.properties.Min_device_sdk)
 	maxSdkAttr := formattedOptionalSdkLevelAttribute(ctx, "max-device-sdk", module.properties.Max_device_sdk)
 	dependenciesAttr := formattedDependenciesAttribute(module.properties.Uses_libs_dependencies.GetOrDefault(ctx, nil))
+	var version *string
+	if module.properties.Lib_version != nil {
+		version = proptools.StringPtr(strconv.FormatInt(*module.properties.Lib_version, 10))
+	}
+	versionAttr := formattedOptionalAttribute("version", version)
+	certDigestAttr := formattedOptionalAttribute("cert-digest", module.properties.Lib_cert_digest)
 	// <library> is understood in all android versions whereas <apex-library> is only understood from API T (and ignored before that).
 	// similarly, min_device_sdk is only understood from T. So if a library is using that, we need to use the apex-library to make sure this library is not loaded before T
 	var libraryTag string
@@ -916,6 +1094,8 @@ func (module *sdkLibraryXml) permissionsContents(ctx android.ModuleContext) stri
 		minSdkAttr,
 		maxSdkAttr,
 		dependenciesAttr,
+		versionAttr,
+		certDigestAttr,
 		"    />\n",
 		"</permissions>\n",
 	}, "")
@@ -925,21 +1105,41 @@ func (module *sdkLibraryXml) GenerateAndroidBuildActions(ctx android.ModuleConte
 	apexInfo, _ := android.ModuleProvider(ctx, android.ApexInfoProvider)
 	module.hideApexVariantFromMake = !apexInfo.IsForPlatform()
 
-	libName := proptools.String(module.properties.Lib_name)
+	fileName := module.fileName(ctx)
 	module.selfValidate(ctx)
 	xmlContent := module.permissionsContents(ctx)
 
-	module.outputFilePath = android.PathForModuleOut(ctx, libName+".xml").OutputPath
+	module.outputFilePath = android.PathForModuleOut(ctx, fileName).OutputPath
 	android.WriteFileRuleVerbatim(ctx, module.outputFilePath, xmlContent)
 
 	module.installDirPath = android.PathForModuleInstall(ctx, "etc", module.SubDir())
-	ctx.PackageFile(module.installDirPath, libName+".xml", module.outputFilePath)
+	ctx.PackageFile(module.installDirPath, fileName, module.outputFilePath)
+
+	deviceDir := path.Join("/", module.installDirPath.Partition(), "etc", module.SubDir())
+	if !apexInfo.IsForPlatform() {
+		deviceDir = path.Join("/apex", apexInfo.BaseApexName, "etc", module.SubDir())
+	}
+	android.SetProvider(ctx, SdkLibraryXmlInfoProvider, SdkLibraryXmlInfo{
+		FileName:  fileName,
+		DeviceDir: deviceDir,
+	})
 
 	ctx.SetOutputFiles(android.OutputPaths{module.outputFilePath}.Paths(), "")
 
 	etc.SetCommonPrebuiltEtcInfo(ctx, module)
 }
 
+// The name of the permissions xml file.
+func (module *sdkLibraryXml) fileName(ctx android.ModuleContext) string {
+	if fileName := proptools.String(module.properties.Xml_filename); fileName != "" {
+		if path.Base(fileName) != fileName || path.Ext(fileName) != ".xml" {
+			ctx.PropertyErrorf("xml_filename", "must be a file name ending in .xml, got %q", fileName)
+		}
+		return fileName
+	}
+	return proptools.String(module.properties.Lib_name) + ".xml"
+}
+
 func (module *sdkLibraryXml) AndroidMkEntries() []android.AndroidMkEntries {
 	if module.hideApexVariantFromMake {
 		return []android.AndroidMkEntries{{
@@ -1034,3 +1234,47 @@ func (module *sdkLibraryXml) validateOnBootclasspathBeforeRequirements(ctx andro
 		}
 	}
 }
+
+// sdkLibraryXmlFilenamesSingleton checks that the permissions xml files of the java_sdk_library
+// modules have unique names. The files installed in etc/permissions of the partitions and of the
+// APEXes are all read into the same system config, so two libraries with the same name collide
+// even when they are installed in different APEXes.
+type sdkLibraryXmlFilenamesSingleton struct{}
+
+func (s *sdkLibraryXmlFilenamesSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	// The libraries and the directories that install each permissions xml file, the variants of a
+	// library install the same file in different directories.
+	libraries := make(map[string]map[string][]string)
+	ctx.VisitAllModuleProxies(func(module android.ModuleProxy) {
+		commonInfo := android.OtherModulePointerProviderOrDefault(ctx, module, android.CommonModuleInfoProvider)
+		if !commonInfo.Enabled || !commonInfo.ExportedToMake {
+			return
+		}
+		if info, ok := android.OtherModuleProvider(ctx, module, SdkLibraryXmlInfoProvider); ok {
+			library := "//" + ctx.ModuleDir(module) + ":" + strings.TrimSuffix(ctx.ModuleName(module), sdkXmlFileSuffix)
+			if libraries[info.FileName] == nil {
+				libraries[info.FileName] = make(map[string][]string)
+			}
+			libraries[info.FileName][library] = append(libraries[info.FileName][library], info.DeviceDir)
+		}
+	})
+
+	for _, fileName := range android.SortedKeys(libraries) {
+		if len(libraries[fileName]) < 2 {
+			continue
+		}
+		var installs []string
+		for _, library := range android.SortedKeys(libraries[fileName]) {
+			for _, dir := range android.SortedUniqueStrings(libraries[fileName][library]) {
+				installs = append(installs, fmt.Sprintf("%s installs %s", library, path.Join(dir, fileName)))
+			}
+		}
+		ctx.Errorf("the permissions xml file %q of multiple java_sdk_library modules collides:\n    %s\n"+
+			"set xml_filename on the libraries to give their permissions xml files unique names",
+			fileName, strings.Join(installs, "\n    "))
+	}
+}
+
+func sdkLibraryXmlFilenamesSingletonFactory() android.Singleton {
+	return &sdkLibraryXmlFilenamesSingleton{}
+}
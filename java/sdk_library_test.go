@@ -22,6 +22,8 @@ import (
 	"testing"
 
 	"android/soong/android"
+
+	"github.com/google/blueprint/proptools"
 )
 
 func TestJavaSdkLibrary(t *testing.T) {
@@ -237,6 +239,47 @@ func TestJavaSdkLibrary_UpdatableLibrary(t *testing.T) {
 	android.AssertStringDoesNotContain(t, "foo.xml contents", fooPermissionsContents, `max-device-sdk`)
 }
 
+func TestJavaSdkLibrary_HiddenApiMaxTargetSdk(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithJavaSdkLibraryFiles,
+		FixtureWithLastReleaseApis("foo"),
+		android.FixtureAddFile("max-target-q.txt", nil),
+	).RunTestWithBp(t, `
+		java_sdk_library {
+			name: "foo",
+			srcs: ["a.java"],
+			api_packages: ["foo"],
+			hidden_api: {
+				max_target_q: ["max-target-q.txt"],
+			},
+			hidden_api_max_target_sdk: {
+				q: ["Lfoo/Foo;->bar()V"],
+				o_low_priority: ["Lfoo/Foo;->baz:I", "Lfoo/Foo;->baz:I"],
+			},
+		}
+	`)
+
+	foo := result.ModuleForTests(t, "foo", "android_common")
+	info, _ := android.OtherModuleProvider(result, foo.Module(), hiddenAPIPropertyInfoProvider)
+
+	maxTargetQ := foo.Output("hiddenapi/max_target_q.txt")
+	android.AssertPathsRelativeToTopEquals(t, "max_target_q",
+		[]string{"max-target-q.txt", maxTargetQ.Output.RelativeToTop().String()}, info.FlagFilesByCategory[hiddenAPIFlagFileCategoryMaxTargetQ])
+	android.AssertStringEquals(t, "max_target_q contents", "Lfoo/Foo;->bar()V\n",
+		android.ContentFromFileRuleForTests(t, result.TestContext, maxTargetQ))
+
+	maxTargetO := foo.Output("hiddenapi/max_target_o_low_priority.txt")
+	android.AssertPathsRelativeToTopEquals(t, "max_target_o_low_priority",
+		[]string{maxTargetO.Output.RelativeToTop().String()}, info.FlagFilesByCategory[hiddenAPIFlagFileCategoryMaxTargetOLowPriority])
+	android.AssertStringEquals(t, "max_target_o_low_priority contents", "Lfoo/Foo;->baz:I\n",
+		android.ContentFromFileRuleForTests(t, result.TestContext, maxTargetO))
+
+	android.AssertPathsRelativeToTopEquals(t, "max_target_p", nil,
+		info.FlagFilesByCategory[hiddenAPIFlagFileCategoryMaxTargetP])
+}
+
 func TestJavaSdkLibrary_UpdatableLibrary_Validation_ValidVersion(t *testing.T) {
 	t.Parallel()
 	android.GroupFixturePreparers(
@@ -376,6 +419,156 @@ func TestJavaSdkLibrary_UpdatableLibrary_usesNewTag(t *testing.T) {
 	android.AssertStringDoesNotContain(t, "foo.xml contents", fooUpdatableContents, `<library`)
 }
 
+func TestJavaSdkLibrary_ProvidesUsesLibAttributes(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithJavaSdkLibraryFiles,
+		FixtureWithLastReleaseApis("foo"),
+	).RunTestWithBp(t,
+		`
+		java_sdk_library {
+			name: "foo",
+			srcs: ["a.java", "b.java"],
+			api_packages: ["foo"],
+			provides_uses_lib_version: 3,
+			provides_uses_lib_cert_digest: "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		}
+`)
+	foo := result.ModuleForTests(t, "foo.xml", "android_common").Output("foo.xml")
+	fooContents := android.ContentFromFileRuleForTests(t, result.TestContext, foo)
+	android.AssertStringDoesContain(t, "foo.xml contents", fooContents, `version="3"`)
+	android.AssertStringDoesContain(t, "foo.xml contents", fooContents,
+		`cert-digest="0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"`)
+}
+
+func TestJavaSdkLibrary_XmlFilename(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithJavaSdkLibraryFiles,
+		FixtureWithLastReleaseApis("foo"),
+	).RunTestWithBp(t,
+		`
+		java_sdk_library {
+			name: "foo",
+			srcs: ["a.java", "b.java"],
+			api_packages: ["foo"],
+			xml_filename: "com.android.foo.xml",
+		}
+`)
+	fooXml := result.ModuleForTests(t, "foo.xml", "android_common")
+	foo := fooXml.Output("com.android.foo.xml")
+	fooContents := android.ContentFromFileRuleForTests(t, result.TestContext, foo)
+	android.AssertStringDoesContain(t, "com.android.foo.xml contents", fooContents, `name="foo"`)
+	if fooXml.MaybeOutput("foo.xml").Rule != nil {
+		t.Errorf("expected no foo.xml output when xml_filename is set")
+	}
+
+	info, _ := android.OtherModuleProvider(result.OtherModuleProviderAdaptor(), fooXml.Module(), SdkLibraryXmlInfoProvider)
+	android.AssertStringEquals(t, "file name", "com.android.foo.xml", info.FileName)
+	android.AssertStringEquals(t, "device dir", "/system/etc/permissions", info.DeviceDir)
+}
+
+func TestJavaSdkLibrary_XmlFilename_Invalid(t *testing.T) {
+	t.Parallel()
+	android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithJavaSdkLibraryFiles,
+		FixtureWithLastReleaseApis("foo"),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`xml_filename: must be a file name ending in .xml, got "permissions/foo.txt"`,
+	)).RunTestWithBp(t,
+		`
+		java_sdk_library {
+			name: "foo",
+			srcs: ["a.java", "b.java"],
+			api_packages: ["foo"],
+			xml_filename: "permissions/foo.txt",
+		}
+`)
+}
+
+func TestJavaSdkLibrary_XmlFilename_Collision(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_sdk_library {
+			name: "foo",
+			srcs: ["a.java", "b.java"],
+			api_packages: ["foo"],
+			xml_filename: %s,
+		}
+
+		java_sdk_library {
+			name: "bar",
+			srcs: ["a.java", "b.java"],
+			api_packages: ["bar"],
+		}
+`
+	preparer := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithJavaSdkLibraryFiles,
+		FixtureWithLastReleaseApis("foo", "bar"),
+	)
+
+	preparer.ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`the permissions xml file "bar.xml" of multiple java_sdk_library modules collides:\s+`+
+			`//\.:bar installs /system/etc/permissions/bar.xml\s+`+
+			`//\.:foo installs /system/etc/permissions/bar.xml`,
+	)).RunTestWithBp(t, fmt.Sprintf(bp, `"bar.xml"`))
+
+	// Giving the permissions xml files unique names resolves the conflict.
+	preparer.RunTestWithBp(t, fmt.Sprintf(bp, `"com.android.foo.xml"`))
+}
+
+func TestJavaSdkLibrary_ImplMinSdkVersion(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithJavaSdkLibraryFiles,
+		FixtureWithLastReleaseApis("foo"),
+	).RunTestWithBp(t, `
+		java_sdk_library {
+			name: "foo",
+			srcs: ["a.java"],
+			min_sdk_version: "30",
+			impl_min_sdk_version: "33",
+			impl_only_static_libs: ["bar"],
+		}
+		java_library {
+			name: "bar",
+			srcs: ["a.java"],
+			sdk_version: "current",
+			min_sdk_version: "33",
+		}
+	`)
+
+	foo := result.ModuleForTests(t, "foo", "android_common").Module().(*SdkLibrary)
+	android.AssertStringEquals(t, "foo min_sdk_version", "30",
+		proptools.String(foo.overridableProperties.Min_sdk_version))
+	fooImpl := result.ModuleForTests(t, "foo.impl", "android_common").Module().(*Library)
+	android.AssertStringEquals(t, "foo.impl min_sdk_version", "33",
+		proptools.String(fooImpl.overridableProperties.Min_sdk_version))
+
+	t.Run("lower than min_sdk_version", func(t *testing.T) {
+		t.Parallel()
+		android.GroupFixturePreparers(
+			prepareForJavaTest,
+			PrepareForTestWithJavaSdkLibraryFiles,
+			FixtureWithLastReleaseApis("foo"),
+		).ExtendWithErrorHandler(android.FixtureExpectsOneErrorPattern(
+			`impl_min_sdk_version: 29 can not be lower than min_sdk_version 30`,
+		)).RunTestWithBp(t, `
+			java_sdk_library {
+				name: "foo",
+				srcs: ["a.java"],
+				min_sdk_version: "30",
+				impl_min_sdk_version: "29",
+			}
+		`)
+	})
+}
+
 func TestJavaSdkLibrary_StubOrImplOnlyLibs(t *testing.T) {
 	t.Parallel()
 	result := android.GroupFixturePreparers(
@@ -513,6 +706,69 @@ func TestJavaSdkLibrary_AccessOutputFiles_NoAnnotations(t *testing.T) {
 		`)
 }
 
+func TestJavaSdkLibrary_KotlinStubs(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithJavaSdkLibraryFiles,
+		FixtureWithLastReleaseApis("foo"),
+	).RunTestWithBp(t, `
+		java_sdk_library {
+			name: "foo",
+			srcs: ["a.java"],
+			api_packages: ["foo"],
+			annotations_enabled: true,
+			kotlin_stubs: true,
+			public: {
+				enabled: true,
+			},
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.kt"],
+			libs: ["foo.stubs.kotlin"],
+		}
+		`)
+
+	metalavaCommand := func(name string) string {
+		manifest := result.ModuleForTests(t, name, "android_common").Output("metalava.sbox.textproto")
+		return String(android.RuleBuilderSboxProtoForTests(t, result.TestContext, manifest).Commands[0].Command)
+	}
+
+	// The nullability annotations of the stubs are migrated from the previous API, but not the
+	// ones of the Kotlin stubs.
+	android.AssertStringDoesContain(t, "foo.stubs.source metalava command", metalavaCommand("foo.stubs.source"), "--migrate-nullness ")
+	kotlinStubsCommand := metalavaCommand("foo.stubs.source.kotlin")
+	android.AssertStringDoesContain(t, "foo.stubs.source.kotlin metalava command", kotlinStubsCommand, "--include-annotations")
+	android.AssertStringDoesNotContain(t, "foo.stubs.source.kotlin metalava command", kotlinStubsCommand, "--migrate-nullness ")
+
+	bar := result.ModuleForTests(t, "bar", "android_common")
+	barKotlincClasspath := android.ContentFromFileRuleForTests(t, result.TestContext, bar.Output("kotlinc/classpath.rsp"))
+	android.AssertStringDoesContain(t, "bar kotlinc classpath", barKotlincClasspath, "foo.stubs.kotlin.jar")
+}
+
+func TestJavaSdkLibrary_KotlinStubsWithoutAnnotations(t *testing.T) {
+	t.Parallel()
+	android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithJavaSdkLibraryFiles,
+		FixtureWithLastReleaseApis("foo"),
+	).
+		ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(`kotlin_stubs: requires annotations_enabled to be set`)).
+		RunTestWithBp(t, `
+		java_sdk_library {
+			name: "foo",
+			srcs: ["a.java"],
+			api_packages: ["foo"],
+			kotlin_stubs: true,
+			public: {
+				enabled: true,
+			},
+		}
+		`)
+}
+
 func TestJavaSdkLibrary_AccessOutputFiles_MissingScope(t *testing.T) {
 	t.Parallel()
 	android.GroupFixturePreparers(
@@ -1222,6 +1478,54 @@ func TestJavaSdkLibraryDist(t *testing.T) {
 	}
 }
 
+func TestJavaSdkLibraryDist_ReleaseConfig(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaBuildComponents,
+		PrepareForTestWithJavaDefaultModules,
+		PrepareForTestWithJavaSdkLibraryFiles,
+		FixtureWithLastReleaseApis("sdklib"),
+		android.PrepareForTestWithBuildFlag("RELEASE_EXPORTABLE_STUBS_RELEASE_CONFIG_DIST", "true"),
+		android.FixtureMergeEnv(map[string]string{
+			"TARGET_RELEASE": "trunk_staging",
+		}),
+	).RunTestWithBp(t, `
+		java_sdk_library {
+			name: "sdklib",
+			srcs: ["foo.java"],
+			dist_group: "foo",
+		}
+	`)
+
+	distDirs := func(dists []android.Dist) []string {
+		var dirs []string
+		for _, dist := range dists {
+			dirs = append(dirs, String(dist.Dir)+"/"+String(dist.Dest)+" "+String(dist.Tag))
+		}
+		return dirs
+	}
+
+	// The "everything" stubs are dist'ed to the default dist tree as the exportable stubs are not
+	// dist'ed by default, and the exportable stubs are dist'ed to the dist tree of the release config.
+	stubs := result.ModuleForTests(t, apiScopePublic.stubsLibraryModuleName("sdklib"), "android_common").Module().(*Library)
+	android.AssertArrayString(t, "stubs dists", []string{
+		"apistubs/foo/public/sdklib.jar .jar",
+	}, distDirs(stubs.Dists()))
+
+	exportableStubs := result.ModuleForTests(t, apiScopePublic.exportableStubsLibraryModuleName("sdklib"), "android_common").Module().(*Library)
+	android.AssertArrayString(t, "exportable stubs dists", []string{
+		"apistubs/trunk_staging/foo/public/sdklib.jar .jar",
+	}, distDirs(exportableStubs.Dists()))
+
+	stubsSources := result.ModuleForTests(t, apiScopePublic.stubsSourceModuleName("sdklib"), "android_common").Module().(*Droidstubs)
+	android.AssertArrayString(t, "stubs sources dists", []string{
+		"apistubs/foo/public/api/sdklib.txt .api.txt",
+		"apistubs/trunk_staging/foo/public/api/sdklib.txt .exportable.api.txt",
+		"apistubs/foo/public/api/sdklib-removed.txt .removed-api.txt",
+		"apistubs/trunk_staging/foo/public/api/sdklib-removed.txt .exportable.removed-api.txt",
+	}, distDirs(stubsSources.Dists()))
+}
+
 func TestSdkLibrary_CheckMinSdkVersion(t *testing.T) {
 	t.Parallel()
 	preparer := android.GroupFixturePreparers(
@@ -1363,6 +1667,53 @@ func TestJavaSdkLibrary_Scope_Libs_PassedToDroidstubs(t *testing.T) {
 	android.AssertStringListContains(t, "foo stubs should depend on bar-lib", fooStubsSources.Javadoc.properties.Libs.GetOrDefault(eval, nil), "bar-lib")
 }
 
+func TestJavaSdkLibrary_Scope_DroiddocOptions_PassedToDroidstubs(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithJavaSdkLibraryFiles,
+		FixtureWithLastReleaseApis("foo"),
+		android.FixtureAddTextFile("system-options.txt", ""),
+	).RunTestWithBp(t, `
+		java_sdk_library {
+			name: "foo",
+			srcs: ["a.java"],
+			droiddoc_options: ["--shared-option"],
+			public: {
+				enabled: true,
+				droiddoc_options: ["--hide-annotation android.annotation.Public"],
+			},
+			system: {
+				enabled: true,
+				droiddoc_options: ["@$(location system-options.txt)"],
+				droiddoc_option_files: ["system-options.txt"],
+			},
+		}
+		`)
+
+	checkArgs := func(module string, expected, unexpected []string) {
+		t.Helper()
+		stubsSources := result.ModuleForTests(t, module, "android_common").Module().(*Droidstubs)
+		args := String(stubsSources.Javadoc.properties.Args)
+		for _, arg := range expected {
+			android.AssertStringDoesContain(t, module+" args", args, arg)
+		}
+		for _, arg := range unexpected {
+			android.AssertStringDoesNotContain(t, module+" args", args, arg)
+		}
+	}
+	checkArgs("foo.stubs.source",
+		[]string{"--shared-option", "--hide-annotation android.annotation.Public"},
+		[]string{"system-options.txt"})
+	checkArgs("foo.stubs.source.system",
+		[]string{"--shared-option", "@$(location system-options.txt)"},
+		[]string{"android.annotation.Public"})
+
+	systemStubsSources := result.ModuleForTests(t, "foo.stubs.source.system", "android_common").Module().(*Droidstubs)
+	android.AssertArrayString(t, "system arg_files", []string{"system-options.txt"},
+		systemStubsSources.Javadoc.properties.Arg_files)
+}
+
 func TestJavaSdkLibrary_ApiLibrary(t *testing.T) {
 	t.Parallel()
 	result := android.GroupFixturePreparers(
@@ -1414,6 +1765,104 @@ func TestJavaSdkLibrary_ApiLibrary(t *testing.T) {
 	}
 }
 
+func TestJavaSdkLibrary_ApiLibraryContributionConflicts(t *testing.T) {
+	t.Parallel()
+	bp := `
+		java_sdk_library {
+			name: "foo",
+			srcs: ["a.java"],
+			api_packages: ["foo"],
+		}
+
+		java_api_library {
+			name: "combined",
+			api_surface: "public",
+			api_contributions: [
+				"foo.stubs.source.api.contribution",
+				"bar.stubs.source.api.contribution",
+			],
+			stubs_type: "everything",
+			%[1]s
+		}
+
+		java_api_library {
+			name: "duplicated",
+			api_surface: "public",
+			api_contributions: [
+				"foo.stubs.source.api.contribution",
+				"foo.stubs.source.api.contribution",
+			],
+			stubs_type: "everything",
+			%[1]s
+		}
+	`
+	preparer := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithJavaSdkLibraryFiles,
+		FixtureWithLastReleaseApis("foo", "bar"),
+		android.FixtureMergeMockFs(android.MockFS{
+			"bar/api/current.txt": nil,
+			"bar/api/removed.txt": nil,
+			"bar/b.java":          nil,
+			"bar/Android.bp": []byte(`
+				java_sdk_library {
+					name: "bar",
+					srcs: ["b.java"],
+					api_packages: ["bar"],
+				}
+			`),
+		}),
+	)
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+		result := preparer.RunTestWithBp(t, fmt.Sprintf(bp, ""))
+
+		// Without a merge strategy the contributions are not checked or deduplicated.
+		combined := result.ModuleForTests(t, "combined", "android_common")
+		if combined.MaybeRule("check_api_contributions").Rule != nil {
+			t.Errorf("expected no check_api_contributions rule without a merge strategy")
+		}
+		duplicated := result.ModuleForTests(t, "duplicated", "android_common")
+		manifest := duplicated.Output("metalava.sbox.textproto")
+		sboxProto := android.RuleBuilderSboxProtoForTests(t, result.TestContext, manifest)
+		android.AssertStringDoesContain(t, "api files passed to metalava",
+			sboxProto.Commands[0].GetCommand(), "--source-files api/current.txt api/current.txt --")
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		result := preparer.ExtendWithErrorHandler(android.FixtureExpectsOneErrorPattern(
+			`module "duplicated".*api_contributions: .* is contributed more than once`)).
+			RunTestWithBp(t, fmt.Sprintf(bp, `api_contributions_merge_strategy: "error",`))
+
+		// Contributions from different modules to the same surface are checked for conflicting classes.
+		combined := result.ModuleForTests(t, "combined", "android_common")
+		check := combined.Rule("check_api_contributions")
+		android.AssertStringListContains(t, "checked api files", check.Implicits.Strings(), "api/current.txt")
+		android.AssertStringListContains(t, "checked api files", check.Implicits.Strings(), "bar/api/current.txt")
+		android.AssertStringDoesContain(t, "api surface passed to check", check.RuleParams.Command, "--api_file public:")
+		metalava := combined.Output("metalava.sbox.textproto")
+		android.AssertStringListContains(t, "metalava validations",
+			metalava.Validations.Strings(), check.Output.String())
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		t.Parallel()
+		result := preparer.RunTestWithBp(t, fmt.Sprintf(bp, `api_contributions_merge_strategy: "merge",`))
+
+		// With the merge strategy duplicates are dropped and not checked.
+		duplicated := result.ModuleForTests(t, "duplicated", "android_common")
+		if duplicated.MaybeRule("check_api_contributions").Rule != nil {
+			t.Errorf("expected no check_api_contributions rule with the merge strategy")
+		}
+		manifest := duplicated.Output("metalava.sbox.textproto")
+		sboxProto := android.RuleBuilderSboxProtoForTests(t, result.TestContext, manifest)
+		android.AssertStringDoesContain(t, "api files passed to metalava",
+			sboxProto.Commands[0].GetCommand(), "--source-files api/current.txt --")
+	})
+}
+
 func TestStaticDepStubLibrariesVisibility(t *testing.T) {
 	t.Parallel()
 	android.GroupFixturePreparers(
@@ -1669,6 +2118,103 @@ func TestMultipleSdkLibraryPrebuilts(t *testing.T) {
 	}
 }
 
+// test that the annotations zip of a java_sdk_library with multiple prebuilt versions is resolved to
+// the version selected by apex_contributions, ignoring the legacy prefer property
+func TestMultipleSdkLibraryPrebuilts_AnnotationsZip(t *testing.T) {
+	t.Parallel()
+	bp := `
+		apex_contributions {
+			name: "my_mainline_module_contributions",
+			api_domain: "my_mainline_module",
+			contents: ["%s"],
+		}
+		java_sdk_library {
+			name: "sdklib",
+			srcs: ["a.java"],
+			sdk_version: "none",
+			system_modules: "none",
+			annotations_enabled: true,
+			public: {
+				enabled: true,
+			},
+		}
+		java_sdk_library_import {
+			name: "sdklib.v1", //prebuilt
+			source_module_name: "sdklib",
+			public: {
+				jars: ["a.jar"],
+				stub_srcs: ["a.java"],
+				current_api: "current.txt",
+				removed_api: "removed.txt",
+				annotations: "v1/annotations.zip",
+			},
+		}
+		java_sdk_library_import {
+			name: "sdklib.v2", //prebuilt
+			source_module_name: "sdklib",
+			prefer: true,
+			public: {
+				jars: ["a.jar"],
+				stub_srcs: ["a.java"],
+				current_api: "current.txt",
+				removed_api: "removed.txt",
+				annotations: "v2/annotations.zip",
+			},
+		}
+		// rdeps
+		java_library {
+			name: "mymodule",
+			srcs: ["a.java"],
+			java_resources: [":sdklib{.public.annotations.zip}"],
+		}
+	`
+	testCases := []struct {
+		desc                   string
+		selectedDependencyName string
+		expectedAnnotationsZip string
+	}{
+		{
+			desc:                   "Source library is selected using apex_contributions",
+			selectedDependencyName: "sdklib",
+			expectedAnnotationsZip: "out/soong/.intermediates/sdklib.stubs.source/",
+		},
+		{
+			desc:                   "Prebuilt library v1 is selected over the preferred v2 using apex_contributions",
+			selectedDependencyName: "prebuilt_sdklib.v1",
+			expectedAnnotationsZip: "v1/annotations.zip",
+		},
+		{
+			desc:                   "Prebuilt library v2 is selected using apex_contributions",
+			selectedDependencyName: "prebuilt_sdklib.v2",
+			expectedAnnotationsZip: "v2/annotations.zip",
+		},
+	}
+
+	fixture := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithJavaSdkLibraryFiles,
+		FixtureWithLastReleaseApis("sdklib", "sdklib.v1", "sdklib.v2"),
+		android.PrepareForTestWithBuildFlag("RELEASE_APEX_CONTRIBUTIONS_ADSERVICES", "my_mainline_module_contributions"),
+	)
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+			result := fixture.RunTestWithBp(t, fmt.Sprintf(bp, tc.selectedDependencyName))
+
+			resources := result.ModuleForTests(t, "mymodule", "android_common").Output("res/mymodule.jar")
+			var annotationsZips []string
+			for _, input := range resources.Implicits.Strings() {
+				if strings.HasSuffix(input, "annotations.zip") {
+					annotationsZips = append(annotationsZips, input)
+				}
+			}
+			android.AssertIntEquals(t, "number of annotations zips", 1, len(annotationsZips))
+			android.AssertStringDoesContain(t, "annotations zip", annotationsZips[0], tc.expectedAnnotationsZip)
+		})
+	}
+}
+
 func TestStubLinkType(t *testing.T) {
 	t.Parallel()
 	android.GroupFixturePreparers(
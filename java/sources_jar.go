@@ -0,0 +1,69 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+// When dist_srcjar is set, a <stem>-sources.jar containing the Java and Kotlin sources of the module,
+// including the generated ones, is created and dist'd next to the class jar for every dist of the
+// module's default output, so that IDEs and consumers of the prebuilt jars can attach the sources.
+
+const sourcesJarTag = "-sources.jar"
+
+// buildSourcesJar creates the sources jar of the module from the Java and Kotlin source files in
+// srcFiles and the source jars in srcJars, and dists it.
+func (j *Module) buildSourcesJar(ctx android.ModuleContext, srcFiles, srcJars android.Paths) {
+	sources := append(srcFiles.FilterByExt(".java"), srcFiles.FilterByExt(".kt")...)
+
+	jars := android.Paths{}
+	if len(sources) > 0 {
+		localSourcesJar := android.PathForModuleOut(ctx, "sources", "local-sources.jar")
+		TransformResourcesToJar(ctx, localSourcesJar, resourcePathsToJarArgs(sources), sources)
+		jars = append(jars, localSourcesJar)
+	}
+	jars = append(jars, srcJars...)
+
+	sourcesJar := android.PathForModuleOut(ctx, "sources", j.Stem()+sourcesJarTag)
+	TransformJarsToJar(ctx, sourcesJar, "for sources jar", jars, android.OptionalPath{},
+		false, nil, nil)
+	j.sourcesJar = sourcesJar
+
+	j.distSourcesJar(ctx)
+}
+
+// distSourcesJar dists the sources jar next to the class jar for each dist of the module's default
+// output, using the name the class jar is dist'ed as with the .jar extension replaced by
+// -sources.jar.
+func (j *Module) distSourcesJar(ctx android.ModuleContext) {
+	for _, dist := range j.Dists() {
+		if tag := proptools.String(dist.Tag); tag != "" && tag != android.DefaultDistTag && tag != ".jar" {
+			continue
+		}
+		dest, err := android.DistDest(ctx.Config(), dist, j.Stem()+".jar")
+		if err != nil {
+			// Invalid dists are reported by ModuleBase.GenerateBuildActions.
+			continue
+		}
+		ctx.DistForGoalsWithFilename(dist.Targets, j.sourcesJar,
+			strings.TrimSuffix(dest, filepath.Ext(dest))+sourcesJarTag)
+	}
+}
@@ -79,6 +79,44 @@ var (
 	},
 		"classpath", "outDir", "workDir")
 
+	jmodsToSystemModules = pctx.AndroidStaticRule("jmodsToSystemModules", blueprint.RuleParams{
+		Command: `rm -rf ${outDir} && ` +
+			`${config.JlinkCmd} --module-path ${modulePath} --add-modules ALL-MODULE-PATH --output ${outDir} ` +
+			// See jarsTosystemModules for why the system-modules jlink plugin is disabled.
+			`  --disable-plugin system-modules && ` +
+			`cp ${config.JrtFsJar} ${outDir}/lib/`,
+		CommandDeps: []string{
+			"${config.JlinkCmd}",
+			"${config.JrtFsJar}",
+		},
+	},
+		"modulePath", "outDir")
+
+	imageToSystemModules = pctx.AndroidStaticRule("imageToSystemModules", blueprint.RuleParams{
+		Command: `rm -rf ${outDir} && mkdir -p ${outDir} && ` +
+			`unzip -qoDD -d ${outDir} $in lib/modules release && ` +
+			`cp ${config.JrtFsJar} ${outDir}/lib/`,
+		CommandDeps: []string{
+			"${config.JrtFsJar}",
+		},
+	},
+		"outDir")
+
+	// Extracts the classes of all the modules in the lib/modules file of system modules into a
+	// header jar, for the tools that take the system modules as a bootclasspath of jars.
+	systemModulesHeaderJar = pctx.AndroidStaticRule("systemModulesHeaderJar", blueprint.RuleParams{
+		Command: `rm -rf ${workDir} && mkdir -p ${workDir} && ` +
+			`${config.JimageCmd} extract --dir ${workDir} $in && ` +
+			`find ${workDir} -name module-info.class -delete && ` +
+			`${config.SoongZipCmd} -jar -o $out $$(for m in ${workDir}/*/; do echo "-C $$m -D $$m"; done) && ` +
+			`rm -rf ${workDir}`,
+		CommandDeps: []string{
+			"${config.JimageCmd}",
+			"${config.SoongZipCmd}",
+		},
+	},
+		"workDir")
+
 	// Dependency tag that causes the added dependencies to be added as java_header_libs
 	// to the sdk/module_exports/snapshot. Dependencies that are added automatically via this tag are
 	// not automatically exported.
@@ -86,14 +124,8 @@ var (
 )
 
 func TransformJarsToSystemModules(ctx android.ModuleContext, jars android.Paths) (android.Path, android.Paths) {
-	outDir := android.PathForModuleOut(ctx, "system")
+	outDir, outputs := systemModulesOutputs(ctx)
 	workDir := android.PathForModuleOut(ctx, "modules")
-	outputFile := android.PathForModuleOut(ctx, "system/lib/modules")
-	outputs := android.WritablePaths{
-		outputFile,
-		android.PathForModuleOut(ctx, "system/lib/jrt-fs.jar"),
-		android.PathForModuleOut(ctx, "system/release"),
-	}
 
 	ctx.Build(pctx, android.BuildParams{
 		Rule:        jarsTosystemModules,
@@ -110,9 +142,76 @@ func TransformJarsToSystemModules(ctx android.ModuleContext, jars android.Paths)
 	return outDir, outputs.Paths()
 }
 
+// TransformJmodsToSystemModules links a set of prebuilt jmod files, which must include java.base,
+// into system modules in a runtime image using the jlink tool.
+func TransformJmodsToSystemModules(ctx android.ModuleContext, jmods android.Paths) (android.Path, android.Paths) {
+	outDir, outputs := systemModulesOutputs(ctx)
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        jmodsToSystemModules,
+		Description: "system modules from jmods",
+		Outputs:     outputs,
+		Inputs:      jmods,
+		Args: map[string]string{
+			"modulePath": strings.Join(jmods.Strings(), ":"),
+			"outDir":     outDir.String(),
+		},
+	})
+
+	return outDir, outputs.Paths()
+}
+
+// TransformImageToSystemModules extracts the system modules from a zip of a prebuilt runtime image
+// created by the jlink tool.
+func TransformImageToSystemModules(ctx android.ModuleContext, image android.Path) (android.Path, android.Paths) {
+	outDir, outputs := systemModulesOutputs(ctx)
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        imageToSystemModules,
+		Description: "system modules from image",
+		Outputs:     outputs,
+		Input:       image,
+		Args: map[string]string{
+			"outDir": outDir.String(),
+		},
+	})
+
+	return outDir, outputs.Paths()
+}
+
+// TransformSystemModulesToHeaderJar extracts the classes of the system modules in a runtime image,
+// given its lib/modules file, into a header jar.
+func TransformSystemModulesToHeaderJar(ctx android.ModuleContext, modules android.Path) android.Path {
+	headerJar := android.PathForModuleOut(ctx, "header", "system-modules.jar")
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        systemModulesHeaderJar,
+		Description: "system modules header jar",
+		Output:      headerJar,
+		Input:       modules,
+		Args: map[string]string{
+			"workDir": android.PathForModuleOut(ctx, "header", "classes").String(),
+		},
+	})
+
+	return headerJar
+}
+
+func systemModulesOutputs(ctx android.ModuleContext) (android.ModuleOutPath, android.WritablePaths) {
+	return android.PathForModuleOut(ctx, "system"), android.WritablePaths{
+		android.PathForModuleOut(ctx, "system/lib/modules"),
+		android.PathForModuleOut(ctx, "system/lib/jrt-fs.jar"),
+		android.PathForModuleOut(ctx, "system/release"),
+	}
+}
+
 // java_system_modules creates a system module from a set of java libraries that can
 // be referenced from the system_modules property. It must contain at a minimum the
 // java.base module which must include classes from java.lang amongst other java packages.
+//
+// Alternatively the system module can be created from prebuilt jmod files or a prebuilt
+// runtime image, e.g. to target an alternative JDK, in which case its header jar is extracted
+// from the runtime image.
 func SystemModulesFactory() android.Module {
 	module := &SystemModules{}
 	module.AddProperties(&module.properties)
@@ -122,7 +221,8 @@ func SystemModulesFactory() android.Module {
 }
 
 type SystemModulesProviderInfo struct {
-	// The aggregated header jars from all jars specified in the libs property.
+	// The aggregated header jars from all jars specified in the libs property, or the header jar
+	// extracted from the runtime image if the system modules are built from jmods or an image.
 	// Used when system module is added as a dependency to bootclasspath.
 	HeaderJars android.Paths
 
@@ -148,6 +248,16 @@ type SystemModules struct {
 type SystemModulesProperties struct {
 	// List of java library modules that should be included in the system modules
 	Libs []string
+
+	// List of prebuilt jmod files that are linked into the system modules instead of building
+	// them from libs, e.g. to target an alternative JDK.  Must include java.base, and the jmod
+	// files must have been created by the same version of the JDK as the jlink tool.
+	Jmods []string `android:"path"`
+
+	// A zip of a prebuilt runtime image created by the jlink tool that is used as the system
+	// modules instead of building them from libs.  Only lib/modules and release are extracted
+	// from the zip.
+	Image *string `android:"path"`
 }
 
 func (system *SystemModules) GenerateAndroidBuildActions(ctx android.ModuleContext) {
@@ -161,13 +271,39 @@ func (system *SystemModules) GenerateAndroidBuildActions(ctx android.ModuleConte
 		}
 	})
 
-	system.outputDir, system.outputDeps = TransformJarsToSystemModules(ctx, jars)
+	jmods := android.PathsForModuleSrc(ctx, system.properties.Jmods)
+	image := android.OptionalPathForModuleSrc(ctx, system.properties.Image)
+
+	sources := 0
+	for _, set := range []bool{len(system.properties.Libs) > 0, len(jmods) > 0, image.Valid()} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		ctx.ModuleErrorf("only one of libs, jmods and image may be set")
+		return
+	}
+
+	// The tools that don't support system modules, e.g. kotlinc and metalava, use the header jars
+	// as the bootclasspath instead, so they are extracted from the runtime image if there are no libs.
+	var extractedHeaderJars android.Paths
+	switch {
+	case len(jmods) > 0:
+		system.outputDir, system.outputDeps = TransformJmodsToSystemModules(ctx, jmods)
+		extractedHeaderJars = android.Paths{TransformSystemModulesToHeaderJar(ctx, system.outputDeps[0])}
+	case image.Valid():
+		system.outputDir, system.outputDeps = TransformImageToSystemModules(ctx, image.Path())
+		extractedHeaderJars = android.Paths{TransformSystemModulesToHeaderJar(ctx, system.outputDeps[0])}
+	default:
+		system.outputDir, system.outputDeps = TransformJarsToSystemModules(ctx, jars)
+	}
 
 	android.SetProvider(ctx, SystemModulesProvider, &SystemModulesProviderInfo{
-		HeaderJars:                     jars,
+		HeaderJars:                     append(jars, extractedHeaderJars...),
 		OutputDir:                      system.outputDir,
 		OutputDirDeps:                  system.outputDeps,
-		TransitiveStaticLibsHeaderJars: depset.New(depset.PREORDER, nil, transitiveStaticLibsHeaderJars),
+		TransitiveStaticLibsHeaderJars: depset.New(depset.PREORDER, extractedHeaderJars, transitiveStaticLibsHeaderJars),
 	})
 }
 
@@ -63,6 +63,84 @@ func TestJavaSystemModules(t *testing.T) {
 	android.AssertArrayString(t, "source system modules inputs", expectedSourcePaths, sourceInputs.RelativeToTop().Strings())
 }
 
+func TestJavaSystemModulesJmods(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureAddFile("jdk/java.base.jmod", nil),
+		android.FixtureAddFile("jdk/java.logging.jmod", nil),
+	).RunTestWithBp(t, `
+		java_system_modules {
+			name: "system-modules",
+			jmods: ["jdk/java.base.jmod", "jdk/java.logging.jmod"],
+		}
+	`)
+
+	rule := result.ModuleForTests(t, "system-modules", "android_common").Rule("jmodsToSystemModules")
+	android.AssertPathsRelativeToTopEquals(t, "jmods system modules inputs",
+		[]string{"jdk/java.base.jmod", "jdk/java.logging.jmod"}, rule.Inputs)
+	android.AssertStringEquals(t, "module path", "jdk/java.base.jmod:jdk/java.logging.jmod", rule.Args["modulePath"])
+	android.AssertPathsRelativeToTopEquals(t, "jmods system modules outputs", []string{
+		"out/soong/.intermediates/system-modules/android_common/system/lib/modules",
+		"out/soong/.intermediates/system-modules/android_common/system/lib/jrt-fs.jar",
+		"out/soong/.intermediates/system-modules/android_common/system/release",
+	}, rule.Outputs.Paths())
+
+	// The header jar is extracted from the linked runtime image for the tools that use the system
+	// modules as a bootclasspath.
+	module := result.ModuleForTests(t, "system-modules", "android_common")
+	headerJar := module.Rule("systemModulesHeaderJar")
+	android.AssertPathRelativeToTopEquals(t, "header jar input",
+		"out/soong/.intermediates/system-modules/android_common/system/lib/modules", headerJar.Input)
+	info, _ := android.OtherModuleProvider(result, module.Module(), SystemModulesProvider)
+	android.AssertPathsRelativeToTopEquals(t, "header jars",
+		[]string{"out/soong/.intermediates/system-modules/android_common/header/system-modules.jar"}, info.HeaderJars)
+	android.AssertPathsRelativeToTopEquals(t, "transitive header jars",
+		[]string{"out/soong/.intermediates/system-modules/android_common/header/system-modules.jar"},
+		info.TransitiveStaticLibsHeaderJars.ToList())
+}
+
+func TestJavaSystemModulesImage(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureAddFile("jdk/image.zip", nil),
+	).RunTestWithBp(t, `
+		java_system_modules {
+			name: "system-modules",
+			image: "jdk/image.zip",
+		}
+	`)
+
+	module := result.ModuleForTests(t, "system-modules", "android_common")
+	rule := module.Rule("imageToSystemModules")
+	android.AssertPathRelativeToTopEquals(t, "image system modules input", "jdk/image.zip", rule.Input)
+
+	headerJar := module.Rule("systemModulesHeaderJar")
+	android.AssertPathRelativeToTopEquals(t, "header jar input",
+		"out/soong/.intermediates/system-modules/android_common/system/lib/modules", headerJar.Input)
+	info, _ := android.OtherModuleProvider(result, module.Module(), SystemModulesProvider)
+	android.AssertPathsRelativeToTopEquals(t, "header jars",
+		[]string{"out/soong/.intermediates/system-modules/android_common/header/system-modules.jar"}, info.HeaderJars)
+}
+
+func TestJavaSystemModulesSourcesExclusive(t *testing.T) {
+	t.Parallel()
+	android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureAddFile("jdk/java.base.jmod", nil),
+		android.FixtureAddFile("jdk/image.zip", nil),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		"only one of libs, jmods and image may be set",
+	)).RunTestWithBp(t, `
+		java_system_modules {
+			name: "system-modules",
+			jmods: ["jdk/java.base.jmod"],
+			image: "jdk/image.zip",
+		}
+	`)
+}
+
 var addPrebuiltSystemModules = android.FixtureAddTextFile("prebuilts/Android.bp", `
 		java_system_modules_import {
 			name: "system-modules",
@@ -53,7 +53,7 @@ type platformSystemServerClasspathModule struct {
 
 func platformSystemServerClasspathFactory() android.Module {
 	m := &platformSystemServerClasspathModule{}
-	initClasspathFragment(m, SYSTEMSERVERCLASSPATH)
+	InitClasspathFragment(m, SYSTEMSERVERCLASSPATH)
 	android.InitAndroidArchModule(m, android.DeviceSupported, android.MultilibCommon)
 	return m
 }
@@ -63,18 +63,18 @@ func (m *platformSystemServerClasspathModule) UniqueApexVariations() bool {
 }
 
 func (p *platformSystemServerClasspathModule) AndroidMkEntries() (entries []android.AndroidMkEntries) {
-	return p.classpathFragmentBase().androidMkEntries()
+	return p.classpathFragmentBase().ClasspathFragmentAndroidMkEntries()
 }
 
 func (p *platformSystemServerClasspathModule) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	configuredJars := p.configuredJars(ctx)
-	classpathJars := configuredJarListToClasspathJars(ctx, configuredJars, p.classpathType)
+	classpathJars := ConfiguredJarListToClasspathJars(ctx, configuredJars, p.classpathType)
 	standaloneConfiguredJars := p.standaloneConfiguredJars(ctx)
-	standaloneClasspathJars := configuredJarListToClasspathJars(ctx, standaloneConfiguredJars, STANDALONE_SYSTEMSERVER_JARS)
+	standaloneClasspathJars := ConfiguredJarListToClasspathJars(ctx, standaloneConfiguredJars, STANDALONE_SYSTEMSERVER_JARS)
 	configuredJars = configuredJars.AppendList(&standaloneConfiguredJars)
 	classpathJars = append(classpathJars, standaloneClasspathJars...)
-	p.classpathFragmentBase().generateClasspathProtoBuildActions(ctx, configuredJars, classpathJars)
-	p.classpathFragmentBase().installClasspathProto(ctx)
+	p.classpathFragmentBase().GenerateClasspathProtoBuildActions(ctx, configuredJars, classpathJars)
+	p.classpathFragmentBase().InstallClasspathProto(ctx)
 }
 
 func (p *platformSystemServerClasspathModule) configuredJars(ctx android.ModuleContext) android.ConfiguredJarList {
@@ -111,13 +111,40 @@ type systemServerClasspathFragmentProperties struct {
 	//
 	// The order does not matter.
 	Standalone_contents proptools.Configurable[[]string] `android:"arch_variant"`
+
+	// What to do with a jar in PRODUCT_APEX_STANDALONE_SYSTEM_SERVER_JARS that belongs to the apex
+	// of this fragment but is not in standalone_contents, e.g. because only the other (source or
+	// prebuilt) version of the apex, which is not selected by apex_contributions, provides it.
+	// The apex of the fragment is the apex of the jars in contents and standalone_contents.
+	// One of:
+	// "skip": the jar is left out of the classpaths config of the fragment. This is the default.
+	// "error": an error is reported.
+	// "substitute": the jar is left out of the classpaths config and falls back to not being
+	// dexpreopted, i.e. its dexpreopt artifacts are not required to be installed.
+	Missing_standalone_jars_policy *string
+}
+
+// MissingStandaloneJarsInfo is provided by the systemserverclasspath_fragment that is selected
+// between the source and prebuilt versions of an apex if it substitutes missing standalone jars.
+type MissingStandaloneJarsInfo struct {
+	// The names of the jars in PRODUCT_APEX_STANDALONE_SYSTEM_SERVER_JARS that the apex does not
+	// provide, and that are therefore not dexpreopted.
+	Jars []string
 }
 
+var MissingStandaloneJarsInfoProvider = blueprint.NewProvider[MissingStandaloneJarsInfo]()
+
+const (
+	missingStandaloneJarsSkip       = "skip"
+	missingStandaloneJarsError      = "error"
+	missingStandaloneJarsSubstitute = "substitute"
+)
+
 func systemServerClasspathFactory() android.Module {
 	m := &SystemServerClasspathModule{}
 	m.AddProperties(&m.properties)
 	android.InitApexModule(m)
-	initClasspathFragment(m, SYSTEMSERVERCLASSPATH)
+	InitClasspathFragment(m, SYSTEMSERVERCLASSPATH)
 	android.InitAndroidArchModule(m, android.DeviceSupported, android.MultilibCommon)
 	return m
 }
@@ -132,12 +159,12 @@ func (s *SystemServerClasspathModule) GenerateAndroidBuildActions(ctx android.Mo
 	}
 
 	configuredJars := s.configuredJars(ctx)
-	classpathJars := configuredJarListToClasspathJars(ctx, configuredJars, s.classpathType)
+	classpathJars := ConfiguredJarListToClasspathJars(ctx, configuredJars, s.classpathType)
 	standaloneConfiguredJars := s.standaloneConfiguredJars(ctx)
-	standaloneClasspathJars := configuredJarListToClasspathJars(ctx, standaloneConfiguredJars, STANDALONE_SYSTEMSERVER_JARS)
+	standaloneClasspathJars := ConfiguredJarListToClasspathJars(ctx, standaloneConfiguredJars, STANDALONE_SYSTEMSERVER_JARS)
 	configuredJars = configuredJars.AppendList(&standaloneConfiguredJars)
 	classpathJars = append(classpathJars, standaloneClasspathJars...)
-	s.classpathFragmentBase().generateClasspathProtoBuildActions(ctx, configuredJars, classpathJars)
+	s.classpathFragmentBase().GenerateClasspathProtoBuildActions(ctx, configuredJars, classpathJars)
 	s.setPartitionInfoOfLibraries(ctx)
 }
 
@@ -200,6 +227,65 @@ func (s *SystemServerClasspathModule) standaloneConfiguredJars(ctx android.Modul
 
 	// TODO(jiakaiz): add a check to ensure that the contents are declared in make.
 
+	return s.applyMissingStandaloneJarsPolicy(ctx, jars)
+}
+
+// applyMissingStandaloneJarsPolicy applies missing_standalone_jars_policy to the jars of the apex
+// of the fragment that are in PRODUCT_APEX_STANDALONE_SYSTEM_SERVER_JARS but not in jars, and
+// returns the jars to add to the classpaths config. The substituted jars are provided in
+// MissingStandaloneJarsInfo so that dexpreopt_systemserver_check does not require their dexpreopt
+// artifacts.
+func (s *SystemServerClasspathModule) applyMissingStandaloneJarsPolicy(ctx android.ModuleContext, jars android.ConfiguredJarList) android.ConfiguredJarList {
+	policy := proptools.StringDefault(s.properties.Missing_standalone_jars_policy, missingStandaloneJarsSkip)
+	switch policy {
+	case missingStandaloneJarsSkip:
+		return jars
+	case missingStandaloneJarsError, missingStandaloneJarsSubstitute:
+	default:
+		ctx.PropertyErrorf("missing_standalone_jars_policy", "unknown policy %q, expected one of %q, %q or %q",
+			policy, missingStandaloneJarsSkip, missingStandaloneJarsError, missingStandaloneJarsSubstitute)
+		return jars
+	}
+
+	// Only the fragment that is selected between the source and prebuilt versions of the apex has
+	// to provide the jars.
+	if !android.IsModulePreferred(ctx.Module()) {
+		return jars
+	}
+
+	global := dexpreopt.GetGlobalConfig(ctx)
+	contents, _ := global.ApexSystemServerJars.Filter(gatherPossibleApexModuleNamesAndStems(ctx,
+		s.properties.Contents.GetOrDefault(ctx, nil), systemServerClasspathFragmentContentDepTag))
+	var apexes []string
+	for i := 0; i < contents.Len(); i++ {
+		apexes = append(apexes, contents.Apex(i))
+	}
+	for i := 0; i < jars.Len(); i++ {
+		apexes = append(apexes, jars.Apex(i))
+	}
+
+	standalone := global.ApexStandaloneSystemServerJars
+	var missing, substituted []string
+	for i := 0; i < standalone.Len(); i++ {
+		apex, jar := standalone.Apex(i), standalone.Jar(i)
+		if !android.InList(apex, apexes) || jars.ContainsJar(jar) {
+			continue
+		}
+		if policy == missingStandaloneJarsError {
+			missing = append(missing, apex+":"+jar)
+		} else {
+			substituted = append(substituted, jar)
+		}
+	}
+	if len(missing) > 0 {
+		ctx.PropertyErrorf("standalone_contents", "%q in PRODUCT_APEX_STANDALONE_SYSTEM_SERVER_JARS are missing "+
+			"from standalone_contents, set missing_standalone_jars_policy to skip or substitute them", missing)
+	}
+	if len(substituted) > 0 {
+		android.SetProvider(ctx, MissingStandaloneJarsInfoProvider, MissingStandaloneJarsInfo{
+			Jars: substituted,
+		})
+	}
 	return jars
 }
 
@@ -0,0 +1,97 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+// Test fixtures are classes, e.g. fakes and builders of test data, that the tests of a library
+// share with the tests of the libraries that depend on it, like the test fixtures of Gradle's
+// java-test-fixtures plugin. A java_library with a test_fixtures block creates a companion
+// <name>.test-fixtures library that is compiled against the library, and tests that list the
+// library in uses_test_fixtures statically include it.
+
+type testFixturesProperties struct {
+	Test_fixtures struct {
+		// list of source files of the test fixtures.
+		Srcs []string `android:"path"`
+
+		// list of java libraries the test fixtures are compiled against, in addition to this
+		// library.
+		Libs []string
+
+		// list of java libraries that are statically included in the test fixtures.
+		Static_libs []string
+	}
+}
+
+func testFixturesModuleName(name string) string {
+	return name + ".test-fixtures"
+}
+
+// createTestFixturesLibrary creates the <name>.test-fixtures library from the test_fixtures block.
+func (j *Library) createTestFixturesLibrary(ctx android.DefaultableHookContext) {
+	fixtures := j.testFixturesProperties.Test_fixtures
+	if len(fixtures.Srcs) == 0 {
+		if len(fixtures.Libs) > 0 || len(fixtures.Static_libs) > 0 {
+			ctx.PropertyErrorf("test_fixtures", "srcs must be set")
+		}
+		return
+	}
+
+	props := struct {
+		Name             *string
+		Srcs             []string
+		Libs             []string
+		Static_libs      []string
+		Host_supported   *bool
+		Device_supported *bool
+		Sdk_version      *string
+		System_modules   *string
+		Installable      *bool
+		Test_only        *bool
+	}{
+		Name:             proptools.StringPtr(testFixturesModuleName(ctx.ModuleName())),
+		Srcs:             fixtures.Srcs,
+		Libs:             append([]string{ctx.ModuleName()}, fixtures.Libs...),
+		Static_libs:      fixtures.Static_libs,
+		Host_supported:   proptools.BoolPtr(j.HostSupported()),
+		Device_supported: proptools.BoolPtr(j.DeviceSupported()),
+		Installable:      proptools.BoolPtr(false),
+		Test_only:        proptools.BoolPtr(true),
+	}
+	if j.DeviceSupported() {
+		props.Sdk_version = j.deviceProperties.Sdk_version
+		props.System_modules = j.deviceProperties.System_modules
+	}
+
+	ctx.CreateModule(LibraryFactory, &props)
+}
+
+// addTestFixturesDeps adds static dependencies on the test fixtures of the libraries in
+// uses_test_fixtures.
+func addTestFixturesDeps(ctx android.BottomUpMutatorContext, libs []string) {
+	for _, lib := range libs {
+		fixtures := testFixturesModuleName(lib)
+		if !ctx.OtherModuleExists(fixtures) && !ctx.Config().AllowMissingDependencies() {
+			ctx.PropertyErrorf("uses_test_fixtures", "%q does not have test_fixtures", lib)
+			continue
+		}
+		ctx.AddVariationDependencies(nil, staticLibTag, fixtures)
+	}
+}
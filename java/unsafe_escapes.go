@@ -0,0 +1,99 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// Some properties are escape hatches that silently disable checks, e.g.
+// unsafe_ignore_missing_latest_api disables the API compatibility checks of a java_sdk_library.
+// The modules that use them are listed in unsafe_escapes.csv, which is disted, so that their
+// use can be tracked. When EnforceUnsafeEscapesAllowlist is set, using one of them is an error
+// unless the module and property are listed in UnsafeEscapesAllowlist as "<module>:<property>",
+// so that new uses require explicit approval.
+
+const (
+	unsafeIgnoreMissingLatestApi = "unsafe_ignore_missing_latest_api"
+	skipPreprocessedApkChecks    = "skip_preprocessed_apk_checks"
+)
+
+type UnsafeEscapesInfo struct {
+	// The names of the unsafe escape hatch properties set by the module.
+	Escapes []string
+}
+
+var UnsafeEscapesInfoProvider = blueprint.NewProvider[UnsafeEscapesInfo]()
+
+// checkUnsafeEscapes records the unsafe escape hatch properties set by the current module for the
+// report, and reports an error for the ones that are not allowlisted if the allowlist is enforced.
+func checkUnsafeEscapes(ctx android.ModuleContext, escapes ...string) {
+	if len(escapes) == 0 {
+		return
+	}
+
+	if ctx.Config().EnforceUnsafeEscapesAllowlist() {
+		name := android.RemoveOptionalPrebuiltPrefix(ctx.ModuleName())
+		allowlist := ctx.Config().UnsafeEscapesAllowlist()
+		for _, escape := range escapes {
+			if !android.InList(name+":"+escape, allowlist) {
+				ctx.PropertyErrorf(escape, "is an unsafe escape hatch that requires approval, "+
+					"add %q to PRODUCT_UNSAFE_ESCAPES_ALLOWLIST to use it", name+":"+escape)
+			}
+		}
+	}
+
+	android.SetProvider(ctx, UnsafeEscapesInfoProvider, UnsafeEscapesInfo{
+		Escapes: escapes,
+	})
+}
+
+func unsafeEscapesReportPath(ctx android.PathContext) android.WritablePath {
+	return android.PathForOutput(ctx, "unsafe_escapes", "unsafe_escapes.csv")
+}
+
+// unsafeEscapesSingleton writes the unsafe escape hatch properties used by every module to a CSV
+// file.
+type unsafeEscapesSingleton struct{}
+
+func (s *unsafeEscapesSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	escapes := make(map[string]bool)
+	ctx.VisitAllModuleProxies(func(module android.ModuleProxy) {
+		if !android.OtherModulePointerProviderOrDefault(ctx, module, android.CommonModuleInfoProvider).Enabled {
+			return
+		}
+		if info, ok := android.OtherModuleProvider(ctx, module, UnsafeEscapesInfoProvider); ok {
+			// Variants of the same module share the same name and properties.
+			name := android.RemoveOptionalPrebuiltPrefix(ctx.ModuleName(module))
+			for _, escape := range info.Escapes {
+				escapes[name+","+escape] = true
+			}
+		}
+	})
+
+	lines := append([]string{"module,property"}, android.SortedKeys(escapes)...)
+
+	report := unsafeEscapesReportPath(ctx)
+	android.WriteFileRule(ctx, report, strings.Join(lines, "\n"))
+	ctx.DistForGoal("droidcore", report)
+}
+
+func unsafeEscapesSingletonFactory() android.Singleton {
+	return &unsafeEscapesSingleton{}
+}
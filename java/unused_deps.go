@@ -0,0 +1,168 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"fmt"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// When SOONG_UNUSED_DEPS_REPORT=true, every java module that compiles sources uses R8's
+// tracereferences tool to find the libs and static_libs dependencies that none of its own classes
+// reference. Each module writes the unused dependencies to unused_deps.txt, one
+// "<module> <property> <dependency>" line per dependency, and the classpath jars its classes do
+// reference to used_classpath.txt, one "<module> <property> <dependency> <jar>" line per jar. The
+// unusedDepsReport collects them into tree-wide reports that can drive the cleanup of libs and
+// static_libs. Modules that set enforce_used_libs are always checked, and fail the build if any of
+// their libs dependencies that is not listed in enforce_used_libs_exemptions is unused.
+
+type UnusedDepsInfo struct {
+	// The report of the unused dependencies of the module.
+	Report android.Path
+
+	// The record of the classpath jars that are referenced by the classes of the module.
+	UsedClasspath android.Path
+}
+
+var UnusedDepsInfoProvider = blueprint.NewProvider[UnusedDepsInfo]()
+
+// unusedDepsReport collects the unused dependencies of all java modules into a single report.
+var unusedDepsReport = &moduleReport[UnusedDepsInfo]{
+	env:      "SOONG_UNUSED_DEPS_REPORT",
+	provider: UnusedDepsInfoProvider,
+	goal:     "unused_deps_report",
+	dist:     true,
+	outputs: []moduleReportOutput[UnusedDepsInfo]{
+		{
+			description: "unused deps report",
+			path:        unusedDepsReportPath,
+			file:        func(info UnusedDepsInfo) android.Path { return info.Report },
+		},
+		{
+			description: "used classpath report",
+			path:        usedClasspathReportPath,
+			file:        func(info UnusedDepsInfo) android.Path { return info.UsedClasspath },
+		},
+	},
+}
+
+// buildUnusedDepsReport generates a report of the libs and static_libs dependencies of the current
+// module that are not referenced by the classes in localJars, which are the classes compiled from
+// the module's own sources. If enforceLibs is true it returns the validation that fails if any of
+// the libs dependencies except exemptLibs is unused, otherwise it returns nil.
+func buildUnusedDepsReport(ctx android.ModuleContext, localJars android.Paths, enforceLibs bool,
+	exemptLibs []string) android.Path {
+	type traced struct {
+		jar  android.Path
+		keep android.WritablePath
+	}
+	type candidate struct {
+		property string
+		name     string
+		traced   []traced
+	}
+	var candidates []candidate
+
+	ctx.VisitDirectDepsProxy(func(module android.ModuleProxy) {
+		var property string
+		switch ctx.OtherModuleDependencyTag(module) {
+		case libTag:
+			property = "libs"
+		case staticLibTag:
+			property = "static_libs"
+		default:
+			return
+		}
+		dep, ok := android.OtherModuleProvider(ctx, module, JavaInfoProvider)
+		if !ok {
+			return
+		}
+		name := ctx.OtherModuleName(module)
+		c := candidate{property: property, name: name}
+		for i, jar := range dep.HeaderJars {
+			keep := android.PathForModuleOut(ctx, "unused_deps", property, name, fmt.Sprintf("%d.keep", i))
+			TraceReferences(ctx, localJars, jar, nil, keep)
+			c.traced = append(c.traced, traced{jar: jar, keep: keep})
+		}
+		if len(c.traced) > 0 {
+			candidates = append(candidates, c)
+		}
+	})
+
+	// A jar is used if tracereferences generated keep rules for it, and a dependency is unused if
+	// none of its jars are used.
+	report := android.PathForModuleOut(ctx, "unused_deps", "unused_deps.txt")
+	usedClasspath := android.PathForModuleOut(ctx, "unused_deps", "used_classpath.txt")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().Text("echo -n >").Output(report)
+	rule.Command().Text("echo -n >").Output(usedClasspath)
+	for _, c := range candidates {
+		var keeps android.Paths
+		for _, t := range c.traced {
+			rule.Command().
+				Text("if [ -s").Input(t.keep).
+				Textf("]; then echo '%s %s %s %s' >> %s; fi",
+					ctx.ModuleName(), c.property, c.name, t.jar.String(), usedClasspath)
+			keeps = append(keeps, t.keep)
+		}
+		rule.Command().
+			Text("if [ -z \"$(cat").Inputs(keeps).
+			Textf(")\" ]; then echo '%s %s %s' >> %s; fi", ctx.ModuleName(), c.property, c.name, report)
+	}
+	rule.Build("unused_deps", "unused deps report")
+
+	unusedDepsReport.setModuleFiles(ctx, UnusedDepsInfo{
+		Report:        report,
+		UsedClasspath: usedClasspath,
+	})
+
+	if !enforceLibs {
+		return nil
+	}
+
+	unusedLibsPrefix := ctx.ModuleName() + " libs "
+	unusedLibs := android.PathForModuleOut(ctx, "unused_deps", "unused_libs.txt")
+	stamp := android.PathForModuleOut(ctx, "unused_deps", "unused_libs.stamp")
+	check := android.NewRuleBuilder(pctx, ctx)
+	cmd := check.Command().
+		Textf("sed -n 's/^%s//p'", unusedLibsPrefix).Input(report)
+	if len(exemptLibs) > 0 {
+		cmd.Text("| { grep -v -x -F").FlagForEachArg("-e ", exemptLibs).Text("|| true; }")
+	}
+	cmd.Text(">").Output(unusedLibs)
+	check.Command().
+		Text("if [ -s").Input(unusedLibs).
+		Textf("]; then echo 'error: %s: libs are not referenced by the classes compiled from its sources,"+
+			" remove them from libs or add them to enforce_used_libs_exemptions:' >&2; sed 's/^/    /' %s >&2;"+
+			" exit 1; fi", ctx.ModuleName(), unusedLibs)
+	check.Command().Text("touch").Output(stamp)
+	check.Build("unused_libs_check", "check unused libs")
+	return stamp
+}
+
+func unusedDepsReportPath(ctx android.PathContext) android.WritablePath {
+	return android.PathForOutput(ctx, "unused_deps", "unused_deps.txt")
+}
+
+func usedClasspathReportPath(ctx android.PathContext) android.WritablePath {
+	return android.PathForOutput(ctx, "unused_deps", "used_classpath.txt")
+}
+
+func unusedDepsSingletonFactory() android.Singleton {
+	return unusedDepsReport
+}
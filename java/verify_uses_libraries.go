@@ -0,0 +1,75 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// The verify_uses_libraries check can be relaxed for individual modules, either with the
+// relax_uses_libs_check property or by listing them in the RelaxUsesLibraryCheckModules product
+// variable, e.g. for apps whose manifests list optional libraries that are missing on some
+// products. A mismatch in a relaxed module is printed as a warning instead of failing the build,
+// and the module is dexpreopted with the "verify" compiler filter. The mismatches of all relaxed
+// modules are collected into verify_uses_libraries_relaxed.txt so that they can be tracked.
+
+type UsesLibrariesRelaxedInfo struct {
+	// The file that the verify_uses_libraries check writes the mismatch to, empty if there is
+	// no mismatch.
+	StatusFile android.Path
+}
+
+var UsesLibrariesRelaxedInfoProvider = blueprint.NewProvider[UsesLibrariesRelaxedInfo]()
+
+// relaxUsesLibrariesCheck returns true if a failure of the verify_uses_libraries check of the
+// current module should be reported as a warning.
+func (u *usesLibrary) relaxUsesLibrariesCheck(ctx android.ModuleContext) bool {
+	return Bool(u.usesLibraryProperties.Relax_uses_libs_check) ||
+		android.InList(ctx.ModuleName(), ctx.Config().RelaxUsesLibraryCheckModules())
+}
+
+func verifyUsesLibrariesRelaxedReportPath(ctx android.PathContext) android.WritablePath {
+	return android.PathForOutput(ctx, "verify_uses_libraries", "verify_uses_libraries_relaxed.txt")
+}
+
+// verifyUsesLibrariesRelaxedSingleton writes the verify_uses_libraries mismatches of all the
+// modules for which the check is relaxed to a single report.
+type verifyUsesLibrariesRelaxedSingleton struct{}
+
+func (s *verifyUsesLibrariesRelaxedSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	var statusFiles android.Paths
+	ctx.VisitAllModuleProxies(func(module android.ModuleProxy) {
+		if info, ok := android.OtherModuleProvider(ctx, module, UsesLibrariesRelaxedInfoProvider); ok {
+			statusFiles = append(statusFiles, info.StatusFile)
+		}
+	})
+	if len(statusFiles) == 0 {
+		return
+	}
+
+	report := verifyUsesLibrariesRelaxedReportPath(ctx)
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		Text("(for f in").Inputs(android.SortedUniquePaths(statusFiles)).
+		Text(`; do if [ -s $f ]; then echo "$f:"; cat $f; fi; done) >`).Output(report)
+	rule.Build("verify_uses_libraries_relaxed", "verify_uses_libraries relaxed report")
+	ctx.DistForGoal("droidcore", report)
+}
+
+func verifyUsesLibrariesRelaxedSingletonFactory() android.Singleton {
+	return &verifyUsesLibrariesRelaxedSingleton{}
+}
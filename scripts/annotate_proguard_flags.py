@@ -0,0 +1,67 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for merging proguard flags files with the origin of each flag.
+
+Every line of the merged configuration that contains a flag is annotated with
+a comment naming the module and the file that the flag came from, which makes
+it possible to find out which dependency contributes a keep rule.
+"""
+
+import argparse
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--flags', nargs=2, default=[], action='append',
+                      metavar=('MODULE', 'FILE'), dest='flags',
+                      help='a proguard flags file and the module it came '
+                      'from, in the order they are passed to R8')
+  parser.add_argument('--output', required=True, dest='output',
+                      help='file to write the annotated configuration to')
+  return parser.parse_args()
+
+
+def annotate(module, path, lines):
+  """Returns the lines of a flags file with the origin of each flag."""
+  annotated = ['', '# including %s from %s' % (path, module)]
+  for line in lines:
+    line = line.rstrip('\n')
+    stripped = line.strip()
+    if not stripped or stripped.startswith('#'):
+      annotated.append(line)
+    else:
+      annotated.append('%s  # %s: %s' % (line, module, path))
+  return annotated
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  lines = []
+  for module, path in args.flags:
+    with open(path) as f:
+      lines.extend(annotate(module, path, f.readlines()))
+
+  with open(args.output, 'w') as f:
+    f.write(''.join(line + '\n' for line in lines))
+
+
+if __name__ == '__main__':
+  main()
@@ -0,0 +1,58 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for annotate_proguard_flags.py."""
+
+import sys
+import unittest
+
+import annotate_proguard_flags
+
+sys.dont_write_bytecode = True
+
+
+class AnnotateTest(unittest.TestCase):
+  """ Unit tests for annotate function """
+
+  def test_annotate(self):
+    lines = [
+        '# Keep the entry points.\n',
+        '-keep class com.foo.Main {\n',
+        '    public static void main(java.lang.String[]);\n',
+        '}\n',
+        '\n',
+        '-dontwarn com.bar.**\n',
+    ]
+    self.assertEqual(
+        ['',
+         '# including foo/proguard.flags from foo',
+         '# Keep the entry points.',
+         '-keep class com.foo.Main {  # foo: foo/proguard.flags',
+         '    public static void main(java.lang.String[]);  '
+         '# foo: foo/proguard.flags',
+         '}  # foo: foo/proguard.flags',
+         '',
+         '-dontwarn com.bar.**  # foo: foo/proguard.flags'],
+        annotate_proguard_flags.annotate('foo', 'foo/proguard.flags', lines))
+
+  def test_empty(self):
+    self.assertEqual(
+        ['', '# including foo/empty.flags from foo'],
+        annotate_proguard_flags.annotate('foo', 'foo/empty.flags', []))
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
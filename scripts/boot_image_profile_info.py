@@ -0,0 +1,134 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for converting a dump of a boot image profile into a proto.
+
+The input is the output of profman --dump-classes-and-methods, which lists the
+classes and methods in a binary profile in the text profile format, e.g.
+
+    Ljava/lang/Object;
+    HSPLjava/lang/Object;-><init>()V
+
+The output is a boot_image_profile_info.BootImageProfileInfo proto that lists
+the classes and methods and summarizes them for the whole profile and for each
+package.
+"""
+
+import argparse
+
+# pylint: disable=import-error
+import boot_image_profile_info_pb2
+
+METHOD_FLAGS = 'HSP'
+
+
+def parse_line(line):
+  """Returns the (descriptor, flags) of a class or method line.
+
+  Flags are None for classes, and a subset of METHOD_FLAGS for methods.
+  Returns None for lines that are neither.
+  """
+  line = line.strip()
+  if not line or line.startswith('#'):
+    return None
+  if '->' not in line:
+    return line, None
+  flags = ''
+  while line and line[0] in METHOD_FLAGS:
+    flags += line[0]
+    line = line[1:]
+  return line, flags
+
+
+def package_of(descriptor):
+  """Returns the package of a class or method descriptor, e.g. java.lang."""
+
+  class_descriptor = descriptor.split('->', 1)[0].lstrip('[')
+  if not class_descriptor.startswith('L'):
+    return ''
+  name = class_descriptor[1:].rstrip(';')
+  return name.rpartition('/')[0].replace('/', '.')
+
+
+def add_to_summary(summary, flags):
+  """Counts a class, or a method with the flags, in the summary."""
+
+  if flags is None:
+    summary.classes += 1
+    return
+  summary.methods += 1
+  if 'H' in flags:
+    summary.hot_methods += 1
+  if 'S' in flags:
+    summary.startup_methods += 1
+  if 'P' in flags:
+    summary.post_startup_methods += 1
+
+
+def create_info(lines):
+  """Returns the BootImageProfileInfo of the lines of a profile dump."""
+
+  info = boot_image_profile_info_pb2.BootImageProfileInfo()
+  packages = {}
+  for line in lines:
+    parsed = parse_line(line)
+    if parsed is None:
+      continue
+    descriptor, flags = parsed
+    if flags is None:
+      info.classes.append(descriptor)
+    else:
+      method = info.methods.add()
+      method.descriptor = descriptor
+      method.hot = 'H' in flags
+      method.startup = 'S' in flags
+      method.post_startup = 'P' in flags
+
+    add_to_summary(info.summary, flags)
+    package = package_of(descriptor)
+    if package not in packages:
+      packages[package] = boot_image_profile_info_pb2.Summary()
+    add_to_summary(packages[package], flags)
+
+  for name in sorted(packages):
+    package = info.packages.add()
+    package.name = name
+    package.summary.CopyFrom(packages[name])
+  return info
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--output', required=True, dest='output',
+                      help='file to write the BootImageProfileInfo proto to')
+  parser.add_argument('dump', help='output of profman --dump-classes-and-methods')
+  return parser.parse_args()
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  with open(args.dump) as f:
+    info = create_info(f)
+  with open(args.output, 'wb') as f:
+    f.write(info.SerializeToString())
+
+
+if __name__ == '__main__':
+  main()
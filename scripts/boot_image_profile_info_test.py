@@ -0,0 +1,80 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for boot_image_profile_info.py."""
+
+import sys
+import unittest
+
+import boot_image_profile_info
+
+sys.dont_write_bytecode = True
+
+
+class BootImageProfileInfoTest(unittest.TestCase):
+  """Unit tests for boot_image_profile_info."""
+
+  def test_parse_line(self):
+    self.assertEqual(('Ljava/lang/Object;', None),
+                     boot_image_profile_info.parse_line('Ljava/lang/Object;\n'))
+    self.assertEqual(('Ljava/lang/Object;-><init>()V', 'HSP'),
+                     boot_image_profile_info.parse_line(
+                         'HSPLjava/lang/Object;-><init>()V\n'))
+    self.assertEqual(('Ljava/lang/Object;->hashCode()I', ''),
+                     boot_image_profile_info.parse_line(
+                         'Ljava/lang/Object;->hashCode()I\n'))
+    self.assertIsNone(boot_image_profile_info.parse_line('\n'))
+
+  def test_package_of(self):
+    self.assertEqual('java.lang',
+                     boot_image_profile_info.package_of('Ljava/lang/Object;'))
+    self.assertEqual('java.util',
+                     boot_image_profile_info.package_of(
+                         'Ljava/util/List;->size()I'))
+    self.assertEqual('android.os',
+                     boot_image_profile_info.package_of('[Landroid/os/Bundle;'))
+    self.assertEqual('', boot_image_profile_info.package_of('LFoo;'))
+
+  def test_create_info(self):
+    info = boot_image_profile_info.create_info([
+        'Ljava/lang/Object;\n',
+        'Ljava/lang/String;\n',
+        'HSPLjava/lang/Object;-><init>()V\n',
+        'SPLjava/lang/String;->length()I\n',
+        'HLjava/util/List;->size()I\n',
+    ])
+
+    self.assertEqual(['Ljava/lang/Object;', 'Ljava/lang/String;'],
+                     list(info.classes))
+    self.assertEqual(
+        [('Ljava/lang/Object;-><init>()V', True, True, True),
+         ('Ljava/lang/String;->length()I', False, True, True),
+         ('Ljava/util/List;->size()I', True, False, False)],
+        [(m.descriptor, m.hot, m.startup, m.post_startup)
+         for m in info.methods])
+
+    self.assertEqual((2, 3, 2, 2, 2),
+                     (info.summary.classes, info.summary.methods,
+                      info.summary.hot_methods, info.summary.startup_methods,
+                      info.summary.post_startup_methods))
+    self.assertEqual(
+        [('java.lang', 2, 2, 1), ('java.util', 0, 1, 1)],
+        [(p.name, p.summary.classes, p.summary.methods,
+          p.summary.hot_methods) for p in info.packages])
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -0,0 +1,101 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Checks the api_contributions of a java_api_library for conflicts.
+
+Fails if two API signature files contributed to the same API surface define
+the same class.  Signature files of different surfaces may define the same
+class, as the later surfaces only list the members they add to it.
+"""
+
+import argparse
+import re
+import sys
+
+PACKAGE_RE = re.compile(r'^package\s+([\w.]+)\s*\{')
+CLASS_RE = re.compile(
+    r'^\s*(?:[\w@.]+\s+)*?(?:class|interface|enum|@interface|record)\s+'
+    r'([\w.$]+)')
+
+
+def parse_args(argv):
+  parser = argparse.ArgumentParser(description=__doc__)
+  parser.add_argument(
+      '--api_file', action='append', default=[], metavar='SURFACE:PATH',
+      help='an API signature file and the API surface it contributes to')
+  parser.add_argument(
+      '--stamp', help='file to touch if there are no conflicts')
+  args = parser.parse_args(argv)
+  for api_file in args.api_file:
+    if ':' not in api_file:
+      parser.error('--api_file must be of the form SURFACE:PATH, got %r' %
+                   api_file)
+  return args
+
+
+def classes_in_signature_file(path):
+  """Returns the fully qualified names of the classes defined in path."""
+  classes = []
+  package = None
+  with open(path) as f:
+    for line in f:
+      m = PACKAGE_RE.match(line)
+      if m:
+        package = m.group(1)
+        continue
+      if package is None or not line.rstrip().endswith('{'):
+        continue
+      m = CLASS_RE.match(line)
+      if m:
+        classes.append(package + '.' + m.group(1))
+  return classes
+
+
+def find_conflicts(api_files):
+  """Returns (surface, class, [paths]) for classes defined more than once.
+
+  api_files is a list of (surface, path) tuples.
+  """
+  definitions = {}
+  for surface, path in api_files:
+    for cls in classes_in_signature_file(path):
+      paths = definitions.setdefault((surface, cls), [])
+      if path not in paths:
+        paths.append(path)
+  return [(surface, cls, paths)
+          for (surface, cls), paths in sorted(definitions.items())
+          if len(paths) > 1]
+
+
+def main(argv):
+  args = parse_args(argv)
+  api_files = [tuple(a.split(':', 1)) for a in args.api_file]
+  conflicts = find_conflicts(api_files)
+  for surface, cls, paths in conflicts:
+    print('error: %s is defined by more than one contribution to the %s API '
+          'surface: %s' % (cls, surface, ', '.join(paths)), file=sys.stderr)
+  if conflicts:
+    print('Set api_contributions_merge_strategy: "merge" to let metalava merge '
+          'the definitions instead.', file=sys.stderr)
+    return 1
+  if args.stamp:
+    with open(args.stamp, 'w'):
+      pass
+  return 0
+
+
+if __name__ == '__main__':
+  sys.exit(main(sys.argv[1:]))
@@ -0,0 +1,107 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Tests for check_api_contributions."""
+
+import check_api_contributions
+import os
+import shutil
+import tempfile
+import unittest
+
+FOO_API = """// Signature format: 2.0
+package android.foo {
+
+  public class Foo {
+    ctor public Foo();
+    method public void foo();
+  }
+
+  public static final class Foo.Inner {
+  }
+
+  public interface Listener<T> {
+  }
+
+}
+
+"""
+
+BAR_API = """// Signature format: 2.0
+package android.foo {
+
+  public class Bar {
+  }
+
+}
+
+"""
+
+
+class CheckApiContributionsTest(unittest.TestCase):
+
+  def setUp(self):
+    self.tmp = tempfile.mkdtemp()
+
+  def tearDown(self):
+    shutil.rmtree(self.tmp)
+
+  def write(self, name, contents):
+    path = os.path.join(self.tmp, name)
+    with open(path, 'w') as f:
+      f.write(contents)
+    return path
+
+  def test_classes_in_signature_file(self):
+    path = self.write('current.txt', FOO_API)
+    self.assertEqual(
+        check_api_contributions.classes_in_signature_file(path),
+        ['android.foo.Foo', 'android.foo.Foo.Inner', 'android.foo.Listener'])
+
+  def test_same_class_in_same_surface_conflicts(self):
+    a = self.write('a.txt', FOO_API)
+    b = self.write('b.txt', FOO_API)
+    conflicts = check_api_contributions.find_conflicts(
+        [('public', a), ('public', b)])
+    self.assertEqual(
+        [c[1] for c in conflicts],
+        ['android.foo.Foo', 'android.foo.Foo.Inner', 'android.foo.Listener'])
+    self.assertEqual(conflicts[0][2], [a, b])
+    self.assertEqual(
+        check_api_contributions.main(
+            ['--api_file', 'public:' + a, '--api_file', 'public:' + b]), 1)
+
+  def test_same_class_in_different_surfaces_is_allowed(self):
+    a = self.write('current.txt', FOO_API)
+    b = self.write('system-current.txt', FOO_API)
+    self.assertEqual(
+        check_api_contributions.find_conflicts([('public', a), ('system', b)]),
+        [])
+
+  def test_no_conflicts_writes_stamp(self):
+    a = self.write('a.txt', FOO_API)
+    b = self.write('b.txt', BAR_API)
+    stamp = os.path.join(self.tmp, 'stamp')
+    self.assertEqual(
+        check_api_contributions.main([
+            '--api_file', 'public:' + a, '--api_file', 'public:' + b,
+            '--stamp', stamp
+        ]), 0)
+    self.assertTrue(os.path.exists(stamp))
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -0,0 +1,110 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for finding classes that are in more than one of the given jars.
+
+When jars are merged only the first copy of a class is kept, so duplicate
+classes mean that the code of some of the dependencies is silently dropped.
+"""
+
+import argparse
+import fnmatch
+import sys
+import zipfile
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--allow', default=[], action='append', dest='allowed',
+                      help='class name or glob of class names that may be '
+                      'duplicated, e.g. com.foo.Bar or com.foo.*')
+  parser.add_argument('--output', required=True, dest='output',
+                      help='file to write the report of duplicate classes to')
+  parser.add_argument('jars', nargs='+', help='jars to check')
+  return parser.parse_args()
+
+
+def class_name(path):
+  """Returns the name of the class in a class file path, e.g. com.foo.Bar."""
+  return path[:-len('.class')].replace('/', '.')
+
+
+def find_duplicate_classes(jar_entries, allowed):
+  """Returns a sorted list of (class name, jars) for the duplicate classes.
+
+  jar_entries is a list of (jar, entry names) in the order the jars are
+  merged.
+  """
+
+  jars_by_class = {}
+  for jar, entries in jar_entries:
+    for entry in entries:
+      if not entry.endswith('.class') or entry.endswith('module-info.class'):
+        continue
+      if entry.startswith('META-INF/'):
+        continue
+      name = class_name(entry)
+      jars = jars_by_class.setdefault(name, [])
+      if jar not in jars:
+        jars.append(jar)
+
+  duplicates = []
+  for name, jars in sorted(jars_by_class.items()):
+    if len(jars) < 2:
+      continue
+    if any(fnmatch.fnmatchcase(name, pattern) for pattern in allowed):
+      continue
+    duplicates.append((name, jars))
+  return duplicates
+
+
+def format_report(duplicates):
+  lines = []
+  for name, jars in duplicates:
+    lines.append('%s is in %d jars, the copy in the first one is used:' %
+                 (name, len(jars)))
+    lines.extend('  ' + jar for jar in jars)
+  return ''.join(line + '\n' for line in lines)
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  jar_entries = []
+  for jar in args.jars:
+    with zipfile.ZipFile(jar) as z:
+      jar_entries.append((jar, z.namelist()))
+
+  duplicates = find_duplicate_classes(jar_entries, args.allowed)
+  report = format_report(duplicates)
+  with open(args.output, 'w') as f:
+    f.write(report)
+
+  if duplicates:
+    sys.stderr.write(report)
+    sys.stderr.write(
+        '\nerror: found %d classes in more than one of the jars merged into '
+        'the module.\nRemove the duplicates from the static_libs, or add the '
+        'classes to allowed_duplicate_classes if they are expected.\n' %
+        len(duplicates))
+    sys.exit(1)
+
+
+if __name__ == '__main__':
+  main()
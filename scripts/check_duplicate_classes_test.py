@@ -0,0 +1,64 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for check_duplicate_classes.py."""
+
+import sys
+import unittest
+
+import check_duplicate_classes
+
+sys.dont_write_bytecode = True
+
+
+class FindDuplicateClassesTest(unittest.TestCase):
+  """ Unit tests for find_duplicate_classes function """
+
+  jar_entries = [
+      ('a.jar', ['com/foo/A.class', 'com/foo/B.class', 'module-info.class',
+                 'META-INF/MANIFEST.MF']),
+      ('b.jar', ['com/foo/B.class', 'com/foo/C.class', 'module-info.class']),
+      ('c.jar', ['com/foo/B.class', 'com/foo/C.class',
+                 'META-INF/versions/9/com/foo/C.class']),
+  ]
+
+  def test_duplicates(self):
+    self.assertEqual(
+        [('com.foo.B', ['a.jar', 'b.jar', 'c.jar']),
+         ('com.foo.C', ['b.jar', 'c.jar'])],
+        check_duplicate_classes.find_duplicate_classes(self.jar_entries, []))
+
+  def test_allowed(self):
+    self.assertEqual(
+        [('com.foo.C', ['b.jar', 'c.jar'])],
+        check_duplicate_classes.find_duplicate_classes(
+            self.jar_entries, ['com.foo.B']))
+    self.assertEqual(
+        [],
+        check_duplicate_classes.find_duplicate_classes(
+            self.jar_entries, ['com.foo.*']))
+
+  def test_report(self):
+    self.assertEqual(
+        'com.foo.C is in 2 jars, the copy in the first one is used:\n'
+        '  b.jar\n'
+        '  c.jar\n',
+        check_duplicate_classes.format_report(
+            [('com.foo.C', ['b.jar', 'c.jar'])]))
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -0,0 +1,204 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for finding uses of banned Java language features in class files.
+
+Some language features are compiled into class file constructs that are not
+supported everywhere the classes may run, e.g. records need the java.lang.Record
+class and switch pattern matching needs java.lang.runtime.SwitchBootstraps.
+The features are detected from the class attributes and the classes referenced
+by the constant pool of each class in the jars.
+"""
+
+import argparse
+import struct
+import sys
+import zipfile
+
+CONSTANT_UTF8 = 1
+CONSTANT_LONG = 5
+CONSTANT_DOUBLE = 6
+CONSTANT_CLASS = 7
+
+# The sizes of the other constant pool entries, excluding the tag.
+CONSTANT_SIZES = {
+    3: 4,  # Integer
+    4: 4,  # Float
+    5: 8,  # Long
+    6: 8,  # Double
+    8: 2,  # String
+    9: 4,  # Fieldref
+    10: 4,  # Methodref
+    11: 4,  # InterfaceMethodref
+    12: 4,  # NameAndType
+    15: 3,  # MethodHandle
+    16: 2,  # MethodType
+    17: 4,  # Dynamic
+    18: 4,  # InvokeDynamic
+    19: 2,  # Module
+    20: 2,  # Package
+}
+
+# The class attributes and referenced classes that identify each feature.
+FEATURES = {
+    'lambdas': ([], ['java/lang/invoke/LambdaMetafactory']),
+    'records': (['Record'], ['java/lang/Record',
+                             'java/lang/runtime/ObjectMethods']),
+    'sealed_classes': (['PermittedSubclasses'], []),
+    'string_templates': ([], ['java/lang/StringTemplate',
+                              'java/lang/runtime/TemplateRuntime']),
+    'switch_patterns': ([], ['java/lang/runtime/SwitchBootstraps']),
+}
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--feature', required=True, action='append',
+                      dest='features', choices=sorted(FEATURES),
+                      help='language feature that must not be used')
+  parser.add_argument('--output', required=True, dest='output',
+                      help='file to write the report of banned features to')
+  parser.add_argument('jars', nargs='+', help='jars to check')
+  return parser.parse_args()
+
+
+class Reader:
+  """Reads big endian values from class file data."""
+
+  def __init__(self, data):
+    self.data = data
+    self.offset = 0
+
+  def read(self, fmt):
+    value = struct.unpack_from(fmt, self.data, self.offset)[0]
+    self.offset += struct.calcsize(fmt)
+    return value
+
+  def u1(self):
+    return self.read('>B')
+
+  def u2(self):
+    return self.read('>H')
+
+  def u4(self):
+    return self.read('>I')
+
+  def skip(self, length):
+    self.offset += length
+
+  def skip_attributes(self):
+    for _ in range(self.u2()):
+      self.skip(2)
+      self.skip(self.u4())
+
+
+def parse_class(data):
+  """Returns the referenced classes and the class attributes of a class file."""
+
+  r = Reader(data)
+  if r.u4() != 0xCAFEBABE:
+    raise ValueError('not a class file')
+  r.skip(4)  # minor_version, major_version
+
+  utf8 = {}
+  class_names = []
+  count = r.u2()
+  i = 1
+  while i < count:
+    tag = r.u1()
+    if tag == CONSTANT_UTF8:
+      length = r.u2()
+      utf8[i] = data[r.offset:r.offset + length].decode('utf-8', 'replace')
+      r.skip(length)
+    elif tag == CONSTANT_CLASS:
+      class_names.append(r.u2())
+    elif tag in CONSTANT_SIZES:
+      r.skip(CONSTANT_SIZES[tag])
+    else:
+      raise ValueError('unknown constant pool tag %d' % tag)
+    # Long and Double constants take up two entries of the constant pool.
+    i += 2 if tag in (CONSTANT_LONG, CONSTANT_DOUBLE) else 1
+
+  r.skip(6)  # access_flags, this_class, super_class
+  r.skip(2 * r.u2())  # interfaces
+  for _ in range(2):  # fields, methods
+    for _ in range(r.u2()):
+      r.skip(6)  # access_flags, name_index, descriptor_index
+      r.skip_attributes()
+
+  attributes = set()
+  for _ in range(r.u2()):
+    attributes.add(utf8[r.u2()])
+    r.skip(r.u4())
+
+  return {utf8[index] for index in class_names}, attributes
+
+
+def find_features(data, features):
+  """Returns the sorted features used by a class file."""
+
+  referenced_classes, attributes = parse_class(data)
+  found = []
+  for feature in sorted(set(features)):
+    feature_attributes, feature_classes = FEATURES[feature]
+    if (attributes.intersection(feature_attributes) or
+        referenced_classes.intersection(feature_classes)):
+      found.append(feature)
+  return found
+
+
+def format_report(uses):
+  """Returns the report of a sorted list of (class name, features)."""
+
+  return ''.join('%s uses %s\n' % (name, ', '.join(features))
+                 for name, features in uses)
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  uses = []
+  for jar in args.jars:
+    with zipfile.ZipFile(jar) as z:
+      for entry in z.namelist():
+        if not entry.endswith('.class') or entry.startswith('META-INF/'):
+          continue
+        if entry.endswith('module-info.class'):
+          continue
+        features = find_features(z.read(entry), args.features)
+        if features:
+          uses.append((entry[:-len('.class')].replace('/', '.'), features))
+
+  uses.sort()
+  report = format_report(uses)
+  with open(args.output, 'w') as f:
+    f.write(report)
+
+  if uses:
+    sys.stderr.write(report)
+    sys.stderr.write(
+        '\nerror: found %d classes that use banned language features.\n'
+        'The features are banned by the banned_language_features property of '
+        'the module, or by the product if the module is on the bootclasspath.\n'
+        % len(uses))
+    sys.exit(1)
+
+
+if __name__ == '__main__':
+  main()
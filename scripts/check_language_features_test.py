@@ -0,0 +1,131 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for check_language_features.py."""
+
+import struct
+import sys
+import unittest
+
+import check_language_features
+
+sys.dont_write_bytecode = True
+
+
+def utf8(s):
+  data = s.encode('utf-8')
+  return struct.pack('>BH', 1, len(data)) + data
+
+
+def make_class(referenced_classes=(), attributes=()):
+  """Returns a class file that references the classes and has the attributes."""
+
+  # 1: Utf8 "Foo", 2: Class #1, 3: Utf8 "java/lang/Object", 4: Class #3,
+  # 5: Long taking up entries 5 and 6.
+  pool = [utf8('Foo'), struct.pack('>BH', 7, 1),
+          utf8('java/lang/Object'), struct.pack('>BH', 7, 3),
+          struct.pack('>BQ', 5, 42)]
+  count = 7
+  for name in referenced_classes:
+    pool.append(utf8(name))
+    pool.append(struct.pack('>BH', 7, count))
+    count += 2
+  attribute_names = []
+  for name in attributes:
+    pool.append(utf8(name))
+    attribute_names.append(count)
+    count += 1
+
+  data = struct.pack('>IHHH', 0xCAFEBABE, 0, 61, count) + b''.join(pool)
+  data += struct.pack('>HHH', 0x21, 2, 4)  # access_flags, this, super
+  data += struct.pack('>H', 0)  # interfaces
+  data += struct.pack('>H', 0)  # fields
+  # A method with an attribute that is skipped.
+  data += struct.pack('>HHHHH', 1, 0, 1, 1, 1) + struct.pack('>HI', 1, 2) + b'\0\0'
+  data += struct.pack('>H', len(attribute_names))
+  for index in attribute_names:
+    data += struct.pack('>HI', index, 2) + b'\0\0'
+  return data
+
+
+class ParseClassTest(unittest.TestCase):
+  """ Unit tests for parse_class function """
+
+  def test_parse_class(self):
+    referenced_classes, attributes = check_language_features.parse_class(
+        make_class(['java/lang/Record'], ['Record', 'SourceFile']))
+    self.assertEqual({'Foo', 'java/lang/Object', 'java/lang/Record'},
+                     referenced_classes)
+    self.assertEqual({'Record', 'SourceFile'}, attributes)
+
+  def test_not_a_class(self):
+    with self.assertRaises(ValueError):
+      check_language_features.parse_class(b'\0' * 16)
+
+
+class FindFeaturesTest(unittest.TestCase):
+  """ Unit tests for find_features function """
+
+  def test_no_features(self):
+    self.assertEqual(
+        [],
+        check_language_features.find_features(
+            make_class(attributes=['SourceFile']),
+            list(check_language_features.FEATURES)))
+
+  def test_record(self):
+    self.assertEqual(
+        ['records'],
+        check_language_features.find_features(
+            make_class(['java/lang/Record'], ['Record']),
+            ['records', 'sealed_classes']))
+
+  def test_sealed_class(self):
+    self.assertEqual(
+        ['sealed_classes'],
+        check_language_features.find_features(
+            make_class(attributes=['PermittedSubclasses']),
+            ['records', 'sealed_classes']))
+
+  def test_referenced_classes(self):
+    self.assertEqual(
+        ['lambdas', 'switch_patterns'],
+        check_language_features.find_features(
+            make_class(['java/lang/runtime/SwitchBootstraps',
+                        'java/lang/invoke/LambdaMetafactory']),
+            ['switch_patterns', 'string_templates', 'lambdas']))
+
+  def test_only_banned_features(self):
+    self.assertEqual(
+        [],
+        check_language_features.find_features(
+            make_class(['java/lang/invoke/LambdaMetafactory']), ['records']))
+
+
+class FormatReportTest(unittest.TestCase):
+  """ Unit tests for format_report function """
+
+  def test_format_report(self):
+    self.assertEqual(
+        'com.foo.Bar uses records\ncom.foo.Baz uses lambdas, switch_patterns\n',
+        check_language_features.format_report([
+            ('com.foo.Bar', ['records']),
+            ('com.foo.Baz', ['lambdas', 'switch_patterns']),
+        ]))
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -0,0 +1,265 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for finding obviously broken rules in proguard flags files.
+
+The flags files of a library are only parsed by R8 when an app or another
+optimized module that depends on the library is built, so a typo in them is
+reported far away from where it was made. This checks the flags files of the
+module that declares them for malformed class specifications, which are
+errors, and for unknown options and modifiers, which are only warnings as R8
+supports options that are not listed here. It is not a full parser, rules that
+it accepts may still be rejected by R8.
+"""
+
+import argparse
+import re
+import sys
+
+# The options that take a class specification.
+CLASS_SPEC_OPTIONS = {
+    '-alwaysinline',
+    '-assumemayhavesideeffects',
+    '-assumenoescapingparameters',
+    '-assumenoexternalreturnvalues',
+    '-assumenoexternalsideeffects',
+    '-assumenosideeffects',
+    '-assumevalues',
+    '-checkdiscard',
+    '-convertchecknotnull',
+    '-identifiernamestring',
+    '-if',
+    '-keep',
+    '-keepclassmembernames',
+    '-keepclassmembers',
+    '-keepclasseswithmembernames',
+    '-keepclasseswithmembers',
+    '-keepnames',
+    '-neverinline',
+    '-whyareyoukeeping',
+}
+
+# The other options that are supported by R8 or ignored by it for compatibility
+# with ProGuard.
+OTHER_OPTIONS = {
+    '-adaptclassstrings',
+    '-adaptresourcefilecontents',
+    '-adaptresourcefilenames',
+    '-addconfigurationdebugging',
+    '-allowaccessmodification',
+    '-android',
+    '-applymapping',
+    '-basedirectory',
+    '-classobfuscationdictionary',
+    '-dontnote',
+    '-dontobfuscate',
+    '-dontoptimize',
+    '-dontpreverify',
+    '-dontshrink',
+    '-dontskipnonpubliclibraryclasses',
+    '-dontskipnonpubliclibraryclassmembers',
+    '-dontusemixedcaseclassnames',
+    '-dontwarn',
+    '-dump',
+    '-flattenpackagehierarchy',
+    '-forceprocessing',
+    '-ignorewarnings',
+    '-include',
+    '-injars',
+    '-keepattributes',
+    '-keepdirectories',
+    '-keepkotlinmetadata',
+    '-keeppackagenames',
+    '-keepparameternames',
+    '-libraryjars',
+    '-maximumremovedandroidloglevel',
+    '-mergeinterfacesaggressively',
+    '-microedition',
+    '-obfuscationdictionary',
+    '-optimizationpasses',
+    '-optimizations',
+    '-outjars',
+    '-overloadaggressively',
+    '-packageobfuscationdictionary',
+    '-printconfiguration',
+    '-printmapping',
+    '-printseeds',
+    '-printusage',
+    '-processkotlinnullchecks',
+    '-renamesourcefileattribute',
+    '-repackageclasses',
+    '-skipnonpubliclibraryclasses',
+    '-target',
+    '-useuniqueclassmembernames',
+    '-verbose',
+}
+
+# The modifiers that can follow the keep options, e.g. -keep,allowobfuscation.
+KEEP_MODIFIERS = {
+    'allowaccessmodification',
+    'allowobfuscation',
+    'allowoptimization',
+    'allowrepackage',
+    'allowshrinking',
+    'includecode',
+    'includedescriptorclasses',
+}
+
+CLASS_TYPE_RE = re.compile(r'(^|[\s!@])(class|interface|enum)\s')
+
+
+class Rule:
+  """An option and the text that follows it up to the next option."""
+
+  def __init__(self, option, line):
+    self.option = option
+    self.line = line
+    self.spec = ''
+    self.has_members = False
+
+
+def check_rule(rule):
+  """Returns the errors and warnings of the option and class specification of a
+  rule."""
+
+  name, _, modifiers = rule.option.partition(',')
+  if name not in CLASS_SPEC_OPTIONS:
+    if name not in OTHER_OPTIONS:
+      return [], ['unknown option %s' % name]
+    if rule.has_members:
+      return ['%s does not take class members' % name], []
+    return [], []
+
+  errors = []
+  warnings = []
+  if modifiers:
+    if not name.startswith('-keep'):
+      errors.append('%s does not take modifiers' % name)
+    else:
+      for modifier in modifiers.split(','):
+        if modifier not in KEEP_MODIFIERS:
+          warnings.append('unknown modifier %s of %s' % (modifier, name))
+
+  if not CLASS_TYPE_RE.search(rule.spec + ' '):
+    errors.append('%s must be followed by a class specification, e.g. '
+                  '"%s class com.example.Foo"' % (name, name))
+  return errors, warnings
+
+
+def check_flags(lines):
+  """Returns the (line number, message) errors and warnings in the lines of a
+  flags file."""
+
+  errors = []
+  warnings = []
+  rule = None
+  member_line = None
+  member = ''
+
+  def finish_rule():
+    if rule:
+      rule_errors, rule_warnings = check_rule(rule)
+      errors.extend((rule.line, e) for e in rule_errors)
+      warnings.extend((rule.line, w) for w in rule_warnings)
+
+  for number, line in enumerate(lines, 1):
+    line = line.split('#', 1)[0]
+    tokens = re.findall(r'[{};]|[^\s{};]+', line)
+    for i, token in enumerate(tokens):
+      if member_line is not None:
+        # Inside the member block of a class specification.
+        if token == '{':
+          errors.append((number, 'unexpected "{" in class members, '
+                         'the member block opened on line %d is not closed'
+                         % member_line))
+        elif token == ';':
+          member = ''
+        elif token == '}':
+          if member:
+            errors.append((number, 'class member "%s" must end with ";"'
+                           % member.strip()))
+          member_line = None
+          member = ''
+        else:
+          member += ' ' + token
+        continue
+
+      if token == '{':
+        if rule is None:
+          errors.append((number, 'class members without a class '
+                         'specification'))
+        else:
+          rule.has_members = True
+        member_line = number
+      elif token == '}':
+        errors.append((number, 'unexpected "}" without a matching "{"'))
+      elif token.startswith('-') and i == 0:
+        finish_rule()
+        rule = Rule(token, number)
+      elif rule is not None:
+        rule.spec += ' ' + token
+      elif token.startswith('@') and i == 0:
+        # An included configuration file.
+        pass
+      elif i == 0:
+        errors.append((number, 'unexpected "%s" before the first option'
+                       % token))
+
+  finish_rule()
+  if member_line is not None:
+    errors.append((member_line, 'class members opened with "{" are never '
+                   'closed'))
+  errors.sort()
+  warnings.sort()
+  return errors, warnings
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--output', required=True, dest='output',
+                      help='file to touch when the flags files are valid')
+  parser.add_argument('flags', nargs='+', help='proguard flags files to check')
+  return parser.parse_args()
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  errors = []
+  for path in args.flags:
+    with open(path) as f:
+      file_errors, file_warnings = check_flags(f.readlines())
+    errors.extend('%s:%d: error: %s' % (path, line, message)
+                  for line, message in file_errors)
+    for line, message in file_warnings:
+      sys.stderr.write('%s:%d: warning: %s\n' % (path, line, message))
+
+  if errors:
+    sys.stderr.write('\n'.join(errors) + '\n')
+    sys.stderr.write(
+        '\nerror: found %d broken rules in proguard flags files.\n' %
+        len(errors))
+    sys.exit(1)
+
+  with open(args.output, 'w'):
+    pass
+
+
+if __name__ == '__main__':
+  main()
@@ -0,0 +1,122 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for check_proguard_flags.py."""
+
+import sys
+import unittest
+
+from check_proguard_flags import check_flags
+
+sys.dont_write_bytecode = True
+
+
+class CheckFlagsTest(unittest.TestCase):
+  """Unit tests for check_flags function."""
+
+  def test_valid(self):
+    lines = [
+        '# Keep the entry points.\n',
+        '-keep,allowobfuscation public class com.foo.Main {\n',
+        '    public static void main(java.lang.String[]);\n',
+        '}\n',
+        '-keepclassmembers enum * { *; }\n',
+        '-keep @interface com.foo.Keep\n',
+        '-if class com.foo.Bar\n',
+        '-keep class com.foo.Baz\n',
+        '-assumevalues class android.os.Build$VERSION {\n',
+        '    int SDK_INT return 21..2147483647;\n',
+        '}\n',
+        '-keepclasseswithmembers\n',
+        '    class * {\n',
+        '        native <methods>;\n',
+        '    }\n',
+        '-dontwarn com.bar.**\n',
+        '-keepattributes *Annotation*,Signature\n',
+        '-applymapping mapping.txt\n',
+        '-assumemayhavesideeffects class com.foo.Log {\n',
+        '    void log();\n',
+        '}\n',
+        '-processkotlinnullchecks remove\n',
+        '@common.flags\n',
+    ]
+    self.assertEqual(([], []), check_flags(lines))
+
+  def test_unknown_option(self):
+    lines = [
+        '-keep class com.foo.Main\n',
+        '-dontwarm com.bar.**\n',
+        '-keep,allowobfuscaton class com.foo.Bar\n',
+    ]
+    # R8 may support options that are not known to the check, so they are
+    # only warnings.
+    self.assertEqual(([], [
+        (2, 'unknown option -dontwarm'),
+        (3, 'unknown modifier allowobfuscaton of -keep'),
+    ]), check_flags(lines))
+
+  def test_missing_class_type(self):
+    lines = [
+        '-keep com.foo.Main { *; }\n',
+        '-keepclassmembers\n',
+        '-dontwarn com.bar.**\n',
+    ]
+    self.assertEqual(([
+        (1, '-keep must be followed by a class specification, e.g. '
+         '"-keep class com.example.Foo"'),
+        (2, '-keepclassmembers must be followed by a class specification, '
+         'e.g. "-keepclassmembers class com.example.Foo"'),
+    ], []), check_flags(lines))
+
+  def test_members(self):
+    lines = [
+        '-keep class com.foo.Main {\n',
+        '    public static void main(java.lang.String[])\n',
+        '}\n',
+        '-dontwarn com.bar.** { *; }\n',
+        '}\n',
+    ]
+    self.assertEqual(([
+        (3, 'class member "public static void main(java.lang.String[])" '
+         'must end with ";"'),
+        (4, '-dontwarn does not take class members'),
+        (5, 'unexpected "}" without a matching "{"'),
+    ], []), check_flags(lines))
+
+  def test_unclosed_members(self):
+    lines = [
+        '-keep class com.foo.Main {\n',
+        '    *;\n',
+        '-keep class com.foo.Bar { *; }\n',
+        '-keep class com.foo.Baz {\n',
+    ]
+    self.assertEqual(([
+        (3, 'unexpected "{" in class members, the member block opened on '
+         'line 1 is not closed'),
+        (4, 'class members opened with "{" are never closed'),
+    ], []), check_flags(lines))
+
+  def test_before_first_option(self):
+    lines = [
+        'class com.foo.Main\n',
+    ]
+    self.assertEqual(([
+        (1, 'unexpected "class" before the first option'),
+    ], []), check_flags(lines))
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
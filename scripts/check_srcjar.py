@@ -0,0 +1,90 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for checking that a srcjar is well-formed.
+
+A srcjar is a zip file of sources that javac, turbine and kotlinc read, so it
+must only contain .java and .kt files (and directories) at relative paths.
+"""
+
+import argparse
+import posixpath
+import sys
+import zipfile
+
+SOURCE_EXTENSIONS = ('.java', '.kt')
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--output', required=True, dest='output',
+                      help='file to write when the srcjar is well-formed')
+  parser.add_argument('srcjar', help='srcjar to check')
+  return parser.parse_args()
+
+
+def find_problems(entries):
+  """Returns a list of the problems with the entry names of a srcjar."""
+
+  problems = []
+  seen = set()
+  for entry in entries:
+    if entry in seen:
+      problems.append('%s: duplicate entry' % entry)
+      continue
+    seen.add(entry)
+
+    normalized = posixpath.normpath(entry)
+    if entry.startswith('/') or normalized == '..' or \
+        normalized.startswith('../'):
+      problems.append('%s: path is not relative to the root of the srcjar' %
+                      entry)
+      continue
+    if entry.endswith('/'):
+      continue
+    if not entry.endswith(SOURCE_EXTENSIONS):
+      problems.append('%s: not a %s file' %
+                      (entry, ' or '.join(SOURCE_EXTENSIONS)))
+  return problems
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  try:
+    with zipfile.ZipFile(args.srcjar) as z:
+      problems = find_problems(z.namelist())
+      corrupt = z.testzip()
+      if corrupt is not None:
+        problems.append('%s: corrupt entry' % corrupt)
+  except zipfile.BadZipFile as e:
+    problems = ['not a zip file: %s' % e]
+
+  if problems:
+    sys.stderr.write(''.join('%s: %s\n' % (args.srcjar, problem)
+                             for problem in problems))
+    sys.stderr.write('error: %s is not a well-formed srcjar\n' % args.srcjar)
+    sys.exit(1)
+
+  with open(args.output, 'w') as f:
+    f.write('')
+
+
+if __name__ == '__main__':
+  main()
@@ -0,0 +1,59 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for check_srcjar.py."""
+
+import sys
+import unittest
+
+import check_srcjar
+
+sys.dont_write_bytecode = True
+
+
+class FindProblemsTest(unittest.TestCase):
+  """ Unit tests for find_problems function """
+
+  def test_well_formed(self):
+    self.assertEqual(
+        [],
+        check_srcjar.find_problems(
+            ['com/', 'com/foo/', 'com/foo/A.java', 'com/foo/B.kt']))
+
+  def test_not_source(self):
+    self.assertEqual(
+        ['com/foo/A.class: not a .java or .kt file',
+         'META-INF/MANIFEST.MF: not a .java or .kt file'],
+        check_srcjar.find_problems(
+            ['com/foo/A.class', 'META-INF/MANIFEST.MF', 'com/foo/B.java']))
+
+  def test_not_relative(self):
+    self.assertEqual(
+        ['/com/foo/A.java: path is not relative to the root of the srcjar',
+         '../com/foo/B.java: path is not relative to the root of the srcjar',
+         'com/../../C.java: path is not relative to the root of the srcjar'],
+        check_srcjar.find_problems(
+            ['/com/foo/A.java', '../com/foo/B.java', 'com/../../C.java',
+             'com/../D.java']))
+
+  def test_duplicate(self):
+    self.assertEqual(
+        ['com/foo/A.java: duplicate entry'],
+        check_srcjar.find_problems(['com/foo/A.java', 'com/foo/A.java']))
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -0,0 +1,267 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for auditing the APIs that D8 desugars in a jar.
+
+The generate command reads the methods that D8 backports for the min_sdk_version
+of the module, as listed by R8's BackportedMethodList, and writes one line per
+backported method that is referenced by the classes in the jar that is passed to
+D8, and one line per desugared library class (in the j$ package) that is
+referenced by the dex code produced by D8. The merge command merges the reports
+of all modules into a single sorted report.
+"""
+
+import argparse
+import struct
+import zipfile
+
+CONSTANT_UTF8 = 1
+CONSTANT_LONG = 5
+CONSTANT_DOUBLE = 6
+CONSTANT_CLASS = 7
+CONSTANT_METHODREF = 10
+CONSTANT_INTERFACE_METHODREF = 11
+CONSTANT_NAME_AND_TYPE = 12
+
+# The sizes of the other constant pool entries, excluding the tag.
+CONSTANT_SIZES = {
+    3: 4,  # Integer
+    4: 4,  # Float
+    5: 8,  # Long
+    6: 8,  # Double
+    8: 2,  # String
+    9: 4,  # Fieldref
+    15: 3,  # MethodHandle
+    16: 2,  # MethodType
+    17: 4,  # Dynamic
+    18: 4,  # InvokeDynamic
+    19: 2,  # Module
+    20: 2,  # Package
+}
+
+DESUGARED_LIBRARY_PREFIX = 'j$/'
+
+DEX_MAGIC = b'dex\n'
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  subparsers = parser.add_subparsers(dest='command', required=True)
+
+  generate = subparsers.add_parser(
+      'generate', help='report the desugared APIs used by a jar')
+  generate.add_argument('--backported-methods', required=True,
+                        dest='backported_methods',
+                        help='the output of R8 BackportedMethodList')
+  generate.add_argument('--module', required=True,
+                        help='the name of the module the jar belongs to')
+  generate.add_argument('--dex-jar', required=True, dest='dex_jar',
+                        help='the jar of dex files produced by D8')
+  generate.add_argument('--output', required=True,
+                        help='file to write the report to')
+  generate.add_argument('jar', help='the jar that is passed to D8')
+
+  merge = subparsers.add_parser('merge', help='merge the reports of modules')
+  merge.add_argument('--output', required=True,
+                     help='file to write the merged report to')
+  merge.add_argument('reports', nargs='*',
+                     help='reports to merge, or @file to read them from a '
+                     'response file')
+  return parser.parse_args()
+
+
+def parse_constant_pool(data):
+  """Returns the referenced classes and methods of a class file.
+
+  Classes are returned as internal names, e.g. java/lang/Math, and methods in
+  the format used by BackportedMethodList, e.g. java/lang/Math#floorMod(II)I.
+  """
+
+  magic, = struct.unpack_from('>I', data, 0)
+  if magic != 0xCAFEBABE:
+    raise ValueError('not a class file')
+  count, = struct.unpack_from('>H', data, 8)
+  offset = 10
+
+  utf8 = {}
+  classes = {}
+  name_and_types = {}
+  method_refs = []
+  i = 1
+  while i < count:
+    tag = data[offset]
+    offset += 1
+    if tag == CONSTANT_UTF8:
+      length, = struct.unpack_from('>H', data, offset)
+      offset += 2
+      utf8[i] = data[offset:offset + length].decode('utf-8', 'replace')
+      offset += length
+    elif tag == CONSTANT_CLASS:
+      classes[i], = struct.unpack_from('>H', data, offset)
+      offset += 2
+    elif tag == CONSTANT_NAME_AND_TYPE:
+      name_and_types[i] = struct.unpack_from('>HH', data, offset)
+      offset += 4
+    elif tag in (CONSTANT_METHODREF, CONSTANT_INTERFACE_METHODREF):
+      method_refs.append(struct.unpack_from('>HH', data, offset))
+      offset += 4
+    elif tag in CONSTANT_SIZES:
+      offset += CONSTANT_SIZES[tag]
+    else:
+      raise ValueError('unknown constant pool tag %d' % tag)
+    # Long and Double constants take up two entries of the constant pool.
+    i += 2 if tag in (CONSTANT_LONG, CONSTANT_DOUBLE) else 1
+
+  methods = set()
+  for class_index, name_and_type_index in method_refs:
+    name_index, descriptor_index = name_and_types[name_and_type_index]
+    methods.add('%s#%s%s' % (utf8[classes[class_index]], utf8[name_index],
+                             utf8[descriptor_index]))
+  return {utf8[index] for index in classes.values()}, methods
+
+
+def read_uleb128(data, offset):
+  """Returns the value of the ULEB128 at offset and the offset after it."""
+  value = 0
+  shift = 0
+  while True:
+    byte = data[offset]
+    offset += 1
+    value |= (byte & 0x7f) << shift
+    if byte & 0x80 == 0:
+      return value, offset
+    shift += 7
+
+
+def parse_dex_types(data):
+  """Returns the internal names of the classes referenced by a dex file.
+
+  Array types are returned as the name of their element class, and primitive
+  types are skipped.
+  """
+
+  if data[:4] != DEX_MAGIC:
+    raise ValueError('not a dex file')
+  string_ids_off, = struct.unpack_from('<I', data, 0x3c)
+  type_ids_size, type_ids_off = struct.unpack_from('<II', data, 0x40)
+
+  types = set()
+  for i in range(type_ids_size):
+    descriptor_idx, = struct.unpack_from('<I', data, type_ids_off + 4 * i)
+    string_data_off, = struct.unpack_from(
+        '<I', data, string_ids_off + 4 * descriptor_idx)
+    # The string data starts with its length in UTF-16 code units, and is
+    # terminated by a NUL byte.
+    _, start = read_uleb128(data, string_data_off)
+    end = data.index(b'\0', start)
+    descriptor = data[start:end].decode('utf-8', 'replace').lstrip('[')
+    if descriptor.startswith('L') and descriptor.endswith(';'):
+      types.add(descriptor[1:-1])
+  return types
+
+
+def find_backported_methods(class_files, backported_methods):
+  """Returns the sorted backported methods used by the class files.
+
+  class_files is an iterable of the contents of class files.
+  """
+
+  backports = set()
+  for data in class_files:
+    _, methods = parse_constant_pool(data)
+    backports.update(methods.intersection(backported_methods))
+  return sorted(backports)
+
+
+def find_desugared_library_classes(dex_files):
+  """Returns the sorted desugared library classes used by the dex files.
+
+  dex_files is an iterable of the contents of dex files. D8 rewrites the
+  references to the APIs provided by the desugared library, so they are only
+  visible in its output.
+  """
+
+  library_classes = set()
+  for data in dex_files:
+    library_classes.update(c for c in parse_dex_types(data)
+                           if c.startswith(DESUGARED_LIBRARY_PREFIX))
+  return sorted(library_classes)
+
+
+def parse_backported_methods(lines):
+  """Returns the set of methods listed by BackportedMethodList."""
+  return {line.strip() for line in lines if line.strip()}
+
+
+def format_report(module, backports, library_classes):
+  return ''.join(
+      ['%s: backport %s\n' % (module, m) for m in backports] +
+      ['%s: library %s\n' % (module, c) for c in library_classes])
+
+
+def expand_response_files(args):
+  """Replaces @file arguments with the whitespace separated paths in them."""
+  expanded = []
+  for arg in args:
+    if arg.startswith('@'):
+      with open(arg[1:]) as f:
+        expanded.extend(f.read().split())
+    else:
+      expanded.append(arg)
+  return expanded
+
+
+def merge_reports(contents):
+  """Returns the sorted, unique lines of the given reports."""
+  lines = set()
+  for content in contents:
+    lines.update(line for line in content.splitlines() if line)
+  return ''.join(line + '\n' for line in sorted(lines))
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  if args.command == 'generate':
+    with open(args.backported_methods) as f:
+      backported_methods = parse_backported_methods(f.readlines())
+    with zipfile.ZipFile(args.jar) as z:
+      class_files = (z.read(name) for name in z.namelist()
+                     if name.endswith('.class') and
+                     not name.startswith('META-INF/') and
+                     not name.endswith('module-info.class'))
+      backports = find_backported_methods(class_files, backported_methods)
+    with zipfile.ZipFile(args.dex_jar) as z:
+      dex_files = (z.read(name) for name in z.namelist()
+                   if name.endswith('.dex') and '/' not in name)
+      library_classes = find_desugared_library_classes(dex_files)
+    report = format_report(args.module, backports, library_classes)
+  else:
+    contents = []
+    for path in expand_response_files(args.reports):
+      with open(path) as f:
+        contents.append(f.read())
+    report = merge_reports(contents)
+
+  with open(args.output, 'w') as f:
+    f.write(report)
+
+
+if __name__ == '__main__':
+  main()
@@ -0,0 +1,172 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for desugaring_audit.py."""
+
+import struct
+import sys
+import unittest
+
+import desugaring_audit
+
+sys.dont_write_bytecode = True
+
+
+def utf8(value):
+  encoded = value.encode('utf-8')
+  return struct.pack('>BH', 1, len(encoded)) + encoded
+
+
+def class_file(*methods, classes=()):
+  """Returns a class file whose constant pool references the given methods.
+
+  methods are (class, name, descriptor) tuples and classes are additional
+  referenced classes.
+  """
+  entries = []
+
+  def add(entry):
+    entries.append(entry)
+    return len(entries)
+
+  # A Long constant takes up two entries of the constant pool.
+  add(struct.pack('>BQ', 5, 42))
+  entries.append(b'')
+  for owner, name, descriptor in methods:
+    class_index = add(struct.pack('>BH', 7, add(utf8(owner))))
+    name_and_type_index = add(struct.pack(
+        '>BHH', 12, add(utf8(name)), add(utf8(descriptor))))
+    add(struct.pack('>BHH', 10, class_index, name_and_type_index))
+  for name in classes:
+    add(struct.pack('>BH', 7, add(utf8(name))))
+  return (struct.pack('>IHHH', 0xCAFEBABE, 0, 52, len(entries) + 1) +
+          b''.join(entries))
+
+
+def uleb128(value):
+  encoded = b''
+  while value > 0x7f:
+    encoded += bytes([(value & 0x7f) | 0x80])
+    value >>= 7
+  return encoded + bytes([value])
+
+
+def dex_file(*descriptors):
+  """Returns a dex file whose type ids reference the given type descriptors."""
+  header_size = 0x70
+  string_ids_off = header_size
+  type_ids_off = string_ids_off + 4 * len(descriptors)
+  string_data_off = type_ids_off + 4 * len(descriptors)
+
+  string_ids = b''
+  string_data = b''
+  for descriptor in descriptors:
+    string_ids += struct.pack('<I', string_data_off + len(string_data))
+    string_data += uleb128(len(descriptor)) + descriptor.encode('utf-8') + b'\0'
+  type_ids = b''.join(struct.pack('<I', i) for i in range(len(descriptors)))
+
+  header = bytearray(header_size)
+  header[0:8] = b'dex\n035\0'
+  struct.pack_into('<IIII', header, 0x38, len(descriptors), string_ids_off,
+                   len(descriptors), type_ids_off)
+  return bytes(header) + string_ids + type_ids + string_data
+
+
+class ParseConstantPoolTest(unittest.TestCase):
+  """ Unit tests for parse_constant_pool function """
+
+  def test_methods_and_classes(self):
+    classes, methods = desugaring_audit.parse_constant_pool(class_file(
+        ('java/lang/Math', 'floorMod', '(II)I'),
+        classes=['j$/time/LocalDate']))
+    self.assertEqual({'java/lang/Math', 'j$/time/LocalDate'}, classes)
+    self.assertEqual({'java/lang/Math#floorMod(II)I'}, methods)
+
+  def test_not_a_class_file(self):
+    with self.assertRaises(ValueError):
+      desugaring_audit.parse_constant_pool(b'\0' * 16)
+
+
+class ParseDexTypesTest(unittest.TestCase):
+  """ Unit tests for parse_dex_types function """
+
+  def test_types(self):
+    self.assertEqual(
+        {'j$/time/LocalDate', 'java/lang/Object', 'j$/util/Optional'},
+        desugaring_audit.parse_dex_types(dex_file(
+            'I', 'Lj$/time/LocalDate;', 'Ljava/lang/Object;',
+            '[[Lj$/util/Optional;')))
+
+  def test_not_a_dex_file(self):
+    with self.assertRaises(ValueError):
+      desugaring_audit.parse_dex_types(b'\0' * 0x70)
+
+
+class ReportTest(unittest.TestCase):
+  """ Unit tests for generating and merging reports """
+
+  backported_methods = desugaring_audit.parse_backported_methods([
+      'java/lang/Math#floorMod(II)I\n',
+      'java/util/List#of()Ljava/util/List;\n',
+      '\n',
+  ])
+
+  def test_find_backported_methods(self):
+    self.assertEqual(
+        ['java/lang/Math#floorMod(II)I', 'java/util/List#of()Ljava/util/List;'],
+        desugaring_audit.find_backported_methods([
+            class_file(('java/util/List', 'of', '()Ljava/util/List;'),
+                       ('java/lang/Math', 'abs', '(I)I')),
+            class_file(('java/lang/Math', 'floorMod', '(II)I'),
+                       classes=['java/time/LocalDate']),
+        ], self.backported_methods))
+
+  def test_nothing_backported(self):
+    self.assertEqual(
+        [],
+        desugaring_audit.find_backported_methods(
+            [class_file(('java/lang/Math', 'abs', '(I)I'))],
+            self.backported_methods))
+
+  def test_find_desugared_library_classes(self):
+    self.assertEqual(
+        ['j$/time/LocalDate', 'j$/util/Optional'],
+        desugaring_audit.find_desugared_library_classes([
+            dex_file('Lj$/time/LocalDate;', 'Ljava/lang/Object;'),
+            dex_file('Lj$/util/Optional;', 'Lj$/time/LocalDate;'),
+        ]))
+
+  def test_format_report(self):
+    self.assertEqual(
+        'foo: backport java/lang/Math#floorMod(II)I\n'
+        'foo: library j$/time/LocalDate\n',
+        desugaring_audit.format_report(
+            'foo', ['java/lang/Math#floorMod(II)I'], ['j$/time/LocalDate']))
+
+  def test_merge_reports(self):
+    self.assertEqual(
+        'bar: backport java/lang/Math#floorMod(II)I\n'
+        'foo: library j$/time/LocalDate\n',
+        desugaring_audit.merge_reports([
+            'foo: library j$/time/LocalDate\n',
+            'bar: backport java/lang/Math#floorMod(II)I\n'
+            'foo: library j$/time/LocalDate\n',
+            '',
+        ]))
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -0,0 +1,366 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for extracting the Kotlin line mappings of the classes in a jar.
+
+The Kotlin compiler gives the code of inlined functions line numbers past the
+end of the calling source file, and records where those lines come from in the
+SMAP (JSR-45) stored in the SourceDebugExtension attribute of the class. JaCoCo
+reports the raw line numbers, so the coverage of inline functions is attributed
+to lines that do not exist. This tool writes the mappings of the classes of a
+jar to a JSON file that the coverage report uses to attribute the lines back
+to the files and lines they were inlined from.
+
+It can also write a copy of the jar in which the line numbers of the inlined
+code are replaced by the lines of the calls they were inlined at, which the
+KotlinDebug stratum of the SMAP records, so that JaCoCo attributes their
+coverage to lines of the calling file. JaCoCo matches the execution data to
+the classes by a CRC64 of the class file, so the tool also writes the ids of
+the original classes mapped to the ids of the corrected classes, which the
+execution data must be rewritten with before it is reported against the
+corrected jar.
+"""
+
+import argparse
+import json
+import struct
+import zipfile
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--output', required=True, dest='output',
+                      help='file to write the JSON line mappings to')
+  parser.add_argument('--classes-output', required=True, dest='classes_output',
+                      help='file to write the jar with corrected line numbers to')
+  parser.add_argument('--class-ids-output', required=True,
+                      dest='class_ids_output',
+                      help='file to write the JSON map of the JaCoCo ids of '
+                      'the original classes to the ids of the corrected '
+                      'classes to')
+  parser.add_argument('jar', help='jar of the classes to extract the SMAPs of')
+  return parser.parse_args()
+
+
+# Sizes of the constant pool entries that are skipped, by tag.
+_CONSTANT_SIZES = {
+    3: 4,  # Integer
+    4: 4,  # Float
+    5: 8,  # Long
+    6: 8,  # Double
+    7: 2,  # Class
+    8: 2,  # String
+    9: 4,  # Fieldref
+    10: 4,  # Methodref
+    11: 4,  # InterfaceMethodref
+    12: 4,  # NameAndType
+    15: 3,  # MethodHandle
+    16: 2,  # MethodType
+    17: 4,  # Dynamic
+    18: 4,  # InvokeDynamic
+    19: 2,  # Module
+    20: 2,  # Package
+}
+
+
+class _Reader(object):
+  """Reads big endian values from the contents of a class file."""
+
+  def __init__(self, data):
+    self.data = data
+    self.pos = 0
+
+  def u1(self):
+    self.pos += 1
+    return self.data[self.pos - 1]
+
+  def u2(self):
+    self.pos += 2
+    return struct.unpack_from('>H', self.data, self.pos - 2)[0]
+
+  def u4(self):
+    self.pos += 4
+    return struct.unpack_from('>I', self.data, self.pos - 4)[0]
+
+  def read(self, n):
+    self.pos += n
+    return self.data[self.pos - n:self.pos]
+
+
+def _parse_class(data):
+  """Returns the SourceDebugExtension of a class file, or None, and the offsets
+  of the line numbers in its LineNumberTable attributes."""
+
+  r = _Reader(data)
+  if r.u4() != 0xCAFEBABE:
+    raise ValueError('not a class file')
+  r.u2()  # minor_version
+  r.u2()  # major_version
+
+  utf8 = {}
+  count = r.u2()
+  i = 1
+  while i < count:
+    tag = r.u1()
+    if tag == 1:
+      utf8[i] = r.read(r.u2()).decode('utf-8', 'replace')
+    elif tag in _CONSTANT_SIZES:
+      r.read(_CONSTANT_SIZES[tag])
+    else:
+      raise ValueError('unknown constant pool tag %d' % tag)
+    # Long and Double take two entries of the constant pool.
+    i += 2 if tag in (5, 6) else 1
+
+  r.u2()  # access_flags
+  r.u2()  # this_class
+  r.u2()  # super_class
+  r.read(2 * r.u2())  # interfaces
+
+  line_offsets = []
+
+  def read_attributes():
+    """Returns the attributes at the reader as (name, offset, length)."""
+    attributes = []
+    for _ in range(r.u2()):
+      name = utf8.get(r.u2())
+      length = r.u4()
+      attributes.append((name, r.pos, length))
+      r.read(length)
+    return attributes
+
+  for _ in range(r.u2()):  # fields
+    r.read(6)
+    read_attributes()
+
+  for _ in range(r.u2()):  # methods
+    r.read(6)
+    for name, offset, _ in read_attributes():
+      if name != 'Code':
+        continue
+      end = r.pos
+      r.pos = offset + 4  # max_stack, max_locals
+      r.read(r.u4())  # code
+      r.read(8 * r.u2())  # exception_table
+      for code_name, code_offset, _ in read_attributes():
+        if code_name == 'LineNumberTable':
+          r.pos = code_offset
+          for _ in range(r.u2()):
+            line_offsets.append(r.pos + 2)  # after start_pc
+            r.read(4)
+      r.pos = end
+
+  smap = None
+  for name, offset, length in read_attributes():
+    if name == 'SourceDebugExtension':
+      smap = data[offset:offset + length].decode('utf-8', 'replace')
+  return smap, line_offsets
+
+
+def source_debug_extension(data):
+  """Returns the SourceDebugExtension of a class file, or None."""
+
+  return _parse_class(data)[0]
+
+
+def _parse_stratum(smap, stratum_name):
+  """Returns the line sections of a stratum of an SMAP.
+
+  Each section is a tuple of the path of the source file, the first line in
+  the source file, the number of source lines, the first line in the class
+  file and the number of class file lines per source line.
+  """
+
+  lines = smap.splitlines()
+  if not lines or lines[0] != 'SMAP':
+    return []
+
+  files = {}
+  sections = []
+  section = None
+  stratum = None
+  file_id = None
+  i = 0
+  while i < len(lines):
+    line = lines[i].strip()
+    i += 1
+    if line.startswith('*S '):
+      stratum = line[3:].strip()
+      section = None
+      continue
+    if line.startswith('*'):
+      section = line[1:2]
+      continue
+    if stratum != stratum_name:
+      continue
+    if section == 'F':
+      # "+ <id> <name>" is followed by the path, "<id> <name>" has none.
+      if line.startswith('+ '):
+        fid, name = line[2:].split(' ', 1)
+        path = lines[i].strip() if i < len(lines) else name
+        i += 1
+      else:
+        fid, name = line.split(' ', 1)
+        path = name
+      files[int(fid)] = path
+    elif section == 'L':
+      # <in_start>[#<file>][,<repeat>]:<out_start>[,<increment>]
+      src, out = line.split(':', 1)
+      if '#' in src:
+        src, fid = src.split('#', 1)
+        if ',' in fid:
+          fid, repeat = fid.split(',', 1)
+        else:
+          repeat = '1'
+        file_id = int(fid)
+      elif ',' in src:
+        src, repeat = src.split(',', 1)
+      else:
+        repeat = '1'
+      if ',' in out:
+        out, increment = out.split(',', 1)
+      else:
+        increment = '1'
+      sections.append((files.get(file_id, ''), int(src), int(repeat),
+                       int(out), int(increment)))
+  return sections
+
+
+def parse_smap(smap):
+  """Returns the line mappings of the Kotlin stratum of an SMAP.
+
+  Each mapping is a dict with the path of the source file, the first line in
+  the source file, and the first and last line in the class file.
+  """
+
+  return [{
+      'path': path,
+      'source_start': source_start,
+      'output_start': output_start,
+      'output_end': output_start + repeat * increment - 1,
+  } for path, source_start, repeat, output_start, increment in
+          _parse_stratum(smap, 'Kotlin')]
+
+
+def call_site_lines(smap):
+  """Returns a dict of the class file lines of inlined code to the lines of
+  the calls they were inlined at, from the KotlinDebug stratum of an SMAP."""
+
+  result = {}
+  for _, source_start, repeat, output_start, increment in _parse_stratum(
+      smap, 'KotlinDebug'):
+    for i in range(repeat * increment):
+      result[output_start + i] = source_start + i // increment
+  return result
+
+
+def correct_lines(data):
+  """Returns a class file with the line numbers of inlined code replaced by the
+  lines of the calls they were inlined at, or the class file unchanged if it
+  has no inlined code."""
+
+  smap, line_offsets = _parse_class(data)
+  if smap is None:
+    return data
+  lines = call_site_lines(smap)
+  if not lines:
+    return data
+
+  corrected = bytearray(data)
+  for offset in line_offsets:
+    line = struct.unpack_from('>H', data, offset)[0]
+    if line in lines:
+      struct.pack_into('>H', corrected, offset, lines[line])
+  return bytes(corrected)
+
+
+def _crc64_table():
+  table = []
+  for i in range(256):
+    v = i
+    for _ in range(8):
+      if v & 1:
+        v = (v >> 1) ^ 0xD800000000000000
+      else:
+        v >>= 1
+    table.append(v)
+  return table
+
+
+_CRC64_TABLE = _crc64_table()
+
+
+def class_id(data):
+  """Returns the id that JaCoCo identifies a class file by, a CRC64 of its
+  contents."""
+
+  # JaCoCo hashes Java 9 class files as if they were Java 8 class files.
+  if len(data) >= 8 and data[6] == 0 and data[7] == 53:
+    data = data[:7] + b'\x34' + data[8:]
+  crc = 0
+  for b in bytearray(data):
+    crc = (crc >> 8) ^ _CRC64_TABLE[(crc ^ b) & 0xff]
+  return '%016x' % crc
+
+
+def extract_mappings(classes):
+  """Returns the line mappings of the classes that have an SMAP.
+
+  classes is a list of (class file name, contents).
+  """
+
+  result = {}
+  for name, data in classes:
+    smap = source_debug_extension(data)
+    if smap is None:
+      continue
+    mappings = parse_smap(smap)
+    if mappings:
+      result[name[:-len('.class')].replace('/', '.')] = mappings
+  return result
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  classes = []
+  class_ids = {}
+  with zipfile.ZipFile(args.jar) as z, \
+      zipfile.ZipFile(args.classes_output, 'w') as out:
+    for info in z.infolist():
+      data = z.read(info)
+      name = info.filename
+      if name.endswith('.class') and not name.startswith('META-INF/'):
+        classes.append((name, data))
+        corrected = correct_lines(data)
+        if corrected != data:
+          class_ids[class_id(data)] = class_id(corrected)
+        data = corrected
+      out.writestr(info, data)
+
+  with open(args.output, 'w') as f:
+    json.dump(extract_mappings(classes), f, indent=2, sort_keys=True)
+    f.write('\n')
+
+  with open(args.class_ids_output, 'w') as f:
+    json.dump(class_ids, f, indent=2, sort_keys=True)
+    f.write('\n')
+
+
+if __name__ == '__main__':
+  main()
@@ -0,0 +1,177 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for extract_kotlin_smap.py."""
+
+import struct
+import sys
+import unittest
+
+import extract_kotlin_smap
+
+sys.dont_write_bytecode = True
+
+
+SMAP = '\n'.join([
+    'SMAP',
+    'Foo.kt',
+    'Kotlin',
+    '*S Kotlin',
+    '*F',
+    '+ 1 Foo.kt',
+    'com/example/Foo.kt',
+    '+ 2 Util.kt',
+    'com/example/util/Util.kt',
+    '*L',
+    '1#1,20:1',
+    '5#2,3:21',
+    '*S KotlinDebug',
+    '*F',
+    '+ 1 Foo.kt',
+    'com/example/Foo.kt',
+    '*L',
+    '7#1:21,3',
+    '*E',
+])
+
+
+def utf8(s):
+  b = s.encode('utf-8')
+  return struct.pack('>BH', 1, len(b)) + b
+
+
+def class_file(attributes, lines=None):
+  """Returns a minimal class file with the given class attributes, and a
+  method with the given line numbers if any."""
+
+  names = [name for name, _ in attributes] + ['Code', 'LineNumberTable']
+  pool = b''.join(utf8(name) for name in names)
+  # A Long constant takes two entries of the constant pool.
+  pool += struct.pack('>BQ', 5, 42)
+  data = struct.pack('>IHHH', 0xCAFEBABE, 0, 52, len(names) + 3) + pool
+  data += struct.pack('>HHHH', 0x21, 0, 0, 0)  # flags, this, super, interfaces
+  data += struct.pack('>H', 0)  # fields
+  if lines:
+    line_numbers = struct.pack('>H', len(lines)) + b''.join(
+        struct.pack('>HH', pc, line) for pc, line in enumerate(lines))
+    code = struct.pack('>HHI', 1, 1, len(lines)) + b'\x00' * len(lines)
+    code += struct.pack('>HH', 0, 1)  # exception_table, attributes
+    code += struct.pack('>HI', len(names), len(line_numbers))
+    code += line_numbers
+    data += struct.pack('>HHHHH', 1, 0x9, 0, 0, 1)  # methods, method
+    data += struct.pack('>HI', len(names) - 1, len(code)) + code
+  else:
+    data += struct.pack('>H', 0)  # methods
+  data += struct.pack('>H', len(attributes))
+  for i, (_, value) in enumerate(attributes):
+    data += struct.pack('>HI', i + 1, len(value)) + value
+  return data
+
+
+def line_numbers(data):
+  """Returns the line numbers of the method of a class file from class_file."""
+
+  _, offsets = extract_kotlin_smap._parse_class(data)
+  return [struct.unpack_from('>H', data, offset)[0] for offset in offsets]
+
+
+class SourceDebugExtensionTest(unittest.TestCase):
+  """ Unit tests for source_debug_extension function """
+
+  def test_with_smap(self):
+    data = class_file([('SourceFile', b'\x00\x01'),
+                       ('SourceDebugExtension', SMAP.encode('utf-8'))])
+    self.assertEqual(extract_kotlin_smap.source_debug_extension(data), SMAP)
+
+  def test_without_smap(self):
+    data = class_file([('SourceFile', b'\x00\x01')])
+    self.assertIsNone(extract_kotlin_smap.source_debug_extension(data))
+
+  def test_not_a_class(self):
+    with self.assertRaises(ValueError):
+      extract_kotlin_smap.source_debug_extension(b'\x00' * 16)
+
+
+class ParseSmapTest(unittest.TestCase):
+  """ Unit tests for parse_smap function """
+
+  def test_kotlin_stratum(self):
+    self.assertEqual(extract_kotlin_smap.parse_smap(SMAP), [
+        {'path': 'com/example/Foo.kt', 'source_start': 1,
+         'output_start': 1, 'output_end': 20},
+        {'path': 'com/example/util/Util.kt', 'source_start': 5,
+         'output_start': 21, 'output_end': 23},
+    ])
+
+  def test_not_an_smap(self):
+    self.assertEqual(extract_kotlin_smap.parse_smap('Foo.kt'), [])
+
+
+class ExtractMappingsTest(unittest.TestCase):
+  """ Unit tests for extract_mappings function """
+
+  def test_extract_mappings(self):
+    classes = [
+        ('com/example/Foo.class',
+         class_file([('SourceDebugExtension', SMAP.encode('utf-8'))])),
+        ('com/example/Bar.class', class_file([])),
+    ]
+    mappings = extract_kotlin_smap.extract_mappings(classes)
+    self.assertEqual(sorted(mappings), ['com.example.Foo'])
+    self.assertEqual(len(mappings['com.example.Foo']), 2)
+
+
+class CorrectLinesTest(unittest.TestCase):
+  """ Unit tests for call_site_lines and correct_lines functions """
+
+  def test_call_site_lines(self):
+    self.assertEqual(extract_kotlin_smap.call_site_lines(SMAP),
+                     {21: 7, 22: 7, 23: 7})
+
+  def test_correct_lines(self):
+    data = class_file([('SourceDebugExtension', SMAP.encode('utf-8'))],
+                      lines=[3, 21, 22, 23, 24])
+    self.assertEqual(line_numbers(data), [3, 21, 22, 23, 24])
+    corrected = extract_kotlin_smap.correct_lines(data)
+    self.assertEqual(line_numbers(corrected), [3, 7, 7, 7, 24])
+    self.assertEqual(len(corrected), len(data))
+
+  def test_without_smap(self):
+    data = class_file([], lines=[3, 21])
+    self.assertEqual(extract_kotlin_smap.correct_lines(data), data)
+
+
+class ClassIdTest(unittest.TestCase):
+  """ Unit tests for class_id function """
+
+  def test_class_id(self):
+    data = class_file([], lines=[3])
+    self.assertRegex(extract_kotlin_smap.class_id(data), '^[0-9a-f]{16}$')
+    self.assertNotEqual(extract_kotlin_smap.class_id(data),
+                        extract_kotlin_smap.class_id(class_file([], lines=[4])))
+
+  def test_java9(self):
+    data = class_file([], lines=[3])
+    java9 = data[:7] + b'\x35' + data[8:]
+    java10 = data[:7] + b'\x36' + data[8:]
+    self.assertEqual(extract_kotlin_smap.class_id(java9),
+                     extract_kotlin_smap.class_id(data))
+    self.assertNotEqual(extract_kotlin_smap.class_id(java10),
+                        extract_kotlin_smap.class_id(data))
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -0,0 +1,311 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for generating the test config of an instrumentation test.
+
+The runner and target package are read from the <instrumentation> tag of the
+manifest, the timeout and the module parameters from the annotations of the
+test classes and their methods.
+"""
+
+import argparse
+import struct
+import sys
+import zipfile
+from xml.dom import minidom
+from xml.sax.saxutils import quoteattr
+
+from manifest import android_ns
+from manifest import get_children_with_tag
+from manifest import parse_manifest
+
+# The timeouts in milliseconds of the test size annotations, a test run is
+# given the timeout of the largest size used by the test classes.
+SIZE_ANNOTATIONS = [
+    ('Landroidx/test/filters/SmallTest;', 60000),
+    ('Landroid/test/suitebuilder/annotation/SmallTest;', 60000),
+    ('Landroidx/test/filters/MediumTest;', 300000),
+    ('Landroid/test/suitebuilder/annotation/MediumTest;', 300000),
+    ('Landroidx/test/filters/LargeTest;', 900000),
+    ('Landroid/test/suitebuilder/annotation/LargeTest;', 900000),
+]
+
+# The module parameters enabled by annotations used by the test classes.
+PARAMETER_ANNOTATIONS = [
+    ('Landroid/platform/test/annotations/AppModeFull;', 'not_instant_app'),
+    ('Landroid/platform/test/annotations/AppModeInstant;', 'instant_app'),
+    ('Landroid/platform/test/annotations/SystemUserOnly;',
+     'not_secondary_user'),
+]
+
+DEFAULT_RUNNER = 'androidx.test.runner.AndroidJUnitRunner'
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--manifest', required=True, dest='manifest',
+                      help='AndroidManifest.xml of the test APK')
+  parser.add_argument('--classes-jar', required=True, dest='classes_jar',
+                      help='jar containing the classes of the test APK')
+  parser.add_argument('--name', required=True, dest='name',
+                      help='name of the test module')
+  parser.add_argument('--apk-name', required=True, dest='apk_name',
+                      help='file name of the installed test APK')
+  parser.add_argument('--test-suite', default=[], action='append',
+                      dest='test_suites',
+                      help='test suite the test is tagged with')
+  parser.add_argument('--extra-config', default=[], action='append',
+                      dest='extra_configs',
+                      help='extra config to add to the configuration')
+  parser.add_argument('--extra-test-runner-config', default=[],
+                      action='append', dest='extra_test_runner_configs',
+                      help='extra config to add to the test runner')
+  parser.add_argument('output', help='output test config file')
+  return parser.parse_args()
+
+
+def read_instrumentation(manifest_doc):
+  """Returns the package, runner and target package of the test."""
+
+  manifest = parse_manifest(manifest_doc)
+  package = manifest.getAttribute('package')
+  runner = DEFAULT_RUNNER
+  target_package = package
+  instrumentations = get_children_with_tag(manifest, 'instrumentation')
+  if instrumentations:
+    instrumentation = instrumentations[0]
+    runner = instrumentation.getAttributeNS(android_ns, 'name') or runner
+    target_package = (instrumentation.getAttributeNS(android_ns,
+                                                     'targetPackage') or
+                      target_package)
+  return package, runner, target_package
+
+
+class ClassReader:
+  """Reads the big endian values of a class file."""
+
+  def __init__(self, data):
+    self.data = data
+    self.offset = 0
+
+  def read(self, fmt):
+    values = struct.unpack_from('>' + fmt, self.data, self.offset)
+    self.offset += struct.calcsize('>' + fmt)
+    return values if len(values) > 1 else values[0]
+
+  def skip(self, length):
+    self.offset += length
+
+
+# The sizes of the constant pool entries with a fixed size, keyed by tag.
+CONSTANT_SIZES = {
+    3: 4,  # Integer
+    4: 4,  # Float
+    5: 8,  # Long
+    6: 8,  # Double
+    7: 2,  # Class
+    8: 2,  # String
+    9: 4,  # Fieldref
+    10: 4,  # Methodref
+    11: 4,  # InterfaceMethodref
+    12: 4,  # NameAndType
+    15: 3,  # MethodHandle
+    16: 2,  # MethodType
+    17: 4,  # Dynamic
+    18: 4,  # InvokeDynamic
+    19: 2,  # Module
+    20: 2,  # Package
+}
+
+ANNOTATIONS_ATTRIBUTES = {
+    'RuntimeVisibleAnnotations',
+    'RuntimeInvisibleAnnotations',
+}
+
+
+def read_constant_pool(reader):
+  """Returns the UTF-8 constants of the constant pool, keyed by index."""
+
+  utf8 = {}
+  count = reader.read('H')
+  index = 1
+  while index < count:
+    tag = reader.read('B')
+    if tag == 1:
+      length = reader.read('H')
+      utf8[index] = reader.data[reader.offset:reader.offset + length].decode(
+          'utf-8', errors='replace')
+      reader.skip(length)
+    elif tag in CONSTANT_SIZES:
+      reader.skip(CONSTANT_SIZES[tag])
+    else:
+      raise ValueError('unknown constant pool tag %d' % tag)
+    # Long and Double constants take two entries.
+    index += 2 if tag in (5, 6) else 1
+  return utf8
+
+
+def skip_element_value(reader):
+  tag = chr(reader.read('B'))
+  if tag == 'e':
+    reader.skip(4)
+  elif tag == '@':
+    read_annotation(reader)
+  elif tag == '[':
+    for _ in range(reader.read('H')):
+      skip_element_value(reader)
+  else:
+    reader.skip(2)
+
+
+def read_annotation(reader):
+  """Returns the index of the type descriptor of the annotation."""
+
+  type_index, pairs = reader.read('HH')
+  for _ in range(pairs):
+    reader.skip(2)
+    skip_element_value(reader)
+  return type_index
+
+
+def read_attributes(reader, utf8, annotations):
+  """Adds the types of the annotations in the attributes to annotations."""
+
+  for _ in range(reader.read('H')):
+    name_index, length = reader.read('HI')
+    end = reader.offset + length
+    if utf8.get(name_index) in ANNOTATIONS_ATTRIBUTES:
+      for _ in range(reader.read('H')):
+        annotations.add(utf8[read_annotation(reader)])
+    reader.offset = end
+
+
+def read_annotations(data):
+  """Returns the type descriptors of the annotations of a class file.
+
+  The annotations of the class and of its fields and methods are returned,
+  annotations of parameters and type uses are ignored.
+  """
+
+  reader = ClassReader(data)
+  if reader.read('I') != 0xCAFEBABE:
+    raise ValueError('not a class file')
+  reader.skip(4)
+  utf8 = read_constant_pool(reader)
+  reader.skip(6)
+  reader.skip(2 * reader.read('H'))
+
+  annotations = set()
+  for _ in range(2):
+    # The fields and the methods.
+    for _ in range(reader.read('H')):
+      reader.skip(6)
+      read_attributes(reader, utf8, annotations)
+  read_attributes(reader, utf8, annotations)
+  return annotations
+
+
+def scan_annotations(class_files):
+  """Returns the timeout and the module parameters of the test."""
+
+  timeout = None
+  parameters = []
+  for data in class_files:
+    annotations = read_annotations(data)
+    for descriptor, size_timeout in SIZE_ANNOTATIONS:
+      if descriptor in annotations:
+        timeout = max(timeout or 0, size_timeout)
+    for descriptor, parameter in PARAMETER_ANNOTATIONS:
+      if descriptor in annotations and parameter not in parameters:
+        parameters.append(parameter)
+  return timeout, sorted(parameters)
+
+
+def read_class_files(classes_jar):
+  with zipfile.ZipFile(classes_jar) as jar:
+    for info in jar.infolist():
+      if info.filename.endswith('.class'):
+        yield jar.read(info)
+
+
+def generate_test_config(name, apk_name, test_suites, package, runner, timeout,
+                         parameters, extra_configs, extra_test_runner_configs):
+  """Returns the contents of the test config."""
+
+  lines = [
+      '<?xml version="1.0" encoding="utf-8"?>',
+      '<!-- Generated from the manifest and annotations of %s. -->' % name,
+      '<configuration description=%s>' % quoteattr('Runs %s.' % name),
+  ]
+  for test_suite in test_suites:
+    lines.append('    <option name="test-suite-tag" value=%s />' %
+                 quoteattr(test_suite))
+  for parameter in parameters:
+    lines.append('    <option name="config-descriptor:metadata" '
+                 'key="parameter" value=%s />' % quoteattr(parameter))
+  lines.extend([
+      '    <target_preparer class="com.android.tradefed.targetprep.suite.'
+      'SuiteApkInstaller">',
+      '        <option name="cleanup-apks" value="true" />',
+      '        <option name="test-file-name" value=%s />' % quoteattr(apk_name),
+      '    </target_preparer>',
+  ])
+  lines.extend('    ' + config for config in extra_configs)
+  lines.extend([
+      '    <test class="com.android.tradefed.testtype.AndroidJUnitTest">',
+      '        <option name="package" value=%s />' % quoteattr(package),
+      '        <option name="runner" value=%s />' % quoteattr(runner),
+  ])
+  if timeout is not None:
+    lines.append('        <option name="test-timeout" value="%d" />' %
+                 timeout)
+  lines.extend('        ' + config for config in extra_test_runner_configs)
+  lines.extend([
+      '    </test>',
+      '</configuration>',
+  ])
+  return '\n'.join(lines) + '\n'
+
+
+def main():
+  """Program entry point."""
+  try:
+    args = parse_args()
+
+    manifest_doc = minidom.parse(args.manifest)
+    package, runner, _ = read_instrumentation(manifest_doc)
+    timeout, parameters = scan_annotations(read_class_files(args.classes_jar))
+
+    # The configs are escaped for sed by the build system.
+    unescape = lambda config: config.replace('\\n', '\n')
+    config = generate_test_config(
+        args.name, args.apk_name, args.test_suites, package, runner, timeout,
+        parameters,
+        [unescape(c) for c in args.extra_configs],
+        [unescape(c) for c in args.extra_test_runner_configs])
+
+    with open(args.output, 'w') as f:
+      f.write(config)
+
+  # pylint: disable=broad-except
+  except Exception as err:
+    print('error: ' + str(err), file=sys.stderr)
+    sys.exit(-1)
+
+if __name__ == '__main__':
+  main()
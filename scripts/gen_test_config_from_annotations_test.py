@@ -0,0 +1,162 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for gen_test_config_from_annotations.py."""
+
+import struct
+import sys
+import unittest
+from xml.dom import minidom
+
+import gen_test_config_from_annotations as gen
+
+sys.dont_write_bytecode = True
+
+
+class ReadInstrumentationTest(unittest.TestCase):
+  """ Unit tests for read_instrumentation function """
+
+  def test_instrumentation(self):
+    doc = minidom.parseString(
+        '<?xml version="1.0" encoding="utf-8"?>\n'
+        '<manifest xmlns:android="http://schemas.android.com/apk/res/android"\n'
+        '    package="com.android.foo.tests">\n'
+        '    <instrumentation android:name="com.android.foo.Runner"\n'
+        '        android:targetPackage="com.android.foo" />\n'
+        '</manifest>\n')
+    self.assertEqual(
+        ('com.android.foo.tests', 'com.android.foo.Runner', 'com.android.foo'),
+        gen.read_instrumentation(doc))
+
+  def test_no_instrumentation(self):
+    doc = minidom.parseString(
+        '<?xml version="1.0" encoding="utf-8"?>\n'
+        '<manifest xmlns:android="http://schemas.android.com/apk/res/android"\n'
+        '    package="com.android.foo.tests">\n'
+        '</manifest>\n')
+    self.assertEqual(
+        ('com.android.foo.tests', gen.DEFAULT_RUNNER, 'com.android.foo.tests'),
+        gen.read_instrumentation(doc))
+
+
+def make_class(class_annotations=(), method_annotations=(), constants=()):
+  """Returns a class file with a method and the given annotations.
+
+  The annotations are given by the descriptors of their types, constants are
+  extra UTF-8 constants that are not referenced by any annotation.
+  """
+
+  pool = []
+
+  def utf8(value):
+    pool.append(value)
+    return len(pool)
+
+  def annotations_attribute(descriptors):
+    # Each annotation has an enum element, which has to be skipped.
+    data = struct.pack('>H', len(descriptors))
+    for descriptor in descriptors:
+      data += struct.pack('>HHHBHH', utf8(descriptor), 1, utf8('value'),
+                          ord('e'), utf8('LFoo;'), utf8('BAR'))
+    return struct.pack('>HI', utf8('RuntimeVisibleAnnotations'),
+                       len(data)) + data
+
+  for constant in constants:
+    utf8(constant)
+  method = struct.pack('>HHHH', 1, utf8('test'), utf8('()V'), 1)
+  method += annotations_attribute(method_annotations)
+  attributes = annotations_attribute(class_annotations)
+
+  data = struct.pack('>IHHH', 0xCAFEBABE, 0, 52, len(pool) + 1)
+  for value in pool:
+    data += struct.pack('>BH', 1, len(value)) + value.encode()
+  data += struct.pack('>HHHH', 1, 0, 0, 0)
+  data += struct.pack('>H', 0)
+  data += struct.pack('>H', 1) + method
+  data += struct.pack('>H', 1) + attributes
+  return data
+
+
+class ScanAnnotationsTest(unittest.TestCase):
+  """ Unit tests for scan_annotations function """
+
+  def test_largest_size(self):
+    classes = [
+        make_class(class_annotations=['Landroidx/test/filters/SmallTest;']),
+        make_class(method_annotations=['Landroidx/test/filters/LargeTest;']),
+    ]
+    self.assertEqual((900000, []), gen.scan_annotations(classes))
+
+  def test_parameters(self):
+    classes = [
+        make_class(
+            class_annotations=[
+                'Landroid/platform/test/annotations/AppModeInstant;'
+            ],
+            method_annotations=[
+                'Landroid/platform/test/annotations/AppModeFull;'
+            ]),
+        make_class(class_annotations=[
+            'Landroid/platform/test/annotations/AppModeFull;'
+        ]),
+    ]
+    self.assertEqual((None, ['instant_app', 'not_instant_app']),
+                     gen.scan_annotations(classes))
+
+  def test_unused_constant(self):
+    classes = [
+        make_class(constants=['Landroidx/test/filters/LargeTest;']),
+    ]
+    self.assertEqual((None, []), gen.scan_annotations(classes))
+
+  def test_not_a_class(self):
+    with self.assertRaises(ValueError):
+      gen.scan_annotations([b'Landroidx/test/filters/LargeTest;'])
+
+
+class GenerateTestConfigTest(unittest.TestCase):
+  """ Unit tests for generate_test_config function """
+
+  def test_generate(self):
+    expected = (
+        '<?xml version="1.0" encoding="utf-8"?>\n'
+        '<!-- Generated from the manifest and annotations of foo. -->\n'
+        '<configuration description="Runs foo.">\n'
+        '    <option name="test-suite-tag" value="device-tests" />\n'
+        '    <option name="config-descriptor:metadata" key="parameter" value="instant_app" />\n'
+        '    <target_preparer class="com.android.tradefed.targetprep.suite.SuiteApkInstaller">\n'
+        '        <option name="cleanup-apks" value="true" />\n'
+        '        <option name="test-file-name" value="FooTests.apk" />\n'
+        '    </target_preparer>\n'
+        '    <option name="extra" value="config" />\n'
+        '    <test class="com.android.tradefed.testtype.AndroidJUnitTest">\n'
+        '        <option name="package" value="com.android.foo" />\n'
+        '        <option name="runner" value="com.android.foo.Runner" />\n'
+        '        <option name="test-timeout" value="60000" />\n'
+        '        <option name="exclude-annotation" value="Flaky" />\n'
+        '    </test>\n'
+        '</configuration>\n')
+    self.assertEqual(
+        expected,
+        gen.generate_test_config(
+            'foo', 'FooTests.apk', ['device-tests'], 'com.android.foo',
+            'com.android.foo.Runner', 60000,
+            ['instant_app'], ['<option name="extra" value="config" />'],
+            ['<option name="exclude-annotation" value="Flaky" />']))
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
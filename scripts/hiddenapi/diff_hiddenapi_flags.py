@@ -0,0 +1,147 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+"""Diff a generated hiddenapi-flags.csv file against a snapshot.
+
+Writes a report of the signatures that were added, removed or whose flags
+changed, and fails if any of the changes is not allowed.
+"""
+import argparse
+import sys
+
+# The API lists a signature can be in, from the most to the least accessible.
+# Moving a signature to a list later in this sequence restricts access to it
+# and can break apps that use it.
+API_LISTS = [
+    'sdk',
+    'unsupported',
+    'max-target-s',
+    'max-target-r',
+    'max-target-q',
+    'max-target-p',
+    'max-target-o',
+    'blocked',
+]
+
+
+def read_flags_from_stream(stream):
+    """Reads a hiddenapi flags file into a dict from signature to flags."""
+    flags = {}
+    for line in stream:
+        line = line.strip()
+        if not line:
+            continue
+        signature, *values = line.split(',')
+        flags[signature] = frozenset(values)
+    return flags
+
+
+def read_flags(path):
+    with open(path, 'r', encoding='utf8') as f:
+        return read_flags_from_stream(f)
+
+
+def api_list_index(flags):
+    """Returns the index in API_LISTS of the API list in the flags."""
+    indices = [API_LISTS.index(f) for f in flags if f in API_LISTS]
+    return max(indices) if indices else None
+
+
+def format_flags(flags):
+    return ','.join(sorted(flags))
+
+
+class FlagsDiff:
+    """The differences between two hiddenapi flags files."""
+
+    def __init__(self, old, new):
+        self.added = sorted(s for s in new if s not in old)
+        self.removed = sorted(s for s in old if s not in new)
+        self.changed = sorted(
+            s for s in new if s in old and new[s] != old[s])
+        self.restricted = []
+        for s in self.changed:
+            old_index = api_list_index(old[s])
+            new_index = api_list_index(new[s])
+            if old_index is not None and new_index is not None and \
+                    new_index > old_index:
+                self.restricted.append(s)
+        self.old = old
+        self.new = new
+
+    def report(self):
+        """Returns a report of the differences, grouped by category."""
+        lines = []
+        lines.append(f'New signatures ({len(self.added)}):')
+        lines.extend(
+            f'  {s}: {format_flags(self.new[s])}' for s in self.added)
+        lines.append(f'Removed signatures ({len(self.removed)}):')
+        lines.extend(
+            f'  {s}: {format_flags(self.old[s])}' for s in self.removed)
+        lines.append(f'Changed signatures ({len(self.changed)}):')
+        lines.extend(f'  {s}: {format_flags(self.old[s])} -> '
+                     f'{format_flags(self.new[s])}' for s in self.changed)
+        return '\n'.join(lines) + '\n'
+
+    def errors(self, allow_removed, allow_restricted):
+        """Returns the errors for the changes that are not allowed."""
+        errors = []
+        if not allow_removed:
+            errors.extend(f'{s} was removed' for s in self.removed)
+        if not allow_restricted:
+            errors.extend(f'{s} was restricted from '
+                          f'{format_flags(self.old[s])} to '
+                          f'{format_flags(self.new[s])}'
+                          for s in self.restricted)
+        return errors
+
+
+def main(argv):
+    args_parser = argparse.ArgumentParser(
+        description='Diff a generated hiddenapi-flags.csv file against a '
+        'snapshot.')
+    args_parser.add_argument(
+        '--snapshot', required=True, help='The checked-in snapshot.')
+    args_parser.add_argument(
+        '--flags', required=True, help='The newly generated flags file.')
+    args_parser.add_argument(
+        '--output', required=True, help='The report to write.')
+    args_parser.add_argument(
+        '--allow-removed',
+        action='store_true',
+        help='Allow signatures to be removed.')
+    args_parser.add_argument(
+        '--allow-restricted',
+        action='store_true',
+        help='Allow signatures to move to a more restrictive API list.')
+    args = args_parser.parse_args(argv[1:])
+
+    diff = FlagsDiff(read_flags(args.snapshot), read_flags(args.flags))
+    with open(args.output, 'w', encoding='utf8') as f:
+        f.write(diff.report())
+
+    errors = diff.errors(args.allow_removed, args.allow_restricted)
+    if errors:
+        for error in errors:
+            print(f'error: {error}', file=sys.stderr)
+        print(f'\nThe hidden API flags in {args.flags} differ from the '
+              f'snapshot in {args.snapshot} in a way that is not allowed, '
+              f'see {args.output} for the full report.\nIf the changes are '
+              f'intended, update the snapshot.', file=sys.stderr)
+        sys.exit(1)
+
+
+if __name__ == '__main__':
+    main(sys.argv)
@@ -0,0 +1,79 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+"""Unit tests for diff_hiddenapi_flags.py."""
+import io
+import unittest
+
+import diff_hiddenapi_flags as dhf
+
+
+def read_flags_from_string(csvdata):
+    with io.StringIO(csvdata) as f:
+        return dhf.read_flags_from_stream(f)
+
+
+class TestDiffHiddenapiFlags(unittest.TestCase):
+
+    def diff(self, old, new):
+        return dhf.FlagsDiff(
+            read_flags_from_string(old), read_flags_from_string(new))
+
+    def test_categories(self):
+        diff = self.diff(
+            'La;->m()V,sdk\n'
+            'La;->n()V,unsupported\n'
+            'La;->o()V,blocked\n',
+            'La;->m()V,sdk,system-api\n'
+            'La;->o()V,blocked\n'
+            'La;->p()V,blocked\n')
+        self.assertEqual(['La;->p()V'], diff.added)
+        self.assertEqual(['La;->n()V'], diff.removed)
+        self.assertEqual(['La;->m()V'], diff.changed)
+        self.assertEqual([], diff.restricted)
+        self.assertEqual(
+            'New signatures (1):\n'
+            '  La;->p()V: blocked\n'
+            'Removed signatures (1):\n'
+            '  La;->n()V: unsupported\n'
+            'Changed signatures (1):\n'
+            '  La;->m()V: sdk -> sdk,system-api\n', diff.report())
+
+    def test_restricted(self):
+        diff = self.diff(
+            'La;->m()V,unsupported\n'
+            'La;->n()V,max-target-o\n',
+            'La;->m()V,blocked\n'
+            'La;->n()V,max-target-r\n')
+        self.assertEqual(['La;->m()V'], diff.restricted)
+        self.assertEqual(
+            ['La;->m()V was restricted from unsupported to blocked'],
+            diff.errors(allow_removed=False, allow_restricted=False))
+        self.assertEqual([],
+                         diff.errors(
+                             allow_removed=False, allow_restricted=True))
+
+    def test_removed(self):
+        diff = self.diff('La;->m()V,sdk\n', '')
+        self.assertEqual(['La;->m()V was removed'],
+                         diff.errors(
+                             allow_removed=False, allow_restricted=False))
+        self.assertEqual([],
+                         diff.errors(
+                             allow_removed=True, allow_restricted=False))
+
+
+if __name__ == '__main__':
+    unittest.main(verbosity=2)
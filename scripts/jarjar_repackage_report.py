@@ -0,0 +1,126 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for reporting the classes that jarjar repackages.
+
+Applies the rename rules of a jarjar rules file to the classes in the jar that
+is passed to jarjar, and writes one line per renamed class attributed to the
+module.
+"""
+
+import argparse
+import re
+import zipfile
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--rules', required=True,
+                      help='the jarjar rules file')
+  parser.add_argument('--module', required=True,
+                      help='the name of the module the jar belongs to')
+  parser.add_argument('--output', required=True,
+                      help='file to write the report to')
+  parser.add_argument('jar', help='the jar that jarjar is run on')
+  return parser.parse_args()
+
+
+def wildcard_to_regex(pattern):
+  """Converts a jarjar class pattern to a regex, the same way jarjar does.
+
+  * matches any characters in a single package name segment and ** matches
+  any characters including package separators. A trailing ** also matches the
+  empty string.
+  """
+  if pattern == '**':
+    raise ValueError("'**' is not a valid pattern")
+  regex = ''
+  for part in re.split(r'(\*\*|\*)', pattern.replace('.', '/')):
+    if part == '**':
+      regex += '(.+?)'
+    elif part == '*':
+      regex += '([^/]+)'
+    else:
+      regex += re.escape(part)
+  if regex.endswith('(.+?)'):
+    regex = regex[:-len('(.+?)')] + '(.*?)'
+  return re.compile(r'\A' + regex + r'\Z')
+
+
+def parse_rules(lines):
+  """Returns the (regex, result) of the rename rules in a jarjar rules file.
+
+  zap and keep rules don't rename classes and are ignored.
+  """
+  rules = []
+  for line in lines:
+    words = line.split('#', 1)[0].split()
+    if len(words) == 3 and words[0] == 'rule':
+      rules.append((wildcard_to_regex(words[1]), words[2].replace('.', '/')))
+  return rules
+
+
+def rename(name, rules):
+  """Returns the name a class is renamed to by the first matching rule.
+
+  name is the internal name of the class, e.g. com/foo/Bar. Returns None if
+  no rule matches the class.
+  """
+  for regex, result in rules:
+    match = regex.match(name)
+    if not match:
+      continue
+    return re.sub(r'@(\d+)', lambda m: match.group(int(m.group(1))), result)
+  return None
+
+
+def find_renamed_classes(entries, rules):
+  """Returns a sorted list of (original, renamed) class names."""
+  renamed = []
+  for entry in entries:
+    if not entry.endswith('.class') or entry.endswith('module-info.class'):
+      continue
+    if entry.startswith('META-INF/'):
+      continue
+    name = entry[:-len('.class')]
+    result = rename(name, rules)
+    if result is not None and result != name:
+      renamed.append((name.replace('/', '.'), result.replace('/', '.')))
+  return sorted(renamed)
+
+
+def format_report(module, renamed):
+  return ''.join('%s: %s -> %s\n' % (module, original, result)
+                 for original, result in renamed)
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  with open(args.rules) as f:
+    rules = parse_rules(f.readlines())
+  with zipfile.ZipFile(args.jar) as z:
+    renamed = find_renamed_classes(z.namelist(), rules)
+
+  with open(args.output, 'w') as f:
+    f.write(format_report(args.module, renamed))
+
+
+if __name__ == '__main__':
+  main()
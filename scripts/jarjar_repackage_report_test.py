@@ -0,0 +1,86 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for jarjar_repackage_report.py."""
+
+import sys
+import unittest
+
+import jarjar_repackage_report
+
+sys.dont_write_bytecode = True
+
+
+class RenameTest(unittest.TestCase):
+  """ Unit tests for the jarjar rule matching """
+
+  rules = jarjar_repackage_report.parse_rules([
+      '# A comment',
+      'rule com.foo.*Impl com.internal.foo.@1Impl',
+      'rule com.foo.** com.repackaged.foo.@1',
+      'zap com.bar.**',
+      'keep com.baz.**',
+  ])
+
+  def test_single_segment_wildcard(self):
+    self.assertEqual('com/internal/foo/BarImpl',
+                     jarjar_repackage_report.rename('com/foo/BarImpl',
+                                                    self.rules))
+
+  def test_first_matching_rule_wins(self):
+    self.assertEqual('com/repackaged/foo/sub/BarImpl',
+                     jarjar_repackage_report.rename('com/foo/sub/BarImpl',
+                                                    self.rules))
+
+  def test_inner_class(self):
+    self.assertEqual('com/repackaged/foo/Bar$Inner',
+                     jarjar_repackage_report.rename('com/foo/Bar$Inner',
+                                                    self.rules))
+
+  def test_no_match(self):
+    self.assertIsNone(jarjar_repackage_report.rename('com/bar/Bar',
+                                                     self.rules))
+    self.assertIsNone(jarjar_repackage_report.rename('com/foobar/Bar',
+                                                     self.rules))
+
+  def test_invalid_pattern(self):
+    with self.assertRaises(ValueError):
+      jarjar_repackage_report.wildcard_to_regex('**')
+
+
+class ReportTest(unittest.TestCase):
+  """ Unit tests for generating and merging reports """
+
+  def test_find_renamed_classes(self):
+    rules = jarjar_repackage_report.parse_rules(
+        ['rule com.foo.** com.repackaged.foo.@1'])
+    entries = ['com/foo/B.class', 'com/foo/A.class', 'com/bar/C.class',
+               'module-info.class', 'META-INF/versions/9/com/foo/A.class',
+               'com/foo/res.txt']
+    self.assertEqual(
+        [('com.foo.A', 'com.repackaged.foo.A'),
+         ('com.foo.B', 'com.repackaged.foo.B')],
+        jarjar_repackage_report.find_renamed_classes(entries, rules))
+
+  def test_format_report(self):
+    self.assertEqual(
+        'foo: com.foo.A -> com.repackaged.foo.A\n',
+        jarjar_repackage_report.format_report(
+            'foo', [('com.foo.A', 'com.repackaged.foo.A')]))
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -55,6 +55,8 @@ def parse_args():
   parser.convert_arg_line_to_args = convert_arg_line_to_args
   parser.add_argument('--project_out', dest='project_out',
                       help='file to which the project.xml contents will be written.')
+  parser.add_argument('--report_project_out', dest='report_project_out',
+                      help='file to which the project.xml contents for merging partial results will be written.')
   parser.add_argument('--config_out', dest='config_out',
                       help='file to which the lint.xml contents will be written.')
   parser.add_argument('--name', dest='name',
@@ -83,6 +85,11 @@ def parse_args():
                       help='directory to use for cached file.')
   parser.add_argument('--root_dir', dest='root_dir',
                       help='directory to use for root dir.')
+  parser.add_argument('--partial_results_dir', dest='partial_results_dir',
+                      help='directory to use for the partial results of the module.')
+  parser.add_argument('--dependency', dest='dependencies', action='append', default=[],
+                      help='name and partial results directory of a library, separated by a colon, whose '
+                      'partial results are merged when reporting.')
   group = parser.add_argument_group('check arguments', 'later arguments override earlier ones.')
   group.add_argument('--fatal_check', dest='checks', action=check_action('fatal'), default=[],
                      help='treat a lint issue as a fatal error.')
@@ -95,14 +102,18 @@ def parse_args():
   return parser.parse_args()
 
 
-def write_project_xml(f, args):
+def write_project_xml(f, args, dependencies=()):
   test_attr = "test='true' " if args.test else ""
+  partial_results_attr = ""
+  if args.partial_results_dir:
+    partial_results_attr = "partial-results-dir='%s' " % args.partial_results_dir
 
   f.write("<?xml version='1.0' encoding='utf-8'?>\n")
   f.write("<project>\n")
   if args.root_dir:
     f.write("  <root dir='%s' />\n" % args.root_dir)
-  f.write("  <module name='%s' android='true' %sdesugar='full' >\n" % (args.name, "library='true' " if args.library else ""))
+  f.write("  <module name='%s' android='true' %s%sdesugar='full' >\n" % (
+      args.name, "library='true' " if args.library else "", partial_results_attr))
   if args.manifest:
     f.write("    <manifest file='%s' %s/>\n" % (args.manifest, test_attr))
   if args.merged_manifest:
@@ -122,7 +133,12 @@ def write_project_xml(f, args):
     f.write("    <classpath jar='%s' />\n" % classpath)
   for extra in args.extra_checks_jars:
     f.write("    <lint-checks jar='%s' />\n" % extra)
+  for name, _ in dependencies:
+    f.write("    <dep module='%s' />\n" % name)
   f.write("  </module>\n")
+  for name, partial_results_dir in dependencies:
+    f.write("  <module name='%s' android='true' library='true' partial-results-dir='%s' />\n" % (
+        name, partial_results_dir))
   if args.cache_dir:
     f.write("  <cache dir='%s'/>\n" % args.cache_dir)
   f.write("</project>\n")
@@ -144,6 +160,11 @@ def main():
     with open(args.project_out, 'w') as f:
       write_project_xml(f, args)
 
+  if args.report_project_out:
+    dependencies = [dep.split(':', 1) for dep in args.dependencies]
+    with open(args.report_project_out, 'w') as f:
+      write_project_xml(f, args, dependencies)
+
   if args.config_out:
     with open(args.config_out, 'w') as f:
       write_config_xml(f, args)
@@ -66,6 +66,12 @@ def parse_args():
         dest='enforce_uses_libraries_relax',
         action='store_true',
         help='do not fail immediately, just save the error message to file')
+    parser.add_argument(
+        '--enforce-uses-libraries-warn',
+        dest='enforce_uses_libraries_warn',
+        action='store_true',
+        help='like --enforce-uses-libraries-relax, but also print the error '
+        'message as a warning')
     parser.add_argument(
         '--enforce-uses-libraries-status',
         dest='enforce_uses_libraries_status',
@@ -147,6 +153,8 @@ def enforce_uses_libraries(manifest, required, optional, missing_optional, relax
         ' (this will set compiler filter "verify" and disable AOT-compilation in dexpreopt)\n',
         '\t- to temporarily disable the check for the whole product, set ',
         '%sPRODUCT_BROKEN_VERIFY_USES_LIBRARIES := true%s in the product makefiles\n' % (C_BOLD, C_OFF),
+        '\t- to report the mismatch as a warning for this module only, set ',
+        '%srelax_uses_libs_check: true%s in its Android.bp\n' % (C_BOLD, C_OFF),
         '\t- to fix the check, make build system properties coherent with the manifest\n',
         '\t- for details, see %sbuild/make/Changes.md%s' % (C_GREEN, C_OFF),
         ' and %shttps://source.android.com/devices/tech/dalvik/art-class-loader-context%s\n' % (C_GREEN, C_OFF)
@@ -359,9 +367,14 @@ def main():
             # Check if the <uses-library> lists in the build system agree with
             # those in the manifest. Raise an exception on mismatch, unless the
             # script was passed a special parameter to suppress exceptions.
+            relax = (args.enforce_uses_libraries_relax or
+                     args.enforce_uses_libraries_warn)
             errmsg = enforce_uses_libraries(manifest, required, optional,
                 args.missing_optional_uses_libraries,
-                args.enforce_uses_libraries_relax, is_apk, args.input)
+                relax, is_apk, args.input)
+            if errmsg is not None and args.enforce_uses_libraries_warn:
+                print('%swarning:%s ' % (C_BLUE, C_OFF) + errmsg,
+                      file=sys.stderr)
 
             # Create a status file that is empty on success, or contains an
             # error message on failure. When exceptions are suppressed,
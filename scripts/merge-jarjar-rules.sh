@@ -0,0 +1,46 @@
+#!/bin/bash
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+set -e
+
+if [[ $# -le 1 ]]; then
+  cat <<EOF2
+Usage:
+  merge-jarjar-rules.sh <output> <rules file>...
+Concatenates the jarjar rules files into <output>, failing if two of the
+files repackage the same pattern to different results.
+EOF2
+  exit 1
+fi
+
+out="$1"
+shift
+
+awk -v out="${out}.tmp" '
+{ print > out }
+$1 == "rule" {
+  if (($2 in results) && results[$2] != $3) {
+    printf "error: conflicting jarjar rules for %s: %s in %s and %s in %s\n",
+      $2, results[$2], files[$2], $3, FILENAME > "/dev/stderr"
+    failed = 1
+  }
+  results[$2] = $3
+  files[$2] = FILENAME
+}
+END { exit failed }
+' "$@"
+
+mv "${out}.tmp" "${out}"
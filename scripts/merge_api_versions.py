@@ -0,0 +1,99 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for merging SDK extension api levels into an api-versions.xml file.
+
+Lint detects calls to APIs that are newer than the min_sdk_version of a module
+using an api-versions.xml database. APIs that are made available by SDK
+extensions are listed with an sdks attribute, which lint uses to accept calls
+that are guarded by an SDK extension version check. The database generated for
+the platform APIs may lag behind the SDK extensions, so this tool merges an
+api-versions.xml file generated with the SDK extension info into it: the
+declared SDKs, the sdks attributes of the classes and members that don't have
+one, and the classes and members that are only listed with the extensions.
+"""
+
+import argparse
+import sys
+import xml.etree.ElementTree as ET
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--extensions', required=True,
+                      help='api-versions.xml generated with the SDK extension '
+                      'info')
+  parser.add_argument('--output', required=True,
+                      help='file to write the merged api-versions.xml to')
+  parser.add_argument('input', help='api-versions.xml to merge into')
+  return parser.parse_args()
+
+
+def element_key(element):
+  """Returns the key identifying an element among its siblings."""
+  return element.tag, element.get('name', element.get('id'))
+
+
+def merge_element(merged, extension):
+  """Merges the sdks attribute and children of extension into merged."""
+  if 'sdks' not in merged.attrib and 'sdks' in extension.attrib:
+    merged.set('sdks', extension.get('sdks'))
+
+  children = {element_key(child): child for child in merged}
+  for child in extension:
+    key = element_key(child)
+    if key in children:
+      merge_element(children[key], child)
+    else:
+      merged.append(child)
+      children[key] = child
+
+
+def merge_api_versions(api, extensions):
+  """Merges the SDK extension api levels in extensions into api."""
+  # The SDKs are declared before the classes, keep them there.
+  sdk_ids = {sdk.get('id') for sdk in api.findall('sdk')}
+  index = len(api.findall('sdk'))
+  for sdk in extensions.findall('sdk'):
+    if sdk.get('id') not in sdk_ids:
+      api.insert(index, sdk)
+      sdk_ids.add(sdk.get('id'))
+      index += 1
+
+  classes = ET.Element('api')
+  classes.extend(c for c in extensions if c.tag != 'sdk')
+  merge_element(api, classes)
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  try:
+    tree = ET.parse(args.input)
+    merge_api_versions(tree.getroot(), ET.parse(args.extensions).getroot())
+    tree.write(args.output, encoding='utf-8', xml_declaration=True)
+
+  # pylint: disable=broad-except
+  except Exception as err:
+    print('error: ' + str(err), file=sys.stderr)
+    sys.exit(-1)
+
+
+if __name__ == '__main__':
+  main()
@@ -0,0 +1,91 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for merge_api_versions.py."""
+
+import sys
+import unittest
+import xml.etree.ElementTree as ET
+
+import merge_api_versions
+
+sys.dont_write_bytecode = True
+
+
+def merge(api, extensions):
+  root = ET.fromstring(api)
+  merge_api_versions.merge_api_versions(root, ET.fromstring(extensions))
+  return root
+
+
+class MergeApiVersionsTest(unittest.TestCase):
+  """ Unit tests for merge_api_versions function """
+
+  def test_sdks(self):
+    root = merge(
+        '<api version="3">'
+        '<sdk id="30" shortname="R-ext" name="R Extensions" />'
+        '<class name="a/A" since="1" />'
+        '</api>',
+        '<api version="3">'
+        '<sdk id="30" shortname="R-ext" name="R Extensions" />'
+        '<sdk id="31" shortname="S-ext" name="S Extensions" />'
+        '</api>')
+    self.assertEqual(['sdk', 'sdk', 'class'], [e.tag for e in root])
+    self.assertEqual(['30', '31'], [e.get('id') for e in root.findall('sdk')])
+
+  def test_sdks_attribute(self):
+    root = merge(
+        '<api version="3">'
+        '<class name="a/A" since="1">'
+        '<method name="foo()V" since="34" />'
+        '<method name="bar()V" since="33" sdks="30:4,0:33" />'
+        '</class>'
+        '</api>',
+        '<api version="3">'
+        '<class name="a/A" since="1" sdks="0:1">'
+        '<method name="foo()V" since="34" sdks="30:5,0:34" />'
+        '<method name="bar()V" since="33" sdks="30:3,0:33" />'
+        '</class>'
+        '</api>')
+    cls = root.find('class')
+    self.assertEqual('0:1', cls.get('sdks'))
+    methods = {m.get('name'): m.get('sdks') for m in cls.findall('method')}
+    self.assertEqual({'foo()V': '30:5,0:34', 'bar()V': '30:4,0:33'}, methods)
+
+  def test_missing_elements(self):
+    root = merge(
+        '<api version="3">'
+        '<class name="a/A" since="1">'
+        '<method name="foo()V" since="1" />'
+        '</class>'
+        '</api>',
+        '<api version="3">'
+        '<class name="a/A" since="1">'
+        '<field name="BAR" since="34" sdks="30:5" />'
+        '</class>'
+        '<class name="a/B" since="34" sdks="30:5">'
+        '<method name="baz()V" since="34" sdks="30:5" />'
+        '</class>'
+        '</api>')
+    self.assertEqual(['a/A', 'a/B'], [c.get('name') for c in root.findall('class')])
+    self.assertEqual(['foo()V'], [m.get('name') for m in root.find('class').findall('method')])
+    self.assertEqual('30:5', root.find('class/field').get('sdks'))
+    self.assertEqual('30:5', root.find("class[@name='a/B']/method").get('sdks'))
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -0,0 +1,203 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A content keyed cache for metalava invocations.
+
+With --hash_inputs, writes a digest of the contents of the files listed in the
+given file, which are the declared inputs and tools of the metalava rule, to
+the --key file.
+
+Otherwise runs the command following -- unless an earlier run with the same
+key has already been stored in the cache directory, in which case the files
+that run wrote to the output directory are restored instead.  The key is a
+digest of the --key file and the command line.  The command runs in the sbox
+sandbox, so paths on the command line are relative to the sandbox and
+identical invocations from different products share cache entries.
+
+The cache is bounded to --max_size_mb, above which the least recently used
+entries are evicted.
+"""
+
+import argparse
+import hashlib
+import os
+import shutil
+import subprocess
+import sys
+import tempfile
+
+# Bump this to invalidate all existing cache entries when the layout or the
+# key computation changes.
+CACHE_VERSION = '3'
+
+
+def parse_args(argv):
+  parser = argparse.ArgumentParser(description=__doc__)
+  parser.add_argument(
+      '--hash_inputs',
+      help='file listing the inputs of the command to hash into --key')
+  parser.add_argument(
+      '--key', required=True,
+      help='file holding the digest of the inputs of the command')
+  parser.add_argument(
+      '--cache_dir',
+      help='directory holding the cache entries, shared between builds')
+  parser.add_argument(
+      '--max_size_mb', type=int, default=10 * 1024,
+      help='size of the cache above which entries are evicted')
+  parser.add_argument(
+      '--out_dir',
+      help='directory whose new or modified files are stored in the cache')
+  parser.add_argument('command', nargs=argparse.REMAINDER)
+  args = parser.parse_args(argv)
+  if args.hash_inputs:
+    return args
+  if not args.cache_dir or not args.out_dir:
+    parser.error('--cache_dir and --out_dir are required to run a command')
+  if args.command and args.command[0] == '--':
+    args.command = args.command[1:]
+  if not args.command:
+    parser.error('missing command after --')
+  return args
+
+
+def hash_file(h, path):
+  h.update(b'file\0' + path.encode() + b'\0')
+  with open(path, 'rb') as f:
+    for chunk in iter(lambda: f.read(1 << 20), b''):
+      h.update(chunk)
+
+
+def inputs_digest(inputs):
+  """Computes the digest of the contents of the inputs of the command."""
+  h = hashlib.sha256()
+  for path in inputs:
+    hash_file(h, path)
+  return h.hexdigest()
+
+
+def cache_key(command, inputs_key):
+  """Computes the cache key for running command with the given inputs."""
+  h = hashlib.sha256()
+  h.update(CACHE_VERSION.encode() + b'\0')
+  h.update(b'inputs\0' + inputs_key.encode() + b'\0')
+  for arg in command:
+    h.update(b'arg\0' + arg.encode() + b'\0')
+  return h.hexdigest()
+
+
+def read_inputs(path):
+  with open(path) as f:
+    return [line for line in f.read().splitlines() if line]
+
+
+def snapshot(out_dir):
+  """Returns the size and modification time of every file under out_dir."""
+  files = {}
+  for root, _, names in os.walk(out_dir):
+    for name in names:
+      path = os.path.join(root, name)
+      st = os.stat(path)
+      files[os.path.relpath(path, out_dir)] = (st.st_size, st.st_mtime_ns)
+  return files
+
+
+def restore(entry, out_dir):
+  files_dir = os.path.join(entry, 'files')
+  for root, _, names in os.walk(files_dir):
+    for name in names:
+      src = os.path.join(root, name)
+      dst = os.path.join(out_dir, os.path.relpath(src, files_dir))
+      os.makedirs(os.path.dirname(dst), exist_ok=True)
+      shutil.copyfile(src, dst)
+  # Entries are evicted in the order they were last used.
+  os.utime(entry)
+
+
+def store(entry, out_dir, before, cache_dir):
+  """Stores the files under out_dir that changed since before into entry."""
+  after = snapshot(out_dir)
+  tmp = tempfile.mkdtemp(dir=cache_dir, prefix='.tmp-')
+  try:
+    for rel, stat in after.items():
+      if before.get(rel) == stat:
+        continue
+      dst = os.path.join(tmp, 'files', rel)
+      os.makedirs(os.path.dirname(dst), exist_ok=True)
+      shutil.copyfile(os.path.join(out_dir, rel), dst)
+    os.makedirs(os.path.join(tmp, 'files'), exist_ok=True)
+    # Another build may have stored the same entry concurrently, in which case
+    # the rename fails and the existing entry is kept.
+    os.rename(tmp, entry)
+  except OSError:
+    shutil.rmtree(tmp, ignore_errors=True)
+
+
+def entry_size(entry):
+  size = 0
+  for root, _, names in os.walk(entry):
+    for name in names:
+      size += os.lstat(os.path.join(root, name)).st_size
+  return size
+
+
+def evict(cache_dir, max_size):
+  """Removes the least recently used entries until the cache fits max_size."""
+  entries = []
+  for name in os.listdir(cache_dir):
+    path = os.path.join(cache_dir, name)
+    if name.startswith('.') or not os.path.isdir(path):
+      continue
+    try:
+      entries.append((os.stat(path).st_mtime, entry_size(path), path))
+    except OSError:
+      # The entry was evicted concurrently by another build.
+      continue
+  total = sum(size for _, size, _ in entries)
+  for _, size, path in sorted(entries):
+    if total <= max_size:
+      break
+    shutil.rmtree(path, ignore_errors=True)
+    total -= size
+
+
+def main(argv):
+  args = parse_args(argv)
+  if args.hash_inputs:
+    with open(args.key, 'w') as f:
+      f.write(inputs_digest(read_inputs(args.hash_inputs)) + '\n')
+    return 0
+
+  os.makedirs(args.cache_dir, exist_ok=True)
+
+  with open(args.key) as f:
+    inputs_key = f.read().strip()
+  key = cache_key(args.command, inputs_key)
+  entry = os.path.join(args.cache_dir, key)
+  if os.path.isdir(entry):
+    restore(entry, args.out_dir)
+    return 0
+
+  before = snapshot(args.out_dir)
+  returncode = subprocess.call(args.command)
+  if returncode == 0:
+    store(entry, args.out_dir, before, args.cache_dir)
+    evict(args.cache_dir, args.max_size_mb * 1024 * 1024)
+  return returncode
+
+
+if __name__ == '__main__':
+  sys.exit(main(sys.argv[1:]))
@@ -0,0 +1,120 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Tests for metalava_cache."""
+
+import metalava_cache
+import os
+import shutil
+import tempfile
+import unittest
+
+
+class MetalavaCacheTest(unittest.TestCase):
+
+  def setUp(self):
+    self.old_cwd = os.getcwd()
+    self.tmp = tempfile.mkdtemp()
+    os.chdir(self.tmp)
+    os.makedirs('out')
+    self.write('a.java', 'class A {}')
+    self.write('srcs.rsp', 'a.java')
+    self.write('lib.jar', 'lib')
+    self.write('inputs.txt', 'a.java\nlib.jar\n')
+
+  def tearDown(self):
+    os.chdir(self.old_cwd)
+    shutil.rmtree(self.tmp)
+
+  def write(self, path, contents):
+    if os.path.dirname(path):
+      os.makedirs(os.path.dirname(path), exist_ok=True)
+    with open(path, 'w') as f:
+      f.write(contents)
+
+  def read(self, path):
+    with open(path) as f:
+      return f.read()
+
+  def run_cached(self, marker, max_size_mb=1):
+    # The key is computed by a separate rule that runs whenever the inputs
+    # change.
+    self.assertEqual(metalava_cache.main([
+        '--hash_inputs', 'inputs.txt', '--key', 'key',
+    ]), 0)
+    # The command appends to a file outside out_dir so the test can tell
+    # whether it actually ran.
+    return metalava_cache.main([
+        '--cache_dir', 'cache', '--max_size_mb', str(max_size_mb),
+        '--out_dir', 'out', '--key', 'key',
+        '--', 'sh', '-c', 'echo ran >> runs; echo %s > out/api.txt' % marker,
+        '@srcs.rsp', '--classpath', 'lib.jar',
+    ])
+
+  def runs(self):
+    return self.read('runs').count('ran') if os.path.exists('runs') else 0
+
+  def test_hit_restores_outputs(self):
+    self.assertEqual(self.run_cached('first'), 0)
+    os.remove('out/api.txt')
+    self.assertEqual(self.run_cached('first'), 0)
+    self.assertEqual(self.runs(), 1)
+    self.assertEqual(self.read('out/api.txt'), 'first\n')
+
+  def test_input_change_misses(self):
+    self.run_cached('first')
+    self.write('a.java', 'class A { int x; }')
+    self.run_cached('first')
+    self.assertEqual(self.runs(), 2)
+
+  def test_classpath_change_misses(self):
+    self.run_cached('first')
+    self.write('lib.jar', 'lib2')
+    self.run_cached('first')
+    self.assertEqual(self.runs(), 2)
+
+  def test_undeclared_file_change_hits(self):
+    self.run_cached('first')
+    self.write('srcs.rsp', 'a.java b.java')
+    self.run_cached('first')
+    self.assertEqual(self.runs(), 1)
+    self.assertEqual(self.read('out/api.txt'), 'first\n')
+
+  def test_argument_change_misses(self):
+    self.run_cached('first')
+    self.run_cached('second')
+    self.assertEqual(self.runs(), 2)
+    self.assertEqual(self.read('out/api.txt'), 'second\n')
+
+  def test_failure_is_not_cached(self):
+    self.write('key', 'digest\n')
+    args = ['--cache_dir', 'cache', '--out_dir', 'out', '--key', 'key',
+            '--', 'false']
+    self.assertNotEqual(metalava_cache.main(args), 0)
+    self.assertEqual(os.listdir('cache'), [])
+
+  def test_least_recently_used_entries_are_evicted(self):
+    self.write('cache/old/files/api.txt', 'x' * 1024 * 1024)
+    os.utime('cache/old', (0, 0))
+    self.run_cached('first')
+    self.assertEqual(len(os.listdir('cache')), 1)
+    self.assertFalse(os.path.exists('cache/old'))
+    self.run_cached('first')
+    self.assertEqual(self.runs(), 1)
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -0,0 +1,58 @@
+#!/bin/bash
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Runs a command that runs annotation processors in a network namespace without network access,
+# so that processors that try to access the network fail the build instead of making it depend on
+# the network. When the command fails after trying to access the network, the annotation
+# processors it runs are listed.
+
+if [[ $# -lt 3 || "$2" != "--" ]]; then
+  cat <<EOF2 >&2
+Usage: $0 <processors> -- <command> [args...]
+  <processors> is a comma separated list of the annotation processors run by <command>, it may be
+  empty if <command> discovers the annotation processors in its processor path.
+EOF2
+  exit 1
+fi
+
+processors="$1"
+shift 2
+
+if ! unshare --net --map-root-user true 2>/dev/null; then
+  echo "error: failed to create a network namespace to run annotation processors without network access." >&2
+  echo "Unset SOONG_JAVA_NETWORK_SANDBOX on hosts that don't support user namespaces." >&2
+  exit 1
+fi
+
+log=$(mktemp)
+trap 'rm -f "${log}"' EXIT
+
+unshare --net --map-root-user -- "$@" 2>"${log}"
+ret=$?
+cat "${log}" >&2
+
+if [[ ${ret} -ne 0 ]] &&
+  grep -qE 'UnknownHostException|ConnectException|NoRouteToHostException|Network is unreachable' "${log}"; then
+  echo "error: an annotation processor tried to access the network, which is not allowed in the build." >&2
+  if [[ -n "${processors}" ]]; then
+    echo "The annotation processors run by this command are: ${processors//,/, }" >&2
+  else
+    echo "The annotation processors run by this command are discovered in its processor path." >&2
+  fi
+  echo "Fix the annotation processor, or set allow_network_access: true on the module if it can't be fixed." >&2
+fi
+
+exit ${ret}
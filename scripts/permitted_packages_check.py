@@ -0,0 +1,129 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for checking permitted_packages against the contents of a jar.
+
+The packages of the classes in the jar are compared with the permitted_packages
+of the module. A package is missing if it contains classes but is not covered
+by any of the permitted packages, and a permitted package is spurious if it
+does not cover any of the classes. The differences are written to the output
+file together with the permitted_packages inferred from the jar, and the check
+fails if there are any.
+"""
+
+import argparse
+import sys
+import zipfile
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser()
+  parser.add_argument('--module', required=True, dest='module',
+                      help='name of the module that is checked')
+  parser.add_argument('--jar', required=True, dest='jar',
+                      help='implementation jar of the module')
+  parser.add_argument('--output', required=True, dest='output',
+                      help='file to write the differences to')
+  parser.add_argument('permitted_packages', nargs='+',
+                      help='permitted_packages of the module')
+  return parser.parse_args()
+
+
+def class_packages(names):
+  """Returns the packages of the class files in a list of jar entries."""
+  packages = set()
+  for name in names:
+    if not name.endswith('.class') or name.startswith('META-INF/'):
+      continue
+    if '/' in name:
+      packages.add(name.rsplit('/', 1)[0].replace('/', '.'))
+    else:
+      packages.add('')
+  return packages
+
+
+def covers(permitted, package):
+  """Returns true if the permitted package covers the package."""
+  return package == permitted or package.startswith(permitted + '.')
+
+
+def collapse(packages):
+  """Removes the packages that are covered by another one of the packages."""
+  return sorted(p for p in packages
+                if not any(covers(o, p) for o in packages if o != p))
+
+
+def diff(permitted_packages, packages):
+  """Compares the permitted packages with the packages of the classes.
+
+  Returns the missing packages, the spurious permitted packages and the
+  permitted packages inferred from the classes, which keeps the permitted
+  packages that are used and adds the missing ones.
+  """
+  uncovered = [p for p in packages
+               if not any(covers(permitted, p) for permitted in permitted_packages)]
+  missing = collapse(uncovered)
+  spurious = sorted(permitted for permitted in set(permitted_packages)
+                    if not any(covers(permitted, p) for p in packages))
+  used = set(permitted_packages) - set(spurious)
+  inferred = collapse(used | set(missing))
+  return missing, spurious, inferred
+
+
+def format_diff(module, missing, spurious, inferred):
+  """Returns the lines describing the differences."""
+  lines = []
+  for package in missing:
+    lines.append('+%s' % package)
+  for package in spurious:
+    lines.append('-%s' % package)
+  if lines:
+    lines = [
+        'permitted_packages of %s does not match the packages of its classes:'
+        % module,
+    ] + ['    ' + line for line in lines] + [
+        'missing packages (+) contain classes but are not permitted, '
+        'spurious packages (-) do not contain any classes.',
+        'Update permitted_packages of %s to:' % module,
+        '    permitted_packages: [',
+    ] + ['        "%s",' % package for package in inferred] + [
+        '    ],',
+    ]
+  return lines
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  with zipfile.ZipFile(args.jar) as jar:
+    packages = class_packages(jar.namelist())
+
+  missing, spurious, inferred = diff(args.permitted_packages, packages)
+  lines = format_diff(args.module, missing, spurious, inferred)
+
+  with open(args.output, 'w') as f:
+    f.write(''.join(line + '\n' for line in lines))
+
+  if lines:
+    print('error: ' + '\n'.join(lines), file=sys.stderr)
+    sys.exit(1)
+
+
+if __name__ == '__main__':
+  main()
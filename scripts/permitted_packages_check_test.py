@@ -0,0 +1,95 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for permitted_packages_check.py."""
+
+import sys
+import unittest
+
+import permitted_packages_check
+
+sys.dont_write_bytecode = True
+
+
+class ClassPackagesTest(unittest.TestCase):
+  """ Unit tests for class_packages function """
+
+  def test_class_packages(self):
+    self.assertEqual(
+        {'com.foo', 'com.foo.bar'},
+        permitted_packages_check.class_packages([
+            'META-INF/MANIFEST.MF',
+            'META-INF/versions/9/module-info.class',
+            'com/foo/Foo.class',
+            'com/foo/Foo$Inner.class',
+            'com/foo/bar/Bar.class',
+            'com/foo/bar/resource.txt',
+        ]))
+
+
+class DiffTest(unittest.TestCase):
+  """ Unit tests for diff function """
+
+  def test_match(self):
+    self.assertEqual(
+        ([], [], ['com.foo']),
+        permitted_packages_check.diff(['com.foo'], {'com.foo', 'com.foo.bar'}))
+
+  def test_missing(self):
+    self.assertEqual(
+        (['com.bar', 'org.baz'], [], ['com.bar', 'com.foo', 'org.baz']),
+        permitted_packages_check.diff(
+            ['com.foo'],
+            {'com.foo', 'com.bar', 'com.bar.internal', 'org.baz'}))
+
+  def test_spurious(self):
+    self.assertEqual(
+        ([], ['com.foobar', 'com.unused'], ['com.foo']),
+        permitted_packages_check.diff(
+            ['com.foo', 'com.unused', 'com.foobar'], {'com.foo.impl'}))
+
+  def test_redundant_sub_package_is_collapsed(self):
+    self.assertEqual(
+        ([], [], ['com.foo']),
+        permitted_packages_check.diff(
+            ['com.foo', 'com.foo.bar'], {'com.foo', 'com.foo.bar'}))
+
+
+class FormatDiffTest(unittest.TestCase):
+  """ Unit tests for format_diff function """
+
+  def test_no_differences(self):
+    self.assertEqual(
+        [], permitted_packages_check.format_diff('foo', [], [], ['com.foo']))
+
+  def test_differences(self):
+    self.assertEqual(
+        ['permitted_packages of foo does not match the packages of its classes:',
+         '    +com.bar',
+         '    -com.unused',
+         'missing packages (+) contain classes but are not permitted, '
+         'spurious packages (-) do not contain any classes.',
+         'Update permitted_packages of foo to:',
+         '    permitted_packages: [',
+         '        "com.bar",',
+         '        "com.foo",',
+         '    ],'],
+        permitted_packages_check.format_diff(
+            'foo', ['com.bar'], ['com.unused'], ['com.bar', 'com.foo']))
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -0,0 +1,147 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""A tool for sharding the test classes of a Robolectric test.
+
+The config subcommand distributes the test classes found in the sources of a
+Robolectric test between a number of shards and writes a test config for each
+shard that only runs the test classes assigned to it, so that the shards can
+run in parallel.
+
+The merge subcommand merges the JUnit XML results of the shards into a single
+result file.
+"""
+
+import argparse
+import os
+import re
+import sys
+from xml.dom import minidom
+
+from manifest import get_children_with_tag
+from manifest import parse_test_config
+from manifest import write_xml
+
+PACKAGE_RE = re.compile(r'^\s*package\s+([\w.]+)', re.MULTILINE)
+
+
+def parse_args():
+  """Parse commandline arguments."""
+
+  parser = argparse.ArgumentParser(fromfile_prefix_chars='@')
+  subparsers = parser.add_subparsers(dest='command', required=True)
+
+  config = subparsers.add_parser(
+      'config', fromfile_prefix_chars='@',
+      help='write a test config for each shard')
+  config.add_argument('--test-config', required=True, dest='test_config',
+                      help='test config that runs all the test classes')
+  config.add_argument('--output', required=True, action='append',
+                      dest='outputs',
+                      help='test config to write for a shard, one per shard')
+  config.add_argument('srcs', nargs='*',
+                      help='source files containing the test classes')
+
+  merge = subparsers.add_parser(
+      'merge', fromfile_prefix_chars='@',
+      help='merge the JUnit XML results of the shards')
+  merge.add_argument('--output', required=True, dest='output',
+                     help='file to write the merged results to')
+  merge.add_argument('results', nargs='*',
+                     help='JUnit XML result files of the shards')
+
+  return parser.parse_args()
+
+
+def test_class(path, contents):
+  """Returns the fully qualified name of the test class in a source file."""
+  name = os.path.splitext(os.path.basename(path))[0]
+  match = PACKAGE_RE.search(contents)
+  if match:
+    return match.group(1) + '.' + name
+  return name
+
+
+def shard_classes(classes, shard_count):
+  """Distributes the sorted test classes round robin between the shards."""
+  shards = [[] for _ in range(shard_count)]
+  for i, cls in enumerate(sorted(set(classes))):
+    shards[i % shard_count].append(cls)
+  return shards
+
+
+def shard_test_config(doc, classes):
+  """Restricts the tests in the test config to the supplied test classes.
+
+  A shard without any test classes has its tests removed, as a test without
+  filters would run all the test classes.
+  """
+  test_config = parse_test_config(doc)
+  for test in get_children_with_tag(test_config, 'test'):
+    if not classes:
+      test_config.removeChild(test)
+      continue
+    for cls in classes:
+      option = doc.createElement('option')
+      option.setAttribute('name', 'include-filter')
+      option.setAttribute('value', cls)
+      test.appendChild(option)
+
+
+def merge_results(docs):
+  """Returns a document containing the test suites of all the results."""
+  merged = minidom.getDOMImplementation().createDocument(
+      None, 'testsuites', None)
+  for doc in docs:
+    root = doc.documentElement
+    if root.tagName == 'testsuite':
+      suites = [root]
+    else:
+      suites = get_children_with_tag(root, 'testsuite')
+    for suite in suites:
+      merged.documentElement.appendChild(merged.importNode(suite, True))
+  return merged
+
+
+def main():
+  """Program entry point."""
+  args = parse_args()
+
+  try:
+    if args.command == 'config':
+      classes = []
+      for src in args.srcs:
+        with open(src) as f:
+          classes.append(test_class(src, f.read()))
+      shards = shard_classes(classes, len(args.outputs))
+      for output, shard in zip(args.outputs, shards):
+        doc = minidom.parse(args.test_config)
+        shard_test_config(doc, shard)
+        with open(output, 'w') as f:
+          write_xml(f, doc)
+    else:
+      merged = merge_results([minidom.parse(r) for r in args.results])
+      with open(args.output, 'w') as f:
+        write_xml(f, merged)
+
+  # pylint: disable=broad-except
+  except Exception as err:
+    print('error: ' + str(err), file=sys.stderr)
+    sys.exit(-1)
+
+
+if __name__ == '__main__':
+  main()
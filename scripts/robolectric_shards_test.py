@@ -0,0 +1,106 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2025 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for robolectric_shards.py."""
+
+import sys
+import unittest
+from xml.dom import minidom
+
+import robolectric_shards
+
+sys.dont_write_bytecode = True
+
+TEST_CONFIG = (
+    '<configuration description="Runs FooRoboTests.">'
+    '<test class="com.android.tradefed.testtype.IsolatedHostTest">'
+    '<option name="jar" value="FooRoboTests.jar" />'
+    '</test>'
+    '</configuration>')
+
+
+class TestClassTest(unittest.TestCase):
+  """ Unit tests for test_class function """
+
+  def test_java(self):
+    self.assertEqual(
+        'com.foo.FooTest',
+        robolectric_shards.test_class(
+            'tests/src/com/foo/FooTest.java',
+            '// Copyright\n\npackage com.foo;\n\npublic class FooTest {}\n'))
+
+  def test_kotlin(self):
+    self.assertEqual(
+        'com.foo.BarTests',
+        robolectric_shards.test_class(
+            'tests/src/BarTests.kt', 'package com.foo\n\nclass BarTests\n'))
+
+  def test_default_package(self):
+    self.assertEqual(
+        'BazTest', robolectric_shards.test_class('BazTest.java', 'class BazTest {}'))
+
+
+class ShardClassesTest(unittest.TestCase):
+  """ Unit tests for shard_classes function """
+
+  def test_round_robin(self):
+    self.assertEqual(
+        [['a.A', 'a.C', 'a.E'], ['a.B', 'a.D']],
+        robolectric_shards.shard_classes(['a.E', 'a.D', 'a.C', 'a.B', 'a.A'], 2))
+
+  def test_more_shards_than_classes(self):
+    self.assertEqual(
+        [['a.A'], []],
+        robolectric_shards.shard_classes(['a.A', 'a.A'], 2))
+
+
+class ShardTestConfigTest(unittest.TestCase):
+  """ Unit tests for shard_test_config function """
+
+  def test_include_filters(self):
+    doc = minidom.parseString(TEST_CONFIG)
+    robolectric_shards.shard_test_config(doc, ['a.A', 'a.C'])
+    test = doc.getElementsByTagName('test')[0]
+    filters = [o.getAttribute('value')
+               for o in test.getElementsByTagName('option')
+               if o.getAttribute('name') == 'include-filter']
+    self.assertEqual(['a.A', 'a.C'], filters)
+
+  def test_empty_shard(self):
+    doc = minidom.parseString(TEST_CONFIG)
+    robolectric_shards.shard_test_config(doc, [])
+    self.assertEqual([], doc.getElementsByTagName('test'))
+
+
+class MergeResultsTest(unittest.TestCase):
+  """ Unit tests for merge_results function """
+
+  def test_merge(self):
+    merged = robolectric_shards.merge_results([
+        minidom.parseString('<testsuite name="a.A" tests="1" />'),
+        minidom.parseString(
+            '<testsuites><testsuite name="a.B" tests="2" />'
+            '<testsuite name="a.C" tests="3" /></testsuites>'),
+    ])
+    self.assertEqual('testsuites', merged.documentElement.tagName)
+    self.assertEqual(
+        ['a.A', 'a.B', 'a.C'],
+        [s.getAttribute('name')
+         for s in merged.getElementsByTagName('testsuite')])
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -0,0 +1,30 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+// BuildTimeBudgetsFileName is the name of the file in the Soong output directory that Soong writes
+// the build time budgets of the modules that set expected_build_seconds to, as a JSON list of
+// BuildTimeBudget, and soong_ui reads to compare them with the build times from the ninja log.
+const BuildTimeBudgetsFileName = "build_time_budgets.json"
+
+// BuildTimeBudget is the expected time to build all the variants of a module.
+type BuildTimeBudget struct {
+	// The name of the module.
+	Name string
+	// The directory of the Android.bp file that defines the module.
+	Dir string
+	// The expected time in seconds to run the actions that build the outputs of the module.
+	ExpectedBuildSeconds int64
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"syscall"
 )
@@ -31,7 +32,9 @@ func IsDebugging() bool {
 
 // Re-executes the binary in question under the control of Delve when
 // delveListen is not the empty string. delvePath gives the path to the Delve.
-func ReexecWithDelveMaybe(delveListen, delvePath string) {
+// If wait is true the binary is paused until a debugger connects, otherwise it
+// runs immediately and debuggers can attach to it while it is running.
+func ReexecWithDelveMaybe(delveListen, delvePath string, wait bool) {
 	isDebugging = os.Getenv("SOONG_DELVE_REEXECUTED") == "true"
 	if isDebugging || delveListen == "" {
 		return
@@ -52,18 +55,43 @@ func ReexecWithDelveMaybe(delveListen, delvePath string) {
 
 	soongDelveEnv = append(soongDelveEnv, "SOONG_DELVE_REEXECUTED=true")
 
+	fmt.Fprintln(os.Stderr, delveInstructions(filepath.Base(os.Args[0]), delveListen, wait))
+
+	dlvArgv := delveArgv(delvePath, delveListen, wait, os.Args)
+	syscall.Exec(delvePath, dlvArgv, soongDelveEnv)
+	fmt.Fprintln(os.Stderr, "exec() failed while trying to reexec with Delve")
+	os.Exit(1)
+}
+
+// delveArgv returns the command line that runs args under a headless Delve
+// server listening on delveListen.
+func delveArgv(delvePath, delveListen string, wait bool, args []string) []string {
 	dlvArgv := []string{
 		delvePath,
 		"--listen=:" + delveListen,
 		"--headless=true",
 		"--api-version=2",
-		"exec",
-		os.Args[0],
-		"--",
 	}
 
-	dlvArgv = append(dlvArgv, os.Args[1:]...)
-	syscall.Exec(delvePath, dlvArgv, soongDelveEnv)
-	fmt.Fprintln(os.Stderr, "exec() failed while trying to reexec with Delve")
-	os.Exit(1)
+	if !wait {
+		// Start running immediately and keep the server up for any number of
+		// debuggers attaching and detaching while the binary runs.
+		dlvArgv = append(dlvArgv, "--accept-multiclient", "--continue")
+	}
+
+	dlvArgv = append(dlvArgv, "exec", args[0], "--")
+	return append(dlvArgv, args[1:]...)
+}
+
+// delveInstructions returns the message telling the user how to connect a
+// debugger to the Delve server.
+func delveInstructions(name, delveListen string, wait bool) string {
+	if wait {
+		return fmt.Sprintf("%s is paused until a debugger connects to the Delve server on port %s, "+
+			"connect with `dlv connect :%s` or a \"Go Remote\" configuration in your IDE",
+			name, delveListen, delveListen)
+	}
+	return fmt.Sprintf("%s is running under the Delve server on port %s, "+
+		"attach with `dlv connect :%s` or a \"Go Remote\" configuration in your IDE",
+		name, delveListen, delveListen)
 }
@@ -0,0 +1,64 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDelveArgv(t *testing.T) {
+	args := []string{"soong_build", "--top", "/src", "--soong_docs", "docs.html"}
+
+	testCases := []struct {
+		name     string
+		wait     bool
+		expected []string
+	}{
+		{
+			name: "wait",
+			wait: true,
+			expected: []string{"dlv", "--listen=:5006", "--headless=true", "--api-version=2",
+				"exec", "soong_build", "--", "--top", "/src", "--soong_docs", "docs.html"},
+		},
+		{
+			name: "attach",
+			wait: false,
+			expected: []string{"dlv", "--listen=:5006", "--headless=true", "--api-version=2",
+				"--accept-multiclient", "--continue",
+				"exec", "soong_build", "--", "--top", "/src", "--soong_docs", "docs.html"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := delveArgv("dlv", "5006", tc.wait, args); !reflect.DeepEqual(tc.expected, got) {
+				t.Errorf("expected %q != got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDelveInstructions(t *testing.T) {
+	if got := delveInstructions("soong_build", "5006", true); !strings.Contains(got, "paused") ||
+		!strings.Contains(got, "dlv connect :5006") {
+		t.Errorf("unexpected instructions when waiting: %q", got)
+	}
+	if got := delveInstructions("soong_build", "5006", false); !strings.Contains(got, "running") ||
+		!strings.Contains(got, "dlv connect :5006") {
+		t.Errorf("unexpected instructions when attaching: %q", got)
+	}
+}
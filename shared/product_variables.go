@@ -0,0 +1,141 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Implements the handling of the file that records the product variables that
+// were used in soong_build, so that soong_ui can check whether they have
+// changed the same way it does for environment variables.
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// The soong config variables are tracked per namespace, all other product
+// variables are tracked by their top-level key in soong.variables.
+const vendorVarsKey = "VendorVars"
+
+// VendorVarsKey returns the key used to track the soong config variables of
+// the given namespace.
+func VendorVarsKey(namespace string) string {
+	return vendorVarsKey + "." + namespace
+}
+
+// flattenProductVariables reads a soong.variables file and returns the
+// compacted JSON value of every tracked key.
+func flattenProductVariables(variablesFile string) (map[string]string, error) {
+	data, err := os.ReadFile(variablesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var variables map[string]json.RawMessage
+	if err := json.Unmarshal(data, &variables); err != nil {
+		return nil, err
+	}
+
+	compact := func(raw json.RawMessage) (string, error) {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, raw); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	result := make(map[string]string, len(variables))
+	for key, raw := range variables {
+		if key == vendorVarsKey {
+			var namespaces map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &namespaces); err != nil {
+				return nil, err
+			}
+			for namespace, nsRaw := range namespaces {
+				if result[VendorVarsKey(namespace)], err = compact(nsRaw); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if result[key], err = compact(raw); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// ProductVariablesFileContents serializes the product variables in the given
+// soong.variables file that soong_build used in the same format as
+// EnvFileContents. The given top-level product variables are considered used,
+// except for the soong config variables, of which only the given namespaces
+// are. Variables and namespaces that were used but are not set are recorded
+// with an empty value so that setting them is detected.
+func ProductVariablesFileContents(variablesFile string, usedVariables, usedNamespaces []string) ([]byte, error) {
+	variables, err := flattenProductVariables(variablesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]string, len(usedVariables)+len(usedNamespaces))
+	for _, key := range usedVariables {
+		if key != vendorVarsKey {
+			used[key] = variables[key]
+		}
+	}
+	for _, namespace := range usedNamespaces {
+		used[VendorVarsKey(namespace)] = variables[VendorVarsKey(namespace)]
+	}
+
+	return EnvFileContents(used)
+}
+
+// StaleProductVariablesFile reads a file written by ProductVariablesFileContents
+// and compares it against the current soong.variables file. If any of the
+// used product variables changed value it prints and returns the changed keys
+// and returns true. Failing to read or parse either file also causes it to
+// return true.
+func StaleProductVariablesFile(usedFile, variablesFile string) (isStale bool,
+	changedProductVariables []string, err error) {
+	used, err := EnvFromFile(usedFile)
+	if err != nil {
+		return true, nil, err
+	}
+
+	current, err := flattenProductVariables(variablesFile)
+	if err != nil {
+		return true, nil, err
+	}
+
+	var changed []string
+	for key, old := range used {
+		if cur := current[key]; cur != old {
+			changed = append(changed, fmt.Sprintf("%s (%s -> %s)", key, old, cur))
+			changedProductVariables = append(changedProductVariables, key)
+		}
+	}
+
+	if len(changed) > 0 {
+		sort.Strings(changed)
+		sort.Strings(changedProductVariables)
+		fmt.Printf("product variables changed value:\n")
+		for _, s := range changed {
+			fmt.Printf("   %s\n", s)
+		}
+		return true, changedProductVariables, nil
+	}
+
+	return false, nil, nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shared
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStaleProductVariablesFile(t *testing.T) {
+	dir := t.TempDir()
+	variablesFile := filepath.Join(dir, "soong.variables")
+	usedFile := filepath.Join(dir, "soong.variables.used")
+
+	writeVariables := func(contents string) {
+		t.Helper()
+		if err := os.WriteFile(variablesFile, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeVariables(`{
+		"Platform_sdk_version": 35,
+		"VendorVars": {
+			"used": {"a": "1"},
+			"unused": {"b": "2"}
+		}
+	}`)
+	data, err := ProductVariablesFileContents(variablesFile,
+		[]string{"Platform_sdk_version", "Eng", "VendorVars"}, []string{"used", "unset"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(usedFile, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		variables string
+		changed   []string
+	}{
+		{
+			name:      "unchanged with different formatting",
+			variables: `{"Platform_sdk_version":35,"VendorVars":{"used":{"a":"1"},"unused":{"b":"2"}}}`,
+		},
+		{
+			name:      "unused namespace changed",
+			variables: `{"Platform_sdk_version":35,"VendorVars":{"used":{"a":"1"},"unused":{"b":"3"}}}`,
+		},
+		{
+			name:      "used namespace changed",
+			variables: `{"Platform_sdk_version":35,"VendorVars":{"used":{"a":"2"},"unused":{"b":"2"}}}`,
+			changed:   []string{"VendorVars.used"},
+		},
+		{
+			name:      "used namespace set",
+			variables: `{"Platform_sdk_version":35,"VendorVars":{"used":{"a":"1"},"unset":{"c":"1"}}}`,
+			changed:   []string{"VendorVars.unset"},
+		},
+		{
+			name:      "top-level variable changed",
+			variables: `{"Platform_sdk_version":36,"VendorVars":{"used":{"a":"1"}}}`,
+			changed:   []string{"Platform_sdk_version"},
+		},
+		{
+			name:      "top-level variable added",
+			variables: `{"Platform_sdk_version":35,"Eng":true,"VendorVars":{"used":{"a":"1"}}}`,
+			changed:   []string{"Eng"},
+		},
+		{
+			name:      "untracked variable changed",
+			variables: `{"Platform_sdk_version":35,"Make_only":"x","VendorVars":{"used":{"a":"1"}}}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			writeVariables(tc.variables)
+			stale, changed, err := StaleProductVariablesFile(usedFile, variablesFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stale != (len(tc.changed) > 0) {
+				t.Errorf("expected stale %v, got %v", len(tc.changed) > 0, stale)
+			}
+			if !reflect.DeepEqual(tc.changed, changed) {
+				t.Errorf("expected changed %q, got %q", tc.changed, changed)
+			}
+		})
+	}
+}
@@ -236,3 +236,13 @@ func (w *compressedFileWriter) Close() error {
 	_, err := w.zipw.Write(buf)
 	return err
 }
+
+// Size returns the number of bytes written so far.
+func (w *Writer) Size() int64 {
+	return w.cw.count
+}
+
+// NumEntries returns the number of entries written so far.
+func (w *Writer) NumEntries() int {
+	return len(w.dir)
+}
@@ -16,6 +16,7 @@ package tradefed
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/google/blueprint"
@@ -174,26 +175,7 @@ func AutoGenTestConfig(ctx android.ModuleContext, options AutoGenTestConfigOptio
 	}
 	path, autogenPath := testConfigPath(ctx, options.TestConfigProp, options.TestSuites, options.AutoGenConfig, options.TestConfigTemplateProp)
 	if autogenPath != nil {
-		templatePath := getTestConfigTemplate(ctx, options.TestConfigTemplateProp)
-		if templatePath.Valid() {
-			autogenTemplate(ctx, name, autogenPath, templatePath.String(), configs, options.TestRunnerOptions, options.OutputFileName, options.TestInstallBase)
-		} else {
-			if ctx.Device() {
-				if Bool(options.StandaloneTest) {
-					options.TestRunnerOptions = append(options.TestRunnerOptions, Option{
-						Name:  "ld-library-path",
-						Value: "{TEST_INSTALL_BASE}/" + name + "/" + ctx.Arch().ArchType.String() + "/standalone-libs",
-					})
-				}
-				autogenTemplate(ctx, name, autogenPath, options.DeviceTemplate, configs, options.TestRunnerOptions, options.OutputFileName, options.TestInstallBase)
-			} else {
-				if Bool(options.UnitTest) {
-					autogenTemplate(ctx, name, autogenPath, options.HostUnitTestTemplate, configs, options.TestRunnerOptions, options.OutputFileName, options.TestInstallBase)
-				} else {
-					autogenTemplate(ctx, name, autogenPath, options.HostTemplate, configs, options.TestRunnerOptions, options.OutputFileName, options.TestInstallBase)
-				}
-			}
-		}
+		autogenTestConfigFromOptions(ctx, name, autogenPath, configs, options)
 		return autogenPath
 	}
 	if len(options.OptionsForAutogenerated) > 0 {
@@ -205,6 +187,61 @@ func AutoGenTestConfig(ctx android.ModuleContext, options AutoGenTestConfigOptio
 	return path
 }
 
+func autogenTestConfigFromOptions(ctx android.ModuleContext, name string, autogenPath android.WritablePath, configs []Config, options AutoGenTestConfigOptions) {
+	templatePath := getTestConfigTemplate(ctx, options.TestConfigTemplateProp)
+	if templatePath.Valid() {
+		autogenTemplate(ctx, name, autogenPath, templatePath.String(), configs, options.TestRunnerOptions, options.OutputFileName, options.TestInstallBase)
+	} else {
+		if ctx.Device() {
+			if Bool(options.StandaloneTest) {
+				options.TestRunnerOptions = append(options.TestRunnerOptions, Option{
+					Name:  "ld-library-path",
+					Value: "{TEST_INSTALL_BASE}/" + name + "/" + ctx.Arch().ArchType.String() + "/standalone-libs",
+				})
+			}
+			autogenTemplate(ctx, name, autogenPath, options.DeviceTemplate, configs, options.TestRunnerOptions, options.OutputFileName, options.TestInstallBase)
+		} else {
+			if Bool(options.UnitTest) {
+				autogenTemplate(ctx, name, autogenPath, options.HostUnitTestTemplate, configs, options.TestRunnerOptions, options.OutputFileName, options.TestInstallBase)
+			} else {
+				autogenTemplate(ctx, name, autogenPath, options.HostTemplate, configs, options.TestRunnerOptions, options.OutputFileName, options.TestInstallBase)
+			}
+		}
+	}
+}
+
+// AutoGenShardedTestConfigs generates one test config per shard for a test that is split into
+// shardCount shards.  Each config is named <name>_shard<index>.config and is generated the same way
+// as by AutoGenTestConfig, plus the options that make Tradefed run only the tests in its shard.
+// It returns nil if the test config is not autogenerated, in which case the test must be sharded
+// by hand.
+func AutoGenShardedTestConfigs(ctx android.ModuleContext, options AutoGenTestConfigOptions, shardCount int) android.Paths {
+	name := options.Name
+	if name == "" {
+		name = ctx.ModuleName()
+	}
+	_, autogenPath := testConfigPath(ctx, options.TestConfigProp, options.TestSuites, options.AutoGenConfig, options.TestConfigTemplateProp)
+	if autogenPath == nil {
+		return nil
+	}
+
+	var shardConfigs android.Paths
+	for i := 0; i < shardCount; i++ {
+		configs := append([]Config{}, options.Config...)
+		for _, c := range options.OptionsForAutogenerated {
+			configs = append(configs, c)
+		}
+		configs = append(configs,
+			Option{Name: "shard-count", Value: strconv.Itoa(shardCount)},
+			Option{Name: "shard-index", Value: strconv.Itoa(i)})
+
+		shardConfig := android.PathForModuleOut(ctx, "shards", fmt.Sprintf("%s_shard%d.config", name, i))
+		autogenTestConfigFromOptions(ctx, name, shardConfig, configs, options)
+		shardConfigs = append(shardConfigs, shardConfig)
+	}
+	return shardConfigs
+}
+
 var autogenInstrumentationTest = pctx.StaticRule("autogenInstrumentationTest", blueprint.RuleParams{
 	Command: "${AutoGenTestConfigScript} $out $in ${EmptyTestConfig} $template ${extraConfigs} ${extraTestRunnerConfigs}",
 	CommandDeps: []string{
@@ -257,5 +294,54 @@ func AutoGenInstrumentationTestConfig(ctx android.ModuleContext, testConfigProp
 	return path
 }
 
+var autogenInstrumentationTestFromAnnotations = pctx.StaticRule("autogenInstrumentationTestFromAnnotations", blueprint.RuleParams{
+	Command: "${GenTestConfigFromAnnotations} --manifest $manifest --classes-jar $classesJar --name $name " +
+		"--apk-name $apkName ${testSuites} ${extraConfigs} ${extraTestRunnerConfigs} $out",
+	CommandDeps: []string{"${GenTestConfigFromAnnotations}"},
+}, "manifest", "classesJar", "name", "apkName", "testSuites", "extraConfigs", "extraTestRunnerConfigs")
+
+// AutoGenInstrumentationTestConfigFromAnnotations generates the test config of an instrumentation
+// test from the <instrumentation> tag of its manifest and the annotations used by the classes in
+// classesJar, instead of from a template. The config installs apkName and is tagged with the test
+// suites of the test. Like AutoGenInstrumentationTestConfig it returns the test_config or
+// AndroidTest.xml if there is one and auto_gen_config is not set.
+func AutoGenInstrumentationTestConfigFromAnnotations(ctx android.ModuleContext, testConfigProp *string,
+	manifest, classesJar android.Path, apkName string, testSuites []string, autoGenConfig *bool, configs []Config, testRunnerConfigs []Option) android.Path {
+	path, autogenPath := testConfigPath(ctx, testConfigProp, testSuites, autoGenConfig, nil)
+	if autogenPath == nil {
+		return path
+	}
+
+	var testSuiteFlags []string
+	for _, testSuite := range testSuites {
+		testSuiteFlags = append(testSuiteFlags, "--test-suite "+proptools.ShellEscape(testSuite))
+	}
+	var extraConfigs []string
+	for _, config := range configs {
+		extraConfigs = append(extraConfigs, "--extra-config "+proptools.ShellEscape(config.Config()))
+	}
+	var extraTestRunnerConfigs []string
+	for _, config := range testRunnerConfigs {
+		extraTestRunnerConfigs = append(extraTestRunnerConfigs, "--extra-test-runner-config "+proptools.ShellEscape(config.Config()))
+	}
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        autogenInstrumentationTestFromAnnotations,
+		Description: "test config from annotations",
+		Inputs:      android.Paths{manifest, classesJar},
+		Output:      autogenPath,
+		Args: map[string]string{
+			"manifest":               manifest.String(),
+			"classesJar":             classesJar.String(),
+			"name":                   ctx.ModuleName(),
+			"apkName":                proptools.ShellEscape(apkName),
+			"testSuites":             strings.Join(testSuiteFlags, " "),
+			"extraConfigs":           strings.Join(extraConfigs, " "),
+			"extraTestRunnerConfigs": strings.Join(extraTestRunnerConfigs, " "),
+		},
+	})
+	return autogenPath
+}
+
 var Bool = proptools.Bool
 var BoolDefault = proptools.BoolDefault
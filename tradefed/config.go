@@ -42,4 +42,6 @@ func init() {
 	pctx.SourcePathVariable("ShellTestConfigTemplate", "build/make/core/shell_test_config_template.xml")
 
 	pctx.SourcePathVariable("EmptyTestConfig", "build/make/core/empty_test_config.xml")
+
+	pctx.HostBinToolVariable("GenTestConfigFromAnnotations", "gen_test_config_from_annotations")
 }
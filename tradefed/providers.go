@@ -37,3 +37,14 @@ type BaseTestProviderData struct {
 }
 
 var BaseTestProviderKey = blueprint.NewProvider[BaseTestProviderData]()
+
+// TestShardingInfo is provided by tests that are split into shards, each of which has its own
+// autogenerated test config.
+type TestShardingInfo struct {
+	// The number of shards the test is split into.
+	ShardCount int
+	// The test config of each shard, in shard index order.
+	ShardTestConfigs android.Paths
+}
+
+var TestShardingInfoProvider = blueprint.NewProvider[TestShardingInfo]()
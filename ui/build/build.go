@@ -15,6 +15,7 @@
 package build
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -292,8 +293,34 @@ func Build(ctx Context, config Config) {
 		return
 	}
 
+	if inList(soongFixGoal, config.Arguments()) {
+		logArgsOtherThan(soongFixGoal)
+		soongFix(ctx, config)
+		return
+	}
+
 	defer waitForDist(ctx)
 
+	if config.CriticalPathReport() {
+		// Deferred so that the report is also written when the build fails.
+		defer writeCriticalPathReport(ctx, config)
+	}
+
+	if config.BuildTimeBudgetsReport() {
+		// Deferred so that the report also covers the actions that ran before a build failure.
+		defer writeBuildTimeBudgetsReport(ctx, config)
+	}
+
+	if config.WarningsReport() {
+		if topDir, err := os.Getwd(); err != nil {
+			ctx.Printf("failed to get the working directory, not writing a warnings report: %s", err.Error())
+		} else {
+			warnings := newWarningsCollector(topDir)
+			ctx.Status.AddOutput(warnings)
+			defer writeWarningsReport(ctx, config, warnings)
+		}
+	}
+
 	// checkProblematicFiles aborts the build if Android.mk or CleanSpec.mk are found at the root of the tree.
 	checkProblematicFiles(ctx)
 
@@ -402,6 +429,7 @@ func Build(ctx Context, config Config) {
 			installCleanIfNecessary(ctx, config)
 		}
 		partialCompileCleanIfNecessary(ctx, config)
+		cleanStaleSoongOutputs(ctx, config)
 		runNinjaForBuild(ctx, config)
 		updateBuildIdDir(ctx, config)
 	}
@@ -530,6 +558,31 @@ func distFile(ctx Context, config Config, src string, subDirs ...string) {
 	}()
 }
 
+// writeCriticalPathReport writes the chain of actions that determined the minimum build time to
+// critical_path.txt in the logs directory, copies it to the dist directory and prints it.
+func writeCriticalPathReport(ctx Context, config Config) {
+	if ctx.CriticalPath == nil {
+		return
+	}
+
+	var report bytes.Buffer
+	if err := ctx.CriticalPath.WriteReport(&report); err != nil {
+		ctx.Printf("failed to generate critical path report: %s", err.Error())
+		return
+	}
+
+	reportFile := filepath.Join(config.LogsDir(), "critical_path.txt")
+	if err := os.WriteFile(reportFile, report.Bytes(), 0666); err != nil { // a+rw
+		ctx.Printf("failed to write %s: %s", reportFile, err.Error())
+		return
+	}
+	distFile(ctx, config, reportFile, "logs")
+
+	fmt.Fprintln(ctx.Writer, "")
+	fmt.Fprint(ctx.Writer, report.String())
+	fmt.Fprintln(ctx.Writer, "Critical path report written to", reportFile)
+}
+
 // Actions to run on every build where 'dist' is in the actions.
 // Be careful, anything added here slows down EVERY CI build
 func runDistActions(ctx Context, config Config) {
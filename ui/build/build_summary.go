@@ -0,0 +1,248 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"android/soong/ui/metrics"
+	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
+	"android/soong/ui/metrics/rbe_metrics_proto"
+	"android/soong/ui/status"
+)
+
+// The soong_metrics proto has everything that is known about a build, but is too large and too
+// hard to read for CI systems that only want to track how long the main phases of the build took
+// and how well the caches worked. At the end of every build soong_ui writes build_summary.json to
+// the logs directory, summarizing the metrics that were already collected, the actions that ninja
+// ran and, for RBE builds, the completion statuses of the remote actions.
+
+// BuildSummary is the content of build_summary.json.
+type BuildSummary struct {
+	// Times, in milliseconds, taken by the phases of the build.
+	TotalMillis            uint64 `json:"total_ms"`
+	SetupMillis            uint64 `json:"setup_ms"`
+	SymlinkMigrationMillis uint64 `json:"symlink_migration_ms"`
+	BootstrapMillis        uint64 `json:"bootstrap_ms"`
+	GlobCheckMillis        uint64 `json:"glob_check_ms"`
+	AnalysisMillis         uint64 `json:"analysis_ms"`
+	KatiMillis             uint64 `json:"kati_ms"`
+	NinjaMillis            uint64 `json:"ninja_ms"`
+
+	GlobChecks       GlobCheckSummary        `json:"glob_checks"`
+	SymlinkMigration SymlinkMigrationSummary `json:"symlink_migration"`
+	Actions          ActionSummary           `json:"actions"`
+
+	// RBE is only set for builds that use RBE and produced RBE metrics.
+	RBE *RBESummary `json:"rbe,omitempty"`
+}
+
+// GlobCheckSummary summarizes the glob checks run before soong_build.
+type GlobCheckSummary struct {
+	Globs      uint64 `json:"globs"`
+	RerunGlobs uint64 `json:"rerun_globs"`
+
+	// The number of dependencies of the globs whose modification times were checked.
+	CheckedDeps uint64 `json:"checked_deps"`
+
+	// The number of glob checks that found a changed glob, causing soong_build to rerun.
+	ChangedGlobChecks uint64 `json:"changed_glob_checks"`
+
+	// The fraction of the globs whose cached results were used without rerunning them.
+	CacheHitRate float64 `json:"cache_hit_rate"`
+}
+
+// SymlinkMigrationSummary summarizes the migration of the symlinks in the output directory.
+type SymlinkMigrationSummary struct {
+	SymlinksFound   uint64 `json:"symlinks_found"`
+	SymlinksUpdated uint64 `json:"symlinks_updated"`
+	DirsWalked      uint64 `json:"dirs_walked"`
+}
+
+// ActionSummary counts the actions run by ninja.
+type ActionSummary struct {
+	Total  uint64 `json:"total"`
+	Failed uint64 `json:"failed"`
+	Local  uint64 `json:"local"`
+	RBE    uint64 `json:"rbe"`
+}
+
+// RBESummary summarizes how the actions sent to RBE were completed.
+type RBESummary struct {
+	Actions          uint64 `json:"actions"`
+	CacheHits        uint64 `json:"cache_hits"`
+	RemoteExecutions uint64 `json:"remote_executions"`
+	LocalExecutions  uint64 `json:"local_executions"`
+	LocalFallbacks   uint64 `json:"local_fallbacks"`
+
+	// The fraction of the actions that were completed with a remote cache hit.
+	CacheHitRate float64 `json:"cache_hit_rate"`
+}
+
+// ActionCounter is a status.StatusOutput that counts the actions run by ninja for the build
+// summary.
+type ActionCounter struct {
+	lock    sync.Mutex
+	actions ActionSummary
+}
+
+// NewActionCounter returns an ActionCounter that hasn't counted any actions yet.
+func NewActionCounter() *ActionCounter {
+	return &ActionCounter{}
+}
+
+func (a *ActionCounter) StartAction(action *status.Action, counts status.Counts) {}
+
+func (a *ActionCounter) FinishAction(result status.ActionResult, counts status.Counts) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.actions.Total++
+	if result.Error != nil {
+		a.actions.Failed++
+	}
+	if result.Action != nil && isRBECommand(result.Command) {
+		a.actions.RBE++
+	} else {
+		a.actions.Local++
+	}
+}
+
+func (a *ActionCounter) Message(level status.MsgLevel, msg string) {}
+
+func (a *ActionCounter) Flush() {}
+
+func (a *ActionCounter) Write(p []byte) (n int, err error) { return len(p), nil }
+
+// isRBECommand returns true if the command is run through rewrapper, the RBE client.
+func isRBECommand(command string) bool {
+	for _, field := range strings.Fields(command) {
+		if field == "rewrapper" || strings.HasSuffix(field, "/rewrapper") {
+			return true
+		}
+	}
+	return false
+}
+
+func millis(nanos uint64) uint64 {
+	return nanos / 1000000
+}
+
+func sumPerfMillis(perfs []*soong_metrics_proto.PerfInfo, description string) uint64 {
+	var total uint64
+	for _, perf := range perfs {
+		if description == "" || perf.GetDescription() == description {
+			total += millis(perf.GetRealTime())
+		}
+	}
+	return total
+}
+
+// newBuildSummary summarizes the collected metrics and actions.
+func newBuildSummary(m *soong_metrics_proto.MetricsBase, actions ActionSummary) *BuildSummary {
+	summary := &BuildSummary{
+		TotalMillis: millis(m.GetTotal().GetRealTime()),
+		SetupMillis: sumPerfMillis(m.GetSetupTools(), ""),
+		// Writing the ninja file that builds soong_build.
+		BootstrapMillis: sumPerfMillis(m.GetSoongRuns(), "blueprint bootstrap"),
+		// Running ninja on the ninja file that runs soong_build.
+		AnalysisMillis: sumPerfMillis(m.GetSoongRuns(), "bootstrap"),
+		KatiMillis:     sumPerfMillis(m.GetKatiRuns(), ""),
+		NinjaMillis:    sumPerfMillis(m.GetNinjaRuns(), "ninja"),
+		Actions:        actions,
+	}
+
+	for _, globCheck := range m.GetGlobChecks() {
+		summary.GlobCheckMillis += globCheck.GetMicros() / 1000
+		summary.GlobChecks.Globs += uint64(globCheck.GetGlobs())
+		summary.GlobChecks.RerunGlobs += uint64(globCheck.GetRerunGlobs())
+		summary.GlobChecks.CheckedDeps += uint64(globCheck.GetCheckedDeps())
+		if globCheck.ChangedGlob != nil {
+			summary.GlobChecks.ChangedGlobChecks++
+		}
+	}
+	if summary.GlobChecks.Globs > 0 {
+		summary.GlobChecks.CacheHitRate =
+			1 - float64(summary.GlobChecks.RerunGlobs)/float64(summary.GlobChecks.Globs)
+	}
+
+	if symlinks := m.GetSymlinkMigration(); symlinks != nil {
+		summary.SymlinkMigrationMillis = symlinks.GetMicros() / 1000
+		summary.SymlinkMigration.SymlinksFound = uint64(symlinks.GetSymlinksFound())
+		summary.SymlinkMigration.SymlinksUpdated = uint64(symlinks.GetSymlinksUpdated())
+		summary.SymlinkMigration.DirsWalked = uint64(symlinks.GetDirsWalked())
+	}
+
+	return summary
+}
+
+// parseRBEMetrics returns the summary of the RBE metrics written by reproxy, or nil if they can't
+// be parsed.  The completion status of each action is counted by the CompletionStatus stat.
+func parseRBEMetrics(data []byte) *RBESummary {
+	stats := &rbe_metrics_proto.Stats{}
+	if err := proto.Unmarshal(data, stats); err != nil {
+		return nil
+	}
+
+	summary := &RBESummary{Actions: uint64(stats.GetNumRecords())}
+	statuses := make(map[string]uint64)
+	for _, stat := range stats.GetStats() {
+		if stat.GetName() != "CompletionStatus" {
+			continue
+		}
+		for _, value := range stat.GetCountsByValue() {
+			statuses[value.GetName()] += uint64(value.GetCount())
+		}
+	}
+
+	summary.CacheHits = statuses["STATUS_CACHE_HIT"]
+	summary.RemoteExecutions = statuses["STATUS_REMOTE_EXECUTION"] + statuses["STATUS_RACING_REMOTE"]
+	summary.LocalExecutions = statuses["STATUS_LOCAL_EXECUTION"] + statuses["STATUS_RACING_LOCAL"]
+	summary.LocalFallbacks = statuses["STATUS_LOCAL_FALLBACK"]
+	if summary.Actions > 0 {
+		summary.CacheHitRate = float64(summary.CacheHits) / float64(summary.Actions)
+	}
+	return summary
+}
+
+// WriteBuildSummary writes the summary of the build to filename.  rbeMetricsFile is the RBE
+// metrics written by reproxy, it is ignored if it doesn't exist.
+func WriteBuildSummary(ctx Context, filename string, met *metrics.Metrics, actions *ActionCounter,
+	rbeMetricsFile string) {
+
+	actions.lock.Lock()
+	summary := newBuildSummary(met.MetricsBase(), actions.actions)
+	actions.lock.Unlock()
+
+	if data, err := os.ReadFile(rbeMetricsFile); err == nil {
+		if summary.RBE = parseRBEMetrics(data); summary.RBE == nil {
+			ctx.Verbosef("failed to parse RBE metrics %s", rbeMetricsFile)
+		}
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		ctx.Printf("failed to marshal the build summary: %s", err.Error())
+		return
+	}
+	if err := os.WriteFile(filename, append(data, '\n'), 0666); err != nil {
+		ctx.Printf("failed to write %s: %s", filename, err.Error())
+	}
+}
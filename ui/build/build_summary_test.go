@@ -0,0 +1,163 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
+	"android/soong/ui/metrics/rbe_metrics_proto"
+	"android/soong/ui/status"
+)
+
+func TestActionCounter(t *testing.T) {
+	counter := NewActionCounter()
+	for _, result := range []status.ActionResult{
+		{Action: &status.Action{Command: "javac -d out Foo.java"}},
+		{Action: &status.Action{Command: "prebuilts/remoteexecution-client/live/rewrapper --labels=type=compile -- clang++ -c foo.cpp"}},
+		{Action: &status.Action{Command: "rewrapper -- javac Bar.java"}, Error: errors.New("failed")},
+		{Action: &status.Action{Command: "echo rewrapper.cfg"}},
+	} {
+		counter.FinishAction(result, status.Counts{})
+	}
+
+	want := ActionSummary{Total: 4, Failed: 1, Local: 2, RBE: 2}
+	if counter.actions != want {
+		t.Errorf("want %+v, got %+v", want, counter.actions)
+	}
+}
+
+func perf(description string, millis uint64) *soong_metrics_proto.PerfInfo {
+	return &soong_metrics_proto.PerfInfo{
+		Description: proto.String(description),
+		RealTime:    proto.Uint64(millis * 1000000),
+	}
+}
+
+func TestNewBuildSummary(t *testing.T) {
+	m := &soong_metrics_proto.MetricsBase{
+		Total:      perf("total", 10000),
+		SetupTools: []*soong_metrics_proto.PerfInfo{perf("path", 100), perf("find modules", 200)},
+		SoongRuns: []*soong_metrics_proto.PerfInfo{
+			perf("soong", 5000),
+			perf("blueprint bootstrap", 300),
+			perf("check_globs", 400),
+			perf("bootstrap", 4000),
+		},
+		KatiRuns:  []*soong_metrics_proto.PerfInfo{perf("kati build", 1000), perf("kati package", 50)},
+		NinjaRuns: []*soong_metrics_proto.PerfInfo{perf("ninja", 3000), perf("installclean", 10)},
+		GlobChecks: []*soong_metrics_proto.GlobCheckMetrics{
+			{
+				Micros:      proto.Uint64(150000),
+				Globs:       proto.Uint32(60),
+				RerunGlobs:  proto.Uint32(10),
+				CheckedDeps: proto.Uint32(90),
+				ChangedGlob: proto.String("foo/*.java"),
+			},
+			{
+				Micros:      proto.Uint64(250000),
+				Globs:       proto.Uint32(40),
+				RerunGlobs:  proto.Uint32(0),
+				CheckedDeps: proto.Uint32(40),
+			},
+		},
+		SymlinkMigration: &soong_metrics_proto.SymlinkMigrationMetrics{
+			Micros:          proto.Uint64(20000),
+			SymlinksFound:   proto.Uint32(7),
+			SymlinksUpdated: proto.Uint32(5),
+			DirsWalked:      proto.Uint32(3),
+		},
+	}
+	actions := ActionSummary{Total: 3, Local: 3}
+
+	got := newBuildSummary(m, actions)
+	want := &BuildSummary{
+		TotalMillis:            10000,
+		SetupMillis:            300,
+		SymlinkMigrationMillis: 20,
+		BootstrapMillis:        300,
+		GlobCheckMillis:        400,
+		AnalysisMillis:         4000,
+		KatiMillis:             1050,
+		NinjaMillis:            3000,
+		GlobChecks: GlobCheckSummary{
+			Globs:             100,
+			RerunGlobs:        10,
+			CheckedDeps:       130,
+			ChangedGlobChecks: 1,
+			CacheHitRate:      0.9,
+		},
+		SymlinkMigration: SymlinkMigrationSummary{SymlinksFound: 7, SymlinksUpdated: 5, DirsWalked: 3},
+		Actions:          actions,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	// An empty build, e.g. one that failed early, has an empty summary.
+	if got, want := newBuildSummary(&soong_metrics_proto.MetricsBase{}, ActionSummary{}), (&BuildSummary{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func rbeStat(name string, countsByValue map[string]int64) *rbe_metrics_proto.Stat {
+	stat := &rbe_metrics_proto.Stat{Name: name, Count: 42}
+	for value, count := range countsByValue {
+		stat.CountsByValue = append(stat.CountsByValue, &rbe_metrics_proto.Stat_Value{Name: value, Count: count})
+	}
+	return stat
+}
+
+func TestParseRBEMetrics(t *testing.T) {
+	data, err := proto.Marshal(&rbe_metrics_proto.Stats{
+		NumRecords: 10,
+		Stats: []*rbe_metrics_proto.Stat{
+			rbeStat("LocalMetadata.Labels", map[string]int64{
+				"type=compile": 10,
+			}),
+			rbeStat("CompletionStatus", map[string]int64{
+				"STATUS_CACHE_HIT":        4,
+				"STATUS_REMOTE_EXECUTION": 2,
+				"STATUS_RACING_REMOTE":    1,
+				"STATUS_RACING_LOCAL":     1,
+				"STATUS_LOCAL_FALLBACK":   2,
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := parseRBEMetrics(data)
+	want := &RBESummary{
+		Actions:          10,
+		CacheHits:        4,
+		RemoteExecutions: 3,
+		LocalExecutions:  1,
+		LocalFallbacks:   2,
+		CacheHitRate:     0.4,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	if got := parseRBEMetrics([]byte{0xff}); got != nil {
+		t.Errorf("want nil for invalid metrics, got %+v", got)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"android/soong/shared"
+)
+
+// buildTimeRegression is a module whose build time exceeds its budget.
+type buildTimeRegression struct {
+	budget   shared.BuildTimeBudget
+	duration time.Duration
+}
+
+// ninjaLogAction identifies an action in a ninja log. An action with multiple outputs has a line
+// for each of its outputs, with the same start and end times and command hash.
+type ninjaLogAction struct {
+	start, end int64
+	hash       string
+}
+
+func (a ninjaLogAction) duration() time.Duration {
+	return time.Duration(a.end-a.start) * time.Millisecond
+}
+
+// readNinjaLogActions returns the action that last built each output in a ninja log. Each line of
+// the log has the form "<start ms>\t<end ms>\t<mtime>\t<output>\t<hash>", and a later line for an
+// output supersedes the earlier ones.
+func readNinjaLogActions(r io.Reader) (map[string]ninjaLogAction, error) {
+	actions := make(map[string]ninjaLogAction)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("malformed ninja log line %q", line)
+		}
+		start, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed start time in ninja log line %q: %w", line, err)
+		}
+		end, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed end time in ninja log line %q: %w", line, err)
+		}
+		var hash string
+		if len(fields) > 4 {
+			hash = fields[4]
+		}
+		actions[fields[3]] = ninjaLogAction{start: start, end: end, hash: hash}
+	}
+	return actions, scanner.Err()
+}
+
+// buildTimeRegressions attributes the actions that built the outputs to the modules by the Soong
+// intermediates directory the outputs are in, and returns the modules whose build time exceeds
+// their budget, sorted by how much they exceed it. The duration of an action with multiple outputs
+// is only counted once for a module.
+func buildTimeRegressions(budgets []shared.BuildTimeBudget, actions map[string]ninjaLogAction,
+	intermediatesDir string) []buildTimeRegression {
+
+	prefixes := make(map[string]int)
+	for i, budget := range budgets {
+		prefixes[filepath.Join(intermediatesDir, budget.Dir, budget.Name)+"/"] = i
+	}
+
+	moduleDurations := make([]time.Duration, len(budgets))
+	moduleActions := make([]map[ninjaLogAction]bool, len(budgets))
+	for output, action := range actions {
+		if !strings.HasPrefix(output, intermediatesDir+"/") {
+			continue
+		}
+		// Outputs are in <intermediates>/<dir>/<name>/<variant>/, try the longest prefix first so
+		// that the outputs of a module are not attributed to a module in a parent directory.
+		for dir := filepath.Dir(output); len(dir) > len(intermediatesDir); dir = filepath.Dir(dir) {
+			if i, ok := prefixes[dir+"/"]; ok {
+				if moduleActions[i] == nil {
+					moduleActions[i] = make(map[ninjaLogAction]bool)
+				}
+				if !moduleActions[i][action] {
+					moduleActions[i][action] = true
+					moduleDurations[i] += action.duration()
+				}
+				break
+			}
+		}
+	}
+
+	var regressions []buildTimeRegression
+	for i, budget := range budgets {
+		if moduleDurations[i] > time.Duration(budget.ExpectedBuildSeconds)*time.Second {
+			regressions = append(regressions, buildTimeRegression{budget, moduleDurations[i]})
+		}
+	}
+
+	overBudget := func(r buildTimeRegression) float64 {
+		return r.duration.Seconds() / float64(r.budget.ExpectedBuildSeconds)
+	}
+	sort.SliceStable(regressions, func(i, j int) bool {
+		return overBudget(regressions[i]) > overBudget(regressions[j])
+	})
+	return regressions
+}
+
+// formatBuildTimeRegressions writes the report of the modules whose build time exceeds their
+// budget.
+func formatBuildTimeRegressions(w io.Writer, regressions []buildTimeRegression, budgets int) {
+	if len(regressions) == 0 {
+		fmt.Fprintf(w, "All %d modules with expected_build_seconds were built within their budget.\n", budgets)
+		return
+	}
+
+	fmt.Fprintf(w, "%d of %d modules with expected_build_seconds exceeded their budget:\n",
+		len(regressions), budgets)
+	for _, r := range regressions {
+		expected := time.Duration(r.budget.ExpectedBuildSeconds) * time.Second
+		fmt.Fprintf(w, "  //%s:%s: built in %s, expected %s (+%.0f%%)\n",
+			r.budget.Dir, r.budget.Name, r.duration.Round(100*time.Millisecond), expected,
+			100*(r.duration.Seconds()/expected.Seconds()-1))
+	}
+}
+
+// writeBuildTimeBudgetsReport compares the build times of the modules from the ninja log with the
+// expected_build_seconds that Soong wrote to build_time_budgets.json, writes the modules that
+// exceed their budget to build_time_budgets.txt in the logs directory, copies it to the dist
+// directory and prints it.
+func writeBuildTimeBudgetsReport(ctx Context, config Config) {
+	data, err := os.ReadFile(filepath.Join(config.SoongOutDir(), shared.BuildTimeBudgetsFileName))
+	if os.IsNotExist(err) {
+		// Soong didn't run, e.g. because the build failed before.
+		return
+	} else if err != nil {
+		ctx.Printf("failed to read build time budgets: %s", err.Error())
+		return
+	}
+	var budgets []shared.BuildTimeBudget
+	if err := json.Unmarshal(data, &budgets); err != nil {
+		ctx.Printf("failed to parse build time budgets: %s", err.Error())
+		return
+	}
+
+	ninjaLog, err := os.Open(filepath.Join(config.OutDir(), ninjaLogFileName))
+	if err != nil {
+		ctx.Printf("failed to read ninja log: %s", err.Error())
+		return
+	}
+	defer ninjaLog.Close()
+	actions, err := readNinjaLogActions(ninjaLog)
+	if err != nil {
+		ctx.Printf("failed to read ninja log: %s", err.Error())
+		return
+	}
+
+	regressions := buildTimeRegressions(budgets, actions, filepath.Join(config.SoongOutDir(), ".intermediates"))
+
+	var report strings.Builder
+	formatBuildTimeRegressions(&report, regressions, len(budgets))
+
+	reportFile := filepath.Join(config.LogsDir(), "build_time_budgets.txt")
+	if err := os.WriteFile(reportFile, []byte(report.String()), 0666); err != nil { // a+rw
+		ctx.Printf("failed to write %s: %s", reportFile, err.Error())
+		return
+	}
+	distFile(ctx, config, reportFile, "logs")
+
+	fmt.Fprintln(ctx.Writer, "")
+	fmt.Fprint(ctx.Writer, report.String())
+	fmt.Fprintln(ctx.Writer, "Build time budgets report written to", reportFile)
+}
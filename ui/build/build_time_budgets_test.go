@@ -0,0 +1,92 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"android/soong/shared"
+)
+
+func TestReadNinjaLogActions(t *testing.T) {
+	log := strings.Join([]string{
+		"# ninja log v5",
+		"0\t1500\t0\tout/soong/.intermediates/a/foo/android_common/foo.jar\t1",
+		"100\t200\t0\tout/soong/.intermediates/a/foo/android_common/foo.jar\t2",
+		"200\t3200\t0\tout/soong/.intermediates/a/bar/android_common/bar.jar\t3",
+		"200\t3200\t0\tout/soong/.intermediates/a/bar/android_common/bar.d\t3",
+		"",
+	}, "\n")
+
+	got, err := readNinjaLogActions(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := map[string]ninjaLogAction{
+		"out/soong/.intermediates/a/foo/android_common/foo.jar": {100, 200, "2"},
+		"out/soong/.intermediates/a/bar/android_common/bar.jar": {200, 3200, "3"},
+		"out/soong/.intermediates/a/bar/android_common/bar.d":   {200, 3200, "3"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	if _, err := readNinjaLogActions(strings.NewReader("0\t1\n")); err == nil {
+		t.Errorf("expected an error for a malformed line")
+	}
+}
+
+func TestBuildTimeRegressions(t *testing.T) {
+	intermediates := "out/soong/.intermediates"
+	budgets := []shared.BuildTimeBudget{
+		{Name: "foo", Dir: "a", ExpectedBuildSeconds: 10},
+		{Name: "bar", Dir: "a", ExpectedBuildSeconds: 2},
+		{Name: "baz", Dir: "a/foo", ExpectedBuildSeconds: 1},
+		{Name: "qux", Dir: "b", ExpectedBuildSeconds: 1},
+	}
+	actions := map[string]ninjaLogAction{
+		// foo takes 12s over two variants.
+		"out/soong/.intermediates/a/foo/android_common/foo.jar":     {0, 8000, "1"},
+		"out/soong/.intermediates/a/foo/linux_glibc_common/foo.jar": {0, 4000, "2"},
+		// bar takes 3s in an action with two outputs, which is only counted once.
+		"out/soong/.intermediates/a/bar/android_common/bar.jar": {1000, 4000, "3"},
+		"out/soong/.intermediates/a/bar/android_common/bar.d":   {1000, 4000, "3"},
+		// baz is in a subdirectory of foo, it must not be counted for foo.
+		"out/soong/.intermediates/a/foo/baz/android_common/baz.jar": {0, 500, "4"},
+		// qux is not in the intermediates directory.
+		"out/target/product/qux/system/qux.jar": {0, 5000, "5"},
+	}
+
+	got := buildTimeRegressions(budgets, actions, intermediates)
+	want := []buildTimeRegression{
+		{budgets[1], 3 * time.Second},
+		{budgets[0], 12 * time.Second},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	var report strings.Builder
+	formatBuildTimeRegressions(&report, got, len(budgets))
+	wantReport := "2 of 4 modules with expected_build_seconds exceeded their budget:\n" +
+		"  //a:bar: built in 3s, expected 2s (+50%)\n" +
+		"  //a:foo: built in 12s, expected 10s (+20%)\n"
+	if report.String() != wantReport {
+		t.Errorf("want report:\n%s\ngot:\n%s", wantReport, report.String())
+	}
+}
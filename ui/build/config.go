@@ -86,7 +86,12 @@ type configImpl struct {
 	jsonModuleGraph bool
 	reportMkMetrics bool // Collect and report mk2bp migration progress metrics.
 	soongDocs       bool
-	skipConfig      bool
+	soongDumpModule string
+	// The allowlist passed to the soong_module_deps goal, if any.
+	soongModuleDepsAllowlist string
+	skipConfig               bool
+	// Whether the soong_ide_metadata goal was requested.
+	soongIdeMetadata bool
 	// Either the user or product config requested that we skip soong (for the banner). The other
 	// skip flags tell whether *this* soong_ui invocation will skip kati - which will be true
 	// during lunch.
@@ -103,6 +108,12 @@ type configImpl struct {
 	buildFromSourceStub       bool
 	incrementalBuildActions   bool
 	ensureAllowlistIntegrity  bool // For CI builds - make sure modules are mixed-built
+	criticalPathReport        bool // Write a report of the longest chain of actions at the end of the build
+	buildTimeBudgetsReport    bool // Write a report of the modules that exceed their build time budget
+	warningsReport            bool // Write a report of the deduplicated warnings grouped by owners
+
+	// Remove or report the files in out/soong that are no longer declared as outputs
+	staleOutputsMode staleOutputsMode
 
 	// From the product config
 	katiArgs        []string
@@ -895,6 +906,16 @@ func (c *configImpl) parseArgs(ctx Context, args []string) {
 			c.skipSoongTests = false
 		} else if arg == "--skip-metrics-upload" {
 			c.skipMetricsUpload = true
+		} else if arg == "--critical-path-report" {
+			c.criticalPathReport = true
+		} else if arg == "--build-time-budgets" {
+			c.buildTimeBudgetsReport = true
+		} else if arg == "--warnings-report" {
+			c.warningsReport = true
+		} else if arg == "--clean-stale-outputs" {
+			c.staleOutputsMode = staleOutputsClean
+		} else if arg == "--report-stale-outputs" {
+			c.staleOutputsMode = staleOutputsReport
 		} else if arg == "--mk-metrics" {
 			c.reportMkMetrics = true
 		} else if arg == "--search-api-dir" {
@@ -979,6 +1000,20 @@ func (c *configImpl) parseArgs(ctx Context, args []string) {
 			c.jsonModuleGraph = true
 		} else if arg == "soong_docs" {
 			c.soongDocs = true
+		} else if arg == "soong_dump_module" {
+			if i+1 >= len(args) {
+				ctx.Fatalln("soong_dump_module requires a module, e.g. m soong_dump_module //path/to/dir:name")
+			}
+			i++
+			c.soongDumpModule = args[i]
+		} else if arg == "soong_module_deps" {
+			if i+1 >= len(args) {
+				ctx.Fatalln("soong_module_deps requires an allowlist file, e.g. m soong_module_deps path/to/allowlist.txt")
+			}
+			i++
+			c.soongModuleDepsAllowlist = args[i]
+		} else if arg == "soong_ide_metadata" {
+			c.soongIdeMetadata = true
 		} else {
 			if arg == "checkbuild" {
 				c.checkbuild = true
@@ -1071,7 +1106,8 @@ func (c *configImpl) SoongBuildInvocationNeeded() bool {
 		return true
 	}
 
-	if !c.JsonModuleGraph() && !c.SoongDocs() {
+	if !c.JsonModuleGraph() && !c.SoongDocs() && c.SoongDumpModule() == "" && c.SoongModuleDepsAllowlist() == "" &&
+		!c.SoongIdeMetadata() {
 		// Command line was empty, the default Ninja target is built
 		return true
 	}
@@ -1147,10 +1183,41 @@ func (c *configImpl) UsedEnvFile(tag string) string {
 	return shared.JoinPath(c.SoongOutDir(), usedEnvFile+"."+tag)
 }
 
+func (c *configImpl) UsedVariablesFile(tag string) string {
+	if v, ok := c.environ.Get("TARGET_PRODUCT"); ok {
+		return shared.JoinPath(c.SoongOutDir(), usedVariablesFile+"."+v+c.CoverageSuffix()+"."+tag)
+	}
+	return shared.JoinPath(c.SoongOutDir(), usedVariablesFile+"."+tag)
+}
+
 func (c *configImpl) SoongDocsHtml() string {
 	return shared.JoinPath(c.SoongOutDir(), "docs/soong_build.html")
 }
 
+// SoongDocsJson returns the JSON index of module types and properties that soong_docs writes
+// next to the HTML documentation.
+func (c *configImpl) SoongDocsJson() string {
+	return shared.JoinPath(c.SoongOutDir(), "docs/soong_build.json")
+}
+
+// SoongDumpModuleFile returns the file that soong_build writes the resolved properties of the
+// module requested with soong_dump_module to.
+func (c *configImpl) SoongDumpModuleFile() string {
+	return shared.JoinPath(c.SoongOutDir(), "soong_dump_module.json")
+}
+
+// SoongModuleDepsFile returns the file that soong_build writes the direct dependencies of the
+// modules in the allowlist passed to soong_module_deps to.
+func (c *configImpl) SoongModuleDepsFile() string {
+	return shared.JoinPath(c.SoongOutDir(), "soong_module_deps.json")
+}
+
+// SoongIdeMetadataFile returns the file that soong_build writes the IDE metadata of all the
+// modules to for the soong_ide_metadata goal.
+func (c *configImpl) SoongIdeMetadataFile() string {
+	return shared.JoinPath(c.SoongOutDir(), "soong_ide_metadata.json")
+}
+
 func (c *configImpl) ModuleGraphFile() string {
 	return shared.JoinPath(c.SoongOutDir(), "module-graph.json")
 }
@@ -1192,6 +1259,21 @@ func (c *configImpl) SoongDocs() bool {
 	return c.soongDocs
 }
 
+// SoongDumpModule returns the module passed to the soong_dump_module goal, if any.
+func (c *configImpl) SoongDumpModule() string {
+	return c.soongDumpModule
+}
+
+// SoongModuleDepsAllowlist returns the allowlist file passed to the soong_module_deps goal, if any.
+func (c *configImpl) SoongModuleDepsAllowlist() string {
+	return c.soongModuleDepsAllowlist
+}
+
+// SoongIdeMetadata returns true if the soong_ide_metadata goal was requested.
+func (c *configImpl) SoongIdeMetadata() bool {
+	return c.soongIdeMetadata
+}
+
 func (c *configImpl) IsVerbose() bool {
 	return c.verbose
 }
@@ -1288,6 +1370,15 @@ func (c *configImpl) SetLogsPrefix(prefix string) {
 	c.logsPrefix = prefix
 }
 
+// NinjaFrontendLog returns the path of the compressed log of the ninja frontend messages for the
+// named ninja invocation, or an empty string if NINJA_FRONTEND_LOG is not set to true.
+func (c *configImpl) NinjaFrontendLog(name string) string {
+	if !c.environ.IsEnvTrue("NINJA_FRONTEND_LOG") {
+		return ""
+	}
+	return filepath.Join(c.LogsDir(), c.logsPrefix+name+"_ninja_frontend.pb.gz")
+}
+
 func (c *configImpl) HighmemParallel() int {
 	if i, ok := c.environ.GetInt("NINJA_HIGHMEM_NUM_JOBS"); ok {
 		return i
@@ -1848,6 +1939,30 @@ func (c *configImpl) SkipMetricsUpload() bool {
 	return c.skipMetricsUpload
 }
 
+// CriticalPathReport returns true if a report of the build's critical path should be written to the logs
+// directory (and dist) when the build finishes.
+func (c *configImpl) CriticalPathReport() bool {
+	return c.criticalPathReport
+}
+
+// BuildTimeBudgetsReport returns true if a report of the modules whose build time exceeds their
+// expected_build_seconds should be written to the logs directory (and dist) when the build finishes.
+func (c *configImpl) BuildTimeBudgetsReport() bool {
+	return c.buildTimeBudgetsReport
+}
+
+// WarningsReport returns true if the javac and kotlinc warnings of the build should be
+// deduplicated and written to the logs directory (and dist) grouped by owners.
+func (c *configImpl) WarningsReport() bool {
+	return c.warningsReport
+}
+
+// StaleOutputsMode returns whether the files in out/soong that are no longer declared as outputs
+// should be removed, only reported, or ignored.
+func (c *configImpl) StaleOutputsMode() staleOutputsMode {
+	return c.staleOutputsMode
+}
+
 func (c *configImpl) EnsureAllowlistIntegrity() bool {
 	return c.ensureAllowlistIntegrity
 }
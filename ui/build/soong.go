@@ -15,6 +15,7 @@
 package build
 
 import (
+	"archive/zip"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -47,12 +48,16 @@ import (
 )
 
 const (
-	availableEnvFile = "soong.environment.available"
-	usedEnvFile      = "soong.environment.used"
+	availableEnvFile  = "soong.environment.available"
+	usedEnvFile       = "soong.environment.used"
+	usedVariablesFile = "soong.variables.used"
 
 	soongBuildTag      = "build"
 	jsonModuleGraphTag = "modulegraph"
 	soongDocsTag       = "soong_docs"
+	soongDumpModuleTag = "soong_dump_module"
+	soongModuleDepsTag = "soong_module_deps"
+	ideMetadataTag     = "soong_ide_metadata"
 
 	// bootstrapEpoch is used to determine if an incremental build is incompatible with the current
 	// version of bootstrap and needs cleaning before continuing the build.  Increment this for
@@ -64,8 +69,8 @@ const (
 var (
 	// Used during parallel update of symlinks in out directory to reflect new
 	// TOP dir.
-	symlinkWg            sync.WaitGroup
-	numFound, numUpdated uint32
+	symlinkWg                     sync.WaitGroup
+	numFound, numUpdated, numDirs uint32
 )
 
 func writeEnvironmentFile(_ Context, envFile string, envDeps map[string]string) error {
@@ -141,6 +146,7 @@ func environmentArgs(config Config, tag string) []string {
 	return []string{
 		"--available_env", shared.JoinPath(config.SoongOutDir(), availableEnvFile),
 		"--used_env", config.UsedEnvFile(tag),
+		"--used_variables", config.UsedVariablesFile(tag),
 	}
 }
 
@@ -174,8 +180,10 @@ type PrimaryBuilderFactory struct {
 	description  string
 	config       Config
 	output       string
+	extraOutputs []string
 	specificArgs []string
 	debugPort    string
+	debugWait    bool
 }
 
 func getGlobPathName(config Config) string {
@@ -218,6 +226,9 @@ func (pb PrimaryBuilderFactory) primaryBuilderInvocation(config Config) bootstra
 		//debug mode
 		commonArgs = append(commonArgs, "--delve_listen", pb.debugPort,
 			"--delve_path", shared.ResolveDelveBinary())
+		if pb.debugWait {
+			commonArgs = append(commonArgs, "--delve_wait")
+		}
 		// GODEBUG=asyncpreemptoff=1 disables the preemption of goroutines. This
 		// is useful because the preemption happens by sending SIGURG to the OS
 		// thread hosting the goroutine in question and each signal results in
@@ -241,11 +252,15 @@ func (pb PrimaryBuilderFactory) primaryBuilderInvocation(config Config) bootstra
 	if profileMem := os.Getenv("SOONG_PROFILE_MEM"); profileMem != "" {
 		allArgs = append(allArgs, "--memprofile", profileMem+"."+pb.name)
 	}
+	if os.Getenv("SOONG_PROFILE_MEM_PER_MUTATOR") != "" {
+		allArgs = append(allArgs, "--memprofile_mutators_dir",
+			filepath.Join(mutatorHeapProfilesDir(pb.config), pb.name))
+	}
 	allArgs = append(allArgs, "Android.bp")
 
 	return bootstrap.PrimaryBuilderInvocation{
 		Implicits:   []string{pb.output + ".glob_results"},
-		Outputs:     []string{pb.output},
+		Outputs:     append([]string{pb.output}, pb.extraOutputs...),
 		Args:        allArgs,
 		Description: pb.description,
 		// NB: Changing the value of this environment variable will not result in a
@@ -336,21 +351,63 @@ func bootstrapBlueprint(ctx Context, config Config) {
 			),
 		},
 		{
-			name:        soongDocsTag,
-			description: fmt.Sprintf("generating Soong docs at %s", config.SoongDocsHtml()),
-			config:      config,
-			output:      config.SoongDocsHtml(),
+			name:         soongDocsTag,
+			description:  fmt.Sprintf("generating Soong docs at %s", config.SoongDocsHtml()),
+			config:       config,
+			output:       config.SoongDocsHtml(),
+			extraOutputs: []string{config.SoongDocsJson()},
 			specificArgs: append(baseArgs,
 				"--soong_docs", config.SoongDocsHtml(),
 			),
 		},
 	}
 
+	if module := config.SoongDumpModule(); module != "" {
+		pbfs = append(pbfs, PrimaryBuilderFactory{
+			name:        soongDumpModuleTag,
+			description: fmt.Sprintf("dumping the properties of %s to %s", module, config.SoongDumpModuleFile()),
+			config:      config,
+			output:      config.SoongDumpModuleFile(),
+			specificArgs: append(baseArgs,
+				"--dump_module", module,
+				"--dump_module_file", config.SoongDumpModuleFile(),
+			),
+		})
+	}
+
+	if allowlist := config.SoongModuleDepsAllowlist(); allowlist != "" {
+		pbfs = append(pbfs, PrimaryBuilderFactory{
+			name:        soongModuleDepsTag,
+			description: fmt.Sprintf("dumping the dependencies of the modules in %s to %s", allowlist, config.SoongModuleDepsFile()),
+			config:      config,
+			output:      config.SoongModuleDepsFile(),
+			specificArgs: append(baseArgs,
+				"--module_deps_allowlist", allowlist,
+				"--module_deps_file", config.SoongModuleDepsFile(),
+			),
+		})
+	}
+
+	if config.SoongIdeMetadata() {
+		pbfs = append(pbfs, PrimaryBuilderFactory{
+			name:        ideMetadataTag,
+			description: fmt.Sprintf("generating the IDE metadata at %s", config.SoongIdeMetadataFile()),
+			config:      config,
+			output:      config.SoongIdeMetadataFile(),
+			specificArgs: append(baseArgs,
+				"--ide_metadata_file", config.SoongIdeMetadataFile(),
+			),
+		})
+	}
+
 	// Figure out which invocations will be run under the debugger:
 	//   * SOONG_DELVE if set specifies listening port
 	//   * SOONG_DELVE_STEPS if set specifies specific invocations to be debugged, otherwise all are
+	//   * SOONG_DELVE_WAIT=false lets the debugged invocations run without waiting for a debugger,
+	//     debuggers can then attach to them while they are running
 	debuggedInvocations := make(map[string]bool)
 	delvePort := os.Getenv("SOONG_DELVE")
+	delveWait := os.Getenv("SOONG_DELVE_WAIT") != "false"
 	if delvePort != "" {
 		if steps := os.Getenv("SOONG_DELVE_STEPS"); steps != "" {
 			var validSteps []string
@@ -379,6 +436,7 @@ func bootstrapBlueprint(ctx Context, config Config) {
 	for _, pbf := range pbfs {
 		if debuggedInvocations[pbf.name] {
 			pbf.debugPort = delvePort
+			pbf.debugWait = delveWait
 		}
 		pbi := pbf.primaryBuilderInvocation(config)
 		invocations = append(invocations, pbi)
@@ -435,8 +493,17 @@ func checkEnvironmentFile(ctx Context, currentEnv *Environment, envFile string)
 	}
 }
 
+// checkProductVariablesFile removes the file containing the product variables used by the previous
+// soong_build invocation if any of them changed, which causes soong_build to rerun.
+func checkProductVariablesFile(variablesFile string, usedFile string) {
+	if stale, _, _ := shared.StaleProductVariablesFile(usedFile, variablesFile); stale {
+		os.Remove(usedFile)
+	}
+}
+
 func updateSymlinks(ctx Context, dir, prevCWD, cwd string, updateSemaphore chan struct{}) error {
 	defer symlinkWg.Done()
+	atomic.AddUint32(&numDirs, 1)
 
 	visit := func(path string, d fs.DirEntry, err error) error {
 		if d.IsDir() && path != dir {
@@ -534,6 +601,9 @@ func fixOutDirSymlinks(ctx Context, config Config, outDir string) error {
 }
 
 func migrateOutputSymlinks(ctx Context, config Config) error {
+	ctx.BeginTrace(metrics.RunSoong, "migrate_output_symlinks")
+	defer ctx.EndTrace()
+
 	// Figure out the real out directory ("out" could be a symlink).
 	outDir := config.OutDir()
 	s, err := os.Lstat(outDir)
@@ -551,7 +621,20 @@ func migrateOutputSymlinks(ctx Context, config Config) error {
 		}
 		outDir = target
 	}
-	return fixOutDirSymlinks(ctx, config, outDir)
+
+	start := time.Now()
+	prevFound, prevUpdated := atomic.LoadUint32(&numFound), atomic.LoadUint32(&numUpdated)
+	prevDirs := atomic.LoadUint32(&numDirs)
+	err = fixOutDirSymlinks(ctx, config, outDir)
+	if ctx.Metrics != nil {
+		ctx.Metrics.SetSymlinkMigrationMetrics(&metrics_proto.SymlinkMigrationMetrics{
+			Micros:          proto.Uint64(uint64(time.Since(start).Microseconds())),
+			SymlinksFound:   proto.Uint32(atomic.LoadUint32(&numFound) - prevFound),
+			SymlinksUpdated: proto.Uint32(atomic.LoadUint32(&numUpdated) - prevUpdated),
+			DirsWalked:      proto.Uint32(atomic.LoadUint32(&numDirs) - prevDirs),
+		})
+	}
+	return err
 }
 
 func runSoong(ctx Context, config Config) {
@@ -575,6 +658,12 @@ func runSoong(ctx Context, config Config) {
 	soongBuildEnv.Set("TOP", os.Getenv("TOP"))
 	soongBuildEnv.Set("LOG_DIR", config.LogsDir())
 
+	// The BUILD_BROKEN_* escape hatch expiry is checked against the date, which Soong reads
+	// from the environment so that it is re-run when the date changes.
+	if buildDateTime, err := strconv.ParseInt(config.BuildDateTime(), 10, 64); err == nil {
+		soongBuildEnv.Set("SOONG_BUILD_BROKEN_TODAY", time.Unix(buildDateTime, 0).UTC().Format("2006-01-02"))
+	}
+
 	// For Soong bootstrapping tests
 	if os.Getenv("ALLOW_MISSING_DEPENDENCIES") == "true" {
 		soongBuildEnv.Set("ALLOW_MISSING_DEPENDENCIES", "true")
@@ -590,13 +679,31 @@ func runSoong(ctx Context, config Config) {
 		defer ctx.EndTrace()
 
 		checkEnvironmentFile(ctx, soongBuildEnv, config.UsedEnvFile(soongBuildTag))
+		checkProductVariablesFile(config.SoongVarsFile(), config.UsedVariablesFile(soongBuildTag))
 
 		if config.JsonModuleGraph() {
 			checkEnvironmentFile(ctx, soongBuildEnv, config.UsedEnvFile(jsonModuleGraphTag))
+			checkProductVariablesFile(config.SoongVarsFile(), config.UsedVariablesFile(jsonModuleGraphTag))
 		}
 
 		if config.SoongDocs() {
 			checkEnvironmentFile(ctx, soongBuildEnv, config.UsedEnvFile(soongDocsTag))
+			checkProductVariablesFile(config.SoongVarsFile(), config.UsedVariablesFile(soongDocsTag))
+		}
+
+		if config.SoongDumpModule() != "" {
+			checkEnvironmentFile(ctx, soongBuildEnv, config.UsedEnvFile(soongDumpModuleTag))
+			checkProductVariablesFile(config.SoongVarsFile(), config.UsedVariablesFile(soongDumpModuleTag))
+		}
+
+		if config.SoongModuleDepsAllowlist() != "" {
+			checkEnvironmentFile(ctx, soongBuildEnv, config.UsedEnvFile(soongModuleDepsTag))
+			checkProductVariablesFile(config.SoongVarsFile(), config.UsedVariablesFile(soongModuleDepsTag))
+		}
+
+		if config.SoongIdeMetadata() {
+			checkEnvironmentFile(ctx, soongBuildEnv, config.UsedEnvFile(ideMetadataTag))
+			checkProductVariablesFile(config.SoongVarsFile(), config.UsedVariablesFile(ideMetadataTag))
 		}
 	}()
 
@@ -605,7 +712,8 @@ func runSoong(ctx Context, config Config) {
 		defer ctx.EndTrace()
 
 		fifo := filepath.Join(config.OutDir(), ".ninja_fifo")
-		nr := status.NewNinjaReader(ctx, ctx.Status.StartTool(), fifo)
+		nr := status.NewNinjaReaderWithFrontendLog(ctx, ctx.Status.StartTool(), fifo,
+			config.NinjaFrontendLog("soong"))
 		defer nr.Close()
 
 		var ninjaCmd string
@@ -693,6 +801,18 @@ func runSoong(ctx Context, config Config) {
 		targets = append(targets, config.SoongDocsHtml())
 	}
 
+	if config.SoongDumpModule() != "" {
+		targets = append(targets, config.SoongDumpModuleFile())
+	}
+
+	if config.SoongModuleDepsAllowlist() != "" {
+		targets = append(targets, config.SoongModuleDepsFile())
+	}
+
+	if config.SoongIdeMetadata() {
+		targets = append(targets, config.SoongIdeMetadataFile())
+	}
+
 	if config.SoongBuildInvocationNeeded() {
 		// This build generates <builddir>/build.ninja, which is used later by build/soong/ui/build/build.go#Build().
 		targets = append(targets, config.SoongNinjaFile())
@@ -710,6 +830,10 @@ func runSoong(ctx Context, config Config) {
 
 	loadSoongBuildMetrics(ctx, config, beforeSoongTimestamp)
 
+	if os.Getenv("SOONG_PROFILE_MEM_PER_MUTATOR") != "" {
+		collectMutatorHeapProfiles(ctx, config)
+	}
+
 	soongNinjaFile := config.SoongNinjaFile()
 	distGzipFile(ctx, config, soongNinjaFile, "soong")
 	for _, file := range blueprint.GetNinjaShardFiles(soongNinjaFile) {
@@ -728,6 +852,90 @@ func runSoong(ctx Context, config Config) {
 	if config.JsonModuleGraph() {
 		distGzipFile(ctx, config, config.ModuleGraphFile(), "soong")
 	}
+
+	if config.SoongDocs() {
+		distFile(ctx, config, config.SoongDocsJson(), "soong")
+	}
+
+	if config.SoongDumpModule() != "" {
+		printDumpedModule(ctx, config)
+	}
+
+	if config.SoongModuleDepsAllowlist() != "" {
+		distFile(ctx, config, config.SoongModuleDepsFile(), "soong")
+		ctx.Printf("The dependencies of the modules in %s were written to %s",
+			config.SoongModuleDepsAllowlist(), config.SoongModuleDepsFile())
+	}
+
+	if config.SoongIdeMetadata() {
+		distFile(ctx, config, config.SoongIdeMetadataFile(), "soong")
+		ctx.Printf("The IDE metadata of all the modules was written to %s", config.SoongIdeMetadataFile())
+	}
+}
+
+// printDumpedModule prints the resolved properties of the module requested with soong_dump_module.
+func printDumpedModule(ctx Context, config Config) {
+	data, err := os.ReadFile(config.SoongDumpModuleFile())
+	if err != nil {
+		ctx.Fatalf("Failed to read the properties of %s: %v", config.SoongDumpModule(), err)
+	}
+	ctx.Println(string(data))
+	ctx.Printf("The properties of %s were written to %s", config.SoongDumpModule(), config.SoongDumpModuleFile())
+}
+
+// mutatorHeapProfilesDir returns the directory that soong_build writes the heap profiles taken
+// after every mutator to when SOONG_PROFILE_MEM_PER_MUTATOR is set.
+func mutatorHeapProfilesDir(config Config) string {
+	return filepath.Join(config.LogsDir(), "soong_mutator_heap_profiles")
+}
+
+// collectMutatorHeapProfiles zips the heap profiles written by the soong_build invocations
+// into a single file in the logs directory and dists it.
+func collectMutatorHeapProfiles(ctx Context, config Config) {
+	dir := mutatorHeapProfilesDir(config)
+	if ok, _ := fileExists(dir); !ok {
+		return
+	}
+	zipFile := dir + ".zip"
+	if err := zipDir(zipFile, dir); err != nil {
+		ctx.Fatalf("failed to collect the mutator heap profiles: %s", err)
+	}
+	ctx.Printf("Heap profiles taken after every mutator are in %s", zipFile)
+	distFile(ctx, config, zipFile, "logs")
+}
+
+// zipDir writes the regular files under dir to zipFile, using their paths relative to dir.
+func zipDir(zipFile, dir string) error {
+	out, err := os.Create(zipFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := zip.NewWriter(out)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		f, err := w.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return w.Close()
 }
 
 // checkGlobs manages the globs that cause soong to rerun.
@@ -783,6 +991,7 @@ func checkGlobs(ctx Context, finalOutFile string) error {
 	wg := sync.WaitGroup{}
 
 	hasChangedGlobs := false
+	var numGlobs, numRerunGlobs, numCheckedDeps uint32
 	var changedGlobNameMutex sync.Mutex
 	var changedGlobName string
 
@@ -799,6 +1008,7 @@ func checkGlobs(ctx Context, finalOutFile string) error {
 				// If not, we don't need to rerun the glob.
 				hasNewDep := false
 				for _, dep := range cachedGlob.Deps {
+					atomic.AddUint32(&numCheckedDeps, 1)
 					info, err := os.Stat(dep)
 					if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
 						hasNewDep = true
@@ -817,6 +1027,7 @@ func checkGlobs(ctx Context, finalOutFile string) error {
 				}
 
 				// Then rerun the glob and check if we got the same result as before.
+				atomic.AddUint32(&numRerunGlobs, 1)
 				result, err := pathtools.Glob(cachedGlob.Pattern, cachedGlob.Excludes, pathtools.FollowSymlinks)
 				if err != nil {
 					errorsChan <- err
@@ -863,6 +1074,7 @@ func checkGlobs(ctx Context, finalOutFile string) error {
 		// Need to clone the GlobResult because the json decoder will
 		// reuse the same slice allocations.
 		globsChan <- cachedGlob.Clone()
+		numGlobs++
 	}
 	close(globsChan)
 	errorsWg.Wait()
@@ -881,6 +1093,20 @@ func checkGlobs(ctx Context, finalOutFile string) error {
 		return err
 	}
 
+	if ctx.Metrics != nil {
+		globCheckMetrics := &metrics_proto.GlobCheckMetrics{
+			Target:      proto.String(finalOutFile),
+			Micros:      proto.Uint64(uint64(time.Now().UnixMicro() - globCheckStartTime)),
+			Globs:       proto.Uint32(numGlobs),
+			RerunGlobs:  proto.Uint32(atomic.LoadUint32(&numRerunGlobs)),
+			CheckedDeps: proto.Uint32(atomic.LoadUint32(&numCheckedDeps)),
+		}
+		if hasChangedGlobs {
+			globCheckMetrics.ChangedGlob = proto.String(changedGlobName)
+		}
+		ctx.Metrics.AddGlobCheckMetrics(globCheckMetrics)
+	}
+
 	if hasChangedGlobs {
 		fmt.Fprintf(os.Stdout, "Globs changed, rerunning soong...\n")
 		fmt.Fprintf(os.Stdout, "One culprit glob (may be more): %s\n", changedGlobName)
@@ -0,0 +1,181 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+// This file implements `m soong_fix`, which runs bpfmt and all the bpfix fixes
+// on the Android.bp files that are modified in the working tree, so that
+// developers don't have to run them over the whole source tree.
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint/parser"
+
+	"android/soong/bpfix/bpfix"
+	"android/soong/ui/metrics"
+)
+
+const soongFixGoal = "soong_fix"
+
+func soongFix(ctx Context, config Config) {
+	ctx.BeginTrace(metrics.RunSetupTool, "soong_fix")
+	defer ctx.EndTrace()
+
+	files := modifiedBlueprintFiles(ctx, config)
+	if len(files) == 0 {
+		ctx.Println("No modified Android.bp files found.")
+		return
+	}
+
+	fixed := 0
+	var failed []string
+	for _, file := range files {
+		changed, err := fixBlueprintFile(file)
+		if err != nil {
+			ctx.Printf("%s: %v", file, err)
+			failed = append(failed, file)
+			continue
+		}
+		if changed {
+			ctx.Println("Fixed", file)
+			fixed++
+		}
+	}
+	ctx.Printf("Checked %d modified Android.bp files, fixed %d.", len(files), fixed)
+	if len(failed) > 0 {
+		ctx.Fatalf("Failed to fix %d Android.bp files: %s", len(failed), strings.Join(failed, ", "))
+	}
+}
+
+// gitProjects returns the git projects in the source tree. In a repo checkout these are listed in
+// .repo/project.list, otherwise the root of the tree is expected to be a git project itself.
+func gitProjects(ctx Context) []string {
+	data, err := os.ReadFile(filepath.Join(".repo", "project.list"))
+	if os.IsNotExist(err) {
+		return []string{"."}
+	} else if err != nil {
+		ctx.Fatalf("Failed to read the list of projects: %v", err)
+	}
+	var projects []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if project := strings.TrimSpace(scanner.Text()); project != "" {
+			projects = append(projects, project)
+		}
+	}
+	return projects
+}
+
+// modifiedBlueprintFiles returns the Android.bp files that are added, modified or untracked in the
+// git projects of the source tree, relative to the root of the tree.
+func modifiedBlueprintFiles(ctx Context, config Config) []string {
+	projects := gitProjects(ctx)
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	var files []string
+	// Running git status in every project of a full checkout takes a while, run them in parallel.
+	sem := make(chan struct{}, runtime.NumCPU())
+	for _, project := range projects {
+		wg.Add(1)
+		go func(project string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			cmd := Command(ctx, config, "git status", "git", "-C", project,
+				"status", "--porcelain=v1", "-z", "--untracked-files=all",
+				"--", "Android.bp", "**/Android.bp")
+			output, err := cmd.Output()
+			if err != nil {
+				ctx.Verbosef("Skipping %s, git status failed: %v", project, err)
+				return
+			}
+			projectFiles := parseGitStatus(project, output)
+			lock.Lock()
+			files = append(files, projectFiles...)
+			lock.Unlock()
+		}(project)
+	}
+	wg.Wait()
+
+	sort.Strings(files)
+	return files
+}
+
+// parseGitStatus parses the output of `git status --porcelain=v1 -z` run in project and returns
+// the Android.bp files that exist in the working tree.
+func parseGitStatus(project string, output []byte) []string {
+	var files []string
+	entries := strings.Split(string(output), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		status, path := entry[:2], entry[3:]
+		if status[0] == 'R' || status[0] == 'C' {
+			// Renames and copies are followed by the original path.
+			i++
+		}
+		if status[0] == 'D' || status[1] == 'D' {
+			continue
+		}
+		if filepath.Base(path) != "Android.bp" {
+			continue
+		}
+		files = append(files, filepath.Join(project, path))
+	}
+	return files
+}
+
+// fixBlueprintFile formats the given Android.bp file and applies all the bpfix fixes to it in
+// place. It returns true if the file was changed.
+func fixBlueprintFile(path string) (bool, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	file, errs := parser.Parse(path, bytes.NewReader(src))
+	if len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Error()
+		}
+		return false, fmt.Errorf("%d parsing errors:\n%s", len(errs), strings.Join(msgs, "\n"))
+	}
+
+	file, err = bpfix.NewFixer(file).Fix(bpfix.NewFixRequest().AddAll())
+	if err != nil {
+		return false, err
+	}
+
+	res, err := parser.Print(file)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(src, res) {
+		return false, nil
+	}
+	return true, os.WriteFile(path, res, 0644)
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGitStatus(t *testing.T) {
+	output := strings.Join([]string{
+		" M Android.bp",
+		"A  foo/Android.bp",
+		"?? bar/Android.bp",
+		" D deleted/Android.bp",
+		" M foo/foo.go",
+		"R  renamed/Android.bp",
+		"old/Android.bp",
+		"",
+	}, "\x00")
+
+	got := parseGitStatus("project", []byte(output))
+	want := []string{
+		"project/Android.bp",
+		"project/foo/Android.bp",
+		"project/bar/Android.bp",
+		"project/renamed/Android.bp",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGitStatus() = %q, want %q", got, want)
+	}
+}
+
+func TestFixBlueprintFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Android.bp")
+
+	unformatted := "java_library { name: \"foo\",\n srcs: [\"a.java\"] }\n"
+	formatted := "java_library {\n    name: \"foo\",\n    srcs: [\"a.java\"],\n}\n"
+
+	if err := os.WriteFile(path, []byte(unformatted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := fixBlueprintFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Errorf("expected %s to be changed", path)
+	}
+	if got, _ := os.ReadFile(path); string(got) != formatted {
+		t.Errorf("expected fixed contents:\n%s\ngot:\n%s", formatted, got)
+	}
+
+	changed, err = fixBlueprintFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Errorf("expected %s to be unchanged the second time", path)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/ui/metrics"
+)
+
+// When modules are removed or renamed their outputs stay in out/soong, where they can be picked up
+// by globs or packaging. With --clean-stale-outputs soong_ui records the outputs declared by the
+// Soong ninja file of each product after running Soong, and removes the files that were declared by
+// the previous build but are no longer declared by any product. --report-stale-outputs only lists
+// the files that would be removed in stale_soong_outputs.txt in the logs directory.
+
+const declaredOutputsSuffix = ".declared_outputs"
+
+type staleOutputsMode int
+
+const (
+	staleOutputsIgnore staleOutputsMode = iota
+	staleOutputsReport
+	staleOutputsClean
+)
+
+// readDeclaredOutputs parses the output of ninja -t targets all and returns the sorted outputs of
+// the non-phony rules in soongOutDir, relative to soongOutDir.
+func readDeclaredOutputs(r io.Reader, soongOutDir string) ([]string, error) {
+	var outputs []string
+	prefix := soongOutDir + "/"
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		i := strings.LastIndex(line, ": ")
+		if i == -1 {
+			return nil, fmt.Errorf("malformed ninja target %q", line)
+		}
+		output, rule := line[:i], line[i+2:]
+		if rule == "phony" || !strings.HasPrefix(output, prefix) {
+			continue
+		}
+		outputs = append(outputs, strings.TrimPrefix(output, prefix))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Strings(outputs)
+	return outputs, nil
+}
+
+// staleOutputs returns the outputs in oldOutputs that are in none of the newOutputs lists.
+func staleOutputs(oldOutputs []string, newOutputs ...[]string) []string {
+	declared := make(map[string]bool)
+	for _, outputs := range newOutputs {
+		for _, output := range outputs {
+			declared[output] = true
+		}
+	}
+
+	var stale []string
+	for _, output := range oldOutputs {
+		if !declared[output] {
+			stale = append(stale, output)
+		}
+	}
+	return stale
+}
+
+func readOutputsList(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+func writeOutputsList(file string, outputs []string) error {
+	return os.WriteFile(file, []byte(strings.Join(outputs, "\n")+"\n"), 0666) // a+rw
+}
+
+// cleanStaleSoongOutputs finds the files in out/soong that were declared as outputs by a previous
+// build but are no longer declared, and removes them or reports them depending on
+// --clean-stale-outputs or --report-stale-outputs.
+func cleanStaleSoongOutputs(ctx Context, config Config) {
+	mode := config.StaleOutputsMode()
+	if mode == staleOutputsIgnore {
+		return
+	}
+
+	ctx.BeginTrace(metrics.RunSetupTool, "stale_outputs")
+	defer ctx.EndTrace()
+
+	soongOutDir := config.SoongOutDir()
+	soongNinjaFile := config.SoongNinjaFile()
+
+	cmd := Command(ctx, config, "ninja", config.PrebuiltBuildTool("ninja"),
+		"-f", soongNinjaFile, "-t", "targets", "all")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		ctx.Fatal(err)
+	}
+	cmd.StartOrFatal()
+	newOutputs, err := readDeclaredOutputs(stdout, soongOutDir)
+	if err != nil {
+		ctx.Fatalf("Failed to read the outputs of %s: %v", soongNinjaFile, err)
+	}
+	cmd.WaitOrFatal()
+
+	manifest := strings.TrimSuffix(soongNinjaFile, ".ninja") + declaredOutputsSuffix
+	oldOutputs, err := readOutputsList(manifest)
+	if os.IsNotExist(err) {
+		// First build with stale output detection, nothing to compare against.
+		if err := writeOutputsList(manifest, newOutputs); err != nil {
+			ctx.Fatalf("Failed to write %s: %v", manifest, err)
+		}
+		return
+	} else if err != nil {
+		ctx.Fatalf("Failed to read %s: %v", manifest, err)
+	}
+
+	// The outputs of other products in the same out directory aren't stale.
+	otherManifests, err := filepath.Glob(filepath.Join(soongOutDir, "*"+declaredOutputsSuffix))
+	if err != nil {
+		ctx.Fatal(err)
+	}
+	declared := [][]string{newOutputs}
+	for _, other := range otherManifests {
+		if other == manifest {
+			continue
+		}
+		outputs, err := readOutputsList(other)
+		if err != nil {
+			ctx.Fatalf("Failed to read %s: %v", other, err)
+		}
+		declared = append(declared, outputs)
+	}
+
+	var stale []string
+	for _, output := range staleOutputs(oldOutputs, declared...) {
+		if _, err := os.Lstat(filepath.Join(soongOutDir, output)); err == nil {
+			stale = append(stale, output)
+		}
+	}
+
+	if mode == staleOutputsReport {
+		reportFile := filepath.Join(config.LogsDir(), "stale_soong_outputs.txt")
+		if err := writeOutputsList(reportFile, stale); err != nil {
+			ctx.Fatalf("Failed to write %s: %v", reportFile, err)
+		}
+		if len(stale) > 0 {
+			ctx.Printf("Found %d stale outputs in %s, see %s\n", len(stale), soongOutDir, reportFile)
+		}
+		// Keep tracking the stale outputs that still exist so that they are reported again, and
+		// removed if cleaning is enabled later.
+		newOutputs = append(newOutputs, stale...)
+		sort.Strings(newOutputs)
+	} else {
+		for _, output := range stale {
+			path := filepath.Join(soongOutDir, output)
+			if err := os.RemoveAll(path); err != nil {
+				ctx.Fatalf("Failed to remove stale output %q: %v", path, err)
+			}
+			ctx.Verboseln("Removed stale output:", path)
+			cleanEmptyDirs(ctx, filepath.Dir(path))
+		}
+		if len(stale) > 0 {
+			ctx.Printf("Removed %d stale outputs from %s\n", len(stale), soongOutDir)
+		}
+	}
+
+	if err := writeOutputsList(manifest, newOutputs); err != nil {
+		ctx.Fatalf("Failed to write %s: %v", manifest, err)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadDeclaredOutputs(t *testing.T) {
+	targets := strings.Join([]string{
+		"out/soong/.intermediates/foo/android_common/foo.jar: javac",
+		"foo: phony",
+		"out/soong/.intermediates/bar/android_common/bar.jar: javac",
+		"out/soong/checkbuild: phony",
+		"out/target/product/generic/system/foo.jar: Cp",
+		"",
+	}, "\n")
+
+	got, err := readDeclaredOutputs(strings.NewReader(targets), "out/soong")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []string{
+		".intermediates/bar/android_common/bar.jar",
+		".intermediates/foo/android_common/foo.jar",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	if _, err := readDeclaredOutputs(strings.NewReader("out/soong/foo\n"), "out/soong"); err == nil {
+		t.Errorf("expected an error for a malformed target")
+	}
+}
+
+func TestStaleOutputs(t *testing.T) {
+	oldOutputs := []string{"a", "b", "c", "d"}
+	newOutputs := []string{"a", "e"}
+	otherProductOutputs := []string{"c"}
+
+	got := staleOutputs(oldOutputs, newOutputs, otherProductOutputs)
+	want := []string{"b", "d"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
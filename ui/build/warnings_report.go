@@ -0,0 +1,265 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"android/soong/ui/status"
+)
+
+// The javac and kotlinc warnings of a module are repeated for each of its variants, which makes
+// them hard to find in the build output. With --warnings-report soong_ui collects the warnings
+// from the output of the actions, deduplicates them, attributes them to the owners listed in the
+// OWNERS file nearest to the source file, and writes a summary grouped by owner to
+// warnings_report.txt in the logs directory.
+
+var (
+	// javac: path/to/Foo.java:12: warning: [deprecation] ...
+	javacWarningRe = regexp.MustCompile(`^(\S+\.java):(\d+): warning: (.*)$`)
+	// kotlinc: w: file:///path/to/Foo.kt:12:5 ... or w: path/to/Foo.kt: (12, 5): ...
+	kotlincWarningRe = regexp.MustCompile(`^w: (?:file://)?(\S+\.kts?)(?::(\d+):\d+|: \((\d+), \d+\):) (.*)$`)
+	// javac warnings that aren't about a source file, e.g. warning: [options] ...
+	javacGlobalWarningRe = regexp.MustCompile(`^warning: (.*)$`)
+)
+
+// buildWarning is a warning about a line of a source file, or about no file at all if File is empty.
+type buildWarning struct {
+	File    string
+	Line    int
+	Message string
+}
+
+// parseWarnings returns the javac and kotlinc warnings in the output of an action. Absolute paths
+// in topDir are made relative to it.
+func parseWarnings(output, topDir string) []buildWarning {
+	var warnings []buildWarning
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		var w buildWarning
+		if m := javacWarningRe.FindStringSubmatch(line); m != nil {
+			w.File, w.Message = m[1], m[3]
+			w.Line, _ = strconv.Atoi(m[2])
+		} else if m := kotlincWarningRe.FindStringSubmatch(line); m != nil {
+			w.File, w.Message = m[1], m[4]
+			lineNumber := m[2]
+			if lineNumber == "" {
+				lineNumber = m[3]
+			}
+			w.Line, _ = strconv.Atoi(lineNumber)
+		} else if m := javacGlobalWarningRe.FindStringSubmatch(line); m != nil {
+			w.Message = m[1]
+		} else {
+			continue
+		}
+		if rel, err := filepath.Rel(topDir, w.File); err == nil && filepath.IsAbs(w.File) && !strings.HasPrefix(rel, "../") {
+			w.File = rel
+		}
+		warnings = append(warnings, w)
+	}
+	return warnings
+}
+
+// warningsCollector is a status.StatusOutput that counts the warnings in the output of the actions.
+type warningsCollector struct {
+	topDir string
+
+	lock     sync.Mutex
+	warnings map[buildWarning]int
+}
+
+func newWarningsCollector(topDir string) *warningsCollector {
+	return &warningsCollector{
+		topDir:   topDir,
+		warnings: make(map[buildWarning]int),
+	}
+}
+
+func (w *warningsCollector) StartAction(action *status.Action, counts status.Counts) {}
+
+func (w *warningsCollector) FinishAction(result status.ActionResult, counts status.Counts) {
+	if result.Output == "" {
+		return
+	}
+	warnings := parseWarnings(result.Output, w.topDir)
+	if len(warnings) == 0 {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, warning := range warnings {
+		w.warnings[warning]++
+	}
+}
+
+func (w *warningsCollector) Message(level status.MsgLevel, msg string) {}
+
+func (w *warningsCollector) Flush() {}
+
+func (w *warningsCollector) Write(p []byte) (n int, err error) { return len(p), nil }
+
+// ownersFinder finds the owners of source files from the nearest OWNERS file.
+type ownersFinder struct {
+	topDir string
+	cache  map[string][]string
+}
+
+func newOwnersFinder(topDir string) *ownersFinder {
+	return &ownersFinder{
+		topDir: topDir,
+		cache:  make(map[string][]string),
+	}
+}
+
+// parseOwners returns the email addresses listed in an OWNERS file, ignoring comments,
+// per-file rules, includes and other directives.
+func parseOwners(r io.Reader) []string {
+	var owners []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i != -1 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if strings.Contains(line, "@") && !strings.ContainsAny(line, " =:") {
+			owners = append(owners, line)
+		}
+	}
+	return owners
+}
+
+// ownersOfDir returns the owners from the nearest OWNERS file that lists any owners in dir or
+// one of its parents, relative to the top of the tree.
+func (o *ownersFinder) ownersOfDir(dir string) []string {
+	if owners, ok := o.cache[dir]; ok {
+		return owners
+	}
+
+	var owners []string
+	if f, err := os.Open(filepath.Join(o.topDir, dir, "OWNERS")); err == nil {
+		owners = parseOwners(f)
+		f.Close()
+	}
+	if len(owners) == 0 && dir != "." && dir != "/" {
+		owners = o.ownersOfDir(filepath.Dir(dir))
+	}
+
+	o.cache[dir] = owners
+	return owners
+}
+
+func (o *ownersFinder) owners(file string) string {
+	if file == "" || filepath.IsAbs(file) {
+		return ""
+	}
+	return strings.Join(o.ownersOfDir(filepath.Dir(file)), ", ")
+}
+
+type countedWarning struct {
+	buildWarning
+	count int
+}
+
+// formatWarningsReport writes the warnings grouped by owners, with the owners with the most
+// warnings first.
+func formatWarningsReport(w io.Writer, warnings map[buildWarning]int, owners func(file string) string) {
+	total := 0
+	byOwners := make(map[string][]countedWarning)
+	for warning, count := range warnings {
+		total += count
+		o := owners(warning.File)
+		byOwners[o] = append(byOwners[o], countedWarning{warning, count})
+	}
+
+	fmt.Fprintf(w, "%d unique warnings (%d including duplicates)\n", len(warnings), total)
+
+	var ownersList []string
+	for o := range byOwners {
+		ownersList = append(ownersList, o)
+	}
+	sort.Slice(ownersList, func(i, j int) bool {
+		a, b := ownersList[i], ownersList[j]
+		if len(byOwners[a]) != len(byOwners[b]) {
+			return len(byOwners[a]) > len(byOwners[b])
+		}
+		return a < b
+	})
+
+	for _, o := range ownersList {
+		list := byOwners[o]
+		sort.Slice(list, func(i, j int) bool {
+			a, b := list[i], list[j]
+			if a.File != b.File {
+				return a.File < b.File
+			}
+			if a.Line != b.Line {
+				return a.Line < b.Line
+			}
+			return a.Message < b.Message
+		})
+
+		if o == "" {
+			o = "no owners"
+		}
+		fmt.Fprintf(w, "\n%s: %d warnings\n", o, len(list))
+		for _, warning := range list {
+			location := "<no file>"
+			if warning.File != "" {
+				location = warning.File + ":" + strconv.Itoa(warning.Line)
+			}
+			fmt.Fprintf(w, "  %s: %s", location, warning.Message)
+			if warning.count > 1 {
+				fmt.Fprintf(w, " (x%d)", warning.count)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// writeWarningsReport writes the warnings collected during the build to warnings_report.txt in
+// the logs directory and copies it to the dist directory.
+func writeWarningsReport(ctx Context, config Config, collector *warningsCollector) {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+
+	reportFile := filepath.Join(config.LogsDir(), "warnings_report.txt")
+	f, err := os.Create(reportFile)
+	if err != nil {
+		ctx.Printf("failed to create %s: %s", reportFile, err.Error())
+		return
+	}
+	w := bufio.NewWriter(f)
+	formatWarningsReport(w, collector.warnings, newOwnersFinder(collector.topDir).owners)
+	if err := w.Flush(); err != nil {
+		ctx.Printf("failed to write %s: %s", reportFile, err.Error())
+	}
+	f.Close()
+
+	distFile(ctx, config, reportFile, "logs")
+
+	if len(collector.warnings) > 0 {
+		fmt.Fprintf(ctx.Writer, "%d unique warnings, see %s\n", len(collector.warnings), reportFile)
+	}
+}
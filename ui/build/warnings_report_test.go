@@ -0,0 +1,128 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"android/soong/ui/status"
+)
+
+func TestParseWarnings(t *testing.T) {
+	output := strings.Join([]string{
+		"frameworks/base/Foo.java:12: warning: [deprecation] bar() in Baz has been deprecated",
+		"    bar();",
+		"    ^",
+		"w: file:///top/frameworks/base/Foo.kt:3:5 'foo' is deprecated.",
+		"w: frameworks/base/Bar.kt: (7, 1): Unchecked cast",
+		"warning: [options] source value 8 is obsolete",
+		"1 warning",
+	}, "\n")
+
+	got := parseWarnings(output, "/top")
+	want := []buildWarning{
+		{"frameworks/base/Foo.java", 12, "[deprecation] bar() in Baz has been deprecated"},
+		{"frameworks/base/Foo.kt", 3, "'foo' is deprecated."},
+		{"frameworks/base/Bar.kt", 7, "Unchecked cast"},
+		{"", 0, "[options] source value 8 is obsolete"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestWarningsCollector(t *testing.T) {
+	collector := newWarningsCollector("/top")
+	for _, variant := range []string{"android_common", "linux_glibc_common"} {
+		collector.FinishAction(status.ActionResult{
+			Action: &status.Action{Description: "javac foo " + variant},
+			Output: "a/Foo.java:1: warning: unchecked\n",
+		}, status.Counts{})
+	}
+
+	want := map[buildWarning]int{{"a/Foo.java", 1, "unchecked"}: 2}
+	if !reflect.DeepEqual(collector.warnings, want) {
+		t.Errorf("want %v, got %v", want, collector.warnings)
+	}
+}
+
+func TestOwnersFinder(t *testing.T) {
+	topDir := t.TempDir()
+	write := func(file, contents string) {
+		path := filepath.Join(topDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a/OWNERS", "# comment\nset noparent\nfoo@example.com\nbar@example.com # lead\nper-file *.kt = baz@example.com\n")
+	write("a/b/OWNERS", "include /a/OWNERS\n")
+	write("a/b/c/Foo.java", "")
+
+	finder := newOwnersFinder(topDir)
+	got := finder.owners("a/b/c/Foo.java")
+	if want := "foo@example.com, bar@example.com"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	if got := finder.owners("d/Foo.java"); got != "" {
+		t.Errorf("want no owners, got %q", got)
+	}
+	if got := finder.owners(""); got != "" {
+		t.Errorf("want no owners, got %q", got)
+	}
+}
+
+func TestFormatWarningsReport(t *testing.T) {
+	warnings := map[buildWarning]int{
+		{"a/Foo.java", 2, "unchecked"}:       2,
+		{"a/Foo.java", 1, "deprecated"}:      1,
+		{"b/Bar.java", 1, "raw type"}:        3,
+		{"", 0, "[options] obsolete source"}: 1,
+	}
+	owners := func(file string) string {
+		if strings.HasPrefix(file, "a/") {
+			return "a@example.com"
+		} else if strings.HasPrefix(file, "b/") {
+			return "b@example.com"
+		}
+		return ""
+	}
+
+	var report strings.Builder
+	formatWarningsReport(&report, warnings, owners)
+	want := strings.Join([]string{
+		"4 unique warnings (7 including duplicates)",
+		"",
+		"a@example.com: 2 warnings",
+		"  a/Foo.java:1: deprecated",
+		"  a/Foo.java:2: unchecked (x2)",
+		"",
+		"no owners: 1 warnings",
+		"  <no file>: [options] obsolete source",
+		"",
+		"b@example.com: 1 warnings",
+		"  b/Bar.java:1: raw type (x3)",
+		"",
+	}, "\n")
+	if report.String() != want {
+		t.Errorf("want report:\n%s\ngot:\n%s", want, report.String())
+	}
+}
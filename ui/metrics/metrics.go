@@ -160,6 +160,18 @@ func (m *Metrics) ExpConfigFetcher(b *soong_metrics_proto.ExpConfigFetcher) {
 	m.metrics.ExpConfigFetcher = b
 }
 
+// AddGlobCheckMetrics stores information about a glob check run before
+// soong_build.
+func (m *Metrics) AddGlobCheckMetrics(b *soong_metrics_proto.GlobCheckMetrics) {
+	m.metrics.GlobChecks = append(m.metrics.GlobChecks, b)
+}
+
+// SetSymlinkMigrationMetrics stores information about the migration of the
+// symlinks in the output directory.
+func (m *Metrics) SetSymlinkMigrationMetrics(b *soong_metrics_proto.SymlinkMigrationMetrics) {
+	m.metrics.SymlinkMigration = b
+}
+
 // SetMetadataMetrics sets information about the build such as the target
 // product, host architecture and out directory.  May be called multiple times.
 func (m *Metrics) SetMetadataMetrics(metadata map[string]string) {
@@ -255,6 +267,12 @@ func (m *Metrics) Dump(out string) error {
 	return shared.Save(&m.metrics, out)
 }
 
+// MetricsBase returns the top-level build metrics collected so far. The
+// returned proto must not be modified.
+func (m *Metrics) MetricsBase() *soong_metrics_proto.MetricsBase {
+	return &m.metrics
+}
+
 // SetSoongBuildMetrics sets the metrics collected from the soong_build
 // execution.
 func (m *Metrics) SetSoongBuildMetrics(metrics *soong_metrics_proto.SoongBuildMetrics) {
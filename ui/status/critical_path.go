@@ -15,6 +15,9 @@
 package status
 
 import (
+	"fmt"
+	"io"
+
 	"android/soong/ui/metrics"
 
 	soong_metrics_proto "android/soong/ui/metrics/metrics_proto"
@@ -52,6 +55,7 @@ func (osClock) Now() time.Time { return time.Now() }
 // perfect parallelism) for an node.
 type node struct {
 	action             *Action
+	start              time.Time
 	cumulativeDuration time.Duration
 	duration           time.Duration
 	input              *node
@@ -89,6 +93,7 @@ func (cp *CriticalPath) FinishAction(action *Action) {
 
 		node := &node{
 			action:             action,
+			start:              start,
 			cumulativeDuration: cumulativeDuration,
 			duration:           duration,
 			input:              criticalPathInput,
@@ -155,3 +160,34 @@ func (cp *CriticalPath) WriteToMetrics(met *metrics.Metrics) {
 	addJobInfos(&criticalPathInfo.CriticalPath, path)
 	met.SetCriticalPathInfo(criticalPathInfo)
 }
+
+// WriteReport writes a human readable report of the critical path to w: the chain of dependent actions that
+// determined the minimum build time, each with its start time relative to the start of the build and its duration.
+func (cp *CriticalPath) WriteReport(w io.Writer) error {
+	path, elapsedTime, criticalTime := cp.criticalPath()
+	if len(path) == 0 {
+		_, err := fmt.Fprintln(w, "no actions were run")
+		return err
+	}
+
+	fmt.Fprintf(w, "critical path: %s\n", criticalTime.Round(time.Millisecond))
+	fmt.Fprintf(w, "elapsed time: %s\n", elapsedTime.Round(time.Millisecond))
+	if elapsedTime > 0 {
+		fmt.Fprintf(w, "perfect parallelism ratio: %d%%\n", int(float64(criticalTime)/float64(elapsedTime)*100))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%10s %10s  %s\n", "start", "duration", "action")
+	for i := len(path) - 1; i >= 0; i-- {
+		n := path[i]
+		desc := n.action.Description
+		if desc == "" && len(n.action.Outputs) > 0 {
+			desc = n.action.Outputs[0]
+		}
+		_, err := fmt.Fprintf(w, "%10s %10s  %s\n",
+			n.start.Sub(cp.start).Round(time.Millisecond), n.duration.Round(time.Millisecond), desc)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
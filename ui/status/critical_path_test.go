@@ -16,6 +16,7 @@ package status
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -162,3 +163,42 @@ func TestCriticalPath(t *testing.T) {
 		})
 	}
 }
+
+func TestCriticalPathReport(t *testing.T) {
+	cp := &testCriticalPath{
+		CriticalPath: NewCriticalPath(),
+		actions:      make(map[int]*Action),
+	}
+
+	//  a
+	//  |\
+	//  b c
+	//  |/
+	//  d
+	cp.start(0, 0, []string{"a"}, nil)
+	cp.finish(0, time.Second)
+	cp.start(1, time.Second, []string{"b"}, []string{"a"})
+	cp.start(2, time.Second, []string{"c"}, []string{"a"})
+	cp.finish(1, 2*time.Second)
+	cp.finish(2, 3*time.Second)
+	cp.start(3, 3*time.Second, []string{"d"}, []string{"b", "c"})
+	cp.finish(3, 4*time.Second+500*time.Millisecond)
+
+	var report strings.Builder
+	if err := cp.WriteReport(&report); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `critical path: 4.5s
+elapsed time: 4.5s
+perfect parallelism ratio: 100%
+
+     start   duration  action
+        0s         1s  a
+        1s         2s  c
+        3s       1.5s  d
+`
+	if got := report.String(); got != want {
+		t.Errorf("WriteReport() = %q, want %q", got, want)
+	}
+}
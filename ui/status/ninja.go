@@ -34,6 +34,14 @@ import (
 // NewNinjaReader reads the protobuf frontend format from ninja and translates it
 // into calls on the ToolStatus API.
 func NewNinjaReader(ctx logger.Logger, status ToolStatus, fifo string) *NinjaReader {
+	return NewNinjaReaderWithFrontendLog(ctx, status, fifo, "")
+}
+
+// NewNinjaReaderWithFrontendLog is like NewNinjaReader, but if frontendLog is not empty it
+// also writes a gzip compressed copy of every message from ninja to it, which can be read
+// back with ReadNinjaFrontendLog.
+func NewNinjaReaderWithFrontendLog(ctx logger.Logger, status ToolStatus, fifo string,
+	frontendLog string) *NinjaReader {
 	os.Remove(fifo)
 
 	if err := syscall.Mkfifo(fifo, 0666); err != nil {
@@ -41,12 +49,13 @@ func NewNinjaReader(ctx logger.Logger, status ToolStatus, fifo string) *NinjaRea
 	}
 
 	n := &NinjaReader{
-		status:     status,
-		fifo:       fifo,
-		forceClose: make(chan bool),
-		done:       make(chan bool),
-		cancelOpen: make(chan bool),
-		running:    make(map[uint32]*Action),
+		status:      status,
+		fifo:        fifo,
+		frontendLog: frontendLog,
+		forceClose:  make(chan bool),
+		done:        make(chan bool),
+		cancelOpen:  make(chan bool),
+		running:     make(map[uint32]*Action),
 	}
 
 	go n.run()
@@ -55,12 +64,13 @@ func NewNinjaReader(ctx logger.Logger, status ToolStatus, fifo string) *NinjaRea
 }
 
 type NinjaReader struct {
-	status     ToolStatus
-	fifo       string
-	forceClose chan bool
-	done       chan bool
-	cancelOpen chan bool
-	running    map[uint32]*Action
+	status      ToolStatus
+	fifo        string
+	frontendLog string
+	forceClose  chan bool
+	done        chan bool
+	cancelOpen  chan bool
+	running     map[uint32]*Action
 }
 
 const NINJA_READER_CLOSE_TIMEOUT = 5 * time.Second
@@ -144,10 +154,25 @@ func (n *NinjaReader) run() {
 
 	msgChan := make(chan *ninja_frontend.Status)
 
+	var log *frontendLog
+	if n.frontendLog != "" {
+		var err error
+		if log, err = newFrontendLog(n.frontendLog); err != nil {
+			n.status.Error(fmt.Sprintf("Failed to create ninja frontend log: %v", err))
+		}
+	}
+
 	// Read from the ninja fifo and decode the protobuf in a goroutine so the main NinjaReader.run goroutine
 	// can listen
 	go func() {
 		defer close(msgChan)
+		defer func() {
+			if log != nil {
+				if err := log.close(); err != nil {
+					n.status.Error(fmt.Sprintf("Failed to write ninja frontend log: %v", err))
+				}
+			}
+		}()
 		for {
 			size, err := readVarInt(r)
 			if err != nil {
@@ -168,6 +193,14 @@ func (n *NinjaReader) run() {
 				return
 			}
 
+			if log != nil {
+				if err := log.write(buf); err != nil {
+					n.status.Error(fmt.Sprintf("Failed to write ninja frontend log: %v", err))
+					log.close()
+					log = nil
+				}
+			}
+
 			msg := &ninja_frontend.Status{}
 			err = proto.Unmarshal(buf, msg)
 			if err != nil {
@@ -0,0 +1,105 @@
+// Copyright 2025 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"google.golang.org/protobuf/proto"
+
+	"android/soong/ui/status/ninja_frontend"
+)
+
+// frontendLog writes a gzip compressed copy of the messages received from the ninja frontend, in the
+// same varint length-delimited format that ninja writes to the fifo. The messages contain the start
+// and end time of every action relative to the start of ninja along with its inputs, outputs and
+// command, which allows a postmortem analysis of the build.
+type frontendLog struct {
+	file *os.File
+	gz   *gzip.Writer
+	buf  []byte
+}
+
+func newFrontendLog(filename string) (*frontendLog, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &frontendLog{
+		file: f,
+		gz:   gzip.NewWriter(f),
+	}, nil
+}
+
+// write appends a serialized ninja_frontend.Status message to the log.
+func (l *frontendLog) write(msg []byte) error {
+	l.buf = binary.AppendUvarint(l.buf[:0], uint64(len(msg)))
+	if _, err := l.gz.Write(l.buf); err != nil {
+		return err
+	}
+	_, err := l.gz.Write(msg)
+	return err
+}
+
+func (l *frontendLog) close() error {
+	err := l.gz.Close()
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ReadNinjaFrontendLog reads the messages from a log written by a NinjaReader created with
+// NewNinjaReaderWithFrontendLog.
+func ReadNinjaFrontendLog(filename string) ([]*ninja_frontend.Status, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	r := bufio.NewReader(gz)
+	var msgs []*ninja_frontend.Status
+	for {
+		size, err := readVarInt(r)
+		if err == io.EOF {
+			return msgs, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, size)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("truncated message of size %d: %w", size, err)
+		}
+
+		msg := &ninja_frontend.Status{}
+		if err := proto.Unmarshal(buf, msg); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+}
@@ -15,13 +15,17 @@
 package status
 
 import (
+	"encoding/binary"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"google.golang.org/protobuf/proto"
+
 	"android/soong/ui/logger"
+	"android/soong/ui/status/ninja_frontend"
 )
 
 // Tests that closing the ninja reader when nothing has opened the other end of the fifo is fast.
@@ -44,6 +48,65 @@ func TestNinjaReader_Close(t *testing.T) {
 	}
 }
 
+// Tests that the messages from ninja are written to the frontend log.
+func TestNinjaReader_FrontendLog(t *testing.T) {
+	tempDir := t.TempDir()
+	fifo := filepath.Join(tempDir, "fifo")
+	logFile := filepath.Join(tempDir, "ninja_frontend.pb.gz")
+
+	stat := &Status{}
+	nr := NewNinjaReaderWithFrontendLog(logger.New(ioutil.Discard), stat.StartTool(), fifo, logFile)
+
+	msgs := []*ninja_frontend.Status{
+		{TotalEdges: &ninja_frontend.Status_TotalEdges{TotalEdges: proto.Uint32(1)}},
+		{EdgeStarted: &ninja_frontend.Status_EdgeStarted{
+			Id:        proto.Uint32(1),
+			StartTime: proto.Uint32(10),
+			Outputs:   []string{"out/foo"},
+			Desc:      proto.String("foo"),
+		}},
+		{EdgeFinished: &ninja_frontend.Status_EdgeFinished{
+			Id:      proto.Uint32(1),
+			EndTime: proto.Uint32(25),
+			Status:  proto.Int32(0),
+		}},
+		{BuildFinished: &ninja_frontend.Status_BuildFinished{}},
+	}
+
+	f, err := os.OpenFile(fifo, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range msgs {
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(append(binary.AppendUvarint(nil, uint64(len(data))), data...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	// Wait for the reader to see the end of the fifo before closing it so that it doesn't stop before
+	// processing the messages.
+	<-nr.done
+	nr.Close()
+
+	got, err := ReadNinjaFrontendLog(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(msgs) {
+		t.Fatalf("expected %d messages, got %d", len(msgs), len(got))
+	}
+	for i := range msgs {
+		if !proto.Equal(msgs[i], got[i]) {
+			t.Errorf("message %d: expected %v, got %v", i, msgs[i], got[i])
+		}
+	}
+}
+
 // Test that error hint is added to output if available
 func TestNinjaReader_CorrectErrorHint(t *testing.T) {
 	errorPattern1 := "pattern-1 in input"
@@ -175,6 +175,7 @@ func main() {
 	sha256Checksum := flags.Bool("sha256", false, "add a zip header to each file containing its SHA256 digest")
 	doNotWrite := flags.Bool("n", false, "Nothing is written to disk -- all other work happens")
 	quiet := flags.Bool("quiet", false, "do not print warnings to console")
+	noZip64 := flags.Bool("no-zip64", false, "fail instead of writing --jar outputs that require the zip64 extensions")
 
 	flags.Var(&rootPrefix{}, "P", "path prefix within the zip at which to place files")
 	flags.Var(&listFiles{}, "l", "file containing list of files to zip")
@@ -240,6 +241,7 @@ func main() {
 		Sha256Checksum:           *sha256Checksum,
 		DoNotWrite:               *doNotWrite,
 		Quiet:                    *quiet,
+		NoZip64:                  *noZip64,
 	})
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "error:", err.Error())
@@ -60,6 +60,10 @@ const minParallelFileSize = parallelBlockSize * 6
 // Size of the ZIP compression window (32KB)
 const windowSize = 32 * 1024
 
+// Size and number of entries above which a jar requires the zip64 extensions, lowered by tests.
+var maxJarSize int64 = jar.MaxSizeWithoutZip64
+var maxJarEntries = jar.MaxEntriesWithoutZip64
+
 type nopCloser struct {
 	io.Writer
 }
@@ -255,6 +259,8 @@ type ZipWriter struct {
 	fs     pathtools.FileSystem
 
 	sha256Checksum bool
+
+	noZip64 bool
 }
 
 type zipEntry struct {
@@ -285,6 +291,11 @@ type ZipArgs struct {
 	DoNotWrite               bool
 	Quiet                    bool
 
+	// NoZip64 makes building a jar that requires the zip64 extensions, because it is 4GB or
+	// larger or has more than 65535 entries, fail with a jar.Zip64RequiredError instead of
+	// producing an archive that some jar consumers cannot read.  Plain zip files are not limited.
+	NoZip64 bool
+
 	Stderr     io.Writer
 	Filesystem pathtools.FileSystem
 }
@@ -308,6 +319,7 @@ func zipTo(args ZipArgs, w io.Writer) error {
 		stderr:             args.Stderr,
 		fs:                 args.Filesystem,
 		sha256Checksum:     args.Sha256Checksum,
+		noZip64:            args.NoZip64,
 	}
 
 	if z.fs == nil {
@@ -687,6 +699,9 @@ func (z *ZipWriter) write(f io.Writer, pathMappings []pathMapping, manifest stri
 		return err
 	default:
 		zipw.Close()
+		if emulateJar && z.noZip64 && (zipw.NumEntries() > maxJarEntries || zipw.Size() > maxJarSize) {
+			return jar.Zip64RequiredError{Entries: zipw.NumEntries(), Size: zipw.Size()}
+		}
 		return nil
 	}
 }
@@ -24,6 +24,7 @@ import (
 	"syscall"
 	"testing"
 
+	"android/soong/jar"
 	"android/soong/third_party/zip"
 
 	"github.com/google/blueprint/pathtools"
@@ -744,3 +745,53 @@ func TestSrcJar(t *testing.T) {
 		t.Errorf("want files %q, got %q", want, got)
 	}
 }
+
+func TestZip64Required(t *testing.T) {
+	defer func(size int64, entries int) {
+		maxJarSize, maxJarEntries = size, entries
+	}(maxJarSize, maxJarEntries)
+
+	testCases := []struct {
+		name       string
+		emulateJar bool
+		noZip64    bool
+		maxSize    int64
+		maxEntries int
+		err        bool
+	}{
+		{name: "jar", emulateJar: true, maxSize: int64(len(fileA)), maxEntries: 1},
+		{name: "jar too large", emulateJar: true, noZip64: true, maxSize: int64(len(fileA)), maxEntries: 100, err: true},
+		{name: "jar too many entries", emulateJar: true, noZip64: true, maxSize: 1 << 20, maxEntries: 1, err: true},
+		{name: "small jar", emulateJar: true, noZip64: true, maxSize: 1 << 20, maxEntries: 100},
+		{name: "zip", emulateJar: false, noZip64: true, maxSize: int64(len(fileA)), maxEntries: 1},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			maxJarSize, maxJarEntries = test.maxSize, test.maxEntries
+
+			args := ZipArgs{}
+			args.FileArgs = fileArgsBuilder().File("a/a/a").File("a/a/b").FileArgs()
+			args.EmulateJar = test.emulateJar
+			args.NoZip64 = test.noZip64
+			args.Filesystem = mockFs
+			args.Stderr = &bytes.Buffer{}
+
+			err := zipTo(args, &bytes.Buffer{})
+			if !test.err {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			zip64Err, ok := err.(jar.Zip64RequiredError)
+			if !ok {
+				t.Fatalf("want Zip64RequiredError, got %v", err)
+			}
+			if zip64Err.Size <= maxJarSize && zip64Err.Entries <= maxJarEntries {
+				t.Errorf("want more than %d entries or %d bytes, got %d entries and %d bytes",
+					maxJarEntries, maxJarSize, zip64Err.Entries, zip64Err.Size)
+			}
+		})
+	}
+}